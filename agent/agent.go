@@ -4,6 +4,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
@@ -14,7 +15,9 @@ import (
 	"github.com/evergreen-ci/evergreen/apimodels"
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/plugin/builtin/docker"
 	"github.com/evergreen-ci/evergreen/plugin/builtin/shell"
+	"github.com/evergreen-ci/evergreen/util"
 	"github.com/mongodb/grip"
 	"github.com/mongodb/grip/slogger"
 	"github.com/pkg/errors"
@@ -45,6 +48,10 @@ const (
 
 	// DefaultAgentSleepInterval is the interval after which the agent retries getting a next task
 	DefaultAgentSleepInterval = 30 * time.Second
+
+	// DefaultCmdRetryBackoff is how long the agent waits before retrying a
+	// failed command if the command's retry_backoff_secs is not specified.
+	DefaultCmdRetryBackoff = 10 * time.Second
 )
 
 var (
@@ -80,6 +87,9 @@ type SignalHandler struct {
 	// signal channels for each background process
 	directoryChan, heartbeatChan, idleTimeoutChan, execTimeoutChan, communicatorChan chan comm.Signal
 
+	// abortChan receives abort requests made through the status server.
+	abortChan chan comm.Signal
+
 	// a single channel for stopping all background processes
 	stopBackgroundChan chan struct{}
 }
@@ -134,9 +144,22 @@ type Agent struct {
 	// to the API server.
 	APILogger *comm.APILogger
 
-	// Holds the current command being executed by the agent.
-	currentCommand      model.PluginCommandConf
-	currentCommandMutex sync.RWMutex
+	// Holds the current command being executed by the agent, and when it
+	// started.
+	currentCommand        model.PluginCommandConf
+	currentCommandStarted time.Time
+	currentCommandMutex   sync.RWMutex
+
+	// taskStarted is when the agent started running the current task.
+	taskStarted time.Time
+
+	// statusMutex guards the fields the status server reads while the agent
+	// replaces them between tasks: signalHandler, APILogger, taskStarted and
+	// the timeout watchers.
+	statusMutex sync.RWMutex
+
+	// trace records the commands run for the current task.
+	trace commandTrace
 
 	// taskConfig holds the project, distro and task objects for the agent's
 	// assigned task.
@@ -152,6 +175,10 @@ type Agent struct {
 
 	// agent's runtime configuration options.
 	opts Options
+
+	// statusServer serves the agent's status, and ensures that only one
+	// agent runs on the host.
+	statusServer *http.Server
 }
 
 // finishAndAwaitCleanup sends the returned TaskEndResponse and error
@@ -193,8 +220,11 @@ func (agt *Agent) finishAndAwaitCleanup(status string) (*apimodels.EndTaskRespon
 		agt.logger.LogExecution(slogger.ERROR, "Error removing task directory: %v", err)
 	}
 
+	detail.Commands = agt.trace.export()
+
 	agt.logger.LogExecution(slogger.INFO, "Sending final status as: %v", detail.Status)
 	agt.APILogger.FlushAndWait()
+	agt.stopLogStream()
 
 	resp, err := agt.End(detail)
 	if err != nil {
@@ -224,6 +254,7 @@ func (sh *SignalHandler) makeChannels() {
 	sh.execTimeoutChan = make(chan comm.Signal, 1)
 	sh.communicatorChan = make(chan comm.Signal, 1)
 	sh.directoryChan = make(chan comm.Signal, 1)
+	sh.abortChan = make(chan comm.Signal, 1)
 	sh.stopBackgroundChan = make(chan struct{})
 }
 
@@ -236,6 +267,7 @@ func (sh *SignalHandler) awaitSignal() comm.Signal {
 	case sig = <-sh.execTimeoutChan:
 	case sig = <-sh.communicatorChan:
 	case sig = <-sh.directoryChan:
+	case sig = <-sh.abortChan:
 	case <-sh.stopBackgroundChan:
 		return comm.Completed
 	}
@@ -268,6 +300,10 @@ func (sh *SignalHandler) HandleSignals(agt *Agent) {
 	case comm.AbortedByUser:
 		detail.Status = evergreen.TaskUndispatched
 		agt.logger.LogTask(slogger.WARN, "Received abort signal - stopping.")
+	case comm.AbortedOnHost:
+		detail.Status = evergreen.TaskFailed
+		detail.Description = "aborted on host"
+		agt.logger.LogTask(slogger.WARN, "Received abort request from the host's status server - stopping.")
 	case comm.DirectoryFailure:
 		detail.Status = evergreen.TaskFailed
 		detail.Type = model.SystemCommandType
@@ -275,6 +311,8 @@ func (sh *SignalHandler) HandleSignals(agt *Agent) {
 	case comm.IdleTimeout:
 		agt.logger.LogTask(slogger.ERROR, "Task timed out: '%v'", detail.Description)
 		detail.TimedOut = true
+		agt.trace.timedOut()
+		agt.collectTimeoutDiagnostics()
 		if agt.taskConfig.Project.Timeout != nil {
 			agt.logger.LogTask(slogger.INFO, "Running task-timeout commands.")
 			start := time.Now()
@@ -309,6 +347,7 @@ func (agt *Agent) GetCurrentCommand() model.PluginCommandConf {
 func (agt *Agent) CheckIn(command model.PluginCommandConf, duration time.Duration) {
 	agt.currentCommandMutex.Lock()
 	agt.currentCommand = command
+	agt.currentCommandStarted = time.Now()
 	agt.currentCommandMutex.Unlock()
 
 	agt.idleTimeoutWatcher.SetDuration(duration)
@@ -363,6 +402,17 @@ type Options struct {
 	Certificate string
 	LogPrefix   string
 	StatusPort  int
+	HealthCheck HealthCheckOptions
+
+	// LogTransport is how task logs are sent to the API server, either
+	// comm.LogTransportPost or comm.LogTransportStream.
+	LogTransport string
+	LogStream    comm.LogStreamOptions
+
+	// Register makes the agent register its host with the API server before
+	// it asks for a task. It is set when the host started the agent itself,
+	// rather than the task runner starting it over SSH.
+	Register bool
 }
 
 // Setup initializes all the signal chans and loggers that are used during one run of the agent.
@@ -374,18 +424,23 @@ func (agt *Agent) Setup() error {
 	// set signal handler
 	sigHandler := &SignalHandler{}
 	sigHandler.makeChannels()
-	agt.signalHandler = sigHandler
 
 	agt.TaskCommunicator.SetSignalChan(sigHandler.communicatorChan)
 
 	// create and set the idle timeout watcher
 	idleTimeoutWatcher := comm.NewTimeoutWatcher(sigHandler.stopBackgroundChan)
 	idleTimeoutWatcher.SetDuration(DefaultIdleTimeout)
-	agt.idleTimeoutWatcher = idleTimeoutWatcher
 
 	// Loggers
 	apiLogger := comm.NewAPILogger(agt.TaskCommunicator)
+
+	agt.statusMutex.Lock()
+	agt.signalHandler = sigHandler
+	agt.idleTimeoutWatcher = idleTimeoutWatcher
 	agt.APILogger = apiLogger
+	agt.maxExecTimeoutWatcher = nil
+	agt.taskStarted = time.Time{}
+	agt.statusMutex.Unlock()
 
 	// set up timeout logger, local and API logger streams
 	streamLogger, err := comm.NewStreamLogger(idleTimeoutWatcher, apiLogger)
@@ -394,6 +449,7 @@ func (agt *Agent) Setup() error {
 	}
 	agt.TaskCommunicator.SetLogger(streamLogger.Execution)
 	agt.logger = streamLogger
+	agt.startLogStream()
 
 	// set up the heartbeat ticker
 	hbTicker := comm.NewHeartbeatTicker(sigHandler.stopBackgroundChan)
@@ -413,6 +469,25 @@ func (agt *Agent) Setup() error {
 	return nil
 }
 
+// startLogStream streams the current task's logs to the API server, if the
+// agent is configured to.
+func (agt *Agent) startLogStream() {
+	httpComm, ok := agt.TaskCommunicator.(*comm.HTTPCommunicator)
+	if !ok || agt.opts.LogTransport != comm.LogTransportStream || httpComm.TaskId == "" {
+		return
+	}
+	grip.Warning(errors.Wrap(httpComm.StartLogStream(agt.opts.LogStream),
+		"error starting log stream, posting logs instead"))
+}
+
+// stopLogStream waits for the task's streamed logs to be acknowledged, so that
+// they are complete when the task ends.
+func (agt *Agent) stopLogStream() {
+	if httpComm, ok := agt.TaskCommunicator.(*comm.HTTPCommunicator); ok {
+		grip.Warning(errors.Wrap(httpComm.StopLogStream(), "error draining log stream"))
+	}
+}
+
 // New creates a new agent to run a given task.
 func New(opts Options) (*Agent, error) {
 
@@ -434,28 +509,48 @@ func New(opts Options) (*Agent, error) {
 	agt.startStatusServer(opts.StatusPort)
 
 	agt.TaskCommunicator = httpCommunicator
-	if err := agt.Setup(); err != nil {
+	if err := agt.initialize(); err != nil {
 		return nil, err
 	}
+	return agt, nil
+}
+
+// initialize sets the agent up for its first task and registers the plugins
+// needed for execution.
+func (agt *Agent) initialize() error {
+	if err := agt.Setup(); err != nil {
+		return err
+	}
 
 	agt.Registry = plugin.NewSimpleRegistry()
 
 	// register plugins needed for execution
 	if err := registerPlugins(agt.Registry, plugin.CommandPlugins, agt.logger); err != nil {
 		grip.Criticalf("error registering plugins: %+v", err)
-		return nil, err
+		return err
 	}
-	return agt, nil
+	return nil
 }
 
 // getNextTask attempts to retrieve a next task and adds it in the
 // agent's communicator if it exists. It returns true if there is a next task in the agent.
 func (agt *Agent) getNextTask() (bool, error) {
+	if err := agt.ensureHealthy(); err != nil {
+		grip.Criticalf("not accepting tasks: %+v", err)
+		return false, err
+	}
+
 	nextTaskResponse, err := agt.GetNextTask()
 	if err != nil {
 		grip.Criticalf("error getting next task: %+v", err)
 		return false, err
 	}
+	if nextTaskResponse.AgentRevision != "" && nextTaskResponse.AgentRevision != evergreen.BuildRevision {
+		grip.Infof("next task response indicates that agent should update: %v", nextTaskResponse.Message)
+		// updateAgent only returns if the update fails
+		err = agt.updateAgent(nextTaskResponse.AgentRevision)
+		return false, errors.Wrapf(err, "error updating agent to revision %s", nextTaskResponse.AgentRevision)
+	}
 	if nextTaskResponse.ShouldExit {
 		grip.Infof("next task response indicates that agent should exit: %v", nextTaskResponse.Message)
 		return false, fmt.Errorf("next task response indicates that agent should exit %v", nextTaskResponse.Message)
@@ -480,6 +575,18 @@ func (agt *Agent) getNextTask() (bool, error) {
 // Run is the agent loop which gets the next task if it exists, and runs the task if it gets one.
 // It returns an exit code when the agent needs to exit
 func (agt *Agent) Run() error {
+	if agt.opts.Register {
+		if err := agt.Register(); err != nil {
+			return errors.Wrap(err, "error registering agent")
+		}
+		grip.Info("registered agent with the API server")
+	}
+
+	// remove the containers of tasks a previous agent didn't clean up
+	if err := docker.CleanupOrphans(agt.logger); err != nil {
+		grip.Errorf("Error cleaning up leftover docker containers: %v", err)
+	}
+
 	var currentTask string
 	// this loop continues until the agent exits
 	for {
@@ -528,6 +635,10 @@ func (agt *Agent) Run() error {
 // indicating the end result of the task.
 func (agt *Agent) RunTask() (*apimodels.EndTaskResponse, error) {
 
+	agt.trace.reset()
+	agt.statusMutex.Lock()
+	agt.taskStarted = time.Now()
+	agt.statusMutex.Unlock()
 	agt.CheckIn(InitialSetupCommand, InitialSetupTimeout)
 
 	agt.logger.LogLocal(slogger.INFO, "Local logger initialized.")
@@ -561,9 +672,12 @@ func (agt *Agent) RunTask() (*apimodels.EndTaskResponse, error) {
 	execTimeout := time.Duration(pt.ExecTimeoutSecs) * time.Second
 	// Set master task timeout, only if included in the taskConfig
 	if execTimeout != 0 {
-		agt.maxExecTimeoutWatcher = comm.NewTimeoutWatcher(
+		maxExecTimeoutWatcher := comm.NewTimeoutWatcher(
 			agt.signalHandler.stopBackgroundChan)
-		agt.maxExecTimeoutWatcher.SetDuration(execTimeout)
+		maxExecTimeoutWatcher.SetDuration(execTimeout)
+		agt.statusMutex.Lock()
+		agt.maxExecTimeoutWatcher = maxExecTimeoutWatcher
+		agt.statusMutex.Unlock()
 	}
 
 	agt.logger.LogExecution(slogger.INFO, "Fetching expansions for project %v...", taskConfig.Task.Project)
@@ -575,6 +689,19 @@ func (agt *Agent) RunTask() (*apimodels.EndTaskResponse, error) {
 	taskConfig.Expansions.Update(*expVars)
 	agt.taskConfig = taskConfig
 
+	privateVars, err := agt.FetchPrivateVars()
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "error fetching private project variables: %v", err)
+		return nil, err
+	}
+	redacted := map[string]string{}
+	taskConfig.PrivateVars = map[string]bool{}
+	for _, name := range privateVars {
+		redacted[name] = taskConfig.Expansions.Get(name)
+		taskConfig.PrivateVars[name] = true
+	}
+	agt.logger.RedactPrivateVars(redacted)
+
 	// set up the system stats collector
 	statsCollectorKill := make(chan struct{})
 	agt.statsCollector = NewSimpleStatsCollector(
@@ -649,6 +776,12 @@ func (agt *Agent) RunTaskCommands() string {
 // All plugins listen on the stop channel and must terminate immediately when a
 // value is received.
 func (agt *Agent) RunCommands(commands []model.PluginCommandConf, returnOnError bool, stop chan bool) error {
+	// the config that the current command runs with, which is scoped to its
+	// function, if any. Expansions scoped to a function are restored if it
+	// ends the run early.
+	conf := agt.taskConfig
+	defer func() { conf.EndFunctionScope() }()
+
 	for i, commandInfo := range commands {
 		parsedCommands, err := agt.Registry.ParseCommandConf(commandInfo, agt.taskConfig.Project.Functions)
 		if err != nil {
@@ -668,6 +801,11 @@ func (agt *Agent) RunCommands(commands []model.PluginCommandConf, returnOnError
 			continue
 		}
 
+		conf = agt.taskConfig
+		if commandInfo.Function != "" {
+			conf = agt.taskConfig.WithFunctionScope()
+		}
+
 		for j, cmd := range cmds {
 
 			fullCommandName := cmd.Plugin() + "." + cmd.Name()
@@ -726,23 +864,81 @@ func (agt *Agent) RunCommands(commands []model.PluginCommandConf, returnOnError
 
 			agt.CheckIn(parsedCommand, timeoutPeriod)
 
+			traceIndex := agt.trace.start(apimodels.CommandTrace{
+				Name:        parsedCommand.Command,
+				DisplayName: parsedCommand.DisplayName,
+				Function:    commandInfo.Function,
+				Type:        parsedCommand.GetType(agt.taskConfig.Project),
+			})
 			start := time.Now()
-			err = cmd.Execute(commandLogger, pluginCom, agt.taskConfig, stop)
+			err = agt.executeCommand(cmd, conf, parsedCommand, commandLogger, pluginCom, timeoutPeriod, stop)
+			agt.trace.finish(traceIndex, err)
 
 			agt.logger.LogExecution(slogger.INFO, "Finished %v in %v", fullCommandName, time.Since(start).String())
 
 			if err != nil {
 				agt.logger.LogTask(slogger.ERROR, "Command failed: %v", err)
+				if parsedCommand.ContinueOnError {
+					agt.logger.LogTask(slogger.WARN, "Continuing after failure of %v since continue_on_err is set",
+						fullCommandName)
+					continue
+				}
 				if returnOnError {
 					return err
 				}
 				continue
 			}
 		}
+		conf.EndFunctionScope()
 	}
 	return nil
 }
 
+// executeCommand runs a command, retrying it with exponential backoff as many
+// times as its retry_on_failure allows. A command is not retried once the stop
+// channel has been closed, since it was most likely killed rather than failed.
+func (agt *Agent) executeCommand(cmd plugin.Command, taskConfig *model.TaskConfig, conf model.PluginCommandConf,
+	logger plugin.Logger, pluginCom plugin.PluginCommunicator, timeout time.Duration, stop chan bool) error {
+	if conf.RetryOnFailure <= 0 {
+		return cmd.Execute(logger, pluginCom, taskConfig, stop)
+	}
+
+	backoff := DefaultCmdRetryBackoff
+	if conf.RetryBackoffSecs > 0 {
+		backoff = time.Duration(conf.RetryBackoffSecs) * time.Second
+	}
+
+	attempt := 0
+	var lastErr error
+	_, err := util.RetryWithStop(func() error {
+		attempt++
+		if attempt > 1 {
+			select {
+			case <-stop:
+				return errors.Wrap(lastErr, "task was stopped before the command could be retried")
+			default:
+			}
+			logger.LogTask(slogger.WARN, "Retrying command after failure (attempt %v of %v)",
+				attempt, conf.RetryOnFailure+1)
+			agt.CheckIn(conf, timeout)
+		}
+
+		lastErr = cmd.Execute(logger, pluginCom, taskConfig, stop)
+		if lastErr == nil {
+			return nil
+		}
+		select {
+		case <-stop:
+			return lastErr
+		default:
+			logger.LogTask(slogger.WARN, "Command failed on attempt %v: %v", attempt, lastErr)
+			return util.RetriableError{Failure: lastErr}
+		}
+	}, conf.RetryOnFailure, backoff, stop)
+
+	return err
+}
+
 // registerPlugins makes plugins available for use by the agent.
 func registerPlugins(registry plugin.Registry, plugins []plugin.CommandPlugin, logger *comm.StreamLogger) error {
 	for _, pl := range plugins {
@@ -869,8 +1065,8 @@ func (agt *Agent) removeTaskDirectory() error {
 	return nil
 }
 
-// cleanup attempts to terminate all processes started by the task
-// and flushes the logs.
+// cleanup attempts to terminate all processes started by the task,
+// removes the docker containers and images it created, and flushes the logs.
 func (agt *Agent) cleanup(taskId string) {
 	grip.Infof("cleaning up processes for task: %s", taskId)
 	if taskId != "" {
@@ -878,6 +1074,9 @@ func (agt *Agent) cleanup(taskId string) {
 			msg := fmt.Sprintf("Error cleaning up spawned processes (agent-exit): %v", err)
 			grip.Critical(msg)
 		}
+		if err := docker.CleanupTask(taskId, agt.logger); err != nil {
+			grip.Criticalf("Error cleaning up docker containers and images: %v", err)
+		}
 	}
 	grip.Infof("processes cleaned up for task %s", taskId)
 }
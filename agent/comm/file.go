@@ -0,0 +1,345 @@
+package comm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/evergreen-ci/evergreen/model/version"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+const (
+	// FileResultsName is the file in a FileCommunicator's output directory
+	// that holds the task's test results.
+	FileResultsName = "results.json"
+	// FileArtifactsName is the file in a FileCommunicator's output directory
+	// that holds the task's attached artifacts.
+	FileArtifactsName = "artifacts.json"
+	// FileEndDetailName is the file in a FileCommunicator's output directory
+	// that holds the task's final status.
+	FileEndDetailName = "end.json"
+	// FileStoreDir is the directory in a FileCommunicator's output directory
+	// that local runs keep the files they put to the artifact store in.
+	FileStoreDir = "store"
+)
+
+// FileCommunicator is a TaskCommunicator for running a task without an API
+// server. The task and its configuration are provided up front, logs are
+// written to files, and everything the task would post to the API server is
+// written as JSON to an output directory.
+type FileCommunicator struct {
+	Task       *task.Task
+	Distro     *distro.Distro
+	Version    *version.Version
+	ProjectRef *model.ProjectRef
+	Expansions apimodels.ExpansionVars
+
+	// OutputDir is the directory logs, test results, artifacts and other
+	// posted data are written to.
+	OutputDir string
+	// Stdout, if set, receives a copy of every log message. The agent's
+	// local logger already writes them to standard output.
+	Stdout io.Writer
+
+	mu        sync.Mutex
+	endDetail *apimodels.TaskEndDetail
+	logFiles  map[string]*os.File
+	results   []task.TestResult
+	artifacts []*artifact.File
+	testLogs  int
+}
+
+// NewFileCommunicator returns a FileCommunicator for the task that writes to
+// outputDir, creating it if necessary. The agent changes directory while it
+// runs a task, so a relative outputDir is made absolute.
+func NewFileCommunicator(outputDir string, t *task.Task) (*FileCommunicator, error) {
+	outputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "error creating output directory %s", outputDir)
+	}
+	return &FileCommunicator{
+		Task:       t,
+		Expansions: apimodels.ExpansionVars{},
+		OutputDir:  outputDir,
+		logFiles:   map[string]*os.File{},
+	}, nil
+}
+
+// EndDetail returns the details the task ended with, or nil if it has not
+// ended yet.
+func (fc *FileCommunicator) EndDetail() *apimodels.TaskEndDetail {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.endDetail
+}
+
+// Close closes the communicator's log files.
+func (fc *FileCommunicator) Close() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	var errs []string
+	for name, f := range fc.logFiles {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+		delete(fc.logFiles, name)
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("error closing log files: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (fc *FileCommunicator) Start() error {
+	return nil
+}
+
+// End records the task's final status. The response always tells the agent
+// to exit, since there is no next task.
+func (fc *FileCommunicator) End(detail *apimodels.TaskEndDetail) (*apimodels.EndTaskResponse, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.endDetail = detail
+	if err := fc.writeJSON(FileEndDetailName, detail); err != nil {
+		return nil, err
+	}
+	return &apimodels.EndTaskResponse{ShouldExit: true, Message: "task ran locally"}, nil
+}
+
+func (fc *FileCommunicator) GetTask() (*task.Task, error) {
+	if fc.Task == nil {
+		return nil, errors.New("no task configured")
+	}
+	return fc.Task, nil
+}
+
+func (fc *FileCommunicator) GetProjectRef() (*model.ProjectRef, error) {
+	if fc.ProjectRef == nil {
+		return nil, errors.New("no project ref configured")
+	}
+	return fc.ProjectRef, nil
+}
+
+func (fc *FileCommunicator) GetDistro() (*distro.Distro, error) {
+	if fc.Distro == nil {
+		return nil, errors.New("no distro configured")
+	}
+	return fc.Distro, nil
+}
+
+func (fc *FileCommunicator) GetVersion() (*version.Version, error) {
+	if fc.Version == nil {
+		return nil, errors.New("no version configured")
+	}
+	return fc.Version, nil
+}
+
+// Log appends each message to the log file for its type, i.e. task.log,
+// agent.log or system.log, and copies it to Stdout if that is set.
+func (fc *FileCommunicator) Log(messages []model.LogMessage) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	for _, msg := range messages {
+		line := fmt.Sprintf("[%s] [%s] %s\n", msg.Timestamp.Format("2006/01/02 15:04:05.000"),
+			msg.Severity, strings.TrimRight(msg.Message, "\n"))
+
+		if fc.Stdout != nil {
+			if _, err := io.WriteString(fc.Stdout, line); err != nil {
+				return errors.Wrap(err, "error writing log to stdout")
+			}
+		}
+
+		f, err := fc.logFile(msg.Type)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, line); err != nil {
+			return errors.Wrapf(err, "error writing log to %s", f.Name())
+		}
+	}
+	return nil
+}
+
+// logFile returns the open log file for the message type, opening it if
+// necessary. It must be called with the lock held.
+func (fc *FileCommunicator) logFile(msgType string) (*os.File, error) {
+	name := "task.log"
+	switch msgType {
+	case model.AgentLogPrefix:
+		name = "agent.log"
+	case model.SystemLogPrefix:
+		name = "system.log"
+	}
+
+	if f, ok := fc.logFiles[name]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(filepath.Join(fc.OutputDir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening log file %s", name)
+	}
+	fc.logFiles[name] = f
+	return f, nil
+}
+
+// Heartbeat always succeeds, since a local task cannot be aborted remotely.
+func (fc *FileCommunicator) Heartbeat() (bool, error) {
+	return false, nil
+}
+
+func (fc *FileCommunicator) FetchExpansionVars() (*apimodels.ExpansionVars, error) {
+	vars := apimodels.ExpansionVars{}
+	for k, v := range fc.Expansions {
+		vars[k] = v
+	}
+	return &vars, nil
+}
+
+// FetchPrivateVars returns no variables, since local expansions are not secret.
+func (fc *FileCommunicator) FetchPrivateVars() ([]string, error) {
+	return []string{}, nil
+}
+
+func (fc *FileCommunicator) GetNextTask() (*apimodels.NextTaskResponse, error) {
+	return &apimodels.NextTaskResponse{ShouldExit: true, Message: "no tasks are dispatched locally"}, nil
+}
+
+// ReportUnhealthy does nothing, since there is no API server to dispatch tasks.
+func (fc *FileCommunicator) ReportUnhealthy([]string) error {
+	return nil
+}
+
+// Register does nothing, since there is no API server to register with.
+func (fc *FileCommunicator) Register() error {
+	return nil
+}
+
+// TryTaskGet responds as if nothing exists at the path, since there is no API
+// server to store data for the task.
+func (fc *FileCommunicator) TryTaskGet(path string) (*http.Response, error) {
+	return fileResponse(http.StatusNotFound, fmt.Sprintf("%s is not available when running locally", path)), nil
+}
+
+// TryTaskPost writes the data to the output directory. Test results and
+// artifacts are collected into results.json and artifacts.json, test logs are
+// written to the test_logs directory and anything else is appended to a file
+// named after the path.
+func (fc *FileCommunicator) TryTaskPost(path string, data interface{}) (*http.Response, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	var err error
+	body := "{}"
+	switch path {
+	case "results":
+		results, ok := data.(*task.TestResults)
+		if !ok {
+			return fileResponse(http.StatusBadRequest, "invalid test results"), nil
+		}
+		fc.results = append(fc.results, results.Results...)
+		err = fc.writeJSON(FileResultsName, task.TestResults{Results: fc.results})
+	case "files":
+		files, ok := data.([]*artifact.File)
+		if !ok {
+			return fileResponse(http.StatusBadRequest, "invalid task files"), nil
+		}
+		fc.artifacts = append(fc.artifacts, files...)
+		err = fc.writeJSON(FileArtifactsName, fc.artifacts)
+	case "test_logs":
+		fc.testLogs++
+		id := fmt.Sprintf("test_log_%d", fc.testLogs)
+		if err = os.MkdirAll(filepath.Join(fc.OutputDir, "test_logs"), 0755); err == nil {
+			err = fc.writeJSON(filepath.Join("test_logs", id+".json"), data)
+		}
+		body = fmt.Sprintf(`{"_id": %q}`, id)
+	default:
+		err = fc.appendJSON(strings.Replace(path, "/", "_", -1)+".json", data)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return fileResponse(http.StatusOK, body), nil
+}
+
+func (fc *FileCommunicator) TryGet(path string) (*http.Response, error) {
+	return fc.TryTaskGet(path)
+}
+
+func (fc *FileCommunicator) TryPostJSON(path string, data interface{}) (*http.Response, error) {
+	return fc.TryTaskPost(path, data)
+}
+
+func (fc *FileCommunicator) SetTask(taskId, taskSecret string) {
+	if fc.Task == nil {
+		fc.Task = &task.Task{}
+	}
+	fc.Task.Id = taskId
+}
+
+func (fc *FileCommunicator) GetCurrentTaskId() string {
+	if fc.Task == nil {
+		return ""
+	}
+	return fc.Task.Id
+}
+
+func (fc *FileCommunicator) SetSignalChan(chan Signal) {}
+
+func (fc *FileCommunicator) SetLogger(*slogger.Logger) {}
+
+// writeJSON replaces the named file in the output directory with the JSON
+// encoding of v. It must be called with the lock held.
+func (fc *FileCommunicator) writeJSON(name string, v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling %s", name)
+	}
+	return errors.Wrapf(ioutil.WriteFile(filepath.Join(fc.OutputDir, name), out, 0644),
+		"error writing %s", name)
+}
+
+// appendJSON appends the JSON encoding of v to the named file in the output
+// directory as a single line. It must be called with the lock held.
+func (fc *FileCommunicator) appendJSON(name string, v interface{}) error {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling %s", name)
+	}
+	f, err := os.OpenFile(filepath.Join(fc.OutputDir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "error opening %s", name)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(out, '\n'))
+	return errors.Wrapf(err, "error writing %s", name)
+}
+
+func fileResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+	}
+}
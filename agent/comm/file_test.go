@@ -0,0 +1,111 @@
+package comm
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/model/task"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFileCommunicator(t *testing.T) {
+	Convey("With a file communicator writing to a temporary directory", t, func() {
+		dir, err := ioutil.TempDir("", "file-comm")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		fc, err := NewFileCommunicator(dir, &task.Task{Id: "local_task"})
+		So(err, ShouldBeNil)
+		defer fc.Close()
+		So(fc.GetCurrentTaskId(), ShouldEqual, "local_task")
+
+		Convey("log messages should be written to a file per type", func() {
+			So(fc.Log([]model.LogMessage{
+				{Type: model.TaskLogPrefix, Severity: "I", Message: "task line", Timestamp: time.Now()},
+				{Type: model.AgentLogPrefix, Severity: "E", Message: "agent line", Timestamp: time.Now()},
+			}), ShouldBeNil)
+			So(fc.Close(), ShouldBeNil)
+
+			taskLog, err := ioutil.ReadFile(filepath.Join(dir, "task.log"))
+			So(err, ShouldBeNil)
+			So(string(taskLog), ShouldContainSubstring, "task line")
+			agentLog, err := ioutil.ReadFile(filepath.Join(dir, "agent.log"))
+			So(err, ShouldBeNil)
+			So(string(agentLog), ShouldContainSubstring, "agent line")
+		})
+
+		Convey("test results and artifacts should accumulate in JSON files", func() {
+			for _, name := range []string{"test1", "test2"} {
+				resp, err := fc.TryTaskPost("results", &task.TestResults{
+					Results: []task.TestResult{{TestFile: name, Status: evergreen.TestSucceededStatus}},
+				})
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			}
+			resp, err := fc.TryTaskPost("files", []*artifact.File{{Name: "binary", Link: "/tmp/binary"}})
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+
+			results := task.TestResults{}
+			data, err := ioutil.ReadFile(filepath.Join(dir, FileResultsName))
+			So(err, ShouldBeNil)
+			So(json.Unmarshal(data, &results), ShouldBeNil)
+			So(len(results.Results), ShouldEqual, 2)
+			So(results.Results[1].TestFile, ShouldEqual, "test2")
+
+			files := []artifact.File{}
+			data, err = ioutil.ReadFile(filepath.Join(dir, FileArtifactsName))
+			So(err, ShouldBeNil)
+			So(json.Unmarshal(data, &files), ShouldBeNil)
+			So(len(files), ShouldEqual, 1)
+			So(files[0].Name, ShouldEqual, "binary")
+		})
+
+		Convey("test logs should be stored and given ids", func() {
+			resp, err := fc.TryTaskPost("test_logs", &model.TestLog{Name: "log"})
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			body, err := ioutil.ReadAll(resp.Body)
+			So(err, ShouldBeNil)
+			So(string(body), ShouldContainSubstring, "test_log_1")
+			_, err = os.Stat(filepath.Join(dir, "test_logs", "test_log_1.json"))
+			So(err, ShouldBeNil)
+		})
+
+		Convey("other posts should be appended to a file named after the path", func() {
+			_, err := fc.TryTaskPost("json/data/name", map[string]int{"a": 1})
+			So(err, ShouldBeNil)
+			_, err = fc.TryTaskPost("json/data/name", map[string]int{"a": 2})
+			So(err, ShouldBeNil)
+			data, err := ioutil.ReadFile(filepath.Join(dir, "json_data_name.json"))
+			So(err, ShouldBeNil)
+			So(len(strings.Split(strings.TrimSpace(string(data)), "\n")), ShouldEqual, 2)
+		})
+
+		Convey("gets should find nothing", func() {
+			resp, err := fc.TryTaskGet("manifest/load")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+		})
+
+		Convey("ending the task should record its status and tell the agent to exit", func() {
+			So(fc.EndDetail(), ShouldBeNil)
+			resp, err := fc.End(&apimodels.TaskEndDetail{Status: evergreen.TaskFailed})
+			So(err, ShouldBeNil)
+			So(resp.ShouldExit, ShouldBeTrue)
+			So(fc.EndDetail().Status, ShouldEqual, evergreen.TaskFailed)
+			_, err = os.Stat(filepath.Join(dir, FileEndDetailName))
+			So(err, ShouldBeNil)
+		})
+	})
+}
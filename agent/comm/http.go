@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/evergreen-ci/evergreen"
@@ -19,6 +20,7 @@ import (
 	"github.com/evergreen-ci/evergreen/model/task"
 	"github.com/evergreen-ci/evergreen/model/version"
 	"github.com/evergreen-ci/evergreen/util"
+	"github.com/mongodb/grip"
 	"github.com/mongodb/grip/slogger"
 	"github.com/pkg/errors"
 )
@@ -46,6 +48,10 @@ type HTTPCommunicator struct {
 	httpClient    *http.Client
 	// TODO only use one Client after global locking is removed
 	heartbeatClient *http.Client
+
+	// logStream, if set, carries the task's logs instead of POST requests.
+	logStream      *LogStream
+	logStreamMutex sync.RWMutex
 }
 
 // NewHTTPCommunicator returns an initialized HTTPCommunicator.
@@ -152,8 +158,25 @@ func (h *HTTPCommunicator) End(detail *apimodels.TaskEndDetail) (*apimodels.EndT
 	return taskEndResp, err
 }
 
-// Log sends a batch of log messages for the task's logs to the API server.
+// Log sends a batch of log messages for the task's logs to the API server,
+// over the task's log stream if it has one.
 func (h *HTTPCommunicator) Log(messages []model.LogMessage) error {
+	h.logStreamMutex.RLock()
+	ls := h.logStream
+	h.logStreamMutex.RUnlock()
+
+	if ls != nil {
+		err := ls.Append(messages)
+		if err == nil {
+			return nil
+		}
+		grip.Warning(errors.Wrap(err, "error streaming logs, posting them instead"))
+	}
+	return h.postLogs(messages)
+}
+
+// postLogs posts a batch of log messages to the API server.
+func (h *HTTPCommunicator) postLogs(messages []model.LogMessage) error {
 
 	outgoingData := model.TaskLog{
 		TaskId:       h.TaskId,
@@ -292,6 +315,66 @@ func (h *HTTPCommunicator) GetNextTask() (*apimodels.NextTaskResponse, error) {
 
 }
 
+// ReportUnhealthy tells the API server that the agent's host is not fit to run
+// tasks, so that it stops dispatching tasks to it.
+func (h *HTTPCommunicator) ReportUnhealthy(reasons []string) error {
+	retriablePost := util.RetriableFunc(
+		func() error {
+			resp, err := h.TryPostJSON("agent/unhealthy", apimodels.HostUnhealthyRequest{Reasons: reasons})
+			if resp != nil {
+				defer resp.Body.Close()
+			}
+			if err != nil {
+				return util.RetriableError{err}
+			}
+			if resp == nil {
+				return util.RetriableError{errors.New("empty response")}
+			}
+			if resp.StatusCode != http.StatusOK {
+				return util.RetriableError{errors.Errorf("unexpected status code %d", resp.StatusCode)}
+			}
+			return nil
+		})
+	retryFail, err := util.Retry(retriablePost, h.MaxAttempts, h.RetrySleep)
+	if retryFail {
+		return errors.Wrapf(err, "reporting host unhealthy failed after %d tries", h.MaxAttempts)
+	}
+	return err
+}
+
+// Register tells the API server that the agent has started on a host that
+// started its own agent, so that the host is marked provisioned and tasks are
+// dispatched to it.
+func (h *HTTPCommunicator) Register() error {
+	retriablePost := util.RetriableFunc(
+		func() error {
+			resp, err := h.TryPostJSON("agent/register", struct{}{})
+			if resp != nil {
+				defer resp.Body.Close()
+			}
+			if err != nil {
+				return util.RetriableError{err}
+			}
+			if resp == nil {
+				return util.RetriableError{errors.New("empty response")}
+			}
+			if resp.StatusCode != http.StatusOK {
+				err = errors.Errorf("unexpected status code %d", resp.StatusCode)
+				if resp.StatusCode >= http.StatusInternalServerError {
+					return util.RetriableError{err}
+				}
+				// the host can't register, which retrying won't change
+				return err
+			}
+			return nil
+		})
+	retryFail, err := util.Retry(retriablePost, h.MaxAttempts, h.RetrySleep)
+	if retryFail {
+		return errors.Wrapf(err, "registering agent failed after %d tries", h.MaxAttempts)
+	}
+	return err
+}
+
 // GetProjectConfig loads the communicator's task's project from the API server.
 func (h *HTTPCommunicator) GetProjectRef() (*model.ProjectRef, error) {
 	projectRef := &model.ProjectRef{}
@@ -482,6 +565,10 @@ func (h *HTTPCommunicator) tryRequestWithClient(path string, method string, clie
 	req.Header.Add(evergreen.TaskSecretHeader, h.TaskSecret)
 	req.Header.Add(evergreen.HostHeader, h.HostId)
 	req.Header.Add(evergreen.HostSecretHeader, h.HostSecret)
+	if evergreen.BuildRevision != "" {
+		// agents built without a revision can't tell if they're out of date
+		req.Header.Add(evergreen.AgentRevisionHeader, evergreen.BuildRevision)
+	}
 	req.Header.Add("Content-Type", "application/json")
 
 	resp, err := client.Do(req)
@@ -572,3 +659,38 @@ func (h *HTTPCommunicator) FetchExpansionVars() (*apimodels.ExpansionVars, error
 	}
 	return resultVars, err
 }
+
+// FetchPrivateVars loads the names of the communicator's task's private project
+// variables from the API server. A server that doesn't report them is treated
+// as having none.
+func (h *HTTPCommunicator) FetchPrivateVars() ([]string, error) {
+	privateVars := []string{}
+	retriableGet := util.RetriableFunc(
+		func() error {
+			resp, err := h.TryTaskGet("fetch_private_vars")
+			if resp != nil {
+				defer resp.Body.Close()
+			}
+			if err != nil {
+				return util.RetriableError{err}
+			}
+			if resp == nil {
+				return util.RetriableError{errors.New("empty response fetching private vars")}
+			}
+			switch {
+			case resp.StatusCode == http.StatusNotFound:
+				return nil
+			case resp.StatusCode >= http.StatusInternalServerError:
+				return util.RetriableError{errors.Errorf("failed fetching private vars, got bad response code: %v", resp.StatusCode)}
+			case resp.StatusCode != http.StatusOK:
+				return errors.Errorf("failed fetching private vars, got bad response code: %v", resp.StatusCode)
+			}
+			return errors.Wrap(util.ReadJSONInto(resp.Body, &privateVars), "failed to read private vars from response")
+		})
+
+	retryFail, err := util.Retry(retriableGet, httpMaxAttempts, 1*time.Second)
+	if retryFail {
+		return nil, errors.Wrap(err, "fetching private vars used up all retries")
+	}
+	return privateVars, errors.WithStack(err)
+}
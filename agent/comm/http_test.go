@@ -278,5 +278,20 @@ func TestCommunicatorServerUp(t *testing.T) {
 			So((*resultingVars)["second_fetch"], ShouldEqual, "more_one")
 
 		})
+
+		Convey("fetching private vars should work", func() {
+			serveMux.HandleFunc("/task/mocktaskid/fetch_private_vars", func(w http.ResponseWriter, req *http.Request) {
+				util.WriteJSON(&w, []string{"aws_secret"}, http.StatusOK)
+			})
+			privateVars, err := agentCommunicator.FetchPrivateVars()
+			So(err, ShouldBeNil)
+			So(privateVars, ShouldResemble, []string{"aws_secret"})
+		})
+
+		Convey("fetching private vars from a server that doesn't report them should return none", func() {
+			privateVars, err := agentCommunicator.FetchPrivateVars()
+			So(err, ShouldBeNil)
+			So(privateVars, ShouldBeEmpty)
+		})
 	})
 }
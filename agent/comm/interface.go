@@ -23,7 +23,10 @@ type TaskCommunicator interface {
 	Log([]model.LogMessage) error
 	Heartbeat() (bool, error)
 	FetchExpansionVars() (*apimodels.ExpansionVars, error)
+	FetchPrivateVars() ([]string, error)
 	GetNextTask() (*apimodels.NextTaskResponse, error)
+	ReportUnhealthy(reasons []string) error
+	Register() error
 	TryTaskGet(path string) (*http.Response, error)
 	TryTaskPost(path string, data interface{}) (*http.Response, error)
 	TryGet(path string) (*http.Response, error)
@@ -0,0 +1,724 @@
+package comm
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// LogTransportPost sends each batch of log messages in its own POST
+	// request. It is the default.
+	LogTransportPost = "post"
+	// LogTransportStream sends log messages over a websocket held open for
+	// the duration of the task.
+	LogTransportStream = "stream"
+
+	// DefaultLogStreamBufferBytes is the size of the compressed log batches
+	// a LogStream holds in memory before it spills them to disk.
+	DefaultLogStreamBufferBytes = 8 * 1024 * 1024
+	// DefaultLogStreamSpoolBytes is the size of the spool file at which a
+	// LogStream blocks the task's logging until the API server catches up.
+	DefaultLogStreamSpoolBytes = 1024 * 1024 * 1024
+	// DefaultLogStreamInFlight is the number of batches a LogStream sends
+	// before it waits for the API server to acknowledge them.
+	DefaultLogStreamInFlight = 16
+	// DefaultLogStreamDrainTimeout is how long the agent waits for the API
+	// server to acknowledge a task's logs when the task ends.
+	DefaultLogStreamDrainTimeout = 2 * time.Minute
+	// DefaultLogStreamResponseTimeout is how long the agent waits for the
+	// API server to complete the websocket handshake, or to acknowledge
+	// batches it has sent, before it reconnects.
+	DefaultLogStreamResponseTimeout = time.Minute
+
+	logStreamDialTimeout = 30 * time.Second
+	logStreamMinBackoff  = time.Second
+	logStreamMaxBackoff  = 30 * time.Second
+
+	// spoolRecordHeader is the size of the sequence number and length that
+	// start every batch in the spool file.
+	spoolRecordHeader = apimodels.LogBatchHeaderSize + 4
+)
+
+// LogStreamOptions configures a LogStream. Zero values are replaced with the
+// defaults.
+type LogStreamOptions struct {
+	// MaxBufferBytes is the size of the compressed batches held in memory.
+	// Once it is reached, further batches are written to a spool file.
+	MaxBufferBytes int
+	// SpoolDir is the directory the spool file is created in. It defaults to
+	// the system's temporary directory.
+	SpoolDir string
+	// MaxSpoolBytes is the size of the spool file at which appending logs
+	// blocks until batches are acknowledged.
+	MaxSpoolBytes int64
+	// MaxInFlight is the number of batches sent but not yet acknowledged.
+	MaxInFlight int
+	// DrainTimeout is how long Close waits for every batch to be
+	// acknowledged.
+	DrainTimeout time.Duration
+	// ResponseTimeout is how long the API server may take to complete the
+	// handshake or to acknowledge a batch before the connection is dropped.
+	ResponseTimeout time.Duration
+}
+
+func (opts *LogStreamOptions) setDefaults() {
+	if opts.MaxBufferBytes <= 0 {
+		opts.MaxBufferBytes = DefaultLogStreamBufferBytes
+	}
+	if opts.SpoolDir == "" {
+		opts.SpoolDir = os.TempDir()
+	}
+	if opts.MaxSpoolBytes <= 0 {
+		opts.MaxSpoolBytes = DefaultLogStreamSpoolBytes
+	}
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = DefaultLogStreamInFlight
+	}
+	if opts.DrainTimeout <= 0 {
+		opts.DrainTimeout = DefaultLogStreamDrainTimeout
+	}
+	if opts.ResponseTimeout <= 0 {
+		opts.ResponseTimeout = DefaultLogStreamResponseTimeout
+	}
+}
+
+// logBatch is a batch of log messages, compressed, and numbered in the order
+// they were logged.
+type logBatch struct {
+	Seq  int64
+	Data []byte
+}
+
+// LogStream sends a task's log messages to the API server over a websocket.
+// Appending messages never waits on the network: batches are queued in
+// memory, spilled to a spool file on disk once the memory buffer is full, and
+// replayed in order when the connection is re-established. The API server
+// acknowledges each batch once it is stored, and ignores batches it has
+// already stored, so no batch is lost or duplicated across reconnects.
+//
+// If the API server does not support streaming, which it signals by rejecting
+// the websocket with a 400 or 404, the LogStream falls back to posting each
+// batch.
+type LogStream struct {
+	opts LogStreamOptions
+	dial func() (*websocket.Conn, error)
+	post func([]model.LogMessage) error
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// seq is the sequence number of the latest batch, and acked that of the
+	// latest batch the API server has acknowledged.
+	seq   int64
+	acked int64
+	// queue holds batches not yet sent, in order. Once the spool file has
+	// batches in it, new batches are spooled behind them until it is empty.
+	queue      []*logBatch
+	queueBytes int
+	spool      *logSpool
+	inFlight   []*logBatch
+	conn       *websocket.Conn
+	connErr    error
+	closed     bool
+	done       chan struct{}
+}
+
+// StartLogStream makes the communicator send the current task's logs over a
+// LogStream, replacing the stream of any previous task.
+func (h *HTTPCommunicator) StartLogStream(opts LogStreamOptions) error {
+	if h.TaskId == "" {
+		return errors.New("cannot stream logs without a task")
+	}
+	if err := h.StopLogStream(); err != nil {
+		grip.Warning(err)
+	}
+	opts.setDefaults()
+
+	location := "ws" + strings.TrimPrefix(h.ServerURLRoot, "http") + "/" + h.getTaskPath("log_stream")
+	config, err := websocket.NewConfig(location, h.ServerURLRoot)
+	if err != nil {
+		return errors.Wrapf(err, "error configuring log stream to %s", location)
+	}
+	config.Header.Add(evergreen.TaskSecretHeader, h.TaskSecret)
+	config.Header.Add(evergreen.HostHeader, h.HostId)
+	config.Header.Add(evergreen.HostSecretHeader, h.HostSecret)
+	if h.HttpsCert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(h.HttpsCert)) {
+			return errors.New("failed to append HttpsCert to new cert pool")
+		}
+		config.TlsConfig = &tls.Config{RootCAs: pool}
+	}
+
+	ls := newLogStream(opts, func() (*websocket.Conn, error) {
+		return dialLogStream(config, opts.ResponseTimeout)
+	}, h.postLogs)
+
+	h.logStreamMutex.Lock()
+	defer h.logStreamMutex.Unlock()
+	h.logStream = ls
+	return nil
+}
+
+// StopLogStream waits for the current task's logs to be acknowledged, then
+// closes its LogStream. Later logs are posted.
+func (h *HTTPCommunicator) StopLogStream() error {
+	h.logStreamMutex.Lock()
+	ls := h.logStream
+	h.logStream = nil
+	h.logStreamMutex.Unlock()
+
+	if ls == nil {
+		return nil
+	}
+	return ls.Close()
+}
+
+// logStreamStatusError is returned when the API server rejects the websocket
+// handshake with an HTTP status other than 101.
+type logStreamStatusError struct {
+	StatusCode int
+}
+
+func (e *logStreamStatusError) Error() string {
+	return fmt.Sprintf("API server rejected log stream with status %d", e.StatusCode)
+}
+
+// statusLineConn records the start of what is read from the connection, so
+// that the status of a rejected handshake can be reported; the websocket
+// package only reports that the status was not 101.
+type statusLineConn struct {
+	net.Conn
+	head []byte
+}
+
+func (c *statusLineConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if len(c.head) < 64 {
+		c.head = append(c.head, p[:n]...)
+	}
+	return n, err
+}
+
+// statusCode parses the status code from the recorded status line, e.g.
+// "HTTP/1.1 404 Not Found". It returns 0 if there is none.
+func (c *statusLineConn) statusCode() int {
+	line := c.head
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) < 2 {
+		return 0
+	}
+	code, _ := strconv.Atoi(fields[1])
+	return code
+}
+
+// dialLogStream opens the websocket, giving up if connecting takes longer
+// than logStreamDialTimeout or the handshake longer than timeout.
+func dialLogStream(config *websocket.Config, timeout time.Duration) (*websocket.Conn, error) {
+	addr := config.Location.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	dialer := &net.Dialer{Timeout: logStreamDialTimeout}
+	var conn net.Conn
+	var err error
+	if config.Location.Scheme == "wss" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, config.TlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error connecting to %s", addr)
+	}
+
+	recorder := &statusLineConn{Conn: conn}
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err == nil {
+		var ws *websocket.Conn
+		if ws, err = websocket.NewClient(config, recorder); err == nil {
+			if err = conn.SetDeadline(time.Time{}); err == nil {
+				return ws, nil
+			}
+		}
+	}
+	conn.Close()
+	if err == websocket.ErrBadStatus {
+		return nil, &logStreamStatusError{StatusCode: recorder.statusCode()}
+	}
+	return nil, errors.Wrap(err, "error completing log stream handshake")
+}
+
+// streamingUnsupported returns whether the error shows that the API server
+// does not support log streaming at all, rather than that it failed to
+// accept this connection.
+func streamingUnsupported(err error) bool {
+	statusErr, ok := err.(*logStreamStatusError)
+	return ok && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusBadRequest)
+}
+
+func newLogStream(opts LogStreamOptions, dial func() (*websocket.Conn, error),
+	post func([]model.LogMessage) error) *LogStream {
+	opts.setDefaults()
+	ls := &LogStream{
+		opts:  opts,
+		dial:  dial,
+		post:  post,
+		spool: &logSpool{dir: opts.SpoolDir, maxBytes: opts.MaxSpoolBytes},
+		done:  make(chan struct{}),
+	}
+	ls.cond = sync.NewCond(&ls.mu)
+	go ls.run()
+	return ls
+}
+
+// Append queues the messages to be sent. It only blocks if both the memory
+// buffer and the spool file are full.
+func (ls *LogStream) Append(messages []model.LogMessage) error {
+	data, err := compressLogMessages(messages)
+	if err != nil {
+		return err
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	for !ls.closed && ls.spool.full(len(data)) {
+		ls.cond.Wait()
+	}
+	if ls.closed {
+		return errors.New("log stream is closed")
+	}
+
+	ls.seq++
+	batch := &logBatch{Seq: ls.seq, Data: data}
+	defer ls.cond.Broadcast()
+
+	if ls.spool.empty() && ls.queueBytes+len(data) <= ls.opts.MaxBufferBytes {
+		ls.queue = append(ls.queue, batch)
+		ls.queueBytes += len(data)
+		return nil
+	}
+	if err = ls.spool.write(batch); err != nil {
+		// keep the batch in memory rather than lose it, behind the batches
+		// already spooled so that batches are still sent in order
+		grip.Warning(errors.Wrap(err, "error spilling logs to disk"))
+		ls.unspool()
+		ls.queue = append(ls.queue, batch)
+		ls.queueBytes += len(data)
+	}
+	return nil
+}
+
+// unspool moves every batch in the spool file to the end of the memory
+// buffer. It must be called with the lock held.
+func (ls *LogStream) unspool() {
+	for !ls.spool.empty() {
+		batch, err := ls.spool.read()
+		if err != nil {
+			grip.Error(errors.Wrap(err, "error reading spooled logs, discarding them"))
+			grip.Error(ls.spool.discard())
+			return
+		}
+		ls.queue = append(ls.queue, batch)
+		ls.queueBytes += len(batch.Data)
+	}
+}
+
+// Drain waits until every batch has been acknowledged, or the timeout
+// elapses, in which case it returns an error.
+func (ls *LogStream) Drain(timeout time.Duration) error {
+	expired := false
+	timer := time.AfterFunc(timeout, func() {
+		ls.mu.Lock()
+		defer ls.mu.Unlock()
+		expired = true
+		ls.cond.Broadcast()
+	})
+	defer timer.Stop()
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	for !expired && ls.acked < ls.seq {
+		ls.cond.Wait()
+	}
+	if ls.acked < ls.seq {
+		return errors.Errorf("%d log batches were not acknowledged after %s", ls.seq-ls.acked, timeout)
+	}
+	return nil
+}
+
+// Close drains the stream and closes its connection. Batches that were not
+// acknowledged are left in the spool file, if there is one.
+func (ls *LogStream) Close() error {
+	err := ls.Drain(ls.opts.DrainTimeout)
+
+	ls.mu.Lock()
+	ls.closed = true
+	if ls.conn != nil {
+		ls.conn.Close()
+	}
+	ls.cond.Broadcast()
+	ls.mu.Unlock()
+	<-ls.done
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if err != nil && !ls.spool.empty() {
+		return errors.Wrapf(err, "undelivered logs were kept in %s", ls.spool.path())
+	}
+	if removeErr := ls.spool.remove(); err == nil {
+		err = errors.WithStack(removeErr)
+	}
+	return err
+}
+
+// run connects to the API server and sends batches until the stream is
+// closed, reconnecting with backoff whenever the connection fails.
+func (ls *LogStream) run() {
+	defer close(ls.done)
+
+	backoff := logStreamMinBackoff
+	for !ls.isClosed() {
+		conn, err := ls.dial()
+		if err != nil {
+			if streamingUnsupported(err) {
+				grip.Warning("API server does not support log streaming, posting logs instead")
+				ls.postBatches()
+				return
+			}
+			grip.Warning(errors.Wrap(err, "error connecting log stream"))
+		} else {
+			backoff = logStreamMinBackoff
+			err = ls.serve(conn)
+			if ls.isClosed() {
+				return
+			}
+			grip.Warning(errors.Wrap(err, "log stream disconnected"))
+		}
+
+		ls.sleep(backoff)
+		if backoff *= 2; backoff > logStreamMaxBackoff {
+			backoff = logStreamMaxBackoff
+		}
+	}
+}
+
+// serve sends batches over the connection until it fails or the stream is
+// closed. The API server first acknowledges the latest batch it already has,
+// so that batches sent before a reconnect are not stored twice.
+func (ls *LogStream) serve(conn *websocket.Conn) error {
+	defer conn.Close()
+
+	ls.mu.Lock()
+	if ls.closed {
+		ls.mu.Unlock()
+		return errors.New("log stream is closed")
+	}
+	ls.conn = conn
+	ls.connErr = nil
+	ls.mu.Unlock()
+
+	ack := apimodels.LogStreamAck{}
+	if err := conn.SetReadDeadline(time.Now().Add(ls.opts.ResponseTimeout)); err != nil {
+		return errors.Wrap(err, "error setting acknowledgement deadline")
+	}
+	if err := websocket.JSON.Receive(conn, &ack); err != nil {
+		return errors.Wrap(err, "error receiving initial acknowledgement")
+	}
+
+	ls.mu.Lock()
+	// resend whatever was in flight when the last connection failed
+	for _, batch := range ls.inFlight {
+		ls.queueBytes += len(batch.Data)
+	}
+	ls.queue = append(ls.inFlight, ls.queue...)
+	ls.inFlight = nil
+	ls.acknowledge(ack.Seq)
+	ls.mu.Unlock()
+
+	go func() {
+		for {
+			ack := apimodels.LogStreamAck{}
+			if err := conn.SetReadDeadline(time.Now().Add(ls.opts.ResponseTimeout)); err != nil {
+				ls.setConnErr(errors.Wrap(err, "error setting acknowledgement deadline"))
+				return
+			}
+			if err := websocket.JSON.Receive(conn, &ack); err != nil {
+				// the server has nothing to acknowledge while the task is
+				// quiet, so only a timeout with batches in flight is a failure
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() && !ls.awaitingAck() {
+					continue
+				}
+				ls.setConnErr(errors.Wrap(err, "error receiving acknowledgement"))
+				return
+			}
+			ls.mu.Lock()
+			ls.acknowledge(ack.Seq)
+			ls.mu.Unlock()
+		}
+	}()
+
+	for {
+		batch, err := ls.next(true)
+		if err != nil {
+			return err
+		}
+		if err = conn.SetWriteDeadline(time.Now().Add(ls.opts.ResponseTimeout)); err == nil {
+			err = websocket.Message.Send(conn, apimodels.EncodeLogBatch(batch.Seq, batch.Data))
+		}
+		if err != nil {
+			ls.setConnErr(errors.Wrap(err, "error sending logs"))
+		}
+	}
+}
+
+// postBatches posts each batch in turn, for API servers that do not support
+// streaming.
+func (ls *LogStream) postBatches() {
+	ls.mu.Lock()
+	ls.connErr = nil
+	ls.mu.Unlock()
+
+	for {
+		batch, err := ls.next(false)
+		if err != nil {
+			return
+		}
+		messages, err := decompressLogMessages(batch.Data)
+		if err == nil {
+			err = ls.post(messages)
+		}
+		grip.Error(errors.Wrapf(err, "error posting log batch %d", batch.Seq))
+
+		ls.mu.Lock()
+		ls.acknowledge(batch.Seq)
+		ls.mu.Unlock()
+	}
+}
+
+// next returns the next batch to send, waiting until there is one. If
+// windowed, it also waits until fewer than MaxInFlight batches are
+// unacknowledged. It returns an error once the connection fails or the
+// stream is closed.
+func (ls *LogStream) next(windowed bool) (*logBatch, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	for {
+		if ls.connErr != nil {
+			return nil, ls.connErr
+		}
+		if ls.closed {
+			return nil, errors.New("log stream is closed")
+		}
+		if !windowed || len(ls.inFlight) < ls.opts.MaxInFlight {
+			batch, err := ls.dequeue()
+			if err != nil {
+				return nil, err
+			}
+			if batch != nil {
+				ls.inFlight = append(ls.inFlight, batch)
+				return batch, nil
+			}
+		}
+		ls.cond.Wait()
+	}
+}
+
+// dequeue removes the oldest unacknowledged batch from memory, or from the
+// spool file if memory is empty. It returns nil if there are no batches. It
+// must be called with the lock held.
+func (ls *LogStream) dequeue() (*logBatch, error) {
+	for len(ls.queue) > 0 {
+		batch := ls.queue[0]
+		ls.queue = ls.queue[1:]
+		ls.queueBytes -= len(batch.Data)
+		if batch.Seq > ls.acked {
+			return batch, nil
+		}
+	}
+	for !ls.spool.empty() {
+		batch, err := ls.spool.read()
+		if err != nil {
+			// the rest of the spool file can't be trusted
+			grip.Error(errors.Wrap(err, "error reading spooled logs, discarding them"))
+			return nil, ls.spool.discard()
+		}
+		ls.cond.Broadcast()
+		if batch.Seq > ls.acked {
+			return batch, nil
+		}
+	}
+	return nil, nil
+}
+
+// acknowledge records that the API server has stored every batch up to seq.
+// It must be called with the lock held.
+func (ls *LogStream) acknowledge(seq int64) {
+	if seq > ls.acked {
+		ls.acked = seq
+	}
+	i := 0
+	for i < len(ls.inFlight) && ls.inFlight[i].Seq <= ls.acked {
+		i++
+	}
+	ls.inFlight = ls.inFlight[i:]
+	ls.cond.Broadcast()
+}
+
+func (ls *LogStream) setConnErr(err error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.connErr == nil {
+		ls.connErr = err
+	}
+	ls.cond.Broadcast()
+}
+
+// awaitingAck returns whether batches have been sent that the API server has
+// not acknowledged.
+func (ls *LogStream) awaitingAck() bool {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return len(ls.inFlight) > 0
+}
+
+func (ls *LogStream) isClosed() bool {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.closed
+}
+
+// sleep waits for the duration, or until the stream is closed.
+func (ls *LogStream) sleep(d time.Duration) {
+	deadline := time.Now().Add(d)
+	for !ls.isClosed() && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func compressLogMessages(messages []model.LogMessage) ([]byte, error) {
+	return apimodels.CompressLogBatch(messages)
+}
+
+func decompressLogMessages(data []byte) ([]model.LogMessage, error) {
+	messages := []model.LogMessage{}
+	if err := apimodels.DecompressLogBatch(data, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// logSpool is a file that batches are appended to and read back from in
+// order. The file is created when the first batch is written, and truncated
+// whenever every batch in it has been read.
+type logSpool struct {
+	dir      string
+	maxBytes int64
+	file     *os.File
+	readAt   int64
+	writeAt  int64
+}
+
+func (s *logSpool) empty() bool {
+	return s.readAt == s.writeAt
+}
+
+// full returns true if a batch of n bytes would not fit. A batch always fits
+// in an empty spool.
+func (s *logSpool) full(n int) bool {
+	return !s.empty() && s.writeAt+spoolRecordHeader+int64(n) > s.maxBytes
+}
+
+func (s *logSpool) path() string {
+	if s.file == nil {
+		return ""
+	}
+	return s.file.Name()
+}
+
+func (s *logSpool) write(batch *logBatch) error {
+	if s.file == nil {
+		f, err := ioutil.TempFile(s.dir, "evergreen-log-spool-")
+		if err != nil {
+			return errors.Wrap(err, "error creating log spool file")
+		}
+		s.file = f
+	}
+
+	record := make([]byte, spoolRecordHeader+len(batch.Data))
+	binary.BigEndian.PutUint64(record, uint64(batch.Seq))
+	binary.BigEndian.PutUint32(record[apimodels.LogBatchHeaderSize:], uint32(len(batch.Data)))
+	copy(record[spoolRecordHeader:], batch.Data)
+	if _, err := s.file.WriteAt(record, s.writeAt); err != nil {
+		return errors.Wrapf(err, "error writing to %s", s.file.Name())
+	}
+	s.writeAt += int64(len(record))
+	return nil
+}
+
+func (s *logSpool) read() (*logBatch, error) {
+	header := make([]byte, spoolRecordHeader)
+	if _, err := s.file.ReadAt(header, s.readAt); err != nil {
+		return nil, errors.Wrapf(err, "error reading from %s", s.file.Name())
+	}
+	batch := &logBatch{
+		Seq:  int64(binary.BigEndian.Uint64(header)),
+		Data: make([]byte, binary.BigEndian.Uint32(header[apimodels.LogBatchHeaderSize:])),
+	}
+	if _, err := s.file.ReadAt(batch.Data, s.readAt+spoolRecordHeader); err != nil {
+		return nil, errors.Wrapf(err, "error reading from %s", s.file.Name())
+	}
+	s.readAt += spoolRecordHeader + int64(len(batch.Data))
+
+	if s.empty() {
+		// the batch was read, so a failure to reclaim the space is not
+		// worth losing it over
+		grip.Warning(s.discard())
+	}
+	return batch, nil
+}
+
+// discard drops every batch not yet read.
+func (s *logSpool) discard() error {
+	s.readAt, s.writeAt = 0, 0
+	if s.file == nil {
+		return nil
+	}
+	return errors.Wrapf(s.file.Truncate(0), "error truncating %s", s.file.Name())
+}
+
+// remove closes and deletes the spool file.
+func (s *logSpool) remove() error {
+	if s.file == nil {
+		return nil
+	}
+	name := s.file.Name()
+	s.file.Close()
+	s.file = nil
+	return os.Remove(name)
+}
@@ -0,0 +1,303 @@
+package comm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/websocket"
+)
+
+// logStreamServer stores streamed log batches in memory, the way the API
+// server stores them in the database.
+type logStreamServer struct {
+	mu       sync.Mutex
+	lastSeq  int64
+	messages []string
+	// dropAfterStore, if set, makes the server close the connection after
+	// storing the next batch, without acknowledging it.
+	dropAfterStore bool
+	down           bool
+	server         *httptest.Server
+}
+
+func newLogStreamServer() *logStreamServer {
+	s := &logStreamServer{}
+	s.server = httptest.NewServer(websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   s.handle,
+	})
+	return s
+}
+
+func (s *logStreamServer) handle(ws *websocket.Conn) {
+	s.mu.Lock()
+	lastSeq := s.lastSeq
+	s.mu.Unlock()
+	if websocket.JSON.Send(ws, apimodels.LogStreamAck{Seq: lastSeq}) != nil {
+		return
+	}
+
+	for {
+		var frame []byte
+		if websocket.Message.Receive(ws, &frame) != nil {
+			return
+		}
+		seq, data, err := apimodels.DecodeLogBatch(frame)
+		if err != nil {
+			return
+		}
+		messages, err := decompressLogMessages(data)
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if seq > s.lastSeq {
+			for _, msg := range messages {
+				s.messages = append(s.messages, msg.Message)
+			}
+			s.lastSeq = seq
+		}
+		lastSeq = s.lastSeq
+		drop := s.dropAfterStore
+		s.dropAfterStore = false
+		s.mu.Unlock()
+
+		if drop {
+			return
+		}
+		if websocket.JSON.Send(ws, apimodels.LogStreamAck{Seq: lastSeq}) != nil {
+			return
+		}
+	}
+}
+
+func (s *logStreamServer) dial() (*websocket.Conn, error) {
+	s.mu.Lock()
+	down := s.down
+	s.mu.Unlock()
+	if down {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return websocket.Dial("ws"+strings.TrimPrefix(s.server.URL, "http"), "", s.server.URL)
+}
+
+// dialer returns a function that dials the log stream of the server at url.
+func dialer(url string, timeout time.Duration) func() (*websocket.Conn, error) {
+	return func() (*websocket.Conn, error) {
+		config, err := websocket.NewConfig("ws"+strings.TrimPrefix(url, "http"), url)
+		if err != nil {
+			return nil, err
+		}
+		return dialLogStream(config, timeout)
+	}
+}
+
+func (s *logStreamServer) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func (s *logStreamServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.messages...)
+}
+
+func logMessages(start, count int) []model.LogMessage {
+	messages := []model.LogMessage{}
+	for i := start; i < start+count; i++ {
+		messages = append(messages, model.LogMessage{Message: fmt.Sprintf("line %d", i)})
+	}
+	return messages
+}
+
+func expectedLines(count int) []string {
+	lines := []string{}
+	for i := 0; i < count; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	return lines
+}
+
+func TestLogStream(t *testing.T) {
+	Convey("With a log stream to a server", t, func() {
+		server := newLogStreamServer()
+		defer server.server.Close()
+		spoolDir, err := ioutil.TempDir("", "log-stream")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(spoolDir)
+
+		opts := LogStreamOptions{SpoolDir: spoolDir, MaxInFlight: 2, DrainTimeout: 10 * time.Second}
+		posted := []model.LogMessage{}
+		post := func(messages []model.LogMessage) error {
+			posted = append(posted, messages...)
+			return nil
+		}
+
+		Convey("every batch should be delivered in order", func() {
+			ls := newLogStream(opts, server.dial, post)
+			for i := 0; i < 50; i += 5 {
+				So(ls.Append(logMessages(i, 5)), ShouldBeNil)
+			}
+			So(ls.Close(), ShouldBeNil)
+			So(server.received(), ShouldResemble, expectedLines(50))
+			So(posted, ShouldBeEmpty)
+		})
+
+		Convey("batches should be spilled to disk while the server is down and replayed once it is back", func() {
+			server.setDown(true)
+			opts.MaxBufferBytes = 1
+			ls := newLogStream(opts, server.dial, post)
+			for i := 0; i < 20; i += 2 {
+				So(ls.Append(logMessages(i, 2)), ShouldBeNil)
+			}
+			ls.mu.Lock()
+			So(ls.spool.empty(), ShouldBeFalse)
+			spoolPath := ls.spool.path()
+			ls.mu.Unlock()
+			So(ls.Drain(100*time.Millisecond), ShouldNotBeNil)
+
+			server.setDown(false)
+			So(ls.Close(), ShouldBeNil)
+			So(server.received(), ShouldResemble, expectedLines(20))
+			_, err = os.Stat(spoolPath)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+
+		Convey("batches kept in memory because the spool failed should be sent after the spooled ones", func() {
+			server.setDown(true)
+			opts.MaxBufferBytes = 1
+			ls := newLogStream(opts, server.dial, post)
+			for i := 0; i < 10; i += 2 {
+				So(ls.Append(logMessages(i, 2)), ShouldBeNil)
+			}
+
+			// make later writes to the spool fail, while it can still be read
+			ls.mu.Lock()
+			So(ls.spool.empty(), ShouldBeFalse)
+			readOnly, err := os.Open(ls.spool.path())
+			So(err, ShouldBeNil)
+			ls.spool.file.Close()
+			ls.spool.file = readOnly
+			ls.mu.Unlock()
+
+			for i := 10; i < 20; i += 2 {
+				So(ls.Append(logMessages(i, 2)), ShouldBeNil)
+			}
+			server.setDown(false)
+			So(ls.Close(), ShouldBeNil)
+			So(server.received(), ShouldResemble, expectedLines(20))
+		})
+
+		Convey("a batch stored before the connection dropped should not be stored twice", func() {
+			server.dropAfterStore = true
+			ls := newLogStream(opts, server.dial, post)
+			for i := 0; i < 10; i++ {
+				So(ls.Append(logMessages(i, 1)), ShouldBeNil)
+			}
+			So(ls.Close(), ShouldBeNil)
+			So(server.received(), ShouldResemble, expectedLines(10))
+		})
+
+		Convey("logs should be posted if the server does not support streaming", func() {
+			notFound := httptest.NewServer(http.NotFoundHandler())
+			defer notFound.Close()
+			ls := newLogStream(opts, dialer(notFound.URL, time.Second), post)
+			So(ls.Append(logMessages(0, 3)), ShouldBeNil)
+			So(ls.Close(), ShouldBeNil)
+			So(len(posted), ShouldEqual, 3)
+			So(posted[2].Message, ShouldEqual, "line 2")
+		})
+
+		Convey("the stream should be retried if the server fails to accept it", func() {
+			attempts := 0
+			flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts == 1 {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				server.server.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer flaky.Close()
+			ls := newLogStream(opts, dialer(flaky.URL, time.Second), post)
+			So(ls.Append(logMessages(0, 3)), ShouldBeNil)
+			So(ls.Close(), ShouldBeNil)
+			So(posted, ShouldBeEmpty)
+			So(server.received(), ShouldResemble, expectedLines(3))
+		})
+
+		Convey("closing should not hang on a server that never responds", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer listener.Close()
+			go func() {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+				}
+			}()
+
+			opts.DrainTimeout = 100 * time.Millisecond
+			ls := newLogStream(opts, dialer("http://"+listener.Addr().String(), 100*time.Millisecond), post)
+			So(ls.Append(logMessages(0, 1)), ShouldBeNil)
+			start := time.Now()
+			So(ls.Close(), ShouldNotBeNil)
+			So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+		})
+	})
+}
+
+func TestLogSpool(t *testing.T) {
+	Convey("With a log spool", t, func() {
+		dir, err := ioutil.TempDir("", "log-spool")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		spool := &logSpool{dir: dir, maxBytes: 100}
+
+		Convey("batches should be read back in the order they were written", func() {
+			So(spool.empty(), ShouldBeTrue)
+			So(spool.write(&logBatch{Seq: 1, Data: []byte("one")}), ShouldBeNil)
+			So(spool.write(&logBatch{Seq: 2, Data: []byte("two")}), ShouldBeNil)
+
+			batch, err := spool.read()
+			So(err, ShouldBeNil)
+			So(batch.Seq, ShouldEqual, 1)
+			So(string(batch.Data), ShouldEqual, "one")
+			batch, err = spool.read()
+			So(err, ShouldBeNil)
+			So(batch.Seq, ShouldEqual, 2)
+			So(string(batch.Data), ShouldEqual, "two")
+
+			So(spool.empty(), ShouldBeTrue)
+			info, err := os.Stat(spool.path())
+			So(err, ShouldBeNil)
+			So(info.Size(), ShouldEqual, 0)
+			So(spool.remove(), ShouldBeNil)
+		})
+
+		Convey("the spool should be full once a batch would exceed its size", func() {
+			So(spool.full(1000), ShouldBeFalse)
+			So(spool.write(&logBatch{Seq: 1, Data: make([]byte, 70)}), ShouldBeNil)
+			So(spool.full(6), ShouldBeFalse)
+			So(spool.full(7), ShouldBeTrue)
+			So(spool.remove(), ShouldBeNil)
+		})
+	})
+}
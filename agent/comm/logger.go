@@ -1,8 +1,10 @@
 package comm
 
 import (
+	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -35,6 +37,18 @@ type StreamLogger struct {
 
 	// apiLogger is used to send data back to the API server.
 	apiLogger APILogger
+
+	// redactor removes private variables from every message logged.
+	redactor *redactor
+}
+
+// RedactPrivateVars makes the logger replace the values of the given private
+// variables with their names in every message it logs from now on, so that
+// secrets can't leak into the task's logs.
+func (lgr *StreamLogger) RedactPrivateVars(vars map[string]string) {
+	if lgr.redactor != nil {
+		lgr.redactor.set(vars)
+	}
 }
 
 // GetTaskLogWriter returns an io.Writer of the given level that writes to the task log stream.
@@ -155,13 +169,16 @@ func (cmdLgr *CommandLogger) Flush() {
 // NewStreamLogger creates a StreamLogger wrapper for the apiLogger with a given timeoutWatcher.
 // Any logged messages on the StreamLogger will reset the TimeoutWatcher.
 func NewStreamLogger(timeoutWatcher *TimeoutWatcher, apiLgr *APILogger) (*StreamLogger, error) {
-	defaultLoggers := []send.Sender{slogger.WrapAppender(apiLgr), grip.GetSender()}
-	timeoutLogger := slogger.WrapAppender(&TimeoutResetLogger{timeoutWatcher, apiLgr})
+	redactor := &redactor{}
+	localLogger := &redactingSender{grip.GetSender(), redactor}
+	defaultLoggers := []send.Sender{&redactingSender{slogger.WrapAppender(apiLgr), redactor}, localLogger}
+	timeoutLogger := &redactingSender{slogger.WrapAppender(&TimeoutResetLogger{timeoutWatcher, apiLgr}), redactor}
 
 	return &StreamLogger{
+		redactor: redactor,
 		Local: &slogger.Logger{
 			Name:      "local",
-			Appenders: []send.Sender{grip.GetSender()},
+			Appenders: []send.Sender{localLogger},
 		},
 
 		System: &slogger.Logger{
@@ -171,7 +188,7 @@ func NewStreamLogger(timeoutWatcher *TimeoutWatcher, apiLgr *APILogger) (*Stream
 
 		Task: &slogger.Logger{
 			Name:      model.TaskLogPrefix,
-			Appenders: []send.Sender{timeoutLogger, grip.GetSender()},
+			Appenders: []send.Sender{timeoutLogger, localLogger},
 		},
 
 		Execution: &slogger.Logger{
@@ -181,6 +198,79 @@ func NewStreamLogger(timeoutWatcher *TimeoutWatcher, apiLgr *APILogger) (*Stream
 	}, nil
 }
 
+// redactor replaces the values of private variables in log messages.
+type redactor struct {
+	mu       sync.RWMutex
+	replacer *strings.Replacer
+}
+
+func (r *redactor) set(vars map[string]string) {
+	names := privateVarsByLength{vars: vars}
+	for name, value := range vars {
+		if value != "" {
+			names.names = append(names.names, name)
+		}
+	}
+	// replace longer values first, so that a value containing another
+	// isn't left partly visible
+	sort.Sort(names)
+
+	pairs := make([]string, 0, 2*len(names.names))
+	for _, name := range names.names {
+		pairs = append(pairs, vars[name], fmt.Sprintf("<REDACTED:%s>", name))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replacer = nil
+	if len(pairs) > 0 {
+		r.replacer = strings.NewReplacer(pairs...)
+	}
+}
+
+func (r *redactor) redact(msg string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.replacer == nil {
+		return msg
+	}
+	return r.replacer.Replace(msg)
+}
+
+// privateVarsByLength sorts variable names by the length of their values,
+// longest first.
+type privateVarsByLength struct {
+	names []string
+	vars  map[string]string
+}
+
+func (p privateVarsByLength) Len() int      { return len(p.names) }
+func (p privateVarsByLength) Swap(i, j int) { p.names[i], p.names[j] = p.names[j], p.names[i] }
+func (p privateVarsByLength) Less(i, j int) bool {
+	return len(p.vars[p.names[i]]) > len(p.vars[p.names[j]])
+}
+
+// redactingSender wraps a send.Sender, redacting the messages sent to it.
+type redactingSender struct {
+	send.Sender
+	redactor *redactor
+}
+
+func (s *redactingSender) Send(m message.Composer) {
+	if log, ok := m.(*slogger.Log); ok {
+		if redacted := s.redactor.redact(log.Message()); redacted != log.Message() {
+			redactedLog := slogger.NewPrefixedLog(log.Prefix, message.NewDefaultMessage(log.Priority(), redacted))
+			redactedLog.Timestamp = log.Timestamp
+			redactedLog.Filename = log.Filename
+			redactedLog.Line = log.Line
+			m = redactedLog
+		}
+	} else if redacted := s.redactor.redact(m.String()); redacted != m.String() {
+		m = message.NewDefaultMessage(m.Priority(), redacted)
+	}
+	s.Sender.Send(m)
+}
+
 // TimeoutResetLogger wraps any slogger.Appender and resets a TimeoutWatcher
 // each time any log message is appended to it.
 type TimeoutResetLogger struct {
@@ -202,6 +292,10 @@ type APILogger struct {
 	// An internal buffer of messages to send.
 	messages []model.LogMessage
 
+	// tail holds the most recent messages, whether or not they have been
+	// sent, for the agent's status server.
+	tail []model.LogMessage
+
 	// a mutex to ensure only one flush attempt is in progress at a time.
 	flushLock sync.Mutex
 
@@ -241,6 +335,23 @@ func NewAPILogger(tc TaskCommunicator) *APILogger {
 	}
 }
 
+// DefaultLogTailLines is the number of recent log messages an APILogger keeps
+// for the agent's status server.
+const DefaultLogTailLines = 100
+
+// Tail returns up to the n most recent messages appended to the logger, oldest
+// first. If n is not positive, every message kept is returned.
+func (apiLgr *APILogger) Tail(n int) []model.LogMessage {
+	apiLgr.appendLock.Lock()
+	defer apiLgr.appendLock.Unlock()
+
+	tail := apiLgr.tail
+	if n > 0 && n < len(tail) {
+		tail = tail[len(tail)-n:]
+	}
+	return append([]model.LogMessage{}, tail...)
+}
+
 // Append (to satisfy the Appender interface) adds a log message to the internal
 // buffer, and translates the log message into a format that is used by the
 // remote endpoint.
@@ -264,6 +375,10 @@ func (apiLgr *APILogger) Append(log *slogger.Log) error {
 	apiLgr.appendLock.Lock()
 	defer apiLgr.appendLock.Unlock()
 	apiLgr.messages = append(apiLgr.messages, *logMessage)
+	apiLgr.tail = append(apiLgr.tail, *logMessage)
+	if len(apiLgr.tail) > DefaultLogTailLines {
+		apiLgr.tail = apiLgr.tail[len(apiLgr.tail)-DefaultLogTailLines:]
+	}
 
 	if len(apiLgr.messages) < apiLgr.SendAfterLines ||
 		time.Since(apiLgr.lastFlush) < apiLgr.SendAfterDuration {
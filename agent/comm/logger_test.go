@@ -1,6 +1,7 @@
 package comm
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -56,6 +57,20 @@ func TestLogging(t *testing.T) {
 			So(len(receivedMsgs), ShouldEqual, 1)
 		})
 
+		Convey("the tail should keep the most recent messages, whether or not they were flushed", func() {
+			for i := 0; i < DefaultLogTailLines+10; i++ {
+				testLogger.Logf(slogger.INFO, "test %v", i)
+			}
+			tail := apiLogger.Tail(0)
+			So(len(tail), ShouldEqual, DefaultLogTailLines)
+			So(tail[0].Message, ShouldEqual, "test 10")
+			So(tail[len(tail)-1].Message, ShouldEqual, fmt.Sprintf("test %v", DefaultLogTailLines+9))
+
+			tail = apiLogger.Tail(2)
+			So(len(tail), ShouldEqual, 2)
+			So(tail[1].Message, ShouldEqual, fmt.Sprintf("test %v", DefaultLogTailLines+9))
+		})
+
 		Convey("Calling flush() when empty should not send anything", func() {
 			apiLogger.Flush()
 			time.Sleep(10 * time.Millisecond)
@@ -101,3 +116,41 @@ func TestCommandLogger(t *testing.T) {
 
 	})
 }
+
+func TestRedactPrivateVars(t *testing.T) {
+	Convey("With a stream logger redacting private variables", t, func() {
+		taskCommunicator := &MockCommunicator{
+			LogChan: make(chan []model.LogMessage, 100),
+		}
+		apiLogger := NewAPILogger(taskCommunicator)
+		logger, err := NewStreamLogger(NewTimeoutWatcher(make(chan struct{})), apiLogger)
+		So(err, ShouldBeNil)
+		logger.RedactPrivateVars(map[string]string{
+			"password":     "hunter2",
+			"long_secret":  "hunter2hunter2",
+			"empty_secret": "",
+		})
+
+		Convey("messages should be redacted", func() {
+			logger.LogTask(slogger.INFO, "logging in with %v", "hunter2")
+			logger.LogExecution(slogger.INFO, "key is hunter2hunter2")
+			tail := apiLogger.Tail(0)
+			So(len(tail), ShouldEqual, 2)
+			So(tail[0].Message, ShouldEqual, "logging in with <REDACTED:password>")
+			So(tail[1].Message, ShouldEqual, "key is <REDACTED:long_secret>")
+		})
+
+		Convey("output written to the log writers should be redacted", func() {
+			_, err := logger.GetTaskLogWriter(slogger.INFO).Write([]byte("echo hunter2\n"))
+			So(err, ShouldBeNil)
+			tail := apiLogger.Tail(0)
+			So(len(tail), ShouldEqual, 1)
+			So(tail[0].Message, ShouldEqual, "echo <REDACTED:password>")
+		})
+
+		Convey("messages without secrets should be unchanged", func() {
+			logger.LogTask(slogger.INFO, "nothing to see here")
+			So(apiLogger.Tail(0)[0].Message, ShouldEqual, "nothing to see here")
+		})
+	})
+}
@@ -114,6 +114,14 @@ func (*MockCommunicator) GetNextTask() (*apimodels.NextTaskResponse, error) {
 	return &apimodels.NextTaskResponse{}, nil
 }
 
+func (*MockCommunicator) ReportUnhealthy([]string) error {
+	return nil
+}
+
+func (*MockCommunicator) Register() error {
+	return nil
+}
+
 func (mc *MockCommunicator) setAbort(b bool) {
 	mc.Lock()
 	defer mc.Unlock()
@@ -160,6 +168,10 @@ func (*MockCommunicator) FetchExpansionVars() (*apimodels.ExpansionVars, error)
 	return &apimodels.ExpansionVars{}, nil
 }
 
+func (*MockCommunicator) FetchPrivateVars() ([]string, error) {
+	return []string{}, nil
+}
+
 func (m *MockCommunicator) SetSignalChan(chan Signal) {
 	return
 }
@@ -23,4 +23,7 @@ const (
 	// Directory Failure indicates that the task failed due to a problem for the agent
 	// creating or moving into a new directory.
 	DirectoryFailure
+	// AbortedOnHost indicates someone on the host asked the agent, through
+	// its status server, to prematurely end the task.
+	AbortedOnHost
 )
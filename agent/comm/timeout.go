@@ -7,10 +7,11 @@ import (
 
 // TimeoutWatcher tracks and handles command timeout within the agent.
 type TimeoutWatcher struct {
-	duration time.Duration
-	timer    *time.Timer
-	stop     <-chan struct{}
-	disabled bool
+	duration  time.Duration
+	timer     *time.Timer
+	stop      <-chan struct{}
+	disabled  bool
+	lastReset time.Time
 
 	mutex sync.Mutex
 }
@@ -36,9 +37,33 @@ func (tw *TimeoutWatcher) CheckIn() {
 
 	if tw.timer != nil && !tw.disabled {
 		tw.timer.Reset(tw.duration)
+		tw.lastReset = time.Now()
 	}
 }
 
+// TimeoutState describes how close a TimeoutWatcher is to timing out.
+type TimeoutState struct {
+	// Duration is the timeout.
+	Duration time.Duration
+	// Elapsed is the time since the timer was started or last reset. It is
+	// zero if the timer has not been started.
+	Elapsed time.Duration
+	// Started is false until the watcher is waiting for timeouts.
+	Started bool
+}
+
+// State returns the watcher's current state.
+func (tw *TimeoutWatcher) State() TimeoutState {
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+
+	state := TimeoutState{Duration: tw.duration, Started: tw.timer != nil}
+	if state.Started {
+		state.Elapsed = time.Since(tw.lastReset)
+	}
+	return state
+}
+
 // NotifyTimeouts sends a signal on sigChan whenever the timeout threshold of
 // the current execution stage is reached.
 func (tw *TimeoutWatcher) NotifyTimeouts(sigChan chan<- Signal) {
@@ -50,6 +75,7 @@ func (tw *TimeoutWatcher) NotifyTimeouts(sigChan chan<- Signal) {
 				panic("can't wait for timeouts with negative duration")
 			}
 			tw.timer = time.NewTimer(tw.duration)
+			tw.lastReset = time.Now()
 		}
 		tw.mutex.Unlock()
 
@@ -27,5 +27,21 @@ func TestTimeoutWatcher(t *testing.T) {
 			So(outSignal, ShouldEqual, IdleTimeout)
 			So(ended, ShouldNotHappenWithin, 3900*time.Millisecond, started)
 		})
+
+		Convey("the state should report how long it has been since the last check in", func() {
+			stop := make(chan struct{})
+			tw.stop = stop
+			So(tw.State(), ShouldResemble, TimeoutState{Duration: time.Second})
+
+			tw.NotifyTimeouts(signalChan)
+			time.Sleep(200 * time.Millisecond)
+			state := tw.State()
+			So(state.Started, ShouldBeTrue)
+			So(state.Elapsed, ShouldBeGreaterThanOrEqualTo, 200*time.Millisecond)
+
+			tw.CheckIn()
+			So(tw.State().Elapsed, ShouldBeLessThan, 200*time.Millisecond)
+			close(stop)
+		})
 	})
 }
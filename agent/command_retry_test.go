@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/agent/comm"
+	"github.com/evergreen-ci/evergreen/agent/testutil"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/mongodb/grip/send"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// flakyCommand is a command that fails a set number of times before it
+// succeeds.
+type flakyCommand struct {
+	failures int
+	runs     int
+}
+
+func (c *flakyCommand) ParseParams(map[string]interface{}) error { return nil }
+func (c *flakyCommand) Name() string                             { return "flaky" }
+func (c *flakyCommand) Plugin() string                           { return "test" }
+
+func (c *flakyCommand) Execute(plugin.Logger, plugin.PluginCommunicator, *model.TaskConfig, chan bool) error {
+	c.runs++
+	if c.runs <= c.failures {
+		return errors.Errorf("failure %d", c.runs)
+	}
+	return nil
+}
+
+func TestExecuteCommandRetries(t *testing.T) {
+	Convey("With an agent running a command that fails twice", t, func() {
+		logger := testutil.NewTestLogger(send.MakeInternalLogger())
+		agt := &Agent{
+			logger:             logger,
+			idleTimeoutWatcher: comm.NewTimeoutWatcher(make(chan struct{})),
+			taskConfig:         &model.TaskConfig{},
+		}
+		cmd := &flakyCommand{failures: 2}
+		stop := make(chan bool)
+
+		Convey("the command should not be retried by default", func() {
+			err := agt.executeCommand(cmd, agt.taskConfig, model.PluginCommandConf{}, logger, nil, DefaultCmdTimeout, stop)
+			So(err, ShouldNotBeNil)
+			So(cmd.runs, ShouldEqual, 1)
+		})
+
+		Convey("the command should succeed if it is retried enough times", func() {
+			conf := model.PluginCommandConf{RetryOnFailure: 2, RetryBackoffSecs: 1}
+			err := agt.executeCommand(cmd, agt.taskConfig, conf, logger, nil, DefaultCmdTimeout, stop)
+			So(err, ShouldBeNil)
+			So(cmd.runs, ShouldEqual, 3)
+			So(agt.GetCurrentCommand().RetryOnFailure, ShouldEqual, 2)
+		})
+
+		Convey("the command should fail if it runs out of retries", func() {
+			conf := model.PluginCommandConf{RetryOnFailure: 1, RetryBackoffSecs: 1}
+			err := agt.executeCommand(cmd, agt.taskConfig, conf, logger, nil, DefaultCmdTimeout, stop)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "failure 2")
+			So(cmd.runs, ShouldEqual, 2)
+		})
+
+		Convey("the command should not be retried once the task is stopped", func() {
+			close(stop)
+			conf := model.PluginCommandConf{RetryOnFailure: 2, RetryBackoffSecs: 1}
+			err := agt.executeCommand(cmd, agt.taskConfig, conf, logger, nil, DefaultCmdTimeout, stop)
+			So(err, ShouldNotBeNil)
+			So(cmd.runs, ShouldEqual, 1)
+		})
+
+		Convey("the command should stop waiting to be retried once the task is stopped", func() {
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				close(stop)
+			}()
+			start := time.Now()
+			conf := model.PluginCommandConf{RetryOnFailure: 2, RetryBackoffSecs: 60}
+			err := agt.executeCommand(cmd, agt.taskConfig, conf, logger, nil, DefaultCmdTimeout, stop)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "failure 1")
+			So(cmd.runs, ShouldEqual, 1)
+			So(time.Since(start), ShouldBeLessThan, 10*time.Second)
+		})
+	})
+}
@@ -0,0 +1,11 @@
+// +build !linux,!darwin,!freebsd,!windows
+
+package agent
+
+import "github.com/pkg/errors"
+
+// getDiskSpace is not supported on this platform, so the agent skips its disk
+// space checks.
+func getDiskSpace(path string) (*diskSpace, error) {
+	return nil, errors.New("checking disk space is not supported on this platform")
+}
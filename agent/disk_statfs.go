@@ -0,0 +1,23 @@
+// +build linux darwin freebsd
+
+package agent
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// getDiskSpace returns the free space and inodes available to the agent on
+// the filesystem that holds path.
+func getDiskSpace(path string) (*diskSpace, error) {
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, errors.Wrapf(err, "error getting filesystem stats for %s", path)
+	}
+	return &diskSpace{
+		FreeBytes:  uint64(stat.Bavail) * uint64(stat.Bsize),
+		FreeInodes: uint64(stat.Ffree),
+		HasInodes:  stat.Files > 0,
+	}, nil
+}
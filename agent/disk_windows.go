@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+var procGetDiskFreeSpaceExW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// getDiskSpace returns the free space available to the agent on the volume
+// that holds path. NTFS has no fixed inode limit, so inodes are not reported.
+func getDiskSpace(path string) (*diskSpace, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var freeBytes uint64
+	r1, _, err := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&freeBytes)), 0, 0)
+	if r1 == 0 {
+		return nil, errors.Wrapf(err, "error getting free disk space for %s", path)
+	}
+	return &diskSpace{FreeBytes: freeBytes}, nil
+}
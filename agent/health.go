@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/evergreen-ci/evergreen/plugin/builtin/shell"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMinFreeDiskMB is the free space, in megabytes, the agent requires
+	// in its working directory before it accepts a task.
+	DefaultMinFreeDiskMB = 1024
+
+	// DefaultMinFreeInodes is the number of free inodes the agent requires in
+	// its working directory before it accepts a task.
+	DefaultMinFreeInodes = 10000
+)
+
+// taskDirName matches the names of the directories createTaskDirectory makes.
+var taskDirName = regexp.MustCompile("^[0-9a-f]{32}$")
+
+// HealthCheckOptions configures the checks the agent makes before it accepts a
+// task, and the cleanup it runs if they fail.
+type HealthCheckOptions struct {
+	// WorkDir is the distro's working directory, which task directories are
+	// created in. If it is empty, the agent's working directory is used.
+	WorkDir string
+
+	// MinFreeDiskMB and MinFreeInodes are the free space and inodes required
+	// in WorkDir. Zero disables the check.
+	MinFreeDiskMB int
+	MinFreeInodes int
+
+	// RemoveStaleTaskDirs enables removing the task directories in WorkDir
+	// left behind by earlier tasks.
+	RemoveStaleTaskDirs bool
+
+	// KillOrphanedProcs enables killing the processes left running by
+	// earlier tasks.
+	KillOrphanedProcs bool
+}
+
+// diskSpace is the free space on a filesystem.
+type diskSpace struct {
+	FreeBytes  uint64
+	FreeInodes uint64
+	// HasInodes is false if the filesystem does not limit inodes.
+	HasInodes bool
+}
+
+func (opts *HealthCheckOptions) workDir() string {
+	if opts.WorkDir != "" {
+		return opts.WorkDir
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return wd
+}
+
+// checkHealth returns the reasons the host is not fit to run a task, if any.
+func (agt *Agent) checkHealth() []string {
+	opts := agt.opts.HealthCheck
+	reasons := []string{}
+
+	if opts.MinFreeDiskMB > 0 || opts.MinFreeInodes > 0 {
+		space, err := getDiskSpace(opts.workDir())
+		if err != nil {
+			// the check can't be made, which isn't the host's fault
+			grip.Warning(errors.Wrap(err, "error checking free disk space"))
+		} else {
+			if opts.MinFreeDiskMB > 0 && space.FreeBytes < uint64(opts.MinFreeDiskMB)*1024*1024 {
+				reasons = append(reasons, fmt.Sprintf("%d MB of disk space is free in %s, below the minimum of %d MB",
+					space.FreeBytes/(1024*1024), opts.workDir(), opts.MinFreeDiskMB))
+			}
+			if opts.MinFreeInodes > 0 && space.HasInodes && space.FreeInodes < uint64(opts.MinFreeInodes) {
+				reasons = append(reasons, fmt.Sprintf("%d inodes are free in %s, below the minimum of %d",
+					space.FreeInodes, opts.workDir(), opts.MinFreeInodes))
+			}
+		}
+	}
+
+	if opts.KillOrphanedProcs {
+		orphans, err := shell.CountOrphanedProcs()
+		if err != nil {
+			grip.Warning(errors.Wrap(err, "error checking for orphaned processes"))
+		} else if orphans > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d processes from earlier tasks are still running", orphans))
+		}
+	}
+
+	return reasons
+}
+
+// cleanupHost removes what earlier tasks left behind on the host.
+func (agt *Agent) cleanupHost() {
+	opts := agt.opts.HealthCheck
+
+	if opts.KillOrphanedProcs {
+		grip.Info("killing processes left running by earlier tasks")
+		grip.Error(errors.Wrap(shell.KillOrphanedProcs(agt.logger), "error killing orphaned processes"))
+	}
+
+	if opts.RemoveStaleTaskDirs {
+		removed, err := removeStaleTaskDirs(opts.workDir())
+		grip.Error(errors.Wrap(err, "error removing stale task directories"))
+		grip.InfoWhenf(removed > 0, "removed %d stale task directories from %s", removed, opts.workDir())
+	}
+}
+
+// ensureHealthy checks that the host is fit to run a task, cleaning it up if it
+// isn't. If it is still unhealthy after the cleanup, it reports the host as
+// unhealthy to the API server, which stops dispatching tasks to it, and
+// returns an error so that the agent exits.
+func (agt *Agent) ensureHealthy() error {
+	reasons := agt.checkHealth()
+	if len(reasons) == 0 {
+		return nil
+	}
+	for _, reason := range reasons {
+		grip.Warningf("host is unhealthy: %s", reason)
+	}
+
+	agt.cleanupHost()
+
+	reasons = agt.checkHealth()
+	if len(reasons) == 0 {
+		grip.Info("host is healthy after cleanup")
+		return nil
+	}
+
+	if err := agt.ReportUnhealthy(reasons); err != nil {
+		grip.Error(errors.Wrap(err, "error reporting host unhealthy"))
+	}
+	return errors.Errorf("host is unhealthy after cleanup: %v", reasons)
+}
+
+// removeStaleTaskDirs removes the task directories in workDir, and returns how
+// many it removed. It must only be called between tasks.
+func removeStaleTaskDirs(workDir string) (int, error) {
+	infos, err := ioutil.ReadDir(workDir)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error reading %s", workDir)
+	}
+
+	catcher := grip.NewCatcher()
+	removed := 0
+	for _, info := range infos {
+		if !info.IsDir() || !taskDirName.MatchString(info.Name()) {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(workDir, info.Name())); err != nil {
+			catcher.Add(err)
+			continue
+		}
+		removed++
+	}
+	return removed, catcher.Resolve()
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRemoveStaleTaskDirs(t *testing.T) {
+	Convey("With a working directory containing old task directories", t, func() {
+		workDir, err := ioutil.TempDir("", "health_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(workDir)
+
+		taskDir := filepath.Join(workDir, "0123456789abcdef0123456789abcdef")
+		otherDir := filepath.Join(workDir, "src")
+		otherFile := filepath.Join(workDir, "fedcba9876543210fedcba9876543210")
+		So(os.MkdirAll(filepath.Join(taskDir, "src"), 0755), ShouldBeNil)
+		So(os.Mkdir(otherDir, 0755), ShouldBeNil)
+		So(ioutil.WriteFile(otherFile, []byte("data"), 0644), ShouldBeNil)
+
+		Convey("only the task directories should be removed", func() {
+			removed, err := removeStaleTaskDirs(workDir)
+			So(err, ShouldBeNil)
+			So(removed, ShouldEqual, 1)
+
+			_, err = os.Stat(taskDir)
+			So(os.IsNotExist(err), ShouldBeTrue)
+			_, err = os.Stat(otherDir)
+			So(err, ShouldBeNil)
+			_, err = os.Stat(otherFile)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func TestCheckHealth(t *testing.T) {
+	Convey("With an agent checking its working directory", t, func() {
+		workDir, err := ioutil.TempDir("", "health_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(workDir)
+		agt := &Agent{opts: Options{HealthCheck: HealthCheckOptions{WorkDir: workDir}}}
+
+		Convey("the host should be healthy if every check is disabled", func() {
+			So(agt.checkHealth(), ShouldBeEmpty)
+		})
+
+		Convey("the host should be healthy if the thresholds are met", func() {
+			agt.opts.HealthCheck.MinFreeDiskMB = 1
+			agt.opts.HealthCheck.MinFreeInodes = 1
+			So(agt.checkHealth(), ShouldBeEmpty)
+		})
+
+		Convey("the host should be unhealthy if too little disk space is free", func() {
+			agt.opts.HealthCheck.MinFreeDiskMB = 1 << 40
+			reasons := agt.checkHealth()
+			So(len(reasons), ShouldEqual, 1)
+			So(reasons[0], ShouldContainSubstring, "disk space")
+		})
+	})
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/agent/comm"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/evergreen-ci/evergreen/model/version"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// LocalVersionId is the version id of tasks run locally.
+const LocalVersionId = "local"
+
+// githubRemote matches the owner and repository in the URL of a GitHub remote.
+var githubRemote = regexp.MustCompile(`github\.com[:/]([^/]+)/([^/]+?)(\.git)?/?$`)
+
+// LocalOptions configures an agent that runs a single task from a project
+// file, without an API server.
+type LocalOptions struct {
+	// ProjectPath is the project file to read the task from.
+	ProjectPath string
+	Variant     string
+	TaskName    string
+
+	// ExpansionsPath, if set, is a YAML file of expansions to use in place of
+	// the project's expansion variables.
+	ExpansionsPath string
+
+	// CheckoutDir is the git checkout of the project whose revision, branch
+	// and remote the task is run against.
+	CheckoutDir string
+
+	// WorkDir is the directory the task directory is created in.
+	WorkDir string
+
+	// OutputDir receives the task's logs, test results and artifacts. The
+	// files the task puts to its artifact store are kept under it as well,
+	// rather than in the project's store.
+	OutputDir string
+}
+
+// NewLocal creates an agent that runs a task from a local project file,
+// using a FileCommunicator in place of the API server. The returned
+// communicator holds the task's final status once RunTask returns.
+func NewLocal(opts LocalOptions) (*Agent, *comm.FileCommunicator, error) {
+	fc, err := newLocalCommunicator(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	agt := &Agent{TaskCommunicator: fc}
+	if err = agt.initialize(); err != nil {
+		grip.CatchError(fc.Close())
+		return nil, nil, err
+	}
+	return agt, fc, nil
+}
+
+// newLocalCommunicator builds the documents the agent would otherwise fetch
+// from the API server from the project file, the expansions file and the
+// checkout.
+func newLocalCommunicator(opts LocalOptions) (*comm.FileCommunicator, error) {
+	config, err := ioutil.ReadFile(opts.ProjectPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading project file")
+	}
+
+	identifier := strings.TrimSuffix(filepath.Base(opts.ProjectPath), filepath.Ext(opts.ProjectPath))
+	project := &model.Project{}
+	if err = model.LoadProjectInto(config, identifier, project); err != nil {
+		return nil, errors.Wrap(err, "error loading project file")
+	}
+	if project.FindBuildVariant(opts.Variant) == nil {
+		return nil, errors.Errorf("variant '%s' is not defined in the project", opts.Variant)
+	}
+	if project.FindProjectTask(opts.TaskName) == nil {
+		return nil, errors.Errorf("task '%s' is not defined in the project", opts.TaskName)
+	}
+
+	expansions := apimodels.ExpansionVars{}
+	if opts.ExpansionsPath != "" {
+		var data []byte
+		if data, err = ioutil.ReadFile(opts.ExpansionsPath); err != nil {
+			return nil, errors.Wrap(err, "error reading expansions file")
+		}
+		if err = yaml.Unmarshal(data, &expansions); err != nil {
+			return nil, errors.Wrap(err, "error parsing expansions file")
+		}
+	}
+
+	checkout, err := filepath.Abs(opts.CheckoutDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	revision, err := gitOutput(checkout, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error finding the revision of %s", checkout)
+	}
+	// a checkout without these is still usable, it just cannot be cloned
+	branch, _ := gitOutput(checkout, "rev-parse", "--abbrev-ref", "HEAD")
+	author, _ := gitOutput(checkout, "log", "-1", "--format=%an")
+	remote, _ := gitOutput(checkout, "config", "--get", "remote.origin.url")
+	owner, repo := "", ""
+	if m := githubRemote.FindStringSubmatch(remote); m != nil {
+		owner, repo = m[1], m[2]
+	}
+	expansions["checkout_dir"] = checkout
+
+	workDir, err := filepath.Abs(opts.WorkDir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = os.MkdirAll(workDir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "error creating work directory %s", workDir)
+	}
+
+	t := &task.Task{
+		Id: fmt.Sprintf("%s_%s_%s_%s", identifier, opts.Variant, opts.TaskName,
+			time.Now().Format(filenameTimestamp)),
+		DisplayName:  opts.TaskName,
+		BuildVariant: opts.Variant,
+		Project:      identifier,
+		Revision:     revision,
+		Version:      LocalVersionId,
+		Requester:    evergreen.RepotrackerVersionRequester,
+	}
+
+	fc, err := comm.NewFileCommunicator(opts.OutputDir, t)
+	if err != nil {
+		return nil, err
+	}
+	fc.Expansions = expansions
+	fc.Distro = &distro.Distro{
+		Id:      "local",
+		WorkDir: workDir,
+	}
+	fc.Version = &version.Version{
+		Id:         LocalVersionId,
+		CreateTime: time.Now(),
+		Revision:   revision,
+		Author:     author,
+		Config:     string(config),
+		Owner:      owner,
+		Repo:       repo,
+		Branch:     branch,
+		RepoKind:   model.GithubRepoType,
+		Identifier: identifier,
+		Requester:  evergreen.RepotrackerVersionRequester,
+	}
+	fc.ProjectRef = &model.ProjectRef{
+		Identifier: identifier,
+		Owner:      owner,
+		Repo:       repo,
+		Branch:     branch,
+		RepoKind:   model.GithubRepoType,
+		Enabled:    true,
+		ArtifactStore: artifactstore.Config{
+			Type: artifactstore.LocalType,
+			Path: filepath.Join(fc.OutputDir, comm.FileStoreDir),
+		},
+	}
+	return fc, nil
+}
+
+// gitOutput runs git in dir and returns its trimmed output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "error running git %s", strings.Join(args, " "))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
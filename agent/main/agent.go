@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/evergreen-ci/evergreen/agent"
+	"github.com/evergreen-ci/evergreen/agent/comm"
 	_ "github.com/evergreen-ci/evergreen/plugin/config"
 	"github.com/mongodb/grip"
 	"github.com/mongodb/grip/level"
@@ -60,6 +61,19 @@ func main() {
 	httpsCertFile := flag.String("https_cert", "", "path to a self-signed private cert")
 	logPrefix := flag.String("log_prefix", "evg-agent", "prefix for the agent's log filename")
 	port := flag.Int("status_port", statsPort, "port to run the status server on")
+	workDir := flag.String("working_dir", "", "the distro's working directory, checked for free space before each task")
+	minFreeDisk := flag.Int("min_free_disk_mb", agent.DefaultMinFreeDiskMB,
+		"free disk space, in MB, required to accept a task (0 to disable)")
+	minFreeInodes := flag.Int("min_free_inodes", agent.DefaultMinFreeInodes,
+		"free inodes required to accept a task (0 to disable)")
+	cleanupDirs := flag.Bool("cleanup_task_dirs", true, "remove stale task directories if the host is unhealthy")
+	cleanupProcs := flag.Bool("cleanup_procs", true, "check for and kill processes left running by earlier tasks")
+	logTransport := flag.String("log_transport", comm.LogTransportPost,
+		fmt.Sprintf("how task logs are sent to the API server (%s or %s)", comm.LogTransportPost, comm.LogTransportStream))
+	logSpoolDir := flag.String("log_spool_dir", "", "directory streamed logs are spilled to while the API server is unreachable")
+	logSpoolMB := flag.Int("log_spool_mb", comm.DefaultLogStreamSpoolBytes/(1024*1024),
+		"megabytes of streamed logs spilled to disk before the task's logging blocks")
+	register := flag.Bool("register", false, "register the host with the API server, for hosts that start their own agent")
 	flag.Parse()
 
 	grip.CatchEmergencyFatal(agent.SetupLogging("agent-startup", "init"))
@@ -67,6 +81,10 @@ func main() {
 	grip.SetThreshold(level.Debug)
 	grip.SetName("evg-agent")
 
+	if *logTransport != comm.LogTransportPost && *logTransport != comm.LogTransportStream {
+		grip.EmergencyFatalf("invalid log transport '%s'", *logTransport)
+	}
+
 	httpsCert, err := getHTTPSCertFile(*httpsCertFile)
 	if err != nil {
 		grip.EmergencyFatalf("could not decode https certificate file: %+v", err)
@@ -80,6 +98,19 @@ func main() {
 		HostSecret:  *hostSecret,
 		StatusPort:  *port,
 		LogPrefix:   *logPrefix,
+		HealthCheck: agent.HealthCheckOptions{
+			WorkDir:             *workDir,
+			MinFreeDiskMB:       *minFreeDisk,
+			MinFreeInodes:       *minFreeInodes,
+			RemoveStaleTaskDirs: *cleanupDirs,
+			KillOrphanedProcs:   *cleanupProcs,
+		},
+		LogTransport: *logTransport,
+		LogStream: comm.LogStreamOptions{
+			SpoolDir:      *logSpoolDir,
+			MaxSpoolBytes: int64(*logSpoolMB) * 1024 * 1024,
+		},
+		Register: *register,
 	}
 
 	agt, err := agent.New(initialOptions)
@@ -1,30 +1,48 @@
 package agent
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"runtime/pprof"
+	"strconv"
+	"time"
 
 	"github.com/codegangsta/negroni"
 	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/agent/comm"
+	"github.com/evergreen-ci/evergreen/model"
 	"github.com/gorilla/mux"
 	"github.com/mongodb/grip"
 	"github.com/mongodb/grip/message"
+	"github.com/pkg/errors"
 )
 
+// statusLogLines is the number of recent log lines included in the status.
+const statusLogLines = 20
+
 func (agt *Agent) startStatusServer(port int) {
 	addr := fmt.Sprintf("127.0.0.1:%d", port)
 
-	go func() {
-		r := mux.NewRouter().StrictSlash(false)
-		r.HandleFunc("/status", agt.statusHandler()).Methods("GET")
+	r := mux.NewRouter().StrictSlash(false)
+	r.HandleFunc("/status", agt.statusHandler()).Methods("GET")
+	r.HandleFunc("/logs", agt.logsHandler()).Methods("GET")
+	r.HandleFunc("/abort", agt.requireHostSecret(agt.abortHandler())).Methods("POST")
+	r.HandleFunc("/dump", agt.requireHostSecret(agt.dumpHandler())).Methods("POST")
 
-		n := negroni.New()
-		n.Use(negroni.NewRecovery())
-		n.UseHandler(r)
+	n := negroni.New()
+	n.Use(negroni.NewRecovery())
+	n.UseHandler(r)
 
-		grip.CatchEmergencyFatal(http.ListenAndServe(addr, n))
+	agt.statusServer = &http.Server{Addr: addr, Handler: n}
+	go func() {
+		err := agt.statusServer.ListenAndServe()
+		if err != http.ErrServerClosed {
+			grip.CatchEmergencyFatal(err)
+		}
 	}()
 
 	grip.Infoln("starting status service on:", addr)
@@ -41,8 +59,26 @@ type statusResponse struct {
 	ProcessTree []*message.ProcessInfo `json:"ps_info"`
 	TaskId      string                 `json:"task_id"`
 
-	// TODO (EVG-1440) include the current task ID when the service is part
-	// of the agent itself.
+	// The remaining fields describe the task the agent is running, if any.
+	TaskElapsed    string             `json:"task_elapsed,omitempty"`
+	Command        string             `json:"command,omitempty"`
+	CommandElapsed string             `json:"command_elapsed,omitempty"`
+	IdleTimeout    *timeoutStatus     `json:"idle_timeout,omitempty"`
+	ExecTimeout    *timeoutStatus     `json:"exec_timeout,omitempty"`
+	RecentLogs     []model.LogMessage `json:"recent_logs,omitempty"`
+}
+
+// timeoutStatus describes how close the task is to one of its timeouts.
+type timeoutStatus struct {
+	Timeout   string `json:"timeout"`
+	Elapsed   string `json:"elapsed,omitempty"`
+	Remaining string `json:"remaining,omitempty"`
+}
+
+// dumpResponse is the response to a dump request.
+type dumpResponse struct {
+	Goroutines  string                 `json:"goroutines"`
+	ProcessTree []*message.ProcessInfo `json:"ps_info"`
 }
 
 // statusHandler is a function that produces the status handler.
@@ -50,40 +86,197 @@ func (agt *Agent) statusHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		grip.Debug("preparing status response")
 		resp := buildResponse(agt.opts, agt.GetCurrentTaskId())
+		agt.addTaskStatus(&resp)
+		writeStatusJSON(w, http.StatusOK, resp)
+	}
+}
+
+// logsHandler produces the handler that returns the current task's recent
+// log messages. The number of messages can be limited with the lines
+// parameter.
+func (agt *Agent) logsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lines := 0
+		if param := r.FormValue("lines"); param != "" {
+			var err error
+			if lines, err = strconv.Atoi(param); err != nil || lines < 0 {
+				writeStatusJSON(w, http.StatusBadRequest, statusError(errors.Errorf("invalid number of lines '%s'", param)))
+				return
+			}
+		}
+
+		agt.statusMutex.RLock()
+		apiLogger := agt.APILogger
+		agt.statusMutex.RUnlock()
+
+		logs := []model.LogMessage{}
+		if apiLogger != nil {
+			logs = apiLogger.Tail(lines)
+		}
+		writeStatusJSON(w, http.StatusOK, logs)
+	}
+}
+
+// abortHandler produces the handler that aborts the running task, which then
+// fails. The agent goes on to ask for its next task.
+func (agt *Agent) abortHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		agt.statusMutex.RLock()
+		sigHandler := agt.signalHandler
+		agt.statusMutex.RUnlock()
+
+		taskId := agt.GetCurrentTaskId()
+		if sigHandler == nil || taskId == "" || isClosed(sigHandler.stopBackgroundChan) {
+			writeStatusJSON(w, http.StatusConflict, statusError(errors.New("no task is running")))
+			return
+		}
+
+		select {
+		case sigHandler.abortChan <- comm.AbortedOnHost:
+			grip.Warningf("aborting task %s at the request of someone on the host", taskId)
+			writeStatusJSON(w, http.StatusOK, struct {
+				TaskId string `json:"task_id"`
+			}{taskId})
+		default:
+			writeStatusJSON(w, http.StatusConflict, statusError(errors.Errorf("task %s is already being aborted", taskId)))
+		}
+	}
+}
+
+// dumpHandler produces the handler that returns the stacks of all of the
+// agent's goroutines and the agent's process tree.
+func (agt *Agent) dumpHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		grip.Info("dumping goroutines and processes at the request of someone on the host")
+
+		stacks := &bytes.Buffer{}
+		if err := pprof.Lookup("goroutine").WriteTo(stacks, 2); err != nil {
+			writeStatusJSON(w, http.StatusInternalServerError, statusError(errors.Wrap(err, "error dumping goroutines")))
+			return
+		}
+		writeStatusJSON(w, http.StatusOK, dumpResponse{
+			Goroutines:  stacks.String(),
+			ProcessTree: collectProcessTree(),
+		})
+	}
+}
 
-		// in the future we may want to use the same render
-		// package used in the service, but doing this
-		// manually is probably good enough for now.
-		out, err := json.MarshalIndent(resp, " ", " ")
-		if err != nil {
-			grip.Error(err)
-			w.WriteHeader(http.StatusInternalServerError)
+// requireHostSecret rejects requests that do not carry the host's secret, so
+// that only those who can read the agent's configuration can control it.
+func (agt *Agent) requireHostSecret(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get(evergreen.HostSecretHeader)
+		if agt.opts.HostSecret == "" ||
+			subtle.ConstantTimeCompare([]byte(secret), []byte(agt.opts.HostSecret)) != 1 {
+			writeStatusJSON(w, http.StatusUnauthorized,
+				statusError(errors.Errorf("request requires the host's secret in the %s header", evergreen.HostSecretHeader)))
 			return
 		}
+		next(w, r)
+	}
+}
+
+// addTaskStatus adds the state of the running task to the response.
+func (agt *Agent) addTaskStatus(resp *statusResponse) {
+	agt.currentCommandMutex.RLock()
+	command := agt.currentCommand
+	commandStarted := agt.currentCommandStarted
+	agt.currentCommandMutex.RUnlock()
 
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		_, err = w.Write(out)
-		grip.CatchError(err)
+	if !commandStarted.IsZero() {
+		resp.Command = command.GetDisplayName()
+		resp.CommandElapsed = formatDuration(time.Since(commandStarted))
+	}
+
+	agt.statusMutex.RLock()
+	defer agt.statusMutex.RUnlock()
+
+	if !agt.taskStarted.IsZero() {
+		resp.TaskElapsed = formatDuration(time.Since(agt.taskStarted))
+	}
+	if agt.idleTimeoutWatcher != nil {
+		resp.IdleTimeout = newTimeoutStatus(agt.idleTimeoutWatcher.State())
+	}
+	if agt.maxExecTimeoutWatcher != nil {
+		resp.ExecTimeout = newTimeoutStatus(agt.maxExecTimeoutWatcher.State())
+	}
+	if agt.APILogger != nil {
+		resp.RecentLogs = agt.APILogger.Tail(statusLogLines)
 	}
 }
 
+func newTimeoutStatus(state comm.TimeoutState) *timeoutStatus {
+	status := &timeoutStatus{Timeout: formatDuration(state.Duration)}
+	if state.Started {
+		status.Elapsed = formatDuration(state.Elapsed)
+		remaining := state.Duration - state.Elapsed
+		if remaining < 0 {
+			remaining = 0
+		}
+		status.Remaining = formatDuration(remaining)
+	}
+	return status
+}
+
+// formatDuration formats d to the second.
+func formatDuration(d time.Duration) string {
+	return (d / time.Second * time.Second).String()
+}
+
+// isClosed returns true if the channel is closed.
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func statusError(err error) interface{} {
+	return struct {
+		Error string `json:"error"`
+	}{err.Error()}
+}
+
+// writeStatusJSON writes v as the response. In the future we may want to use
+// the same render package used in the service, but doing this manually is
+// probably good enough for now.
+func writeStatusJSON(w http.ResponseWriter, status int, v interface{}) {
+	out, err := json.MarshalIndent(v, " ", " ")
+	if err != nil {
+		grip.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(out)
+	grip.CatchError(err)
+}
+
 // buildResponse produces the response document for the current
 // process, and is separate to facilitate testing.
 func buildResponse(opts Options, taskId string) statusResponse {
-	out := statusResponse{
-		BuildId:    evergreen.BuildRevision,
-		AgentPid:   os.Getpid(),
-		APIServer:  opts.APIURL,
-		HostId:     opts.HostId,
-		TaskId:     taskId,
-		SystemInfo: message.CollectSystemInfo().(*message.SystemInfo),
+	return statusResponse{
+		BuildId:     evergreen.BuildRevision,
+		AgentPid:    os.Getpid(),
+		APIServer:   opts.APIURL,
+		HostId:      opts.HostId,
+		TaskId:      taskId,
+		SystemInfo:  message.CollectSystemInfo().(*message.SystemInfo),
+		ProcessTree: collectProcessTree(),
 	}
+}
 
+// collectProcessTree returns information about the agent's process and its
+// children.
+func collectProcessTree() []*message.ProcessInfo {
 	psTree := message.CollectProcessInfoSelfWithChildren()
-	out.ProcessTree = make([]*message.ProcessInfo, len(psTree))
+	out := make([]*message.ProcessInfo, len(psTree))
 	for idx, p := range psTree {
-		out.ProcessTree[idx] = p.(*message.ProcessInfo)
+		out[idx] = p.(*message.ProcessInfo)
 	}
-
 	return out
 }
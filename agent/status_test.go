@@ -1,14 +1,21 @@
 package agent
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/agent/comm"
+	"github.com/evergreen-ci/evergreen/model"
 	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/level"
+	"github.com/mongodb/grip/message"
+	"github.com/mongodb/grip/slogger"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -67,3 +74,82 @@ func TestAgentConstructorStartsStatusServer(t *testing.T) {
 		})
 	})
 }
+
+func TestAgentStatusControl(t *testing.T) {
+	Convey("With an agent running a task", t, func() {
+		sigHandler := &SignalHandler{}
+		sigHandler.makeChannels()
+		agt := &Agent{
+			opts:             Options{HostSecret: "secret"},
+			TaskCommunicator: &comm.MockCommunicator{TaskId: "task1"},
+			signalHandler:    sigHandler,
+			APILogger:        comm.NewAPILogger(&comm.MockCommunicator{}),
+			taskStarted:      time.Now().Add(-time.Minute),
+		}
+		agt.currentCommand = model.PluginCommandConf{Command: "shell.exec", DisplayName: "run tests"}
+		agt.currentCommandStarted = time.Now().Add(-time.Second)
+		for i := 0; i < 30; i++ {
+			So(agt.APILogger.Append(slogger.NewPrefixedLog(model.TaskLogPrefix,
+				message.NewFormattedMessage(level.Info, "line %d", i))), ShouldBeNil)
+		}
+
+		request := func(handler http.HandlerFunc, method, url, secret string) *httptest.ResponseRecorder {
+			r, err := http.NewRequest(method, url, nil)
+			So(err, ShouldBeNil)
+			if secret != "" {
+				r.Header.Set(evergreen.HostSecretHeader, secret)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			return w
+		}
+
+		Convey("the status should include the current command and recent logs", func() {
+			resp := statusResponse{}
+			agt.addTaskStatus(&resp)
+			So(resp.Command, ShouldEqual, "run tests")
+			So(resp.CommandElapsed, ShouldEqual, "1s")
+			So(resp.TaskElapsed, ShouldEqual, "1m0s")
+			So(len(resp.RecentLogs), ShouldEqual, statusLogLines)
+			So(resp.RecentLogs[statusLogLines-1].Message, ShouldEqual, "line 29")
+		})
+
+		Convey("the logs endpoint should return the requested number of lines", func() {
+			w := request(agt.logsHandler(), "GET", "/logs?lines=3", "")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			logs := []model.LogMessage{}
+			So(json.Unmarshal(w.Body.Bytes(), &logs), ShouldBeNil)
+			So(len(logs), ShouldEqual, 3)
+			So(logs[0].Message, ShouldEqual, "line 27")
+
+			w = request(agt.logsHandler(), "GET", "/logs?lines=many", "")
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		})
+
+		Convey("control requests without the host secret should be rejected", func() {
+			w := request(agt.requireHostSecret(agt.abortHandler()), "POST", "/abort", "")
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			w = request(agt.requireHostSecret(agt.dumpHandler()), "POST", "/dump", "wrong")
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("an abort request should signal the task to abort once", func() {
+			w := request(agt.requireHostSecret(agt.abortHandler()), "POST", "/abort", "secret")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(<-sigHandler.abortChan, ShouldEqual, comm.AbortedOnHost)
+
+			close(sigHandler.stopBackgroundChan)
+			w = request(agt.requireHostSecret(agt.abortHandler()), "POST", "/abort", "secret")
+			So(w.Code, ShouldEqual, http.StatusConflict)
+		})
+
+		Convey("a dump request should return the agent's goroutines", func() {
+			w := request(agt.requireHostSecret(agt.dumpHandler()), "POST", "/dump", "secret")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			dump := dumpResponse{}
+			So(json.Unmarshal(w.Body.Bytes(), &dump), ShouldBeNil)
+			So(dump.Goroutines, ShouldContainSubstring, "TestAgentStatusControl")
+			So(len(dump.ProcessTree), ShouldBeGreaterThanOrEqualTo, 1)
+		})
+	})
+}
@@ -0,0 +1,316 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/agent/comm"
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/plugin/builtin/shell"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
+	"github.com/goamz/goamz/s3"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultTimeoutHookSecs is how long a timeout hook may run for each
+	// process if its timeout_secs is not specified.
+	DefaultTimeoutHookSecs = 60
+
+	// DefaultTimeoutSignalWaitSecs is how long the agent waits for a timed out
+	// task's processes to exit after signaling them, if the project's
+	// signal_wait_secs is not specified.
+	DefaultTimeoutSignalWaitSecs = 30
+
+	// timeoutHookOutputLines is the most lines of a hook's output kept.
+	timeoutHookOutputLines = 100000
+)
+
+var timeoutSignals = map[string]syscall.Signal{
+	"SIGABRT": syscall.SIGABRT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGSEGV": syscall.SIGSEGV,
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// collectTimeoutDiagnostics collects the data configured in the project's
+// timeout_diagnostics section from the processes of a task that has timed out,
+// before they are killed, and attaches it to the task. Failures are logged
+// rather than returned, since the task has already failed.
+func (agt *Agent) collectTimeoutDiagnostics() {
+	conf := agt.taskConfig
+	diag := conf.Project.TimeoutDiagnostics
+	if diag == nil {
+		return
+	}
+	start := time.Now()
+	agt.logger.LogTask(slogger.INFO, "Collecting timeout diagnostics.")
+
+	procs, err := shell.FindSpawnedProcs(conf.Task.Id)
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error finding the task's processes: %v", err)
+	}
+
+	files := []*artifact.File{}
+	for _, hook := range diag.Hooks {
+		files = append(files, agt.runTimeoutHook(hook, procs)...)
+	}
+
+	if diag.Signal != "" && len(procs) > 0 {
+		agt.signalTimedOutProcs(diag, procs)
+	}
+
+	if len(diag.CoreFiles) > 0 {
+		files = append(files, agt.collectCoreFiles(diag)...)
+	}
+
+	if len(files) > 0 {
+		pluginCom := &comm.TaskJSONCommunicator{TaskCommunicator: agt.TaskCommunicator}
+		if err = pluginCom.PostTaskFiles(files); err != nil {
+			agt.logger.LogExecution(slogger.ERROR, "Error attaching timeout diagnostics: %v", err)
+		}
+	}
+	agt.logger.LogTask(slogger.INFO, "Finished collecting timeout diagnostics in %v.", time.Since(start).String())
+}
+
+// runTimeoutHook runs the hook against each of the processes it matches, and
+// posts the output of each run as a test log.
+func (agt *Agent) runTimeoutHook(hook model.TimeoutHook, procs []shell.SpawnedProc) []*artifact.File {
+	var processes *regexp.Regexp
+	if hook.Processes != "" {
+		var err error
+		if processes, err = regexp.Compile(hook.Processes); err != nil {
+			agt.logger.LogExecution(slogger.ERROR, "Invalid processes regex for timeout hook '%v': %v", hook.Name, err)
+			return nil
+		}
+	}
+	timeout := time.Duration(hook.TimeoutSecs) * time.Second
+	if timeout <= 0 {
+		timeout = DefaultTimeoutHookSecs * time.Second
+	}
+
+	files := []*artifact.File{}
+	for _, proc := range procs {
+		if processes != nil && !processes.MatchString(proc.Command) {
+			continue
+		}
+		expansions := command.NewExpansions(*agt.taskConfig.Expansions)
+		expansions.Put("pid", strconv.Itoa(proc.Pid))
+		cmd, err := expansions.ExpandString(hook.Command)
+		if err != nil {
+			agt.logger.LogExecution(slogger.ERROR, "Error expanding timeout hook '%v': %v", hook.Name, err)
+			return files
+		}
+
+		agt.logger.LogTask(slogger.INFO, "Running timeout hook '%v' against pid %v (%v).", hook.Name, proc.Pid, proc.Command)
+		output, err := runTimeoutHookCommand(cmd, agt.taskConfig.WorkDir, timeout)
+		if err != nil {
+			agt.logger.LogTask(slogger.WARN, "Timeout hook '%v' failed against pid %v: %v", hook.Name, proc.Pid, err)
+		}
+
+		name := fmt.Sprintf("%s (pid %d)", hook.Name, proc.Pid)
+		if file := agt.postTimeoutOutput(name, output); file != nil {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// runTimeoutHookCommand runs cmd with the shell, and returns its combined
+// output even if it fails.
+func runTimeoutHookCommand(cmd, workDir string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Dir = workDir
+	out, err := c.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = errors.Errorf("timed out after %v", timeout)
+	}
+	return string(out), errors.WithStack(err)
+}
+
+// postTimeoutOutput posts output as a test log, and returns the artifact
+// linking to it.
+func (agt *Agent) postTimeoutOutput(name, output string) *artifact.File {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > timeoutHookOutputLines {
+		lines = lines[len(lines)-timeoutHookOutputLines:]
+	}
+	pluginCom := &comm.TaskJSONCommunicator{TaskCommunicator: agt.TaskCommunicator}
+	logId, err := pluginCom.TaskPostTestLog(&model.TestLog{
+		Name:          "timeout diagnostics: " + name,
+		Task:          agt.taskConfig.Task.Id,
+		TaskExecution: agt.taskConfig.Task.Execution,
+		Lines:         lines,
+	})
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error posting output of timeout hook '%v': %v", name, err)
+		return nil
+	}
+	return &artifact.File{
+		Name: "timeout diagnostics: " + name,
+		Link: "/test_log/" + logId,
+	}
+}
+
+// signalTimedOutProcs sends the configured signal to the task's processes, and
+// waits for them to exit so that they can finish writing core files.
+func (agt *Agent) signalTimedOutProcs(diag *model.TimeoutDiagnostics, procs []shell.SpawnedProc) {
+	sig, ok := timeoutSignals[diag.Signal]
+	if !ok {
+		agt.logger.LogExecution(slogger.ERROR, "Invalid timeout diagnostics signal '%v'", diag.Signal)
+		return
+	}
+	for _, proc := range procs {
+		p, err := os.FindProcess(proc.Pid)
+		if err == nil {
+			err = p.Signal(sig)
+		}
+		if err != nil {
+			agt.logger.LogExecution(slogger.WARN, "Error sending %v to pid %v: %v", diag.Signal, proc.Pid, err)
+			continue
+		}
+		agt.logger.LogTask(slogger.INFO, "Sent %v to pid %v.", diag.Signal, proc.Pid)
+	}
+
+	wait := time.Duration(diag.SignalWaitSecs) * time.Second
+	if wait <= 0 {
+		wait = DefaultTimeoutSignalWaitSecs * time.Second
+	}
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		remaining, err := shell.FindSpawnedProcs(agt.taskConfig.Task.Id)
+		if err != nil || len(remaining) == 0 {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+	agt.logger.LogTask(slogger.WARN, "The task's processes were still running %v after %v was sent.", wait, diag.Signal)
+}
+
+// collectCoreFiles finds the core files written since the task started, and
+// uploads them to the project's artifact store if S3 settings are configured.
+// Otherwise, their paths are logged.
+func (agt *Agent) collectCoreFiles(diag *model.TimeoutDiagnostics) []*artifact.File {
+	agt.statusMutex.RLock()
+	taskStarted := agt.taskStarted
+	agt.statusMutex.RUnlock()
+
+	cores, err := findCoreFiles(diag.CoreFiles, agt.taskConfig.WorkDir, taskStarted)
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error finding core files: %v", err)
+	}
+	if len(cores) == 0 {
+		agt.logger.LogTask(slogger.INFO, "No core files were found.")
+		return nil
+	}
+	if diag.S3 == nil {
+		for _, core := range cores {
+			agt.logger.LogTask(slogger.INFO, "Found core file %v.", core)
+		}
+		return nil
+	}
+
+	dest, err := agt.expandTimeoutS3(diag.S3)
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error expanding timeout diagnostics s3 settings: %v", err)
+		return nil
+	}
+	storeConf := artifactstore.Config{}
+	if agt.taskConfig.ProjectRef != nil {
+		storeConf = agt.taskConfig.ProjectRef.ArtifactStore
+	}
+	store, err := artifactstore.New(storeConf, artifactstore.Credentials{
+		Key:    dest.AwsKey,
+		Secret: dest.AwsSecret,
+	})
+	if err != nil {
+		agt.logger.LogExecution(slogger.ERROR, "Error setting up artifact store for core files: %v", err)
+		return nil
+	}
+	opts := artifactstore.PutOptions{
+		ContentType: "application/octet-stream",
+		Permissions: dest.Permissions,
+	}
+
+	files := []*artifact.File{}
+	for _, core := range cores {
+		remotePath := path.Join(dest.RemotePrefix, filepath.Base(core))
+		link := store.Link(dest.Bucket, remotePath)
+		agt.logger.LogTask(slogger.INFO, "Uploading core file %v to %v.", core, link)
+		sums, err := store.Put(dest.Bucket, remotePath, core, opts)
+		if err != nil {
+			agt.logger.LogExecution(slogger.ERROR, "Error uploading core file %v: %v", core, err)
+			continue
+		}
+		files = append(files, &artifact.File{
+			Name:          "core file: " + filepath.Base(core),
+			Link:          link,
+			Visibility:    dest.Visibility,
+			Backend:       store.Type(),
+			Bucket:        dest.Bucket,
+			Key:           remotePath,
+			ContentMD5:    sums.MD5,
+			ContentSHA256: sums.SHA256,
+		})
+	}
+	return files
+}
+
+// expandTimeoutS3 returns a copy of the S3 settings with the task's expansions
+// applied.
+func (agt *Agent) expandTimeoutS3(conf *model.TimeoutDiagnosticsS3) (*model.TimeoutDiagnosticsS3, error) {
+	dest := *conf
+	for _, field := range []*string{&dest.Bucket, &dest.AwsKey, &dest.AwsSecret, &dest.RemotePrefix} {
+		expanded, err := agt.taskConfig.Expansions.ExpandString(*field)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		*field = expanded
+	}
+	if dest.Permissions == "" {
+		dest.Permissions = string(s3.Private)
+	}
+	return &dest, nil
+}
+
+// findCoreFiles returns the files matching the patterns that were modified
+// after since, so that cores left by earlier tasks are not collected. Relative
+// patterns are relative to workDir.
+func findCoreFiles(patterns []string, workDir string, since time.Time) ([]string, error) {
+	seen := map[string]bool{}
+	cores := []string{}
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(workDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return cores, errors.Wrapf(err, "invalid core file pattern '%s'", pattern)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() || info.ModTime().Before(since) || seen[match] {
+				continue
+			}
+			seen[match] = true
+			cores = append(cores, match)
+		}
+	}
+	return cores, nil
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/agent/testutil"
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
+	"github.com/mongodb/grip/send"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFindCoreFiles(t *testing.T) {
+	Convey("With a working directory containing old and new core files", t, func() {
+		workDir, err := ioutil.TempDir("", "timeout_diagnostics_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(workDir)
+
+		oldCore := filepath.Join(workDir, "core.1")
+		newCore := filepath.Join(workDir, "core.2")
+		So(ioutil.WriteFile(oldCore, []byte("old"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(newCore, []byte("new"), 0644), ShouldBeNil)
+		So(os.Mkdir(filepath.Join(workDir, "core.dir"), 0755), ShouldBeNil)
+		taskStarted := time.Now().Add(-time.Minute)
+		So(os.Chtimes(oldCore, taskStarted.Add(-time.Hour), taskStarted.Add(-time.Hour)), ShouldBeNil)
+
+		Convey("only the files written since the task started should be found", func() {
+			cores, err := findCoreFiles([]string{"core.*", filepath.Join(workDir, "core.2")}, workDir, taskStarted)
+			So(err, ShouldBeNil)
+			So(cores, ShouldResemble, []string{newCore})
+		})
+
+		Convey("an invalid pattern should be an error", func() {
+			_, err := findCoreFiles([]string{"core.["}, workDir, taskStarted)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestRunTimeoutHookCommand(t *testing.T) {
+	Convey("When running a timeout hook command", t, func() {
+		Convey("its output should be returned", func() {
+			out, err := runTimeoutHookCommand("echo stack; echo trace 1>&2", "", time.Minute)
+			So(err, ShouldBeNil)
+			So(out, ShouldEqual, "stack\ntrace\n")
+		})
+
+		Convey("a command that runs too long should be killed", func() {
+			start := time.Now()
+			_, err := runTimeoutHookCommand("exec sleep 30", "", 100*time.Millisecond)
+			So(err, ShouldNotBeNil)
+			So(time.Since(start), ShouldBeLessThan, 10*time.Second)
+		})
+	})
+}
+
+func TestCollectCoreFiles(t *testing.T) {
+	Convey("With a core file written by a task whose project keeps artifacts locally", t, func() {
+		workDir, err := ioutil.TempDir("", "collect-cores")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(workDir)
+		So(ioutil.WriteFile(filepath.Join(workDir, "core.1"), []byte("core"), 0644), ShouldBeNil)
+
+		storeDir := filepath.Join(workDir, "store")
+		agt := &Agent{
+			logger:      testutil.NewTestLogger(send.MakeInternalLogger()),
+			taskStarted: time.Now().Add(-time.Hour),
+			taskConfig: &model.TaskConfig{
+				WorkDir:    workDir,
+				Expansions: command.NewExpansions(map[string]string{"task_id": "t1"}),
+				ProjectRef: &model.ProjectRef{
+					ArtifactStore: artifactstore.Config{Type: artifactstore.LocalType, Path: storeDir},
+				},
+			},
+		}
+		diag := &model.TimeoutDiagnostics{
+			CoreFiles: []string{"core.*"},
+			S3:        &model.TimeoutDiagnosticsS3{Bucket: "cores", RemotePrefix: "${task_id}"},
+		}
+
+		Convey("it should be put to the project's artifact store", func() {
+			files := agt.collectCoreFiles(diag)
+			So(len(files), ShouldEqual, 1)
+			So(files[0].Backend, ShouldEqual, artifactstore.LocalType)
+			So(files[0].Bucket, ShouldEqual, "cores")
+			So(files[0].Key, ShouldEqual, "t1/core.1")
+			So(files[0].ContentSHA256, ShouldHaveLength, 64)
+
+			data, err := ioutil.ReadFile(filepath.Join(storeDir, "cores", "t1", "core.1"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "core")
+		})
+	})
+}
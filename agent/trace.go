@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/apimodels"
+)
+
+// commandTrace records every command the agent runs for a task, so that the
+// API server can show which command failed, how long it ran and why.
+type commandTrace struct {
+	mu       sync.Mutex
+	commands []apimodels.CommandTrace
+}
+
+// start records that a command has started and returns its index in the
+// trace, which is passed to finish once the command returns.
+func (ct *commandTrace) start(cmd apimodels.CommandTrace) int {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	cmd.Start = time.Now()
+	ct.commands = append(ct.commands, cmd)
+	return len(ct.commands) - 1
+}
+
+// finish records the outcome of the command at index i.
+func (ct *commandTrace) finish(i int, err error) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	if i < 0 || i >= len(ct.commands) {
+		return
+	}
+	cmd := &ct.commands[i]
+	cmd.End = time.Now()
+	cmd.Status = evergreen.TaskSucceeded
+	if err != nil {
+		cmd.Status = evergreen.TaskFailed
+		cmd.Error = err.Error()
+	}
+}
+
+// timedOut marks the commands that are still running as having timed out.
+func (ct *commandTrace) timedOut() {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	for i := range ct.commands {
+		if ct.commands[i].End.IsZero() {
+			ct.commands[i].TimedOut = true
+		}
+	}
+}
+
+// export returns a copy of the trace.
+func (ct *commandTrace) export() []apimodels.CommandTrace {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	if len(ct.commands) == 0 {
+		return nil
+	}
+	out := make([]apimodels.CommandTrace, len(ct.commands))
+	copy(out, ct.commands)
+	return out
+}
+
+// reset clears the trace for the next task.
+func (ct *commandTrace) reset() {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.commands = nil
+}
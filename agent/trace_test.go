@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCommandTrace(t *testing.T) {
+	Convey("With a command trace", t, func() {
+		trace := &commandTrace{}
+
+		Convey("an empty trace should export nothing", func() {
+			So(trace.export(), ShouldBeNil)
+		})
+
+		Convey("commands should be recorded in order with their outcomes", func() {
+			first := trace.start(apimodels.CommandTrace{Name: "git.get_project", Function: "fetch"})
+			trace.finish(first, nil)
+			second := trace.start(apimodels.CommandTrace{Name: "shell.exec"})
+			trace.finish(second, errors.New("exit status 1"))
+
+			commands := trace.export()
+			So(len(commands), ShouldEqual, 2)
+			So(commands[0].Name, ShouldEqual, "git.get_project")
+			So(commands[0].Function, ShouldEqual, "fetch")
+			So(commands[0].Status, ShouldEqual, evergreen.TaskSucceeded)
+			So(commands[0].End.Before(commands[0].Start), ShouldBeFalse)
+			So(commands[1].Status, ShouldEqual, evergreen.TaskFailed)
+			So(commands[1].Error, ShouldEqual, "exit status 1")
+			So(commands[1].TimedOut, ShouldBeFalse)
+		})
+
+		Convey("only commands that are still running should be marked as timed out", func() {
+			trace.finish(trace.start(apimodels.CommandTrace{Name: "shell.exec"}), nil)
+			running := trace.start(apimodels.CommandTrace{Name: "subprocess.exec"})
+			trace.timedOut()
+			trace.finish(running, errors.New("killed"))
+
+			commands := trace.export()
+			So(commands[0].TimedOut, ShouldBeFalse)
+			So(commands[1].TimedOut, ShouldBeTrue)
+			So(commands[1].Status, ShouldEqual, evergreen.TaskFailed)
+		})
+
+		Convey("resetting the trace should clear it", func() {
+			trace.start(apimodels.CommandTrace{Name: "shell.exec"})
+			trace.reset()
+			So(trace.export(), ShouldBeNil)
+		})
+	})
+}
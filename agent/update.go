@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/kardianos/osext"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// updateAgent downloads the agent for the given revision from the API server,
+// verifies its checksum, replaces the running binary with it and re-executes
+// it with the same arguments. This lets hosts the API server can't reach over
+// SSH stay up to date. It only returns if the update fails.
+func (agt *Agent) updateAgent(revision string) error {
+	arch := fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH)
+
+	info := &apimodels.AgentUpdateInfo{}
+	if err := agt.getAgentUpdate("agent/update/"+arch, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(info)
+	}); err != nil {
+		return errors.Wrap(err, "error getting agent update information")
+	}
+	if info.Revision != revision {
+		return errors.Errorf("API server has agent revision %s for %s, not %s", info.Revision, arch, revision)
+	}
+
+	exe, err := osext.Executable()
+	if err != nil {
+		return errors.Wrap(err, "error finding the agent's executable")
+	}
+
+	var updated string
+	if err = agt.getAgentUpdate("agent/binary/"+arch, func(body io.Reader) (writeErr error) {
+		updated, writeErr = writeVerifiedBinary(filepath.Dir(exe), body, info.SHA256)
+		return writeErr
+	}); err != nil {
+		return errors.Wrap(err, "error downloading agent")
+	}
+
+	if err = replaceExecutable(exe, updated); err != nil {
+		grip.Warning(os.Remove(updated))
+		return errors.Wrapf(err, "error replacing %s", exe)
+	}
+	grip.Noticef("updated agent at %s to revision %s, restarting", exe, revision)
+
+	// the new agent needs the status port
+	if agt.statusServer != nil {
+		grip.Warning(errors.Wrap(agt.statusServer.Close(), "error stopping status server"))
+	}
+	return errors.Wrap(reexec(exe), "error restarting agent")
+}
+
+// getAgentUpdate makes a GET request to the API server and passes the body
+// of a successful response to read.
+func (agt *Agent) getAgentUpdate(path string, read func(io.Reader) error) error {
+	resp, err := agt.TryGet(path)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if resp == nil {
+		return errors.New("empty response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return read(resp.Body)
+}
+
+// writeVerifiedBinary writes the binary to a new executable file in dir,
+// which should be on the same filesystem as the binary it replaces, and
+// returns its path. It fails if the binary's SHA-256 checksum isn't the
+// expected one.
+func writeVerifiedBinary(dir string, binary io.Reader, expectedSHA256 string) (string, error) {
+	f, err := ioutil.TempFile(dir, ".agent-update-")
+	if err != nil {
+		return "", errors.Wrap(err, "error creating file for agent update")
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hash), binary)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		if sum := hex.EncodeToString(hash.Sum(nil)); sum != expectedSHA256 {
+			err = errors.Errorf("checksum of downloaded agent is %s, expected %s", sum, expectedSHA256)
+		}
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0755)
+	}
+
+	if err != nil {
+		grip.Warning(os.Remove(f.Name()))
+		return "", errors.WithStack(err)
+	}
+	return f.Name(), nil
+}
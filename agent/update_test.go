@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteVerifiedBinary(t *testing.T) {
+	Convey("When writing a downloaded agent binary", t, func() {
+		dir, err := ioutil.TempDir("", "agent-update")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		binary := []byte("agent binary")
+		hash := sha256.Sum256(binary)
+		sum := hex.EncodeToString(hash[:])
+
+		Convey("a binary with the expected checksum should be written as an executable", func() {
+			path, err := writeVerifiedBinary(dir, bytes.NewReader(binary), sum)
+			So(err, ShouldBeNil)
+			written, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(written, ShouldResemble, binary)
+			if runtime.GOOS != "windows" {
+				info, err := os.Stat(path)
+				So(err, ShouldBeNil)
+				So(info.Mode()&0100, ShouldNotEqual, 0)
+			}
+		})
+
+		Convey("a binary with the wrong checksum should be rejected and removed", func() {
+			_, err := writeVerifiedBinary(dir, bytes.NewReader([]byte("tampered")), sum)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "checksum")
+			files, err := ioutil.ReadDir(dir)
+			So(err, ShouldBeNil)
+			So(files, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,21 @@
+// +build !windows
+
+package agent
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// replaceExecutable atomically moves the updated binary over the running one.
+func replaceExecutable(exe, updated string) error {
+	return errors.WithStack(os.Rename(updated, exe))
+}
+
+// reexec replaces the agent's process with the binary, keeping its pid,
+// arguments and environment.
+func reexec(exe string) error {
+	return errors.WithStack(syscall.Exec(exe, os.Args, os.Environ()))
+}
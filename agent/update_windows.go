@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// replaceExecutable moves the updated binary over the running one. Windows
+// won't replace a running executable, but it will rename it.
+func replaceExecutable(exe, updated string) error {
+	old := exe + ".old"
+	if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error removing %s", old)
+	}
+	if err := os.Rename(exe, old); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.Rename(updated, exe); err != nil {
+		// put the running binary back so that the agent can still be restarted
+		_ = os.Rename(old, exe)
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// reexec starts the binary with the agent's arguments, and exits once it has
+// started, since Windows can't replace a running process.
+func reexec(exe string) error {
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return errors.WithStack(err)
+	}
+	os.Exit(0)
+	return nil
+}
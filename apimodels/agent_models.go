@@ -1,5 +1,7 @@
 package apimodels
 
+import "time"
+
 // TaskStartRequest holds information sent by the agent to the
 // API server at the beginning of each task run.
 type TaskStartRequest struct {
@@ -19,6 +21,28 @@ type TaskEndDetail struct {
 	Type        string `bson:"type,omitempty" json:"type,omitempty"`
 	Description string `bson:"desc,omitempty" json:"desc,omitempty"`
 	TimedOut    bool   `bson:"timed_out,omitempty" json:"timed_out,omitempty"`
+
+	// Commands traces each command the agent ran for the task, in order.
+	Commands []CommandTrace `bson:"commands,omitempty" json:"commands,omitempty"`
+}
+
+// CommandTrace records a single command invocation made while running a task.
+type CommandTrace struct {
+	// Name is the command's identifier, e.g. shell.exec.
+	Name        string `bson:"name" json:"name"`
+	DisplayName string `bson:"display_name,omitempty" json:"display_name,omitempty"`
+	// Function is the project function the command was run as part of, if any.
+	Function string `bson:"func,omitempty" json:"func,omitempty"`
+	// Type is the command type (setup, system or test) it ran as.
+	Type  string    `bson:"type,omitempty" json:"type,omitempty"`
+	Start time.Time `bson:"start" json:"start"`
+	End   time.Time `bson:"end,omitempty" json:"end,omitempty"`
+	// Status is the command's outcome: success or failed. It is empty if
+	// the command did not finish.
+	Status string `bson:"status,omitempty" json:"status,omitempty"`
+	// Error is the error the command failed with, such as its exit status.
+	Error    string `bson:"error,omitempty" json:"error,omitempty"`
+	TimedOut bool   `bson:"timed_out,omitempty" json:"timed_out,omitempty"`
 }
 
 type TaskEndDetails struct {
@@ -45,6 +69,31 @@ type NextTaskResponse struct {
 	TaskSecret string `json:"task_secret,omitempty"`
 	ShouldExit bool   `json:"should_exit,omitempty"`
 	Message    string `json:"message,omitempty"`
+	// AgentRevision is set if the agent is out of date and should update
+	// itself to the revision before running another task.
+	AgentRevision string `json:"agent_revision,omitempty"`
+}
+
+// AgentUpdateInfo describes the compiled agent an agent downloads to update
+// itself.
+type AgentUpdateInfo struct {
+	Revision string `json:"revision"`
+	Arch     string `json:"arch"`
+	// SHA256 is the hex-encoded checksum of the binary.
+	SHA256 string `json:"sha256"`
+}
+
+// HostUnhealthyRequest is sent by the agent when its host is not fit to run
+// tasks, even after the agent has cleaned it up.
+type HostUnhealthyRequest struct {
+	Reasons []string `json:"reasons"`
+}
+
+// LogStreamAck is sent by the API server over a task's log stream when it has
+// stored every batch of log messages up to and including Seq. The first one
+// is sent when the stream connects.
+type LogStreamAck struct {
+	Seq int64 `json:"seq"`
 }
 
 // EndTaskResponse is what is returned when the task ends
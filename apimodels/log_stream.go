@@ -0,0 +1,71 @@
+package apimodels
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// LogBatchHeaderSize is the size of the sequence number that starts every
+// batch of log messages the agent streams to the API server.
+const LogBatchHeaderSize = 8
+
+// MaxLogBatchBytes is the largest a batch of log messages may be once
+// decompressed. It is the most the database can store as one task log.
+const MaxLogBatchBytes = 16 * 1024 * 1024
+
+// EncodeLogBatch returns the frame a batch of log messages is streamed as:
+// its sequence number, followed by its compressed messages.
+func EncodeLogBatch(seq int64, data []byte) []byte {
+	frame := make([]byte, LogBatchHeaderSize+len(data))
+	binary.BigEndian.PutUint64(frame, uint64(seq))
+	copy(frame[LogBatchHeaderSize:], data)
+	return frame
+}
+
+// DecodeLogBatch parses a streamed frame into its sequence number and
+// compressed messages.
+func DecodeLogBatch(frame []byte) (int64, []byte, error) {
+	if len(frame) < LogBatchHeaderSize {
+		return 0, nil, errors.Errorf("log batch of %d bytes is too short", len(frame))
+	}
+	return int64(binary.BigEndian.Uint64(frame)), frame[LogBatchHeaderSize:], nil
+}
+
+// CompressLogBatch encodes a batch of log messages as gzipped JSON.
+func CompressLogBatch(messages interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if err := json.NewEncoder(zw).Encode(messages); err != nil {
+		return nil, errors.Wrap(err, "error compressing log messages")
+	}
+	if err := zw.Close(); err != nil {
+		return nil, errors.Wrap(err, "error compressing log messages")
+	}
+	return buf.Bytes(), nil
+}
+
+// DecompressLogBatch decodes a batch of log messages compressed by
+// CompressLogBatch into out. Batches larger than MaxLogBatchBytes once
+// decompressed are refused.
+func DecompressLogBatch(data []byte, out interface{}) error {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "error decompressing log messages")
+	}
+	defer zr.Close()
+
+	decompressed, err := ioutil.ReadAll(io.LimitReader(zr, MaxLogBatchBytes+1))
+	if err != nil {
+		return errors.Wrap(err, "error decompressing log messages")
+	}
+	if len(decompressed) > MaxLogBatchBytes {
+		return errors.Errorf("log batch is larger than %d bytes", MaxLogBatchBytes)
+	}
+	return errors.Wrap(json.Unmarshal(decompressed, out), "error decoding log messages")
+}
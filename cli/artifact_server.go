@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// artifactSecretEnv is the environment variable the artifact server reads its
+// secret from, so that it doesn't show up in the server's command line.
+const artifactSecretEnv = "EVERGREEN_ARTIFACT_SECRET"
+
+// ArtifactServerCommand serves the files of an HTTP artifact store from a
+// directory. Projects using the store set its address as the endpoint, and
+// the key and secret as their credentials.
+type ArtifactServerCommand struct {
+	Root       string `short:"r" long:"root" description:"directory to keep the stored files in" required:"true"`
+	Listen     string `short:"l" long:"listen" default:":9000" description:"address to listen on"`
+	Key        string `short:"k" long:"key" description:"user name clients must authenticate with (the secret is read from $EVERGREEN_ARTIFACT_SECRET)" required:"true"`
+	PublicRead bool   `long:"public-read" description:"let files be downloaded without authenticating"`
+	TLSCert    string `long:"tls-cert" description:"path to a TLS certificate to serve with"`
+	TLSKey     string `long:"tls-key" description:"path to the TLS certificate's private key"`
+}
+
+func (ac *ArtifactServerCommand) Execute(_ []string) error {
+	secret := os.Getenv(artifactSecretEnv)
+	if secret == "" {
+		return errors.Errorf("the server's secret must be set in $%s", artifactSecretEnv)
+	}
+	if (ac.TLSCert == "") != (ac.TLSKey == "") {
+		return errors.New("tls-cert and tls-key must be set together")
+	}
+	if err := os.MkdirAll(ac.Root, 0755); err != nil {
+		return errors.Wrapf(err, "error creating %s", ac.Root)
+	}
+
+	server := &artifactstore.FileServer{
+		Root:       ac.Root,
+		Key:        ac.Key,
+		Secret:     secret,
+		PublicRead: ac.PublicRead,
+	}
+	if ac.TLSCert == "" {
+		grip.Warning("serving artifacts without TLS; credentials are sent in the clear")
+		grip.Infof("serving artifacts from %s on %s", ac.Root, ac.Listen)
+		return errors.WithStack(http.ListenAndServe(ac.Listen, server))
+	}
+	grip.Infof("serving artifacts from %s on %s with TLS", ac.Root, ac.Listen)
+	return errors.WithStack(http.ListenAndServeTLS(ac.Listen, ac.TLSCert, ac.TLSKey, server))
+}
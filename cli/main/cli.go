@@ -27,6 +27,8 @@ func main() {
 	parser.AddCommand("evaluate", "display a project file's evaluated and expanded form", "", &cli.EvaluateCommand{})
 	parser.AddCommand("fetch", "fetch data associated with a task", "", &cli.FetchCommand{GlobalOpts: &opts})
 	parser.AddCommand("export", "export statistics as csv or json for given options", "", &cli.ExportCommand{GlobalOpts: &opts})
+	parser.AddCommand("run-local", "run a task from a project file on this machine", "", &cli.RunLocalCommand{})
+	parser.AddCommand("artifact-server", "serve the files of an http artifact store", "", &cli.ArtifactServerCommand{})
 	parser.AddCommand("test-history", "retrieve test history for a given project", "", &cli.TestHistoryCommand{GlobalOpts: &opts})
 
 	_, err := parser.Parse()
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/agent"
+	"github.com/evergreen-ci/evergreen/agent/comm"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// RunLocalCommand runs a task from a project file on the local machine,
+// without an API server, the same way the agent runs it on a host.
+type RunLocalCommand struct {
+	Project    string `short:"p" long:"project" description:"path to the project file" required:"true"`
+	Variant    string `short:"v" long:"variant" description:"variant to run the task on" required:"true"`
+	Task       string `short:"t" long:"task" description:"task to run" required:"true"`
+	Expansions string `short:"e" long:"expansions" description:"path to a YAML file of expansions"`
+	Checkout   string `long:"checkout" default:"." description:"git checkout of the project to run against"`
+	WorkDir    string `long:"workdir" description:"directory to run the task in (defaults to a temporary directory)"`
+	Output     string `short:"o" long:"output" default:"evergreen-local" description:"directory to write logs, test results and artifacts to"`
+}
+
+func (rc *RunLocalCommand) Execute(_ []string) error {
+	workDir := rc.WorkDir
+	if workDir == "" {
+		tmp, err := ioutil.TempDir("", "evergreen-local")
+		if err != nil {
+			return errors.Wrap(err, "error creating work directory")
+		}
+		defer os.RemoveAll(tmp)
+		workDir = tmp
+	}
+
+	agt, fc, err := agent.NewLocal(agent.LocalOptions{
+		ProjectPath:    rc.Project,
+		Variant:        rc.Variant,
+		TaskName:       rc.Task,
+		ExpansionsPath: rc.Expansions,
+		CheckoutDir:    rc.Checkout,
+		WorkDir:        workDir,
+		OutputDir:      rc.Output,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error setting up local agent")
+	}
+	defer func() { grip.CatchError(fc.Close()) }()
+
+	if _, err = agt.RunTask(); err != nil {
+		return errors.Wrap(err, "error running task")
+	}
+
+	detail := fc.EndDetail()
+	if detail == nil {
+		return errors.New("task did not report a final status")
+	}
+	fmt.Printf("Task %s finished with status '%s'; output is in %s\n", rc.Task, detail.Status, fc.OutputDir)
+	fmt.Printf("Test results: %s, artifacts: %s, stored files: %s\n",
+		comm.FileResultsName, comm.FileArtifactsName, comm.FileStoreDir)
+	if detail.Status != evergreen.TaskSucceeded {
+		return errors.Errorf("task failed: %s", detail.Description)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package cloud
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/host"
+)
+
+// BootstrapOptions configures the script returned by BootstrapScript.
+type BootstrapOptions struct {
+	// HostIdCommand, if set, is a shell command that prints the host's id.
+	// Providers that only learn the host's id once its instance is created,
+	// such as EC2, use it in place of the intent host's id.
+	HostIdCommand string
+
+	// Detach makes the script exit once the agent has started. Otherwise the
+	// script replaces itself with the agent, as a container entrypoint must.
+	Detach bool
+
+	// User, if set, is who the agent runs as, by su, and who owns the working
+	// directory. Providers whose scripts run as root, such as EC2's user data,
+	// set it to the distro's user.
+	User string
+}
+
+const (
+	// bootstrapDownloadAttempts and bootstrapDownloadInterval bound how long
+	// the script keeps trying to download the agent. Any error is retried,
+	// since the API server answers with an error until the host is in its
+	// database, which may be after the instance has started.
+	bootstrapDownloadAttempts = 30
+	bootstrapDownloadInterval = 10 * time.Second
+)
+
+// BootstrapScript returns a shell script that sets up a host whose distro uses
+// distro.BootstrapMethodUserData and starts its agent, for providers to run as
+// the host's user data or container entrypoint. The script runs the distro's
+// setup script, downloads the agent from the API server using the host's id
+// and secret, and starts it. The agent then registers the host with the API
+// server.
+func BootstrapScript(settings *evergreen.Settings, h *host.Host, opts BootstrapOptions) string {
+	workDir := h.Distro.WorkDir
+	binary := path.Join(workDir, "evergreen-agent")
+	binaryURL := fmt.Sprintf("%s/api/%d/agent/binary/%s", strings.TrimRight(settings.ApiUrl, "/"),
+		evergreen.AgentAPIVersion, h.Distro.Arch)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "#!/bin/sh")
+	if h.Distro.Setup != "" {
+		fmt.Fprintln(buf, "(")
+		fmt.Fprintln(buf, h.Distro.Setup)
+		fmt.Fprintln(buf, ") || exit 1")
+	}
+	fmt.Fprintln(buf, "set -e")
+	if opts.HostIdCommand != "" {
+		fmt.Fprintf(buf, "host_id=$(%s)\n", opts.HostIdCommand)
+	} else {
+		fmt.Fprintf(buf, "host_id=%s\n", shellQuote(h.Id))
+	}
+	fmt.Fprintf(buf, "mkdir -p %s\n", shellQuote(workDir))
+	fmt.Fprintln(buf, "attempts=0")
+	fmt.Fprintf(buf, "until curl --fail --silent --show-error --location -H %s -H %s -o %s %s; do\n",
+		`"`+evergreen.HostHeader+`: $host_id"`, shellQuote(evergreen.HostSecretHeader+": "+h.Secret),
+		shellQuote(binary), shellQuote(binaryURL))
+	fmt.Fprintln(buf, "  attempts=$((attempts + 1))")
+	fmt.Fprintf(buf, "  if [ $attempts -ge %d ]; then exit 1; fi\n", bootstrapDownloadAttempts)
+	fmt.Fprintf(buf, "  sleep %d\n", int(bootstrapDownloadInterval.Seconds()))
+	fmt.Fprintln(buf, "done")
+	fmt.Fprintf(buf, "chmod +x %s\n", shellQuote(binary))
+	if opts.User != "" {
+		fmt.Fprintf(buf, "chown -R %s %s\n", shellQuote(opts.User), shellQuote(workDir))
+	}
+	fmt.Fprintf(buf, "cd %s\n", shellQuote(workDir))
+
+	agentCmd := fmt.Sprintf(`%s -api_server %s -host_id "$host_id" -host_secret %s -log_prefix %s -working_dir %s -register`,
+		shellQuote(binary), shellQuote(settings.ApiUrl), shellQuote(h.Secret),
+		shellQuote(path.Join(workDir, "agent")), shellQuote(workDir))
+	if sumoEndpoint, ok := settings.Credentials["sumologic"]; ok {
+		fmt.Fprintf(buf, "export GRIP_SUMO_ENDPOINT=%s\n", shellQuote(sumoEndpoint))
+	}
+	if opts.User != "" {
+		// su keeps the environment, so the user's shell expands the host id
+		fmt.Fprintln(buf, "export host_id")
+		agentCmd = fmt.Sprintf("su -s /bin/sh %s -c %s", shellQuote(opts.User), shellQuote("exec "+agentCmd))
+	}
+	if opts.Detach {
+		fmt.Fprintf(buf, "nohup %s > /dev/null 2>&1 &\n", agentCmd)
+	} else {
+		fmt.Fprintf(buf, "exec %s\n", agentCmd)
+	}
+
+	return buf.String()
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,67 @@
+package cloud
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIntentSecret(t *testing.T) {
+	assert := assert.New(t)
+
+	d := distro.Distro{Id: "d1"}
+	assert.Empty(NewIntent(d, "h1", "mock", HostOptions{}).Secret)
+
+	d.BootstrapMethod = distro.BootstrapMethodUserData
+	h := NewIntent(d, "h2", "mock", HostOptions{})
+	assert.NotEmpty(h.Secret)
+	assert.NotEqual(h.Secret, NewIntent(d, "h3", "mock", HostOptions{}).Secret)
+}
+
+func TestBootstrapScript(t *testing.T) {
+	assert := assert.New(t)
+
+	settings := &evergreen.Settings{ApiUrl: "https://evergreen.example.com/"}
+	h := &host.Host{
+		Id:     "h1",
+		Secret: "it's-secret",
+		Distro: distro.Distro{
+			Arch:    "linux_amd64",
+			WorkDir: "/data/mci",
+			Setup:   "echo setting up",
+		},
+	}
+
+	script := BootstrapScript(settings, h, BootstrapOptions{Detach: true})
+	assert.True(strings.HasPrefix(script, "#!/bin/sh\n"))
+	assert.True(strings.Index(script, "echo setting up") < strings.Index(script, "curl"))
+	assert.Contains(script, "'https://evergreen.example.com/api/2/agent/binary/linux_amd64'")
+	assert.Contains(script, "host_id='h1'\n")
+	assert.Contains(script, `-H "Host-Id: $host_id" -H 'Host-Secret: it'\''s-secret'`)
+	assert.Contains(script, `nohup '/data/mci/evergreen-agent' -api_server 'https://evergreen.example.com/' -host_id "$host_id"`)
+	assert.Contains(script, " -register > /dev/null 2>&1 &\n")
+	assert.NotContains(script, "GRIP_SUMO_ENDPOINT")
+
+	h.Distro.Setup = ""
+	settings.Credentials = map[string]string{"sumologic": "https://sumo.example.com"}
+	script = BootstrapScript(settings, h, BootstrapOptions{HostIdCommand: "hostname"})
+	assert.NotContains(script, "(\n")
+	assert.Contains(script, "host_id=$(hostname)\n")
+	assert.Contains(script, "export GRIP_SUMO_ENDPOINT='https://sumo.example.com'\n")
+	assert.Contains(script, "exec '/data/mci/evergreen-agent'")
+	assert.True(strings.HasSuffix(script, " -register\n"))
+	assert.Contains(script, "until curl --fail")
+	assert.Contains(script, "if [ $attempts -ge 30 ]; then exit 1; fi\n")
+	assert.NotContains(script, "su ")
+
+	script = BootstrapScript(settings, h, BootstrapOptions{Detach: true, User: "ec2-user"})
+	assert.Contains(script, "chown -R 'ec2-user' '/data/mci'\n")
+	assert.Contains(script, "export host_id\n")
+	assert.Contains(script, `nohup su -s /bin/sh 'ec2-user' -c 'exec '\''/data/mci/evergreen-agent'\'' -api_server`)
+	assert.Contains(script, ` -host_id "$host_id" `)
+	assert.True(strings.HasSuffix(script, " -register' > /dev/null 2>&1 &\n"))
+}
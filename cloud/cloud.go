@@ -122,6 +122,13 @@ type HostOptions struct {
 	UserName           string
 	UserData           string
 	UserHost           bool
+
+	// ProjectId, TaskId and CostCenter identify who the host is used by and
+	// for, and are added to the tags of the host's instance along with Tags.
+	ProjectId  string
+	TaskId     string
+	CostCenter string
+	Tags       map[string]string
 }
 
 // NewIntent creates an IntentHost using the given host settings. An IntentHost is a host that
@@ -158,11 +165,46 @@ func NewIntent(d distro.Distro, instanceName, provider string, options HostOptio
 	if options.UserData != "" {
 		intentHost.UserData = options.UserData
 	}
+	intentHost.InstanceTags = makeHostTags(options)
+
+	// a host that starts its own agent is created knowing its secret, since
+	// nothing connects to it to hand it one
+	if !d.BootstrapsOverSSH() {
+		intentHost.Secret = util.RandomString()
+	}
 
 	return intentHost
 
 }
 
+// makeHostTags returns the tags that identify the owner of a new host, for
+// providers to add to the host's instance.
+func makeHostTags(options HostOptions) map[string]string {
+	tags := map[string]string{}
+	for k, v := range options.Tags {
+		tags[k] = v
+	}
+
+	taskId := options.TaskId
+	if taskId == "" && options.ProvisionOptions != nil {
+		taskId = options.ProvisionOptions.TaskId
+	}
+	if taskId != "" {
+		tags[TagTask] = taskId
+	}
+	if options.ProjectId != "" {
+		tags[TagProject] = options.ProjectId
+	}
+	if options.CostCenter != "" {
+		tags[TagCostCenter] = options.CostCenter
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
 //CloudHost is a provider-agnostic host object that delegates methods
 //like status checks, ssh options, DNS name checks, termination, etc. to the
 //underlying provider's implementation.
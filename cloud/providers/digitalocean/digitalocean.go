@@ -72,7 +72,8 @@ func (_ *DigitalOceanManager) GetSettings() cloud.ProviderSettings {
 	return &Settings{}
 }
 
-//SpawnInstance creates a new droplet for the given distro.
+//SpawnInstance creates a new droplet for the given distro. The droplet is
+//not tagged, since version 1 of the DigitalOcean API has no tags.
 func (digoMgr *DigitalOceanManager) SpawnInstance(d *distro.Distro, hostOpts cloud.HostOptions) (*host.Host, error) {
 	if d.Provider != ProviderName {
 		return nil, errors.Errorf("Can't spawn instance of %v for distro %v: provider is %v",
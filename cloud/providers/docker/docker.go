@@ -31,6 +31,7 @@ const (
 )
 
 type DockerManager struct {
+	settings *evergreen.Settings
 }
 
 type portRange struct {
@@ -238,23 +239,49 @@ func (dockerMgr *DockerManager) SpawnInstance(d *distro.Distro, hostOpts cloud.H
 		return nil, err
 	}
 
+	// Build the host document first, so the container can be labeled with it
+	instanceName := "container-" +
+		fmt.Sprintf("%d", rand.New(rand.NewSource(time.Now().UnixNano())).Int())
+	intentHost := cloud.NewIntent(*d, instanceName, ProviderName, hostOpts)
+
+	// record the host before starting its container, since a container
+	// that starts its own agent identifies itself to the API server as it
+	// boots
+	if err = intentHost.Insert(); err != nil {
+		err = errors.Wrapf(err, "failed to insert new host '%s'", intentHost.Id)
+		grip.Error(err)
+		return nil, err
+	}
+
+	// a container that starts its own agent runs it as its entrypoint,
+	// rather than waiting for it to be started over SSH
+	cmd := []string{"/usr/sbin/sshd", "-D"}
+	if !d.BootstrapsOverSSH() {
+		cmd = []string{"/bin/sh", "-c", cloud.BootstrapScript(dockerMgr.settings, intentHost, cloud.BootstrapOptions{})}
+	}
+
 	// Build container
 	containerName := "docker-" + bson.NewObjectId().Hex()
 	newContainer, err := dockerClient.CreateContainer(
 		docker.CreateContainerOptions{
 			Name: containerName,
 			Config: &docker.Config{
-				Cmd: []string{"/usr/sbin/sshd", "-D"},
+				Cmd: cmd,
 				ExposedPorts: map[docker.Port]struct{}{
 					SSHDPort: {},
 				},
-				Image: settings.ImageId,
+				Image:  settings.ImageId,
+				Labels: cloud.MakeTags(intentHost),
 			},
 			HostConfig: hostConfig,
 		},
 	)
 	if err != nil {
 		err = errors.Wrapf(err, "Docker create container API call failed for host '%s'", settings.HostIp)
+		if err2 := intentHost.Remove(); err2 != nil {
+			err = errors.Errorf("create container error: %+v;\nunable to remove host '%s': %+v",
+				err, intentHost.Id, err2)
+		}
 		grip.Error(err)
 		return nil, err
 	}
@@ -273,6 +300,10 @@ func (dockerMgr *DockerManager) SpawnInstance(d *distro.Distro, hostOpts cloud.H
 		if err2 != nil {
 			err = errors.Errorf("start container error: %+v;\nunable to cleanup: %+v", err, err2)
 		}
+		if err2 = intentHost.Remove(); err2 != nil {
+			err = errors.Errorf("start container error: %+v;\nunable to remove host '%s': %+v",
+				err, intentHost.Id, err2)
+		}
 		grip.Error(err)
 		return nil, err
 	}
@@ -293,13 +324,7 @@ func (dockerMgr *DockerManager) SpawnInstance(d *distro.Distro, hostOpts cloud.H
 
 	hostStr := fmt.Sprintf("%s:%s", settings.BindIp, hostPort)
 	// Add host info to db
-	instanceName := "container-" +
-		fmt.Sprintf("%d", rand.New(rand.NewSource(time.Now().UnixNano())).Int())
-
-	intentHost := cloud.NewIntent(*d, instanceName, ProviderName, hostOpts)
-	intentHost.Host = hostStr
-
-	err = errors.Wrapf(intentHost.Insert(), "failed to insert new host '%s'", intentHost.Id)
+	err = errors.Wrapf(intentHost.SetDNSName(hostStr), "failed to set address of host '%s'", intentHost.Id)
 	if err != nil {
 		grip.Error(err)
 		return nil, err
@@ -395,6 +420,7 @@ func (dockerMgr *DockerManager) TerminateInstance(host *host.Host) error {
 //Configure populates a DockerManager by reading relevant settings from the
 //config object.
 func (dockerMgr *DockerManager) Configure(settings *evergreen.Settings) error {
+	dockerMgr.settings = settings
 	return nil
 }
 
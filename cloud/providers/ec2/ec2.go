@@ -20,8 +20,13 @@ import (
 // EC2Manager implements the CloudManager interface for Amazon EC2
 type EC2Manager struct {
 	awsCredentials *aws.Auth
+	settings       *evergreen.Settings
 }
 
+// instanceIdCommand prints the id of the EC2 instance it runs on, which is
+// the id of the instance's host.
+const instanceIdCommand = "curl --fail --silent --retry 10 http://169.254.169.254/latest/meta-data/instance-id"
+
 //Valid values for EC2 instance states:
 //pending | running | shutting-down | terminated | stopping | stopped
 //see http://goo.gl/3OrCGn
@@ -80,6 +85,7 @@ func (cloudManager *EC2Manager) Configure(settings *evergreen.Settings) error {
 		AccessKey: settings.Providers.AWS.Id,
 		SecretKey: settings.Providers.AWS.Secret,
 	}
+	cloudManager.settings = settings
 	return nil
 }
 
@@ -162,6 +168,14 @@ func (cloudManager *EC2Manager) SpawnInstance(d *distro.Distro, hostOpts cloud.H
 		BlockDevices:   blockDevices,
 	}
 
+	// a host that starts its own agent does so from its user data, which
+	// runs as root. The host is renamed to its instance id once it starts, so
+	// the script asks the instance for its id.
+	if !d.BootstrapsOverSSH() {
+		options.UserData = []byte(cloud.BootstrapScript(cloudManager.settings, intentHost,
+			cloud.BootstrapOptions{HostIdCommand: instanceIdCommand, Detach: true, User: d.User}))
+	}
+
 	// if it's a Vpc override the options to be the correct VPC settings.
 	if ec2Settings.IsVpc {
 		options.SecurityGroups = ec2.SecurityGroupIds(ec2Settings.SecurityGroup)
@@ -250,6 +264,46 @@ func (cloudManager *EC2Manager) TerminateInstance(host *host.Host) error {
 	return host.Terminate()
 }
 
+// ListManagedInstances returns the on-demand instances that were created by
+// Evergreen and have not been terminated.
+func (cloudManager *EC2Manager) ListManagedInstances() ([]cloud.ManagedInstance, error) {
+	filter := ec2.NewFilter()
+	filter.Add("tag:"+cloud.TagManagedBy, cloud.ManagedByEvergreen)
+	filter.Add("tag:"+cloud.TagProvider, OnDemandProviderName)
+	filter.Add("instance-state-name", EC2StatusPending, EC2StatusRunning, EC2StatusStopped)
+
+	ec2Handle := getUSEast(*cloudManager.awsCredentials)
+	resp, err := ec2Handle.DescribeInstances(nil, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing instances")
+	}
+
+	managed := []cloud.ManagedInstance{}
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			tags := map[string]string{}
+			for _, tag := range instance.Tags {
+				tags[tag.Key] = tag.Value
+			}
+			launched, _ := time.Parse(time.RFC3339, instance.LaunchTime)
+			managed = append(managed, cloud.ManagedInstance{
+				Id:        instance.InstanceId,
+				Location:  instance.AvailabilityZone,
+				Tags:      tags,
+				CreatedAt: launched,
+			})
+		}
+	}
+	return managed, nil
+}
+
+// TerminateManagedInstance terminates an instance that has no host.
+func (cloudManager *EC2Manager) TerminateManagedInstance(instance cloud.ManagedInstance) error {
+	ec2Handle := getUSEast(*cloudManager.awsCredentials)
+	_, err := ec2Handle.TerminateInstances([]string{instance.Id})
+	return errors.Wrapf(err, "error terminating instance %s", instance.Id)
+}
+
 // determine how long until a payment is due for the host
 func (cloudManager *EC2Manager) TimeTilNextPayment(host *host.Host) time.Duration {
 	return timeTilNextEC2Payment(host)
@@ -6,8 +6,6 @@ import (
 	"math"
 	"net"
 	"net/http"
-	"os"
-	"os/user"
 	"regexp"
 	"strconv"
 	"strings"
@@ -29,8 +27,8 @@ const (
 	NameTimeFormat       = "20060102150405"
 	OnDemandProviderName = "ec2"
 	SpotProviderName     = "ec2-spot"
-	SpawnHostExpireDays  = 90
-	MciHostExpireDays    = 30
+	SpawnHostExpireDays  = cloud.SpawnHostExpireDays
+	MciHostExpireDays    = cloud.HostExpireDays
 )
 
 type MountPoint struct {
@@ -205,56 +203,13 @@ func ec2StatusToEvergreenStatus(ec2Status string) cloud.CloudStatus {
 	}
 }
 
-// expireInDays creates an expire-on string in the format YYYY-MM-DD for numDays days
-// in the future.
-func expireInDays(numDays int) string {
-	return time.Now().AddDate(0, 0, numDays).Format("2006-01-02")
-}
-
 //makeTags populates a map of tags based on a host object, which contain keys
-//for the user, owner, hostname, and if it's a spawnhost or not.
+//for the user, owner, hostname, and if it's a spawnhost or not. The expire-on
+//tag is required by MongoDB's AWS reaping policy, an external script that
+//terminates every ec2 instance whose expire-on tag has passed, so that hosts
+//we forget about or that fail to terminate do not stay alive forever.
 func makeTags(intentHost *host.Host) map[string]string {
-	// get requester host name
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = "unknown"
-	}
-
-	// get requester user name
-	var username string
-	user, err := user.Current()
-	if err != nil {
-		username = "unknown"
-	} else {
-		username = user.Name
-	}
-
-	// The expire-on tag is required by MongoDB's AWS reaping policy.
-	// The reaper is an external script that scans every ec2 instance for an expire-on tag,
-	// and if that tag is passed the reaper terminates the host. This reaping occurs to
-	// ensure that any hosts that we forget about or that fail to terminate do not stay alive
-	// forever.
-	expireOn := expireInDays(MciHostExpireDays)
-	if intentHost.UserHost {
-		// If this is a spawn host, use a different expiration date.
-		expireOn = expireInDays(SpawnHostExpireDays)
-	}
-
-	tags := map[string]string{
-		"name":              intentHost.Id,
-		"distro":            intentHost.Distro.Id,
-		"evergreen-service": hostname,
-		"username":          username,
-		"owner":             intentHost.StartedBy,
-		"mode":              "production",
-		"start-time":        intentHost.CreationTime.Format(NameTimeFormat),
-		"expire-on":         expireOn,
-	}
-
-	if intentHost.UserHost {
-		tags["mode"] = "testing"
-	}
-	return tags
+	return cloud.MakeTags(intentHost)
 }
 
 //attachTags makes a call to EC2 to attach the given map of tags to a resource.
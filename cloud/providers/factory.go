@@ -6,9 +6,11 @@ import (
 	"github.com/evergreen-ci/evergreen/cloud/providers/digitalocean"
 	"github.com/evergreen-ci/evergreen/cloud/providers/docker"
 	"github.com/evergreen-ci/evergreen/cloud/providers/ec2"
+	"github.com/evergreen-ci/evergreen/cloud/providers/gce"
 	"github.com/evergreen-ci/evergreen/cloud/providers/mock"
 	"github.com/evergreen-ci/evergreen/cloud/providers/openstack"
 	"github.com/evergreen-ci/evergreen/cloud/providers/static"
+	"github.com/evergreen-ci/evergreen/cloud/providers/vsphere"
 	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/pkg/errors"
 )
@@ -33,6 +35,10 @@ func GetCloudManager(providerName string, settings *evergreen.Settings) (cloud.C
 		provider = &docker.DockerManager{}
 	case openstack.ProviderName:
 		provider = &openstack.Manager{}
+	case gce.ProviderName:
+		provider = &gce.Manager{}
+	case vsphere.ProviderName:
+		provider = &vsphere.Manager{}
 	default:
 		return nil, errors.Errorf("No known provider for '%v'", providerName)
 	}
@@ -0,0 +1,394 @@
+package gce
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/hostutil"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/util"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/message"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProviderName is used to distinguish between different cloud providers.
+	ProviderName = "gce"
+
+	diskTypeStandard = "pd-standard"
+	diskTypeSSD      = "pd-ssd"
+)
+
+// Manager implements the CloudManager interface for Google Compute Engine.
+type Manager struct {
+	projectID string
+	client    client
+}
+
+// SSHKey is a public key added to an instance's metadata, allowing the named
+// user to log in with the matching private key.
+type SSHKey struct {
+	Username  string `mapstructure:"username"`
+	PublicKey string `mapstructure:"public_key"`
+}
+
+// ProviderSettings specifies the settings used to configure a host instance.
+type ProviderSettings struct {
+	Zone string `mapstructure:"zone"`
+
+	// Either a predefined machine type, or both CustomCPUs and
+	// CustomMemoryMB to use a custom machine type.
+	MachineType    string `mapstructure:"machine_type"`
+	CustomCPUs     int64  `mapstructure:"custom_cpus"`
+	CustomMemoryMB int64  `mapstructure:"custom_memory_mb"`
+
+	// Either an image name, or an image family to use the latest image in
+	// that family. ImageProject defaults to the Evergreen project.
+	ImageName    string `mapstructure:"image_name"`
+	ImageFamily  string `mapstructure:"image_family"`
+	ImageProject string `mapstructure:"image_project"`
+
+	DiskSizeGB int64  `mapstructure:"disk_size_gb"`
+	DiskType   string `mapstructure:"disk_type"`
+
+	Network     string   `mapstructure:"network"`
+	NetworkTags []string `mapstructure:"network_tags"`
+	SSHKeys     []SSHKey `mapstructure:"ssh_keys"`
+	Preemptible bool     `mapstructure:"preemptible"`
+}
+
+// Validate verifies a set of ProviderSettings.
+func (opts *ProviderSettings) Validate() error {
+	if opts.Zone == "" {
+		return errors.New("Zone must not be blank")
+	}
+
+	custom := opts.CustomCPUs != 0 || opts.CustomMemoryMB != 0
+	if opts.MachineType == "" && !custom {
+		return errors.New("Machine type or custom CPUs and memory must be specified")
+	}
+	if opts.MachineType != "" && custom {
+		return errors.New("Machine type and custom CPUs and memory cannot both be specified")
+	}
+	if custom {
+		if opts.CustomCPUs <= 0 || opts.CustomMemoryMB <= 0 {
+			return errors.New("Custom machine types need both CPUs and memory")
+		}
+		if opts.CustomCPUs != 1 && opts.CustomCPUs%2 != 0 {
+			return errors.New("Custom machine types must have 1 or an even number of CPUs")
+		}
+		if opts.CustomMemoryMB%customMemoryGranularityMB != 0 {
+			return errors.Errorf("Custom machine type memory must be a multiple of %d MB",
+				customMemoryGranularityMB)
+		}
+	}
+
+	if (opts.ImageName == "") == (opts.ImageFamily == "") {
+		return errors.New("Exactly one of image name or image family must be specified")
+	}
+
+	if opts.DiskSizeGB < 0 {
+		return errors.New("Disk size must not be negative")
+	}
+	if opts.DiskType != "" && opts.DiskType != diskTypeStandard && opts.DiskType != diskTypeSSD {
+		return errors.Errorf("Disk type must be %s or %s", diskTypeStandard, diskTypeSSD)
+	}
+
+	for _, key := range opts.SSHKeys {
+		if key.Username == "" || key.PublicKey == "" {
+			return errors.New("SSH keys need both a username and a public key")
+		}
+	}
+
+	return nil
+}
+
+// machineType returns the name of the machine type to request.
+func (opts *ProviderSettings) machineType() string {
+	if opts.MachineType != "" {
+		return opts.MachineType
+	}
+	return customMachineType(opts.CustomCPUs, opts.CustomMemoryMB)
+}
+
+// sourceImage returns the partial URL of the boot disk's image.
+func (opts *ProviderSettings) sourceImage(projectID string) string {
+	project := opts.ImageProject
+	if project == "" {
+		project = projectID
+	}
+	if opts.ImageFamily != "" {
+		return "projects/" + project + "/global/images/family/" + opts.ImageFamily
+	}
+	return "projects/" + project + "/global/images/" + opts.ImageName
+}
+
+// GetSettings returns an empty ProviderSettings struct since settings are configured on
+// instance creation.
+func (m *Manager) GetSettings() cloud.ProviderSettings {
+	return &ProviderSettings{}
+}
+
+// Configure loads the necessary credentials from the global config object.
+func (m *Manager) Configure(s *evergreen.Settings) error {
+	config := s.Providers.GCE
+
+	if m.client == nil {
+		m.client = &clientImpl{}
+	}
+
+	if err := m.client.Init(&config); err != nil {
+		return errors.Wrap(err, "Failed to initialize client connection")
+	}
+
+	m.projectID = config.ProjectID
+	return nil
+}
+
+// SpawnInstance attempts to create a new host by requesting one from the Compute Engine API.
+// Information about the intended (and eventually created) host is recorded in a DB document.
+//
+// ProviderSettings in the distro should have the following settings:
+//     - Zone:           zone to create the instance in, e.g. us-central1-a
+//     - MachineType:    predefined machine type, e.g. n1-standard-8, or
+//     - CustomCPUs:     number of vCPUs for a custom machine type, and
+//     - CustomMemoryMB: memory for a custom machine type
+//     - ImageName:      boot disk image, or
+//     - ImageFamily:    image family to take the latest boot disk image from
+//     - ImageProject:   (optional) project owning the image
+//     - DiskSizeGB:     (optional) boot disk size
+//     - DiskType:       (optional) pd-standard or pd-ssd
+//     - Network:        (optional) network name, defaults to "default"
+//     - NetworkTags:    (optional) tags used to apply firewall rules
+//     - SSHKeys:        (optional) keys to add to the instance metadata
+//     - Preemptible:    (optional) whether to request a preemptible instance
+func (m *Manager) SpawnInstance(d *distro.Distro, hostOpts cloud.HostOptions) (*host.Host, error) {
+	if d.Provider != ProviderName {
+		return nil, errors.Errorf("Can't spawn instance of %s for distro %s: provider is %s",
+			ProviderName, d.Id, d.Provider)
+	}
+
+	settings := &ProviderSettings{}
+	if err := mapstructure.Decode(d.ProviderSettings, settings); err != nil {
+		return nil, errors.Wrapf(err, "Error decoding params for distro %s", d.Id)
+	}
+
+	if err := settings.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "Invalid settings in distro %s", d.Id)
+	}
+
+	// Proactively record all information about the host we want to create. This way, if we are
+	// unable to start it, we have a way of knowing what went wrong. The instance name is also
+	// the host's ID, so there is no need to update the document once it is started.
+	name := generateName(d)
+	intentHost := cloud.NewIntent(*d, name, ProviderName, hostOpts)
+	intentHost.InstanceType = settings.machineType()
+	if err := intentHost.Insert(); err != nil {
+		err = errors.Wrapf(err, "Could not insert intent host '%s'", intentHost.Id)
+		grip.Error(err)
+		return nil, err
+	}
+	grip.Debugf("Inserted intent host '%s' for distro '%s' to signal instance spawn intent", name, d.Id)
+
+	// Start the instance, and remove the intent host document if unsuccessful.
+	if err := m.client.CreateInstance(settings.Zone, makeInstance(intentHost, settings, m.projectID)); err != nil {
+		if rmErr := intentHost.Remove(); rmErr != nil {
+			grip.Errorf("Could not remove intent host '%s': %+v", intentHost.Id, rmErr)
+		}
+		grip.Error(err)
+		return nil, errors.Wrapf(err, "Could not start new instance for distro '%s'", d.Id)
+	}
+
+	grip.Debugf("New instance: %v", message.Fields{"instance": name, "object": intentHost})
+	return intentHost, nil
+}
+
+// CanSpawn always returns true for now.
+//
+// Like other providers, quota limits may prevent new instances from starting, but there
+// is no way to know ahead of time.
+func (m *Manager) CanSpawn() (bool, error) {
+	return true, nil
+}
+
+// GetInstanceStatus gets the current operational status of the provisioned host.
+func (m *Manager) GetInstanceStatus(host *host.Host) (cloud.CloudStatus, error) {
+	zone, err := hostZone(host)
+	if err != nil {
+		return cloud.StatusUnknown, err
+	}
+
+	instance, err := m.client.GetInstance(zone, host.Id)
+	if err == errNotFound {
+		return cloud.StatusTerminated, nil
+	}
+	if err != nil {
+		return cloud.StatusUnknown, err
+	}
+
+	return gceStatusToEvgStatus(instance.Status), nil
+}
+
+// TerminateInstance requests a server previously provisioned to be removed.
+func (m *Manager) TerminateInstance(host *host.Host) error {
+	if host.Status == evergreen.HostTerminated {
+		err := errors.Errorf("Can not terminate %s - already marked as terminated!", host.Id)
+		grip.Error(err)
+		return err
+	}
+
+	zone, err := hostZone(host)
+	if err != nil {
+		return err
+	}
+
+	// an instance that no longer exists has already been terminated
+	if err = m.client.DeleteInstance(zone, host.Id); err != nil && err != errNotFound {
+		return err
+	}
+
+	return errors.WithStack(host.Terminate())
+}
+
+// IsUp checks whether the provisioned host is running.
+func (m *Manager) IsUp(host *host.Host) (bool, error) {
+	status, err := m.GetInstanceStatus(host)
+	if err != nil {
+		return false, err
+	}
+
+	return status == cloud.StatusRunning, nil
+}
+
+// OnUp does nothing since labels are attached in SpawnInstance.
+func (m *Manager) OnUp(host *host.Host) error {
+	return nil
+}
+
+// IsSSHReachable returns true if the host can successfully accept and run an SSH command.
+func (m *Manager) IsSSHReachable(host *host.Host, keyPath string) (bool, error) {
+	opts, err := m.GetSSHOptions(host, keyPath)
+	if err != nil {
+		return false, err
+	}
+
+	return hostutil.CheckSSHResponse(host, opts)
+}
+
+// GetDNSName returns the external IPv4 address of the host.
+func (m *Manager) GetDNSName(host *host.Host) (string, error) {
+	zone, err := hostZone(host)
+	if err != nil {
+		return "", err
+	}
+
+	instance, err := m.client.GetInstance(zone, host.Id)
+	if err != nil {
+		return "", err
+	}
+
+	return externalIP(instance), nil
+}
+
+// GetSSHOptions generates the command line args to be passed to SSH to allow connection
+// to the machine.
+func (m *Manager) GetSSHOptions(host *host.Host, keyPath string) ([]string, error) {
+	if keyPath == "" {
+		return []string{}, errors.New("No key specified for host")
+	}
+
+	opts := []string{"-i", keyPath}
+	for _, opt := range host.Distro.SSHOptions {
+		opts = append(opts, "-o", opt)
+	}
+
+	return opts, nil
+}
+
+// TimeTilNextPayment returns the time until the host has been up for a minute. Compute
+// Engine bills per second after the first minute, so there is no benefit to keeping a
+// host around any longer.
+func (m *Manager) TimeTilNextPayment(host *host.Host) time.Duration {
+	if util.IsZeroTime(host.CreationTime) {
+		return time.Duration(0)
+	}
+
+	tilMinimum := minimumBillingDuration - time.Since(host.CreationTime)
+	if tilMinimum < 0 {
+		return time.Duration(0)
+	}
+	return tilMinimum
+}
+
+// CostForDuration returns the cost of running a host between the given start and end times,
+// based on the list price of its machine type.
+func (m *Manager) CostForDuration(h *host.Host, start, end time.Time) (float64, error) {
+	// sanity check
+	if end.Before(start) || util.IsZeroTime(start) || util.IsZeroTime(end) {
+		return 0, errors.New("task timing data is malformed")
+	}
+
+	settings := &ProviderSettings{}
+	if err := mapstructure.Decode(h.Distro.ProviderSettings, settings); err != nil {
+		return 0, errors.Wrapf(err, "Error decoding params for host %s", h.Id)
+	}
+
+	machineType := h.InstanceType
+	if machineType == "" {
+		machineType = settings.machineType()
+	}
+
+	return instanceCost(machineType, settings.Preemptible, end.Sub(start))
+}
+
+// ListManagedInstances returns the instances in the project that were
+// created by Evergreen. Instances that are TERMINATED in Compute Engine are
+// only stopped, and still keep their disks, so they are included.
+func (m *Manager) ListManagedInstances() ([]cloud.ManagedInstance, error) {
+	filter := fmt.Sprintf("labels.%s = %s AND labels.%s = %s",
+		cloud.TagManagedBy, cloud.ManagedByEvergreen, cloud.TagProvider, ProviderName)
+	instances, err := m.client.ListInstances(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	managed := []cloud.ManagedInstance{}
+	for _, i := range instances {
+		created, _ := time.Parse(time.RFC3339, i.CreationTimestamp)
+		managed = append(managed, cloud.ManagedInstance{
+			Id:        i.Name,
+			Location:  path.Base(i.Zone),
+			Tags:      i.Labels,
+			CreatedAt: created,
+		})
+	}
+	return managed, nil
+}
+
+// TerminateManagedInstance deletes an instance that has no host.
+func (m *Manager) TerminateManagedInstance(i cloud.ManagedInstance) error {
+	if err := m.client.DeleteInstance(i.Location, i.Id); err != nil && err != errNotFound {
+		return err
+	}
+	return nil
+}
+
+// hostZone returns the zone the host was created in, as recorded in the
+// host's copy of its distro's settings.
+func hostZone(h *host.Host) (string, error) {
+	settings := &ProviderSettings{}
+	if err := mapstructure.Decode(h.Distro.ProviderSettings, settings); err != nil {
+		return "", errors.Wrapf(err, "Error decoding params for host %s", h.Id)
+	}
+	if strings.TrimSpace(settings.Zone) == "" {
+		return "", errors.Errorf("No zone recorded for host %s", h.Id)
+	}
+	return settings.Zone, nil
+}
@@ -0,0 +1,324 @@
+package gce
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultEndpoint = "https://www.googleapis.com/compute/v1/"
+	defaultTokenURI = "https://oauth2.googleapis.com/token"
+	computeScope    = "https://www.googleapis.com/auth/compute"
+	jwtGrantType    = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+	// tokens are refreshed this long before they expire
+	tokenExpiryMargin = time.Minute
+)
+
+// errNotFound is returned by the client when the requested instance does
+// not exist.
+var errNotFound = errors.New("instance not found")
+
+// The client interface wraps the Compute Engine API interaction.
+type client interface {
+	Init(*evergreen.GCEConfig) error
+	CreateInstance(zone string, i *instance) error
+	GetInstance(zone, name string) (*instance, error)
+	DeleteInstance(zone, name string) error
+	ListInstances(filter string) ([]*instance, error)
+}
+
+// clientImpl talks to the Compute Engine v1 REST API, authenticating as a
+// service account with signed JWT assertions.
+type clientImpl struct {
+	httpClient *http.Client
+	endpoint   string
+	projectID  string
+
+	clientEmail  string
+	privateKey   *rsa.PrivateKey
+	privateKeyID string
+	tokenURI     string
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// Init validates the service account credentials. It does not contact the
+// API; an access token is requested on the first call that needs one.
+func (c *clientImpl) Init(config *evergreen.GCEConfig) error {
+	if config.ProjectID == "" {
+		return errors.New("GCE project ID must not be blank")
+	}
+	if config.ClientEmail == "" {
+		return errors.New("GCE client email must not be blank")
+	}
+
+	key, err := parsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return errors.Wrap(err, "invalid GCE private key")
+	}
+
+	c.httpClient = &http.Client{Timeout: time.Minute}
+	c.endpoint = config.Endpoint
+	if c.endpoint == "" {
+		c.endpoint = defaultEndpoint
+	}
+	if !strings.HasSuffix(c.endpoint, "/") {
+		c.endpoint += "/"
+	}
+	c.tokenURI = config.TokenURI
+	if c.tokenURI == "" {
+		c.tokenURI = defaultTokenURI
+	}
+	c.projectID = config.ProjectID
+	c.clientEmail = config.ClientEmail
+	c.privateKey = key
+	c.privateKeyID = config.PrivateKeyID
+
+	return nil
+}
+
+// CreateInstance requests a new instance in the given zone.
+func (c *clientImpl) CreateInstance(zone string, i *instance) error {
+	op := &operation{}
+	err := c.do("POST", c.instancesURL(zone, ""), i, op)
+	if err != nil {
+		return errors.Wrap(err, "GCE instances.insert API call failed")
+	}
+	return errors.Wrap(op.err(), "GCE instances.insert operation failed")
+}
+
+// GetInstance requests details on a single instance, by name.
+func (c *clientImpl) GetInstance(zone, name string) (*instance, error) {
+	i := &instance{}
+	if err := c.do("GET", c.instancesURL(zone, name), nil, i); err != nil {
+		if err == errNotFound {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "GCE instances.get API call failed")
+	}
+	return i, nil
+}
+
+// DeleteInstance requests an instance previously provisioned to be removed, by name.
+func (c *clientImpl) DeleteInstance(zone, name string) error {
+	op := &operation{}
+	if err := c.do("DELETE", c.instancesURL(zone, name), nil, op); err != nil {
+		if err == errNotFound {
+			return err
+		}
+		return errors.Wrap(err, "GCE instances.delete API call failed")
+	}
+	return errors.Wrap(op.err(), "GCE instances.delete operation failed")
+}
+
+// ListInstances requests the instances in all zones that match the filter
+// expression, following pages of results until all have been read.
+func (c *clientImpl) ListInstances(filter string) ([]*instance, error) {
+	instances := []*instance{}
+	pageToken := ""
+	for {
+		q := url.Values{}
+		q.Set("filter", filter)
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		u := fmt.Sprintf("%sprojects/%s/aggregated/instances?%s", c.endpoint,
+			url.PathEscape(c.projectID), q.Encode())
+
+		page := &aggregatedInstanceList{}
+		if err := c.do("GET", u, nil, page); err != nil {
+			return nil, errors.Wrap(err, "GCE instances.aggregatedList API call failed")
+		}
+		for _, scoped := range page.Items {
+			instances = append(instances, scoped.Instances...)
+		}
+
+		if page.NextPageToken == "" {
+			return instances, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (c *clientImpl) instancesURL(zone, name string) string {
+	u := fmt.Sprintf("%sprojects/%s/zones/%s/instances", c.endpoint,
+		url.PathEscape(c.projectID), url.PathEscape(zone))
+	if name != "" {
+		u += "/" + url.PathEscape(name)
+	}
+	return u
+}
+
+// do sends an authenticated request with an optional JSON body and decodes
+// the JSON response into out.
+func (c *clientImpl) do(method, u string, in, out interface{}) error {
+	token, err := c.accessToken()
+	if err != nil {
+		return errors.Wrap(err, "could not get GCE access token")
+	}
+
+	var body []byte
+	if in != nil {
+		body, err = json.Marshal(in)
+		if err != nil {
+			return errors.Wrap(err, "could not marshal request")
+		}
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "could not read response")
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode >= 300 {
+		apiErr := &apiErrorResponse{}
+		if json.Unmarshal(respBody, apiErr) == nil && apiErr.Error.Message != "" {
+			return errors.Errorf("%s (%d)", apiErr.Error.Message, resp.StatusCode)
+		}
+		return errors.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return errors.Wrap(json.Unmarshal(respBody, out), "could not decode response")
+}
+
+// accessToken returns a cached OAuth2 access token, exchanging a newly signed
+// JWT assertion for one if the cached token is missing or about to expire.
+func (c *clientImpl) accessToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Add(tokenExpiryMargin).Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := c.signAssertion(now)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", jwtGrantType)
+	form.Set("assertion", assertion)
+	resp, err := c.httpClient.PostForm(c.tokenURI, form)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("token request failed with status %s", resp.Status)
+	}
+
+	tok := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", errors.Wrap(err, "could not decode token response")
+	}
+	if tok.AccessToken == "" {
+		return "", errors.New("token response did not contain an access token")
+	}
+
+	c.token = tok.AccessToken
+	c.tokenExpiry = now.Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// signAssertion builds a JWT asserting the service account's identity,
+// signed with its private key.
+func (c *clientImpl) signAssertion(now time.Time) (string, error) {
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if c.privateKeyID != "" {
+		header["kid"] = c.privateKeyID
+	}
+	claims := map[string]interface{}{
+		"iss":   c.clientEmail,
+		"scope": computeScope,
+		"aud":   c.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+	hash := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", errors.Wrap(err, "could not sign token assertion")
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parsePrivateKey decodes a PEM-encoded RSA private key, as found in the
+// "private_key" field of a service account's JSON key file.
+func parsePrivateKey(key string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+
+	rsaKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse private key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,145 @@
+package gce
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Hourly list prices, in USD, for N1 machine types in us-central1. Compute
+// Engine bills for vCPUs and memory separately, per second, with a one
+// minute minimum.
+const (
+	predefinedCPUPrice      = 0.031611
+	predefinedMemoryPrice   = 0.004237
+	customCPUPrice          = 0.033174
+	customMemoryPrice       = 0.004446
+	preemptibleCPUPrice     = 0.00664
+	preemptibleMemoryPrice  = 0.000892
+	customPreemptibleCPU    = 0.00698
+	customPreemptibleMemory = 0.00094
+
+	sharedCoreMicroPrice   = 0.0076
+	sharedCoreMicroPreempt = 0.0035
+	sharedCoreSmallPrice   = 0.0257
+	sharedCoreSmallPreempt = 0.007
+
+	minimumBillingDuration = time.Minute
+)
+
+const (
+	customMachineTypePrefix = "custom-"
+	n1MachineTypePrefix     = "n1-"
+	sharedCoreMicroType     = "f1-micro"
+	sharedCoreSmallType     = "g1-small"
+
+	// gigabytes of memory per vCPU of each N1 machine type family
+	standardMemoryPerCPU   = 3.75
+	highMemoryMemoryPerCPU = 6.5
+	highCPUMemoryPerCPU    = 0.9
+
+	megabytesPerGigabyte      = 1024.0
+	customMemoryGranularityMB = 256
+)
+
+// machineResources returns the number of vCPUs and the gigabytes of memory
+// of a predefined N1 or custom machine type.
+func machineResources(machineType string) (float64, float64, error) {
+	if strings.HasPrefix(machineType, customMachineTypePrefix) {
+		parts := strings.Split(strings.TrimPrefix(machineType, customMachineTypePrefix), "-")
+		if len(parts) != 2 {
+			return 0, 0, errors.Errorf("malformed custom machine type '%s'", machineType)
+		}
+		cpus, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "malformed custom machine type '%s'", machineType)
+		}
+		memMB, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "malformed custom machine type '%s'", machineType)
+		}
+		return float64(cpus), float64(memMB) / megabytesPerGigabyte, nil
+	}
+
+	if !strings.HasPrefix(machineType, n1MachineTypePrefix) {
+		return 0, 0, errors.Errorf("no pricing information for machine type '%s'", machineType)
+	}
+	parts := strings.Split(strings.TrimPrefix(machineType, n1MachineTypePrefix), "-")
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("malformed machine type '%s'", machineType)
+	}
+	cpus, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "malformed machine type '%s'", machineType)
+	}
+
+	var memPerCPU float64
+	switch parts[0] {
+	case "standard":
+		memPerCPU = standardMemoryPerCPU
+	case "highmem":
+		memPerCPU = highMemoryMemoryPerCPU
+	case "highcpu":
+		memPerCPU = highCPUMemoryPerCPU
+	default:
+		return 0, 0, errors.Errorf("no pricing information for machine type '%s'", machineType)
+	}
+	return float64(cpus), float64(cpus) * memPerCPU, nil
+}
+
+// hourlyPrice returns the price of running an instance of the given machine
+// type for an hour.
+func hourlyPrice(machineType string, preemptible bool) (float64, error) {
+	switch machineType {
+	case sharedCoreMicroType:
+		if preemptible {
+			return sharedCoreMicroPreempt, nil
+		}
+		return sharedCoreMicroPrice, nil
+	case sharedCoreSmallType:
+		if preemptible {
+			return sharedCoreSmallPreempt, nil
+		}
+		return sharedCoreSmallPrice, nil
+	}
+
+	cpus, memGB, err := machineResources(machineType)
+	if err != nil {
+		return 0, err
+	}
+
+	cpuPrice, memPrice := predefinedCPUPrice, predefinedMemoryPrice
+	custom := strings.HasPrefix(machineType, customMachineTypePrefix)
+	switch {
+	case custom && preemptible:
+		cpuPrice, memPrice = customPreemptibleCPU, customPreemptibleMemory
+	case custom:
+		cpuPrice, memPrice = customCPUPrice, customMemoryPrice
+	case preemptible:
+		cpuPrice, memPrice = preemptibleCPUPrice, preemptibleMemoryPrice
+	}
+
+	return cpus*cpuPrice + memGB*memPrice, nil
+}
+
+// instanceCost returns the cost of running an instance of the given machine
+// type for the given duration.
+func instanceCost(machineType string, preemptible bool, dur time.Duration) (float64, error) {
+	price, err := hourlyPrice(machineType, preemptible)
+	if err != nil {
+		return 0, err
+	}
+	if dur < minimumBillingDuration {
+		dur = minimumBillingDuration
+	}
+	return price * dur.Hours(), nil
+}
+
+// customMachineType returns the name of a custom machine type with the given
+// number of vCPUs and megabytes of memory.
+func customMachineType(cpus, memoryMB int64) string {
+	return fmt.Sprintf("%s%d-%d", customMachineTypePrefix, cpus, memoryMB)
+}
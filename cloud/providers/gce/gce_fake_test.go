@@ -0,0 +1,199 @@
+package gce
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+)
+
+const (
+	fakeProjectID   = "fake-project"
+	fakeAccessToken = "fake-access-token"
+)
+
+// fakeComputeAPI is an HTTP server that implements enough of the Compute
+// Engine instances API and the OAuth2 token endpoint to exercise the client.
+type fakeComputeAPI struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	instances map[string]*instance // keyed by zone/name
+	requests  []string
+	failNext  bool
+}
+
+func newFakeComputeAPI() *fakeComputeAPI {
+	api := &fakeComputeAPI{instances: map[string]*instance{}}
+	api.server = httptest.NewServer(http.HandlerFunc(api.handle))
+	return api
+}
+
+func (api *fakeComputeAPI) Close() { api.server.Close() }
+
+// config returns a GCE configuration that points a client at the fake
+// server, authenticating with a freshly generated key.
+func (api *fakeComputeAPI) config() *evergreen.GCEConfig {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		panic(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	return &evergreen.GCEConfig{
+		ProjectID:   fakeProjectID,
+		ClientEmail: "evergreen@fake-project.iam.gserviceaccount.com",
+		PrivateKey:  string(keyPEM),
+		TokenURI:    api.server.URL + "/token",
+		Endpoint:    api.server.URL + "/compute/v1/",
+	}
+}
+
+func (api *fakeComputeAPI) setInstance(zone string, i *instance) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.instances[zone+"/"+i.Name] = i
+}
+
+func (api *fakeComputeAPI) getInstance(zone, name string) *instance {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.instances[zone+"/"+name]
+}
+
+func (api *fakeComputeAPI) handle(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.requests = append(api.requests, r.Method+" "+r.URL.Path)
+
+	if r.URL.Path == "/token" {
+		if r.FormValue("grant_type") != jwtGrantType || len(strings.Split(r.FormValue("assertion"), ".")) != 3 {
+			writeFakeError(w, http.StatusBadRequest, "invalid assertion")
+			return
+		}
+		writeFakeJSON(w, map[string]interface{}{"access_token": fakeAccessToken, "expires_in": 3600})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+fakeAccessToken {
+		writeFakeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+	if api.failNext {
+		api.failNext = false
+		writeFakeError(w, http.StatusInternalServerError, "backend error")
+		return
+	}
+
+	// paths look like /compute/v1/projects/<project>/zones/<zone>/instances[/<name>]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/compute/v1/"), "/")
+	if r.Method == "GET" && len(parts) == 4 && parts[0] == "projects" && parts[1] == fakeProjectID &&
+		parts[2] == "aggregated" && parts[3] == "instances" {
+		api.listInstances(w, r.URL.Query().Get("filter"))
+		return
+	}
+	if len(parts) < 5 || parts[0] != "projects" || parts[1] != fakeProjectID ||
+		parts[2] != "zones" || parts[4] != "instances" {
+		writeFakeError(w, http.StatusNotFound, "unknown resource")
+		return
+	}
+	zone := parts[3]
+
+	switch {
+	case r.Method == "POST" && len(parts) == 5:
+		i := &instance{}
+		if err := json.NewDecoder(r.Body).Decode(i); err != nil {
+			writeFakeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		key := zone + "/" + i.Name
+		if _, ok := api.instances[key]; ok {
+			writeFakeError(w, http.StatusConflict, "instance already exists")
+			return
+		}
+		i.Status = StatusProvisioning
+		i.Zone = "https://www.googleapis.com/compute/v1/projects/" + fakeProjectID + "/zones/" + zone
+		i.CreationTimestamp = time.Now().Format(time.RFC3339)
+		i.NetworkInterfaces[0].AccessConfigs[0].NatIP = fmt.Sprintf("203.0.113.%d", len(api.instances)+1)
+		api.instances[key] = i
+		writeFakeJSON(w, operation{Name: "insert-" + i.Name, Status: "PENDING"})
+	case r.Method == "GET" && len(parts) == 6:
+		i, ok := api.instances[zone+"/"+parts[5]]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "instance not found")
+			return
+		}
+		writeFakeJSON(w, i)
+	case r.Method == "DELETE" && len(parts) == 6:
+		key := zone + "/" + parts[5]
+		if _, ok := api.instances[key]; !ok {
+			writeFakeError(w, http.StatusNotFound, "instance not found")
+			return
+		}
+		delete(api.instances, key)
+		writeFakeJSON(w, operation{Name: "delete-" + parts[5], Status: "PENDING"})
+	default:
+		writeFakeError(w, http.StatusMethodNotAllowed, "unsupported request")
+	}
+}
+
+// listInstances responds with the instances whose labels match a filter of
+// the form "labels.<key> = <value> AND ...", grouped by zone.
+func (api *fakeComputeAPI) listInstances(w http.ResponseWriter, filter string) {
+	clauses := map[string]string{}
+	for _, clause := range strings.Split(filter, " AND ") {
+		kv := strings.SplitN(clause, " = ", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], "labels.") {
+			writeFakeError(w, http.StatusBadRequest, "unsupported filter")
+			return
+		}
+		clauses[strings.TrimPrefix(kv[0], "labels.")] = kv[1]
+	}
+
+	list := aggregatedInstanceList{}
+	list.Items = map[string]struct {
+		Instances []*instance `json:"instances"`
+	}{}
+	for key, i := range api.instances {
+		matches := true
+		for k, v := range clauses {
+			if i.Labels[k] != v {
+				matches = false
+			}
+		}
+		if !matches {
+			continue
+		}
+		zone := "zones/" + strings.SplitN(key, "/", 2)[0]
+		scoped := list.Items[zone]
+		scoped.Instances = append(scoped.Instances, i)
+		list.Items[zone] = scoped
+	}
+	writeFakeJSON(w, list)
+}
+
+func writeFakeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeFakeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	resp := apiErrorResponse{}
+	resp.Error.Code = status
+	resp.Error.Message = msg
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,407 @@
+package gce
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type GCESuite struct {
+	api      *fakeComputeAPI
+	manager  *Manager
+	distro   *distro.Distro
+	settings map[string]interface{}
+	suite.Suite
+}
+
+func TestGCESuite(t *testing.T) {
+	suite.Run(t, new(GCESuite))
+}
+
+func (s *GCESuite) SetupSuite() {
+	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(testutil.TestConfig()))
+}
+
+func (s *GCESuite) SetupTest() {
+	s.api = newFakeComputeAPI()
+	s.manager = &Manager{}
+	s.NoError(s.manager.Configure(&evergreen.Settings{
+		Providers: evergreen.CloudProviders{GCE: *s.api.config()},
+	}))
+
+	s.settings = map[string]interface{}{
+		"zone":         "us-central1-a",
+		"machine_type": "n1-standard-2",
+		"image_family": "evergreen-ubuntu1604",
+	}
+	s.distro = &distro.Distro{
+		Id:               "ubuntu1604-gce",
+		Provider:         ProviderName,
+		ProviderSettings: &s.settings,
+	}
+}
+
+func (s *GCESuite) TearDownTest() {
+	s.api.Close()
+}
+
+func (s *GCESuite) TestValidateSettings() {
+	settingsOk := &ProviderSettings{
+		Zone:        "zone",
+		MachineType: "n1-standard-2",
+		ImageName:   "image",
+	}
+	s.NoError(settingsOk.Validate())
+
+	settingsCustom := &ProviderSettings{
+		Zone:           "zone",
+		CustomCPUs:     4,
+		CustomMemoryMB: 5120,
+		ImageFamily:    "family",
+		DiskType:       diskTypeSSD,
+	}
+	s.NoError(settingsCustom.Validate())
+
+	settingsNoZone := *settingsOk
+	settingsNoZone.Zone = ""
+	s.Error(settingsNoZone.Validate())
+
+	settingsNoMachine := *settingsOk
+	settingsNoMachine.MachineType = ""
+	s.Error(settingsNoMachine.Validate())
+
+	settingsBothMachines := *settingsCustom
+	settingsBothMachines.MachineType = "n1-standard-2"
+	s.Error(settingsBothMachines.Validate())
+
+	settingsOddCPUs := *settingsCustom
+	settingsOddCPUs.CustomCPUs = 3
+	s.Error(settingsOddCPUs.Validate())
+
+	settingsBadMemory := *settingsCustom
+	settingsBadMemory.CustomMemoryMB = 5000
+	s.Error(settingsBadMemory.Validate())
+
+	settingsNoImage := *settingsOk
+	settingsNoImage.ImageName = ""
+	s.Error(settingsNoImage.Validate())
+
+	settingsBothImages := *settingsOk
+	settingsBothImages.ImageFamily = "family"
+	s.Error(settingsBothImages.Validate())
+
+	settingsBadDisk := *settingsOk
+	settingsBadDisk.DiskType = "pd-floppy"
+	s.Error(settingsBadDisk.Validate())
+
+	settingsBadKey := *settingsOk
+	settingsBadKey.SSHKeys = []SSHKey{{Username: "evg"}}
+	s.Error(settingsBadKey.Validate())
+}
+
+func (s *GCESuite) TestConfigureRequiresCredentials() {
+	config := s.api.config()
+
+	noProject := *config
+	noProject.ProjectID = ""
+	s.Error((&Manager{}).Configure(&evergreen.Settings{
+		Providers: evergreen.CloudProviders{GCE: noProject},
+	}))
+
+	noEmail := *config
+	noEmail.ClientEmail = ""
+	s.Error((&Manager{}).Configure(&evergreen.Settings{
+		Providers: evergreen.CloudProviders{GCE: noEmail},
+	}))
+
+	badKey := *config
+	badKey.PrivateKey = "not a key"
+	s.Error((&Manager{}).Configure(&evergreen.Settings{
+		Providers: evergreen.CloudProviders{GCE: badKey},
+	}))
+}
+
+func (s *GCESuite) TestClientLifecycle() {
+	c := s.manager.client
+	i := &instance{
+		Name:              "evg-test",
+		MachineType:       "zones/us-central1-a/machineTypes/n1-standard-2",
+		NetworkInterfaces: []networkInterface{{AccessConfigs: []accessConfig{{Type: "ONE_TO_ONE_NAT"}}}},
+	}
+	s.NoError(c.CreateInstance("us-central1-a", i))
+	s.Error(c.CreateInstance("us-central1-a", i))
+
+	found, err := c.GetInstance("us-central1-a", "evg-test")
+	s.NoError(err)
+	s.Equal("evg-test", found.Name)
+	s.Equal(StatusProvisioning, found.Status)
+
+	_, err = c.GetInstance("us-central1-b", "evg-test")
+	s.Equal(errNotFound, err)
+
+	s.NoError(c.DeleteInstance("us-central1-a", "evg-test"))
+	s.Equal(errNotFound, c.DeleteInstance("us-central1-a", "evg-test"))
+
+	// the access token is only requested once
+	tokenRequests := 0
+	for _, r := range s.api.requests {
+		if r == "POST /token" {
+			tokenRequests++
+		}
+	}
+	s.Equal(1, tokenRequests)
+}
+
+func (s *GCESuite) TestClientReportsAPIErrors() {
+	s.api.failNext = true
+	_, err := s.manager.client.GetInstance("us-central1-a", "evg-test")
+	s.Error(err)
+	s.NotEqual(errNotFound, err)
+	s.Contains(err.Error(), "backend error")
+}
+
+func (s *GCESuite) TestGetInstanceStatus() {
+	h := &host.Host{Id: "evg-status", Distro: *s.distro}
+
+	status, err := s.manager.GetInstanceStatus(h)
+	s.NoError(err)
+	s.Equal(cloud.StatusTerminated, status)
+
+	for gceStatus, expected := range map[string]cloud.CloudStatus{
+		StatusProvisioning: cloud.StatusInitializing,
+		StatusStaging:      cloud.StatusInitializing,
+		StatusRunning:      cloud.StatusRunning,
+		StatusStopping:     cloud.StatusStopped,
+		StatusSuspended:    cloud.StatusStopped,
+		StatusTerminated:   cloud.StatusTerminated,
+		"SOMETHING_ELSE":   cloud.StatusUnknown,
+	} {
+		s.api.setInstance("us-central1-a", &instance{Name: h.Id, Status: gceStatus})
+		status, err = s.manager.GetInstanceStatus(h)
+		s.NoError(err)
+		s.Equal(expected, status, gceStatus)
+
+		up, err := s.manager.IsUp(h)
+		s.NoError(err)
+		s.Equal(expected == cloud.StatusRunning, up)
+	}
+
+	noZone := &host.Host{Id: "evg-status"}
+	_, err = s.manager.GetInstanceStatus(noZone)
+	s.Error(err)
+}
+
+func (s *GCESuite) TestGetDNSName() {
+	h := &host.Host{Id: "evg-dns", Distro: *s.distro}
+
+	_, err := s.manager.GetDNSName(h)
+	s.Error(err)
+
+	s.api.setInstance("us-central1-a", &instance{
+		Name: h.Id,
+		NetworkInterfaces: []networkInterface{
+			{NetworkIP: "10.0.0.2", AccessConfigs: []accessConfig{{NatIP: "203.0.113.7"}}},
+		},
+	})
+	dns, err := s.manager.GetDNSName(h)
+	s.NoError(err)
+	s.Equal("203.0.113.7", dns)
+}
+
+func (s *GCESuite) TestSpawnInstance() {
+	h, err := s.manager.SpawnInstance(s.distro, cloud.HostOptions{UserName: evergreen.User})
+	s.NoError(err)
+	s.Require().NotNil(h)
+	s.Equal("n1-standard-2", h.InstanceType)
+
+	i := s.api.getInstance("us-central1-a", h.Id)
+	s.Require().NotNil(i)
+	s.Equal("zones/us-central1-a/machineTypes/n1-standard-2", i.MachineType)
+	s.Equal("projects/fake-project/global/images/family/evergreen-ubuntu1604",
+		i.Disks[0].InitializeParams.SourceImage)
+	s.Equal("ubuntu1604-gce", i.Labels["distro"])
+
+	dbHost, err := host.FindOne(host.ById(h.Id))
+	s.NoError(err)
+	s.Require().NotNil(dbHost)
+	s.Equal(ProviderName, dbHost.Provider)
+}
+
+func (s *GCESuite) TestSpawnInstanceFailureRemovesIntentHost() {
+	s.api.failNext = true
+	h, err := s.manager.SpawnInstance(s.distro, cloud.HostOptions{UserName: evergreen.User})
+	s.Error(err)
+	s.Nil(h)
+
+	hosts, err := host.Find(host.ByDistroId(s.distro.Id))
+	s.NoError(err)
+	s.Len(hosts, 0)
+}
+
+func (s *GCESuite) TestSpawnInstanceInvalidSettings() {
+	d := *s.distro
+	d.Provider = "ec2"
+	_, err := s.manager.SpawnInstance(&d, cloud.HostOptions{})
+	s.Error(err)
+
+	d = *s.distro
+	d.ProviderSettings = &map[string]interface{}{"zone": "us-central1-a"}
+	_, err = s.manager.SpawnInstance(&d, cloud.HostOptions{})
+	s.Error(err)
+}
+
+func (s *GCESuite) TestTerminateInstance() {
+	h := &host.Host{Id: "evg-terminate", Distro: *s.distro, Status: evergreen.HostRunning}
+	s.NoError(h.Insert())
+	s.api.setInstance("us-central1-a", &instance{Name: h.Id, Status: StatusRunning})
+
+	s.NoError(s.manager.TerminateInstance(h))
+	s.Nil(s.api.getInstance("us-central1-a", h.Id))
+	s.Equal(evergreen.HostTerminated, h.Status)
+
+	s.Error(s.manager.TerminateInstance(h))
+}
+
+func (s *GCESuite) TestListManagedInstances() {
+	h := &host.Host{
+		Id:           "evg-managed",
+		Distro:       *s.distro,
+		Provider:     ProviderName,
+		CreationTime: time.Now(),
+	}
+	i := makeInstance(h, &ProviderSettings{Zone: "us-central1-a", MachineType: "n1-standard-2"}, fakeProjectID)
+	i.Zone = "https://www.googleapis.com/compute/v1/projects/fake-project/zones/us-central1-a"
+	s.api.setInstance("us-central1-a", i)
+	s.api.setInstance("us-east1-b", &instance{Name: "unmanaged", Labels: map[string]string{"team": "other"}})
+
+	instances, err := s.manager.ListManagedInstances()
+	s.NoError(err)
+	s.Require().Len(instances, 1)
+	s.Equal("evg-managed", instances[0].Id)
+	s.Equal("us-central1-a", instances[0].Location)
+	s.Equal(cloud.ManagedByEvergreen, instances[0].Tags[cloud.TagManagedBy])
+
+	s.NoError(s.manager.TerminateManagedInstance(instances[0]))
+	s.Nil(s.api.getInstance("us-central1-a", "evg-managed"))
+	s.NoError(s.manager.TerminateManagedInstance(instances[0]))
+}
+
+func (s *GCESuite) TestMakeInstance() {
+	h := &host.Host{
+		Id:           "evg-make",
+		Distro:       *s.distro,
+		StartedBy:    "Some.User",
+		CreationTime: time.Now(),
+	}
+	settings := &ProviderSettings{
+		Zone:           "us-central1-a",
+		CustomCPUs:     4,
+		CustomMemoryMB: 5120,
+		ImageName:      "ubuntu-1604-v20170601",
+		ImageProject:   "ubuntu-os-cloud",
+		DiskSizeGB:     100,
+		DiskType:       diskTypeSSD,
+		NetworkTags:    []string{"evergreen"},
+		SSHKeys: []SSHKey{
+			{Username: "evg", PublicKey: "ssh-rsa AAAA evg\n"},
+			{Username: "admin", PublicKey: "ssh-rsa BBBB admin"},
+		},
+		Preemptible: true,
+	}
+
+	i := makeInstance(h, settings, fakeProjectID)
+	s.Equal("evg-make", i.Name)
+	s.Equal("zones/us-central1-a/machineTypes/custom-4-5120", i.MachineType)
+	s.Require().Len(i.Disks, 1)
+	s.True(i.Disks[0].Boot)
+	s.True(i.Disks[0].AutoDelete)
+	s.Equal("projects/ubuntu-os-cloud/global/images/ubuntu-1604-v20170601", i.Disks[0].InitializeParams.SourceImage)
+	s.Equal(int64(100), i.Disks[0].InitializeParams.DiskSizeGb)
+	s.Equal("zones/us-central1-a/diskTypes/pd-ssd", i.Disks[0].InitializeParams.DiskType)
+	s.Equal("global/networks/default", i.NetworkInterfaces[0].Network)
+	s.Equal([]string{"evergreen"}, i.Tags.Items)
+
+	s.Require().NotNil(i.Metadata)
+	s.Equal("ssh-keys", i.Metadata.Items[0].Key)
+	s.Equal("evg:ssh-rsa AAAA evg\nadmin:ssh-rsa BBBB admin", i.Metadata.Items[0].Value)
+
+	s.Require().NotNil(i.Scheduling)
+	s.True(i.Scheduling.Preemptible)
+	s.False(*i.Scheduling.AutomaticRestart)
+	s.Equal("TERMINATE", i.Scheduling.OnHostMaintenance)
+
+	s.Equal("some-user", i.Labels["owner"])
+	s.Equal("production", i.Labels["mode"])
+
+	settings.Preemptible = false
+	settings.SSHKeys = nil
+	i = makeInstance(h, settings, fakeProjectID)
+	s.Nil(i.Scheduling)
+	s.Nil(i.Metadata)
+}
+
+func (s *GCESuite) TestGenerateName() {
+	valid := regexp.MustCompile("^[a-z]([-a-z0-9]*[a-z0-9])?$")
+
+	for _, id := range []string{
+		"ubuntu1604-gce",
+		"Windows_2012_R2",
+		"a-very-long-distro-name-that-would-not-fit-in-an-instance-name-at-all",
+	} {
+		name := generateName(&distro.Distro{Id: id})
+		s.True(valid.MatchString(name), name)
+		s.True(len(name) <= maxNameLength, name)
+	}
+}
+
+func (s *GCESuite) TestCostForDuration() {
+	start := time.Now()
+	h := &host.Host{Id: "evg-cost", Distro: *s.distro}
+
+	_, err := s.manager.CostForDuration(h, start, start.Add(-time.Hour))
+	s.Error(err)
+	_, err = s.manager.CostForDuration(h, time.Time{}, start)
+	s.Error(err)
+
+	cost, err := s.manager.CostForDuration(h, start, start.Add(time.Hour))
+	s.NoError(err)
+	s.InDelta(2*predefinedCPUPrice+7.5*predefinedMemoryPrice, cost, 0.000001)
+
+	// the first minute is always billed
+	short, err := s.manager.CostForDuration(h, start, start.Add(time.Second))
+	s.NoError(err)
+	s.InDelta(cost/60, short, 0.000001)
+
+	s.settings["preemptible"] = true
+	h.Distro = *s.distro
+	h.InstanceType = "custom-4-5120"
+	cost, err = s.manager.CostForDuration(h, start, start.Add(2*time.Hour))
+	s.NoError(err)
+	s.InDelta(2*(4*customPreemptibleCPU+5*customPreemptibleMemory), cost, 0.000001)
+
+	h.InstanceType = "f1-micro"
+	cost, err = s.manager.CostForDuration(h, start, start.Add(time.Hour))
+	s.NoError(err)
+	s.InDelta(sharedCoreMicroPreempt, cost, 0.000001)
+
+	h.InstanceType = "m9-mystery-64"
+	_, err = s.manager.CostForDuration(h, start, start.Add(time.Hour))
+	s.Error(err)
+}
+
+func (s *GCESuite) TestTimeTilNextPayment() {
+	h := &host.Host{CreationTime: time.Now().Add(-30 * time.Second)}
+	tilNext := s.manager.TimeTilNextPayment(h)
+	s.True(tilNext > 0 && tilNext <= 30*time.Second)
+
+	h.CreationTime = time.Now().Add(-time.Hour)
+	s.Equal(time.Duration(0), s.manager.TimeTilNextPayment(h))
+}
@@ -0,0 +1,262 @@
+package gce
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/pkg/errors"
+)
+
+const (
+	// NameTimeFormat is the format in which to log times like instance start time.
+	NameTimeFormat = "20060102150405"
+	// StatusProvisioning means resources are being allocated for the instance.
+	StatusProvisioning = "PROVISIONING"
+	// StatusStaging means the instance is being prepared for its first boot.
+	StatusStaging = "STAGING"
+	// StatusRunning means the instance is booting up or running.
+	StatusRunning = "RUNNING"
+	// StatusStopping means the instance is being stopped.
+	StatusStopping = "STOPPING"
+	// StatusSuspending means the instance is being suspended.
+	StatusSuspending = "SUSPENDING"
+	// StatusSuspended means the instance has been suspended.
+	StatusSuspended = "SUSPENDED"
+	// StatusTerminated means the instance was shut down or preempted.
+	StatusTerminated = "TERMINATED"
+
+	// maxNameLength is the maximum length of instance names and label values.
+	maxNameLength = 63
+)
+
+var (
+	invalidNameChars  = regexp.MustCompile("[^a-z0-9-]+")
+	invalidLabelChars = regexp.MustCompile("[^a-z0-9_-]+")
+)
+
+// instance is the subset of the Compute Engine instance resource that
+// Evergreen reads and writes.
+type instance struct {
+	Name              string             `json:"name"`
+	Zone              string             `json:"zone,omitempty"`
+	MachineType       string             `json:"machineType"`
+	Status            string             `json:"status,omitempty"`
+	CreationTimestamp string             `json:"creationTimestamp,omitempty"`
+	Disks             []attachedDisk     `json:"disks"`
+	NetworkInterfaces []networkInterface `json:"networkInterfaces"`
+	Metadata          *metadata          `json:"metadata,omitempty"`
+	Labels            map[string]string  `json:"labels,omitempty"`
+	Tags              *tags              `json:"tags,omitempty"`
+	Scheduling        *scheduling        `json:"scheduling,omitempty"`
+}
+
+type attachedDisk struct {
+	Boot             bool                    `json:"boot"`
+	AutoDelete       bool                    `json:"autoDelete"`
+	InitializeParams *attachedDiskInitParams `json:"initializeParams,omitempty"`
+}
+
+type attachedDiskInitParams struct {
+	SourceImage string `json:"sourceImage"`
+	DiskSizeGb  int64  `json:"diskSizeGb,omitempty"`
+	DiskType    string `json:"diskType,omitempty"`
+}
+
+type networkInterface struct {
+	Network       string         `json:"network,omitempty"`
+	NetworkIP     string         `json:"networkIP,omitempty"`
+	AccessConfigs []accessConfig `json:"accessConfigs,omitempty"`
+}
+
+type accessConfig struct {
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type,omitempty"`
+	NatIP string `json:"natIP,omitempty"`
+}
+
+type metadata struct {
+	Items []metadataItem `json:"items"`
+}
+
+type metadataItem struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type tags struct {
+	Items []string `json:"items"`
+}
+
+type scheduling struct {
+	Preemptible       bool   `json:"preemptible"`
+	AutomaticRestart  *bool  `json:"automaticRestart,omitempty"`
+	OnHostMaintenance string `json:"onHostMaintenance,omitempty"`
+}
+
+// aggregatedInstanceList is a page of the instances in all zones, keyed by
+// the zone's path.
+type aggregatedInstanceList struct {
+	Items map[string]struct {
+		Instances []*instance `json:"instances"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
+
+// operation is the subset of the Compute Engine operation resource returned
+// by mutating API calls.
+type operation struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  *struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error,omitempty"`
+}
+
+func (op *operation) err() error {
+	if op.Error == nil || len(op.Error.Errors) == 0 {
+		return nil
+	}
+	msgs := []string{}
+	for _, e := range op.Error.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", e.Code, e.Message))
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+type apiErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func gceStatusToEvgStatus(status string) cloud.CloudStatus {
+	switch status {
+	case StatusProvisioning, StatusStaging:
+		return cloud.StatusInitializing
+	case StatusRunning:
+		return cloud.StatusRunning
+	case StatusStopping, StatusSuspending, StatusSuspended:
+		return cloud.StatusStopped
+	case StatusTerminated:
+		return cloud.StatusTerminated
+	default:
+		return cloud.StatusUnknown
+	}
+}
+
+// generateName returns a unique instance name for the distro that satisfies
+// the Compute Engine naming rules: lowercase letters, digits and dashes,
+// starting with a letter and at most 63 characters long.
+func generateName(d *distro.Distro) string {
+	distroPart := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(d.Id), "-"), "-")
+	suffix := fmt.Sprintf("-%s-%d", time.Now().Format(NameTimeFormat),
+		rand.New(rand.NewSource(time.Now().UnixNano())).Int31())
+
+	maxDistroLength := maxNameLength - len("evg-") - len(suffix)
+	if len(distroPart) > maxDistroLength {
+		distroPart = strings.TrimRight(distroPart[:maxDistroLength], "-")
+	}
+	return "evg-" + distroPart + suffix
+}
+
+// makeInstance builds the instance resource to request for the intent host.
+func makeInstance(h *host.Host, s *ProviderSettings, projectID string) *instance {
+	initParams := &attachedDiskInitParams{
+		SourceImage: s.sourceImage(projectID),
+		DiskSizeGb:  s.DiskSizeGB,
+	}
+	if s.DiskType != "" {
+		initParams.DiskType = fmt.Sprintf("zones/%s/diskTypes/%s", s.Zone, s.DiskType)
+	}
+
+	network := s.Network
+	if network == "" {
+		network = "default"
+	}
+
+	i := &instance{
+		Name:        h.Id,
+		MachineType: fmt.Sprintf("zones/%s/machineTypes/%s", s.Zone, s.machineType()),
+		Disks: []attachedDisk{
+			{Boot: true, AutoDelete: true, InitializeParams: initParams},
+		},
+		NetworkInterfaces: []networkInterface{
+			{
+				Network:       "global/networks/" + network,
+				AccessConfigs: []accessConfig{{Name: "External NAT", Type: "ONE_TO_ONE_NAT"}},
+			},
+		},
+		Labels: makeLabels(h),
+	}
+
+	if len(s.SSHKeys) > 0 {
+		keys := []string{}
+		for _, k := range s.SSHKeys {
+			keys = append(keys, fmt.Sprintf("%s:%s", k.Username, strings.TrimSpace(k.PublicKey)))
+		}
+		i.Metadata = &metadata{Items: []metadataItem{{Key: "ssh-keys", Value: strings.Join(keys, "\n")}}}
+	}
+
+	if len(s.NetworkTags) > 0 {
+		i.Tags = &tags{Items: s.NetworkTags}
+	}
+
+	// preemptible instances cannot be restarted automatically or live migrated
+	if s.Preemptible {
+		restart := false
+		i.Scheduling = &scheduling{
+			Preemptible:       true,
+			AutomaticRestart:  &restart,
+			OnHostMaintenance: "TERMINATE",
+		}
+	}
+
+	return i
+}
+
+// makeLabels returns the labels identifying the instance as an Evergreen
+// host, with keys and values sanitized to meet the Compute Engine label rules.
+func makeLabels(intent *host.Host) map[string]string {
+	labels := map[string]string{}
+	for k, v := range cloud.MakeTags(intent) {
+		k = sanitizeLabel(k)
+		if k == "" {
+			continue
+		}
+		labels[k] = sanitizeLabel(v)
+	}
+	return labels
+}
+
+// sanitizeLabel lowercases a label key or value, replaces the characters
+// Compute Engine does not allow and truncates it to the maximum length.
+func sanitizeLabel(v string) string {
+	v = invalidLabelChars.ReplaceAllString(strings.ToLower(v), "-")
+	if len(v) > maxNameLength {
+		v = v[:maxNameLength]
+	}
+	return v
+}
+
+// externalIP returns the external address of the instance's first network
+// interface, if it has one.
+func externalIP(i *instance) string {
+	for _, ni := range i.NetworkInterfaces {
+		for _, ac := range ni.AccessConfigs {
+			if ac.NatIP != "" {
+				return ac.NatIP
+			}
+		}
+	}
+	return ""
+}
@@ -168,6 +168,34 @@ func (m *Manager) TerminateInstance(host *host.Host) error {
 	return m.client.DeleteInstance(host.Id)
 }
 
+// ListManagedInstances returns the servers in the current tenant that were
+// created by Evergreen.
+func (m *Manager) ListManagedInstances() ([]cloud.ManagedInstance, error) {
+	list, err := m.client.ListInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	managed := []cloud.ManagedInstance{}
+	for _, server := range list {
+		if server.Metadata[cloud.TagManagedBy] != cloud.ManagedByEvergreen ||
+			server.Metadata[cloud.TagProvider] != ProviderName {
+			continue
+		}
+		managed = append(managed, cloud.ManagedInstance{
+			Id:        server.ID,
+			Tags:      server.Metadata,
+			CreatedAt: server.Created,
+		})
+	}
+	return managed, nil
+}
+
+// TerminateManagedInstance requests a server that has no host to be removed.
+func (m *Manager) TerminateManagedInstance(instance cloud.ManagedInstance) error {
+	return m.client.DeleteInstance(instance.Id)
+}
+
 // IsUp checks whether the provisioned host is running.
 func (m *Manager) IsUp(host *host.Host) (bool, error) {
 	status, err := m.GetInstanceStatus(host)
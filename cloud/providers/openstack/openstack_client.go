@@ -14,6 +14,7 @@ type client interface {
 	CreateInstance(servers.CreateOpts, string) (*servers.Server, error)
 	GetInstance(string) (*servers.Server, error)	
 	DeleteInstance(string) error
+	ListInstances() ([]servers.Server, error)
 }
 
 type clientImpl struct {
@@ -57,3 +58,13 @@ func (c *clientImpl) DeleteInstance(id string) error {
 	err := servers.Delete(c.ServiceClient, id).ExtractErr()
 	return errors.Wrap(err, "OpenStack Delete API call failed")
 }
+
+// ListInstances requests details on all servers in the current tenant.
+func (c *clientImpl) ListInstances() ([]servers.Server, error) {
+	pages, err := servers.List(c.ServiceClient, servers.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, errors.Wrap(err, "OpenStack List API call failed")
+	}
+	list, err := servers.ExtractServers(pages)
+	return list, errors.Wrap(err, "OpenStack List API call failed")
+}
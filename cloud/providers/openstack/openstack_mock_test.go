@@ -16,9 +16,11 @@ type clientMock struct {
 	failCreate bool
 	failGet    bool
 	failDelete bool
+	failList   bool
 
 	// Other options
 	isServerActive bool
+	servers        []servers.Server
 }
 
 func (c *clientMock) Init(_ gophercloud.AuthOptions, _ gophercloud.EndpointOpts) error {
@@ -62,3 +64,11 @@ func (c *clientMock) DeleteInstance(id string) error {
 
 	return nil
 }
+
+func (c *clientMock) ListInstances() ([]servers.Server, error) {
+	if c.failList {
+		return nil, errors.New("failed to list instances")
+	}
+
+	return c.servers, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/db"
 	"github.com/evergreen-ci/evergreen/testutil"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -128,6 +129,30 @@ func (s *OpenStackSuite) TestTerminateInstanceAPICall() {
 	s.Error(s.manager.TerminateInstance(host))
 }
 
+func (s *OpenStackSuite) TestListManagedInstances() {
+	mock, ok := s.client.(*clientMock)
+	s.True(ok)
+
+	managedTags := map[string]string{
+		cloud.TagManagedBy: cloud.ManagedByEvergreen,
+		cloud.TagProvider:  ProviderName,
+	}
+	mock.servers = []servers.Server{
+		{ID: "managed", Metadata: managedTags},
+		{ID: "unmanaged", Metadata: map[string]string{"owner": "someone"}},
+		{ID: "other-provider", Metadata: map[string]string{cloud.TagManagedBy: cloud.ManagedByEvergreen}},
+	}
+
+	instances, err := s.manager.ListManagedInstances()
+	s.NoError(err)
+	s.Require().Len(instances, 1)
+	s.Equal("managed", instances[0].Id)
+
+	mock.failList = true
+	_, err = s.manager.ListManagedInstances()
+	s.Error(err)
+}
+
 func (s *OpenStackSuite) TestGetDNSNameAPICall() {
 	mock, ok := s.client.(*clientMock)
 	s.True(ok)
@@ -1,8 +1,6 @@
 package openstack
 
 import (
-	"os"
-	"os/user"
 
 	"github.com/evergreen-ci/evergreen/cloud"
 	"github.com/evergreen-ci/evergreen/model/host"
@@ -51,33 +49,7 @@ func getSpawnOptions(h *host.Host, s *ProviderSettings, d *distro.Distro) server
 	}
 }
 
+// makeTags returns the metadata identifying the server as an Evergreen host.
 func makeTags(intent *host.Host) map[string]string {
-	// Get requester host name
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = "unknown"
-	}
-
-	// Get requester user name
-	var username string
-	user, err := user.Current()
-	if err != nil {
-		username = "unknown"
-	} else {
-		username = user.Name
-	}
-
-	tags := map[string]string{
-		"distro":            intent.Distro.Id,
-		"evergreen-service": hostname,
-		"username":          username,
-		"owner":             intent.StartedBy,
-		"mode":              "production",
-		"start-time":        intent.CreationTime.Format(NameTimeFormat),
-	}
-
-	if intent.UserHost {
-		tags["mode"] = "testing"
-	}
-	return tags
+	return cloud.MakeTags(intent)
 }
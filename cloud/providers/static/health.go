@@ -0,0 +1,46 @@
+package static
+
+import (
+	"fmt"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+// DecodeSettings returns the static provider settings of a distro.
+func DecodeSettings(d *distro.Distro) (*Settings, error) {
+	settings := &Settings{}
+	if err := mapstructure.Decode(d.ProviderSettings, settings); err != nil {
+		return nil, errors.Wrapf(err, "invalid static settings for distro '%s'", d.Id)
+	}
+	return settings, nil
+}
+
+// RecordTaskResult keeps count of the tasks in a row that have ended in a
+// system failure on a static host, and quarantines the host once the count
+// reaches the distro's limit. Hosts from other providers are ignored.
+func RecordTaskResult(h *host.Host, systemFailure bool) error {
+	if h.Provider != evergreen.HostTypeStatic {
+		return nil
+	}
+
+	if err := h.RecordTaskOutcome(systemFailure); err != nil {
+		return errors.Wrapf(err, "error recording task outcome for host %s", h.Id)
+	}
+
+	settings, err := DecodeSettings(&h.Distro)
+	if err != nil {
+		return err
+	}
+	if settings.QuarantineAfterFailures == 0 || h.SystemFailures < settings.QuarantineAfterFailures {
+		return nil
+	}
+	if h.Status != evergreen.HostRunning {
+		return nil
+	}
+
+	return h.Quarantine(fmt.Sprintf("%d tasks in a row ended in a system failure", h.SystemFailures), true)
+}
@@ -19,6 +19,16 @@ type StaticManager struct{}
 
 type Settings struct {
 	Hosts []Host `mapstructure:"hosts" json:"hosts" bson:"hosts"`
+
+	// HealthCheck is a shell script run periodically over SSH on each idle
+	// host. A host whose check fails is quarantined, and a host that was
+	// quarantined automatically is re-admitted once its check passes.
+	HealthCheck string `mapstructure:"health_check" json:"health_check" bson:"health_check"`
+
+	// QuarantineAfterFailures is the number of tasks in a row that may end in
+	// a system failure on a host before it is quarantined. Zero disables
+	// automatic quarantine.
+	QuarantineAfterFailures int `mapstructure:"quarantine_after_failures" json:"quarantine_after_failures" bson:"quarantine_after_failures"`
 }
 
 type Host struct {
@@ -40,6 +50,9 @@ func (s *Settings) Validate() error {
 			return errors.New("host 'name' field can not be blank")
 		}
 	}
+	if s.QuarantineAfterFailures < 0 {
+		return errors.New("'quarantine_after_failures' can not be negative")
+	}
 	return nil
 }
 
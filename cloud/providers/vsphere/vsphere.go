@@ -0,0 +1,266 @@
+package vsphere
+
+import (
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/hostutil"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/message"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProviderName is used to distinguish between different cloud providers.
+	ProviderName = "vsphere"
+)
+
+// Manager implements the CloudManager interface for vSphere.
+type Manager struct {
+	client client
+}
+
+// ProviderSettings specifies the settings used to configure a host instance.
+// Placement settings are vCenter managed object identifiers, e.g.
+// "resgroup-42", and default to the template's placement when blank.
+type ProviderSettings struct {
+	Template     string `mapstructure:"template"`
+	Folder       string `mapstructure:"folder"`
+	ResourcePool string `mapstructure:"resource_pool"`
+	Host         string `mapstructure:"host"`
+	Cluster      string `mapstructure:"cluster"`
+	Datastore    string `mapstructure:"datastore"`
+}
+
+// Validate verifies a set of ProviderSettings.
+func (opts *ProviderSettings) Validate() error {
+	if opts.Template == "" {
+		return errors.New("Template name must not be blank")
+	}
+
+	if opts.Host != "" && opts.Cluster != "" {
+		return errors.New("Host and cluster cannot both be specified")
+	}
+
+	return nil
+}
+
+func (opts *ProviderSettings) placement() *placement {
+	p := &placement{
+		Folder:       opts.Folder,
+		ResourcePool: opts.ResourcePool,
+		Host:         opts.Host,
+		Cluster:      opts.Cluster,
+		Datastore:    opts.Datastore,
+	}
+	if p.isEmpty() {
+		return nil
+	}
+	return p
+}
+
+// GetSettings returns an empty ProviderSettings struct since settings are configured on
+// instance creation.
+func (m *Manager) GetSettings() cloud.ProviderSettings {
+	return &ProviderSettings{}
+}
+
+// Configure loads the necessary credentials from the global config object.
+func (m *Manager) Configure(s *evergreen.Settings) error {
+	config := s.Providers.VSphere
+
+	if m.client == nil {
+		m.client = &clientImpl{}
+	}
+
+	if err := m.client.Init(&config); err != nil {
+		return errors.Wrap(err, "Failed to initialize client connection")
+	}
+
+	return nil
+}
+
+// SpawnInstance attempts to create a new host by cloning the distro's template.
+// Information about the intended (and eventually created) host is recorded in a DB document.
+// The VM is not tagged, since vSphere tags are not key-value pairs (see cloud.MakeTags).
+//
+// ProviderSettings in the distro should have the following settings:
+//     - Template:     name of the VM template to clone
+//     - Folder:       (optional) folder to place the VM in
+//     - ResourcePool: (optional) resource pool to run the VM in
+//     - Host:         (optional) ESXi host to run the VM on
+//     - Cluster:      (optional) cluster to run the VM in
+//     - Datastore:    (optional) datastore for the VM's disks
+func (m *Manager) SpawnInstance(d *distro.Distro, hostOpts cloud.HostOptions) (*host.Host, error) {
+	if d.Provider != ProviderName {
+		return nil, errors.Errorf("Can't spawn instance of %s for distro %s: provider is %s",
+			ProviderName, d.Id, d.Provider)
+	}
+
+	settings := &ProviderSettings{}
+	if err := mapstructure.Decode(d.ProviderSettings, settings); err != nil {
+		return nil, errors.Wrapf(err, "Error decoding params for distro %s", d.Id)
+	}
+
+	if err := settings.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "Invalid settings in distro %s", d.Id)
+	}
+
+	templateID, err := m.client.FindVM(settings.Template)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not find template '%s' for distro '%s'",
+			settings.Template, d.Id)
+	}
+
+	// Proactively record all information about the host we want to create. This way, if we are
+	// unable to start it or record its VM ID, we have a way of knowing what went wrong.
+	name := d.GenerateName()
+	intentHost := cloud.NewIntent(*d, name, ProviderName, hostOpts)
+	if err = intentHost.Insert(); err != nil {
+		err = errors.Wrapf(err, "Could not insert intent host '%s'", intentHost.Id)
+		grip.Error(err)
+		return nil, err
+	}
+	grip.Debugf("Inserted intent host '%s' for distro '%s' to signal instance spawn intent", name, d.Id)
+
+	// Clone and power on the VM, and remove the intent host document if unsuccessful.
+	vmID, err := m.client.CloneVM(&cloneSpec{
+		Name:      name,
+		Source:    templateID,
+		Placement: settings.placement(),
+		PowerOn:   true,
+	})
+	if err != nil {
+		if rmErr := intentHost.Remove(); rmErr != nil {
+			grip.Errorf("Could not remove intent host '%s': %+v", intentHost.Id, rmErr)
+		}
+		grip.Error(err)
+		return nil, errors.Wrapf(err, "Could not clone new VM for distro '%s'", d.Id)
+	}
+
+	// Update the record of the actual VM.
+	actualHost, err := intentHost.UpdateDocumentID(vmID)
+	if err != nil {
+		err = errors.Wrapf(err, "Could not start new VM for distro '%s.' "+
+			"Accompanying host record is '%s'", d.Id, intentHost.Id)
+		grip.Error(err)
+		return nil, err
+	}
+
+	grip.Debugf("New VM: %v", message.Fields{"instance": name, "object": actualHost})
+	return actualHost, nil
+}
+
+// CanSpawn always returns true for now.
+//
+// Whether a clone succeeds depends on the capacity of the lab's hosts and datastores,
+// which there is no way to know ahead of time.
+func (m *Manager) CanSpawn() (bool, error) {
+	return true, nil
+}
+
+// GetInstanceStatus gets the current power state of the provisioned host.
+func (m *Manager) GetInstanceStatus(host *host.Host) (cloud.CloudStatus, error) {
+	vm, err := m.client.GetVM(host.Id)
+	if err == errNotFound {
+		return cloud.StatusTerminated, nil
+	}
+	if err != nil {
+		return cloud.StatusUnknown, err
+	}
+
+	return vsphereStatusToEvgStatus(vm.PowerState), nil
+}
+
+// TerminateInstance powers off and deletes a VM previously cloned for the host.
+func (m *Manager) TerminateInstance(host *host.Host) error {
+	if host.Status == evergreen.HostTerminated {
+		err := errors.Errorf("Can not terminate %s - already marked as terminated!", host.Id)
+		grip.Error(err)
+		return err
+	}
+
+	vm, err := m.client.GetVM(host.Id)
+	if err != nil && err != errNotFound {
+		return err
+	}
+
+	// a VM that no longer exists has already been terminated
+	if err == nil {
+		if vm.PowerState == PowerStateOn {
+			if err = m.client.PowerOff(host.Id); err != nil {
+				return err
+			}
+		}
+		if err = m.client.DeleteVM(host.Id); err != nil && err != errNotFound {
+			return err
+		}
+	}
+
+	return errors.WithStack(host.Terminate())
+}
+
+// IsUp checks whether the provisioned host is powered on.
+func (m *Manager) IsUp(host *host.Host) (bool, error) {
+	status, err := m.GetInstanceStatus(host)
+	if err != nil {
+		return false, err
+	}
+
+	return status == cloud.StatusRunning, nil
+}
+
+// OnUp does nothing since templates are expected to be fully configured.
+func (m *Manager) OnUp(host *host.Host) error {
+	return nil
+}
+
+// IsSSHReachable returns true if the host can successfully accept and run an SSH command.
+func (m *Manager) IsSSHReachable(host *host.Host, keyPath string) (bool, error) {
+	opts, err := m.GetSSHOptions(host, keyPath)
+	if err != nil {
+		return false, err
+	}
+
+	return hostutil.CheckSSHResponse(host, opts)
+}
+
+// GetDNSName returns the IP address reported by the VM's guest tools.
+func (m *Manager) GetDNSName(host *host.Host) (string, error) {
+	identity, err := m.client.GetGuestIdentity(host.Id)
+	if err == errNotFound {
+		return "", errors.Errorf("Guest tools are not yet running on VM '%s'", host.Id)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if identity.IPAddress != "" {
+		return identity.IPAddress, nil
+	}
+	return identity.HostName, nil
+}
+
+// GetSSHOptions generates the command line args to be passed to SSH to allow connection
+// to the machine.
+func (m *Manager) GetSSHOptions(host *host.Host, keyPath string) ([]string, error) {
+	if keyPath == "" {
+		return []string{}, errors.New("No key specified for host")
+	}
+
+	opts := []string{"-i", keyPath}
+	for _, opt := range host.Distro.SSHOptions {
+		opts = append(opts, "-o", opt)
+	}
+
+	return opts, nil
+}
+
+// TimeTilNextPayment always returns 0 since on-premises VMs are not billed.
+func (m *Manager) TimeTilNextPayment(host *host.Host) time.Duration {
+	return time.Duration(0)
+}
@@ -0,0 +1,255 @@
+package vsphere
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/pkg/errors"
+)
+
+const (
+	apiPrefix     = "/api"
+	sessionHeader = "vmware-api-session-id"
+)
+
+// errNotFound is returned by the client when the requested VM or template
+// does not exist.
+var errNotFound = errors.New("virtual machine not found")
+
+// The client interface wraps the vCenter REST API interaction.
+type client interface {
+	Init(*evergreen.VSphereConfig) error
+	FindVM(name string) (string, error)
+	CloneVM(spec *cloneSpec) (string, error)
+	GetVM(id string) (*vmInfo, error)
+	GetGuestIdentity(id string) (*guestIdentity, error)
+	PowerOn(id string) error
+	PowerOff(id string) error
+	DeleteVM(id string) error
+}
+
+// clientImpl talks to the vCenter REST API (vSphere 7.0 and later),
+// authenticating with a session created from basic credentials.
+type clientImpl struct {
+	httpClient *http.Client
+	baseURL    string
+	username   string
+	password   string
+
+	mu      sync.Mutex
+	session string
+}
+
+// Init validates the connection settings. It does not contact the server; a
+// session is created on the first call that needs one.
+func (c *clientImpl) Init(config *evergreen.VSphereConfig) error {
+	if config.Host == "" {
+		return errors.New("vSphere host must not be blank")
+	}
+	if config.Username == "" || config.Password == "" {
+		return errors.New("vSphere username and password must not be blank")
+	}
+	if _, err := url.Parse(config.Host); err != nil {
+		return errors.Wrapf(err, "invalid vSphere host '%s'", config.Host)
+	}
+
+	transport := &http.Transport{}
+	if config.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	c.httpClient = &http.Client{Timeout: 5 * time.Minute, Transport: transport}
+	c.baseURL = strings.TrimSuffix(config.Host, "/") + apiPrefix
+	c.username = config.Username
+	c.password = config.Password
+
+	return nil
+}
+
+// FindVM returns the identifier of the VM or template with the given name.
+func (c *clientImpl) FindVM(name string) (string, error) {
+	vms := []vmSummary{}
+	u := c.baseURL + "/vcenter/vm?names=" + url.QueryEscape(name)
+	if err := c.do("GET", u, nil, &vms); err != nil {
+		return "", errors.Wrap(err, "vSphere VM list API call failed")
+	}
+	if len(vms) == 0 {
+		return "", errNotFound
+	}
+	return vms[0].VM, nil
+}
+
+// CloneVM creates a new VM from the spec's source, returning its identifier.
+func (c *clientImpl) CloneVM(spec *cloneSpec) (string, error) {
+	var id string
+	if err := c.do("POST", c.baseURL+"/vcenter/vm?action=clone", spec, &id); err != nil {
+		return "", errors.Wrap(err, "vSphere VM clone API call failed")
+	}
+	return id, nil
+}
+
+// GetVM requests details on a single VM, by identifier.
+func (c *clientImpl) GetVM(id string) (*vmInfo, error) {
+	info := &vmInfo{}
+	if err := c.do("GET", c.vmURL(id, ""), nil, info); err != nil {
+		if err == errNotFound {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "vSphere VM get API call failed")
+	}
+	return info, nil
+}
+
+// GetGuestIdentity requests the guest operating system's view of a VM,
+// which is only available once VMware Tools is running.
+func (c *clientImpl) GetGuestIdentity(id string) (*guestIdentity, error) {
+	identity := &guestIdentity{}
+	if err := c.do("GET", c.vmURL(id, "/guest/identity"), nil, identity); err != nil {
+		if err == errNotFound {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "vSphere guest identity API call failed")
+	}
+	return identity, nil
+}
+
+// PowerOn starts a VM.
+func (c *clientImpl) PowerOn(id string) error {
+	return errors.Wrap(c.do("POST", c.vmURL(id, "/power?action=start"), nil, nil),
+		"vSphere power on API call failed")
+}
+
+// PowerOff stops a VM without shutting down its guest operating system.
+func (c *clientImpl) PowerOff(id string) error {
+	return errors.Wrap(c.do("POST", c.vmURL(id, "/power?action=stop"), nil, nil),
+		"vSphere power off API call failed")
+}
+
+// DeleteVM removes a powered off VM and its disks.
+func (c *clientImpl) DeleteVM(id string) error {
+	if err := c.do("DELETE", c.vmURL(id, ""), nil, nil); err != nil {
+		if err == errNotFound {
+			return err
+		}
+		return errors.Wrap(err, "vSphere VM delete API call failed")
+	}
+	return nil
+}
+
+func (c *clientImpl) vmURL(id, suffix string) string {
+	return c.baseURL + "/vcenter/vm/" + url.PathEscape(id) + suffix
+}
+
+// do sends a request within the current session, creating a new session if
+// there is none or the current one has expired.
+func (c *clientImpl) do(method, u string, in, out interface{}) error {
+	session, err := c.getSession(false)
+	if err != nil {
+		return err
+	}
+
+	status, body, err := c.send(method, u, session, in)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusUnauthorized {
+		if session, err = c.getSession(true); err != nil {
+			return err
+		}
+		if status, body, err = c.send(method, u, session, in); err != nil {
+			return err
+		}
+	}
+
+	if status == http.StatusNotFound {
+		return errNotFound
+	}
+	if status >= 300 {
+		apiErr := &apiError{}
+		if json.Unmarshal(body, apiErr) == nil && apiErr.message() != "" {
+			return errors.Errorf("%s: %s (%d)", apiErr.ErrorType, apiErr.message(), status)
+		}
+		return errors.Errorf("unexpected response status %d", status)
+	}
+
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	return errors.Wrap(json.Unmarshal(body, out), "could not decode response")
+}
+
+func (c *clientImpl) send(method, u, session string, in interface{}) (int, []byte, error) {
+	var reqBody []byte
+	var err error
+	if in != nil {
+		if reqBody, err = json.Marshal(in); err != nil {
+			return 0, nil, errors.Wrap(err, "could not marshal request")
+		}
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(reqBody))
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	req.Header.Set(sessionHeader, session)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "could not read response")
+	}
+	return resp.StatusCode, body, nil
+}
+
+// getSession returns the current session token, logging in if there is no
+// session or if refresh is set.
+func (c *clientImpl) getSession(refresh bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session != "" && !refresh {
+		return c.session, nil
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+"/session", nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	req.SetBasicAuth(c.username, c.password)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "vSphere session API call failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("vSphere login failed with status %s", resp.Status)
+	}
+
+	var session string
+	if err = json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return "", errors.Wrap(err, "could not decode vSphere session")
+	}
+	if session == "" {
+		return "", errors.New("vSphere login did not return a session")
+	}
+
+	c.session = session
+	return c.session, nil
+}
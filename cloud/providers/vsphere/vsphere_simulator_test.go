@@ -0,0 +1,223 @@
+package vsphere
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/evergreen-ci/evergreen"
+)
+
+const (
+	simulatorUser     = "administrator@vsphere.local"
+	simulatorPassword = "password"
+	simulatorTemplate = "ubuntu1604-template"
+)
+
+type simulatedVM struct {
+	id         string
+	name       string
+	powerState string
+	ipAddress  string
+	template   bool
+	placement  *placement
+}
+
+// vcenterSimulator is an HTTP server that implements enough of the vCenter
+// REST API to exercise the client: sessions, listing, cloning, power
+// operations and deletion of VMs.
+type vcenterSimulator struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	vms       map[string]*simulatedVM
+	sessions  map[string]bool
+	nextID    int
+	logins    int
+	failNext  bool
+	failClone bool
+}
+
+func newVCenterSimulator() *vcenterSimulator {
+	sim := &vcenterSimulator{
+		vms:      map[string]*simulatedVM{},
+		sessions: map[string]bool{},
+		nextID:   100,
+	}
+	sim.addVM(&simulatedVM{name: simulatorTemplate, powerState: PowerStateOff, template: true})
+	sim.server = httptest.NewServer(http.HandlerFunc(sim.handle))
+	return sim
+}
+
+func (sim *vcenterSimulator) Close() { sim.server.Close() }
+
+func (sim *vcenterSimulator) config() *evergreen.VSphereConfig {
+	return &evergreen.VSphereConfig{
+		Host:     sim.server.URL,
+		Username: simulatorUser,
+		Password: simulatorPassword,
+	}
+}
+
+func (sim *vcenterSimulator) addVM(vm *simulatedVM) string {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	return sim.addVMLocked(vm)
+}
+
+func (sim *vcenterSimulator) addVMLocked(vm *simulatedVM) string {
+	sim.nextID++
+	vm.id = fmt.Sprintf("vm-%d", sim.nextID)
+	sim.vms[vm.id] = vm
+	return vm.id
+}
+
+func (sim *vcenterSimulator) getVM(id string) *simulatedVM {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	return sim.vms[id]
+}
+
+// expireSessions invalidates all sessions, as happens when vCenter restarts
+// or a session times out.
+func (sim *vcenterSimulator) expireSessions() {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	sim.sessions = map[string]bool{}
+}
+
+func (sim *vcenterSimulator) handle(w http.ResponseWriter, r *http.Request) {
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+
+	if r.URL.Path == "/api/session" && r.Method == "POST" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != simulatorUser || pass != simulatorPassword {
+			writeSimulatorError(w, http.StatusUnauthorized, "UNAUTHENTICATED", "invalid credentials")
+			return
+		}
+		sim.logins++
+		session := fmt.Sprintf("session-%d", sim.logins)
+		sim.sessions[session] = true
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(session)
+		return
+	}
+
+	if !sim.sessions[r.Header.Get(sessionHeader)] {
+		writeSimulatorError(w, http.StatusUnauthorized, "UNAUTHENTICATED", "session is not authenticated")
+		return
+	}
+	if sim.failNext {
+		sim.failNext = false
+		writeSimulatorError(w, http.StatusInternalServerError, "ERROR", "simulated failure")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/vcenter/vm")
+	action := r.URL.Query().Get("action")
+
+	switch {
+	case path == "" && r.Method == "GET":
+		names := r.URL.Query()["names"]
+		vms := []vmSummary{}
+		for _, vm := range sim.vms {
+			for _, name := range names {
+				if vm.name == name {
+					vms = append(vms, vmSummary{VM: vm.id, Name: vm.name, PowerState: vm.powerState})
+				}
+			}
+		}
+		writeSimulatorJSON(w, http.StatusOK, vms)
+
+	case path == "" && r.Method == "POST" && action == "clone":
+		spec := &cloneSpec{}
+		if err := json.NewDecoder(r.Body).Decode(spec); err != nil {
+			writeSimulatorError(w, http.StatusBadRequest, "INVALID_ARGUMENT", err.Error())
+			return
+		}
+		if sim.failClone {
+			sim.failClone = false
+			writeSimulatorError(w, http.StatusServiceUnavailable, "RESOURCE_BUSY", "insufficient capacity")
+			return
+		}
+		if _, ok := sim.vms[spec.Source]; !ok {
+			writeSimulatorError(w, http.StatusNotFound, "NOT_FOUND", "source not found")
+			return
+		}
+		vm := &simulatedVM{name: spec.Name, powerState: PowerStateOff, placement: spec.Placement}
+		if spec.PowerOn {
+			vm.powerState = PowerStateOn
+		}
+		id := sim.addVMLocked(vm)
+		vm.ipAddress = fmt.Sprintf("192.0.2.%d", sim.nextID%250)
+		writeSimulatorJSON(w, http.StatusOK, id)
+
+	case strings.HasPrefix(path, "/"):
+		parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+		vm, ok := sim.vms[parts[0]]
+		if !ok {
+			writeSimulatorError(w, http.StatusNotFound, "NOT_FOUND", "vm not found")
+			return
+		}
+		sub := ""
+		if len(parts) == 2 {
+			sub = parts[1]
+		}
+		sim.handleVM(w, r, vm, sub, action)
+
+	default:
+		writeSimulatorError(w, http.StatusNotFound, "NOT_FOUND", "unknown resource")
+	}
+}
+
+func (sim *vcenterSimulator) handleVM(w http.ResponseWriter, r *http.Request, vm *simulatedVM, sub, action string) {
+	switch {
+	case sub == "" && r.Method == "GET":
+		info := vmInfo{Name: vm.name, PowerState: vm.powerState}
+		writeSimulatorJSON(w, http.StatusOK, info)
+
+	case sub == "" && r.Method == "DELETE":
+		if vm.powerState == PowerStateOn {
+			writeSimulatorError(w, http.StatusBadRequest, "RESOURCE_IN_USE", "vm is powered on")
+			return
+		}
+		delete(sim.vms, vm.id)
+		w.WriteHeader(http.StatusNoContent)
+
+	case sub == "guest/identity" && r.Method == "GET":
+		if vm.powerState != PowerStateOn {
+			writeSimulatorError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "guest tools not running")
+			return
+		}
+		writeSimulatorJSON(w, http.StatusOK, guestIdentity{HostName: vm.name, IPAddress: vm.ipAddress})
+
+	case sub == "power" && r.Method == "POST" && action == "start":
+		vm.powerState = PowerStateOn
+		w.WriteHeader(http.StatusNoContent)
+
+	case sub == "power" && r.Method == "POST" && action == "stop":
+		vm.powerState = PowerStateOff
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeSimulatorError(w, http.StatusNotFound, "NOT_FOUND", "unknown operation")
+	}
+}
+
+func writeSimulatorJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeSimulatorError(w http.ResponseWriter, status int, errorType, msg string) {
+	resp := map[string]interface{}{
+		"error_type": errorType,
+		"messages":   []map[string]string{{"id": "simulator.error", "default_message": msg}},
+	}
+	writeSimulatorJSON(w, status, resp)
+}
@@ -0,0 +1,250 @@
+package vsphere
+
+import (
+	"testing"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type VSphereSuite struct {
+	sim      *vcenterSimulator
+	manager  *Manager
+	distro   *distro.Distro
+	settings map[string]interface{}
+	suite.Suite
+}
+
+func TestVSphereSuite(t *testing.T) {
+	suite.Run(t, new(VSphereSuite))
+}
+
+func (s *VSphereSuite) SetupSuite() {
+	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(testutil.TestConfig()))
+}
+
+func (s *VSphereSuite) SetupTest() {
+	s.sim = newVCenterSimulator()
+	s.manager = &Manager{}
+	s.NoError(s.manager.Configure(&evergreen.Settings{
+		Providers: evergreen.CloudProviders{VSphere: *s.sim.config()},
+	}))
+
+	s.settings = map[string]interface{}{
+		"template":      simulatorTemplate,
+		"resource_pool": "resgroup-42",
+	}
+	s.distro = &distro.Distro{
+		Id:               "ubuntu1604-vsphere",
+		Provider:         ProviderName,
+		ProviderSettings: &s.settings,
+	}
+}
+
+func (s *VSphereSuite) TearDownTest() {
+	s.sim.Close()
+}
+
+func (s *VSphereSuite) TestValidateSettings() {
+	settingsOk := &ProviderSettings{Template: "template"}
+	s.NoError(settingsOk.Validate())
+
+	settingsNoTemplate := &ProviderSettings{Folder: "group-v3"}
+	s.Error(settingsNoTemplate.Validate())
+
+	settingsHostAndCluster := &ProviderSettings{
+		Template: "template",
+		Host:     "host-12",
+		Cluster:  "domain-c7",
+	}
+	s.Error(settingsHostAndCluster.Validate())
+
+	s.Nil(settingsOk.placement())
+	settingsOk.Datastore = "datastore-11"
+	s.Equal(&placement{Datastore: "datastore-11"}, settingsOk.placement())
+}
+
+func (s *VSphereSuite) TestConfigureRequiresCredentials() {
+	config := s.sim.config()
+
+	noHost := *config
+	noHost.Host = ""
+	s.Error((&Manager{}).Configure(&evergreen.Settings{
+		Providers: evergreen.CloudProviders{VSphere: noHost},
+	}))
+
+	noPassword := *config
+	noPassword.Password = ""
+	s.Error((&Manager{}).Configure(&evergreen.Settings{
+		Providers: evergreen.CloudProviders{VSphere: noPassword},
+	}))
+
+	badPassword := *config
+	badPassword.Password = "wrong"
+	m := &Manager{}
+	s.NoError(m.Configure(&evergreen.Settings{
+		Providers: evergreen.CloudProviders{VSphere: badPassword},
+	}))
+	_, err := m.client.FindVM(simulatorTemplate)
+	s.Error(err)
+}
+
+func (s *VSphereSuite) TestClientLifecycle() {
+	c := s.manager.client
+
+	templateID, err := c.FindVM(simulatorTemplate)
+	s.NoError(err)
+	_, err = c.FindVM("no-such-template")
+	s.Equal(errNotFound, err)
+
+	id, err := c.CloneVM(&cloneSpec{Name: "evg-test", Source: templateID})
+	s.NoError(err)
+
+	vm, err := c.GetVM(id)
+	s.NoError(err)
+	s.Equal("evg-test", vm.Name)
+	s.Equal(PowerStateOff, vm.PowerState)
+
+	s.NoError(c.PowerOn(id))
+	s.Error(c.DeleteVM(id))
+	s.NoError(c.PowerOff(id))
+	s.NoError(c.DeleteVM(id))
+
+	_, err = c.GetVM(id)
+	s.Equal(errNotFound, err)
+	s.Equal(errNotFound, c.DeleteVM(id))
+
+	// the session is reused across calls
+	s.Equal(1, s.sim.logins)
+}
+
+func (s *VSphereSuite) TestClientLogsInAgainWhenSessionExpires() {
+	_, err := s.manager.client.FindVM(simulatorTemplate)
+	s.NoError(err)
+
+	s.sim.expireSessions()
+	_, err = s.manager.client.FindVM(simulatorTemplate)
+	s.NoError(err)
+	s.Equal(2, s.sim.logins)
+}
+
+func (s *VSphereSuite) TestClientReportsAPIErrors() {
+	s.sim.failNext = true
+	_, err := s.manager.client.GetVM("vm-1")
+	s.Error(err)
+	s.NotEqual(errNotFound, err)
+	s.Contains(err.Error(), "simulated failure")
+}
+
+func (s *VSphereSuite) TestGetInstanceStatus() {
+	h := &host.Host{Id: "vm-nonexistent", Distro: *s.distro}
+
+	status, err := s.manager.GetInstanceStatus(h)
+	s.NoError(err)
+	s.Equal(cloud.StatusTerminated, status)
+
+	for powerState, expected := range map[string]cloud.CloudStatus{
+		PowerStateOn:        cloud.StatusRunning,
+		PowerStateOff:       cloud.StatusStopped,
+		PowerStateSuspended: cloud.StatusStopped,
+		"SOMETHING_ELSE":    cloud.StatusUnknown,
+	} {
+		h.Id = s.sim.addVM(&simulatedVM{name: "evg-status", powerState: powerState})
+		status, err = s.manager.GetInstanceStatus(h)
+		s.NoError(err)
+		s.Equal(expected, status, powerState)
+
+		up, err := s.manager.IsUp(h)
+		s.NoError(err)
+		s.Equal(expected == cloud.StatusRunning, up)
+	}
+}
+
+func (s *VSphereSuite) TestGetDNSName() {
+	id := s.sim.addVM(&simulatedVM{name: "evg-dns", powerState: PowerStateOff})
+	h := &host.Host{Id: id, Distro: *s.distro}
+
+	// guest tools are not running while the VM is off
+	_, err := s.manager.GetDNSName(h)
+	s.Error(err)
+
+	vm := s.sim.getVM(id)
+	vm.powerState = PowerStateOn
+	vm.ipAddress = "192.0.2.7"
+	dns, err := s.manager.GetDNSName(h)
+	s.NoError(err)
+	s.Equal("192.0.2.7", dns)
+
+	vm.ipAddress = ""
+	dns, err = s.manager.GetDNSName(h)
+	s.NoError(err)
+	s.Equal("evg-dns", dns)
+}
+
+func (s *VSphereSuite) TestSpawnInstance() {
+	h, err := s.manager.SpawnInstance(s.distro, cloud.HostOptions{UserName: evergreen.User})
+	s.NoError(err)
+	s.Require().NotNil(h)
+
+	vm := s.sim.getVM(h.Id)
+	s.Require().NotNil(vm)
+	s.Equal(PowerStateOn, vm.powerState)
+	s.Equal(&placement{ResourcePool: "resgroup-42"}, vm.placement)
+
+	dbHost, err := host.FindOne(host.ById(h.Id))
+	s.NoError(err)
+	s.Require().NotNil(dbHost)
+	s.Equal(ProviderName, dbHost.Provider)
+}
+
+func (s *VSphereSuite) TestSpawnInstanceFailureRemovesIntentHost() {
+	s.settings["template"] = "no-such-template"
+	h, err := s.manager.SpawnInstance(s.distro, cloud.HostOptions{UserName: evergreen.User})
+	s.Error(err)
+	s.Nil(h)
+
+	s.settings["template"] = simulatorTemplate
+	s.sim.failClone = true
+	h, err = s.manager.SpawnInstance(s.distro, cloud.HostOptions{UserName: evergreen.User})
+	s.Error(err)
+	s.Nil(h)
+
+	hosts, err := host.Find(host.ByDistroId(s.distro.Id))
+	s.NoError(err)
+	s.Len(hosts, 0)
+}
+
+func (s *VSphereSuite) TestSpawnInstanceInvalidSettings() {
+	d := *s.distro
+	d.Provider = "ec2"
+	_, err := s.manager.SpawnInstance(&d, cloud.HostOptions{})
+	s.Error(err)
+
+	d = *s.distro
+	d.ProviderSettings = &map[string]interface{}{"folder": "group-v3"}
+	_, err = s.manager.SpawnInstance(&d, cloud.HostOptions{})
+	s.Error(err)
+}
+
+func (s *VSphereSuite) TestTerminateInstance() {
+	id := s.sim.addVM(&simulatedVM{name: "evg-terminate", powerState: PowerStateOn})
+	h := &host.Host{Id: id, Distro: *s.distro, Status: evergreen.HostRunning}
+	s.NoError(h.Insert())
+
+	s.NoError(s.manager.TerminateInstance(h))
+	s.Nil(s.sim.getVM(id))
+	s.Equal(evergreen.HostTerminated, h.Status)
+
+	s.Error(s.manager.TerminateInstance(h))
+
+	// a VM that was already deleted only needs its host document updated
+	gone := &host.Host{Id: "vm-gone", Distro: *s.distro, Status: evergreen.HostRunning}
+	s.NoError(gone.Insert())
+	s.NoError(s.manager.TerminateInstance(gone))
+	s.Equal(evergreen.HostTerminated, gone.Status)
+}
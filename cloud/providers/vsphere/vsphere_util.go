@@ -0,0 +1,95 @@
+package vsphere
+
+import (
+	"strings"
+
+	"github.com/evergreen-ci/evergreen/cloud"
+)
+
+const (
+	// PowerStateOn means the VM is running.
+	PowerStateOn = "POWERED_ON"
+	// PowerStateOff means the VM is stopped.
+	PowerStateOff = "POWERED_OFF"
+	// PowerStateSuspended means the VM's memory has been saved to disk and
+	// it is not running.
+	PowerStateSuspended = "SUSPENDED"
+)
+
+// vmSummary is an entry in the response to a VM list request.
+type vmSummary struct {
+	VM         string `json:"vm"`
+	Name       string `json:"name"`
+	PowerState string `json:"power_state"`
+}
+
+// cloneSpec is the body of a request to clone a VM.
+type cloneSpec struct {
+	Name      string     `json:"name"`
+	Source    string     `json:"source"`
+	Placement *placement `json:"placement,omitempty"`
+	PowerOn   bool       `json:"power_on"`
+}
+
+// placement specifies where a cloned VM is created. Unset fields default to
+// those of the source VM.
+type placement struct {
+	Folder       string `json:"folder,omitempty"`
+	ResourcePool string `json:"resource_pool,omitempty"`
+	Host         string `json:"host,omitempty"`
+	Cluster      string `json:"cluster,omitempty"`
+	Datastore    string `json:"datastore,omitempty"`
+}
+
+func (p *placement) isEmpty() bool {
+	return *p == placement{}
+}
+
+// vmInfo is the subset of the VM resource that Evergreen reads.
+type vmInfo struct {
+	Name       string `json:"name"`
+	PowerState string `json:"power_state"`
+	CPU        struct {
+		Count int `json:"count"`
+	} `json:"cpu"`
+	Memory struct {
+		SizeMiB int `json:"size_MiB"`
+	} `json:"memory"`
+}
+
+// guestIdentity is the guest operating system's view of a VM.
+type guestIdentity struct {
+	HostName  string `json:"host_name"`
+	IPAddress string `json:"ip_address"`
+	Family    string `json:"family"`
+}
+
+// apiError is the body of an error response from the REST API.
+type apiError struct {
+	ErrorType string `json:"error_type"`
+	Messages  []struct {
+		ID             string `json:"id"`
+		DefaultMessage string `json:"default_message"`
+	} `json:"messages"`
+}
+
+func (e *apiError) message() string {
+	msgs := []string{}
+	for _, m := range e.Messages {
+		msgs = append(msgs, m.DefaultMessage)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func vsphereStatusToEvgStatus(powerState string) cloud.CloudStatus {
+	// Note: There is no equivalent to the 'terminated' power state since VMs are no
+	// longer detectable once they have been deleted.
+	switch powerState {
+	case PowerStateOn:
+		return cloud.StatusRunning
+	case PowerStateOff, PowerStateSuspended:
+		return cloud.StatusStopped
+	default:
+		return cloud.StatusUnknown
+	}
+}
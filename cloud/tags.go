@@ -0,0 +1,136 @@
+package cloud
+
+import (
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/model/host"
+)
+
+// Tag keys that providers apply, as tags, labels or metadata, to the
+// instances Evergreen creates.
+const (
+	TagName       = "name"
+	TagDistro     = "distro"
+	TagProvider   = "evergreen-provider"
+	TagService    = "evergreen-service"
+	TagUsername   = "username"
+	TagOwner      = "owner"
+	TagMode       = "mode"
+	TagStartTime  = "start-time"
+	TagExpireOn   = "expire-on"
+	TagProject    = "project"
+	TagTask       = "task"
+	TagCostCenter = "cost-center"
+	TagManagedBy  = "managed-by"
+
+	// ManagedByEvergreen is the value of the managed-by tag on every instance
+	// that Evergreen creates, which is how orphaned instances are found.
+	ManagedByEvergreen = "evergreen"
+
+	// TagTimeFormat is the format of the start-time tag.
+	TagTimeFormat = "20060102150405"
+	// ExpireOnFormat is the format of the expire-on tag.
+	ExpireOnFormat = "2006-01-02"
+
+	// HostExpireDays and SpawnHostExpireDays are how long after they start
+	// hosts and spawn hosts are tagged to expire. The expire-on tag is used by
+	// external reapers to clean up instances that Evergreen loses track of,
+	// so it is deliberately later than any expiration Evergreen enforces.
+	HostExpireDays      = 30
+	SpawnHostExpireDays = 90
+)
+
+// MakeTags returns the tags to apply to a host's instance. The distro's tags
+// are applied first, so that they can be overridden by the host's ownership
+// metadata, which in turn cannot override the tags Evergreen relies on.
+//
+// The DigitalOcean and vSphere providers don't apply tags: the DigitalOcean
+// API Evergreen uses has no tags, and vSphere's tags are names in categories,
+// not key-value pairs, so tags that differ for each host would each need a
+// tag of their own. Their instances aren't checked for orphans either.
+func MakeTags(h *host.Host) map[string]string {
+	tags := map[string]string{}
+	for k, v := range h.Distro.InstanceTags {
+		tags[k] = v
+	}
+	if h.Distro.CostCenter != "" {
+		tags[TagCostCenter] = h.Distro.CostCenter
+	}
+	for k, v := range h.InstanceTags {
+		tags[k] = v
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Name
+	}
+
+	created := h.CreationTime
+	if created.IsZero() {
+		created = time.Now()
+	}
+	expireOn := created.AddDate(0, 0, HostExpireDays)
+	if h.UserHost {
+		expireOn = created.AddDate(0, 0, SpawnHostExpireDays)
+	}
+
+	tags[TagName] = h.Id
+	tags[TagDistro] = h.Distro.Id
+	tags[TagProvider] = h.Provider
+	tags[TagService] = hostname
+	tags[TagUsername] = username
+	tags[TagOwner] = h.StartedBy
+	tags[TagMode] = "production"
+	if h.UserHost {
+		tags[TagMode] = "testing"
+	}
+	tags[TagStartTime] = created.Format(TagTimeFormat)
+	tags[TagExpireOn] = expireOn.Format(ExpireOnFormat)
+	tags[TagManagedBy] = ManagedByEvergreen
+
+	return tags
+}
+
+// ManagedInstance is an instance, found in a provider, that was tagged as
+// created by Evergreen.
+type ManagedInstance struct {
+	// Id is the identifier Evergreen uses for the instance's host.
+	Id string
+	// Location is where the instance runs, e.g. its zone, if the provider
+	// needs it to address the instance.
+	Location  string
+	Tags      map[string]string
+	CreatedAt time.Time
+}
+
+// Age returns how long ago the instance was created, falling back to its
+// start-time tag if the provider did not report a creation time. Instances
+// of unknown age are treated as old.
+func (i *ManagedInstance) Age(now time.Time) time.Duration {
+	created := i.CreatedAt
+	if created.IsZero() {
+		var err error
+		if created, err = time.Parse(TagTimeFormat, i.Tags[TagStartTime]); err != nil {
+			return time.Duration(1<<63 - 1)
+		}
+	}
+	return now.Sub(created)
+}
+
+// InstanceLister is implemented by cloud managers that can list the instances
+// Evergreen created in the provider, so that instances without a matching host
+// can be found and terminated.
+type InstanceLister interface {
+	// ListManagedInstances returns all instances that are not terminated and
+	// are tagged as created by Evergreen through this provider.
+	ListManagedInstances() ([]ManagedInstance, error)
+
+	// TerminateManagedInstance destroys an instance that has no host.
+	TerminateManagedInstance(ManagedInstance) error
+}
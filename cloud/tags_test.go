@@ -0,0 +1,70 @@
+package cloud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeTags(t *testing.T) {
+	assert := assert.New(t)
+
+	created := time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+	h := &host.Host{
+		Id:           "h1",
+		Provider:     "mock",
+		StartedBy:    "someone",
+		CreationTime: created,
+		Distro: distro.Distro{
+			Id:         "d1",
+			CostCenter: "ci",
+			InstanceTags: map[string]string{
+				"team":       "build",
+				TagOwner:     "distro-owner",
+				TagManagedBy: "someone-else",
+			},
+		},
+		InstanceTags: map[string]string{
+			TagProject:    "evergreen",
+			TagCostCenter: "project-budget",
+		},
+	}
+
+	tags := MakeTags(h)
+	assert.Equal("h1", tags[TagName])
+	assert.Equal("d1", tags[TagDistro])
+	assert.Equal("mock", tags[TagProvider])
+	assert.Equal("build", tags["team"])
+	assert.Equal("evergreen", tags[TagProject])
+	assert.Equal("project-budget", tags[TagCostCenter])
+	assert.Equal("someone", tags[TagOwner])
+	assert.Equal(ManagedByEvergreen, tags[TagManagedBy])
+	assert.Equal("production", tags[TagMode])
+	assert.Equal("20170601120000", tags[TagStartTime])
+	assert.Equal("2017-07-01", tags[TagExpireOn])
+
+	h.UserHost = true
+	h.InstanceTags = nil
+	tags = MakeTags(h)
+	assert.Equal("testing", tags[TagMode])
+	assert.Equal("ci", tags[TagCostCenter])
+	assert.Equal("2017-08-30", tags[TagExpireOn])
+}
+
+func TestMakeHostTags(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(makeHostTags(HostOptions{}))
+
+	tags := makeHostTags(HostOptions{
+		ProvisionOptions: &host.ProvisionOptions{TaskId: "t1"},
+		ProjectId:        "p1",
+		Tags:             map[string]string{"team": "build"},
+	})
+	assert.Equal("t1", tags[TagTask])
+	assert.Equal("p1", tags[TagProject])
+	assert.Equal("build", tags["team"])
+}
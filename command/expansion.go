@@ -1,6 +1,7 @@
 package command
 
 import (
+	"fmt"
 	"io/ioutil"
 	"regexp"
 	"strings"
@@ -32,21 +33,71 @@ func (self *Expansions) Update(newItems map[string]string) {
 }
 
 // Read a map of keys/values from the given file, and update the expansions
-// to include them (overwriting any duplicates with the new value).
+// to include them (overwriting any duplicates with the new value). Nested
+// maps are flattened into keys joined by '.', so that a "host" key within a
+// "server" map gives "server.host". Lists are flattened into a key for each
+// of their elements, suffixed by its index, as well as a key for the whole
+// list, whose value is its elements separated by spaces.
 func (self *Expansions) UpdateFromYaml(filename string) error {
 	filedata, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return err
 	}
-	newExpansions := make(map[string]string)
+	newExpansions := make(map[string]yamlExpansion)
 	err = yaml.Unmarshal(filedata, newExpansions)
 	if err != nil {
 		return err
 	}
-	self.Update(newExpansions)
+	for key, value := range newExpansions {
+		value.flatten(key, *self)
+	}
 	return nil
 }
 
+// yamlExpansion is a value in a file of expansions, which is either a
+// scalar, a list or a map.
+type yamlExpansion struct {
+	scalar *string
+	list   []yamlExpansion
+	nested map[string]yamlExpansion
+}
+
+func (e *yamlExpansion) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// scalars are read as strings so they keep their text, e.g. "1.10"
+	var scalar string
+	if err := unmarshal(&scalar); err == nil {
+		e.scalar = &scalar
+		return nil
+	}
+	var list []yamlExpansion
+	if err := unmarshal(&list); err == nil {
+		e.list = list
+		return nil
+	}
+	return unmarshal(&e.nested)
+}
+
+// flatten puts the value, under the given key, into the expansions.
+func (e yamlExpansion) flatten(key string, out Expansions) {
+	switch {
+	case e.scalar != nil:
+		out.Put(key, *e.scalar)
+	case e.nested != nil:
+		for k, v := range e.nested {
+			v.flatten(key+"."+k, out)
+		}
+	default:
+		elements := []string{}
+		for i, v := range e.list {
+			v.flatten(fmt.Sprintf("%s.%d", key, i), out)
+			if v.scalar != nil {
+				elements = append(elements, *v.scalar)
+			}
+		}
+		out.Put(key, strings.Join(elements, " "))
+	}
+}
+
 // Set a single value in the expansions.
 func (self *Expansions) Put(expansion string, value string) {
 	(*self)[expansion] = value
@@ -83,6 +83,26 @@ func TestExpansions(t *testing.T) {
 			So(expansions.Get("key1"), ShouldEqual, "blah")
 		})
 
+		Convey("updating from a yaml file should flatten nested keys and lists", func() {
+			expansions := NewExpansions(map[string]string{})
+
+			err := expansions.UpdateFromYaml(filepath.Join(
+				testutil.GetDirectoryOfFile(), "testdata", "nested_expansions.yml"))
+			So(err, ShouldBeNil)
+			So(expansions.Get("version"), ShouldEqual, "1.10")
+			So(expansions.Get("enabled"), ShouldEqual, "true")
+			So(expansions.Exists("empty"), ShouldBeTrue)
+			So(expansions.Get("empty"), ShouldEqual, "")
+			So(expansions.Get("server.host"), ShouldEqual, "example.com")
+			So(expansions.Get("server.ports.https"), ShouldEqual, "443")
+			So(expansions.Exists("server"), ShouldBeFalse)
+			So(expansions.Get("hosts"), ShouldEqual, "alpha beta gamma")
+			So(expansions.Get("hosts.1"), ShouldEqual, "beta")
+			So(expansions.Get("services.0.port"), ShouldEqual, "27017")
+			So(expansions.Get("services.1.name"), ShouldEqual, "web")
+			So(expansions.Get("services"), ShouldEqual, "")
+		})
+
 	})
 }
 
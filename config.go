@@ -116,6 +116,9 @@ type UIConfig struct {
 // MonitorConfig holds logging settings for the monitor process.
 type MonitorConfig struct {
 	LogFile string
+	// TerminateOrphanedInstances makes the monitor terminate instances that
+	// were created by Evergreen but have no host, rather than only report them.
+	TerminateOrphanedInstances bool
 }
 
 // RunnerConfig holds logging and timing settings for the runner process.
@@ -163,6 +166,8 @@ type CloudProviders struct {
 	AWS          AWSConfig          `yaml:"aws"`
 	DigitalOcean DigitalOceanConfig `yaml:"digitalocean"`
 	OpenStack    OpenStackConfig    `yaml:"openstack"`
+	GCE          GCEConfig          `yaml:"gce"`
+	VSphere      VSphereConfig      `yaml:"vsphere"`
 }
 
 // AWSConfig stores auth info for Amazon Web Services.
@@ -177,6 +182,33 @@ type DigitalOceanConfig struct {
 	Key      string `yaml:"key"`
 }
 
+// GCEConfig stores auth info for Google Compute Engine, using the fields of a
+// service account's JSON key file. ProjectID, ClientEmail and PrivateKey are
+// required.
+type GCEConfig struct {
+	ProjectID    string `yaml:"project_id"`
+	ClientEmail  string `yaml:"client_email"`
+	PrivateKey   string `yaml:"private_key"`
+	PrivateKeyID string `yaml:"private_key_id"`
+	TokenURI     string `yaml:"token_uri"`
+
+	// Endpoint overrides the base URL of the Compute Engine API.
+	Endpoint string `yaml:"endpoint"`
+}
+
+// VSphereConfig stores auth info for a vCenter server's REST API. Host,
+// Username and Password are required.
+type VSphereConfig struct {
+	// Host is the base URL of the vCenter server, e.g. https://vcenter.example.com.
+	Host     string `yaml:"host"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	// Insecure skips verification of the server's TLS certificate, for
+	// servers using self-signed certificates.
+	Insecure bool `yaml:"insecure"`
+}
+
 // OpenStackConfig stores auth info for Linaro using Identity V3. All fields required.
 //
 // The config is NOT compatible with Identity V2.
@@ -31,6 +31,7 @@ const (
 	TaskSucceeded        = "success"
 	TaskInactive         = "inactive"
 	TaskSystemFailed     = "system-failed"
+	TaskSetupFailed      = "setup-failed"
 	TaskTimedOut         = "task-timed-out"
 	TaskSystemUnresponse = "system-unresponsive"
 	TaskSystemTimedOut   = "system-timed-out"
@@ -97,6 +98,8 @@ const (
 	TaskSecretHeader = "Task-Secret"
 	HostHeader       = "Host-Id"
 	HostSecretHeader = "Host-Secret"
+	// AgentRevisionHeader is sent by agents that can update themselves.
+	AgentRevisionHeader = "Agent-Revision"
 )
 
 // HTTP constants. Added after Go1.4. Here for compatibility with GCCGO
@@ -85,6 +85,13 @@ func (init *HostInit) setupReadyHosts() error {
 			continue
 		}
 
+		// a host that starts its own agent has already run its setup script,
+		// and is marked provisioned when its agent registers
+		if !h.Distro.BootstrapsOverSSH() {
+			grip.Debugf("Host %s is up, waiting for its agent to register", h.Id)
+			continue
+		}
+
 		grip.Infoln("Running setup script for host", h.Id)
 
 		// kick off the setup, in its own goroutine, so pending setups don't have
@@ -174,6 +181,11 @@ func (init *HostInit) IsHostReady(host *host.Host) (bool, error) {
 		}
 	}
 
+	// a host that starts its own agent need not be reachable via SSH
+	if !host.Distro.BootstrapsOverSSH() {
+		return true, nil
+	}
+
 	// check if the host is reachable via SSH
 	cloudHost, err := providers.GetCloudHost(host, init.Settings)
 	if err != nil {
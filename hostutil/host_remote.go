@@ -2,6 +2,7 @@ package hostutil
 
 import (
 	"bytes"
+	"strings"
 	"time"
 
 	"github.com/evergreen-ci/evergreen/command"
@@ -14,6 +15,23 @@ const SSHTimeout = time.Minute * 10
 // RunRemoteScript executes a shell script that already exists on the remote host,
 // returning logs and any errors that occur. Logs may still be returned for some errors.
 func RunRemoteScript(h *host.Host, script string, sshOptions []string) (string, error) {
+	// run the remote script as sudo, if appropriate
+	sudoStr := ""
+	if h.Distro.SetupAsSudo {
+		sudoStr = "sudo "
+	}
+	return runRemote(h, sudoStr+"sh "+script, h.Distro.SetupAsSudo, sshOptions)
+}
+
+// RunRemoteScriptContents executes the given shell script on the remote host as
+// the distro's user, returning logs and any errors that occur. Logs may still be
+// returned for some errors.
+func RunRemoteScriptContents(h *host.Host, script string, sshOptions []string) (string, error) {
+	quoted := "'" + strings.Replace(script, "'", `'\''`, -1) + "'"
+	return runRemote(h, "sh -c "+quoted, false, sshOptions)
+}
+
+func runRemote(h *host.Host, cmdString string, forceTTY bool, sshOptions []string) (string, error) {
 	// parse the hostname into the user, host and port
 	hostInfo, err := util.ParseSSHInfo(h.Host)
 	if err != nil {
@@ -24,18 +42,13 @@ func RunRemoteScript(h *host.Host, script string, sshOptions []string) (string,
 		user = hostInfo.User
 	}
 
-	// run the remote script as sudo, if appropriate
-	sudoStr := ""
-	if h.Distro.SetupAsSudo {
-		sudoStr = "sudo "
-	}
 	// run command to ssh into remote machine and execute script
 	sshCmdStd := &util.CappedWriter{
 		Buffer:   &bytes.Buffer{},
 		MaxBytes: 1024 * 1024, // 1MB
 	}
 	cmd := &command.RemoteCommand{
-		CmdString:      sudoStr + "sh " + script,
+		CmdString:      cmdString,
 		Stdout:         sshCmdStd,
 		Stderr:         sshCmdStd,
 		RemoteHostName: hostInfo.Hostname,
@@ -44,7 +57,7 @@ func RunRemoteScript(h *host.Host, script string, sshOptions []string) (string,
 		Background:     false,
 	}
 	// force creation of a tty if sudo
-	if h.Distro.SetupAsSudo {
+	if forceTTY {
 		cmd.Options = []string{"-t", "-t", "-p", hostInfo.Port}
 	}
 	cmd.Options = append(cmd.Options, sshOptions...)
@@ -35,6 +35,15 @@ type File struct {
 	Link string `json:"link" bson:"link"`
 	// Visibility determines who can see the file in the UI
 	Visibility string `json:"visibility" bson:"visibility"`
+	// ContentMD5 and ContentSHA256 are the hex-encoded checksums of the file,
+	// if they were computed when it was uploaded
+	ContentMD5    string `json:"content_md5,omitempty" bson:"content_md5,omitempty"`
+	ContentSHA256 string `json:"content_sha256,omitempty" bson:"content_sha256,omitempty"`
+	// Backend is the type of artifact store the file is kept in, and Bucket
+	// and Key are where in it, if it was stored by an artifact command
+	Backend string `json:"backend,omitempty" bson:"backend,omitempty"`
+	Bucket  string `json:"bucket,omitempty" bson:"bucket,omitempty"`
+	Key     string `json:"key,omitempty" bson:"key,omitempty"`
 }
 
 // Array turns the parameter map into an array of File structs.
@@ -42,7 +51,7 @@ type File struct {
 func (params Params) Array() []File {
 	var files []File
 	for name, link := range params {
-		files = append(files, File{name, link, ""})
+		files = append(files, File{Name: name, Link: link})
 	}
 	return files
 }
@@ -72,11 +72,15 @@ func SetCachedTaskStarted(buildId, taskId string, startTime time.Time) error {
 // SetCachedTaskFinished sets the given task to "finished"
 // along with a time taken in the cache of the given build.
 func SetCachedTaskFinished(buildId, taskId string, detail *apimodels.TaskEndDetail, timeTaken time.Duration) error {
+	// the command trace is only needed on the task itself, so don't copy
+	// it into every build document
+	cached := *detail
+	cached.Commands = nil
 	return updateOneTaskCache(buildId, taskId, bson.M{
 		"$set": bson.M{
 			TasksKey + ".$." + TaskCacheTimeTakenKey:     timeTaken,
 			TasksKey + ".$." + TaskCacheStatusKey:        detail.Status,
-			TasksKey + ".$." + TaskCacheStatusDetailsKey: detail,
+			TasksKey + ".$." + TaskCacheStatusDetailsKey: cached,
 		},
 	})
 }
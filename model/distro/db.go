@@ -11,6 +11,8 @@ var (
 	IdKey               = bsonutil.MustHaveTag(Distro{}, "Id")
 	ArchKey             = bsonutil.MustHaveTag(Distro{}, "Arch")
 	PoolSizeKey         = bsonutil.MustHaveTag(Distro{}, "PoolSize")
+	MinHostsKey         = bsonutil.MustHaveTag(Distro{}, "MinHosts")
+	WarmSchedulesKey    = bsonutil.MustHaveTag(Distro{}, "WarmSchedules")
 	ProviderKey         = bsonutil.MustHaveTag(Distro{}, "Provider")
 	ProviderSettingsKey = bsonutil.MustHaveTag(Distro{}, "ProviderSettings")
 	SetupAsSudoKey      = bsonutil.MustHaveTag(Distro{}, "SetupAsSudo")
@@ -20,10 +22,13 @@ var (
 	SSHOptionsKey       = bsonutil.MustHaveTag(Distro{}, "SSHOptions")
 	WorkDirKey          = bsonutil.MustHaveTag(Distro{}, "WorkDir")
 
-	UserDataKey = bsonutil.MustHaveTag(Distro{}, "UserData")
+	UserDataKey        = bsonutil.MustHaveTag(Distro{}, "UserData")
+	BootstrapMethodKey = bsonutil.MustHaveTag(Distro{}, "BootstrapMethod")
 
 	SpawnAllowedKey = bsonutil.MustHaveTag(Distro{}, "SpawnAllowed")
 	ExpansionsKey   = bsonutil.MustHaveTag(Distro{}, "Expansions")
+	CostCenterKey   = bsonutil.MustHaveTag(Distro{}, "CostCenter")
+	InstanceTagsKey = bsonutil.MustHaveTag(Distro{}, "InstanceTags")
 
 	// bson fields for the UserData struct
 	UserDataFileKey     = bsonutil.MustHaveTag(UserData{}, "File")
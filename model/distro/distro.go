@@ -15,11 +15,24 @@ const (
 	NameTimeFormat               = "20060102150405"
 )
 
+// Ways a distro's hosts get their agent started
+const (
+	// BootstrapMethodSSH means the task runner copies the agent to the host
+	// and starts it over SSH. It is the default.
+	BootstrapMethodSSH = "ssh"
+	// BootstrapMethodUserData means the host starts the agent itself, from
+	// the user data or container entrypoint it is created with, and the agent
+	// registers the host with the API server.
+	BootstrapMethodUserData = "user-data"
+)
+
 type Distro struct {
 	Id               string                  `bson:"_id" json:"_id,omitempty" mapstructure:"_id,omitempty"`
 	Arch             string                  `bson:"arch" json:"arch,omitempty" mapstructure:"arch,omitempty"`
 	WorkDir          string                  `bson:"work_dir" json:"work_dir,omitempty" mapstructure:"work_dir,omitempty"`
 	PoolSize         int                     `bson:"pool_size,omitempty" json:"pool_size,omitempty" mapstructure:"pool_size,omitempty" yaml:"poolsize"`
+	MinHosts         int                     `bson:"min_hosts,omitempty" json:"min_hosts,omitempty" mapstructure:"min_hosts,omitempty" yaml:"min_hosts"`
+	WarmSchedules    []WarmSchedule          `bson:"warm_schedules,omitempty" json:"warm_schedules,omitempty" mapstructure:"warm_schedules,omitempty" yaml:"warm_schedules"`
 	Provider         string                  `bson:"provider" json:"provider,omitempty" mapstructure:"provider,omitempty"`
 	ProviderSettings *map[string]interface{} `bson:"settings" json:"settings,omitempty" mapstructure:"settings,omitempty"`
 
@@ -31,8 +44,18 @@ type Distro struct {
 	SSHOptions  []string `bson:"ssh_options,omitempty" json:"ssh_options,omitempty" mapstructure:"ssh_options,omitempty"`
 	UserData    UserData `bson:"user_data,omitempty" json:"user_data,omitempty" mapstructure:"user_data,omitempty"`
 
+	// BootstrapMethod is how the agent is started on the distro's hosts. If
+	// it is empty, BootstrapMethodSSH is used.
+	BootstrapMethod string `bson:"bootstrap_method,omitempty" json:"bootstrap_method,omitempty" mapstructure:"bootstrap_method,omitempty"`
+
 	SpawnAllowed bool        `bson:"spawn_allowed" json:"spawn_allowed,omitempty" mapstructure:"spawn_allowed,omitempty"`
 	Expansions   []Expansion `bson:"expansions,omitempty" json:"expansions,omitempty" mapstructure:"expansions,omitempty"`
+
+	// CostCenter and InstanceTags are applied as tags or labels to the distro's
+	// instances by providers that support them, which are all but DigitalOcean
+	// and vSphere.
+	CostCenter   string            `bson:"cost_center,omitempty" json:"cost_center,omitempty" mapstructure:"cost_center,omitempty"`
+	InstanceTags map[string]string `bson:"instance_tags,omitempty" json:"instance_tags,omitempty" mapstructure:"instance_tags,omitempty"`
 }
 
 type ValidateFormat string
@@ -47,6 +70,12 @@ type Expansion struct {
 	Value string `bson:"value,omitempty" json:"value,omitempty"`
 }
 
+// BootstrapsOverSSH returns true if the distro's hosts are set up and have
+// their agent started over SSH, rather than starting the agent themselves.
+func (d *Distro) BootstrapsOverSSH() bool {
+	return d.BootstrapMethod == "" || d.BootstrapMethod == BootstrapMethodSSH
+}
+
 // GenerateName generates a unique instance name for a distro.
 func (d *Distro) GenerateName() string {
 	return "evg_" + d.Id + "_" + time.Now().Format(NameTimeFormat) +
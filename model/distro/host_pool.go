@@ -0,0 +1,153 @@
+package distro
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WarmSchedule describes a recurring window of time during which a distro
+// should keep a minimum number of hosts provisioned, even if there are no
+// tasks waiting to run on them.
+type WarmSchedule struct {
+	// Days is the list of days on which the schedule applies. Entries are
+	// day names (e.g. "monday"), or one of "weekdays" or "weekends". An empty
+	// list means that the schedule applies every day.
+	Days []string `bson:"days,omitempty" json:"days,omitempty" mapstructure:"days,omitempty" yaml:"days"`
+
+	// StartHour and EndHour bound the window, in hours of the day [0, 24),
+	// in the schedule's time zone. The window includes StartHour and
+	// excludes EndHour; a window where EndHour is before StartHour wraps
+	// around midnight.
+	StartHour int `bson:"start_hour" json:"start_hour" mapstructure:"start_hour" yaml:"start_hour"`
+	EndHour   int `bson:"end_hour" json:"end_hour" mapstructure:"end_hour" yaml:"end_hour"`
+
+	// TimeZone is the IANA name of the time zone the hours refer to. It
+	// defaults to UTC.
+	TimeZone string `bson:"time_zone,omitempty" json:"time_zone,omitempty" mapstructure:"time_zone,omitempty" yaml:"time_zone"`
+
+	// MinHosts is the number of hosts to keep provisioned while the window
+	// is active.
+	MinHosts int `bson:"min_hosts" json:"min_hosts" mapstructure:"min_hosts" yaml:"min_hosts"`
+}
+
+const (
+	warmScheduleWeekdays = "weekdays"
+	warmScheduleWeekends = "weekends"
+)
+
+// Validate checks that the schedule's days, hours and time zone are
+// well-formed.
+func (s *WarmSchedule) Validate() error {
+	if s.StartHour < 0 || s.StartHour > 23 {
+		return errors.Errorf("start hour %d must be between 0 and 23", s.StartHour)
+	}
+	if s.EndHour < 0 || s.EndHour > 24 {
+		return errors.Errorf("end hour %d must be between 0 and 24", s.EndHour)
+	}
+	if s.StartHour == s.EndHour {
+		return errors.New("start hour and end hour cannot be the same")
+	}
+	if s.MinHosts < 0 {
+		return errors.Errorf("minimum hosts %d cannot be negative", s.MinHosts)
+	}
+	for _, day := range s.Days {
+		if _, err := parseWarmScheduleDay(day); err != nil {
+			return err
+		}
+	}
+	if _, err := s.location(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// IsActive returns true if the given time falls within the schedule's
+// window. Schedules that are not valid are never active.
+func (s *WarmSchedule) IsActive(now time.Time) bool {
+	loc, err := s.location()
+	if err != nil {
+		return false
+	}
+	now = now.In(loc)
+	hour := now.Hour()
+
+	// for windows that wrap around midnight, the early hours belong to the
+	// window that started on the previous day
+	day := now.Weekday()
+	if s.EndHour < s.StartHour {
+		if hour >= s.EndHour && hour < s.StartHour {
+			return false
+		}
+		if hour < s.EndHour {
+			day = (day + 6) % 7
+		}
+	} else if hour < s.StartHour || hour >= s.EndHour {
+		return false
+	}
+
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		days, err := parseWarmScheduleDay(d)
+		if err != nil {
+			return false
+		}
+		for _, wd := range days {
+			if wd == day {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *WarmSchedule) location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid time zone '%s'", s.TimeZone)
+	}
+	return loc, nil
+}
+
+// parseWarmScheduleDay converts a day name, or the "weekdays" and "weekends"
+// shorthands, to the days of the week it covers.
+func parseWarmScheduleDay(day string) ([]time.Weekday, error) {
+	switch strings.ToLower(day) {
+	case warmScheduleWeekdays:
+		return []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, nil
+	case warmScheduleWeekends:
+		return []time.Weekday{time.Saturday, time.Sunday}, nil
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(day, wd.String()) {
+			return []time.Weekday{wd}, nil
+		}
+	}
+	return nil, errors.Errorf("invalid day '%s'", day)
+}
+
+// MinimumHosts returns the number of hosts the distro should keep
+// provisioned at the given time: the larger of the distro's MinHosts and the
+// minimum of any active warm schedule, capped at the distro's pool size.
+func (d *Distro) MinimumHosts(now time.Time) int {
+	min := d.MinHosts
+	for i := range d.WarmSchedules {
+		s := &d.WarmSchedules[i]
+		if s.MinHosts > min && s.IsActive(now) {
+			min = s.MinHosts
+		}
+	}
+	if min > d.PoolSize {
+		min = d.PoolSize
+	}
+	if min < 0 {
+		min = 0
+	}
+	return min
+}
@@ -0,0 +1,79 @@
+package distro
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmScheduleValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError((&WarmSchedule{StartHour: 8, EndHour: 18, MinHosts: 2}).Validate())
+	assert.NoError((&WarmSchedule{StartHour: 22, EndHour: 6, Days: []string{"Weekdays", "saturday"}}).Validate())
+	assert.NoError((&WarmSchedule{StartHour: 0, EndHour: 24, TimeZone: "America/New_York"}).Validate())
+
+	assert.Error((&WarmSchedule{StartHour: -1, EndHour: 6}).Validate())
+	assert.Error((&WarmSchedule{StartHour: 24, EndHour: 6}).Validate())
+	assert.Error((&WarmSchedule{StartHour: 6, EndHour: 25}).Validate())
+	assert.Error((&WarmSchedule{StartHour: 6, EndHour: 6}).Validate())
+	assert.Error((&WarmSchedule{StartHour: 6, EndHour: 8, MinHosts: -1}).Validate())
+	assert.Error((&WarmSchedule{StartHour: 6, EndHour: 8, Days: []string{"someday"}}).Validate())
+	assert.Error((&WarmSchedule{StartHour: 6, EndHour: 8, TimeZone: "Nowhere/Special"}).Validate())
+}
+
+func TestWarmScheduleIsActive(t *testing.T) {
+	assert := assert.New(t)
+
+	// 2017-06-05 is a Monday
+	monday := func(hour int) time.Time {
+		return time.Date(2017, time.June, 5, hour, 30, 0, 0, time.UTC)
+	}
+	saturday := time.Date(2017, time.June, 10, 10, 0, 0, 0, time.UTC)
+
+	s := &WarmSchedule{StartHour: 8, EndHour: 18, Days: []string{"weekdays"}}
+	assert.False(s.IsActive(monday(7)))
+	assert.True(s.IsActive(monday(8)))
+	assert.True(s.IsActive(monday(17)))
+	assert.False(s.IsActive(monday(18)))
+	assert.False(s.IsActive(saturday))
+
+	s = &WarmSchedule{StartHour: 8, EndHour: 18}
+	assert.True(s.IsActive(saturday))
+
+	// a window wrapping around midnight belongs to the day it starts on
+	s = &WarmSchedule{StartHour: 22, EndHour: 2, Days: []string{"sunday"}}
+	assert.True(s.IsActive(monday(1)))
+	assert.False(s.IsActive(monday(2)))
+	assert.False(s.IsActive(monday(23)))
+
+	// hours are interpreted in the schedule's time zone
+	s = &WarmSchedule{StartHour: 8, EndHour: 18, TimeZone: "America/New_York"}
+	assert.False(s.IsActive(monday(8)))
+	assert.True(s.IsActive(monday(13)))
+}
+
+func TestDistroMinimumHosts(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2017, time.June, 5, 10, 0, 0, 0, time.UTC)
+
+	d := &Distro{PoolSize: 10}
+	assert.Equal(0, d.MinimumHosts(now))
+
+	d.MinHosts = 2
+	assert.Equal(2, d.MinimumHosts(now))
+
+	d.WarmSchedules = []WarmSchedule{
+		{StartHour: 8, EndHour: 18, MinHosts: 5},
+		{StartHour: 18, EndHour: 8, MinHosts: 8},
+	}
+	assert.Equal(5, d.MinimumHosts(now))
+	assert.Equal(8, d.MinimumHosts(now.Add(10*time.Hour)))
+
+	d.WarmSchedules[0].MinHosts = 1
+	assert.Equal(2, d.MinimumHosts(now))
+
+	d.PoolSize = 4
+	assert.Equal(4, d.MinimumHosts(now.Add(10*time.Hour)))
+}
@@ -3,6 +3,7 @@ package event
 import (
 	"time"
 
+	"github.com/evergreen-ci/evergreen"
 	"github.com/mongodb/grip"
 )
 
@@ -23,6 +24,10 @@ const (
 	EventTaskFinished             = "HOST_TASK_FINISHED"
 	EventHostTeardown             = "HOST_TEARDOWN"
 	EventHostTerminatedExternally = "HOST_TERMINATED_EXTERNALLY"
+	EventHostQuarantined          = "HOST_QUARANTINED"
+	EventHostReadmitted           = "HOST_READMITTED"
+	EventHostHealthCheck          = "HOST_HEALTH_CHECK"
+	EventHostAgentUnhealthy       = "HOST_AGENT_UNHEALTHY"
 )
 
 // implements EventData
@@ -38,6 +43,7 @@ type HostEventData struct {
 	TaskPid    string        `bson:"t_pid,omitempty" json:"task_pid,omitempty"`
 	TaskStatus string        `bson:"t_st,omitempty" json:"task_status,omitempty"`
 	MonitorOp  string        `bson:"monitor_op,omitempty" json:"monitor,omitempty"`
+	Reason     string        `bson:"reason,omitempty" json:"reason,omitempty"`
 	Successful bool          `bson:"successful,omitempty" json:"successful"`
 	Duration   time.Duration `bson:"duration,omitempty" json:"duration"`
 }
@@ -112,3 +118,22 @@ func LogHostTeardown(hostId, teardownLogs string, success bool, duration time.Du
 func LogMonitorOperation(hostId string, op string) {
 	LogHostEvent(hostId, EventHostMonitorFlag, HostEventData{MonitorOp: op})
 }
+
+func LogHostQuarantined(hostId, oldStatus, reason string) {
+	LogHostEvent(hostId, EventHostQuarantined,
+		HostEventData{OldStatus: oldStatus, NewStatus: evergreen.HostQuarantined, Reason: reason})
+}
+
+func LogHostReadmitted(hostId, reason string) {
+	LogHostEvent(hostId, EventHostReadmitted,
+		HostEventData{OldStatus: evergreen.HostQuarantined, NewStatus: evergreen.HostRunning, Reason: reason})
+}
+
+func LogHostHealthCheck(hostId, logs string, success bool, duration time.Duration) {
+	LogHostEvent(hostId, EventHostHealthCheck,
+		HostEventData{Logs: logs, Successful: success, Duration: duration})
+}
+
+func LogHostAgentUnhealthy(hostId, reason string) {
+	LogHostEvent(hostId, EventHostAgentUnhealthy, HostEventData{Reason: reason})
+}
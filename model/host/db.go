@@ -41,9 +41,13 @@ var (
 	InstanceTypeKey          = bsonutil.MustHaveTag(Host{}, "InstanceType")
 	NotificationsKey         = bsonutil.MustHaveTag(Host{}, "Notifications")
 	UserDataKey              = bsonutil.MustHaveTag(Host{}, "UserData")
+	InstanceTagsKey          = bsonutil.MustHaveTag(Host{}, "InstanceTags")
 	LastReachabilityCheckKey = bsonutil.MustHaveTag(Host{}, "LastReachabilityCheck")
 	LastCommunicationTimeKey = bsonutil.MustHaveTag(Host{}, "LastCommunicationTime")
 	UnreachableSinceKey      = bsonutil.MustHaveTag(Host{}, "UnreachableSince")
+	SystemFailuresKey        = bsonutil.MustHaveTag(Host{}, "SystemFailures")
+	LastHealthCheckKey       = bsonutil.MustHaveTag(Host{}, "LastHealthCheck")
+	AutoQuarantinedKey       = bsonutil.MustHaveTag(Host{}, "AutoQuarantined")
 )
 
 // === Queries ===
@@ -263,6 +267,25 @@ func ByNotMonitoredSince(threshold time.Time) db.Q {
 	})
 }
 
+// ByStaticNeedsHealthCheck produces a query that returns all static hosts
+// that are running or quarantined, are not running a task, and whose health
+// has not been checked since the specified threshold.
+func ByStaticNeedsHealthCheck(threshold time.Time) db.Q {
+	return db.Query(bson.M{
+		"$and": []bson.M{
+			{ProviderKey: evergreen.HostTypeStatic},
+			{RunningTaskKey: bson.M{"$exists": false}},
+			{StatusKey: bson.M{
+				"$in": []string{evergreen.HostRunning, evergreen.HostQuarantined},
+			}},
+			{"$or": []bson.M{
+				{LastHealthCheckKey: bson.M{"$lte": threshold}},
+				{LastHealthCheckKey: bson.M{"$exists": false}},
+			}},
+		},
+	})
+}
+
 // ByExpiringBetween produces a query that returns  any user-spawned hosts
 // that will expire between the specified times.
 func ByExpiringBetween(lowerBound time.Time, upperBound time.Time) db.Q {
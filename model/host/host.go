@@ -58,11 +58,25 @@ type Host struct {
 	// stores userdata that was placed on the host at spawn time
 	UserData string `bson:"userdata" json:"userdata,omitempty"`
 
+	// ownership metadata, such as the project and task the host was started
+	// for, that is applied to the host's instance as tags or labels
+	InstanceTags map[string]string `bson:"instance_tags,omitempty" json:"instance_tags,omitempty"`
+
 	// the last time that the host's reachability was checked
 	LastReachabilityCheck time.Time `bson:"last_reachability_check" json:"last_reachability_check"`
 
 	// if set, the time at which the host first became unreachable
 	UnreachableSince time.Time `bson:"unreachable_since,omitempty" json:"unreachable_since"`
+
+	// the number of tasks in a row that ended in a system failure on this host
+	SystemFailures int `bson:"system_failures,omitempty" json:"system_failures,omitempty"`
+
+	// the last time that the host's health check script was run
+	LastHealthCheck time.Time `bson:"last_health_check,omitempty" json:"last_health_check"`
+
+	// true if the host was quarantined by Evergreen rather than by a user,
+	// in which case it is re-admitted once its health check passes
+	AutoQuarantined bool `bson:"auto_quarantined,omitempty" json:"auto_quarantined,omitempty"`
 }
 
 // ProvisionOptions is struct containing options about how a new host should be set up.
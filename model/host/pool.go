@@ -0,0 +1,86 @@
+package host
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// PoolState is a snapshot of the hosts Evergreen is running for a distro,
+// along with the size of the warm pool the distro must currently keep.
+type PoolState struct {
+	DistroId     string
+	NumHosts     int
+	NumFreeHosts int
+	MinHosts     int
+}
+
+// hostCounts is the number of working and free hosts of a distro, as counted
+// by GetPoolStates.
+type hostCounts struct {
+	DistroId     string `bson:"_id"`
+	NumHosts     int    `bson:"n"`
+	NumFreeHosts int    `bson:"f"`
+}
+
+// GetPoolStates counts the working hosts of each of the given distros, in a
+// single query, and computes their warm pool minimums at the given time. The
+// states are in the same order as the distros.
+func GetPoolStates(distros []distro.Distro, now time.Time) ([]PoolState, error) {
+	ids := make([]string, 0, len(distros))
+	for _, d := range distros {
+		ids = append(ids, d.Id)
+	}
+
+	distroIdKey := fmt.Sprintf("%v.%v", DistroKey, distro.IdKey)
+	pipeline := []bson.M{
+		// the hosts ByDistroId would find for any of the distros
+		{"$match": bson.M{
+			distroIdKey:  bson.M{"$in": ids},
+			StartedByKey: evergreen.User,
+			StatusKey:    bson.M{"$in": evergreen.UphostStatus},
+		}},
+		// count them, and those of them ByAvailableForDistro would find:
+		// running, with no running task set
+		{"$group": bson.M{
+			"_id": "$" + distroIdKey,
+			"n":   bson.M{"$sum": 1},
+			"f": bson.M{"$sum": bson.M{
+				"$cond": bson.M{
+					"if": bson.M{"$and": []bson.M{
+						{"$eq": []interface{}{"$" + StatusKey, evergreen.HostRunning}},
+						{"$eq": []interface{}{bson.M{"$ifNull": []interface{}{"$" + RunningTaskKey, false}}, false}},
+					}},
+					"then": 1,
+					"else": 0,
+				},
+			}},
+		}},
+	}
+	counts := []hostCounts{}
+	if err := db.Aggregate(Collection, pipeline, &counts); err != nil {
+		return nil, errors.Wrap(err, "error counting hosts for distros")
+	}
+	countsByDistro := make(map[string]hostCounts, len(counts))
+	for _, c := range counts {
+		countsByDistro[c.DistroId] = c
+	}
+
+	states := make([]PoolState, 0, len(distros))
+	for i := range distros {
+		d := &distros[i]
+		c := countsByDistro[d.Id]
+		states = append(states, PoolState{
+			DistroId:     d.Id,
+			NumHosts:     c.NumHosts,
+			NumFreeHosts: c.NumFreeHosts,
+			MinHosts:     d.MinimumHosts(now),
+		})
+	}
+	return states, nil
+}
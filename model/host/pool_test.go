@@ -0,0 +1,41 @@
+package host
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPoolStates(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError(db.Clear(Collection))
+
+	hosts := []Host{
+		{Id: "free", Distro: distro.Distro{Id: "d1"}, StartedBy: evergreen.User, Status: evergreen.HostRunning},
+		{Id: "busy", Distro: distro.Distro{Id: "d1"}, StartedBy: evergreen.User, Status: evergreen.HostRunning, RunningTask: "t1"},
+		{Id: "uninitialized", Distro: distro.Distro{Id: "d1"}, StartedBy: evergreen.User, Status: evergreen.HostUninitialized},
+		{Id: "initializing", Distro: distro.Distro{Id: "d2"}, StartedBy: evergreen.User, Status: evergreen.HostInitializing},
+		{Id: "terminated", Distro: distro.Distro{Id: "d2"}, StartedBy: evergreen.User, Status: evergreen.HostTerminated},
+		{Id: "spawned", Distro: distro.Distro{Id: "d2"}, StartedBy: "user", Status: evergreen.HostRunning},
+	}
+	for i := range hosts {
+		assert.NoError(hosts[i].Insert())
+	}
+
+	distros := []distro.Distro{
+		{Id: "d2", PoolSize: 10, MinHosts: 2},
+		{Id: "d1", PoolSize: 10},
+		{Id: "d3", PoolSize: 1, MinHosts: 2},
+	}
+	states, err := GetPoolStates(distros, time.Now())
+	assert.NoError(err)
+	assert.Equal([]PoolState{
+		{DistroId: "d2", NumHosts: 1, NumFreeHosts: 0, MinHosts: 2},
+		{DistroId: "d1", NumHosts: 3, NumFreeHosts: 1, MinHosts: 0},
+		{DistroId: "d3", NumHosts: 0, NumFreeHosts: 0, MinHosts: 1},
+	}, states)
+}
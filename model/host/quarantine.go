@@ -0,0 +1,109 @@
+package host
+
+import (
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/event"
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Quarantine stops new tasks from being dispatched to the host. A task that is
+// already running is allowed to finish, after which the agent exits. If auto is
+// set, the host was quarantined by Evergreen and will be re-admitted as soon as
+// it is found to be healthy again. Only static hosts can be quarantined, since
+// a quarantined cloud host would never be reaped.
+func (h *Host) Quarantine(reason string, auto bool) error {
+	if h.Provider != evergreen.HostTypeStatic {
+		return errors.Errorf("cannot quarantine host %s with provider %s: only static hosts can be quarantined",
+			h.Id, h.Provider)
+	}
+	if h.Status == evergreen.HostTerminated || h.Status == evergreen.HostDecommissioned {
+		return errors.Errorf("cannot quarantine host %s with status %s", h.Id, h.Status)
+	}
+
+	err := UpdateOne(
+		bson.M{
+			IdKey:     h.Id,
+			StatusKey: h.Status,
+		},
+		bson.M{
+			"$set": bson.M{
+				StatusKey:          evergreen.HostQuarantined,
+				AutoQuarantinedKey: auto,
+			},
+		},
+	)
+	if err != nil {
+		return errors.Wrapf(err, "error quarantining host %s", h.Id)
+	}
+
+	event.LogHostQuarantined(h.Id, h.Status, reason)
+	h.Status = evergreen.HostQuarantined
+	h.AutoQuarantined = auto
+	return nil
+}
+
+// Readmit returns a quarantined host to service, clearing its record of
+// system failures.
+func (h *Host) Readmit(reason string) error {
+	if h.Status != evergreen.HostQuarantined {
+		return errors.Errorf("cannot re-admit host %s with status %s", h.Id, h.Status)
+	}
+
+	err := UpdateOne(
+		bson.M{
+			IdKey:     h.Id,
+			StatusKey: evergreen.HostQuarantined,
+		},
+		bson.M{
+			"$set": bson.M{
+				StatusKey: evergreen.HostRunning,
+			},
+			"$unset": bson.M{
+				AutoQuarantinedKey: 1,
+				SystemFailuresKey:  1,
+			},
+		},
+	)
+	if err != nil {
+		return errors.Wrapf(err, "error re-admitting host %s", h.Id)
+	}
+
+	event.LogHostReadmitted(h.Id, reason)
+	h.Status = evergreen.HostRunning
+	h.AutoQuarantined = false
+	h.SystemFailures = 0
+	return nil
+}
+
+// RecordTaskOutcome updates the count of tasks in a row that have ended in a
+// system failure on the host, resetting it when a task ends any other way.
+func (h *Host) RecordTaskOutcome(systemFailure bool) error {
+	if !systemFailure {
+		if h.SystemFailures == 0 {
+			return nil
+		}
+		h.SystemFailures = 0
+		return UpdateOne(
+			bson.M{IdKey: h.Id},
+			bson.M{"$unset": bson.M{SystemFailuresKey: 1}},
+		)
+	}
+
+	h.SystemFailures++
+	return UpdateOne(
+		bson.M{IdKey: h.Id},
+		bson.M{"$inc": bson.M{SystemFailuresKey: 1}},
+	)
+}
+
+// SetHealthChecked records the time the host's health check was last run.
+func (h *Host) SetHealthChecked(checkedAt time.Time) error {
+	h.LastHealthCheck = checkedAt
+	return UpdateOne(
+		bson.M{IdKey: h.Id},
+		bson.M{"$set": bson.M{LastHealthCheckKey: checkedAt}},
+	)
+}
@@ -0,0 +1,134 @@
+package host
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/event"
+	"github.com/stretchr/testify/suite"
+)
+
+type QuarantineSuite struct {
+	h *Host
+	suite.Suite
+}
+
+func TestQuarantineSuite(t *testing.T) {
+	suite.Run(t, new(QuarantineSuite))
+}
+
+func (s *QuarantineSuite) SetupTest() {
+	s.NoError(db.ClearCollections(Collection, event.AllLogCollection))
+	s.h = &Host{
+		Id:       "static-host",
+		Provider: evergreen.HostTypeStatic,
+		Status:   evergreen.HostRunning,
+	}
+	s.NoError(s.h.Insert())
+}
+
+func (s *QuarantineSuite) TestQuarantineAndReadmit() {
+	s.Error(s.h.Readmit("not quarantined"))
+
+	s.NoError(s.h.Quarantine("failing disk", true))
+	s.Equal(evergreen.HostQuarantined, s.h.Status)
+	s.True(s.h.AutoQuarantined)
+
+	dbHost, err := FindOne(ById(s.h.Id))
+	s.NoError(err)
+	s.Require().NotNil(dbHost)
+	s.Equal(evergreen.HostQuarantined, dbHost.Status)
+	s.True(dbHost.AutoQuarantined)
+
+	s.NoError(s.h.Readmit("disk replaced"))
+	s.Equal(evergreen.HostRunning, s.h.Status)
+	s.False(s.h.AutoQuarantined)
+
+	dbHost, err = FindOne(ById(s.h.Id))
+	s.NoError(err)
+	s.Require().NotNil(dbHost)
+	s.Equal(evergreen.HostRunning, dbHost.Status)
+	s.False(dbHost.AutoQuarantined)
+
+	events, err := event.Find(event.AllLogCollection, event.MostRecentHostEvents(s.h.Id, 10))
+	s.NoError(err)
+	eventTypes := []string{}
+	for _, e := range events {
+		eventTypes = append(eventTypes, e.EventType)
+	}
+	s.Contains(eventTypes, event.EventHostQuarantined)
+	s.Contains(eventTypes, event.EventHostReadmitted)
+}
+
+func (s *QuarantineSuite) TestCannotQuarantineTerminatedHost() {
+	s.NoError(s.h.SetTerminated())
+	s.Error(s.h.Quarantine("too late", false))
+}
+
+func (s *QuarantineSuite) TestCannotQuarantineCloudHost() {
+	h := &Host{Id: "ec2-host", Provider: "ec2", Status: evergreen.HostRunning}
+	s.NoError(h.Insert())
+	s.Error(h.Quarantine("not static", false))
+
+	dbHost, err := FindOne(ById(h.Id))
+	s.NoError(err)
+	s.Equal(evergreen.HostRunning, dbHost.Status)
+}
+
+func (s *QuarantineSuite) TestRecordTaskOutcome() {
+	s.NoError(s.h.RecordTaskOutcome(true))
+	s.NoError(s.h.RecordTaskOutcome(true))
+	s.Equal(2, s.h.SystemFailures)
+
+	dbHost, err := FindOne(ById(s.h.Id))
+	s.NoError(err)
+	s.Require().NotNil(dbHost)
+	s.Equal(2, dbHost.SystemFailures)
+
+	s.NoError(s.h.RecordTaskOutcome(false))
+	s.Equal(0, s.h.SystemFailures)
+
+	dbHost, err = FindOne(ById(s.h.Id))
+	s.NoError(err)
+	s.Require().NotNil(dbHost)
+	s.Equal(0, dbHost.SystemFailures)
+}
+
+func (s *QuarantineSuite) TestStaticNeedsHealthCheck() {
+	now := time.Now()
+	threshold := now.Add(-10 * time.Minute)
+
+	busy := &Host{
+		Id:          "busy",
+		Provider:    evergreen.HostTypeStatic,
+		Status:      evergreen.HostRunning,
+		RunningTask: "task",
+	}
+	quarantined := &Host{
+		Id:       "quarantined",
+		Provider: evergreen.HostTypeStatic,
+		Status:   evergreen.HostQuarantined,
+	}
+	dynamic := &Host{
+		Id:       "dynamic",
+		Provider: "ec2",
+		Status:   evergreen.HostRunning,
+	}
+	for _, h := range []*Host{busy, quarantined, dynamic} {
+		s.NoError(h.Insert())
+	}
+
+	hosts, err := Find(ByStaticNeedsHealthCheck(threshold))
+	s.NoError(err)
+	s.Len(hosts, 2)
+	s.True(hostIdInSlice(hosts, s.h.Id))
+	s.True(hostIdInSlice(hosts, quarantined.Id))
+
+	s.NoError(s.h.SetHealthChecked(now))
+	hosts, err = Find(ByStaticNeedsHealthCheck(threshold))
+	s.NoError(err)
+	s.Len(hosts, 1)
+	s.Equal(quarantined.Id, hosts[0].Id)
+}
@@ -0,0 +1,257 @@
+package perf
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultChangePointThreshold is the score, a t-statistic, that a shift in
+	// a series' mean must reach to be reported as a change point.
+	DefaultChangePointThreshold = 5.0
+
+	// DefaultMinSegment is the fewest points that may lie on either side of a
+	// change point.
+	DefaultMinSegment = 3
+)
+
+// DefaultPercentiles are the percentiles series are summarized by if no
+// others are asked for.
+var DefaultPercentiles = []float64{50, 90, 95, 99}
+
+// Percentile is the value below which a percentage of a series' values lie.
+type Percentile struct {
+	Percentile float64 `json:"percentile"`
+	Value      float64 `json:"value"`
+}
+
+// Stats summarize the values of a series.
+type Stats struct {
+	Count       int          `json:"count"`
+	Min         float64      `json:"min"`
+	Max         float64      `json:"max"`
+	Mean        float64      `json:"mean"`
+	StdDev      float64      `json:"std_dev"`
+	Percentiles []Percentile `json:"percentiles"`
+}
+
+// ChangePoint is a revision at which the mean of a series shifts.
+type ChangePoint struct {
+	// TaskId, Revision and RevisionOrderNumber identify the first point
+	// after the shift.
+	TaskId              string `json:"task_id"`
+	Revision            string `json:"revision"`
+	RevisionOrderNumber int    `json:"order"`
+
+	// Before and After are the means of the series between the change
+	// points on either side of this one.
+	Before        float64 `json:"before"`
+	After         float64 `json:"after"`
+	PercentChange float64 `json:"percent_change"`
+
+	// Score is the t-statistic of the shift.
+	Score float64 `json:"score"`
+}
+
+// AnalysisOptions control how series are analyzed. Zero values select the
+// defaults.
+type AnalysisOptions struct {
+	Percentiles []float64
+	Threshold   float64
+	MinSegment  int
+}
+
+// Series is the points of a metric in order of revision, along with a
+// summary of their values and the revisions at which they shift.
+type Series struct {
+	Points       []Point       `json:"points"`
+	Stats        Stats         `json:"stats"`
+	ChangePoints []ChangePoint `json:"change_points"`
+}
+
+// Analyze summarizes points, which must be in order of revision, and finds
+// their change points.
+func Analyze(points []Point, opts AnalysisOptions) Series {
+	if len(opts.Percentiles) == 0 {
+		opts.Percentiles = DefaultPercentiles
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultChangePointThreshold
+	}
+	if opts.MinSegment < 2 {
+		opts.MinSegment = DefaultMinSegment
+	}
+
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	return Series{
+		Points:       points,
+		Stats:        Summarize(values, opts.Percentiles),
+		ChangePoints: FindChangePoints(points, opts.Threshold, opts.MinSegment),
+	}
+}
+
+// Summarize returns the stats of values, with the given percentiles, which
+// are interpolated between the nearest values.
+func Summarize(values []float64, percentiles []float64) Stats {
+	stats := Stats{Count: len(values), Percentiles: []Percentile{}}
+	if len(values) == 0 {
+		return stats
+	}
+
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	stats.Mean, stats.StdDev = meanAndStdDev(sorted)
+
+	for _, p := range percentiles {
+		rank := p / 100 * float64(len(sorted)-1)
+		lower := int(math.Floor(rank))
+		upper := int(math.Ceil(rank))
+		value := sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+		stats.Percentiles = append(stats.Percentiles, Percentile{Percentile: p, Value: value})
+	}
+	return stats
+}
+
+// meanAndStdDev returns the mean and sample standard deviation of values.
+func meanAndStdDev(values []float64) (float64, float64) {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	squares := 0.0
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
+
+// FindChangePoints finds the revisions at which the mean of points, which
+// must be in order of revision, shifts. It splits the points where the
+// difference between the means on either side is most significant, by
+// Welch's t-statistic, and then splits each side in turn, for as long as a
+// split scores at least threshold and leaves at least minSegment points on
+// either side. Each level of splitting takes time linear in the number of
+// points.
+func FindChangePoints(points []Point, threshold float64, minSegment int) []ChangePoint {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+
+	splits := []int{}
+	var segment func(lo, hi int)
+	segment = func(lo, hi int) {
+		sums := newPrefixSums(values[lo:hi])
+		n := hi - lo
+		best, bestScore := -1, 0.0
+		for i := lo + minSegment; i <= hi-minSegment; i++ {
+			if score := welchT(sums.stats(0, i-lo), sums.stats(i-lo, n)); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 || bestScore < threshold {
+			return
+		}
+		splits = append(splits, best)
+		segment(lo, best)
+		segment(best, hi)
+	}
+	segment(0, len(values))
+	sort.Ints(splits)
+
+	changePoints := []ChangePoint{}
+	for i, split := range splits {
+		lo, hi := 0, len(values)
+		if i > 0 {
+			lo = splits[i-1]
+		}
+		if i < len(splits)-1 {
+			hi = splits[i+1]
+		}
+		before, after := statsOf(values[lo:split]), statsOf(values[split:hi])
+		change := ChangePoint{
+			TaskId:              points[split].TaskId,
+			Revision:            points[split].Revision,
+			RevisionOrderNumber: points[split].RevisionOrderNumber,
+			Before:              before.mean,
+			After:               after.mean,
+			Score:               welchT(before, after),
+		}
+		if before.mean != 0 {
+			change.PercentChange = (after.mean - before.mean) / math.Abs(before.mean) * 100
+		}
+		changePoints = append(changePoints, change)
+	}
+	return changePoints
+}
+
+// segmentStats describes a run of consecutive values.
+type segmentStats struct {
+	n        int
+	mean     float64
+	variance float64
+}
+
+func statsOf(values []float64) segmentStats {
+	mean, sd := meanAndStdDev(values)
+	return segmentStats{n: len(values), mean: mean, variance: sd * sd}
+}
+
+// prefixSums holds the running sums of a series of values and of their
+// squares, so that the mean and variance of any run of them can be found in
+// constant time. The values are offset by their mean, so that series of
+// large values that differ little don't lose precision.
+type prefixSums struct {
+	offset  float64
+	sums    []float64
+	squares []float64
+}
+
+func newPrefixSums(values []float64) *prefixSums {
+	p := &prefixSums{
+		sums:    make([]float64, len(values)+1),
+		squares: make([]float64, len(values)+1),
+	}
+	if len(values) > 0 {
+		p.offset, _ = meanAndStdDev(values)
+	}
+	for i, v := range values {
+		v -= p.offset
+		p.sums[i+1] = p.sums[i] + v
+		p.squares[i+1] = p.squares[i] + v*v
+	}
+	return p
+}
+
+// stats returns the mean and sample variance of the values from lo up to,
+// but not including, hi.
+func (p *prefixSums) stats(lo, hi int) segmentStats {
+	n := hi - lo
+	sum := p.sums[hi] - p.sums[lo]
+	mean := sum / float64(n)
+	stats := segmentStats{n: n, mean: mean + p.offset}
+	if n > 1 {
+		squares := p.squares[hi] - p.squares[lo] - sum*mean
+		stats.variance = math.Max(0, squares/float64(n-1))
+	}
+	return stats
+}
+
+// welchT returns the magnitude of Welch's t-statistic for the difference
+// between the means of a and b.
+func welchT(a, b segmentStats) float64 {
+	stdErr := math.Sqrt(a.variance/float64(a.n) + b.variance/float64(b.n))
+	// noiseless segments would give an infinite statistic, so treat them as
+	// having a tiny amount of noise relative to their values
+	floor := 1e-9 * math.Max(1, math.Max(math.Abs(a.mean), math.Abs(b.mean)))
+	return math.Abs(a.mean-b.mean) / math.Max(stdErr, floor)
+}
@@ -0,0 +1,82 @@
+package perf
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func seriesOf(values ...float64) []Point {
+	points := make([]Point, len(values))
+	for i, v := range values {
+		points[i] = Point{TaskId: "task", RevisionOrderNumber: i + 1, Value: v}
+	}
+	return points
+}
+
+func TestSummarize(t *testing.T) {
+	Convey("Summarizing values", t, func() {
+		Convey("should give their extremes, mean, spread and percentiles", func() {
+			stats := Summarize([]float64{5, 1, 4, 2, 3}, []float64{0, 50, 90, 100})
+			So(stats.Count, ShouldEqual, 5)
+			So(stats.Min, ShouldEqual, 1)
+			So(stats.Max, ShouldEqual, 5)
+			So(stats.Mean, ShouldEqual, 3)
+			So(stats.StdDev, ShouldAlmostEqual, 1.5811, 0.0001)
+			So(stats.Percentiles, ShouldResemble, []Percentile{
+				{Percentile: 0, Value: 1},
+				{Percentile: 50, Value: 3},
+				{Percentile: 90, Value: 4.6},
+				{Percentile: 100, Value: 5},
+			})
+		})
+
+		Convey("should handle no values", func() {
+			stats := Summarize(nil, DefaultPercentiles)
+			So(stats.Count, ShouldEqual, 0)
+			So(stats.Percentiles, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestFindChangePoints(t *testing.T) {
+	Convey("Finding change points", t, func() {
+		Convey("should find nothing in a noisy but steady series", func() {
+			points := seriesOf(100, 102, 99, 101, 100, 98, 101, 100, 102, 99)
+			So(FindChangePoints(points, DefaultChangePointThreshold, DefaultMinSegment), ShouldBeEmpty)
+		})
+
+		Convey("should find where a series steps down", func() {
+			points := seriesOf(100, 102, 99, 101, 100, 80, 81, 79, 80, 82)
+			changes := FindChangePoints(points, DefaultChangePointThreshold, DefaultMinSegment)
+			So(len(changes), ShouldEqual, 1)
+			So(changes[0].RevisionOrderNumber, ShouldEqual, 6)
+			So(changes[0].Before, ShouldEqual, 100.4)
+			So(changes[0].After, ShouldEqual, 80.4)
+			So(changes[0].PercentChange, ShouldAlmostEqual, -19.92, 0.01)
+		})
+
+		Convey("should find several shifts in order", func() {
+			points := seriesOf(10, 10, 10, 10, 20, 20, 20, 20, 5, 5, 5, 5)
+			changes := FindChangePoints(points, DefaultChangePointThreshold, DefaultMinSegment)
+			So(len(changes), ShouldEqual, 2)
+			So(changes[0].RevisionOrderNumber, ShouldEqual, 5)
+			So(changes[1].RevisionOrderNumber, ShouldEqual, 9)
+			So(changes[1].Before, ShouldEqual, 20)
+			So(changes[1].After, ShouldEqual, 5)
+		})
+
+		Convey("should not split off segments shorter than the minimum", func() {
+			points := seriesOf(10, 10, 10, 10, 10, 10, 50)
+			So(FindChangePoints(points, DefaultChangePointThreshold, DefaultMinSegment), ShouldBeEmpty)
+			So(len(FindChangePoints(points, DefaultChangePointThreshold, 1)), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Analyzing a series should apply the default options", t, func() {
+		series := Analyze(seriesOf(1, 1, 1, 9, 9, 9), AnalysisOptions{})
+		So(len(series.Points), ShouldEqual, 6)
+		So(len(series.Stats.Percentiles), ShouldEqual, len(DefaultPercentiles))
+		So(len(series.ChangePoints), ShouldEqual, 1)
+	})
+}
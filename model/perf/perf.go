@@ -0,0 +1,255 @@
+// Package perf stores the numeric metrics, such as benchmark results, that
+// tasks report, and summarizes how each metric changes across the revisions
+// of a project.
+package perf
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/db/bsonutil"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	Collection = "perf_metrics"
+
+	maxNameLength = 128
+	maxUnitLength = 32
+)
+
+// NameRegex is what metric names must match. Names are used in URLs, so
+// they are limited to characters that need no escaping there.
+var NameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-]*$`)
+
+// Metric is a named measurement reported by a task, e.g. the throughput of
+// a benchmark, in units such as "ops/sec".
+type Metric struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// Validate checks that a metric has a valid name and unit and a finite
+// value.
+func (m Metric) Validate() error {
+	if !NameRegex.MatchString(m.Name) || len(m.Name) > maxNameLength {
+		return errors.Errorf("invalid metric name '%v': names must be at most %d letters, "+
+			"digits, '.', '_' or '-', starting with a letter or digit", m.Name, maxNameLength)
+	}
+	if m.Unit == "" || len(m.Unit) > maxUnitLength {
+		return errors.Errorf("metric '%v' must have a unit of at most %d characters", m.Name, maxUnitLength)
+	}
+	if math.IsNaN(m.Value) || math.IsInf(m.Value, 0) {
+		return errors.Errorf("metric '%v' must have a finite value", m.Name)
+	}
+	return nil
+}
+
+// Schema declares the metrics a task reports, mapping their names to their
+// units.
+type Schema map[string]string
+
+// Validate checks that metrics are valid and have unique names, and, if the
+// schema declares any metrics, that they are exactly the declared metrics,
+// in the declared units.
+func (s Schema) Validate(metrics []Metric) error {
+	if len(metrics) == 0 {
+		return errors.New("no metrics given")
+	}
+	seen := map[string]bool{}
+	for _, m := range metrics {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		if seen[m.Name] {
+			return errors.Errorf("metric '%v' is given more than once", m.Name)
+		}
+		seen[m.Name] = true
+
+		if len(s) == 0 {
+			continue
+		}
+		unit, ok := s[m.Name]
+		if !ok {
+			return errors.Errorf("metric '%v' is not declared in the schema", m.Name)
+		}
+		if unit != m.Unit {
+			return errors.Errorf("metric '%v' is in '%v', but the schema declares it in '%v'",
+				m.Name, m.Unit, unit)
+		}
+	}
+
+	missing := []string{}
+	for name := range s {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return errors.Errorf("metrics %v are declared in the schema but not given", missing)
+	}
+	return nil
+}
+
+// Point is a metric reported by a task, along with where the task falls in
+// its project's history.
+type Point struct {
+	TaskId              string    `bson:"task_id" json:"task_id"`
+	TaskName            string    `bson:"task_name" json:"task_name"`
+	ProjectId           string    `bson:"project_id" json:"project_id"`
+	Variant             string    `bson:"variant" json:"variant"`
+	VersionId           string    `bson:"version_id" json:"version_id"`
+	Revision            string    `bson:"revision" json:"revision"`
+	RevisionOrderNumber int       `bson:"order" json:"order"`
+	IsPatch             bool      `bson:"is_patch" json:"is_patch"`
+	CreateTime          time.Time `bson:"create_time" json:"create_time"`
+	Name                string    `bson:"name" json:"name"`
+	Value               float64   `bson:"value" json:"value"`
+	Unit                string    `bson:"unit" json:"unit"`
+}
+
+var (
+	// BSON fields for the Point struct
+	TaskIdKey              = bsonutil.MustHaveTag(Point{}, "TaskId")
+	TaskNameKey            = bsonutil.MustHaveTag(Point{}, "TaskName")
+	ProjectIdKey           = bsonutil.MustHaveTag(Point{}, "ProjectId")
+	VariantKey             = bsonutil.MustHaveTag(Point{}, "Variant")
+	VersionIdKey           = bsonutil.MustHaveTag(Point{}, "VersionId")
+	RevisionKey            = bsonutil.MustHaveTag(Point{}, "Revision")
+	RevisionOrderNumberKey = bsonutil.MustHaveTag(Point{}, "RevisionOrderNumber")
+	IsPatchKey             = bsonutil.MustHaveTag(Point{}, "IsPatch")
+	CreateTimeKey          = bsonutil.MustHaveTag(Point{}, "CreateTime")
+	NameKey                = bsonutil.MustHaveTag(Point{}, "Name")
+	ValueKey               = bsonutil.MustHaveTag(Point{}, "Value")
+	UnitKey                = bsonutil.MustHaveTag(Point{}, "Unit")
+)
+
+// NewPoint returns the point for a metric reported by a task.
+func NewPoint(t *task.Task, m Metric) Point {
+	return Point{
+		TaskId:              t.Id,
+		TaskName:            t.DisplayName,
+		ProjectId:           t.Project,
+		Variant:             t.BuildVariant,
+		VersionId:           t.Version,
+		Revision:            t.Revision,
+		RevisionOrderNumber: t.RevisionOrderNumber,
+		IsPatch:             t.Requester == evergreen.PatchVersionRequester,
+		CreateTime:          t.CreateTime,
+		Name:                m.Name,
+		Value:               m.Value,
+		Unit:                m.Unit,
+	}
+}
+
+// SeriesQuery identifies a metric of a task on a variant of a project, and
+// the range of revisions to find its points for.
+type SeriesQuery struct {
+	ProjectId string
+	Variant   string
+	TaskName  string
+	Name      string
+
+	// StartOrder and EndOrder, if non-zero, bound the revision order numbers
+	// of the points, inclusively.
+	StartOrder int
+	EndOrder   int
+
+	// Limit, if non-zero, limits the series to its most recent points.
+	Limit int
+}
+
+// === Queries ===
+
+// ByTaskId returns a query for the points reported by a task, sorted by name.
+func ByTaskId(taskId string) db.Q {
+	return db.Query(bson.M{TaskIdKey: taskId}).Sort([]string{NameKey})
+}
+
+// BySeries returns a query for the points of a series reported by mainline
+// (i.e. not patch) tasks, most recent first.
+func BySeries(q SeriesQuery) db.Q {
+	filter := bson.M{
+		ProjectIdKey: q.ProjectId,
+		VariantKey:   q.Variant,
+		TaskNameKey:  q.TaskName,
+		NameKey:      q.Name,
+		IsPatchKey:   false,
+	}
+	order := bson.M{}
+	if q.StartOrder != 0 {
+		order["$gte"] = q.StartOrder
+	}
+	if q.EndOrder != 0 {
+		order["$lte"] = q.EndOrder
+	}
+	if len(order) > 0 {
+		filter[RevisionOrderNumberKey] = order
+	}
+	query := db.Query(filter).Sort([]string{"-" + RevisionOrderNumberKey})
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+	return query
+}
+
+// === DB Logic ===
+
+// FindAll gets every point for the given query.
+func FindAll(query db.Q) ([]Point, error) {
+	points := []Point{}
+	err := db.FindAllQ(Collection, query, &points)
+	return points, err
+}
+
+// FindSeries gets the points of a series in order of revision.
+func FindSeries(q SeriesQuery) ([]Point, error) {
+	points, err := FindAll(BySeries(q))
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding metric series")
+	}
+	// reverse the points, which were sorted backwards to apply the limit
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	return points, nil
+}
+
+// Save records the metrics reported by a task, replacing any it reported
+// before under the same names. Each metric must be in the unit that its
+// series has been recorded in so far.
+func Save(t *task.Task, metrics []Metric) error {
+	for _, m := range metrics {
+		latest := Point{}
+		err := db.FindOneQ(Collection, BySeries(SeriesQuery{
+			ProjectId: t.Project,
+			Variant:   t.BuildVariant,
+			TaskName:  t.DisplayName,
+			Name:      m.Name,
+		}), &latest)
+		if err != nil && err != mgo.ErrNotFound {
+			return errors.Wrapf(err, "error finding series of metric '%v'", m.Name)
+		}
+		if err == nil && latest.Unit != m.Unit {
+			return errors.Errorf("metric '%v' is in '%v', but has been recorded in '%v'",
+				m.Name, m.Unit, latest.Unit)
+		}
+	}
+
+	for _, m := range metrics {
+		_, err := db.Upsert(Collection, bson.M{TaskIdKey: t.Id, NameKey: m.Name}, NewPoint(t, m))
+		if err != nil {
+			return errors.Wrapf(err, "error saving metric '%v'", m.Name)
+		}
+	}
+	return nil
+}
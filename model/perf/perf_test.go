@@ -0,0 +1,99 @@
+package perf
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func init() {
+	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(testutil.TestConfig()))
+}
+
+func TestSchemaValidate(t *testing.T) {
+	Convey("With metrics reported by a task", t, func() {
+		metrics := []Metric{
+			{Name: "insert", Value: 1200.5, Unit: "ops/sec"},
+			{Name: "query.latency_p99", Value: 3.2, Unit: "ms"},
+		}
+
+		Convey("valid metrics should pass without a schema", func() {
+			So(Schema{}.Validate(metrics), ShouldBeNil)
+		})
+
+		Convey("invalid metrics should be rejected", func() {
+			So(Schema{}.Validate(nil), ShouldNotBeNil)
+			So(Schema{}.Validate([]Metric{{Name: "bad name", Value: 1, Unit: "ms"}}), ShouldNotBeNil)
+			So(Schema{}.Validate([]Metric{{Name: "../x", Value: 1, Unit: "ms"}}), ShouldNotBeNil)
+			So(Schema{}.Validate([]Metric{{Name: "x", Value: 1}}), ShouldNotBeNil)
+			So(Schema{}.Validate([]Metric{{Name: "x", Value: math.NaN(), Unit: "ms"}}), ShouldNotBeNil)
+			So(Schema{}.Validate(append(metrics, metrics[0])), ShouldNotBeNil)
+		})
+
+		Convey("a schema should require exactly its metrics in its units", func() {
+			schema := Schema{"insert": "ops/sec", "query.latency_p99": "ms"}
+			So(schema.Validate(metrics), ShouldBeNil)
+			So(schema.Validate(metrics[:1]), ShouldNotBeNil)
+			So(Schema{"insert": "ops/sec"}.Validate(metrics), ShouldNotBeNil)
+			So(Schema{"insert": "ops/min", "query.latency_p99": "ms"}.Validate(metrics), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestSaveAndFindSeries(t *testing.T) {
+	Convey("With metrics reported by tasks across revisions", t, func() {
+		testutil.HandleTestingErr(db.Clear(Collection), t, "Error clearing collection")
+
+		newTask := func(id string, order int, requester string) *task.Task {
+			return &task.Task{
+				Id:                  id,
+				DisplayName:         "bench",
+				Project:             "project",
+				BuildVariant:        "linux",
+				RevisionOrderNumber: order,
+				Requester:           requester,
+			}
+		}
+		for order := 1; order <= 5; order++ {
+			tsk := newTask(fmt.Sprintf("t%d", order), order, evergreen.RepotrackerVersionRequester)
+			So(Save(tsk, []Metric{{Name: "insert", Value: float64(order * 100), Unit: "ops/sec"}}), ShouldBeNil)
+		}
+		patch := newTask("patch", 5, evergreen.PatchVersionRequester)
+		So(Save(patch, []Metric{{Name: "insert", Value: 1, Unit: "ops/sec"}}), ShouldBeNil)
+
+		Convey("series should be in order of revision and exclude patches", func() {
+			points, err := FindSeries(SeriesQuery{ProjectId: "project", Variant: "linux", TaskName: "bench", Name: "insert"})
+			So(err, ShouldBeNil)
+			So(len(points), ShouldEqual, 5)
+			So(points[0].RevisionOrderNumber, ShouldEqual, 1)
+			So(points[4].Value, ShouldEqual, 500)
+		})
+
+		Convey("series should be limited to the most recent points in range", func() {
+			points, err := FindSeries(SeriesQuery{ProjectId: "project", Variant: "linux", TaskName: "bench",
+				Name: "insert", EndOrder: 4, Limit: 2})
+			So(err, ShouldBeNil)
+			So(len(points), ShouldEqual, 2)
+			So(points[0].RevisionOrderNumber, ShouldEqual, 3)
+			So(points[1].RevisionOrderNumber, ShouldEqual, 4)
+		})
+
+		Convey("metrics should be found by task", func() {
+			points, err := FindAll(ByTaskId("patch"))
+			So(err, ShouldBeNil)
+			So(len(points), ShouldEqual, 1)
+			So(points[0].IsPatch, ShouldBeTrue)
+		})
+
+		Convey("metrics in a different unit than their series should be rejected", func() {
+			tsk := newTask("t6", 6, evergreen.RepotrackerVersionRequester)
+			So(Save(tsk, []Metric{{Name: "insert", Value: 1, Unit: "ops/min"}}), ShouldNotBeNil)
+		})
+	})
+}
@@ -19,6 +19,7 @@ import (
 const (
 	TestCommandType   = "test"
 	SystemCommandType = "system"
+	SetupCommandType  = "setup"
 )
 
 const (
@@ -50,10 +51,72 @@ type Project struct {
 	Tasks           []ProjectTask              `yaml:"tasks,omitempty" bson:"tasks"`
 	ExecTimeoutSecs int                        `yaml:"exec_timeout_secs,omitempty" bson:"exec_timeout_secs"`
 
+	// TimeoutDiagnostics configures the data the agent collects from a
+	// task's processes when the task times out, before they are killed.
+	TimeoutDiagnostics *TimeoutDiagnostics `yaml:"timeout_diagnostics,omitempty" bson:"timeout_diagnostics"`
+
 	// Flag that indicates a project as requiring user authentication
 	Private bool `yaml:"private,omitempty" bson:"private"`
 }
 
+// TimeoutDiagnostics configures the data the agent collects when a task times
+// out. The hooks run first, while the task's processes are still running, then
+// the signal is sent to make them dump core, and then the core files are
+// collected. Everything collected is attached to the task.
+type TimeoutDiagnostics struct {
+	// Hooks are commands, such as gdb or jstack, run against each of the
+	// task's processes.
+	Hooks []TimeoutHook `yaml:"hooks,omitempty" bson:"hooks"`
+
+	// Signal is the name of the signal sent to the task's processes after
+	// the hooks run, e.g. SIGABRT. No signal is sent if it is empty.
+	Signal string `yaml:"signal,omitempty" bson:"signal"`
+
+	// SignalWaitSecs is how long to wait for the processes to exit after
+	// the signal is sent, so that they can finish writing core files.
+	SignalWaitSecs int `yaml:"signal_wait_secs,omitempty" bson:"signal_wait_secs"`
+
+	// CoreFiles are glob patterns matching the core files to collect.
+	// Relative patterns are relative to the task's working directory.
+	CoreFiles []string `yaml:"core_files,omitempty" bson:"core_files"`
+
+	// S3 is where core files are uploaded. If it is not set, the paths of
+	// the core files are logged instead.
+	S3 *TimeoutDiagnosticsS3 `yaml:"s3,omitempty" bson:"s3"`
+}
+
+// TimeoutHook is a command run against each of a timed out task's processes.
+type TimeoutHook struct {
+	Name string `yaml:"name" bson:"name"`
+
+	// Command is run with the shell, with ${pid} expanded to the id of the
+	// process, e.g. "gdb -p ${pid} -batch -ex 'thread apply all bt'".
+	Command string `yaml:"command" bson:"command"`
+
+	// Processes is a regular expression limiting the hook to the processes
+	// whose command lines match it. The hook runs against every process if
+	// it is empty.
+	Processes string `yaml:"processes,omitempty" bson:"processes"`
+
+	// TimeoutSecs is how long the command may run for each process.
+	TimeoutSecs int `yaml:"timeout_secs,omitempty" bson:"timeout_secs"`
+}
+
+// TimeoutDiagnosticsS3 is the S3 location core files are uploaded to. Its
+// fields may contain expansions.
+type TimeoutDiagnosticsS3 struct {
+	Bucket       string `yaml:"bucket" bson:"bucket"`
+	AwsKey       string `yaml:"aws_key" bson:"aws_key"`
+	AwsSecret    string `yaml:"aws_secret" bson:"aws_secret"`
+	RemotePrefix string `yaml:"remote_prefix,omitempty" bson:"remote_prefix"`
+	Permissions  string `yaml:"permissions,omitempty" bson:"permissions"`
+	Visibility   string `yaml:"visibility,omitempty" bson:"visibility"`
+}
+
+// TimeoutDiagnosticsSignals are the signals that may be sent to a timed out
+// task's processes.
+var TimeoutDiagnosticsSignals = []string{"SIGABRT", "SIGQUIT", "SIGSEGV", "SIGINT", "SIGTERM"}
+
 // Unmarshalled from the "tasks" list in an individual build variant
 type BuildVariantTask struct {
 	// Name has to match the name field of one of the tasks specified at
@@ -184,6 +247,18 @@ type PluginCommandConf struct {
 	// for. If undefined, it is unbounded.
 	TimeoutSecs int `yaml:"timeout_secs,omitempty" bson:"timeout_secs"`
 
+	// RetryOnFailure is the number of times the command is retried if it
+	// fails. If undefined, a failed command is not retried.
+	RetryOnFailure int `yaml:"retry_on_failure,omitempty" bson:"retry_on_failure"`
+
+	// RetryBackoffSecs is how long to wait before the first retry. The wait
+	// doubles with each further retry.
+	RetryBackoffSecs int `yaml:"retry_backoff_secs,omitempty" bson:"retry_backoff_secs"`
+
+	// ContinueOnError indicates that the task should carry on as though the
+	// command succeeded if it fails.
+	ContinueOnError bool `yaml:"continue_on_err,omitempty" bson:"continue_on_err"`
+
 	// Params are used to supply configuratiion specific information.
 	Params map[string]interface{} `yaml:"params,omitempty" bson:"params"`
 
@@ -292,6 +367,69 @@ type TaskConfig struct {
 	BuildVariant *BuildVariant
 	Expansions   *command.Expansions
 	WorkDir      string
+
+	// PrivateVars is the set of project variables whose values are secret,
+	// and so must not be written out by commands.
+	PrivateVars map[string]bool
+
+	// functionScope maps the expansions updated for the rest of the function
+	// this config runs only to the values they had before, or to nil if they
+	// were unset. It is nil outside of functions.
+	functionScope map[string]*string
+}
+
+// WithFunctionScope returns a copy of the config for running the commands of
+// a function, which records the expansions that are updated for the rest of
+// the function only. The copy shares the config's expansions, but each copy
+// has its own record, so that functions run at the same time, such as a
+// timeout block run while a task's function is being killed, don't restore
+// each other's expansions.
+func (tc *TaskConfig) WithFunctionScope() *TaskConfig {
+	scoped := *tc
+	scoped.functionScope = map[string]*string{}
+	return &scoped
+}
+
+// EndFunctionScope restores the expansions that were updated for the rest
+// of the function that has finished to their values from before it began.
+// It does nothing outside of functions.
+func (tc *TaskConfig) EndFunctionScope() {
+	for key, value := range tc.functionScope {
+		if value == nil {
+			delete(*tc.Expansions, key)
+		} else {
+			tc.Expansions.Put(key, *value)
+		}
+	}
+	tc.functionScope = nil
+}
+
+// InFunctionScope returns whether a function is being run.
+func (tc *TaskConfig) InFunctionScope() bool {
+	return tc.functionScope != nil
+}
+
+// PutExpansion sets an expansion, either for the rest of the task, or, if
+// functionOnly is set and a function is being run, for the rest of that
+// function only.
+func (tc *TaskConfig) PutExpansion(key, value string, functionOnly bool) {
+	if tc.functionScope != nil {
+		if functionOnly {
+			if _, ok := tc.functionScope[key]; !ok {
+				var old *string
+				if tc.Expansions.Exists(key) {
+					v := tc.Expansions.Get(key)
+					old = &v
+				}
+				tc.functionScope[key] = old
+			}
+		} else {
+			// the expansion is meant to outlive the function, so it must not
+			// be restored when the function ends
+			delete(tc.functionScope, key)
+		}
+	}
+	tc.Expansions.Put(key, value)
 }
 
 // TaskIdTable is a map of [variant, task display name]->[task id].
@@ -459,7 +597,16 @@ func NewTaskConfig(d *distro.Distro, v *version.Version, p *Project, t *task.Tas
 	}
 
 	e := populateExpansions(d, v, bv, t)
-	return &TaskConfig{d, v, r, p, t, bv, e, d.WorkDir}, nil
+	return &TaskConfig{
+		Distro:       d,
+		Version:      v,
+		ProjectRef:   r,
+		Project:      p,
+		Task:         t,
+		BuildVariant: bv,
+		Expansions:   e,
+		WorkDir:      d.WorkDir,
+	}, nil
 }
 
 func populateExpansions(d *distro.Distro, v *version.Version, bv *BuildVariant, t *task.Task) *command.Expansions {
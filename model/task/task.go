@@ -252,7 +252,9 @@ func (t *Task) UIStatus() string {
 		status = evergreen.TaskSucceeded
 	} else if t.Status == evergreen.TaskFailed {
 		status = evergreen.TaskFailed
-		if t.Details.Type == "system" {
+		if t.Details.Type == "setup" {
+			status = evergreen.TaskSetupFailed
+		} else if t.Details.Type == "system" {
 			status = evergreen.TaskSystemFailed
 			if t.Details.TimedOut {
 				if t.Details.Description == "heartbeat" {
@@ -44,6 +44,8 @@ type TaskLog struct {
 	Timestamp    time.Time     `bson:"ts" json:"ts"`
 	MessageCount int           `bson:"c" json:"c"`
 	Messages     []LogMessage  `bson:"m" json:"m"`
+	// Seq numbers the batches of messages streamed by the agent.
+	Seq int64 `bson:"seq,omitempty" json:"seq,omitempty"`
 }
 
 var (
@@ -54,6 +56,7 @@ var (
 	TaskLogTimestampKey    = bsonutil.MustHaveTag(TaskLog{}, "Timestamp")
 	TaskLogMessageCountKey = bsonutil.MustHaveTag(TaskLog{}, "MessageCount")
 	TaskLogMessagesKey     = bsonutil.MustHaveTag(TaskLog{}, "Messages")
+	TaskLogSeqKey          = bsonutil.MustHaveTag(TaskLog{}, "Seq")
 
 	// bson fields for the log message struct
 	LogMessageTypeKey      = bsonutil.MustHaveTag(LogMessage{}, "Type")
@@ -130,6 +133,29 @@ func FindAllTaskLogs(taskId string, execution int) ([]TaskLog, error) {
 	return result, err
 }
 
+// FindLastTaskLogSeq returns the sequence number of the latest batch of log
+// messages the agent streamed for the task execution, or 0 if there are none.
+func FindLastTaskLogSeq(taskId string, execution int) (int64, error) {
+	session, db, err := getSessionAndDB()
+	if err != nil {
+		return 0, err
+	}
+	defer session.Close()
+
+	result := TaskLog{}
+	err = db.C(TaskLogCollection).Find(
+		bson.M{
+			TaskLogTaskIdKey:    taskId,
+			TaskLogExecutionKey: execution,
+			TaskLogSeqKey:       bson.M{"$gt": 0},
+		},
+	).Select(bson.M{TaskLogSeqKey: 1}).Sort("-" + TaskLogSeqKey).One(&result)
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	return result.Seq, err
+}
+
 func FindMostRecentTaskLogs(taskId string, execution int, limit int) ([]TaskLog, error) {
 	session, db, err := getSessionAndDB()
 	if err != nil {
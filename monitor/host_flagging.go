@@ -73,6 +73,12 @@ func flagIdleHosts(d []distro.Distro, s *evergreen.Settings) ([]host.Host, error
 		return nil, errors.Wrap(err, "error finding free hosts")
 	}
 
+	// figure out how many hosts each distro with a warm pool can spare
+	surplusHosts, err := warmPoolSurplus(d, time.Now())
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding warm pool sizes")
+	}
+
 	// go through the hosts, and see if they have idled long enough to
 	// be terminated
 	for _, freeHost := range freeHosts {
@@ -107,6 +113,16 @@ func flagIdleHosts(d []distro.Distro, s *evergreen.Settings) ([]host.Host, error
 		//  less than 5 minutes til next payment
 		if (communicationTime >= CommunicationTimeCutoff || idleTime >= IdleTimeCutoff) &&
 			tilNextPayment <= MaxTimeTilNextPayment {
+
+			// keep the host if terminating it would shrink its distro's
+			// warm pool below the minimum
+			if surplus, ok := surplusHosts[freeHost.Distro.Id]; ok {
+				if surplus <= 0 {
+					continue
+				}
+				surplusHosts[freeHost.Distro.Id] = surplus - 1
+			}
+
 			idleHosts = append(idleHosts, freeHost)
 		}
 
@@ -115,6 +131,24 @@ func flagIdleHosts(d []distro.Distro, s *evergreen.Settings) ([]host.Host, error
 	return idleHosts, nil
 }
 
+// warmPoolSurplus returns a map of distro id -> the number of hosts above its
+// current warm pool minimum, for every distro that keeps a warm pool
+func warmPoolSurplus(distros []distro.Distro, now time.Time) (map[string]int, error) {
+	surplusHosts := make(map[string]int)
+	for _, d := range distros {
+		minHosts := d.MinimumHosts(now)
+		if minHosts == 0 {
+			continue
+		}
+		numHosts, err := host.Count(host.ByDistroId(d.Id))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error counting hosts for distro %v", d.Id)
+		}
+		surplusHosts[d.Id] = numHosts - minHosts
+	}
+	return surplusHosts, nil
+}
+
 // flagExcessHosts is a hostFlaggingFunc to get all hosts that push their
 // distros over the specified max hosts
 func flagExcessHosts(distros []distro.Distro, s *evergreen.Settings) ([]host.Host, error) {
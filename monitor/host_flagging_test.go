@@ -183,6 +183,30 @@ func TestFlaggingIdleHosts(t *testing.T) {
 			So(len(idle), ShouldEqual, 1)
 			So(idle[0].Id, ShouldEqual, "h1")
 		})
+		Convey("idle hosts should not be flagged if that would shrink their"+
+			" distro's warm pool below the minimum", func() {
+			d := distro.Distro{Id: "warm", PoolSize: 5, MinHosts: 2}
+			for _, id := range []string{"h1", "h2", "h3"} {
+				idleHost := host.Host{
+					Id:                    id,
+					Distro:                d,
+					Provider:              mock.ProviderName,
+					LastCommunicationTime: time.Now().Add(-time.Minute * 20),
+					Status:                evergreen.HostRunning,
+					StartedBy:             evergreen.User,
+				}
+				So(idleHost.Insert(), ShouldBeNil)
+			}
+
+			idle, err := flagIdleHosts([]distro.Distro{d}, nil)
+			So(err, ShouldBeNil)
+			So(len(idle), ShouldEqual, 1)
+
+			d.MinHosts = 3
+			idle, err = flagIdleHosts([]distro.Distro{d}, nil)
+			So(err, ShouldBeNil)
+			So(len(idle), ShouldEqual, 0)
+		})
 
 	})
 
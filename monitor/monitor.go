@@ -35,6 +35,8 @@ var (
 	// the functions the host monitor will run through to do simpler checks
 	defaultHostMonitoringFuncs = []hostMonitoringFunc{
 		monitorReachability,
+		monitorStaticHostHealth,
+		monitorOrphanedInstances,
 	}
 
 	// the functions the notifier will use to build notifications that need
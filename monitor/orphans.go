@@ -0,0 +1,107 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/cloud/providers"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+const (
+	// instances younger than this are never considered orphaned, since their
+	// host documents may not have been updated with their ids yet
+	OrphanGracePeriod = time.Hour
+)
+
+// monitorOrphanedInstances is a hostMonitoringFunc responsible for finding
+// instances that Evergreen created in a provider but no longer has a running
+// host for. Orphaned instances are reported, and terminated if the monitor is
+// configured to do so.
+func monitorOrphanedInstances(settings *evergreen.Settings) []error {
+	grip.Info("Checking for orphaned instances...")
+
+	distros, err := distro.Find(db.Q{})
+	if err != nil {
+		return []error{errors.Wrap(err, "error finding distros")}
+	}
+
+	// check each provider that is in use once
+	var errs []error
+	seen := map[string]bool{}
+	for _, d := range distros {
+		if seen[d.Provider] {
+			continue
+		}
+		seen[d.Provider] = true
+
+		cloudManager, err := providers.GetCloudManager(d.Provider, settings)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "error getting cloud manager for provider %s", d.Provider))
+			continue
+		}
+		lister, ok := cloudManager.(cloud.InstanceLister)
+		if !ok {
+			continue
+		}
+
+		if err := checkOrphanedInstances(d.Provider, lister, settings.Monitor.TerminateOrphanedInstances); err != nil {
+			errs = append(errs, errors.Wrapf(err, "error checking provider %s for orphaned instances", d.Provider))
+		}
+	}
+
+	return errs
+}
+
+// checkOrphanedInstances reports, and optionally terminates, the instances of
+// a single provider that have no matching host.
+func checkOrphanedInstances(provider string, lister cloud.InstanceLister, terminate bool) error {
+	instances, err := lister.ListManagedInstances()
+	if err != nil {
+		return errors.Wrap(err, "error listing instances")
+	}
+
+	now := time.Now()
+	catcher := grip.NewCatcher()
+	for _, instance := range instances {
+		if instance.Age(now) < OrphanGracePeriod {
+			continue
+		}
+
+		orphaned, err := isOrphaned(instance)
+		if err != nil {
+			catcher.Add(err)
+			continue
+		}
+		if !orphaned {
+			continue
+		}
+
+		if !terminate {
+			grip.Warningf("Found orphaned %s instance %s (distro '%s', owner '%s')", provider,
+				instance.Id, instance.Tags[cloud.TagDistro], instance.Tags[cloud.TagOwner])
+			continue
+		}
+		grip.Warningf("Terminating orphaned %s instance %s (distro '%s', owner '%s')", provider,
+			instance.Id, instance.Tags[cloud.TagDistro], instance.Tags[cloud.TagOwner])
+		catcher.Add(errors.Wrapf(lister.TerminateManagedInstance(instance),
+			"error terminating orphaned instance %s", instance.Id))
+	}
+
+	return catcher.Resolve()
+}
+
+// isOrphaned returns whether the instance has no host, or its host is
+// terminated.
+func isOrphaned(instance cloud.ManagedInstance) (bool, error) {
+	h, err := host.FindOne(host.ById(instance.Id))
+	if err != nil {
+		return false, errors.Wrapf(err, "error finding host for instance %s", instance.Id)
+	}
+	return h == nil || h.Status == evergreen.HostTerminated, nil
+}
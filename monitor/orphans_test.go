@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud"
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeInstanceLister returns a fixed set of instances and records the ones it
+// is asked to terminate.
+type fakeInstanceLister struct {
+	instances  []cloud.ManagedInstance
+	terminated []string
+}
+
+func (l *fakeInstanceLister) ListManagedInstances() ([]cloud.ManagedInstance, error) {
+	return l.instances, nil
+}
+
+func (l *fakeInstanceLister) TerminateManagedInstance(i cloud.ManagedInstance) error {
+	l.terminated = append(l.terminated, i.Id)
+	return nil
+}
+
+func TestCheckOrphanedInstances(t *testing.T) {
+	testConfig := testutil.TestConfig()
+	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(testConfig))
+
+	Convey("When checking a provider for orphaned instances", t, func() {
+		testutil.HandleTestingErr(db.ClearCollections(host.Collection),
+			t, "error clearing hosts collection")
+
+		old := time.Now().Add(-2 * OrphanGracePeriod)
+		running := &host.Host{Id: "running", Status: evergreen.HostRunning}
+		terminated := &host.Host{Id: "terminated", Status: evergreen.HostTerminated}
+		So(running.Insert(), ShouldBeNil)
+		So(terminated.Insert(), ShouldBeNil)
+
+		lister := &fakeInstanceLister{
+			instances: []cloud.ManagedInstance{
+				{Id: "running", CreatedAt: old},
+				{Id: "terminated", CreatedAt: old},
+				{Id: "missing", CreatedAt: old},
+				{Id: "new", CreatedAt: time.Now()},
+				{Id: "tagged", Tags: map[string]string{
+					cloud.TagStartTime: old.Format(cloud.TagTimeFormat),
+				}},
+			},
+		}
+
+		Convey("orphans should only be reported if termination is disabled", func() {
+			So(checkOrphanedInstances("mock", lister, false), ShouldBeNil)
+			So(lister.terminated, ShouldBeEmpty)
+		})
+
+		Convey("instances without a running host and past the grace period"+
+			" should be terminated", func() {
+			So(checkOrphanedInstances("mock", lister, true), ShouldBeNil)
+			So(lister.terminated, ShouldResemble, []string{"terminated", "missing", "tagged"})
+		})
+	})
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud/providers"
+	"github.com/evergreen-ci/evergreen/cloud/providers/static"
+	"github.com/evergreen-ci/evergreen/hostutil"
+	"github.com/evergreen-ci/evergreen/model/event"
+	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+const (
+	// how long to wait in between health checks of a static host
+	HealthCheckInterval = 10 * time.Minute
+)
+
+// monitorStaticHostHealth is a hostMonitoringFunc responsible for running the
+// health check script of idle static hosts, quarantining hosts that fail it and
+// re-admitting automatically quarantined hosts that pass it.
+func monitorStaticHostHealth(settings *evergreen.Settings) []error {
+	grip.Info("Running static host health checks...")
+
+	threshold := time.Now().Add(-HealthCheckInterval)
+	hosts, err := host.Find(host.ByStaticNeedsHealthCheck(threshold))
+	if err != nil {
+		return []error{errors.Wrap(err, "error finding static hosts not checked recently")}
+	}
+
+	// continue on error so that other hosts can be checked
+	var errs []error
+	for _, h := range hosts {
+		if err := checkStaticHostHealth(&h, settings); err != nil {
+			errs = append(errs, errors.Wrapf(err, "error checking health of host %s", h.Id))
+		}
+	}
+
+	return errs
+}
+
+// checkStaticHostHealth runs the health check for a single host, if its distro
+// has one, and updates the host's status accordingly.
+func checkStaticHostHealth(h *host.Host, settings *evergreen.Settings) error {
+	staticSettings, err := static.DecodeSettings(&h.Distro)
+	if err != nil {
+		return err
+	}
+	if staticSettings.HealthCheck == "" {
+		return nil
+	}
+
+	cloudHost, err := providers.GetCloudHost(h, settings)
+	if err != nil {
+		return errors.Wrapf(err, "error getting cloud host for host %s", h.Id)
+	}
+	sshOptions, err := cloudHost.GetSSHOptions()
+	if err != nil {
+		return errors.Wrapf(err, "error getting ssh options for host %s", h.Id)
+	}
+
+	grip.Infoln("Running health check for host:", h.Id)
+	startTime := time.Now()
+	logs, checkErr := hostutil.RunRemoteScriptContents(h, staticSettings.HealthCheck, sshOptions)
+	event.LogHostHealthCheck(h.Id, logs, checkErr == nil, time.Since(startTime))
+
+	if err = h.SetHealthChecked(startTime); err != nil {
+		return errors.Wrapf(err, "error recording health check for host %s", h.Id)
+	}
+
+	switch {
+	case checkErr != nil && h.Status == evergreen.HostRunning:
+		grip.Warningf("Quarantining host %s after failed health check: %v", h.Id, checkErr)
+		return h.Quarantine("health check failed: "+checkErr.Error(), true)
+	case checkErr == nil && h.Status == evergreen.HostQuarantined && h.AutoQuarantined:
+		grip.Infof("Re-admitting host %s after passing health check", h.Id)
+		return h.Readmit("health check passed")
+	}
+
+	return nil
+}
@@ -24,6 +24,8 @@ const (
 	AttachPluginName      = "attach"
 	AttachResultsCmd      = "results"
 	AttachXunitResultsCmd = "xunit_results"
+	AttachTestResultsCmd  = "test_results"
+	AttachTaskFilesCmd    = "task_files"
 
 	AttachResultsAPIEndpoint = "results"
 	AttachLogsAPIEndpoint    = "test_logs"
@@ -73,6 +75,25 @@ func stripHiddenFiles(files []artifact.File, pluginUser *user.DBUser) []artifact
 	return publicFiles
 }
 
+// relinkFiles points the links to files kept in the project's artifact store
+// at wherever the store serves them from now, e.g. after its base URL is
+// changed.
+func relinkFiles(files []artifact.File, projectRef *model.ProjectRef) []artifact.File {
+	if projectRef == nil {
+		return files
+	}
+	storeConf := projectRef.ArtifactStore
+	for i, file := range files {
+		if file.Backend == "" || file.Backend != storeConf.StoreType() {
+			continue
+		}
+		if link := storeConf.Link(file.Bucket, file.Key); link != "" {
+			files[i].Link = link
+		}
+	}
+	return files
+}
+
 // GetPanelConfig returns a plugin.PanelConfig struct representing panels
 // that will be added to the Task and Build pages.
 func (self *AttachPlugin) GetPanelConfig() (*plugin.PanelConfig, error) {
@@ -94,7 +115,7 @@ func (self *AttachPlugin) GetPanelConfig() (*plugin.PanelConfig, error) {
 					if artifactEntry == nil {
 						return nil, nil
 					}
-					return stripHiddenFiles(artifactEntry.Files, context.User), nil
+					return relinkFiles(stripHiddenFiles(artifactEntry.Files, context.User), context.ProjectRef), nil
 				},
 			},
 			{
@@ -112,7 +133,8 @@ func (self *AttachPlugin) GetPanelConfig() (*plugin.PanelConfig, error) {
 					}
 					for i := range taskArtifactFiles {
 						// remove hidden files if the user isn't logged in
-						taskArtifactFiles[i].Files = stripHiddenFiles(taskArtifactFiles[i].Files, context.User)
+						taskArtifactFiles[i].Files = relinkFiles(
+							stripHiddenFiles(taskArtifactFiles[i].Files, context.User), context.ProjectRef)
 					}
 					return taskArtifactFiles, nil
 				},
@@ -130,6 +152,10 @@ func (self *AttachPlugin) NewCommand(cmdName string) (plugin.Command,
 		return &AttachResultsCommand{}, nil
 	case AttachXunitResultsCmd:
 		return &AttachXUnitResultsCommand{}, nil
+	case AttachTestResultsCmd:
+		return &AttachTestResultsCommand{}, nil
+	case AttachTaskFilesCmd:
+		return &AttachTaskFilesCommand{}, nil
 	default:
 		return nil, errors.Errorf("No such %v command: %v", AttachPluginName, cmdName)
 	}
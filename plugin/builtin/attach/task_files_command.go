@@ -0,0 +1,148 @@
+package attach
+
+import (
+	"net/url"
+	"path"
+	"sort"
+
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
+	"github.com/evergreen-ci/evergreen/util"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+// AttachTaskFilesCommand is used to attach links to files to the task page.
+// Each file is given either by its link, or by its key in a bucket of the
+// project's artifact store, in which case it is linked to wherever the store
+// serves it from.
+type AttachTaskFilesCommand struct {
+	// Files maps the names the files are shown by to their links or keys.
+	Files map[string]string `mapstructure:"files" plugin:"expand"`
+
+	// Bucket is the bucket of the project's artifact store that files given
+	// by their key are in.
+	Bucket string `mapstructure:"bucket" plugin:"expand"`
+
+	// Visibility determines who can see the links in the UI, as it does for
+	// s3.put. If unset, they are public.
+	Visibility string `mapstructure:"visibility" plugin:"expand"`
+}
+
+func (self *AttachTaskFilesCommand) Name() string {
+	return AttachTaskFilesCmd
+}
+
+func (self *AttachTaskFilesCommand) Plugin() string {
+	return AttachPluginName
+}
+
+// ParseParams decodes and validates the command's parameters.
+func (self *AttachTaskFilesCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if err := self.validateParams(); err != nil {
+		return errors.Wrapf(err, "error validating '%v' params", self.Name())
+	}
+	return nil
+}
+
+func (self *AttachTaskFilesCommand) validateParams() error {
+	if len(self.Files) == 0 {
+		return errors.New("files cannot be empty")
+	}
+	if !util.SliceContains(artifact.ValidVisibilities, self.Visibility) {
+		return errors.Errorf("invalid visibility setting: %v", self.Visibility)
+	}
+	if self.Bucket == "" {
+		for name, file := range self.Files {
+			if !isLink(file) && !plugin.IsExpandable(file) {
+				return errors.Errorf("bucket cannot be blank, as file '%v' is not a link", name)
+			}
+		}
+	}
+	return nil
+}
+
+// isLink returns whether a file is given by its link, rather than its key.
+func isLink(file string) bool {
+	u, err := url.Parse(file)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// Execute attaches the links to the task.
+func (self *AttachTaskFilesCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator,
+	taskConfig *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, taskConfig.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := self.validateParams(); err != nil {
+		return errors.Wrap(err, "expanded params are not valid")
+	}
+
+	storeConf := artifactstore.Config{}
+	if taskConfig.ProjectRef != nil {
+		storeConf = taskConfig.ProjectRef.ArtifactStore
+	}
+	files, err := self.taskFiles(storeConf)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	errChan := make(chan error)
+	go func() {
+		pluginLogger.LogExecution(slogger.INFO, "Attaching %d files", len(files))
+		errChan <- errors.Wrap(pluginCom.PostTaskFiles(files), "Attach files failed")
+	}()
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			return err
+		}
+		pluginLogger.LogTask(slogger.INFO, "Attach task files succeeded")
+		return nil
+	case <-stop:
+		pluginLogger.LogExecution(slogger.INFO, "Received signal to terminate"+
+			" execution of attach task files command")
+		return nil
+	}
+}
+
+// taskFiles returns the files to attach, in order of name, linking those
+// given by key to the artifact store.
+func (self *AttachTaskFilesCommand) taskFiles(storeConf artifactstore.Config) ([]*artifact.File, error) {
+	names := []string{}
+	for name := range self.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := []*artifact.File{}
+	for _, name := range names {
+		file := &artifact.File{
+			Name:       name,
+			Link:       self.Files[name],
+			Visibility: self.Visibility,
+		}
+		if !isLink(file.Link) {
+			file.Backend = storeConf.StoreType()
+			file.Bucket = self.Bucket
+			file.Key = path.Clean("/" + self.Files[name])[1:]
+			file.Link = storeConf.Link(file.Bucket, file.Key)
+			if file.Link == "" {
+				return nil, errors.Errorf("cannot link to '%v' in the %v artifact store "+
+					"without its base_url", file.Key, file.Backend)
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
@@ -0,0 +1,118 @@
+package attach
+
+import (
+	"testing"
+
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAttachTaskFilesParams(t *testing.T) {
+	Convey("With an attach task_files command", t, func() {
+		cmd := &AttachTaskFilesCommand{}
+
+		Convey("files must be given", func() {
+			So(cmd.ParseParams(map[string]interface{}{}), ShouldNotBeNil)
+		})
+
+		Convey("links can be given without a bucket", func() {
+			So(cmd.ParseParams(map[string]interface{}{
+				"files": map[string]interface{}{"docs": "https://example.com/docs.html"},
+			}), ShouldBeNil)
+		})
+
+		Convey("keys need a bucket", func() {
+			params := map[string]interface{}{
+				"files": map[string]interface{}{"build": "builds/${revision}.tgz", "log": "logs/build.log"},
+			}
+			So(cmd.ParseParams(params), ShouldNotBeNil)
+			params["bucket"] = "artifacts"
+			So(cmd.ParseParams(params), ShouldBeNil)
+		})
+
+		Convey("the visibility must be valid", func() {
+			So(cmd.ParseParams(map[string]interface{}{
+				"files":      map[string]interface{}{"docs": "https://example.com/docs.html"},
+				"visibility": "everyone",
+			}), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestAttachTaskFilesLinks(t *testing.T) {
+	Convey("With files given by link and by key", t, func() {
+		cmd := &AttachTaskFilesCommand{
+			Files: map[string]string{
+				"docs":  "https://example.com/docs.html",
+				"build": "/builds/app.tgz",
+			},
+			Bucket:     "artifacts",
+			Visibility: artifact.Private,
+		}
+
+		Convey("keys should be linked to S3 by default", func() {
+			files, err := cmd.taskFiles(artifactstore.Config{})
+			So(err, ShouldBeNil)
+			So(len(files), ShouldEqual, 2)
+			So(*files[0], ShouldResemble, artifact.File{
+				Name:       "build",
+				Link:       "https://s3.amazonaws.com/artifacts/builds/app.tgz",
+				Visibility: artifact.Private,
+				Backend:    artifactstore.S3Type,
+				Bucket:     "artifacts",
+				Key:        "builds/app.tgz",
+			})
+			So(*files[1], ShouldResemble, artifact.File{
+				Name:       "docs",
+				Link:       "https://example.com/docs.html",
+				Visibility: artifact.Private,
+			})
+		})
+
+		Convey("keys should be linked to the project's artifact store", func() {
+			files, err := cmd.taskFiles(artifactstore.Config{
+				Type:     artifactstore.HTTPType,
+				Endpoint: "http://files.example.com:8080",
+			})
+			So(err, ShouldBeNil)
+			So(files[0].Link, ShouldEqual, "http://files.example.com:8080/artifacts/builds/app.tgz")
+			So(files[0].Backend, ShouldEqual, artifactstore.HTTPType)
+		})
+
+		Convey("keys can't be linked to a store that doesn't say where it serves files", func() {
+			_, err := cmd.taskFiles(artifactstore.Config{Type: artifactstore.AzureType})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestRelinkFiles(t *testing.T) {
+	Convey("With files attached from an artifact store", t, func() {
+		files := []artifact.File{
+			{Name: "stored", Link: "file:///srv/artifacts/bucket/a.txt",
+				Backend: artifactstore.LocalType, Bucket: "bucket", Key: "a.txt"},
+			{Name: "linked", Link: "https://example.com/b.txt"},
+			{Name: "elsewhere", Link: "https://s3.amazonaws.com/bucket/c.txt",
+				Backend: artifactstore.S3Type, Bucket: "bucket", Key: "c.txt"},
+		}
+
+		Convey("links should point to where the project's store serves them", func() {
+			relinked := relinkFiles(files, &model.ProjectRef{
+				ArtifactStore: artifactstore.Config{
+					Type:    artifactstore.LocalType,
+					Path:    "/srv/artifacts",
+					BaseURL: "https://artifacts.example.com/",
+				},
+			})
+			So(relinked[0].Link, ShouldEqual, "https://artifacts.example.com/bucket/a.txt")
+			So(relinked[1].Link, ShouldEqual, "https://example.com/b.txt")
+			So(relinked[2].Link, ShouldEqual, "https://s3.amazonaws.com/bucket/c.txt")
+		})
+
+		Convey("links should be left alone without a project", func() {
+			So(relinkFiles(files, nil)[0].Link, ShouldEqual, "file:///srv/artifacts/bucket/a.txt")
+		})
+	})
+}
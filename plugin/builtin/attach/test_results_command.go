@@ -0,0 +1,160 @@
+package attach
+
+import (
+	"os"
+	"strings"
+
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/plugin/builtin/attach/testresults"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+// AttachTestResultsCommand reads test reports in one of the formats supported
+// by the testresults package, and attaches their tests and logs to the task.
+type AttachTestResultsCommand struct {
+	// File describes the relative path of the file to be sent. Supports globbing.
+	// Note that this can also be described via expansions.
+	File  string   `mapstructure:"file" plugin:"expand"`
+	Files []string `mapstructure:"files" plugin:"expand"`
+
+	// Format is the format of the reports, e.g. "tap" or "test2json".
+	Format string `mapstructure:"format"`
+}
+
+func (c *AttachTestResultsCommand) Name() string {
+	return AttachTestResultsCmd
+}
+
+func (c *AttachTestResultsCommand) Plugin() string {
+	return AttachPluginName
+}
+
+// ParseParams reads and validates the command parameters. This is required
+// to satisfy the 'Command' interface
+func (c *AttachTestResultsCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, c); err != nil {
+		return errors.Wrapf(err, "error decoding '%s' params", c.Name())
+	}
+
+	if err := c.validateParams(); err != nil {
+		return errors.Wrapf(err, "error validating '%s' params", c.Name())
+	}
+
+	return nil
+}
+
+// validateParams ensures that there are files to read and that their format
+// is supported.
+func (c *AttachTestResultsCommand) validateParams() error {
+	if c.File == "" && len(c.Files) == 0 {
+		return errors.New("must specify at least one file")
+	}
+	if _, ok := testresults.Parsers[c.Format]; !ok {
+		return errors.Errorf("format must be one of %s, not '%s'",
+			strings.Join(testresults.Formats(), ", "), c.Format)
+	}
+	return nil
+}
+
+func (c *AttachTestResultsCommand) expandParams(conf *model.TaskConfig) error {
+	if c.File != "" {
+		c.Files = append(c.Files, c.File)
+	}
+
+	var err error
+	catcher := grip.NewCatcher()
+
+	for idx, f := range c.Files {
+		c.Files[idx], err = conf.Expansions.ExpandString(f)
+		catcher.Add(err)
+	}
+
+	return errors.Wrapf(catcher.Resolve(), "problem expanding paths")
+}
+
+// Execute carries out the AttachTestResultsCommand command - this is required
+// to satisfy the 'Command' interface
+func (c *AttachTestResultsCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator,
+	taskConfig *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := c.expandParams(taskConfig); err != nil {
+		return err
+	}
+
+	errChan := make(chan error)
+	go func() {
+		errChan <- c.parseAndUploadResults(taskConfig, pluginLogger, pluginCom)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-stop:
+		pluginLogger.LogExecution(slogger.INFO, "Received signal to terminate"+
+			" execution of attach test results command")
+		return nil
+	}
+}
+
+func (c *AttachTestResultsCommand) parseAndUploadResults(
+	taskConfig *model.TaskConfig, pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator) error {
+	tests := []task.TestResult{}
+	logs := []*model.TestLog{}
+	logIdxToTestIdx := []int{}
+
+	reportFilePaths, err := getFilePaths(taskConfig.WorkDir, c.Files)
+	if err != nil {
+		return err
+	}
+	if len(reportFilePaths) == 0 {
+		pluginLogger.LogTask(slogger.WARN, "No %v reports matched %v", c.Format, c.Files)
+	}
+
+	parse := testresults.Parsers[c.Format]
+	for _, reportFileLoc := range reportFilePaths {
+		file, err := os.Open(reportFileLoc)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't open %s file", c.Format)
+		}
+
+		results, err := parse(file)
+		if err != nil {
+			_ = file.Close()
+			return errors.Wrapf(err, "error parsing %s file '%s'", c.Format, reportFileLoc)
+		}
+
+		if err = file.Close(); err != nil {
+			return errors.Wrapf(err, "error closing %s file", c.Format)
+		}
+
+		pluginLogger.LogTask(slogger.INFO, "Parsed %v tests from %v", len(results), reportFileLoc)
+		for _, result := range results {
+			test, log := result.ToModelTestResultAndLog(taskConfig.Task)
+			if log != nil {
+				logs = append(logs, log)
+				logIdxToTestIdx = append(logIdxToTestIdx, len(tests))
+			}
+			tests = append(tests, test)
+		}
+	}
+
+	for i, log := range logs {
+		logId, err := SendJSONLogs(pluginLogger, pluginCom, log)
+		if err != nil {
+			pluginLogger.LogTask(slogger.WARN, "Error uploading logs for %v", log.Name)
+			continue
+		}
+		tests[logIdxToTestIdx[i]].LogId = logId
+		tests[logIdxToTestIdx[i]].LineNum = 1
+	}
+
+	return SendJSONResults(taskConfig, pluginLogger, pluginCom, &task.TestResults{Results: tests})
+}
@@ -0,0 +1,68 @@
+package attach_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/task"
+	. "github.com/evergreen-ci/evergreen/plugin/builtin/attach"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var TestResultsConfig = filepath.Join(workingDirectory, "testdata", "plugin_attach_test_results.yml")
+
+// testResultsDBTests are the database verification tests for the
+// test_results command
+func testResultsDBTests(taskId string) {
+	t, err := task.FindOne(task.ById(taskId))
+	So(err, ShouldBeNil)
+	So(t, ShouldNotBeNil)
+	// 8 tap, 8 pytest report log, 4 pytest json report and 5 cucumber tests
+	So(len(t.TestResults), ShouldEqual, 25)
+
+	statuses := map[string]string{}
+	for _, res := range t.TestResults {
+		statuses[res.TestFile] = res.Status
+	}
+	So(statuses["query_delete"], ShouldEqual, evergreen.TestSkippedStatus)
+	So(statuses["query_insert___with_hash"], ShouldEqual, evergreen.TestFailedStatus)
+	So(statuses["Checkout_Pay_by_card"], ShouldEqual, evergreen.TestSucceededStatus)
+
+	Convey("along with the proper logs", func() {
+		tl := dBFindOneTestLog("tests_test_math.py__test_divide", taskId)
+		So(tl.Lines[0], ShouldEqual, "CALL:")
+		tl = dBFindOneTestLog("Checkout_Pay_by_voucher", taskId)
+		So(tl.Lines[1], ShouldContainSubstring, "failed")
+	})
+}
+
+func TestAttachTestResults(t *testing.T) {
+	runTest(t, TestResultsConfig, testResultsDBTests)
+}
+
+func TestAttachTestResultsParams(t *testing.T) {
+	Convey("With an attach.test_results command", t, func() {
+		cmd := &AttachTestResultsCommand{}
+
+		Convey("a supported format should be accepted", func() {
+			So(cmd.ParseParams(map[string]interface{}{
+				"file":   "report.tap",
+				"format": "tap",
+			}), ShouldBeNil)
+		})
+
+		Convey("an unsupported format should be rejected", func() {
+			So(cmd.ParseParams(map[string]interface{}{
+				"file":   "report.xml",
+				"format": "nunit",
+			}), ShouldNotBeNil)
+		})
+
+		Convey("a missing file should be rejected", func() {
+			So(cmd.ParseParams(map[string]interface{}{
+				"format": "tap",
+			}), ShouldNotBeNil)
+		})
+	})
+}
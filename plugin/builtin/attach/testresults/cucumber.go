@@ -0,0 +1,124 @@
+package testresults
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/pkg/errors"
+)
+
+type cucumberFeature struct {
+	Name     string            `json:"name"`
+	URI      string            `json:"uri"`
+	Elements []cucumberElement `json:"elements"`
+}
+
+type cucumberElement struct {
+	Name      string         `json:"name"`
+	Type      string         `json:"type"`
+	Keyword   string         `json:"keyword"`
+	Line      int            `json:"line"`
+	StartTime string         `json:"start_timestamp"`
+	Before    []cucumberStep `json:"before"`
+	Steps     []cucumberStep `json:"steps"`
+	After     []cucumberStep `json:"after"`
+}
+
+type cucumberStep struct {
+	Keyword string         `json:"keyword"`
+	Name    string         `json:"name"`
+	Result  cucumberResult `json:"result"`
+}
+
+type cucumberResult struct {
+	Status       string `json:"status"`
+	Duration     int64  `json:"duration"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// ParseCucumber parses a Cucumber JSON report. Each scenario, including each
+// example of a scenario outline, is a test named after its feature, and its
+// steps and hooks make up its log. A feature's background is run as part of
+// each of its scenarios. Undefined and ambiguous steps fail their scenario;
+// pending and skipped steps skip it.
+func ParseCucumber(reader io.Reader) ([]Result, error) {
+	features := []cucumberFeature{}
+	if err := json.NewDecoder(reader).Decode(&features); err != nil {
+		return nil, errors.Wrap(err, "error parsing cucumber report")
+	}
+
+	results := []Result{}
+	for _, feature := range features {
+		featureName := feature.Name
+		if featureName == "" {
+			featureName = feature.URI
+		}
+		seen := map[string]int{}
+		var background *cucumberElement
+		for i := range feature.Elements {
+			element := feature.Elements[i]
+			if element.Type == "background" {
+				background = &element
+				continue
+			}
+
+			name := featureName + "/" + element.Name
+			seen[name]++
+			if seen[name] > 1 {
+				// the examples of an outline share its name
+				name = fmt.Sprintf("%s (line %d)", name, element.Line)
+			}
+
+			res := Result{Name: name}
+			if start, err := time.Parse(time.RFC3339Nano, element.StartTime); err == nil {
+				res.Start = start
+			}
+			statuses := []string{}
+			steps := [][]cucumberStep{element.Before}
+			if background != nil {
+				steps = append(steps, background.Before, background.Steps, background.After)
+				background = nil
+			}
+			steps = append(steps, element.Steps, element.After)
+			for _, group := range steps {
+				for _, step := range group {
+					res.Duration += time.Duration(step.Result.Duration)
+					statuses = append(statuses, cucumberStatus(step.Result.Status))
+					res.Log = append(res.Log, step.lines()...)
+				}
+			}
+			res.Status = worstStatus(statuses...)
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+func (s cucumberStep) lines() []string {
+	name := strings.TrimSpace(s.Keyword + s.Name)
+	if name == "" {
+		name = "hook"
+	}
+	lines := []string{fmt.Sprintf("%s ... %s (%v)", name, s.Result.Status, time.Duration(s.Result.Duration))}
+	for _, line := range splitLines(s.Result.ErrorMessage) {
+		lines = append(lines, "    "+line)
+	}
+	return lines
+}
+
+// cucumberStatus converts a step status to a test status. A step after a
+// failed step is skipped, so only a scenario without failures is skipped.
+func cucumberStatus(status string) string {
+	switch status {
+	case "passed":
+		return evergreen.TestSucceededStatus
+	case "skipped", "pending":
+		return evergreen.TestSkippedStatus
+	default:
+		return evergreen.TestFailedStatus
+	}
+}
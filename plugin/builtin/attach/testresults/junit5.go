@@ -0,0 +1,165 @@
+package testresults
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/pkg/errors"
+)
+
+// junit5UniqueId matches the segments of a JUnit Platform unique id, e.g.
+// [engine:junit-jupiter]/[class:com.example.FooTest]/[method:bar()]
+var junit5UniqueId = regexp.MustCompile(`\[([a-z-]+):([^\]]*)\]`)
+
+// junit5Suite is a suite in the XML report written by the JUnit Platform's
+// legacy XML reporter and by Surefire. Suites may be nested.
+type junit5Suite struct {
+	Suites []junit5Suite `xml:"testsuite"`
+	Cases  []junit5Case  `xml:"testcase"`
+}
+
+type junit5Case struct {
+	Name         string          `xml:"name,attr"`
+	ClassName    string          `xml:"classname,attr"`
+	Time         float64         `xml:"time,attr"`
+	Failures     []junit5Details `xml:"failure"`
+	Errors       []junit5Details `xml:"error"`
+	Skipped      *junit5Details  `xml:"skipped"`
+	RerunFailure []junit5Details `xml:"rerunFailure"`
+	FlakyFailure []junit5Details `xml:"flakyFailure"`
+	FlakyError   []junit5Details `xml:"flakyError"`
+	SystemOut    string          `xml:"system-out"`
+	SystemErr    string          `xml:"system-err"`
+}
+
+type junit5Details struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+// ParseJUnit5 parses a JUnit 5 XML report. Unlike the xunit parser, it keeps
+// each test's output, reports retried tests, and names parameterized and
+// dynamic tests as subtests of their template using the unique ids the JUnit
+// Platform writes to each test's output.
+func ParseJUnit5(reader io.Reader) ([]Result, error) {
+	root := junit5Suite{}
+	if err := xml.NewDecoder(reader).Decode(&root); err != nil {
+		return nil, errors.Wrap(err, "error parsing JUnit 5 report")
+	}
+	results := []Result{}
+	root.addResults(&results)
+	return results, nil
+}
+
+func (s junit5Suite) addResults(results *[]Result) {
+	for _, suite := range s.Suites {
+		suite.addResults(results)
+	}
+	for _, tc := range s.Cases {
+		*results = append(*results, tc.toResult())
+	}
+}
+
+func (tc junit5Case) toResult() Result {
+	res := Result{
+		Duration: time.Duration(tc.Time * float64(time.Second)),
+		Status:   evergreen.TestSucceededStatus,
+	}
+
+	name := ""
+	output := []string{}
+	for _, line := range splitLines(strings.TrimSpace(tc.SystemOut)) {
+		switch {
+		case strings.HasPrefix(line, "unique-id: "):
+			name = junit5Name(tc.ClassName, strings.TrimPrefix(line, "unique-id: "))
+		case strings.HasPrefix(line, "display-name: "):
+			// the name already identifies the test
+		default:
+			output = append(output, line)
+		}
+	}
+	if name == "" {
+		name = tc.Name
+		if tc.ClassName != "" {
+			name = fmt.Sprintf("%s.%s", strings.Replace(tc.ClassName, "$", ".", -1), tc.Name)
+		}
+	}
+	res.Name = name
+
+	switch {
+	case len(tc.Failures) > 0 || len(tc.Errors) > 0:
+		res.Status = evergreen.TestFailedStatus
+	case tc.Skipped != nil:
+		res.Status = evergreen.TestSkippedStatus
+	}
+
+	for _, d := range tc.Failures {
+		res.Log = append(res.Log, d.lines("FAILURE")...)
+	}
+	for _, d := range tc.Errors {
+		res.Log = append(res.Log, d.lines("ERROR")...)
+	}
+	for _, d := range tc.RerunFailure {
+		res.Log = append(res.Log, d.lines("RERUN FAILURE")...)
+	}
+	for _, d := range append(tc.FlakyFailure, tc.FlakyError...) {
+		res.Log = append(res.Log, d.lines("FLAKY FAILURE")...)
+	}
+	if tc.Skipped != nil && (tc.Skipped.Message != "" || strings.TrimSpace(tc.Skipped.Content) != "") {
+		res.Log = append(res.Log, tc.Skipped.lines("SKIPPED")...)
+	}
+	if len(output) > 0 {
+		res.Log = append(res.Log, "STDOUT:")
+		res.Log = append(res.Log, output...)
+	}
+	if stderr := splitLines(strings.TrimSpace(tc.SystemErr)); len(stderr) > 0 {
+		res.Log = append(res.Log, "STDERR:")
+		res.Log = append(res.Log, stderr...)
+	}
+	return res
+}
+
+// junit5Name names a test from its unique id: the class, then the method or
+// template, then any invocations and dynamic tests as subtests.
+func junit5Name(className, uniqueId string) string {
+	parts := []string{}
+	classes := []string{}
+	for _, segment := range junit5UniqueId.FindAllStringSubmatch(uniqueId, -1) {
+		switch segment[1] {
+		case "engine":
+		case "class":
+			classes = []string{segment[2]}
+		case "nested-class":
+			classes = append(classes, segment[2])
+		default:
+			parts = append(parts, segment[2])
+		}
+	}
+	if len(classes) == 0 && className != "" {
+		classes = []string{strings.Replace(className, "$", ".", -1)}
+	}
+	if len(parts) == 0 {
+		return strings.Join(classes, ".")
+	}
+	if len(classes) == 0 {
+		return strings.Join(parts, "/")
+	}
+	return strings.Join(classes, ".") + "." + strings.Join(parts, "/")
+}
+
+func (d junit5Details) lines(kind string) []string {
+	header := kind
+	if d.Message != "" {
+		header += ": " + d.Message
+	}
+	if d.Type != "" {
+		header += fmt.Sprintf(" (%s)", d.Type)
+	}
+	return append([]string{header}, splitLines(strings.TrimSpace(d.Content))...)
+}
@@ -0,0 +1,233 @@
+package testresults
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func parseFile(t *testing.T, format, name string) []Result {
+	file, err := os.Open(filepath.Join(testutil.GetDirectoryOfFile(), "testdata", name))
+	testutil.HandleTestingErr(err, t, "Error reading file")
+	defer file.Close()
+
+	results, err := Parsers[format](file)
+	So(err, ShouldBeNil)
+	return results
+}
+
+func byName(results []Result) map[string]Result {
+	out := map[string]Result{}
+	for _, r := range results {
+		out[r.Name] = r
+	}
+	return out
+}
+
+func TestParseTAP(t *testing.T) {
+	Convey("With a TAP report", t, func() {
+		results := parseFile(t, TAP, "results.tap")
+		So(len(results), ShouldEqual, 8)
+		tests := byName(results)
+
+		Convey("tests should be decoded with their statuses", func() {
+			So(tests["parses the config"].Status, ShouldEqual, evergreen.TestSucceededStatus)
+			So(tests["connects to the database"].Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(tests["test 5"].Status, ShouldEqual, evergreen.TestSucceededStatus)
+		})
+
+		Convey("skipped and todo tests should be skipped", func() {
+			So(tests["caches results"].Status, ShouldEqual, evergreen.TestSkippedStatus)
+			So(tests["caches results"].Log[0], ShouldEqual, "TODO: not implemented")
+			So(tests["query/delete"].Status, ShouldEqual, evergreen.TestSkippedStatus)
+		})
+
+		Convey("subtests should be named after their parents", func() {
+			So(tests["query"].Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(tests["query/select"].Status, ShouldEqual, evergreen.TestSucceededStatus)
+			So(tests["query/insert # with hash"].Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(tests["query/insert # with hash"].Log, ShouldResemble, []string{"    # insert returned 0 rows"})
+		})
+
+		Convey("YAML diagnostics should be logged", func() {
+			So(tests["connects to the database"].Log, ShouldContain, "  message: connection refused")
+		})
+	})
+
+	Convey("With a TAP report that bails out", t, func() {
+		results := parseFile(t, TAP, "bail_out.tap")
+		So(len(results), ShouldEqual, 2)
+		So(results[1].Name, ShouldEqual, "tap_bail_out")
+		So(results[1].Status, ShouldEqual, evergreen.TestFailedStatus)
+	})
+
+	Convey("With a TAP report missing planned tests", t, func() {
+		results, err := ParseTAP(strings.NewReader("1..3\nok 1 - first\n"))
+		So(err, ShouldBeNil)
+		So(len(results), ShouldEqual, 2)
+		So(results[1].Name, ShouldEqual, "tap_plan")
+		So(results[1].Status, ShouldEqual, evergreen.TestFailedStatus)
+	})
+}
+
+func TestParseJUnit5(t *testing.T) {
+	Convey("With a JUnit 5 report", t, func() {
+		results := parseFile(t, JUnit5, "junit5.xml")
+		So(len(results), ShouldEqual, 5)
+		tests := byName(results)
+
+		Convey("tests should be named from their unique ids", func() {
+			So(tests["com.example.ConfigTest.parsesConfig()"].Status, ShouldEqual, evergreen.TestSucceededStatus)
+			So(tests["com.example.ConfigTest.parsesConfig()"].Duration, ShouldEqual, 12*time.Millisecond)
+			So(tests["com.example.ConfigTest.parsesConfig()"].Log, ShouldResemble, []string{"STDOUT:", "loaded 3 keys"})
+		})
+
+		Convey("parameterized and nested tests should be named after their containers", func() {
+			So(tests["com.example.CalculatorTest.add(int, int)/#1"].Status, ShouldEqual, evergreen.TestSucceededStatus)
+			So(tests["com.example.CalculatorTest.add(int, int)/#2"].Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(tests["com.example.CalculatorTest.Division.divides()"].Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(tests["com.example.CalculatorTest.Division.divides()"].Log, ShouldContain, "warning: dividing by zero")
+		})
+
+		Convey("tests without unique ids should fall back to their class names", func() {
+			So(tests["com.example.SlowTest.slowTest()"].Status, ShouldEqual, evergreen.TestSkippedStatus)
+			So(tests["com.example.SlowTest.slowTest()"].Log[0], ShouldEqual, "SKIPPED: disabled on CI")
+		})
+	})
+}
+
+func TestParseTest2JSON(t *testing.T) {
+	Convey("With a go test2json report", t, func() {
+		results := parseFile(t, Test2JSON, "test2json.json")
+		So(len(results), ShouldEqual, 6)
+		tests := byName(results)
+
+		Convey("tests and subtests should be decoded with their output", func() {
+			parent := tests["example.com/config.TestParse"]
+			So(parent.Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(parent.Duration, ShouldEqual, 10*time.Millisecond)
+			So(parent.Start.IsZero(), ShouldBeFalse)
+
+			sub := tests["example.com/config.TestParse/empty"]
+			So(sub.Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(sub.Log, ShouldContain, "    config_test.go:12: expected an error")
+
+			So(tests["example.com/config.TestParse/windows"].Status, ShouldEqual, evergreen.TestSkippedStatus)
+			So(tests["example.com/config.TestLoad"].Status, ShouldEqual, evergreen.TestSucceededStatus)
+		})
+
+		Convey("tests that never finished should fail", func() {
+			So(tests["example.com/server.TestServe"].Status, ShouldEqual, evergreen.TestFailedStatus)
+		})
+
+		Convey("packages that failed to build should fail", func() {
+			So(tests["example.com/broken"].Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(tests["example.com/broken"].Log[0], ShouldEqual, "broken.go:3:1: syntax error")
+		})
+	})
+}
+
+func TestParsePytest(t *testing.T) {
+	Convey("With a pytest report log", t, func() {
+		results := parseFile(t, Pytest, "pytest_report_log.jsonl")
+		So(len(results), ShouldEqual, 8)
+		tests := byName(results)
+
+		Convey("the phases of each test should be combined", func() {
+			add := tests["tests/test_math.py::test_add[1-2]"]
+			So(add.Status, ShouldEqual, evergreen.TestSucceededStatus)
+			So(add.Duration, ShouldEqual, 4*time.Millisecond)
+			So(add.Log, ShouldResemble, []string{"----- Captured stdout call -----", "adding 1 and 2"})
+
+			So(tests["tests/test_math.py::test_divide"].Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(tests["tests/test_math.py::test_divide"].Log, ShouldContain, "E       ZeroDivisionError: division by zero")
+			So(tests["tests/test_math.py::test_db"].Status, ShouldEqual, evergreen.TestFailedStatus)
+		})
+
+		Convey("skipped and expected failures should be skipped", func() {
+			So(tests["tests/test_math.py::test_sqrt"].Status, ShouldEqual, evergreen.TestSkippedStatus)
+			So(tests["tests/test_math.py::test_round"].Status, ShouldEqual, evergreen.TestSkippedStatus)
+		})
+
+		Convey("subtests and collection errors should be reported", func() {
+			So(tests["tests/test_math.py::test_parity/[i=3]"].Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(tests["tests/test_broken.py"].Status, ShouldEqual, evergreen.TestFailedStatus)
+		})
+	})
+
+	Convey("With a pytest-json-report report", t, func() {
+		results := parseFile(t, Pytest, "pytest_json_report.json")
+		So(len(results), ShouldEqual, 4)
+		tests := byName(results)
+
+		So(tests["tests/test_math.py::test_add"].Status, ShouldEqual, evergreen.TestSucceededStatus)
+		So(tests["tests/test_math.py::test_divide"].Status, ShouldEqual, evergreen.TestFailedStatus)
+		So(tests["tests/test_math.py::test_divide"].Log, ShouldContain, "dividing")
+		So(tests["tests/test_math.py::test_sqrt"].Status, ShouldEqual, evergreen.TestSkippedStatus)
+		So(tests["tests/test_broken.py"].Status, ShouldEqual, evergreen.TestFailedStatus)
+	})
+}
+
+func TestParseCucumber(t *testing.T) {
+	Convey("With a cucumber report", t, func() {
+		results := parseFile(t, Cucumber, "cucumber.json")
+		So(len(results), ShouldEqual, 5)
+		tests := byName(results)
+
+		Convey("scenarios should include their background steps", func() {
+			card := tests["Checkout/Pay by card"]
+			So(card.Status, ShouldEqual, evergreen.TestSucceededStatus)
+			So(card.Duration, ShouldEqual, 6500*time.Microsecond)
+			So(card.Log, ShouldContain, "Given a logged in customer ... passed (1ms)")
+		})
+
+		Convey("scenario outline examples should be reported separately", func() {
+			So(tests["Checkout/Pay by voucher"].Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(tests["Checkout/Pay by voucher"].Log, ShouldContain, "    expected the voucher to be accepted")
+			So(tests["Checkout/Pay by voucher (line 16)"].Status, ShouldEqual, evergreen.TestSucceededStatus)
+		})
+
+		Convey("pending scenarios should be skipped and undefined ones failed", func() {
+			So(tests["Refund/Refund an order"].Status, ShouldEqual, evergreen.TestSkippedStatus)
+			So(tests["Refund/Refund twice"].Status, ShouldEqual, evergreen.TestFailedStatus)
+		})
+	})
+}
+
+func TestToModelTestResultAndLog(t *testing.T) {
+	Convey("With a parsed result", t, func() {
+		start := time.Unix(1496318400, 0)
+		tsk := &task.Task{Id: "t1", Execution: 2}
+
+		Convey("a result with output should have a log", func() {
+			res, log := Result{
+				Name:     "pkg/TestA",
+				Status:   evergreen.TestFailedStatus,
+				Start:    start,
+				Duration: 2 * time.Second,
+				Log:      []string{"boom"},
+			}.ToModelTestResultAndLog(tsk)
+			So(res.TestFile, ShouldEqual, "pkg_TestA")
+			So(res.Status, ShouldEqual, evergreen.TestFailedStatus)
+			So(res.StartTime, ShouldEqual, 1496318400)
+			So(res.EndTime, ShouldEqual, 1496318402)
+			So(log, ShouldNotBeNil)
+			So(log.Task, ShouldEqual, "t1")
+			So(log.TaskExecution, ShouldEqual, 2)
+			So(res.URL, ShouldEqual, log.URL())
+		})
+
+		Convey("a result without output should not", func() {
+			res, log := Result{Name: "TestB", Status: evergreen.TestSucceededStatus}.ToModelTestResultAndLog(tsk)
+			So(log, ShouldBeNil)
+			So(res.URL, ShouldEqual, "")
+		})
+	})
+}
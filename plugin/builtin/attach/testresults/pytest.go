@@ -0,0 +1,357 @@
+package testresults
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/pkg/errors"
+)
+
+// pytestReport is a line of the log written by pytest's --report-log option.
+type pytestReport struct {
+	ReportType string            `json:"$report_type"`
+	NodeId     string            `json:"nodeid"`
+	When       string            `json:"when"`
+	Outcome    string            `json:"outcome"`
+	Duration   float64           `json:"duration"`
+	Start      float64           `json:"start"`
+	LongRepr   json.RawMessage   `json:"longrepr"`
+	Sections   [][]string        `json:"sections"`
+	WasXFail   *string           `json:"wasxfail"`
+	Context    *pytestSubContext `json:"context"`
+}
+
+// pytestSubContext identifies a subtest reported by the pytest-subtests plugin.
+type pytestSubContext struct {
+	Msg    *string                `json:"msg"`
+	Kwargs map[string]interface{} `json:"kwargs"`
+}
+
+// pytestJSONReport is the report written by the pytest-json-report plugin.
+type pytestJSONReport struct {
+	Tests      []pytestJSONTest `json:"tests"`
+	Collectors []struct {
+		NodeId   string          `json:"nodeid"`
+		Outcome  string          `json:"outcome"`
+		LongRepr json.RawMessage `json:"longrepr"`
+	} `json:"collectors"`
+}
+
+type pytestJSONTest struct {
+	NodeId   string           `json:"nodeid"`
+	Outcome  string           `json:"outcome"`
+	Setup    *pytestJSONPhase `json:"setup"`
+	Call     *pytestJSONPhase `json:"call"`
+	Teardown *pytestJSONPhase `json:"teardown"`
+}
+
+type pytestJSONPhase struct {
+	Duration float64         `json:"duration"`
+	Outcome  string          `json:"outcome"`
+	LongRepr json.RawMessage `json:"longrepr"`
+	Stdout   string          `json:"stdout"`
+	Stderr   string          `json:"stderr"`
+	Log      []struct {
+		Msg string `json:"msg"`
+	} `json:"log"`
+}
+
+// pytestTest accumulates the reports of the phases of one test.
+type pytestTest struct {
+	result   Result
+	statuses []string
+	sections map[string]bool
+}
+
+// ParsePytest parses either the log written by pytest's --report-log option
+// or the report written by the pytest-json-report plugin. Each test's setup,
+// call and teardown are combined into one result, so that an error in a
+// fixture fails the test. Parametrized tests are reported by their node ids,
+// and the subtests of the pytest-subtests plugin as subtests of their test.
+func ParsePytest(reader io.Reader) ([]Result, error) {
+	decoder := json.NewDecoder(reader)
+	first := map[string]json.RawMessage{}
+	if err := decoder.Decode(&first); err == io.EOF {
+		return []Result{}, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "error parsing pytest report")
+	}
+	if _, ok := first["$report_type"]; !ok {
+		if _, ok = first["tests"]; ok {
+			return parsePytestJSONReport(first)
+		}
+	}
+
+	tests := map[string]*pytestTest{}
+	order := []*pytestTest{}
+	add := func(raw []byte) error {
+		report := pytestReport{}
+		if err := json.Unmarshal(raw, &report); err != nil {
+			return errors.Wrap(err, "error parsing pytest report log")
+		}
+		name, ok := report.name()
+		if !ok {
+			return nil
+		}
+		t, ok := tests[name]
+		if !ok {
+			t = &pytestTest{result: Result{Name: name}, sections: map[string]bool{}}
+			if report.Start > 0 {
+				t.result.Start = time.Unix(0, int64(report.Start*float64(time.Second)))
+			}
+			tests[name] = t
+			order = append(order, t)
+		}
+		t.add(report)
+		return nil
+	}
+
+	raw, err := json.Marshal(first)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = add(raw); err != nil {
+		return nil, err
+	}
+	for {
+		var line json.RawMessage
+		if err = decoder.Decode(&line); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "error parsing pytest report log")
+		}
+		if err = add(line); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]Result, 0, len(order))
+	for _, t := range order {
+		t.result.Status = worstStatus(t.statuses...)
+		results = append(results, t.result)
+	}
+	return results, nil
+}
+
+// name returns the name of the test the report is about, and false if the
+// report isn't about a test.
+func (r pytestReport) name() (string, bool) {
+	switch r.ReportType {
+	case "TestReport":
+		return r.NodeId, true
+	case "SubTestReport":
+		if r.Context == nil {
+			return r.NodeId, true
+		}
+		parts := []string{}
+		if r.Context.Msg != nil {
+			parts = append(parts, *r.Context.Msg)
+		}
+		keys := make([]string, 0, len(r.Context.Kwargs))
+		for key := range r.Context.Kwargs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%s=%v", key, r.Context.Kwargs[key]))
+		}
+		return fmt.Sprintf("%s/[%s]", r.NodeId, strings.Join(parts, " ")), true
+	case "CollectReport":
+		// only failed collections, e.g. import errors, are reported
+		return r.NodeId, r.Outcome == "failed"
+	}
+	return "", false
+}
+
+func (t *pytestTest) add(r pytestReport) {
+	t.result.Duration += time.Duration(r.Duration * float64(time.Second))
+
+	status := pytestStatus(r.Outcome)
+	if r.WasXFail != nil {
+		// an expected failure isn't a failure, and an unexpected pass is
+		// only reported by strict mode, as a failure
+		status = evergreen.TestSkippedStatus
+		if r.Outcome == "failed" {
+			status = evergreen.TestFailedStatus
+		}
+	}
+	t.statuses = append(t.statuses, status)
+
+	if status != evergreen.TestSucceededStatus {
+		if lines := pytestLongRepr(r.LongRepr); len(lines) > 0 {
+			t.result.Log = append(t.result.Log, strings.ToUpper(phaseName(r))+":")
+			t.result.Log = append(t.result.Log, lines...)
+		}
+	}
+	// the captured output is repeated by each phase's report
+	for _, section := range r.Sections {
+		if len(section) != 2 || t.sections[section[0]] {
+			continue
+		}
+		t.sections[section[0]] = true
+		t.result.Log = append(t.result.Log, fmt.Sprintf("----- %s -----", section[0]))
+		t.result.Log = append(t.result.Log, splitLines(section[1])...)
+	}
+}
+
+func phaseName(r pytestReport) string {
+	if r.When == "" {
+		return "collect"
+	}
+	return r.When
+}
+
+func parsePytestJSONReport(raw map[string]json.RawMessage) ([]Result, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	report := pytestJSONReport{}
+	if err = json.Unmarshal(data, &report); err != nil {
+		return nil, errors.Wrap(err, "error parsing pytest json report")
+	}
+
+	results := []Result{}
+	for _, c := range report.Collectors {
+		if c.Outcome != "failed" {
+			continue
+		}
+		results = append(results, Result{
+			Name:   c.NodeId,
+			Status: evergreen.TestFailedStatus,
+			Log:    pytestLongRepr(c.LongRepr),
+		})
+	}
+	for _, t := range report.Tests {
+		res := Result{Name: t.NodeId, Status: pytestStatus(t.Outcome)}
+		for _, phase := range []struct {
+			name string
+			*pytestJSONPhase
+		}{{"setup", t.Setup}, {"call", t.Call}, {"teardown", t.Teardown}} {
+			if phase.pytestJSONPhase == nil {
+				continue
+			}
+			res.Duration += time.Duration(phase.Duration * float64(time.Second))
+			if lines := pytestLongRepr(phase.LongRepr); len(lines) > 0 && phase.Outcome != "passed" {
+				res.Log = append(res.Log, strings.ToUpper(phase.name)+":")
+				res.Log = append(res.Log, lines...)
+			}
+			if phase.Stdout != "" {
+				res.Log = append(res.Log, fmt.Sprintf("----- Captured stdout %s -----", phase.name))
+				res.Log = append(res.Log, splitLines(phase.Stdout)...)
+			}
+			if phase.Stderr != "" {
+				res.Log = append(res.Log, fmt.Sprintf("----- Captured stderr %s -----", phase.name))
+				res.Log = append(res.Log, splitLines(phase.Stderr)...)
+			}
+			if len(phase.Log) > 0 {
+				res.Log = append(res.Log, fmt.Sprintf("----- Captured log %s -----", phase.name))
+				for _, record := range phase.Log {
+					res.Log = append(res.Log, record.Msg)
+				}
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// pytestStatus converts a pytest outcome to a test status.
+func pytestStatus(outcome string) string {
+	switch outcome {
+	case "passed", "xpassed":
+		return evergreen.TestSucceededStatus
+	case "skipped", "xfailed":
+		return evergreen.TestSkippedStatus
+	default:
+		return evergreen.TestFailedStatus
+	}
+}
+
+// pytestLongRepr returns the lines of a serialized failure representation,
+// which pytest writes as a string, as a [path, line, reason] list for skips,
+// or as an object holding the traceback.
+func pytestLongRepr(raw json.RawMessage) []string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return splitLines(text)
+	}
+
+	var list []interface{}
+	if err := json.Unmarshal(raw, &list); err == nil {
+		if len(list) == 3 {
+			return []string{fmt.Sprintf("%v:%v: %v", list[0], list[1], list[2])}
+		}
+		return []string{fmt.Sprint(list...)}
+	}
+
+	repr := pytestExceptionRepr{}
+	if err := json.Unmarshal(raw, &repr); err != nil {
+		return []string{string(raw)}
+	}
+	lines := []string{}
+	if len(repr.Chain) > 0 {
+		for _, link := range repr.Chain {
+			if len(link) == 0 {
+				continue
+			}
+			traceback := pytestTraceback{}
+			if json.Unmarshal(link[0], &traceback) == nil {
+				lines = append(lines, traceback.lines()...)
+			}
+			if len(link) > 2 {
+				var description string
+				if json.Unmarshal(link[2], &description) == nil && description != "" {
+					lines = append(lines, "", description, "")
+				}
+			}
+		}
+	} else {
+		lines = append(lines, repr.ReprTraceback.lines()...)
+	}
+	if len(lines) == 0 && repr.ReprCrash != nil {
+		lines = append(lines, fmt.Sprintf("%s:%d: %s", repr.ReprCrash.Path, repr.ReprCrash.Lineno, repr.ReprCrash.Message))
+	}
+	return lines
+}
+
+type pytestExceptionRepr struct {
+	ReprCrash     *pytestFileLoc      `json:"reprcrash"`
+	ReprTraceback pytestTraceback     `json:"reprtraceback"`
+	Chain         [][]json.RawMessage `json:"chain"`
+}
+
+type pytestTraceback struct {
+	ReprEntries []struct {
+		Data struct {
+			Lines       []string       `json:"lines"`
+			ReprFileLoc *pytestFileLoc `json:"reprfileloc"`
+		} `json:"data"`
+	} `json:"reprentries"`
+}
+
+type pytestFileLoc struct {
+	Path    string `json:"path"`
+	Lineno  int    `json:"lineno"`
+	Message string `json:"message"`
+}
+
+func (t pytestTraceback) lines() []string {
+	lines := []string{}
+	for _, entry := range t.ReprEntries {
+		lines = append(lines, entry.Data.Lines...)
+		if loc := entry.Data.ReprFileLoc; loc != nil {
+			lines = append(lines, fmt.Sprintf("%s:%d: %s", loc.Path, loc.Lineno, loc.Message))
+		}
+	}
+	return lines
+}
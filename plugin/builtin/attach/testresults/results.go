@@ -0,0 +1,110 @@
+// Package testresults parses the reports of common test frameworks into test
+// results and logs.
+package testresults
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/evergreen-ci/evergreen/util"
+)
+
+const (
+	TAP       = "tap"
+	JUnit5    = "junit5"
+	Test2JSON = "test2json"
+	Pytest    = "pytest"
+	Cucumber  = "cucumber"
+)
+
+// Result is a single test parsed from a report. Subtests are results of their
+// own, named after their parents.
+type Result struct {
+	Name   string
+	Status string
+	// Start is zero if the report doesn't record when the test started.
+	Start    time.Time
+	Duration time.Duration
+	// Log holds the test's output and failure details, if any.
+	Log []string
+}
+
+// Parser reads a report and returns the tests in it.
+type Parser func(io.Reader) ([]Result, error)
+
+// Parsers holds the parser for each supported format.
+var Parsers = map[string]Parser{
+	TAP:       ParseTAP,
+	JUnit5:    ParseJUnit5,
+	Test2JSON: ParseTest2JSON,
+	Pytest:    ParsePytest,
+	Cucumber:  ParseCucumber,
+}
+
+// Formats returns the names of the supported formats.
+func Formats() []string {
+	formats := make([]string, 0, len(Parsers))
+	for format := range Parsers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// ToModelTestResultAndLog converts the result into a task.TestResult and a
+// model.TestLog. The log is nil if the test has no output.
+func (r Result) ToModelTestResultAndLog(t *task.Task) (task.TestResult, *model.TestLog) {
+	res := task.TestResult{
+		TestFile: util.CleanForPath(r.Name),
+		Status:   r.Status,
+	}
+
+	start := r.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	res.StartTime = float64(start.UnixNano()) / float64(time.Second)
+	res.EndTime = res.StartTime + r.Duration.Seconds()
+
+	if len(r.Log) == 0 {
+		return res, nil
+	}
+	log := &model.TestLog{
+		Name:          res.TestFile,
+		Task:          t.Id,
+		TaskExecution: t.Execution,
+		Lines:         r.Log,
+	}
+	res.URL = log.URL()
+	return res, log
+}
+
+// splitLines splits s into lines, without a trailing empty line.
+func splitLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// worstStatus returns the status that should be reported for a test made up
+// of parts with the given statuses: failed if any part failed, otherwise
+// skipped if any part was skipped.
+func worstStatus(statuses ...string) string {
+	status := evergreen.TestSucceededStatus
+	for _, s := range statuses {
+		switch s {
+		case evergreen.TestFailedStatus:
+			return evergreen.TestFailedStatus
+		case evergreen.TestSkippedStatus:
+			status = evergreen.TestSkippedStatus
+		}
+	}
+	return status
+}
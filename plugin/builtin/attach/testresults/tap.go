@@ -0,0 +1,196 @@
+package testresults
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/pkg/errors"
+)
+
+// tapIndent is the indentation of each level of TAP 14 subtests.
+const tapIndent = 4
+
+var (
+	tapTestLine = regexp.MustCompile(`^(not )?ok\b(?:\s+(\d+))?(?:\s*-)?\s*(.*)$`)
+	tapPlanLine = regexp.MustCompile(`^1\.\.(\d+)`)
+	tapSubtest  = regexp.MustCompile(`^#\s*Subtest:\s*(.*)$`)
+)
+
+// tapParser holds the state of a TAP stream being parsed.
+type tapParser struct {
+	results []Result
+	// subtests holds the names of the subtests being run, outermost first.
+	subtests []string
+	// pendingSubtest is the name from the last "# Subtest:" comment, which
+	// names the subtest whose indented lines follow it. Producers differ on
+	// whether the comment is indented like the subtest or like its parent.
+	pendingSubtest string
+	// current is the index of the result the following diagnostics belong
+	// to, or -1.
+	current int
+	// inYAML is true inside a YAML diagnostics block.
+	inYAML bool
+	// planned and ran count the top level tests.
+	planned int
+	ran     int
+}
+
+// ParseTAP parses a Test Anything Protocol stream, including TAP 14 subtests,
+// which are indented by four spaces and named by "# Subtest:" comments.
+// Diagnostics and YAML blocks become the log of the test they follow.
+func ParseTAP(reader io.Reader) ([]Result, error) {
+	p := &tapParser{current: -1, planned: -1}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		if bailed := p.parseLine(scanner.Text()); bailed {
+			return p.results, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "error reading TAP stream")
+	}
+
+	if p.planned > p.ran {
+		p.results = append(p.results, Result{
+			Name:   "tap_plan",
+			Status: evergreen.TestFailedStatus,
+			Log:    []string{fmt.Sprintf("planned %d tests but only %d ran", p.planned, p.ran)},
+		})
+	}
+	return p.results, nil
+}
+
+// parseLine parses one line of the stream, and returns true if the stream
+// bailed out.
+func (p *tapParser) parseLine(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	level := (len(line) - len(trimmed)) / tapIndent
+	trimmed = strings.TrimRight(trimmed, " \r")
+
+	if p.inYAML {
+		if trimmed == "..." {
+			p.inYAML = false
+		}
+		p.addLog(line)
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(trimmed, "Bail out!"):
+		p.results = append(p.results, Result{
+			Name:   "tap_bail_out",
+			Status: evergreen.TestFailedStatus,
+			Log:    []string{trimmed},
+		})
+		return true
+	case tapSubtest.MatchString(trimmed):
+		p.pendingSubtest = strings.TrimSpace(tapSubtest.FindStringSubmatch(trimmed)[1])
+	case tapTestLine.MatchString(trimmed):
+		p.enterSubtest(level)
+		p.addTest(level, tapTestLine.FindStringSubmatch(trimmed))
+	case tapPlanLine.MatchString(trimmed):
+		p.enterSubtest(level)
+		if level == 0 {
+			p.planned, _ = strconv.Atoi(tapPlanLine.FindStringSubmatch(trimmed)[1])
+		}
+	case trimmed == "---":
+		p.inYAML = true
+		p.addLog(line)
+	case strings.HasPrefix(trimmed, "#"), trimmed != "" && !strings.HasPrefix(trimmed, "TAP version"):
+		p.addLog(line)
+	}
+	return false
+}
+
+// enterSubtest names the subtest at the given level after the pending
+// "# Subtest:" comment, if there is one.
+func (p *tapParser) enterSubtest(level int) {
+	name := p.pendingSubtest
+	p.pendingSubtest = ""
+	if name == "" || level == 0 {
+		return
+	}
+	for len(p.subtests) < level-1 {
+		p.subtests = append(p.subtests, "")
+	}
+	p.subtests = append(p.subtests[:level-1], name)
+}
+
+func (p *tapParser) addTest(level int, match []string) {
+	failed := match[1] != ""
+	number, description := match[2], match[3]
+
+	directive, reason := "", ""
+	if idx := directiveIndex(description); idx >= 0 {
+		fields := strings.SplitN(strings.TrimSpace(description[idx+1:]), " ", 2)
+		switch strings.ToUpper(fields[0]) {
+		case "SKIP", "SKIPPED", "TODO":
+			directive = strings.ToUpper(fields[0])
+			if len(fields) > 1 {
+				reason = strings.TrimSpace(fields[1])
+			}
+			description = description[:idx]
+		}
+	}
+	description = strings.Replace(strings.TrimSpace(description), `\#`, "#", -1)
+	if description == "" {
+		description = "test " + number
+	}
+
+	status := evergreen.TestSucceededStatus
+	switch {
+	case directive != "":
+		// failing TODO tests are expected to fail, so they don't fail the run
+		status = evergreen.TestSkippedStatus
+	case failed:
+		status = evergreen.TestFailedStatus
+	}
+
+	if level > len(p.subtests) {
+		level = len(p.subtests)
+	}
+	names := []string{}
+	for _, name := range p.subtests[:level] {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	// the subtests nested in this test have all finished
+	p.subtests = p.subtests[:level]
+
+	result := Result{
+		Name:   strings.Join(append(names, description), "/"),
+		Status: status,
+	}
+	if directive != "" && reason != "" {
+		result.Log = []string{fmt.Sprintf("%s: %s", directive, reason)}
+	}
+	p.results = append(p.results, result)
+	p.current = len(p.results) - 1
+	if level == 0 {
+		p.ran++
+	}
+}
+
+func (p *tapParser) addLog(line string) {
+	if p.current >= 0 {
+		p.results[p.current].Log = append(p.results[p.current].Log, line)
+	}
+}
+
+// directiveIndex returns the index of the unescaped '#' starting a test
+// line's directive, or -1.
+func directiveIndex(description string) int {
+	for i := 0; i < len(description); i++ {
+		if description[i] == '#' && (i == 0 || description[i-1] != '\\') {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,116 @@
+package testresults
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/pkg/errors"
+)
+
+// test2jsonEvent is an event written by "go test -json" or "go tool test2json".
+type test2jsonEvent struct {
+	Time       time.Time
+	Action     string
+	Package    string
+	ImportPath string
+	Test       string
+	Elapsed    float64
+	Output     string
+}
+
+var test2jsonStatuses = map[string]string{
+	"pass": evergreen.TestSucceededStatus,
+	"fail": evergreen.TestFailedStatus,
+	"skip": evergreen.TestSkippedStatus,
+}
+
+// test2jsonTest accumulates the events of one test, or of a package when the
+// test is empty.
+type test2jsonTest struct {
+	pkg      string
+	test     string
+	status   string
+	start    time.Time
+	duration time.Duration
+	output   bytes.Buffer
+}
+
+// ParseTest2JSON parses the output of "go test -json". Subtests are named by
+// the go tool as their parent's name followed by a slash. A package that fails
+// without a failing test, e.g. because it didn't build or it panicked, is
+// reported as a failed test named after the package.
+func ParseTest2JSON(reader io.Reader) ([]Result, error) {
+	tests := map[string]*test2jsonTest{}
+	order := []*test2jsonTest{}
+	get := func(pkg, test string, ts time.Time) *test2jsonTest {
+		key := pkg + "\x00" + test
+		t, ok := tests[key]
+		if !ok {
+			t = &test2jsonTest{pkg: pkg, test: test, start: ts}
+			tests[key] = t
+			order = append(order, t)
+		}
+		return t
+	}
+
+	decoder := json.NewDecoder(reader)
+	for {
+		event := test2jsonEvent{}
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "error parsing go test output")
+		}
+
+		switch event.Action {
+		case "build-output":
+			get(event.ImportPath, "", event.Time).output.WriteString(event.Output)
+		case "build-fail":
+			get(event.ImportPath, "", event.Time).status = evergreen.TestFailedStatus
+		case "output":
+			get(event.Package, event.Test, event.Time).output.WriteString(event.Output)
+		case "run", "start":
+			get(event.Package, event.Test, event.Time)
+		case "pass", "fail", "skip":
+			t := get(event.Package, event.Test, event.Time)
+			t.duration = time.Duration(event.Elapsed * float64(time.Second))
+			t.status = test2jsonStatuses[event.Action]
+		}
+	}
+
+	failedTests := map[string]bool{}
+	results := []Result{}
+	for _, t := range order {
+		if t.test == "" {
+			continue
+		}
+		res := t.result(t.pkg + "." + t.test)
+		if t.status == "" {
+			// the test never finished, e.g. because the package panicked
+			res.Status = evergreen.TestFailedStatus
+		}
+		if res.Status == evergreen.TestFailedStatus {
+			failedTests[t.pkg] = true
+		}
+		results = append(results, res)
+	}
+	for _, t := range order {
+		if t.test == "" && t.status == evergreen.TestFailedStatus && !failedTests[t.pkg] {
+			results = append(results, t.result(t.pkg))
+		}
+	}
+	return results, nil
+}
+
+func (t *test2jsonTest) result(name string) Result {
+	return Result{
+		Name:     name,
+		Status:   t.status,
+		Start:    t.start,
+		Duration: t.duration,
+		Log:      splitLines(t.output.String()),
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/mongodb/grip/send"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	plugin.Publish(&CachePlugin{})
+}
+
+const (
+	CachePluginName = "cache"
+	SaveCmdName     = "save"
+	RestoreCmdName  = "restore"
+
+	// CacheDirName is the directory, under the distro's work directory, that
+	// holds the host's cache.
+	CacheDirName = "task_cache"
+
+	// DefaultMaxSizeMB is the size the host's cache is kept under if a
+	// cache.save command does not specify one.
+	DefaultMaxSizeMB = 10 * 1024
+)
+
+// CachePlugin has commands for keeping the outputs of tasks in a cache on the
+// host, optionally backed by a remote store, so that later tasks with the
+// same inputs can restore them instead of downloading or rebuilding them.
+type CachePlugin struct{}
+
+// Name returns the name of the plugin. Fulfills the Plugin interface.
+func (self *CachePlugin) Name() string {
+	return CachePluginName
+}
+
+// NewCommand takes a command name as a string and returns the requested command,
+// or an error if the command does not exist. Fulfills the Plugin interface.
+func (self *CachePlugin) NewCommand(cmdName string) (plugin.Command, error) {
+	switch cmdName {
+	case SaveCmdName:
+		return &SaveCommand{}, nil
+	case RestoreCmdName:
+		return &RestoreCommand{}, nil
+	default:
+		return nil, &plugin.ErrUnknownCommand{CommandName: cmdName}
+	}
+}
+
+// hostCache returns the host's cache, which lives in the distro's work
+// directory if it has one.
+func hostCache(conf *model.TaskConfig, maxBytes int64) (*LocalCache, error) {
+	dir := filepath.Join(os.TempDir(), CacheDirName)
+	if conf.Distro != nil && conf.Distro.WorkDir != "" {
+		dir = filepath.Join(conf.Distro.WorkDir, CacheDirName)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "error creating cache directory %v", dir)
+	}
+	return &LocalCache{Dir: dir, MaxBytes: maxBytes}, nil
+}
+
+// agentAppender passes the messages of archive.BuildArchive, which takes a
+// slogger.Logger, on to the plugin logger.
+type agentAppender struct {
+	pluginLogger plugin.Logger
+}
+
+// satisfy the slogger.Appender interface
+func (self *agentAppender) Append(log *slogger.Log) error {
+	self.pluginLogger.LogExecution(log.Level, slogger.FormatLog(log))
+	return nil
+}
+
+func newArchiveLogger(pluginLogger plugin.Logger) *slogger.Logger {
+	return &slogger.Logger{
+		Name:      "",
+		Appenders: []send.Sender{slogger.WrapAppender(&agentAppender{pluginLogger: pluginLogger})},
+	}
+}
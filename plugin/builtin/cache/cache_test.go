@@ -0,0 +1,163 @@
+package cache_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	. "github.com/evergreen-ci/evergreen/plugin/builtin/cache"
+	"github.com/evergreen-ci/evergreen/plugin/plugintest"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCacheParseParams(t *testing.T) {
+	Convey("With cache commands", t, func() {
+		Convey("cache.save should require a key, source_dir and include", func() {
+			So((&SaveCommand{}).ParseParams(map[string]interface{}{
+				"source_dir": "s",
+				"include":    []string{"i"},
+			}), ShouldNotBeNil)
+			So((&SaveCommand{}).ParseParams(map[string]interface{}{
+				"key":     "k",
+				"include": []string{"i"},
+			}), ShouldNotBeNil)
+			So((&SaveCommand{}).ParseParams(map[string]interface{}{
+				"key":        "k",
+				"source_dir": "s",
+			}), ShouldNotBeNil)
+
+			cmd := &SaveCommand{}
+			So(cmd.ParseParams(map[string]interface{}{
+				"key":        "k",
+				"source_dir": "s",
+				"include":    []string{"i"},
+			}), ShouldBeNil)
+			So(cmd.MaxSizeMB, ShouldEqual, DefaultMaxSizeMB)
+		})
+
+		Convey("cache.restore should require a key and dest_dir", func() {
+			So((&RestoreCommand{}).ParseParams(map[string]interface{}{
+				"dest_dir": "d",
+			}), ShouldNotBeNil)
+			So((&RestoreCommand{}).ParseParams(map[string]interface{}{
+				"key": "k",
+			}), ShouldNotBeNil)
+		})
+
+		Convey("remotes should be validated", func() {
+			params := map[string]interface{}{
+				"key":      "k",
+				"dest_dir": "d",
+				"remote":   map[string]interface{}{"type": "ftp"},
+			}
+			So((&RestoreCommand{}).ParseParams(params), ShouldNotBeNil)
+
+			params["remote"] = map[string]interface{}{"type": "s3", "bucket": "b"}
+			So((&RestoreCommand{}).ParseParams(params), ShouldNotBeNil)
+
+			params["remote"] = map[string]interface{}{"type": "local"}
+			So((&RestoreCommand{}).ParseParams(params), ShouldNotBeNil)
+
+			cmd := &RestoreCommand{}
+			params["remote"] = map[string]interface{}{"type": "local", "path": "/mnt/cache"}
+			So(cmd.ParseParams(params), ShouldBeNil)
+			So(cmd.Remote.Path, ShouldEqual, "/mnt/cache")
+		})
+	})
+}
+
+// hostConfig returns the config of a task on a host with the given work
+// directory.
+func hostConfig(t *testing.T, workDir string) *model.TaskConfig {
+	distroDir, err := ioutil.TempDir("", "cache_distro")
+	testutil.HandleTestingErr(err, t, "Error creating temp dir")
+	return &model.TaskConfig{
+		Distro:     &distro.Distro{WorkDir: distroDir},
+		Expansions: command.NewExpansions(map[string]string{"lock_hash": "abc123"}),
+		WorkDir:    workDir,
+	}
+}
+
+func TestCacheSaveAndRestore(t *testing.T) {
+	Convey("With a file to cache and a local remote", t, func() {
+		workDir, err := ioutil.TempDir("", "cache_work")
+		testutil.HandleTestingErr(err, t, "Error creating temp dir")
+		defer os.RemoveAll(workDir)
+		remoteDir, err := ioutil.TempDir("", "cache_remote")
+		testutil.HandleTestingErr(err, t, "Error creating temp dir")
+		defer os.RemoveAll(remoteDir)
+
+		So(os.MkdirAll(filepath.Join(workDir, "build", "lib"), 0755), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(workDir, "build", "lib", "out.a"), []byte("compiled"), 0644), ShouldBeNil)
+
+		remote := map[string]interface{}{"type": "local", "path": remoteDir}
+		save := &SaveCommand{}
+		So(save.ParseParams(map[string]interface{}{
+			"key":        "build-${lock_hash}",
+			"source_dir": "build",
+			"include":    []string{"**"},
+			"remote":     remote,
+		}), ShouldBeNil)
+
+		conf := hostConfig(t, workDir)
+		defer os.RemoveAll(conf.Distro.WorkDir)
+		So(save.Execute(&plugintest.MockLogger{}, nil, conf, make(chan bool)), ShouldBeNil)
+
+		Convey("the archive should be in the host's cache and the remote", func() {
+			cached := filepath.Join(conf.Distro.WorkDir, CacheDirName, EntryName("build-abc123"))
+			_, err = os.Stat(cached)
+			So(err, ShouldBeNil)
+			_, err = os.Stat(filepath.Join(remoteDir, EntryName("build-abc123")))
+			So(err, ShouldBeNil)
+		})
+
+		Convey("restoring on the same host should extract the archive", func() {
+			restore := &RestoreCommand{}
+			So(restore.ParseParams(map[string]interface{}{
+				"key":      "build-${lock_hash}",
+				"dest_dir": "restored",
+			}), ShouldBeNil)
+			So(restore.Execute(&plugintest.MockLogger{}, nil, conf, make(chan bool)), ShouldBeNil)
+
+			data, err := ioutil.ReadFile(filepath.Join(workDir, "restored", "lib", "out.a"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "compiled")
+		})
+
+		Convey("restoring on another host should download the archive from the remote", func() {
+			otherConf := hostConfig(t, workDir)
+			defer os.RemoveAll(otherConf.Distro.WorkDir)
+
+			restore := &RestoreCommand{}
+			So(restore.ParseParams(map[string]interface{}{
+				"key":      "build-${lock_hash}",
+				"dest_dir": "downloaded",
+				"remote":   remote,
+			}), ShouldBeNil)
+			So(restore.Execute(&plugintest.MockLogger{}, nil, otherConf, make(chan bool)), ShouldBeNil)
+
+			data, err := ioutil.ReadFile(filepath.Join(workDir, "downloaded", "lib", "out.a"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "compiled")
+			_, err = os.Stat(filepath.Join(otherConf.Distro.WorkDir, CacheDirName, EntryName("build-abc123")))
+			So(err, ShouldBeNil)
+		})
+
+		Convey("restoring a key that isn't cached should do nothing", func() {
+			restore := &RestoreCommand{}
+			So(restore.ParseParams(map[string]interface{}{
+				"key":      "build-other",
+				"dest_dir": "missing",
+				"remote":   remote,
+			}), ShouldBeNil)
+			So(restore.Execute(&plugintest.MockLogger{}, nil, conf, make(chan bool)), ShouldBeNil)
+			_, err = os.Stat(filepath.Join(workDir, "missing"))
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+}
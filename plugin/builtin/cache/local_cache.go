@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+const (
+	entryExt   = ".tgz"
+	tempPrefix = "tmp_"
+
+	// staleTempAge is how old a temporary file must be before eviction
+	// assumes that the task writing it is gone.
+	staleTempAge = 24 * time.Hour
+)
+
+// EntryName returns the name of the archive stored under key, both in the
+// host's cache and in remote stores. Keys are hashed, since they are
+// arbitrary strings.
+func EntryName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + entryExt
+}
+
+// LocalCache is a directory of archives named after their keys. The least
+// recently used archives are evicted to keep it under MaxBytes. Archives are
+// written to temporary files and renamed into place, so readers never see a
+// partial archive.
+type LocalCache struct {
+	Dir      string
+	MaxBytes int64
+}
+
+// Get returns the path of the archive stored under key, and whether there is
+// one. A hit marks the archive as recently used.
+func (c *LocalCache) Get(key string) (string, bool) {
+	path := filepath.Join(c.Dir, EntryName(key))
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	now := time.Now()
+	grip.Warning(os.Chtimes(path, now, now))
+	return path, true
+}
+
+// TempFile creates a file in the cache directory that an archive can be
+// written to before it is added.
+func (c *LocalCache) TempFile() (*os.File, error) {
+	f, err := ioutil.TempFile(c.Dir, tempPrefix)
+	return f, errors.WithStack(err)
+}
+
+// Add moves the archive at tempPath into the cache under key, and returns the
+// archive's path.
+func (c *LocalCache) Add(key, tempPath string) (string, error) {
+	path := filepath.Join(c.Dir, EntryName(key))
+	if err := os.Rename(tempPath, path); err != nil {
+		return "", errors.Wrapf(err, "error adding %v to the cache", tempPath)
+	}
+	return path, nil
+}
+
+type entriesByAge []os.FileInfo
+
+func (e entriesByAge) Len() int           { return len(e) }
+func (e entriesByAge) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e entriesByAge) Less(i, j int) bool { return e[i].ModTime().Before(e[j].ModTime()) }
+
+// Evict removes the least recently used archives until the cache is no larger
+// than MaxBytes, along with temporary files abandoned by earlier tasks. The
+// archive at keep is never removed.
+func (c *LocalCache) Evict(keep string) error {
+	infos, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	catcher := grip.NewCatcher()
+	entries := []os.FileInfo{}
+	var total int64
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(info.Name(), tempPrefix) {
+			if time.Since(info.ModTime()) > staleTempAge {
+				catcher.Add(os.Remove(filepath.Join(c.Dir, info.Name())))
+			}
+			continue
+		}
+		if filepath.Ext(info.Name()) != entryExt {
+			continue
+		}
+		entries = append(entries, info)
+		total += info.Size()
+	}
+
+	sort.Sort(entriesByAge(entries))
+	for _, info := range entries {
+		if total <= c.MaxBytes {
+			break
+		}
+		path := filepath.Join(c.Dir, info.Name())
+		if path == keep {
+			continue
+		}
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			catcher.Add(err)
+			continue
+		}
+		total -= info.Size()
+	}
+	return catcher.Resolve()
+}
@@ -0,0 +1,85 @@
+package cache_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/evergreen-ci/evergreen/plugin/builtin/cache"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// addEntry adds an archive of the given size to the cache, last used at the
+// given time.
+func addEntry(t *testing.T, cache *LocalCache, key string, size int, used time.Time) string {
+	f, err := cache.TempFile()
+	testutil.HandleTestingErr(err, t, "Error creating temp file")
+	_, err = f.Write(make([]byte, size))
+	testutil.HandleTestingErr(err, t, "Error writing temp file")
+	testutil.HandleTestingErr(f.Close(), t, "Error closing temp file")
+	path, err := cache.Add(key, f.Name())
+	testutil.HandleTestingErr(err, t, "Error adding entry")
+	testutil.HandleTestingErr(os.Chtimes(path, used, used), t, "Error setting entry times")
+	return path
+}
+
+func TestLocalCache(t *testing.T) {
+	Convey("With a local cache", t, func() {
+		dir, err := ioutil.TempDir("", "cache_test")
+		testutil.HandleTestingErr(err, t, "Error creating temp dir")
+		defer os.RemoveAll(dir)
+		cache := &LocalCache{Dir: dir, MaxBytes: 250}
+
+		Convey("entries should be found under their keys", func() {
+			added := addEntry(t, cache, "deps-abc", 10, time.Now())
+			path, ok := cache.Get("deps-abc")
+			So(ok, ShouldBeTrue)
+			So(path, ShouldEqual, added)
+			So(filepath.Base(path), ShouldEqual, EntryName("deps-abc"))
+
+			_, ok = cache.Get("deps-def")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("eviction should remove the least recently used entries", func() {
+			now := time.Now()
+			oldest := addEntry(t, cache, "a", 100, now.Add(-3*time.Hour))
+			used := addEntry(t, cache, "b", 100, now.Add(-2*time.Hour))
+			newest := addEntry(t, cache, "c", 100, now.Add(-time.Hour))
+
+			// using b makes a and c older than it
+			_, ok := cache.Get("b")
+			So(ok, ShouldBeTrue)
+
+			So(cache.Evict(newest), ShouldBeNil)
+			_, err = os.Stat(oldest)
+			So(os.IsNotExist(err), ShouldBeTrue)
+			_, err = os.Stat(used)
+			So(err, ShouldBeNil)
+			_, err = os.Stat(newest)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("eviction should keep the given entry even if it alone is too large", func() {
+			big := addEntry(t, cache, "big", 500, time.Now().Add(-time.Hour))
+			So(cache.Evict(big), ShouldBeNil)
+			_, ok := cache.Get("big")
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("eviction should remove abandoned temp files", func() {
+			f, err := cache.TempFile()
+			So(err, ShouldBeNil)
+			So(f.Close(), ShouldBeNil)
+			old := time.Now().Add(-48 * time.Hour)
+			So(os.Chtimes(f.Name(), old, old), ShouldBeNil)
+
+			So(cache.Evict(""), ShouldBeNil)
+			_, err = os.Stat(f.Name())
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/evergreen-ci/evergreen/archive"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+// RestoreCommand extracts the archive saved under a key, looking in the
+// host's cache first and then in the remote store if there is one. A key that
+// isn't cached anywhere is not an error.
+type RestoreCommand struct {
+	// Key identifies the archive, as in cache.save.
+	Key string `mapstructure:"key" plugin:"expand"`
+
+	// DestDir is the directory the archive is extracted into.
+	DestDir string `mapstructure:"dest_dir" plugin:"expand"`
+
+	// MaxSizeMB is the size that the host's cache is kept under when an
+	// archive is downloaded into it.
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+
+	Remote RemoteConfig `mapstructure:"remote" plugin:"expand"`
+}
+
+func (self *RestoreCommand) Name() string {
+	return RestoreCmdName
+}
+
+func (self *RestoreCommand) Plugin() string {
+	return CachePluginName
+}
+
+// ParseParams reads in the given parameters for the command.
+func (self *RestoreCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error parsing '%v' params", self.Name())
+	}
+	if err := self.validateParams(); err != nil {
+		return errors.Wrapf(err, "error validating '%v' params", self.Name())
+	}
+	return nil
+}
+
+func (self *RestoreCommand) validateParams() error {
+	if self.Key == "" {
+		return errors.New("key cannot be blank")
+	}
+	if self.DestDir == "" {
+		return errors.New("dest_dir cannot be blank")
+	}
+	if self.MaxSizeMB < 0 {
+		return errors.New("max_size_mb cannot be negative")
+	}
+	if self.MaxSizeMB == 0 {
+		self.MaxSizeMB = DefaultMaxSizeMB
+	}
+	return errors.Wrap(self.Remote.validate(), "invalid remote")
+}
+
+// Execute restores the files from the cache.
+func (self *RestoreCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator,
+	conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.Wrap(err, "error expanding params")
+	}
+	if self.Key == "" {
+		return errors.New("key expanded to a blank string")
+	}
+
+	// if the dest dir is a relative path, join it to the working dir
+	if !filepath.IsAbs(self.DestDir) {
+		self.DestDir = filepath.Join(conf.WorkDir, self.DestDir)
+	}
+
+	errChan := make(chan error)
+	go func() {
+		errChan <- self.Restore(conf, pluginLogger)
+	}()
+
+	select {
+	case err := <-errChan:
+		return errors.WithStack(err)
+	case <-stop:
+		pluginLogger.LogExecution(slogger.INFO, "Received signal to terminate"+
+			" execution of cache restore command")
+		return nil
+	}
+}
+
+// Restore extracts the archive saved under the key, downloading it into the
+// host's cache if only the remote store has it.
+func (self *RestoreCommand) Restore(conf *model.TaskConfig, pluginLogger plugin.Logger) error {
+	cache, err := hostCache(conf, int64(self.MaxSizeMB)*1024*1024)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	path, ok := cache.Get(self.Key)
+	if ok {
+		pluginLogger.LogTask(slogger.INFO, "Found key '%v' in the host's cache", self.Key)
+	} else if store := self.Remote.NewStore(); store != nil {
+		path, ok = self.download(cache, store, pluginLogger)
+	}
+	if !ok {
+		pluginLogger.LogTask(slogger.INFO, "Key '%v' is not cached", self.Key)
+		return nil
+	}
+
+	f, _, tarReader, err := archive.TarGzReader(path)
+	if err != nil {
+		return errors.Wrapf(err, "error opening cached archive %v", path)
+	}
+	defer f.Close()
+
+	if err = os.MkdirAll(self.DestDir, 0755); err != nil {
+		return errors.Wrapf(err, "error creating destination dir %v", self.DestDir)
+	}
+	if err = archive.Extract(tarReader, self.DestDir); err != nil {
+		return errors.Wrapf(err, "error extracting key '%v'", self.Key)
+	}
+	pluginLogger.LogTask(slogger.INFO, "Restored key '%v' to %v", self.Key, self.DestDir)
+	return nil
+}
+
+// download adds the archive saved under the key in the store to the host's
+// cache, and returns its path. Failing to download is treated as a miss.
+func (self *RestoreCommand) download(cache *LocalCache, store Store, pluginLogger plugin.Logger) (string, bool) {
+	tempFile, err := cache.TempFile()
+	if err != nil {
+		pluginLogger.LogExecution(slogger.WARN, "Error creating file to download key '%v' into: %v", self.Key, err)
+		return "", false
+	}
+	tempPath := tempFile.Name()
+	grip.CatchError(tempFile.Close())
+
+	found, err := store.Get(self.Key, tempPath)
+	if err != nil || !found {
+		grip.CatchError(os.Remove(tempPath))
+		if err != nil {
+			pluginLogger.LogTask(slogger.WARN, "Error downloading key '%v' from the remote: %v", self.Key, err)
+		}
+		return "", false
+	}
+
+	path, err := cache.Add(self.Key, tempPath)
+	if err != nil {
+		grip.CatchError(os.Remove(tempPath))
+		pluginLogger.LogExecution(slogger.WARN, "Error caching key '%v': %v", self.Key, err)
+		return "", false
+	}
+	pluginLogger.LogTask(slogger.INFO, "Downloaded key '%v' from the %v remote", self.Key, self.Remote.Type)
+	if err = cache.Evict(path); err != nil {
+		pluginLogger.LogExecution(slogger.WARN, "Error evicting old cache entries: %v", err)
+	}
+	return path, true
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/evergreen-ci/evergreen/archive"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+// SaveCommand archives files into the host's cache under a key, and uploads
+// the archive to the remote store if there is one. Since a key identifies the
+// files' inputs, nothing is saved if the key is already cached.
+type SaveCommand struct {
+	// Key identifies the archive, e.g. "deps-${lockfile_hash}".
+	Key string `mapstructure:"key" plugin:"expand"`
+
+	// SourceDir is the directory the included files are relative to.
+	SourceDir string `mapstructure:"source_dir" plugin:"expand"`
+
+	// Include and ExcludeFiles are filename blobs of the files to archive,
+	// as in archive.targz_pack.
+	Include      []string `mapstructure:"include" plugin:"expand"`
+	ExcludeFiles []string `mapstructure:"exclude_files" plugin:"expand"`
+
+	// MaxSizeMB is the size that the host's cache is kept under.
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+
+	Remote RemoteConfig `mapstructure:"remote" plugin:"expand"`
+}
+
+func (self *SaveCommand) Name() string {
+	return SaveCmdName
+}
+
+func (self *SaveCommand) Plugin() string {
+	return CachePluginName
+}
+
+// ParseParams reads in the given parameters for the command.
+func (self *SaveCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error parsing '%v' params", self.Name())
+	}
+	if err := self.validateParams(); err != nil {
+		return errors.Wrapf(err, "error validating '%v' params", self.Name())
+	}
+	return nil
+}
+
+func (self *SaveCommand) validateParams() error {
+	if self.Key == "" {
+		return errors.New("key cannot be blank")
+	}
+	if self.SourceDir == "" {
+		return errors.New("source_dir cannot be blank")
+	}
+	if len(self.Include) == 0 {
+		return errors.New("include cannot be empty")
+	}
+	if self.MaxSizeMB < 0 {
+		return errors.New("max_size_mb cannot be negative")
+	}
+	if self.MaxSizeMB == 0 {
+		self.MaxSizeMB = DefaultMaxSizeMB
+	}
+	return errors.Wrap(self.Remote.validate(), "invalid remote")
+}
+
+// Execute saves the files to the cache.
+func (self *SaveCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator,
+	conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.Wrap(err, "error expanding params")
+	}
+	if self.Key == "" {
+		return errors.New("key expanded to a blank string")
+	}
+
+	// if the source dir is a relative path, join it to the working dir
+	if !filepath.IsAbs(self.SourceDir) {
+		self.SourceDir = filepath.Join(conf.WorkDir, self.SourceDir)
+	}
+
+	errChan := make(chan error)
+	go func() {
+		errChan <- self.Save(conf, pluginLogger)
+	}()
+
+	select {
+	case err := <-errChan:
+		return errors.WithStack(err)
+	case <-stop:
+		pluginLogger.LogExecution(slogger.INFO, "Received signal to terminate"+
+			" execution of cache save command")
+		return nil
+	}
+}
+
+// Save archives the files into the host's cache and uploads the archive to
+// the remote store. Failing to upload is not an error, since the cache on the
+// host still has the archive.
+func (self *SaveCommand) Save(conf *model.TaskConfig, pluginLogger plugin.Logger) error {
+	cache, err := hostCache(conf, int64(self.MaxSizeMB)*1024*1024)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, ok := cache.Get(self.Key); ok {
+		pluginLogger.LogTask(slogger.INFO, "Key '%v' is already cached, not saving it again", self.Key)
+		return nil
+	}
+
+	tempFile, err := cache.TempFile()
+	if err != nil {
+		return errors.Wrap(err, "error creating archive")
+	}
+	tempPath := tempFile.Name()
+	grip.CatchError(tempFile.Close())
+
+	filesArchived, err := self.buildArchive(tempPath, pluginLogger)
+	if err != nil || filesArchived == 0 {
+		grip.CatchError(os.Remove(tempPath))
+		if err != nil {
+			return errors.Wrap(err, "error creating archive")
+		}
+		pluginLogger.LogTask(slogger.WARN, "No files matched, not caching key '%v'", self.Key)
+		return nil
+	}
+
+	path, err := cache.Add(self.Key, tempPath)
+	if err != nil {
+		grip.CatchError(os.Remove(tempPath))
+		return errors.WithStack(err)
+	}
+	pluginLogger.LogTask(slogger.INFO, "Cached %v files under key '%v'", filesArchived, self.Key)
+	if err = cache.Evict(path); err != nil {
+		pluginLogger.LogExecution(slogger.WARN, "Error evicting old cache entries: %v", err)
+	}
+
+	if store := self.Remote.NewStore(); store != nil {
+		pluginLogger.LogTask(slogger.INFO, "Uploading key '%v' to the %v remote", self.Key, self.Remote.Type)
+		if err = store.Put(self.Key, path); err != nil {
+			pluginLogger.LogTask(slogger.WARN, "Error uploading key '%v' to the remote: %v", self.Key, err)
+		}
+	}
+	return nil
+}
+
+func (self *SaveCommand) buildArchive(target string, pluginLogger plugin.Logger) (int, error) {
+	f, gz, tarWriter, err := archive.TarGzWriter(target)
+	if err != nil {
+		return -1, errors.Wrapf(err, "error opening archive file %s", target)
+	}
+	filesArchived, err := archive.BuildArchive(tarWriter, self.SourceDir, self.Include,
+		self.ExcludeFiles, newArchiveLogger(pluginLogger))
+
+	// the archive is incomplete unless every writer is closed
+	catcher := grip.NewCatcher()
+	catcher.Add(err)
+	catcher.Add(tarWriter.Close())
+	catcher.Add(gz.Close())
+	catcher.Add(f.Close())
+	return filesArchived, catcher.Resolve()
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/evergreen-ci/evergreen/thirdparty"
+	"github.com/evergreen-ci/evergreen/util"
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/s3"
+	"github.com/pkg/errors"
+)
+
+const (
+	LocalStoreType = "local"
+	S3StoreType    = "s3"
+)
+
+// Store is a remote backing store for the host's cache, shared between hosts.
+type Store interface {
+	// Get downloads the archive stored under key to path, and returns false
+	// if there is no such archive.
+	Get(key, path string) (bool, error)
+	// Put uploads the archive at path under key.
+	Put(key, path string) error
+}
+
+// RemoteConfig configures the remote store, if any, of a cache command.
+type RemoteConfig struct {
+	// Type is the kind of store, "local" or "s3". The command has no remote
+	// store if it's blank.
+	Type string `mapstructure:"type"`
+
+	// Path is the directory of a local store.
+	Path string `mapstructure:"path" plugin:"expand"`
+
+	// Bucket and Prefix are where an s3 store keeps its archives.
+	Bucket string `mapstructure:"bucket" plugin:"expand"`
+	Prefix string `mapstructure:"prefix" plugin:"expand"`
+
+	AwsKey      string `mapstructure:"aws_key" plugin:"expand"`
+	AwsSecret   string `mapstructure:"aws_secret" plugin:"expand"`
+	Permissions string `mapstructure:"permissions"`
+}
+
+func (r RemoteConfig) validate() error {
+	switch r.Type {
+	case "":
+		return nil
+	case LocalStoreType:
+		if r.Path == "" {
+			return errors.New("path cannot be blank for a local remote")
+		}
+	case S3StoreType:
+		if r.Bucket == "" {
+			return errors.New("bucket cannot be blank for an s3 remote")
+		}
+		if r.AwsKey == "" || r.AwsSecret == "" {
+			return errors.New("aws_key and aws_secret cannot be blank for an s3 remote")
+		}
+		if r.Permissions != "" && !util.SliceContains(validS3Permissions, r.Permissions) {
+			return errors.Errorf("permissions '%v' are not valid", r.Permissions)
+		}
+	default:
+		return errors.Errorf("remote type must be '%v' or '%v', not '%v'",
+			LocalStoreType, S3StoreType, r.Type)
+	}
+	return nil
+}
+
+var validS3Permissions = []string{
+	string(s3.Private),
+	string(s3.PublicRead),
+	string(s3.PublicReadWrite),
+	string(s3.AuthenticatedRead),
+	string(s3.BucketOwnerRead),
+	string(s3.BucketOwnerFull),
+}
+
+// NewStore returns the store the config describes, or nil if there is none.
+func (r RemoteConfig) NewStore() Store {
+	switch r.Type {
+	case LocalStoreType:
+		return &LocalStore{Dir: r.Path}
+	case S3StoreType:
+		perm := r.Permissions
+		if perm == "" {
+			perm = string(s3.Private)
+		}
+		return &S3Store{
+			Auth:        &aws.Auth{AccessKey: r.AwsKey, SecretKey: r.AwsSecret},
+			Bucket:      r.Bucket,
+			Prefix:      r.Prefix,
+			Permissions: perm,
+		}
+	default:
+		return nil
+	}
+}
+
+// LocalStore is a Store in a directory, such as a shared mount.
+type LocalStore struct {
+	Dir string
+}
+
+func (s *LocalStore) Get(key, dest string) (bool, error) {
+	src, err := os.Open(filepath.Join(s.Dir, EntryName(key)))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer src.Close()
+	return true, errors.WithStack(copyToFile(src, dest))
+}
+
+func (s *LocalStore) Put(key, path string) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer src.Close()
+
+	// write under a temporary name first, so that readers never see a
+	// partial archive
+	dest := filepath.Join(s.Dir, EntryName(key))
+	tmp := dest + ".tmp" + util.RandomString()
+	if err = copyToFile(src, tmp); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp, dest))
+}
+
+// S3Store is a Store in an s3 bucket.
+type S3Store struct {
+	Auth        *aws.Auth
+	Bucket      string
+	Prefix      string
+	Permissions string
+}
+
+func (s *S3Store) url(key string) string {
+	return "s3://" + s.Bucket + "/" + path.Join(s.Prefix, EntryName(key))
+}
+
+func (s *S3Store) Get(key, dest string) (bool, error) {
+	src, err := thirdparty.GetS3File(s.Auth, s.url(key))
+	if s3Err, ok := err.(*s3.Error); ok && s3Err.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "error getting %v", s.url(key))
+	}
+	defer src.Close()
+	return true, errors.WithStack(copyToFile(src, dest))
+}
+
+func (s *S3Store) Put(key, path string) error {
+	return errors.WithStack(thirdparty.PutS3File(s.Auth, path, s.url(key), "application/x-gzip", s.Permissions))
+}
+
+func copyToFile(src io.Reader, path string) error {
+	dest, err := os.Create(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err = io.Copy(dest, src); err != nil {
+		_ = dest.Close()
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(dest.Close())
+}
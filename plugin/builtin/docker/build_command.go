@@ -0,0 +1,116 @@
+package docker
+
+import (
+	"path/filepath"
+
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	dockerclient "github.com/fsouza/go-dockerclient"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+// BuildCommand builds an image from a Dockerfile. The image is removed when
+// the task finishes.
+type BuildCommand struct {
+	// Context is the directory to build the image from, relative to the
+	// task's working directory. Defaults to the working directory itself.
+	Context string `mapstructure:"context" plugin:"expand"`
+
+	// Dockerfile is the path of the Dockerfile within the context. Defaults
+	// to "Dockerfile".
+	Dockerfile string `mapstructure:"dockerfile" plugin:"expand"`
+
+	// Tag is the name to give the image, e.g. "myapp:${revision}".
+	Tag string `mapstructure:"tag" plugin:"expand"`
+
+	// NoCache, if set, builds every step of the Dockerfile anew.
+	NoCache bool `mapstructure:"no_cache"`
+
+	// Pull, if set, pulls newer versions of the base images before building.
+	Pull bool `mapstructure:"pull"`
+
+	// Host is the address of the Docker daemon to use. If blank, the agent's
+	// DOCKER_HOST is used.
+	Host string `mapstructure:"host" plugin:"expand"`
+}
+
+func (self *BuildCommand) Name() string {
+	return BuildCmdName
+}
+
+func (self *BuildCommand) Plugin() string {
+	return DockerPluginName
+}
+
+// ParseParams decodes and validates the command's parameters.
+func (self *BuildCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.Tag == "" {
+		return errors.Errorf("error validating '%v' params: tag cannot be blank", self.Name())
+	}
+	return nil
+}
+
+// Execute builds the image, streaming the build's output to the task logs.
+func (self *BuildCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator,
+	conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+	client, err := newClient(self.Host)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	opts := dockerclient.BuildImageOptions{
+		Name:                self.Tag,
+		Dockerfile:          self.Dockerfile,
+		NoCache:             self.NoCache,
+		Pull:                self.Pull,
+		RmTmpContainer:      true,
+		ForceRmTmpContainer: true,
+		ContextDir:          filepath.Join(conf.WorkDir, self.Context),
+		OutputStream:        pluginLogger.GetTaskLogWriter(slogger.INFO),
+	}
+
+	// track the image before building it, since the task may be stopped and
+	// cleaned up while the build is still running
+	trackImage(conf.Task.Id, self.Host, self.Tag)
+
+	errChan := make(chan error, 1)
+	go func() {
+		pluginLogger.LogTask(slogger.INFO, "Building image %v from %v", self.Tag, opts.ContextDir)
+		err := client.BuildImage(opts)
+		if err != nil {
+			untrackImage(conf.Task.Id, self.Host, self.Tag)
+		} else if !imageTracked(conf.Task.Id, self.Host, self.Tag) {
+			// the task was cleaned up before the build finished
+			rmErr := client.RemoveImage(self.Tag)
+			if rmErr != nil && rmErr != dockerclient.ErrNoSuchImage {
+				pluginLogger.LogSystem(slogger.ERROR, "Error removing image %v built after cleanup: %v",
+					self.Tag, rmErr)
+			}
+		}
+		errChan <- errors.Wrapf(err, "error building image %v", self.Tag)
+	}()
+
+	defer pluginLogger.Flush()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			return err
+		}
+		pluginLogger.LogTask(slogger.INFO, "Built image %v", self.Tag)
+		return nil
+	case <-stop:
+		pluginLogger.LogExecution(slogger.INFO, "Received signal to terminate execution of docker build command")
+		return errors.New("docker build interrupted")
+	}
+}
@@ -0,0 +1,281 @@
+package docker
+
+import (
+	"sync"
+
+	"github.com/evergreen-ci/evergreen/plugin"
+	dockerclient "github.com/fsouza/go-dockerclient"
+	"github.com/mongodb/grip"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	plugin.Publish(&DockerPlugin{})
+}
+
+const (
+	DockerPluginName = "docker"
+	BuildCmdName     = "build"
+	RunCmdName       = "run"
+	PushCmdName      = "push"
+
+	// TaskIdLabel is the label containers started by a task are given, so
+	// that they can be traced back to the task.
+	TaskIdLabel = "evergreen.task_id"
+)
+
+// DockerPlugin has commands for building images, running containers and
+// pushing images to registries. The containers and images a task creates are
+// removed when the task finishes, even if it times out or is aborted.
+type DockerPlugin struct{}
+
+// Name returns the name of the plugin. Fulfills the Plugin interface.
+func (self *DockerPlugin) Name() string {
+	return DockerPluginName
+}
+
+// NewCommand returns commands of the given name.
+// Fulfills the Plugin interface.
+func (self *DockerPlugin) NewCommand(cmdName string) (plugin.Command, error) {
+	switch cmdName {
+	case BuildCmdName:
+		return &BuildCommand{}, nil
+	case RunCmdName:
+		return &RunCommand{}, nil
+	case PushCmdName:
+		return &PushCommand{}, nil
+	default:
+		return nil, errors.Errorf("no such command: %v", cmdName)
+	}
+}
+
+// newClient returns a client for the Docker daemon at host, or, if host is
+// blank, for the one the agent's environment (DOCKER_HOST, DOCKER_TLS_VERIFY
+// and DOCKER_CERT_PATH) points to.
+func newClient(host string) (*dockerclient.Client, error) {
+	if host == "" {
+		client, err := dockerclient.NewClientFromEnv()
+		return client, errors.Wrap(err, "error creating docker client from the environment")
+	}
+	client, err := dockerclient.NewClient(host)
+	return client, errors.Wrapf(err, "error creating docker client for %v", host)
+}
+
+// resource is a container or image created by a task on a Docker daemon.
+type resource struct {
+	host string
+	id   string
+}
+
+// taskResources are the containers and images created by a task that have
+// yet to be removed.
+type taskResources struct {
+	containers []resource
+	images     []resource
+}
+
+var tracked = struct {
+	sync.Mutex
+	tasks map[string]*taskResources
+}{tasks: map[string]*taskResources{}}
+
+func resourcesFor(taskId string) *taskResources {
+	resources, ok := tracked.tasks[taskId]
+	if !ok {
+		resources = &taskResources{}
+		tracked.tasks[taskId] = resources
+	}
+	return resources
+}
+
+// trackContainer records that a task created a container, so that it is
+// removed when the task finishes.
+func trackContainer(taskId, host, id string) {
+	tracked.Lock()
+	defer tracked.Unlock()
+	resources := resourcesFor(taskId)
+	resources.containers = append(resources.containers, resource{host: host, id: id})
+}
+
+// untrackContainer forgets a container that has already been removed.
+func untrackContainer(taskId, host, id string) {
+	tracked.Lock()
+	defer tracked.Unlock()
+	resources := resourcesFor(taskId)
+	for i, container := range resources.containers {
+		if container.host == host && container.id == id {
+			resources.containers = append(resources.containers[:i], resources.containers[i+1:]...)
+			return
+		}
+	}
+}
+
+// trackImage records that a task built or tagged an image, so that it is
+// removed when the task finishes.
+func trackImage(taskId, host, name string) {
+	tracked.Lock()
+	defer tracked.Unlock()
+	resources := resourcesFor(taskId)
+	for _, image := range resources.images {
+		if image.host == host && image.id == name {
+			return
+		}
+	}
+	resources.images = append(resources.images, resource{host: host, id: name})
+}
+
+// untrackImage forgets an image that a task failed to build.
+func untrackImage(taskId, host, name string) {
+	tracked.Lock()
+	defer tracked.Unlock()
+	resources, ok := tracked.tasks[taskId]
+	if !ok {
+		return
+	}
+	for i, image := range resources.images {
+		if image.host == host && image.id == name {
+			resources.images = append(resources.images[:i], resources.images[i+1:]...)
+			return
+		}
+	}
+}
+
+// imageTracked returns whether an image is still to be removed when the
+// task finishes, i.e. whether the task has not been cleaned up yet.
+func imageTracked(taskId, host, name string) bool {
+	tracked.Lock()
+	defer tracked.Unlock()
+	resources, ok := tracked.tasks[taskId]
+	if !ok {
+		return false
+	}
+	for _, image := range resources.images {
+		if image.host == host && image.id == name {
+			return true
+		}
+	}
+	return false
+}
+
+// removeContainer forcibly removes a container and its volumes. A container
+// that no longer exists is not an error.
+func removeContainer(client *dockerclient.Client, id string) error {
+	err := client.RemoveContainer(dockerclient.RemoveContainerOptions{
+		ID:            id,
+		RemoveVolumes: true,
+		Force:         true,
+	})
+	if _, ok := err.(*dockerclient.NoSuchContainer); ok {
+		return nil
+	}
+	return errors.Wrapf(err, "error removing container %v", id)
+}
+
+// sweepContainers removes every container on the Docker daemon at host that
+// carries the given label filter, which is either a bare label or a
+// label=value pair. When the daemon is optional, i.e. the task never used it,
+// a daemon that can't be reached is skipped rather than reported.
+func sweepContainers(host, filter string, optional bool, pluginLogger plugin.Logger) error {
+	client, err := newClient(host)
+	if err != nil {
+		return err
+	}
+	if optional {
+		if err = client.Ping(); err != nil {
+			return nil
+		}
+	}
+	containers, err := client.ListContainers(dockerclient.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {filter}},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error listing containers labeled %v", filter)
+	}
+
+	catcher := grip.NewCatcher()
+	for _, container := range containers {
+		if err = removeContainer(client, container.ID); err != nil {
+			catcher.Add(err)
+			continue
+		}
+		pluginLogger.LogSystem(slogger.INFO, "Cleanup removed docker container %v labeled %v",
+			container.ID, filter)
+	}
+	return catcher.Resolve()
+}
+
+// CleanupTask removes the containers, and then the images, that were
+// created by the given task and have not been removed yet. It is called by
+// the agent alongside the cleanup of the task's processes.
+//
+// Besides the containers the task is known to have created, the containers
+// labeled with the task's id are removed from every daemon the task used and
+// from the default one, so that none are left behind if the task was not
+// tracked, e.g. because it was created before the agent restarted.
+func CleanupTask(taskId string, pluginLogger plugin.Logger) error {
+	tracked.Lock()
+	resources, ok := tracked.tasks[taskId]
+	delete(tracked.tasks, taskId)
+	tracked.Unlock()
+	if !ok {
+		resources = &taskResources{}
+	}
+
+	catcher := grip.NewCatcher()
+	hosts := map[string]bool{}
+	for _, container := range resources.containers {
+		hosts[container.host] = true
+		client, err := newClient(container.host)
+		if err != nil {
+			catcher.Add(err)
+			continue
+		}
+		if err = removeContainer(client, container.id); err != nil {
+			catcher.Add(err)
+			continue
+		}
+		pluginLogger.LogSystem(slogger.INFO, "Cleanup removed docker container %v", container.id)
+	}
+	for _, image := range resources.images {
+		hosts[image.host] = true
+	}
+
+	filter := TaskIdLabel + "=" + taskId
+	if !hosts[""] {
+		catcher.Add(sweepContainers("", filter, true, pluginLogger))
+	}
+	for host := range hosts {
+		catcher.Add(sweepContainers(host, filter, false, pluginLogger))
+	}
+
+	// remove the most recent tags first, as they may refer to earlier ones
+	for i := len(resources.images) - 1; i >= 0; i-- {
+		image := resources.images[i]
+		client, err := newClient(image.host)
+		if err != nil {
+			catcher.Add(err)
+			continue
+		}
+		err = client.RemoveImage(image.id)
+		if err == dockerclient.ErrNoSuchImage {
+			continue
+		}
+		if err != nil {
+			catcher.Add(errors.Wrapf(err, "error removing image %v", image.id))
+			continue
+		}
+		pluginLogger.LogSystem(slogger.INFO, "Cleanup removed docker image %v", image.id)
+	}
+	return catcher.Resolve()
+}
+
+// CleanupOrphans removes every container labeled with a task id from the
+// default Docker daemon. It is called by the agent when it starts, before it
+// runs any task, to remove the containers of tasks whose cleanup never ran,
+// e.g. because the previous agent was killed. Hosts without a reachable
+// daemon are skipped.
+func CleanupOrphans(pluginLogger plugin.Logger) error {
+	return sweepContainers("", TaskIdLabel, true, pluginLogger)
+}
@@ -0,0 +1,205 @@
+package docker
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/evergreen-ci/evergreen/plugin/plugintest"
+	dockerclient "github.com/fsouza/go-dockerclient"
+	dockertest "github.com/fsouza/go-dockerclient/testing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newFakeDaemon starts a fake Docker daemon whose containers exit soon after
+// they start: with code 1 if their command is "false", and never if it is
+// "sleep".
+func newFakeDaemon() (*dockertest.DockerServer, error) {
+	containers := make(chan *dockerclient.Container)
+	server, err := dockertest.NewServer("127.0.0.1:0", containers, nil)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for container := range containers {
+			if !container.State.Running || container.Path == "sleep" {
+				continue
+			}
+			exitCode := 0
+			if container.Path == "false" {
+				exitCode = 1
+			}
+			go func(id string) {
+				time.Sleep(10 * time.Millisecond)
+				server.MutateContainer(id, dockerclient.State{ExitCode: exitCode})
+			}(container.ID)
+		}
+	}()
+	return server, nil
+}
+
+func TestDockerParams(t *testing.T) {
+	Convey("docker commands should require their parameters", t, func() {
+		So((&BuildCommand{}).ParseParams(map[string]interface{}{"context": "src"}), ShouldNotBeNil)
+		So((&BuildCommand{}).ParseParams(map[string]interface{}{"tag": "app:${revision}"}), ShouldBeNil)
+
+		So((&RunCommand{}).ParseParams(map[string]interface{}{"command": []string{"true"}}), ShouldNotBeNil)
+		So((&RunCommand{}).ParseParams(map[string]interface{}{
+			"image":   "busybox",
+			"volumes": []string{"/data"},
+		}), ShouldNotBeNil)
+		So((&RunCommand{}).ParseParams(map[string]interface{}{
+			"image":   "busybox",
+			"volumes": []string{"./src:/src:ro", "cache:/cache"},
+		}), ShouldBeNil)
+
+		So((&PushCommand{}).ParseParams(map[string]interface{}{"registry": "localhost:5000"}), ShouldNotBeNil)
+		So((&PushCommand{}).ParseParams(map[string]interface{}{"image": "app"}), ShouldBeNil)
+	})
+}
+
+func TestDockerCommands(t *testing.T) {
+	server, err := newFakeDaemon()
+	if err != nil {
+		t.Fatalf("failed to start fake docker daemon: %v", err)
+	}
+	defer server.Stop()
+	host := strings.TrimSuffix(server.URL(), "/")
+	client, err := newClient(host)
+	if err != nil {
+		t.Fatalf("failed to create docker client: %v", err)
+	}
+
+	stopper := make(chan bool)
+	defer close(stopper)
+
+	Convey("With a task that uses docker", t, func() {
+		dir, err := ioutil.TempDir("", "docker_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		So(ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM busybox\n"), 0644), ShouldBeNil)
+
+		conf := &model.TaskConfig{
+			Expansions: command.NewExpansions(map[string]string{"revision": "abc123"}),
+			Task:       &task.Task{Id: "docker_test"},
+			WorkDir:    dir,
+		}
+		logger := &plugintest.MockLogger{}
+		defer CleanupTask(conf.Task.Id, logger)
+
+		containers := func() []dockerclient.APIContainers {
+			list, err := client.ListContainers(dockerclient.ListContainersOptions{All: true})
+			So(err, ShouldBeNil)
+			return list
+		}
+
+		Convey("docker.build should build and tag an image", func() {
+			build := &BuildCommand{Tag: "app:${revision}", Host: host}
+			So(build.Execute(logger, nil, conf, stopper), ShouldBeNil)
+			_, err := client.InspectImage("app:abc123")
+			So(err, ShouldBeNil)
+
+			Convey("which docker.push should push to a local registry", func() {
+				push := &PushCommand{Image: "app:${revision}", Registry: "localhost:5000", Host: host}
+				So(push.Execute(logger, nil, conf, stopper), ShouldBeNil)
+				_, err := client.InspectImage("localhost:5000/app:abc123")
+				So(err, ShouldBeNil)
+
+				Convey("and both tags should be removed when the task is cleaned up", func() {
+					So(CleanupTask(conf.Task.Id, logger), ShouldBeNil)
+					_, err := client.InspectImage("app:abc123")
+					So(err, ShouldEqual, dockerclient.ErrNoSuchImage)
+					_, err = client.InspectImage("localhost:5000/app:abc123")
+					So(err, ShouldEqual, dockerclient.ErrNoSuchImage)
+				})
+			})
+		})
+
+		Convey("docker.push should fail for images that don't exist", func() {
+			push := &PushCommand{Image: "missing:1", Host: host}
+			So(push.Execute(logger, nil, conf, stopper), ShouldNotBeNil)
+		})
+
+		Convey("docker.run should pull the image and remove the container once it exits", func() {
+			run := &RunCommand{
+				Image:   "busybox:latest",
+				Command: []string{"true"},
+				Env:     map[string]string{"REVISION": "${revision}"},
+				Volumes: []string{"./src:/src"},
+				Host:    host,
+			}
+			So(run.Execute(logger, nil, conf, stopper), ShouldBeNil)
+			So(containers(), ShouldBeEmpty)
+		})
+
+		Convey("docker.run should fail if the container exits with an error", func() {
+			run := &RunCommand{Image: "busybox", Command: []string{"false"}, Host: host}
+			So(run.Execute(logger, nil, conf, stopper), ShouldNotBeNil)
+			run = &RunCommand{Image: "busybox", Command: []string{"false"}, ContinueOnError: true, Host: host}
+			So(run.Execute(logger, nil, conf, stopper), ShouldBeNil)
+		})
+
+		Convey("kept and background containers should be labeled and removed by cleanup", func() {
+			run := &RunCommand{Image: "busybox", Command: []string{"true"}, Keep: true, Host: host}
+			So(run.Execute(logger, nil, conf, stopper), ShouldBeNil)
+			run = &RunCommand{Image: "busybox", Command: []string{"sleep"}, Background: true, Host: host}
+			So(run.Execute(logger, nil, conf, stopper), ShouldBeNil)
+
+			list := containers()
+			So(len(list), ShouldEqual, 2)
+			container, err := client.InspectContainer(list[0].ID)
+			So(err, ShouldBeNil)
+			So(container.Config.Labels[TaskIdLabel], ShouldEqual, conf.Task.Id)
+
+			So(CleanupTask(conf.Task.Id, logger), ShouldBeNil)
+			So(containers(), ShouldBeEmpty)
+		})
+
+		Convey("an interrupted docker.run should remove its container", func() {
+			stop := make(chan bool, 1)
+			stop <- true
+			run := &RunCommand{Image: "busybox", Command: []string{"sleep"}, Host: host}
+			So(run.Execute(logger, nil, conf, stop), ShouldNotBeNil)
+			So(containers(), ShouldBeEmpty)
+		})
+
+		Convey("an image whose interrupted build finishes after cleanup should be removed", func() {
+			release, built := make(chan struct{}), make(chan struct{})
+			server.CustomHandler("/build", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+				server.DefaultHandler().ServeHTTP(w, r)
+				close(built)
+			}))
+			defer server.CustomHandler("/build", server.DefaultHandler())
+
+			stop := make(chan bool, 1)
+			stop <- true
+			build := &BuildCommand{Tag: "interrupted", Host: host}
+			So(build.Execute(logger, nil, conf, stop), ShouldNotBeNil)
+			So(CleanupTask(conf.Task.Id, logger), ShouldBeNil)
+			close(release)
+			<-built
+
+			removed := func() bool {
+				tracked.Lock()
+				_, ok := tracked.tasks[conf.Task.Id]
+				tracked.Unlock()
+				_, err := client.InspectImage("interrupted")
+				return !ok && err == dockerclient.ErrNoSuchImage
+			}
+			// the image is removed once the client sees the build finish
+			deadline := time.Now().Add(5 * time.Second)
+			for !removed() && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			So(removed(), ShouldBeTrue)
+		})
+	})
+}
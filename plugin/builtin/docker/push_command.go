@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	dockerclient "github.com/fsouza/go-dockerclient"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+// PushCommand pushes an image to a registry.
+type PushCommand struct {
+	// Image is the image to push, e.g. "myapp:${revision}".
+	Image string `mapstructure:"image" plugin:"expand"`
+
+	// Registry, if set, is the address of the registry to push the image to,
+	// e.g. "localhost:5000" for a registry run by an earlier docker.run. The
+	// image is tagged with the registry's address before it is pushed, and
+	// that tag is removed when the task finishes. If blank, the image is
+	// pushed to the registry its name refers to.
+	Registry string `mapstructure:"registry" plugin:"expand"`
+
+	// Username and Password are the credentials to log in to the registry
+	// with. Registries that don't need them, such as local ones, can be
+	// pushed to without.
+	Username string `mapstructure:"username" plugin:"expand"`
+	Password string `mapstructure:"password" plugin:"expand"`
+
+	// Host is the address of the Docker daemon to use. If blank, the agent's
+	// DOCKER_HOST is used.
+	Host string `mapstructure:"host" plugin:"expand"`
+}
+
+func (self *PushCommand) Name() string {
+	return PushCmdName
+}
+
+func (self *PushCommand) Plugin() string {
+	return DockerPluginName
+}
+
+// ParseParams decodes and validates the command's parameters.
+func (self *PushCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.Image == "" {
+		return errors.Errorf("error validating '%v' params: image cannot be blank", self.Name())
+	}
+	return nil
+}
+
+// Execute tags the image for the registry, if one is given, and pushes it.
+func (self *PushCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator,
+	conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+	client, err := newClient(self.Host)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	repository, tag := dockerclient.ParseRepositoryTag(self.Image)
+	if self.Registry != "" {
+		repository = self.Registry + "/" + repository
+		err = client.TagImage(self.Image, dockerclient.TagImageOptions{
+			Repo:  repository,
+			Tag:   tag,
+			Force: true,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error tagging image %v for %v", self.Image, self.Registry)
+		}
+		tagged := repository
+		if tag != "" {
+			tagged += ":" + tag
+		}
+		trackImage(conf.Task.Id, self.Host, tagged)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		pluginLogger.LogTask(slogger.INFO, "Pushing image %v", repository)
+		err := client.PushImage(dockerclient.PushImageOptions{
+			Name:         repository,
+			Tag:          tag,
+			Registry:     self.Registry,
+			OutputStream: pluginLogger.GetTaskLogWriter(slogger.INFO),
+		}, dockerclient.AuthConfiguration{
+			Username:      self.Username,
+			Password:      self.Password,
+			ServerAddress: self.Registry,
+		})
+		errChan <- errors.Wrapf(err, "error pushing image %v", repository)
+	}()
+
+	defer pluginLogger.Flush()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			return err
+		}
+		pluginLogger.LogTask(slogger.INFO, "Pushed image %v", repository)
+		return nil
+	case <-stop:
+		pluginLogger.LogExecution(slogger.INFO, "Received signal to terminate execution of docker push command")
+		return errors.New("docker push interrupted")
+	}
+}
@@ -0,0 +1,226 @@
+package docker
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	dockerclient "github.com/fsouza/go-dockerclient"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+// RunCommand runs a container, streaming its output to the task logs. The
+// container is removed when it exits, or, if it runs in the background or is
+// kept, when the task finishes.
+type RunCommand struct {
+	// Image is the image to run. It is pulled if the daemon doesn't have it.
+	Image string `mapstructure:"image" plugin:"expand"`
+
+	// Command is the command to run in the container, in place of the image's
+	// default command. Each element is passed as one argument.
+	Command []string `mapstructure:"command" plugin:"expand"`
+
+	// Env sets environment variables in the container.
+	Env map[string]string `mapstructure:"env" plugin:"expand"`
+
+	// WorkingDir is the directory in the container to run the command in.
+	WorkingDir string `mapstructure:"working_dir" plugin:"expand"`
+
+	// Volumes are bind mounts or named volumes in the form
+	// "source:destination[:options]". Sources that begin with "." are
+	// relative to the task's working directory.
+	Volumes []string `mapstructure:"volumes" plugin:"expand"`
+
+	// Network is the network to connect the container to, e.g. "host".
+	Network string `mapstructure:"network" plugin:"expand"`
+
+	// ContainerName, if set, is the name to give the container.
+	ContainerName string `mapstructure:"name" plugin:"expand"`
+
+	// Background, if set, returns as soon as the container has started
+	// rather than waiting for it to exit.
+	Background bool `mapstructure:"background"`
+
+	// Keep, if set, keeps the container once it exits, so that later
+	// commands can inspect it, rather than removing it straight away.
+	Keep bool `mapstructure:"keep"`
+
+	// ContinueOnError determines whether or not a non-zero exit code should
+	// cause the task to be marked as failed.
+	ContinueOnError bool `mapstructure:"continue_on_err"`
+
+	// Host is the address of the Docker daemon to use. If blank, the agent's
+	// DOCKER_HOST is used.
+	Host string `mapstructure:"host" plugin:"expand"`
+}
+
+func (self *RunCommand) Name() string {
+	return RunCmdName
+}
+
+func (self *RunCommand) Plugin() string {
+	return DockerPluginName
+}
+
+// ParseParams decodes and validates the command's parameters.
+func (self *RunCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.Image == "" {
+		return errors.Errorf("error validating '%v' params: image cannot be blank", self.Name())
+	}
+	for _, volume := range self.Volumes {
+		if len(strings.Split(volume, ":")) < 2 {
+			return errors.Errorf("error validating '%v' params: volume '%v' "+
+				"must be in the form source:destination", self.Name(), volume)
+		}
+	}
+	return nil
+}
+
+// Execute creates and starts the container, then, unless it runs in the
+// background, streams its output until it exits.
+func (self *RunCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator,
+	conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+	client, err := newClient(self.Host)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	container, err := self.createContainer(client, conf, pluginLogger)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	trackContainer(conf.Task.Id, self.Host, container.ID)
+	pluginLogger.LogExecution(slogger.INFO, "Created container %v from image %v", container.ID, self.Image)
+
+	if err = client.StartContainer(container.ID, nil); err != nil {
+		return errors.Wrapf(err, "error starting container %v", container.ID)
+	}
+	if self.Background {
+		pluginLogger.LogTask(slogger.INFO, "Started container %v in the background", container.ID)
+		return nil
+	}
+
+	type result struct {
+		exitCode int
+		err      error
+	}
+	resultChan := make(chan result, 1)
+	go func() {
+		err := client.AttachToContainer(dockerclient.AttachToContainerOptions{
+			Container:    container.ID,
+			OutputStream: pluginLogger.GetTaskLogWriter(slogger.INFO),
+			ErrorStream:  pluginLogger.GetTaskLogWriter(slogger.ERROR),
+			Logs:         true,
+			Stream:       true,
+			Stdout:       true,
+			Stderr:       true,
+		})
+		if err != nil {
+			pluginLogger.LogExecution(slogger.WARN, "Error streaming output of container %v: %v", container.ID, err)
+		}
+		exitCode, err := client.WaitContainer(container.ID)
+		resultChan <- result{exitCode: exitCode, err: errors.Wrapf(err, "error waiting for container %v", container.ID)}
+	}()
+
+	defer pluginLogger.Flush()
+	select {
+	case res := <-resultChan:
+		if res.err != nil {
+			return res.err
+		}
+		if !self.Keep {
+			if err = removeContainer(client, container.ID); err != nil {
+				pluginLogger.LogExecution(slogger.WARN, "%v", err)
+			} else {
+				untrackContainer(conf.Task.Id, self.Host, container.ID)
+			}
+		}
+		if res.exitCode != 0 {
+			if self.ContinueOnError {
+				pluginLogger.LogExecution(slogger.INFO, "(ignoring) Container exited with code %v", res.exitCode)
+				return nil
+			}
+			return errors.Errorf("container %v exited with code %v", container.ID, res.exitCode)
+		}
+		pluginLogger.LogExecution(slogger.INFO, "Container %v exited successfully", container.ID)
+		return nil
+	case <-stop:
+		pluginLogger.LogExecution(slogger.INFO, "Got kill signal, removing container %v", container.ID)
+		if err = removeContainer(client, container.ID); err != nil {
+			pluginLogger.LogExecution(slogger.ERROR, "%v", err)
+		} else {
+			untrackContainer(conf.Task.Id, self.Host, container.ID)
+		}
+		return errors.New("docker run interrupted")
+	}
+}
+
+// createContainer creates the container, pulling its image first if the
+// daemon doesn't have it.
+func (self *RunCommand) createContainer(client *dockerclient.Client, conf *model.TaskConfig,
+	pluginLogger plugin.Logger) (*dockerclient.Container, error) {
+
+	env := []string{}
+	for name, value := range self.Env {
+		env = append(env, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(env)
+
+	binds := []string{}
+	for _, volume := range self.Volumes {
+		parts := strings.SplitN(volume, ":", 2)
+		if strings.HasPrefix(parts[0], ".") {
+			parts[0] = filepath.Join(conf.WorkDir, parts[0])
+		}
+		binds = append(binds, strings.Join(parts, ":"))
+	}
+
+	opts := dockerclient.CreateContainerOptions{
+		Name: self.ContainerName,
+		Config: &dockerclient.Config{
+			Image:        self.Image,
+			Cmd:          self.Command,
+			Env:          env,
+			WorkingDir:   self.WorkingDir,
+			AttachStdout: true,
+			AttachStderr: true,
+			Labels:       map[string]string{TaskIdLabel: conf.Task.Id},
+		},
+		HostConfig: &dockerclient.HostConfig{
+			Binds:       binds,
+			NetworkMode: self.Network,
+		},
+	}
+
+	container, err := client.CreateContainer(opts)
+	if err != dockerclient.ErrNoSuchImage {
+		return container, errors.Wrap(err, "error creating container")
+	}
+
+	repository, tag := dockerclient.ParseRepositoryTag(self.Image)
+	pluginLogger.LogTask(slogger.INFO, "Pulling image %v", self.Image)
+	err = client.PullImage(dockerclient.PullImageOptions{
+		Repository:   repository,
+		Tag:          tag,
+		OutputStream: pluginLogger.GetTaskLogWriter(slogger.INFO),
+	}, dockerclient.AuthConfiguration{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error pulling image %v", self.Image)
+	}
+	container, err = client.CreateContainer(opts)
+	return container, errors.Wrap(err, "error creating container")
+}
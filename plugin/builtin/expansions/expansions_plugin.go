@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"path/filepath"
 
+	"github.com/evergreen-ci/evergreen/command"
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/plugin"
 	"github.com/mitchellh/mapstructure"
@@ -19,6 +20,11 @@ func init() {
 const (
 	ExpansionsPluginName = "expansions"
 	UpdateVarsCmdName    = "update"
+
+	// TaskScope and FunctionScope are how long updated expansions last: for
+	// the rest of the task, or for the rest of the function being run.
+	TaskScope     = "task"
+	FunctionScope = "function"
 )
 
 // ExpansionsPlugin handles updating expansions in a task at runtime.
@@ -48,6 +54,8 @@ func (self *ExpansionsPlugin) NewCommand(cmdName string) (plugin.Command, error)
 		return &UpdateCommand{}, nil
 	} else if cmdName == FetchVarsCmdname {
 		return &FetchVarsCommand{}, nil
+	} else if cmdName == WriteVarsCmdName {
+		return &WriteCommand{}, nil
 	}
 	return nil, &plugin.ErrUnknownCommand{cmdName}
 }
@@ -63,6 +71,11 @@ type UpdateCommand struct {
 	// in the form of
 	//   "expansion_key: expansions_value"
 	YamlFile string `mapstructure:"file"`
+
+	// Scope is how long the updates last, either "task" (the default), for
+	// the rest of the task, or "function", for the rest of the function the
+	// command is in, after which the expansions revert to their old values.
+	Scope string `mapstructure:"scope"`
 }
 
 // PutCommandParams are pairings of expansion names
@@ -101,6 +114,11 @@ func (self *UpdateCommand) ParseParams(params map[string]interface{}) error {
 		}
 	}
 
+	if self.Scope != "" && self.Scope != TaskScope && self.Scope != FunctionScope {
+		return errors.Errorf("error parsing '%v' params: scope must be either "+
+			"'%v' or '%v'", self.Name(), TaskScope, FunctionScope)
+	}
+
 	return nil
 }
 
@@ -112,7 +130,7 @@ func (self *UpdateCommand) ExecuteUpdates(conf *model.TaskConfig) error {
 			if err != nil {
 				return err
 			}
-			conf.Expansions.Put(update.Key, newValue)
+			conf.PutExpansion(update.Key, newValue, self.Scope == FunctionScope)
 		} else {
 			newValue, err := conf.Expansions.ExpandString(update.Concat)
 			if err != nil {
@@ -120,7 +138,7 @@ func (self *UpdateCommand) ExecuteUpdates(conf *model.TaskConfig) error {
 			}
 
 			oldValue := conf.Expansions.Get(update.Key)
-			conf.Expansions.Put(update.Key, oldValue+newValue)
+			conf.PutExpansion(update.Key, oldValue+newValue, self.Scope == FunctionScope)
 		}
 	}
 
@@ -131,6 +149,11 @@ func (self *UpdateCommand) ExecuteUpdates(conf *model.TaskConfig) error {
 func (self *UpdateCommand) Execute(pluginLogger plugin.Logger,
 	pluginCom plugin.PluginCommunicator, conf *model.TaskConfig, stop chan bool) error {
 
+	if self.Scope == FunctionScope && !conf.InFunctionScope() {
+		pluginLogger.LogTask(slogger.WARN, "Not running in a function, so updating "+
+			"expansions for the rest of the task")
+	}
+
 	err := self.ExecuteUpdates(conf)
 	if err != nil {
 		return err
@@ -144,10 +167,14 @@ func (self *UpdateCommand) Execute(pluginLogger plugin.Logger,
 
 		pluginLogger.LogTask(slogger.INFO, "Updating expansions with keys from file: %v", self.YamlFile)
 		filename := filepath.Join(conf.WorkDir, self.YamlFile)
-		err := conf.Expansions.UpdateFromYaml(filename)
+		updates := command.Expansions{}
+		err := updates.UpdateFromYaml(filename)
 		if err != nil {
 			return err
 		}
+		for key, value := range updates {
+			conf.PutExpansion(key, value, self.Scope == FunctionScope)
+		}
 	}
 	return nil
 
@@ -45,6 +45,65 @@ func TestExpansionsPlugin(t *testing.T) {
 		So(expansions.Get("topping"), ShouldEqual, "bacon,sausage")
 	})
 
+	Convey("Should be able to scope updates to a function", t, func() {
+		expansions := command.Expansions{}
+		expansions.Put("base", "eggs")
+		expansions.Put("topping", "bacon")
+		taskConfig := model.TaskConfig{
+			Expansions: &expansions,
+		}
+
+		functionConfig := taskConfig.WithFunctionScope()
+		functionUpdate := UpdateCommand{
+			Scope: FunctionScope,
+			Updates: []PutCommandParams{
+				{Key: "base", Value: "toast"},
+				{Key: "side", Value: "beans"},
+				{Key: "topping", Value: "butter"},
+			},
+		}
+		So(functionUpdate.ExecuteUpdates(functionConfig), ShouldBeNil)
+		taskUpdate := UpdateCommand{
+			Scope:   TaskScope,
+			Updates: []PutCommandParams{{Key: "topping", Concat: ",jam"}},
+		}
+		So(taskUpdate.ExecuteUpdates(functionConfig), ShouldBeNil)
+
+		So(expansions.Get("base"), ShouldEqual, "toast")
+		So(expansions.Get("side"), ShouldEqual, "beans")
+		So(expansions.Get("topping"), ShouldEqual, "butter,jam")
+
+		functionConfig.EndFunctionScope()
+		So(expansions.Get("base"), ShouldEqual, "eggs")
+		So(expansions.Exists("side"), ShouldBeFalse)
+		So(expansions.Get("topping"), ShouldEqual, "butter,jam")
+	})
+
+	Convey("Functions run at the same time should only restore their own updates", t, func() {
+		expansions := command.Expansions{}
+		taskConfig := model.TaskConfig{
+			Expansions: &expansions,
+		}
+
+		main := taskConfig.WithFunctionScope()
+		timeout := taskConfig.WithFunctionScope()
+		main.PutExpansion("main", "1", true)
+		timeout.PutExpansion("timeout", "2", true)
+		So(taskConfig.InFunctionScope(), ShouldBeFalse)
+
+		timeout.EndFunctionScope()
+		So(expansions.Get("main"), ShouldEqual, "1")
+		So(expansions.Exists("timeout"), ShouldBeFalse)
+		So(main.InFunctionScope(), ShouldBeTrue)
+
+		main.EndFunctionScope()
+		So(expansions.Exists("main"), ShouldBeFalse)
+	})
+
+	Convey("Should reject an unknown scope", t, func() {
+		So((&UpdateCommand{}).ParseParams(map[string]interface{}{"scope": "build"}), ShouldNotBeNil)
+		So((&UpdateCommand{}).ParseParams(map[string]interface{}{"scope": "function"}), ShouldBeNil)
+	})
 }
 
 func TestExpansionsPluginWExecution(t *testing.T) {
@@ -0,0 +1,182 @@
+package expansions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	WriteVarsCmdName = "write"
+
+	YAMLFormat = "yaml"
+	JSONFormat = "json"
+	EnvFormat  = "env"
+)
+
+// envNameRegex matches the characters that may not appear in the name of a
+// shell variable.
+var envNameRegex = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// WriteCommand writes the task's expansions, other than private project
+// variables, to a file that scripts can read or source. The values of private
+// variables are redacted from those of other expansions, as they are from the
+// task's logs.
+type WriteCommand struct {
+	// File is where to write the expansions, relative to the working
+	// directory.
+	File string `mapstructure:"file" plugin:"expand"`
+
+	// Format is one of "yaml" (the default), which expansions.update can read
+	// back in, "json", or "env", a shell script of exports to source. Since
+	// shell variables can only hold letters, digits and '_', any other
+	// characters in the names of expansions are replaced by '_' in env files.
+	Format string `mapstructure:"format"`
+}
+
+func (self *WriteCommand) Name() string {
+	return WriteVarsCmdName
+}
+
+func (self *WriteCommand) Plugin() string {
+	return ExpansionsPluginName
+}
+
+// ParseParams validates the input to the WriteCommand, returning an error
+// if something is incorrect. Fulfills Command interface.
+func (self *WriteCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.File == "" {
+		return errors.Errorf("error parsing '%v' params: file must not be blank", self.Name())
+	}
+	if self.Format == "" {
+		self.Format = YAMLFormat
+	}
+	if self.Format != YAMLFormat && self.Format != JSONFormat && self.Format != EnvFormat {
+		return errors.Errorf("error parsing '%v' params: format must be one of %v, %v or %v",
+			self.Name(), YAMLFormat, JSONFormat, EnvFormat)
+	}
+	return nil
+}
+
+// Execute writes the expansions to the file. Fulfills Command interface.
+func (self *WriteCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator, conf *model.TaskConfig, stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// values of private variables may have been copied under other names,
+	// e.g. by expansions.fetch or an update's concat, so redact them by value
+	// too
+	replacer := privateValueReplacer(conf)
+	expansions := map[string]string{}
+	redacted := []string{}
+	for key, value := range *conf.Expansions {
+		if conf.PrivateVars[key] {
+			continue
+		}
+		if replaced := replacer.Replace(value); replaced != value {
+			redacted = append(redacted, key)
+			value = replaced
+		}
+		expansions[key] = value
+	}
+	if len(redacted) > 0 {
+		sort.Strings(redacted)
+		pluginLogger.LogTask(slogger.WARN, "Redacting private variables from the values of %v", redacted)
+	}
+
+	var out []byte
+	var err error
+	switch self.Format {
+	case JSONFormat:
+		out, err = json.MarshalIndent(expansions, "", "  ")
+	case EnvFormat:
+		out = formatEnv(expansions)
+	default:
+		out, err = yaml.Marshal(expansions)
+	}
+	if err != nil {
+		return errors.Wrap(err, "error formatting expansions")
+	}
+
+	filename := self.File
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(conf.WorkDir, filename)
+	}
+	pluginLogger.LogTask(slogger.INFO, "Writing %d expansions to %v", len(expansions), filename)
+	return errors.Wrapf(ioutil.WriteFile(filename, out, 0644), "error writing expansions to %v", filename)
+}
+
+// privateValueReplacer returns a replacer of the values of the task's private
+// variables with their names, replacing longer values first, so that a value
+// containing another isn't left partly visible.
+func privateValueReplacer(conf *model.TaskConfig) *strings.Replacer {
+	names := privateVarsByLength{expansions: conf.Expansions}
+	for name := range conf.PrivateVars {
+		if conf.Expansions.Get(name) != "" {
+			names.names = append(names.names, name)
+		}
+	}
+	sort.Sort(names)
+
+	pairs := make([]string, 0, 2*len(names.names))
+	for _, name := range names.names {
+		pairs = append(pairs, conf.Expansions.Get(name), fmt.Sprintf("<REDACTED:%s>", name))
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// privateVarsByLength sorts variable names by the length of their values,
+// longest first, and then by name.
+type privateVarsByLength struct {
+	names      []string
+	expansions *command.Expansions
+}
+
+func (p privateVarsByLength) Len() int      { return len(p.names) }
+func (p privateVarsByLength) Swap(i, j int) { p.names[i], p.names[j] = p.names[j], p.names[i] }
+func (p privateVarsByLength) Less(i, j int) bool {
+	li, lj := len(p.expansions.Get(p.names[i])), len(p.expansions.Get(p.names[j]))
+	if li != lj {
+		return li > lj
+	}
+	return p.names[i] < p.names[j]
+}
+
+// formatEnv returns a shell script that exports the expansions, sorted by
+// name.
+func formatEnv(expansions map[string]string) []byte {
+	keys := make([]string, 0, len(expansions))
+	for key := range expansions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf := []byte{}
+	for _, key := range keys {
+		name := envNameRegex.ReplaceAllString(key, "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+		value := strings.Replace(expansions[key], "'", `'\''`, -1)
+		buf = append(buf, fmt.Sprintf("export %s='%s'\n", name, value)...)
+	}
+	return buf
+}
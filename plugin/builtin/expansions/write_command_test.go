@@ -0,0 +1,85 @@
+package expansions_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	. "github.com/evergreen-ci/evergreen/plugin/builtin/expansions"
+	"github.com/evergreen-ci/evergreen/plugin/plugintest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteCommand(t *testing.T) {
+	Convey("With a task's expansions, including a private variable", t, func() {
+		workDir, err := ioutil.TempDir("", "expansions-write")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(workDir)
+
+		conf := &model.TaskConfig{
+			Expansions: command.NewExpansions(map[string]string{
+				"project":     "mci",
+				"server.host": "example.com",
+				"quote":       "it's",
+				"secret":      "hunter2",
+				"secret_copy": "hunter2",
+				"login":       "admin:hunter2",
+			}),
+			PrivateVars: map[string]bool{"secret": true},
+			WorkDir:     workDir,
+		}
+		logger := &plugintest.MockLogger{}
+
+		Convey("invalid params should be rejected", func() {
+			So((&WriteCommand{}).ParseParams(map[string]interface{}{}), ShouldNotBeNil)
+			So((&WriteCommand{}).ParseParams(map[string]interface{}{"file": "x", "format": "xml"}), ShouldNotBeNil)
+			cmd := &WriteCommand{}
+			So(cmd.ParseParams(map[string]interface{}{"file": "x"}), ShouldBeNil)
+			So(cmd.Format, ShouldEqual, YAMLFormat)
+		})
+
+		Convey("yaml should be readable by expansions.update", func() {
+			cmd := &WriteCommand{File: "expansions.yml", Format: YAMLFormat}
+			So(cmd.Execute(logger, nil, conf, nil), ShouldBeNil)
+
+			written := command.Expansions{}
+			So(written.UpdateFromYaml(filepath.Join(workDir, "expansions.yml")), ShouldBeNil)
+			So(written.Get("server.host"), ShouldEqual, "example.com")
+			So(written.Get("quote"), ShouldEqual, "it's")
+			So(written.Exists("secret"), ShouldBeFalse)
+			So(written.Get("secret_copy"), ShouldEqual, "<REDACTED:secret>")
+			So(written.Get("login"), ShouldEqual, "admin:<REDACTED:secret>")
+		})
+
+		Convey("json should hold the expansions", func() {
+			cmd := &WriteCommand{File: "expansions.json", Format: JSONFormat}
+			So(cmd.Execute(logger, nil, conf, nil), ShouldBeNil)
+
+			data, err := ioutil.ReadFile(filepath.Join(workDir, "expansions.json"))
+			So(err, ShouldBeNil)
+			written := map[string]string{}
+			So(json.Unmarshal(data, &written), ShouldBeNil)
+			So(written["project"], ShouldEqual, "mci")
+			So(written, ShouldNotContainKey, "secret")
+			So(written["secret_copy"], ShouldEqual, "<REDACTED:secret>")
+			So(written["login"], ShouldEqual, "admin:<REDACTED:secret>")
+		})
+
+		Convey("env files should export shell-safe names and quoted values", func() {
+			cmd := &WriteCommand{File: "expansions.sh", Format: EnvFormat}
+			So(cmd.Execute(logger, nil, conf, nil), ShouldBeNil)
+
+			data, err := ioutil.ReadFile(filepath.Join(workDir, "expansions.sh"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "export login='admin:<REDACTED:secret>'\n"+
+				"export project='mci'\n"+
+				"export quote='it'\\''s'\n"+
+				"export secret_copy='<REDACTED:secret>'\n"+
+				"export server_host='example.com'\n")
+		})
+	})
+}
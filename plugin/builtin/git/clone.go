@@ -0,0 +1,104 @@
+package git
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/evergreen-ci/evergreen/util"
+)
+
+// MirrorsDirName is the directory, under the distro's work directory, in which
+// the agent keeps the mirrors that clones reference.
+const MirrorsDirName = "git_mirrors"
+
+// cloneOptions control how git.get_project clones and checks out the project
+// and its modules, and how patches are applied to them.
+type cloneOptions struct {
+	// depth, if positive, makes clones shallow.
+	depth int
+	// sparse, if not empty, are the only paths checked out.
+	sparse []string
+	// mirrorsDir, if set, is the directory holding the mirrors to use as
+	// references for clones.
+	mirrorsDir string
+	// submodules makes checkouts update the repository's submodules.
+	submodules bool
+}
+
+// mirrorPath returns the path of the mirror of the repository at location.
+func (opts cloneOptions) mirrorPath(location string) string {
+	return filepath.ToSlash(filepath.Join(opts.mirrorsDir, util.CleanForPath(location)))
+}
+
+// cloneCommands returns the commands that clone the repository at location
+// into dir. If branch is not empty, it is the branch that is checked out. Any
+// checkout is left to checkoutCommands, so that sparse checkouts only ever
+// write the paths asked for.
+func (opts cloneOptions) cloneCommands(location, branch, dir string) []string {
+	cmds := []string{}
+	cloneCmd := fmt.Sprintf("git clone '%s' '%s'", location, dir)
+
+	if opts.mirrorsDir != "" {
+		// keep a mirror of the repository on the host, so that clones only
+		// need to fetch the objects it doesn't have yet
+		mirror := opts.mirrorPath(location)
+		cmds = append(cmds,
+			fmt.Sprintf("mkdir -p '%s'", filepath.ToSlash(opts.mirrorsDir)),
+			fmt.Sprintf("if [ -d '%s' ]; then git --git-dir='%s' fetch --prune --quiet || rm -rf '%s'; fi", mirror, mirror, mirror),
+			fmt.Sprintf("if [ ! -d '%s' ]; then git clone --mirror --quiet '%s' '%s'; fi", mirror, location, mirror),
+		)
+		cloneCmd = fmt.Sprintf("%s --reference '%s'", cloneCmd, mirror)
+	}
+	if branch != "" {
+		cloneCmd = fmt.Sprintf("%s --branch '%s'", cloneCmd, branch)
+	}
+	if opts.depth > 0 {
+		cloneCmd = fmt.Sprintf("%s --depth %d", cloneCmd, opts.depth)
+	}
+	if len(opts.sparse) > 0 {
+		cloneCmd = fmt.Sprintf("%s --no-checkout", cloneCmd)
+	}
+	cmds = append(cmds, cloneCmd)
+
+	if len(opts.sparse) > 0 {
+		paths := make([]string, 0, len(opts.sparse))
+		for _, path := range opts.sparse {
+			paths = append(paths, fmt.Sprintf("'%s'", path))
+		}
+		cmds = append(cmds,
+			fmt.Sprintf("git -C '%s' config core.sparseCheckout true", dir),
+			fmt.Sprintf("printf '%%s\\n' %s > '%s/.git/info/sparse-checkout'", strings.Join(paths, " "), dir),
+		)
+	}
+	return cmds
+}
+
+// checkoutCommands returns the commands that check out revision in the
+// current directory, either with reset --hard or with checkout. In a shallow
+// clone, the history is fetched if the revision is not already in it.
+func (opts cloneOptions) checkoutCommands(revision string, reset bool) []string {
+	cmds := []string{}
+	if opts.depth > 0 {
+		cmds = append(cmds, fmt.Sprintf("if ! git rev-parse --quiet --verify '%s^{commit}' > /dev/null; then git fetch --unshallow; fi", revision))
+	}
+	if reset {
+		cmds = append(cmds, fmt.Sprintf("git reset --hard '%s'", revision))
+	} else {
+		cmds = append(cmds, fmt.Sprintf("git checkout '%s'", revision))
+	}
+	return cmds
+}
+
+// submoduleCommands returns the commands that bring the submodules of the
+// repository in the current directory in line with the checked out revision.
+func (opts cloneOptions) submoduleCommands() []string {
+	if !opts.submodules {
+		return nil
+	}
+	updateCmd := "git submodule update --init --recursive"
+	if opts.depth > 0 {
+		updateCmd = fmt.Sprintf("%s --depth %d", updateCmd, opts.depth)
+	}
+	return []string{"git submodule sync --recursive", updateCmd}
+}
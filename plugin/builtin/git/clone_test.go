@@ -0,0 +1,97 @@
+package git
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen/model/patch"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCloneCommands(t *testing.T) {
+	Convey("With clone options", t, func() {
+		Convey("no options should do a plain clone and checkout", func() {
+			opts := cloneOptions{}
+			So(opts.cloneCommands("git@github.com:evergreen-ci/evergreen.git", "master", "src"), ShouldResemble, []string{
+				"git clone 'git@github.com:evergreen-ci/evergreen.git' 'src' --branch 'master'",
+			})
+			So(opts.checkoutCommands("abc123", true), ShouldResemble, []string{"git reset --hard 'abc123'"})
+			So(opts.submoduleCommands(), ShouldBeEmpty)
+		})
+
+		Convey("a clone depth should make the clone shallow and unshallow it if needed", func() {
+			opts := cloneOptions{depth: 50}
+			cmds := opts.cloneCommands("repo", "", "src")
+			So(cmds, ShouldResemble, []string{"git clone 'repo' 'src' --depth 50"})
+
+			cmds = opts.checkoutCommands("abc123", false)
+			So(len(cmds), ShouldEqual, 2)
+			So(cmds[0], ShouldContainSubstring, "git rev-parse --quiet --verify 'abc123^{commit}'")
+			So(cmds[0], ShouldContainSubstring, "git fetch --unshallow")
+			So(cmds[1], ShouldEqual, "git checkout 'abc123'")
+		})
+
+		Convey("sparse paths should be written before anything is checked out", func() {
+			opts := cloneOptions{sparse: []string{"src/", "docs/*.md"}}
+			cmds := opts.cloneCommands("repo", "", "src")
+			So(cmds, ShouldResemble, []string{
+				"git clone 'repo' 'src' --no-checkout",
+				"git -C 'src' config core.sparseCheckout true",
+				"printf '%s\\n' 'src/' 'docs/*.md' > 'src/.git/info/sparse-checkout'",
+			})
+		})
+
+		Convey("a mirror should be updated and referenced", func() {
+			opts := cloneOptions{mirrorsDir: "/data/mci/git_mirrors"}
+			cmds := opts.cloneCommands("git@github.com:evergreen-ci/evergreen.git", "", "src")
+			mirror := "/data/mci/git_mirrors/git_github.com_evergreen-ci_evergreen.git"
+			So(len(cmds), ShouldEqual, 4)
+			So(cmds[1], ShouldContainSubstring, "git --git-dir='"+mirror+"' fetch --prune")
+			So(cmds[2], ShouldContainSubstring, "git clone --mirror --quiet 'git@github.com:evergreen-ci/evergreen.git' '"+mirror+"'")
+			So(cmds[3], ShouldEqual, "git clone 'git@github.com:evergreen-ci/evergreen.git' 'src' --reference '"+mirror+"'")
+		})
+
+		Convey("submodules should be updated to the same depth", func() {
+			opts := cloneOptions{depth: 10, submodules: true}
+			So(opts.submoduleCommands(), ShouldResemble, []string{
+				"git submodule sync --recursive",
+				"git submodule update --init --recursive --depth 10",
+			})
+		})
+
+		Convey("patches should be applied with the same options", func() {
+			opts := cloneOptions{depth: 10, submodules: true}
+			cmds := getPatchCommands(patch.ModulePatch{
+				Githash:  "abc123",
+				PatchSet: patch.PatchSet{Patch: "these are words"},
+			}, "src", "/tmp/patch", opts)
+			joined := strings.Join(cmds, "\n")
+			So(joined, ShouldContainSubstring, "git fetch --unshallow")
+			So(strings.Index(joined, "git apply --whitespace=fix"), ShouldBeLessThan,
+				strings.Index(joined, "git submodule update"))
+		})
+
+		Convey("patches to a sparse checkout should check out the paths they touch first", func() {
+			opts := cloneOptions{sparse: []string{"src/"}}
+			cmds := getPatchCommands(patch.ModulePatch{
+				Githash: "abc123",
+				PatchSet: patch.PatchSet{Patch: strings.Join([]string{
+					"diff --git a/docs/README b/docs/README",
+					"--- a/docs/README",
+					"+++ b/docs/README",
+					"@@ -1 +1 @@",
+					"-old",
+					"+new",
+					"diff --git a/old name b/new name",
+					"similarity index 100%",
+					"rename from old name",
+					"rename to new name",
+				}, "\n")},
+			}, "src", "/tmp/patch", opts)
+			joined := strings.Join(cmds, "\n")
+			So(cmds, ShouldContain, "printf '%s\\n' '/docs/README' '/old name' '/new name' >> .git/info/sparse-checkout")
+			So(strings.Index(joined, "git read-tree -mu HEAD"), ShouldBeLessThan,
+				strings.Index(joined, "git apply --check"))
+		})
+	})
+}
@@ -25,6 +25,23 @@ type GitGetProjectCommand struct {
 	// Revisions are the optional revisions associated with the modules of a project.
 	// Note: If a module does not have a revision it will use the module's branch to get the project.
 	Revisions map[string]string `plugin:"expand"`
+
+	// CloneDepth, if positive, makes the clones of the project and its modules
+	// shallow, with only that many commits of history.
+	CloneDepth int `mapstructure:"clone_depth"`
+
+	// SparseCheckout, if not empty, are the only paths of the project that are
+	// checked out.
+	SparseCheckout []string `mapstructure:"sparse_checkout" plugin:"expand"`
+
+	// UseMirror makes the clones reference mirrors of the repositories that
+	// the agent keeps in the distro's work directory, so that only new
+	// objects are fetched.
+	UseMirror bool `mapstructure:"use_mirror"`
+
+	// Submodules makes the checkouts of the project and its modules update
+	// their submodules.
+	Submodules bool `mapstructure:"submodules"`
 }
 
 func (ggpc *GitGetProjectCommand) Name() string {
@@ -47,9 +64,38 @@ func (ggpc *GitGetProjectCommand) ParseParams(params map[string]interface{}) err
 		return errors.Errorf("error parsing '%v' params: value for directory "+
 			"must not be blank", ggpc.Name())
 	}
+	if ggpc.CloneDepth < 0 {
+		return errors.Errorf("error parsing '%v' params: clone_depth "+
+			"must not be negative", ggpc.Name())
+	}
+	for _, path := range ggpc.SparseCheckout {
+		if path == "" {
+			return errors.Errorf("error parsing '%v' params: sparse_checkout "+
+				"paths must not be blank", ggpc.Name())
+		}
+	}
 	return nil
 }
 
+// cloneOptions returns the options the project and its modules are cloned
+// with. Mirrors are only used if the distro has a work directory to keep them
+// in.
+func (ggpc *GitGetProjectCommand) cloneOptions(conf *model.TaskConfig, pluginLogger plugin.Logger) cloneOptions {
+	opts := cloneOptions{
+		depth:      ggpc.CloneDepth,
+		sparse:     ggpc.SparseCheckout,
+		submodules: ggpc.Submodules,
+	}
+	if ggpc.UseMirror {
+		if conf.Distro == nil || conf.Distro.WorkDir == "" {
+			pluginLogger.LogExecution(slogger.WARN, "Not using a git mirror, since the distro has no work directory")
+		} else {
+			opts.mirrorsDir = filepath.Join(conf.Distro.WorkDir, MirrorsDirName)
+		}
+	}
+	return opts
+}
+
 // Execute gets the source code required by the project
 func (ggpc *GitGetProjectCommand) Execute(pluginLogger plugin.Logger,
 	pluginCom plugin.PluginCommunicator,
@@ -67,20 +113,17 @@ func (ggpc *GitGetProjectCommand) Execute(pluginLogger plugin.Logger,
 		return err
 	}
 
+	opts := ggpc.cloneOptions(conf, pluginLogger)
+
 	gitCommands := []string{
 		fmt.Sprintf("set -o errexit"),
 		fmt.Sprintf("set -o verbose"),
 		fmt.Sprintf("rm -rf %s", ggpc.Directory),
 	}
-
-	cloneCmd := fmt.Sprintf("git clone '%s' '%s'", location, ggpc.Directory)
-	if conf.ProjectRef.Branch != "" {
-		cloneCmd = fmt.Sprintf("%s --branch '%s'", cloneCmd, conf.ProjectRef.Branch)
-	}
-
-	gitCommands = append(gitCommands,
-		cloneCmd,
-		fmt.Sprintf("cd %v; git reset --hard %s", ggpc.Directory, conf.Task.Revision))
+	gitCommands = append(gitCommands, opts.cloneCommands(location, conf.ProjectRef.Branch, ggpc.Directory)...)
+	gitCommands = append(gitCommands, fmt.Sprintf("cd %v", ggpc.Directory))
+	gitCommands = append(gitCommands, opts.checkoutCommands(conf.Task.Revision, true)...)
+	gitCommands = append(gitCommands, opts.submoduleCommands()...)
 
 	cmdsJoined := strings.Join(gitCommands, "\n")
 
@@ -149,12 +192,24 @@ func (ggpc *GitGetProjectCommand) Execute(pluginLogger plugin.Logger,
 			revision = module.Branch
 		}
 
+		// modules are always checked out in full, since the sparse paths
+		// are relative to the project
+		moduleOpts := opts
+		moduleOpts.sparse = nil
+		moduleBranch := ""
+		if moduleOpts.depth > 0 {
+			// a shallow clone only has the history of the branch it clones
+			moduleBranch = module.Branch
+		}
+
 		moduleCmds := []string{
 			fmt.Sprintf("set -o errexit"),
 			fmt.Sprintf("set -o verbose"),
-			fmt.Sprintf("git clone %v '%v'", module.Repo, filepath.ToSlash(moduleBase)),
-			fmt.Sprintf("cd %v; git checkout '%v'", filepath.ToSlash(moduleBase), revision),
 		}
+		moduleCmds = append(moduleCmds, moduleOpts.cloneCommands(module.Repo, moduleBranch, filepath.ToSlash(moduleBase))...)
+		moduleCmds = append(moduleCmds, fmt.Sprintf("cd %v", filepath.ToSlash(moduleBase)))
+		moduleCmds = append(moduleCmds, moduleOpts.checkoutCommands(revision, false)...)
+		moduleCmds = append(moduleCmds, moduleOpts.submoduleCommands()...)
 
 		moduleFetchCmd := &command.LocalCommand{
 			CmdString:        strings.Join(moduleCmds, "\n"),
@@ -204,7 +259,7 @@ func (ggpc *GitGetProjectCommand) Execute(pluginLogger plugin.Logger,
 			pluginLogger.LogExecution(slogger.ERROR, "Failed to get patch contents: %v", err)
 			errChan <- errors.Wrap(err, "Failed to get patch contents")
 		}
-		err = ggpc.applyPatch(conf, patch, pluginLogger)
+		err = ggpc.applyPatch(conf, patch, opts, pluginLogger)
 		if err != nil {
 			pluginLogger.LogExecution(slogger.INFO, "Failed to apply patch: %v", err)
 			errChan <- errors.Wrap(err, "Failed to apply patch")
@@ -154,27 +154,91 @@ func (ggpc GitGetProjectCommand) getPatchContents(com plugin.PluginCommunicator,
 // GetPatchCommands, given a module patch of a patch, will return the appropriate list of commands that
 // need to be executed. If the patch is empty it will not apply the patch.
 func GetPatchCommands(modulePatch patch.ModulePatch, dir, patchPath string) []string {
+	return getPatchCommands(modulePatch, dir, patchPath, cloneOptions{})
+}
+
+// getPatchCommands returns the commands that apply the patch to a checkout
+// that was cloned with opts.
+func getPatchCommands(modulePatch patch.ModulePatch, dir, patchPath string, opts cloneOptions) []string {
 	patchCommands := []string{
 		fmt.Sprintf("set -o verbose"),
 		fmt.Sprintf("set -o errexit"),
 		fmt.Sprintf("ls"),
 		fmt.Sprintf("cd '%s'", dir),
-		fmt.Sprintf("git reset --hard '%s'", modulePatch.Githash),
 	}
+	patchCommands = append(patchCommands, opts.checkoutCommands(modulePatch.Githash, true)...)
+
 	if modulePatch.PatchSet.Patch == "" {
-		return patchCommands
+		return append(patchCommands, opts.submoduleCommands()...)
 	}
-	return append(patchCommands, []string{
+	if len(opts.sparse) > 0 {
+		// the patch can only be applied to files that are checked out, so
+		// add the ones it touches to the sparse checkout
+		paths := []string{}
+		for _, path := range patchPaths(modulePatch.PatchSet.Patch) {
+			paths = append(paths, fmt.Sprintf("'/%s'", strings.Replace(path, "'", `'\''`, -1)))
+		}
+		if len(paths) > 0 {
+			patchCommands = append(patchCommands,
+				fmt.Sprintf("printf '%%s\\n' %s >> .git/info/sparse-checkout", strings.Join(paths, " ")),
+				"git read-tree -mu HEAD",
+			)
+		}
+	}
+	patchCommands = append(patchCommands, []string{
 		fmt.Sprintf("git apply --check --whitespace=fix '%v'", patchPath),
 		fmt.Sprintf("git apply --stat '%v'", patchPath),
 		fmt.Sprintf("git apply --whitespace=fix < '%v'", patchPath),
 	}...)
+	// the patch may move submodules
+	return append(patchCommands, opts.submoduleCommands()...)
+}
+
+// patchPaths returns the paths of the files a patch in git's format touches,
+// before and after any renames, in the order they appear.
+func patchPaths(patchText string) []string {
+	paths := []string{}
+	seen := map[string]bool{}
+	add := func(path string) {
+		path = strings.TrimRight(path, "\t")
+		if path == "" || path == "/dev/null" || seen[path] {
+			return
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	for _, line := range strings.Split(patchText, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			// "diff --git a/<path> b/<path>" can only be split reliably if
+			// the paths are the same; renames have their own lines below
+			names := strings.TrimPrefix(line, "diff --git ")
+			half := len(names) / 2
+			if len(names)%2 == 1 && names[half] == ' ' &&
+				strings.HasPrefix(names, "a/") && names[2:half] == names[half+3:] {
+				add(names[2:half])
+			}
+		case strings.HasPrefix(line, "--- a/"):
+			add(strings.TrimPrefix(line, "--- a/"))
+		case strings.HasPrefix(line, "+++ b/"):
+			add(strings.TrimPrefix(line, "+++ b/"))
+		case strings.HasPrefix(line, "rename from "):
+			add(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			add(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "copy from "):
+			add(strings.TrimPrefix(line, "copy from "))
+		case strings.HasPrefix(line, "copy to "):
+			add(strings.TrimPrefix(line, "copy to "))
+		}
+	}
+	return paths
 }
 
 // applyPatch is used by the agent to copy patch data onto disk
 // and then call the necessary git commands to apply the patch file
 func (ggpc *GitGetProjectCommand) applyPatch(conf *model.TaskConfig,
-	p *patch.Patch, pluginLogger plugin.Logger) error {
+	p *patch.Patch, opts cloneOptions, pluginLogger plugin.Logger) error {
 	// patch sets and contain multiple patches, some of them for modules
 	for _, patchPart := range p.Patches {
 		var dir string
@@ -203,6 +267,11 @@ func (ggpc *GitGetProjectCommand) applyPatch(conf *model.TaskConfig,
 			dir = filepath.Join(ggpc.Directory, module.Prefix, module.Name)
 			pluginLogger.LogExecution(slogger.INFO, "Applying module patch with git...")
 		}
+		partOpts := opts
+		if patchPart.ModuleName != "" {
+			// modules are checked out in full
+			partOpts.sparse = nil
+		}
 
 		// create a temporary folder and store patch files on disk,
 		// for later use in shell script
@@ -218,7 +287,7 @@ func (ggpc *GitGetProjectCommand) applyPatch(conf *model.TaskConfig,
 		tempAbsPath := tempFile.Name()
 
 		// this applies the patch using the patch files in the temp directory
-		patchCommandStrings := GetPatchCommands(patchPart, dir, tempAbsPath)
+		patchCommandStrings := getPatchCommands(patchPart, dir, tempAbsPath, partOpts)
 		cmdsJoined := strings.Join(patchCommandStrings, "\n")
 		patchCmd := &command.LocalCommand{
 			CmdString:        cmdsJoined,
@@ -0,0 +1,335 @@
+package keyval
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/util"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+const (
+	GetCommandName = "get"
+	SetCommandName = "set"
+	CASCommandName = "cas"
+
+	GetRoute = "get"
+	SetRoute = "set"
+	CASRoute = "cas"
+)
+
+// Request is the body of requests to the routes that read and write entries
+// and locks. Keys are namespaced by the project of the requesting task.
+type Request struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+
+	// Old is the value that a compare-and-swap expects, or nil if it expects
+	// the key to have no value.
+	Old *string `json:"old,omitempty"`
+
+	// TTLSecs is how long a value or lock lasts, or, if zero, how long a
+	// value lasts is unlimited and a lock lasts DefaultLockTTL.
+	TTLSecs int `json:"ttl_secs,omitempty"`
+}
+
+// Response is the reply to a Request.
+type Response struct {
+	// Ok is whether a compare-and-swap or lock succeeded.
+	Ok bool `json:"ok"`
+
+	// Entry and Lock are as they stand after the request, and are nil if
+	// there are none.
+	Entry *Entry `json:"entry,omitempty"`
+	Lock  *Lock  `json:"lock,omitempty"`
+}
+
+// readRequest reads a request to one of the routes, writing an error
+// response if it is invalid.
+func readRequest(w http.ResponseWriter, r *http.Request) (*Request, EntryId, bool) {
+	task := plugin.GetTask(r)
+	if task == nil {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return nil, EntryId{}, false
+	}
+	req := &Request{}
+	if err := util.ReadJSONInto(r.Body, req); err != nil {
+		plugin.WriteJSON(w, http.StatusBadRequest, err.Error())
+		return nil, EntryId{}, false
+	}
+	if req.Key == "" {
+		plugin.WriteJSON(w, http.StatusBadRequest, "key must not be blank")
+		return nil, EntryId{}, false
+	}
+	if req.TTLSecs < 0 {
+		plugin.WriteJSON(w, http.StatusBadRequest, "ttl_secs must not be negative")
+		return nil, EntryId{}, false
+	}
+	return req, EntryId{Namespace: task.Project, Key: req.Key}, true
+}
+
+// GetKeyHandler returns the entry stored in the given key.
+func GetKeyHandler(w http.ResponseWriter, r *http.Request) {
+	_, id, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	entry, err := GetEntry(id)
+	if err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Error getting key: %v", err)
+		plugin.WriteJSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	plugin.WriteJSON(w, http.StatusOK, Response{Ok: entry != nil, Entry: entry})
+}
+
+// SetKeyHandler stores the given value in the given key, and returns it.
+func SetKeyHandler(w http.ResponseWriter, r *http.Request) {
+	req, id, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	entry, err := SetEntry(id, req.Value, time.Duration(req.TTLSecs)*time.Second)
+	if err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Error setting key: %v", err)
+		plugin.WriteJSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	plugin.WriteJSON(w, http.StatusOK, Response{Ok: true, Entry: entry})
+}
+
+// CASKeyHandler stores the given value in the given key if the key holds
+// the old value, and returns the key's entry.
+func CASKeyHandler(w http.ResponseWriter, r *http.Request) {
+	req, id, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	swapped, entry, err := CompareAndSwap(id, req.Old, req.Value, time.Duration(req.TTLSecs)*time.Second)
+	if err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Error swapping key: %v", err)
+		plugin.WriteJSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	plugin.WriteJSON(w, http.StatusOK, Response{Ok: swapped, Entry: entry})
+}
+
+// postRequest sends a request to one of the routes, retrying on errors other
+// than invalid requests.
+func postRequest(pluginCom plugin.PluginCommunicator, route string, req *Request) (*Response, error) {
+	out := &Response{}
+	postFunc := func() error {
+		resp, err := pluginCom.TaskPostJSON(route, req)
+		if resp != nil {
+			defer resp.Body.Close()
+		}
+		if err != nil {
+			return util.RetriableError{err}
+		}
+		if resp.StatusCode == http.StatusBadRequest {
+			msg := ""
+			_ = util.ReadJSONInto(resp.Body, &msg)
+			return errors.Errorf("invalid request: %v", msg)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return util.RetriableError{
+				errors.Errorf("unexpected status code: %v", resp.StatusCode),
+			}
+		}
+		return errors.Wrap(util.ReadJSONInto(resp.Body, out), "failed to read JSON reply")
+	}
+
+	retryFail, err := util.Retry(postFunc, 10, 1*time.Second)
+	if retryFail {
+		return nil, errors.Wrapf(err, "%v request failed after %v tries", route, 10)
+	}
+	return out, err
+}
+
+// GetCommand puts the value of a key into an expansion.
+type GetCommand struct {
+	Key         string `mapstructure:"key" plugin:"expand"`
+	Destination string `mapstructure:"destination" plugin:"expand"`
+
+	// Default is the value to use if the key has none.
+	Default string `mapstructure:"default" plugin:"expand"`
+}
+
+func (self *GetCommand) Name() string {
+	return GetCommandName
+}
+
+func (self *GetCommand) Plugin() string {
+	return KeyValPluginName
+}
+
+// ParseParams validates the input to the GetCommand, returning an error
+// if something is incorrect. Fulfills Command interface.
+func (self *GetCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.Key == "" || self.Destination == "" {
+		return errors.Errorf("error parsing '%v' params: key and destination may not be blank",
+			self.Name())
+	}
+	return nil
+}
+
+// Execute fetches the value from the API server.
+func (self *GetCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator, conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+
+	resp, err := postRequest(pluginCom, GetRoute, &Request{Key: self.Key})
+	if err != nil {
+		return err
+	}
+	if resp.Entry == nil {
+		pluginLogger.LogTask(slogger.INFO, "Key '%v' has no value, using default", self.Key)
+		conf.Expansions.Put(self.Destination, self.Default)
+		return nil
+	}
+	conf.Expansions.Put(self.Destination, resp.Entry.Value)
+	return nil
+}
+
+// SetCommand stores a value in a key.
+type SetCommand struct {
+	Key   string `mapstructure:"key" plugin:"expand"`
+	Value string `mapstructure:"value" plugin:"expand"`
+
+	// TTLSecs, if positive, is how long the value lasts.
+	TTLSecs int `mapstructure:"ttl_secs"`
+}
+
+func (self *SetCommand) Name() string {
+	return SetCommandName
+}
+
+func (self *SetCommand) Plugin() string {
+	return KeyValPluginName
+}
+
+// ParseParams validates the input to the SetCommand, returning an error
+// if something is incorrect. Fulfills Command interface.
+func (self *SetCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.Key == "" {
+		return errors.Errorf("error parsing '%v' params: key may not be blank", self.Name())
+	}
+	if self.TTLSecs < 0 {
+		return errors.Errorf("error parsing '%v' params: ttl_secs may not be negative", self.Name())
+	}
+	return nil
+}
+
+// Execute stores the value on the API server.
+func (self *SetCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator, conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err := postRequest(pluginCom, SetRoute, &Request{Key: self.Key, Value: self.Value, TTLSecs: self.TTLSecs})
+	return err
+}
+
+// CASCommand atomically stores a value in a key if the key holds an
+// expected value, so that tasks can coordinate, e.g. so that only the
+// first of several tasks to claim a key does some work.
+type CASCommand struct {
+	Key string `mapstructure:"key" plugin:"expand"`
+
+	// Old is the value the key must hold. If it is not given, the key must
+	// have no value.
+	Old *string `mapstructure:"old"`
+
+	New string `mapstructure:"new" plugin:"expand"`
+
+	// TTLSecs, if positive, is how long the new value lasts.
+	TTLSecs int `mapstructure:"ttl_secs"`
+
+	// Destination, if given, is an expansion to set to "true" or "false"
+	// according to whether the value was stored. Otherwise, the command
+	// fails if the value was not stored.
+	Destination string `mapstructure:"destination" plugin:"expand"`
+}
+
+func (self *CASCommand) Name() string {
+	return CASCommandName
+}
+
+func (self *CASCommand) Plugin() string {
+	return KeyValPluginName
+}
+
+// ParseParams validates the input to the CASCommand, returning an error
+// if something is incorrect. Fulfills Command interface.
+func (self *CASCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.Key == "" {
+		return errors.Errorf("error parsing '%v' params: key may not be blank", self.Name())
+	}
+	if self.TTLSecs < 0 {
+		return errors.Errorf("error parsing '%v' params: ttl_secs may not be negative", self.Name())
+	}
+	return nil
+}
+
+// Execute swaps the value on the API server.
+func (self *CASCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator, conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+	req := &Request{Key: self.Key, Value: self.New, TTLSecs: self.TTLSecs}
+	if self.Old != nil {
+		old, err := conf.Expansions.ExpandString(*self.Old)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		req.Old = &old
+	}
+
+	resp, err := postRequest(pluginCom, CASRoute, req)
+	if err != nil {
+		return err
+	}
+	if self.Destination != "" {
+		conf.Expansions.Put(self.Destination, strconv.FormatBool(resp.Ok))
+	}
+	if resp.Ok {
+		pluginLogger.LogTask(slogger.INFO, "Set key '%v' to '%v'", self.Key, self.New)
+		return nil
+	}
+
+	current := "no value"
+	if resp.Entry != nil {
+		current = fmt.Sprintf("'%v'", resp.Entry.Value)
+	}
+	pluginLogger.LogTask(slogger.INFO, "Did not set key '%v', which has %v", self.Key, current)
+	if self.Destination != "" {
+		return nil
+	}
+	return errors.Errorf("key '%v' has %v", self.Key, current)
+}
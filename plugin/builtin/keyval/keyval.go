@@ -75,6 +75,11 @@ func (incCmd *IncCommand) ParseParams(params map[string]interface{}) error {
 func (self *KeyValPlugin) GetAPIHandler() http.Handler {
 	r := http.NewServeMux()
 	r.HandleFunc("/inc", IncKeyHandler)
+	r.HandleFunc("/"+GetRoute, GetKeyHandler)
+	r.HandleFunc("/"+SetRoute, SetKeyHandler)
+	r.HandleFunc("/"+CASRoute, CASKeyHandler)
+	r.HandleFunc("/"+LockRoute, LockKeyHandler)
+	r.HandleFunc("/"+UnlockRoute, UnlockKeyHandler)
 	r.HandleFunc("/", http.NotFound)
 	return r
 }
@@ -151,8 +156,19 @@ func (incCmd *IncCommand) Execute(pluginLogger plugin.Logger,
 }
 
 func (self *KeyValPlugin) NewCommand(cmdName string) (plugin.Command, error) {
-	if cmdName == IncCommandName {
+	switch cmdName {
+	case IncCommandName:
 		return &IncCommand{}, nil
+	case GetCommandName:
+		return &GetCommand{}, nil
+	case SetCommandName:
+		return &SetCommand{}, nil
+	case CASCommandName:
+		return &CASCommand{}, nil
+	case LockCommandName:
+		return &LockCommand{}, nil
+	case UnlockCommandName:
+		return &UnlockCommand{}, nil
 	}
 	return nil, &plugin.ErrUnknownCommand{cmdName}
 }
@@ -63,3 +63,56 @@ func TestIncKey(t *testing.T) {
 		})
 	})
 }
+
+func TestCoordinationCommands(t *testing.T) {
+	Convey("With keyval plugin installed", t, func() {
+		err := db.ClearCollections(keyval.EntriesCollection, keyval.LocksCollection)
+		testutil.HandleTestingErr(err, t, "Couldn't clear test collections")
+		registry := plugin.NewSimpleRegistry()
+		kvPlugin := &keyval.KeyValPlugin{}
+		err = registry.Register(kvPlugin)
+		testutil.HandleTestingErr(err, t, "Couldn't register keyval plugin")
+
+		testConfig := testutil.TestConfig()
+
+		server, err := service.CreateTestServer(testConfig, nil, []plugin.APIPlugin{kvPlugin})
+		testutil.HandleTestingErr(err, t, "couldn't create test server")
+		defer server.Close()
+
+		configPath := filepath.Join(testutil.GetDirectoryOfFile(), "testdata", "plugin_keyval_coordination.yml")
+
+		modelData, err := modelutil.SetupAPITestData(testConfig, "testcoordination", "linux-64", configPath, modelutil.NoPatch)
+		testutil.HandleTestingErr(err, t, "couldn't create test task")
+		httpCom := plugintest.TestAgentCommunicator(modelData, server.URL)
+		logger := agentutil.NewTestLogger(slogger.StdOutAppender())
+
+		Convey("get, set, cas, lock and unlock commands should coordinate through keys", func() {
+			taskConfig := modelData.TaskConfig
+			for _, task := range taskConfig.Project.Tasks {
+				for _, command := range task.Commands {
+					pluginCmds, err := registry.GetCommands(command, nil)
+					testutil.HandleTestingErr(err, t, "Couldn't get plugin command: %v")
+					for _, cmd := range pluginCmds {
+						pluginCom := &comm.TaskJSONCommunicator{PluginName: cmd.Plugin(), TaskCommunicator: httpCom}
+						err = cmd.Execute(logger, pluginCom, taskConfig, make(chan bool))
+						So(err, ShouldBeNil)
+					}
+				}
+			}
+			So(taskConfig.Expansions.Get("before"), ShouldEqual, "none")
+			So(taskConfig.Expansions.Get("claimed"), ShouldEqual, "true")
+			So(taskConfig.Expansions.Get("claimed_again"), ShouldEqual, "false")
+			So(taskConfig.Expansions.Get("greeting"), ShouldEqual, "goodbye")
+			So(taskConfig.Expansions.Get("locked"), ShouldEqual, "true")
+
+			entry, err := keyval.GetEntry(keyval.EntryId{Namespace: taskConfig.Task.Project, Key: "docs_version"})
+			So(err, ShouldBeNil)
+			So(entry.Value, ShouldEqual, taskConfig.Task.Id)
+
+			released, err := keyval.ReleaseLock(keyval.EntryId{Namespace: taskConfig.Task.Project, Key: "publish"},
+				taskConfig.Task.Id)
+			So(err, ShouldBeNil)
+			So(released, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,199 @@
+package keyval
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+const (
+	LockCommandName   = "lock"
+	UnlockCommandName = "unlock"
+
+	LockRoute   = "lock"
+	UnlockRoute = "unlock"
+
+	// DefaultLockTTL is how long locks last if no ttl is given, so that a
+	// task that dies without releasing a lock does not hold it forever.
+	DefaultLockTTL = time.Hour
+
+	// lockPollInterval is how often a lock command that waits for a lock
+	// tries to take it.
+	lockPollInterval = 5 * time.Second
+)
+
+// LockKeyHandler takes the lock on the given key for the requesting task.
+func LockKeyHandler(w http.ResponseWriter, r *http.Request) {
+	req, id, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	ttl := time.Duration(req.TTLSecs) * time.Second
+	if ttl == 0 {
+		ttl = DefaultLockTTL
+	}
+	acquired, lock, err := AcquireLock(id, plugin.GetTask(r).Id, ttl)
+	if err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Error locking key: %v", err)
+		plugin.WriteJSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	plugin.WriteJSON(w, http.StatusOK, Response{Ok: acquired, Lock: lock})
+}
+
+// UnlockKeyHandler releases the lock on the given key if the requesting
+// task holds it.
+func UnlockKeyHandler(w http.ResponseWriter, r *http.Request) {
+	_, id, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+	released, err := ReleaseLock(id, plugin.GetTask(r).Id)
+	if err != nil {
+		evergreen.Logger.Logf(slogger.ERROR, "Error unlocking key: %v", err)
+		plugin.WriteJSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	plugin.WriteJSON(w, http.StatusOK, Response{Ok: released})
+}
+
+// LockCommand takes a lock on a key for the task, so that, e.g., only one
+// task in a version publishes its docs. Locks are held until they are
+// released with an unlock command or their ttl passes.
+type LockCommand struct {
+	Key string `mapstructure:"key" plugin:"expand"`
+
+	// TTLSecs is how long the lock lasts, or, if zero, DefaultLockTTL.
+	TTLSecs int `mapstructure:"ttl_secs"`
+
+	// WaitSecs is how long to wait for another task to release the lock.
+	WaitSecs int `mapstructure:"wait_secs"`
+
+	// Destination, if given, is an expansion to set to "true" or "false"
+	// according to whether the lock was taken. Otherwise, the command fails
+	// if the lock was not taken.
+	Destination string `mapstructure:"destination" plugin:"expand"`
+}
+
+func (self *LockCommand) Name() string {
+	return LockCommandName
+}
+
+func (self *LockCommand) Plugin() string {
+	return KeyValPluginName
+}
+
+// ParseParams validates the input to the LockCommand, returning an error
+// if something is incorrect. Fulfills Command interface.
+func (self *LockCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.Key == "" {
+		return errors.Errorf("error parsing '%v' params: key may not be blank", self.Name())
+	}
+	if self.TTLSecs < 0 || self.WaitSecs < 0 {
+		return errors.Errorf("error parsing '%v' params: ttl_secs and wait_secs may not be negative",
+			self.Name())
+	}
+	return nil
+}
+
+// Execute takes the lock from the API server, waiting for it if need be.
+func (self *LockCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator, conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+
+	deadline := time.Now().Add(time.Duration(self.WaitSecs) * time.Second)
+	for {
+		resp, err := postRequest(pluginCom, LockRoute, &Request{Key: self.Key, TTLSecs: self.TTLSecs})
+		if err != nil {
+			return err
+		}
+		if resp.Ok {
+			pluginLogger.LogTask(slogger.INFO, "Locked key '%v' until %v", self.Key, resp.Lock.ExpiresAt)
+			if self.Destination != "" {
+				conf.Expansions.Put(self.Destination, "true")
+			}
+			return nil
+		}
+
+		holder := "another task"
+		if resp.Lock != nil {
+			holder = resp.Lock.Owner
+		}
+		if time.Now().Before(deadline) {
+			pluginLogger.LogTask(slogger.INFO, "Key '%v' is locked by %v, waiting", self.Key, holder)
+			select {
+			case <-time.After(lockPollInterval):
+				continue
+			case <-stop:
+				return errors.New("lock command was stopped")
+			}
+		}
+
+		pluginLogger.LogTask(slogger.INFO, "Key '%v' is locked by %v", self.Key, holder)
+		if self.Destination != "" {
+			conf.Expansions.Put(self.Destination, "false")
+			return nil
+		}
+		return errors.Errorf("key '%v' is locked by %v", self.Key, holder)
+	}
+}
+
+// UnlockCommand releases a lock the task holds on a key.
+type UnlockCommand struct {
+	Key string `mapstructure:"key" plugin:"expand"`
+}
+
+func (self *UnlockCommand) Name() string {
+	return UnlockCommandName
+}
+
+func (self *UnlockCommand) Plugin() string {
+	return KeyValPluginName
+}
+
+// ParseParams validates the input to the UnlockCommand, returning an error
+// if something is incorrect. Fulfills Command interface.
+func (self *UnlockCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.Key == "" {
+		return errors.Errorf("error parsing '%v' params: key may not be blank", self.Name())
+	}
+	return nil
+}
+
+// Execute releases the lock on the API server. Releasing a lock the task
+// does not hold, e.g. one that has expired, is not an error.
+func (self *UnlockCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator, conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+
+	resp, err := postRequest(pluginCom, UnlockRoute, &Request{Key: self.Key})
+	if err != nil {
+		return err
+	}
+	if !resp.Ok {
+		pluginLogger.LogTask(slogger.WARN, "Key '%v' was not locked by this task", self.Key)
+		return nil
+	}
+	pluginLogger.LogTask(slogger.INFO, "Unlocked key '%v'", self.Key)
+	return nil
+}
@@ -0,0 +1,192 @@
+package keyval
+
+import (
+	"time"
+
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/db/bsonutil"
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	EntriesCollection = "keyval_plugin_entries"
+	LocksCollection   = "keyval_plugin_locks"
+)
+
+// EntryId identifies a key within a namespace, which is the project of the
+// tasks that use it.
+type EntryId struct {
+	Namespace string `bson:"namespace" json:"namespace"`
+	Key       string `bson:"key" json:"key"`
+}
+
+// Entry is a string value stored under a key. An entry whose expiration time
+// has passed is treated as though it does not exist.
+type Entry struct {
+	Id        EntryId   `bson:"_id" json:"id"`
+	Value     string    `bson:"value" json:"value"`
+	ExpiresAt time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+// Lock is held on a key by a task until the task releases it or it expires.
+type Lock struct {
+	Id        EntryId   `bson:"_id" json:"id"`
+	Owner     string    `bson:"owner" json:"owner"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+var (
+	// BSON fields for the Entry and Lock structs
+	IdKey        = bsonutil.MustHaveTag(Entry{}, "Id")
+	ValueKey     = bsonutil.MustHaveTag(Entry{}, "Value")
+	ExpiresAtKey = bsonutil.MustHaveTag(Entry{}, "ExpiresAt")
+	OwnerKey     = bsonutil.MustHaveTag(Lock{}, "Owner")
+)
+
+// live returns a query for the documents with the given id that have not
+// expired.
+func live(id EntryId, now time.Time) bson.M {
+	return bson.M{
+		IdKey: id,
+		"$or": []bson.M{
+			{ExpiresAtKey: bson.M{"$exists": false}},
+			{ExpiresAtKey: bson.M{"$gt": now}},
+		},
+	}
+}
+
+// setValue returns an update that sets an entry's value, and its expiration
+// time if ttl is positive.
+func setValue(value string, ttl time.Duration, now time.Time) bson.M {
+	if ttl > 0 {
+		return bson.M{"$set": bson.M{ValueKey: value, ExpiresAtKey: now.Add(ttl)}}
+	}
+	return bson.M{
+		"$set":   bson.M{ValueKey: value},
+		"$unset": bson.M{ExpiresAtKey: ""},
+	}
+}
+
+// GetEntry returns the entry for the given id, or nil if there is none.
+func GetEntry(id EntryId) (*Entry, error) {
+	entry := &Entry{}
+	err := db.FindOne(EntriesCollection, live(id, time.Now()), db.NoProjection, db.NoSort, entry)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error finding key '%v'", id.Key)
+	}
+	return entry, nil
+}
+
+// SetEntry sets the value of the given id, which expires after ttl if it is
+// positive.
+func SetEntry(id EntryId, value string, ttl time.Duration) (*Entry, error) {
+	entry := &Entry{}
+	change := mgo.Change{
+		Update:    setValue(value, ttl, time.Now()),
+		ReturnNew: true,
+		Upsert:    true,
+	}
+	if _, err := db.FindAndModify(EntriesCollection, bson.M{IdKey: id}, nil, change, entry); err != nil {
+		return nil, errors.Wrapf(err, "error setting key '%v'", id.Key)
+	}
+	return entry, nil
+}
+
+// CompareAndSwap atomically sets the value of the given id to value if its
+// current value is old, or, if old is nil, if it has no value. It returns
+// whether the value was set, along with the entry as it now stands.
+func CompareAndSwap(id EntryId, old *string, value string, ttl time.Duration) (bool, *Entry, error) {
+	now := time.Now()
+	change := mgo.Change{
+		Update:    setValue(value, ttl, now),
+		ReturnNew: true,
+	}
+
+	var query bson.M
+	if old == nil {
+		// an expired entry may be replaced, and a missing one inserted; if a
+		// live entry exists, the insert fails on its id
+		query = bson.M{IdKey: id, ExpiresAtKey: bson.M{"$lte": now}}
+		change.Upsert = true
+	} else {
+		query = live(id, now)
+		query[ValueKey] = *old
+	}
+
+	entry := &Entry{}
+	_, err := db.FindAndModify(EntriesCollection, query, nil, change, entry)
+	if err == nil {
+		return true, entry, nil
+	}
+	if err != mgo.ErrNotFound && !mgo.IsDup(err) {
+		return false, nil, errors.Wrapf(err, "error swapping key '%v'", id.Key)
+	}
+
+	current, err := GetEntry(id)
+	return false, current, err
+}
+
+// AcquireLock atomically takes the lock on the given id for owner, until
+// ttl passes, if no one else holds it. Owners may acquire locks they
+// already hold, which extends them. It returns whether the lock was
+// acquired, along with the lock as it now stands.
+func AcquireLock(id EntryId, owner string, ttl time.Duration) (bool, *Lock, error) {
+	if ttl <= 0 {
+		return false, nil, errors.New("locks must have a positive ttl")
+	}
+	now := time.Now()
+	query := bson.M{
+		IdKey: id,
+		"$or": []bson.M{
+			{OwnerKey: owner},
+			{ExpiresAtKey: bson.M{"$lte": now}},
+		},
+	}
+	change := mgo.Change{
+		Update:    bson.M{"$set": bson.M{OwnerKey: owner, ExpiresAtKey: now.Add(ttl)}},
+		ReturnNew: true,
+		Upsert:    true,
+	}
+
+	lock := &Lock{}
+	_, err := db.FindAndModify(LocksCollection, query, nil, change, lock)
+	if err == nil {
+		return true, lock, nil
+	}
+	if !mgo.IsDup(err) {
+		return false, nil, errors.Wrapf(err, "error locking key '%v'", id.Key)
+	}
+
+	// someone else holds the lock
+	lock = &Lock{}
+	err = db.FindOne(LocksCollection, bson.M{IdKey: id}, db.NoProjection, db.NoSort, lock)
+	if err == mgo.ErrNotFound {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, errors.Wrapf(err, "error finding lock on key '%v'", id.Key)
+	}
+	return false, lock, nil
+}
+
+// ReleaseLock releases the lock on the given id if owner holds it, and
+// returns whether it did.
+func ReleaseLock(id EntryId, owner string) (bool, error) {
+	err := db.Remove(LocksCollection, bson.M{
+		IdKey:        id,
+		OwnerKey:     owner,
+		ExpiresAtKey: bson.M{"$gt": time.Now()},
+	})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "error unlocking key '%v'", id.Key)
+	}
+	return true, nil
+}
@@ -0,0 +1,230 @@
+package keyval_test
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/db"
+	"github.com/evergreen-ci/evergreen/plugin/builtin/keyval"
+	"github.com/evergreen-ci/evergreen/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEntries(t *testing.T) {
+	Convey("With an empty store", t, func() {
+		testutil.HandleTestingErr(db.Clear(keyval.EntriesCollection), t, "Couldn't clear entries")
+		id := keyval.EntryId{Namespace: "project", Key: "docs"}
+
+		Convey("keys without values should have no entry", func() {
+			entry, err := keyval.GetEntry(id)
+			So(err, ShouldBeNil)
+			So(entry, ShouldBeNil)
+		})
+
+		Convey("set values should be namespaced by project", func() {
+			_, err := keyval.SetEntry(id, "v1", 0)
+			So(err, ShouldBeNil)
+			entry, err := keyval.GetEntry(id)
+			So(err, ShouldBeNil)
+			So(entry.Value, ShouldEqual, "v1")
+
+			other, err := keyval.GetEntry(keyval.EntryId{Namespace: "other", Key: "docs"})
+			So(err, ShouldBeNil)
+			So(other, ShouldBeNil)
+		})
+
+		Convey("values should expire after their ttl", func() {
+			_, err := keyval.SetEntry(id, "v1", 50*time.Millisecond)
+			So(err, ShouldBeNil)
+			time.Sleep(100 * time.Millisecond)
+			entry, err := keyval.GetEntry(id)
+			So(err, ShouldBeNil)
+			So(entry, ShouldBeNil)
+
+			swapped, _, err := keyval.CompareAndSwap(id, nil, "v2", 0)
+			So(err, ShouldBeNil)
+			So(swapped, ShouldBeTrue)
+		})
+
+		Convey("compare-and-swap should only set expected values", func() {
+			swapped, entry, err := keyval.CompareAndSwap(id, nil, "v1", 0)
+			So(err, ShouldBeNil)
+			So(swapped, ShouldBeTrue)
+			So(entry.Value, ShouldEqual, "v1")
+
+			swapped, entry, err = keyval.CompareAndSwap(id, nil, "v2", 0)
+			So(err, ShouldBeNil)
+			So(swapped, ShouldBeFalse)
+			So(entry.Value, ShouldEqual, "v1")
+
+			wrong := "v0"
+			swapped, _, err = keyval.CompareAndSwap(id, &wrong, "v2", 0)
+			So(err, ShouldBeNil)
+			So(swapped, ShouldBeFalse)
+
+			right := "v1"
+			swapped, entry, err = keyval.CompareAndSwap(id, &right, "v2", 0)
+			So(err, ShouldBeNil)
+			So(swapped, ShouldBeTrue)
+			So(entry.Value, ShouldEqual, "v2")
+		})
+
+		Convey("only one of many concurrent claims on a key should succeed", func() {
+			var wg sync.WaitGroup
+			results := make(chan bool, 20)
+			errs := make(chan error, 20)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					swapped, _, err := keyval.CompareAndSwap(id, nil, fmt.Sprintf("task%d", i), 0)
+					if err != nil {
+						errs <- err
+						return
+					}
+					results <- swapped
+				}(i)
+			}
+			wg.Wait()
+			close(results)
+			close(errs)
+
+			So(<-errs, ShouldBeNil)
+			successes := 0
+			for swapped := range results {
+				if swapped {
+					successes++
+				}
+			}
+			So(successes, ShouldEqual, 1)
+		})
+
+		Convey("concurrent compare-and-swap increments should not be lost", func() {
+			_, err := keyval.SetEntry(id, "0", 0)
+			So(err, ShouldBeNil)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 10)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for n := 0; n < 10; {
+						entry, err := keyval.GetEntry(id)
+						if err != nil {
+							errs <- err
+							return
+						}
+						value, err := strconv.Atoi(entry.Value)
+						if err != nil {
+							errs <- err
+							return
+						}
+						swapped, _, err := keyval.CompareAndSwap(id, &entry.Value, strconv.Itoa(value+1), 0)
+						if err != nil {
+							errs <- err
+							return
+						}
+						if swapped {
+							n++
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			So(<-errs, ShouldBeNil)
+
+			entry, err := keyval.GetEntry(id)
+			So(err, ShouldBeNil)
+			So(entry.Value, ShouldEqual, "100")
+		})
+	})
+}
+
+func TestLocks(t *testing.T) {
+	Convey("With no locks held", t, func() {
+		testutil.HandleTestingErr(db.Clear(keyval.LocksCollection), t, "Couldn't clear locks")
+		id := keyval.EntryId{Namespace: "project", Key: "publish"}
+
+		Convey("only one of many concurrent tasks should take a lock", func() {
+			var wg sync.WaitGroup
+			winners := make(chan string, 20)
+			errs := make(chan error, 20)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(owner string) {
+					defer wg.Done()
+					acquired, _, err := keyval.AcquireLock(id, owner, time.Minute)
+					if err != nil {
+						errs <- err
+						return
+					}
+					if acquired {
+						winners <- owner
+					}
+				}(fmt.Sprintf("task%d", i))
+			}
+			wg.Wait()
+			close(winners)
+			close(errs)
+			So(<-errs, ShouldBeNil)
+
+			owners := []string{}
+			for owner := range winners {
+				owners = append(owners, owner)
+			}
+			So(len(owners), ShouldEqual, 1)
+
+			Convey("and others should see who holds it", func() {
+				acquired, lock, err := keyval.AcquireLock(id, "late", time.Minute)
+				So(err, ShouldBeNil)
+				So(acquired, ShouldBeFalse)
+				So(lock.Owner, ShouldEqual, owners[0])
+			})
+
+			Convey("and the holder should be able to extend it", func() {
+				acquired, _, err := keyval.AcquireLock(id, owners[0], time.Minute)
+				So(err, ShouldBeNil)
+				So(acquired, ShouldBeTrue)
+			})
+
+			Convey("and only the holder should be able to release it", func() {
+				released, err := keyval.ReleaseLock(id, "late")
+				So(err, ShouldBeNil)
+				So(released, ShouldBeFalse)
+
+				released, err = keyval.ReleaseLock(id, owners[0])
+				So(err, ShouldBeNil)
+				So(released, ShouldBeTrue)
+
+				acquired, _, err := keyval.AcquireLock(id, "late", time.Minute)
+				So(err, ShouldBeNil)
+				So(acquired, ShouldBeTrue)
+			})
+		})
+
+		Convey("an expired lock should be free to take", func() {
+			acquired, _, err := keyval.AcquireLock(id, "first", 50*time.Millisecond)
+			So(err, ShouldBeNil)
+			So(acquired, ShouldBeTrue)
+			time.Sleep(100 * time.Millisecond)
+
+			acquired, _, err = keyval.AcquireLock(id, "second", time.Minute)
+			So(err, ShouldBeNil)
+			So(acquired, ShouldBeTrue)
+
+			released, err := keyval.ReleaseLock(id, "first")
+			So(err, ShouldBeNil)
+			So(released, ShouldBeFalse)
+		})
+
+		Convey("locks should require a ttl", func() {
+			_, _, err := keyval.AcquireLock(id, "first", 0)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
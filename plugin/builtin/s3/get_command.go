@@ -3,7 +3,6 @@ package s3
 import (
 	"archive/tar"
 	"compress/gzip"
-	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -11,9 +10,8 @@ import (
 	"github.com/evergreen-ci/evergreen/archive"
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/plugin"
-	"github.com/evergreen-ci/evergreen/thirdparty"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
 	"github.com/evergreen-ci/evergreen/util"
-	"github.com/goamz/goamz/aws"
 	"github.com/mitchellh/mapstructure"
 	"github.com/mongodb/grip/slogger"
 	"github.com/pkg/errors"
@@ -48,6 +46,15 @@ type S3GetCommand struct {
 	// downloaded to the specified directory.
 	LocalFile string `mapstructure:"local_file" plugin:"expand"`
 	ExtractTo string `mapstructure:"extract_to" plugin:"expand"`
+
+	// storeConf is the project's artifact store, which files are gotten from
+	// instead of S3 if set.
+	storeConf artifactstore.Config
+
+	// checksums are those of the last file downloaded, and verified is
+	// whether they were checked against the remote file.
+	checksums *artifactstore.Checksums
+	verified  bool
 }
 
 func (self *S3GetCommand) Name() string {
@@ -75,11 +82,14 @@ func (self *S3GetCommand) ParseParams(params map[string]interface{}) error {
 // Validate that all necessary params are set, and that only one of
 // local_file and extract_to is specified.
 func (self *S3GetCommand) validateParams() error {
-	if self.AwsKey == "" {
-		return errors.New("aws_key cannot be blank")
-	}
-	if self.AwsSecret == "" {
-		return errors.New("aws_secret cannot be blank")
+	// local stores don't use credentials
+	if self.storeConf.UsesCredentials() {
+		if self.AwsKey == "" {
+			return errors.New("aws_key cannot be blank")
+		}
+		if self.AwsSecret == "" {
+			return errors.New("aws_secret cannot be blank")
+		}
 	}
 	if self.RemoteFile == "" {
 		return errors.New("remote_file cannot be blank")
@@ -129,6 +139,8 @@ func (self *S3GetCommand) Execute(pluginLogger plugin.Logger,
 		return err
 	}
 
+	self.storeConf = projectStoreConfig(conf)
+
 	// validate the params
 	if err := self.validateParams(); err != nil {
 		return errors.Wrap(err, "expanded params are not valid")
@@ -178,6 +190,14 @@ func (self *S3GetCommand) GetWithRetry(pluginLogger plugin.Logger) error {
 					" s3 bucket: %v", err)
 				return util.RetriableError{err}
 			}
+			if self.verified {
+				pluginLogger.LogTask(slogger.INFO, "Verified %v: md5 %v, sha256 %v",
+					self.RemoteFile, self.checksums.MD5, self.checksums.SHA256)
+			} else {
+				pluginLogger.LogTask(slogger.WARN, "Could not verify %v against the"+
+					" remote file: md5 %v, sha256 %v",
+					self.RemoteFile, self.checksums.MD5, self.checksums.SHA256)
+			}
 			return nil
 		},
 	)
@@ -191,29 +211,22 @@ func (self *S3GetCommand) GetWithRetry(pluginLogger plugin.Logger) error {
 	return nil
 }
 
-// Fetch the specified resource from s3.
+// Fetch the specified resource from s3. An interrupted download is resumed
+// by the next call, and the download is checked against the remote file's
+// checksums where possible.
 func (self *S3GetCommand) Get() error {
-	// get the appropriate session and bucket
-	auth := &aws.Auth{
-		AccessKey: self.AwsKey,
-		SecretKey: self.AwsSecret,
-	}
-
-	session := thirdparty.NewS3Session(auth, aws.USEast)
-	bucket := session.Bucket(self.Bucket)
-
-	// get a reader for the bucket
-	reader, err := bucket.GetReader(self.RemoteFile)
+	store, err := artifactstore.New(self.storeConf, artifactstore.Credentials{
+		Key:    self.AwsKey,
+		Secret: self.AwsSecret,
+	})
 	if err != nil {
-		return errors.Wrapf(err, "error getting bucket reader for file %v", self.RemoteFile)
+		return errors.WithStack(err)
 	}
-	defer reader.Close()
 
 	// either untar the remote, or just write to a file
 	if self.LocalFile != "" {
-		var exists bool
 		// remove the file, if it exists
-		exists, err = util.FileExists(self.LocalFile)
+		exists, err := util.FileExists(self.LocalFile)
 		if err != nil {
 			return errors.Wrapf(err, "error checking existence of local file %v",
 				self.LocalFile)
@@ -224,16 +237,25 @@ func (self *S3GetCommand) Get() error {
 			}
 		}
 
-		// open the local file
-		file, err := os.Create(self.LocalFile)
-		if err != nil {
-			return errors.Wrapf(err, "error opening local file %v", self.LocalFile)
-		}
-		defer file.Close()
+		self.checksums, self.verified, err = store.Get(self.Bucket, self.RemoteFile, self.LocalFile)
+		return errors.WithStack(err)
+	}
 
-		_, err = io.Copy(file, reader)
+	// download the archive somewhere that outlives this attempt, so that the
+	// next one can resume it
+	archivePath := filepath.Join(os.TempDir(),
+		"s3_get_"+util.CleanForPath(self.Bucket+"/"+self.RemoteFile))
+	self.checksums, self.verified, err = store.Get(self.Bucket, self.RemoteFile, archivePath)
+	if err != nil {
 		return errors.WithStack(err)
 	}
+	defer os.Remove(archivePath)
+
+	reader, err := os.Open(archivePath)
+	if err != nil {
+		return errors.Wrapf(err, "error opening downloaded %v", self.RemoteFile)
+	}
+	defer reader.Close()
 
 	// wrap the reader in a gzip reader and a tar reader
 	gzipReader, err := gzip.NewReader(reader)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/evergreen-ci/evergreen/command"
 	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -43,6 +44,18 @@ func TestS3GetValidateParams(t *testing.T) {
 
 			})
 
+			Convey("missing aws credentials should be allowed for a local store", func() {
+
+				params := map[string]interface{}{
+					"remote_file": "remote",
+					"bucket":      "bck",
+					"local_file":  "local",
+				}
+				So(cmd.ParseParams(params), ShouldNotBeNil)
+				cmd.storeConf = artifactstore.Config{Type: artifactstore.LocalType, Path: "store"}
+				So(cmd.validateParams(), ShouldBeNil)
+			})
+
 			Convey("a missing remote file should cause an error", func() {
 
 				params := map[string]interface{}{
@@ -2,17 +2,17 @@ package s3
 
 import (
 	"fmt"
-	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/artifact"
 	"github.com/evergreen-ci/evergreen/plugin"
 	"github.com/evergreen-ci/evergreen/thirdparty"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
 	"github.com/evergreen-ci/evergreen/util"
-	"github.com/goamz/goamz/aws"
 	"github.com/mitchellh/mapstructure"
 	"github.com/mongodb/grip"
 	"github.com/mongodb/grip/slogger"
@@ -22,7 +22,12 @@ import (
 var (
 	maxS3PutAttempts = 5
 	s3PutSleep       = 5 * time.Second
-	s3baseURL        = "https://s3.amazonaws.com/"
+)
+
+const (
+	// defaultS3PutConcurrency is how many files, and how many parts of each
+	// large file, are uploaded at once unless otherwise specified.
+	defaultS3PutConcurrency = 4
 )
 
 var errSkippedFile = errors.New("missing optional file was skipped")
@@ -79,6 +84,28 @@ type S3PutCommand struct {
 	// the path specified in local_file does not exist. Defaults to false, which triggers errors
 	// for missing files.
 	Optional bool `mapstructure:"optional"`
+
+	// PreservePath, when set to true, names the files uploaded via
+	// LocalFilesIncludeFilter after their path relative to the working
+	// directory, rather than just their base name.
+	PreservePath bool `mapstructure:"preserve_path"`
+
+	// Concurrency is how many files, and how many parts of a large file, are
+	// uploaded at once. Defaults to 4.
+	Concurrency int `mapstructure:"concurrency"`
+
+	// PartSizeMB is the size, in MB, of the parts that files larger than it
+	// are uploaded in. Defaults to 16, and must be at least 5.
+	PartSizeMB int `mapstructure:"part_size_mb"`
+
+	// storeConf is the project's artifact store, which files are put to
+	// instead of S3 if set.
+	storeConf artifactstore.Config
+
+	// checksums holds the checksums of the files uploaded so far, by their
+	// local path, so that retries don't upload them again.
+	checksums   map[string]*artifactstore.Checksums
+	checksumsMu sync.Mutex
 }
 
 func (s3pc *S3PutCommand) Name() string {
@@ -105,11 +132,14 @@ func (s3pc *S3PutCommand) ParseParams(params map[string]interface{}) error {
 
 // Validate that all necessary params are set and valid.
 func (s3pc *S3PutCommand) validateParams() error {
-	if s3pc.AwsKey == "" {
-		return errors.New("aws_key cannot be blank")
-	}
-	if s3pc.AwsSecret == "" {
-		return errors.New("aws_secret cannot be blank")
+	// local stores don't use credentials
+	if s3pc.storeConf.UsesCredentials() {
+		if s3pc.AwsKey == "" {
+			return errors.New("aws_key cannot be blank")
+		}
+		if s3pc.AwsSecret == "" {
+			return errors.New("aws_secret cannot be blank")
+		}
 	}
 	if s3pc.LocalFile == "" && len(s3pc.LocalFilesIncludeFilter) == 0 {
 		return errors.New("local_file and local_files_include_filter cannot both be blank")
@@ -120,6 +150,15 @@ func (s3pc *S3PutCommand) validateParams() error {
 	if s3pc.Optional && len(s3pc.LocalFilesIncludeFilter) != 0 {
 		return errors.New("cannot use optional upload with local_files_include_filter")
 	}
+	if s3pc.PreservePath && len(s3pc.LocalFilesIncludeFilter) == 0 {
+		return errors.New("preserve_path can only be used with local_files_include_filter")
+	}
+	if s3pc.Concurrency < 0 {
+		return errors.New("concurrency cannot be negative")
+	}
+	if s3pc.PartSizeMB != 0 && s3pc.PartSizeMB*1024*1024 < thirdparty.S3MinPartSize {
+		return errors.Errorf("part_size_mb must be at least %d", thirdparty.S3MinPartSize/(1024*1024))
+	}
 	if s3pc.RemoteFile == "" {
 		return errors.New("remote_file cannot be blank")
 	}
@@ -176,6 +215,8 @@ func (s3pc *S3PutCommand) Execute(log plugin.Logger,
 		return errors.WithStack(err)
 	}
 
+	s3pc.storeConf = projectStoreConfig(conf)
+
 	// validate the params
 	if err := s3pc.validateParams(); err != nil {
 		return errors.Wrap(err, "expanded params are not valid")
@@ -251,7 +292,8 @@ func (s3pc *S3PutCommand) PutWithRetry(log plugin.Logger, com plugin.PluginCommu
 	return nil
 }
 
-// Put the specified resource to s3.
+// Put the specified resource to s3. When putting multiple files, several are
+// uploaded at once, and files already put by an earlier attempt are skipped.
 func (s3pc *S3PutCommand) Put() ([]string, error) {
 	var err error
 
@@ -262,37 +304,105 @@ func (s3pc *S3PutCommand) Put() ([]string, error) {
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
-	}
-	for _, fpath := range filesList {
-		remoteName := s3pc.RemoteFile
-		if s3pc.isMulti() {
-			fname := filepath.Base(fpath)
-			remoteName = fmt.Sprintf("%s%s", s3pc.RemoteFile, fname)
+	} else if s3pc.Optional {
+		if _, err = os.Stat(s3pc.LocalFile); os.IsNotExist(err) {
+			// important to *not* wrap this error.
+			return nil, errSkippedFile
 		}
+	}
 
-		auth := &aws.Auth{
-			AccessKey: s3pc.AwsKey,
-			SecretKey: s3pc.AwsSecret,
-		}
-		s3URL := url.URL{
-			Scheme: "s3",
-			Host:   s3pc.Bucket,
-			Path:   remoteName,
+	store, err := s3pc.store()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	concurrency := s3pc.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultS3PutConcurrency
+	}
+	opts := artifactstore.PutOptions{
+		ContentType: s3pc.ContentType,
+		Permissions: s3pc.Permissions,
+		PartSize:    int64(s3pc.PartSizeMB) * 1024 * 1024,
+		Concurrency: concurrency,
+	}
+
+	toPut := make(chan string, len(filesList))
+	for _, fpath := range filesList {
+		if s3pc.getChecksums(fpath) == nil {
+			toPut <- fpath
 		}
-		err := thirdparty.PutS3File(auth, fpath, s3URL.String(), s3pc.ContentType, s3pc.Permissions)
-		if err != nil {
-			if !s3pc.isMulti() {
-				if s3pc.Optional && os.IsNotExist(err) {
-					// important to *not* wrap this error.
-					return nil, errSkippedFile
+	}
+	close(toPut)
+
+	catcher := grip.NewCatcher()
+	var catcherMu sync.Mutex
+	wg := &sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fpath := range toPut {
+				sums, err := store.Put(s3pc.Bucket, s3pc.remoteFileName(fpath), fpath, opts)
+				if err != nil {
+					catcherMu.Lock()
+					catcher.Add(errors.Wrapf(err, "error putting %v", fpath))
+					catcherMu.Unlock()
+					continue
 				}
+				s3pc.setChecksums(fpath, sums)
 			}
-			return nil, errors.WithStack(err)
-		}
+		}()
+	}
+	wg.Wait()
+
+	if catcher.HasErrors() {
+		return nil, catcher.Resolve()
 	}
 	return filesList, nil
 }
 
+// store returns the artifact store files are put to.
+func (s3pc *S3PutCommand) store() (artifactstore.Store, error) {
+	return artifactstore.New(s3pc.storeConf, artifactstore.Credentials{
+		Key:    s3pc.AwsKey,
+		Secret: s3pc.AwsSecret,
+	})
+}
+
+func (s3pc *S3PutCommand) getChecksums(localFile string) *artifactstore.Checksums {
+	s3pc.checksumsMu.Lock()
+	defer s3pc.checksumsMu.Unlock()
+	return s3pc.checksums[localFile]
+}
+
+func (s3pc *S3PutCommand) setChecksums(localFile string, sums *artifactstore.Checksums) {
+	s3pc.checksumsMu.Lock()
+	defer s3pc.checksumsMu.Unlock()
+	if s3pc.checksums == nil {
+		s3pc.checksums = map[string]*artifactstore.Checksums{}
+	}
+	s3pc.checksums[localFile] = sums
+}
+
+// fileName returns the name a local file is known by when putting multiple
+// files: its path if paths are preserved, or else its base name.
+func (s3pc *S3PutCommand) fileName(localFile string) string {
+	if s3pc.PreservePath {
+		return filepath.ToSlash(filepath.Clean(localFile))
+	}
+	return filepath.Base(localFile)
+}
+
+// remoteFileName returns the path within the bucket that a local file is put
+// to.
+func (s3pc *S3PutCommand) remoteFileName(localFile string) string {
+	if s3pc.isMulti() {
+		return fmt.Sprintf("%s%s", s3pc.RemoteFile, s3pc.fileName(localFile))
+	}
+	return s3pc.RemoteFile
+}
+
 // AttachTaskFiles is responsible for sending the
 // specified file to the API Server. Does not support multiple file putting.
 func (s3pc *S3PutCommand) AttachTaskFiles(log plugin.Logger,
@@ -300,23 +410,33 @@ func (s3pc *S3PutCommand) AttachTaskFiles(log plugin.Logger,
 
 	remoteFileName := filepath.ToSlash(remoteFile)
 	if s3pc.isMulti() {
-		remoteFileName = fmt.Sprintf("%s%s", remoteFile, filepath.Base(localFile))
+		remoteFileName = fmt.Sprintf("%s%s", remoteFile, s3pc.fileName(localFile))
 	}
 
-	fileLink := s3baseURL + s3pc.Bucket + "/" + remoteFileName
+	store, err := s3pc.store()
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
 	displayName := s3pc.DisplayName
 	if s3pc.isMulti() || displayName == "" {
-		displayName = fmt.Sprintf("%s %s", s3pc.DisplayName, filepath.Base(localFile))
+		displayName = fmt.Sprintf("%s %s", s3pc.DisplayName, s3pc.fileName(localFile))
 	}
 
 	file := &artifact.File{
 		Name:       displayName,
-		Link:       fileLink,
+		Link:       store.Link(s3pc.Bucket, remoteFileName),
 		Visibility: s3pc.Visibility,
+		Backend:    store.Type(),
+		Bucket:     s3pc.Bucket,
+		Key:        remoteFileName,
+	}
+	if sums := s3pc.getChecksums(localFile); sums != nil {
+		file.ContentMD5 = sums.MD5
+		file.ContentSHA256 = sums.SHA256
 	}
 
-	err := com.PostTaskFiles([]*artifact.File{file})
+	err = com.PostTaskFiles([]*artifact.File{file})
 	if err != nil {
 		return errors.Wrap(err, "Attach files failed")
 	}
@@ -1,11 +1,19 @@
 package s3
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/evergreen-ci/evergreen/command"
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/artifact"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/plugin/plugintest"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
+	"github.com/evergreen-ci/evergreen/thirdparty/s3stub"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -33,6 +41,20 @@ func TestS3PutValidateParams(t *testing.T) {
 				So(cmd.ParseParams(params), ShouldNotBeNil)
 				So(cmd.validateParams(), ShouldNotBeNil)
 			})
+			Convey("missing aws credentials should be allowed for a local store", func() {
+
+				params := map[string]interface{}{
+					"local_file":   "local",
+					"remote_file":  "remote",
+					"bucket":       "bck",
+					"permissions":  "public-read",
+					"content_type": "application/x-tar",
+					"display_name": "test_file",
+				}
+				So(cmd.ParseParams(params), ShouldNotBeNil)
+				cmd.storeConf = artifactstore.Config{Type: artifactstore.LocalType, Path: "store"}
+				So(cmd.validateParams(), ShouldBeNil)
+			})
 			Convey("a defined local file and inclusion filter should cause an error", func() {
 
 				params := map[string]interface{}{
@@ -275,3 +297,99 @@ func TestExpandS3PutParams(t *testing.T) {
 
 	})
 }
+
+// s3baseURL is where files put to S3 are linked to.
+const s3baseURL = "https://s3.amazonaws.com/"
+
+// filesCommunicator records the files attached through it.
+type filesCommunicator struct {
+	plugin.PluginCommunicator
+	files []*artifact.File
+}
+
+func (c *filesCommunicator) PostTaskFiles(files []*artifact.File) error {
+	c.files = append(c.files, files...)
+	return nil
+}
+
+func TestS3PutFiles(t *testing.T) {
+	Convey("With a local S3 server and files to put", t, func() {
+		server := s3stub.NewServer()
+		defer server.Close()
+
+		dir, err := ioutil.TempDir("", "s3_put_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		wd, err := os.Getwd()
+		So(err, ShouldBeNil)
+		So(os.Chdir(dir), ShouldBeNil)
+		defer os.Chdir(wd)
+
+		So(os.MkdirAll(filepath.Join("build", "docs"), 0755), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join("build", "a.txt"), []byte("a"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join("build", "docs", "b.txt"), []byte("b"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join("build", "c.log"), []byte("c"), 0644), ShouldBeNil)
+
+		cmd := &S3PutCommand{
+			AwsKey:                  "key",
+			AwsSecret:               "secret",
+			LocalFilesIncludeFilter: []string{"*.txt"},
+			RemoteFile:              "prefix/",
+			Bucket:                  "bucket",
+			Permissions:             "private",
+			ContentType:             "text/plain",
+			DisplayName:             "docs",
+			Concurrency:             2,
+			storeConf: artifactstore.Config{
+				Endpoint: server.URL,
+				BaseURL:  s3baseURL,
+			},
+		}
+		com := &filesCommunicator{}
+
+		Convey("matching files should be put under their base names", func() {
+			So(cmd.PutWithRetry(&plugintest.MockLogger{}, com), ShouldBeNil)
+			So(server.Object("bucket", "prefix/a.txt"), ShouldNotBeNil)
+			So(server.Object("bucket", "prefix/b.txt"), ShouldNotBeNil)
+			So(server.Object("bucket", "prefix/c.log"), ShouldBeNil)
+			So(len(com.files), ShouldEqual, 2)
+		})
+
+		Convey("matching files should be put under their paths if asked", func() {
+			cmd.PreservePath = true
+			So(cmd.PutWithRetry(&plugintest.MockLogger{}, com), ShouldBeNil)
+			So(server.Object("bucket", "prefix/build/a.txt"), ShouldNotBeNil)
+			So(server.Object("bucket", "prefix/build/docs/b.txt"), ShouldNotBeNil)
+
+			So(len(com.files), ShouldEqual, 2)
+			names := []string{com.files[0].Name, com.files[1].Name}
+			sort.Strings(names)
+			So(names, ShouldResemble, []string{"docs build/a.txt", "docs build/docs/b.txt"})
+			for _, file := range com.files {
+				So(file.Link, ShouldStartWith, s3baseURL+"bucket/prefix/build/")
+				So(file.ContentMD5, ShouldHaveLength, 32)
+				So(file.ContentSHA256, ShouldHaveLength, 64)
+			}
+		})
+
+		Convey("files should be put to the project's artifact store if it has one", func() {
+			cmd.storeConf = artifactstore.Config{
+				Type:    artifactstore.LocalType,
+				Path:    filepath.Join(dir, "store"),
+				BaseURL: "http://artifacts.example.com",
+			}
+			So(cmd.PutWithRetry(&plugintest.MockLogger{}, com), ShouldBeNil)
+			So(server.Object("bucket", "prefix/a.txt"), ShouldBeNil)
+			_, err := os.Stat(filepath.Join(dir, "store", "bucket", "prefix", "a.txt"))
+			So(err, ShouldBeNil)
+
+			So(len(com.files), ShouldEqual, 2)
+			for _, file := range com.files {
+				So(file.Link, ShouldStartWith, "http://artifacts.example.com/bucket/prefix/")
+				So(file.Backend, ShouldEqual, artifactstore.LocalType)
+				So(file.Bucket, ShouldEqual, "bucket")
+				So(file.Key, ShouldStartWith, "prefix/")
+			}
+		})
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
 	"github.com/evergreen-ci/evergreen/util"
 	"github.com/goamz/goamz/s3"
 	"github.com/pkg/errors"
@@ -46,6 +48,15 @@ func (self *S3Plugin) NewCommand(cmdName string) (plugin.Command, error) {
 	return nil, errors.Errorf("No such command: %v", cmdName)
 }
 
+// projectStoreConfig returns the config of the artifact store the task's
+// project keeps its files in.
+func projectStoreConfig(conf *model.TaskConfig) artifactstore.Config {
+	if conf == nil || conf.ProjectRef == nil {
+		return artifactstore.Config{}
+	}
+	return conf.ProjectRef.ArtifactStore
+}
+
 func validateS3BucketName(bucket string) error {
 	// if it's an expandable string, we can't expand yet since we don't have
 	// access to the task config expansions. So, we defer till during runtime
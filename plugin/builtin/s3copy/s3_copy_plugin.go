@@ -12,9 +12,8 @@ import (
 	"github.com/evergreen-ci/evergreen/model/artifact"
 	"github.com/evergreen-ci/evergreen/model/version"
 	"github.com/evergreen-ci/evergreen/plugin"
-	"github.com/evergreen-ci/evergreen/thirdparty"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
 	"github.com/evergreen-ci/evergreen/util"
-	"github.com/goamz/goamz/aws"
 	"github.com/goamz/goamz/s3"
 	"github.com/mitchellh/mapstructure"
 	"github.com/mongodb/grip"
@@ -30,7 +29,6 @@ const (
 	s3CopyCmd         = "copy"
 	s3CopyPluginName  = "s3Copy"
 	s3CopyAPIEndpoint = "s3Copy"
-	s3baseURL         = "https://s3.amazonaws.com/"
 
 	s3CopyRetrySleepTimeSec = 5
 	s3CopyRetryNumRetries   = 5
@@ -59,10 +57,24 @@ type S3CopyCommand struct {
 
 	// An array of file copy configurations
 	S3CopyFiles []*s3CopyFile `mapstructure:"s3_copy_files" plugin:"expand"`
+
+	// storeConf is the project's artifact store, which files are copied
+	// within instead of S3 if set.
+	storeConf artifactstore.Config
 }
 
 // S3CopyPlugin is used to copy files around in s3
-type S3CopyPlugin struct{}
+type S3CopyPlugin struct {
+	// AllowedEndpoints are the endpoints of S3, GCS and Azure services the
+	// API server will copy files within, for projects whose artifact stores
+	// override their service's endpoint.
+	AllowedEndpoints []string
+}
+
+// s3CopyParams are the settings of the plugin in the server's config.
+type s3CopyParams struct {
+	AllowedEndpoints []string `mapstructure:"allowed_endpoints"`
+}
 
 type s3CopyFile struct {
 	// Each source and destination is specified in the
@@ -107,12 +119,17 @@ func (scp *S3CopyPlugin) Name() string {
 
 func (scp *S3CopyPlugin) GetAPIHandler() http.Handler {
 	r := http.NewServeMux()
-	r.HandleFunc(fmt.Sprintf("/%v", s3CopyAPIEndpoint), S3CopyHandler) // POST
+	r.HandleFunc(fmt.Sprintf("/%v", s3CopyAPIEndpoint), scp.S3CopyHandler) // POST
 	r.HandleFunc("/", http.NotFound)
 	return r
 }
 
-func (self *S3CopyPlugin) Configure(map[string]interface{}) error {
+func (scp *S3CopyPlugin) Configure(conf map[string]interface{}) error {
+	params := &s3CopyParams{}
+	if err := mapstructure.Decode(conf, params); err != nil {
+		return errors.Wrapf(err, "error decoding %v settings", s3CopyPluginName)
+	}
+	scp.AllowedEndpoints = params.AllowedEndpoints
 	return nil
 }
 
@@ -233,6 +250,9 @@ func (scc *S3CopyCommand) Execute(pluginLogger plugin.Logger,
 // production destination
 func (scc *S3CopyCommand) S3Copy(taskConfig *model.TaskConfig,
 	pluginLogger plugin.Logger, pluginCom plugin.PluginCommunicator) error {
+	if taskConfig.ProjectRef != nil {
+		scc.storeConf = taskConfig.ProjectRef.ArtifactStore
+	}
 	for _, s3CopyFile := range scc.S3CopyFiles {
 		if len(s3CopyFile.BuildVariants) > 0 && !util.SliceContains(
 			s3CopyFile.BuildVariants, taskConfig.BuildVariant.Name) {
@@ -253,6 +273,23 @@ func (scc *S3CopyCommand) S3Copy(taskConfig *model.TaskConfig,
 			S3DestinationPath:   s3CopyFile.Destination.Path,
 			S3DisplayName:       s3CopyFile.DisplayName,
 		}
+
+		if !copiesOnServer(scc.storeConf) {
+			if err := scc.copyOnAgent(pluginLogger, s3CopyReq); err != nil {
+				if s3CopyFile.Optional {
+					pluginLogger.LogExecution(slogger.ERROR,
+						"ignoring optional file, which encountered error: %+v",
+						err.Error())
+					continue
+				}
+				return errors.WithStack(err)
+			}
+			if err := scc.AttachTaskFiles(pluginLogger, pluginCom, s3CopyReq); err != nil {
+				return errors.WithStack(err)
+			}
+			continue
+		}
+
 		resp, err := pluginCom.TaskPostJSON(s3CopyAPIEndpoint, s3CopyReq)
 		if resp != nil {
 			defer resp.Body.Close()
@@ -298,10 +335,47 @@ func (scc *S3CopyCommand) S3Copy(taskConfig *model.TaskConfig,
 	return nil
 }
 
+// copyOnAgent copies a file within a local or HTTP artifact store from the
+// agent, which the API server won't do. There's no push log for such copies,
+// since it's kept by the API server, so a copy from an older version isn't
+// kept from replacing one from a newer version.
+func (scc *S3CopyCommand) copyOnAgent(pluginLogger plugin.Logger, request S3CopyRequest) error {
+	store, err := artifactstore.New(scc.storeConf, artifactstore.Credentials{
+		Key:    request.AwsKey,
+		Secret: request.AwsSecret,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	pluginLogger.LogExecution(slogger.INFO, "Copying within %s artifact store: %v/%v => %v/%v",
+		store.Type(), request.S3SourceBucket, request.S3SourcePath,
+		request.S3DestinationBucket, request.S3DestinationPath)
+	_, err = util.Retry(func() error {
+		err := store.Copy(
+			request.S3SourceBucket,
+			request.S3SourcePath,
+			request.S3DestinationBucket,
+			request.S3DestinationPath,
+			artifactstore.PutOptions{},
+		)
+		if err != nil {
+			pluginLogger.LogExecution(slogger.ERROR, "copy failed, retrying: %v", err)
+			return util.RetriableError{err}
+		}
+		return nil
+	}, s3CopyRetryNumRetries, s3CopyRetrySleepTimeSec*time.Second)
+	if err != nil {
+		return errors.Wrap(err, "copy failed")
+	}
+	pluginLogger.LogExecution(slogger.INFO, "copy succeeded")
+	return nil
+}
+
 // Takes a request for a task's file to be copied from
 // one s3 location to another. Ensures that if the destination
 // file path already exists, no file copy is performed.
-func S3CopyHandler(w http.ResponseWriter, r *http.Request) {
+func (scp *S3CopyPlugin) S3CopyHandler(w http.ResponseWriter, r *http.Request) {
 	task := plugin.GetTask(r)
 	if task == nil {
 		http.Error(w, "task not found", http.StatusNotFound)
@@ -315,6 +389,24 @@ func S3CopyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The copy runs on the API server, so it may only use stores whose
+	// copies stay within the store's own service, at an allowed endpoint
+	projectRef, err := model.FindOneProjectRef(task.Project)
+	if err != nil {
+		grip.Errorf("error finding project ref for task %s: %+v", task.Id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	storeConf := artifactstore.Config{}
+	if projectRef != nil {
+		storeConf = projectRef.ArtifactStore
+	}
+	if err = validateServerSideCopy(storeConf, scp.AllowedEndpoints); err != nil {
+		grip.Warningf("refusing copy for task %s: %v", task.Id, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Get the version for this task, so we can check if it has
 	// any already-done pushes
 	v, err := version.FindOne(version.ById(task.Version))
@@ -357,21 +449,27 @@ func S3CopyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Now copy the file into the permanent location
-	auth := &aws.Auth{
-		AccessKey: s3CopyReq.AwsKey,
-		SecretKey: s3CopyReq.AwsSecret,
+	// Now copy the file into the permanent location, within the project's
+	// artifact store
+	store, err := artifactstore.New(storeConf, artifactstore.Credentials{
+		Key:    s3CopyReq.AwsKey,
+		Secret: s3CopyReq.AwsSecret,
+	})
+	if err != nil {
+		grip.Errorf("error creating artifact store for task %s: %+v", task.Id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	grip.Infof("performing S3 copy: '%s' => '%s'", copyFromLocation, copyToLocation)
+	grip.Infof("performing %s copy: '%s' => '%s'", store.Type(), copyFromLocation, copyToLocation)
 
 	_, err = util.Retry(func() error {
-		err = errors.WithStack(thirdparty.S3CopyFile(auth,
+		err = errors.WithStack(store.Copy(
 			s3CopyReq.S3SourceBucket,
 			s3CopyReq.S3SourcePath,
 			s3CopyReq.S3DestinationBucket,
 			s3CopyReq.S3DestinationPath,
-			string(s3.PublicRead),
+			artifactstore.PutOptions{Permissions: string(s3.PublicRead)},
 		))
 		if err != nil {
 			grip.Errorf("S3 copy failed for task %s, retrying: %+v", task.Id, err)
@@ -404,7 +502,13 @@ func (c *S3CopyCommand) AttachTaskFiles(pluginLogger plugin.Logger,
 	pluginCom plugin.PluginCommunicator, request S3CopyRequest) error {
 
 	remotePath := filepath.ToSlash(request.S3DestinationPath)
-	fileLink := s3baseURL + request.S3DestinationBucket + "/" + remotePath
+	store, err := artifactstore.New(c.storeConf, artifactstore.Credentials{
+		Key:    request.AwsKey,
+		Secret: request.AwsSecret,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
 
 	displayName := request.S3DisplayName
 
@@ -414,13 +518,16 @@ func (c *S3CopyCommand) AttachTaskFiles(pluginLogger plugin.Logger,
 
 	pluginLogger.LogExecution(slogger.INFO, "attaching file with name %v", displayName)
 	file := artifact.File{
-		Name: displayName,
-		Link: fileLink,
+		Name:    displayName,
+		Link:    store.Link(request.S3DestinationBucket, remotePath),
+		Backend: store.Type(),
+		Bucket:  request.S3DestinationBucket,
+		Key:     remotePath,
 	}
 
 	files := []*artifact.File{&file}
 
-	err := pluginCom.PostTaskFiles(files)
+	err = pluginCom.PostTaskFiles(files)
 	if err != nil {
 		return errors.Wrap(err, "Attach files failed")
 	}
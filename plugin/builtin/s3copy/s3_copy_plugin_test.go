@@ -1,6 +1,10 @@
 package s3copy_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"testing"
 
@@ -8,6 +12,7 @@ import (
 	agentutil "github.com/evergreen-ci/evergreen/agent/testutil"
 	"github.com/evergreen-ci/evergreen/db"
 	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/task"
 	modelutil "github.com/evergreen-ci/evergreen/model/testutil"
 	"github.com/evergreen-ci/evergreen/model/version"
 	"github.com/evergreen-ci/evergreen/plugin"
@@ -16,6 +21,7 @@ import (
 	"github.com/evergreen-ci/evergreen/plugin/plugintest"
 	"github.com/evergreen-ci/evergreen/service"
 	"github.com/evergreen-ci/evergreen/testutil"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
 	"github.com/mongodb/grip/slogger"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -73,3 +79,87 @@ func TestS3CopyPluginExecution(t *testing.T) {
 		})
 	})
 }
+
+func TestS3CopyHandlerRefusesLocalStores(t *testing.T) {
+	testConfig := testutil.TestConfig()
+	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(testConfig))
+
+	Convey("With a project whose artifact store is local", t, func() {
+		testutil.HandleTestingErr(
+			db.ClearCollections(model.PushlogCollection, version.Collection, model.ProjectRefCollection), t,
+			"error clearing test collections")
+		projectRef := &model.ProjectRef{
+			Identifier:    "local-store",
+			ArtifactStore: artifactstore.Config{Type: artifactstore.LocalType, Path: "/"},
+		}
+		So(projectRef.Insert(), ShouldBeNil)
+		So((&version.Version{Id: "v"}).Insert(), ShouldBeNil)
+
+		Convey("the copy handler should refuse to copy within it", func() {
+			body, err := json.Marshal(S3CopyRequest{
+				S3SourceBucket:      "etc",
+				S3SourcePath:        "passwd",
+				S3DestinationBucket: "tmp",
+				S3DestinationPath:   "passwd",
+			})
+			So(err, ShouldBeNil)
+			r, err := http.NewRequest("POST", "/s3Copy/s3Copy", bytes.NewReader(body))
+			So(err, ShouldBeNil)
+			plugin.SetTask(r, &task.Task{Id: "t", Project: projectRef.Identifier, Version: "v"})
+			w := httptest.NewRecorder()
+
+			(&S3CopyPlugin{}).S3CopyHandler(w, r)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+			pushLog, err := model.FindPushLogAfter("tmp/passwd", 0)
+			So(err, ShouldBeNil)
+			So(pushLog, ShouldBeNil)
+		})
+	})
+}
+
+func TestS3CopyHandlerRefusesUnlistedEndpoints(t *testing.T) {
+	testConfig := testutil.TestConfig()
+	db.SetGlobalSessionProvider(db.SessionFactoryFromConfig(testConfig))
+
+	Convey("With a project whose S3 store has its own endpoint", t, func() {
+		testutil.HandleTestingErr(
+			db.ClearCollections(model.PushlogCollection, version.Collection, model.ProjectRefCollection), t,
+			"error clearing test collections")
+		projectRef := &model.ProjectRef{
+			Identifier:    "s3-endpoint",
+			ArtifactStore: artifactstore.Config{Type: artifactstore.S3Type, Endpoint: "http://169.254.169.254"},
+		}
+		So(projectRef.Insert(), ShouldBeNil)
+		So((&version.Version{Id: "v"}).Insert(), ShouldBeNil)
+
+		Convey("the copy handler should refuse to copy unless the endpoint is allowed", func() {
+			s3CopyPlugin := &S3CopyPlugin{}
+			So(s3CopyPlugin.Configure(map[string]interface{}{
+				"allowed_endpoints": []string{"https://storage.example.com"},
+			}), ShouldBeNil)
+			So(s3CopyPlugin.AllowedEndpoints, ShouldResemble, []string{"https://storage.example.com"})
+
+			body, err := json.Marshal(S3CopyRequest{
+				AwsKey:              "key",
+				AwsSecret:           "secret",
+				S3SourceBucket:      "bucket",
+				S3SourcePath:        "source",
+				S3DestinationBucket: "bucket",
+				S3DestinationPath:   "destination",
+			})
+			So(err, ShouldBeNil)
+			r, err := http.NewRequest("POST", "/s3Copy/s3Copy", bytes.NewReader(body))
+			So(err, ShouldBeNil)
+			plugin.SetTask(r, &task.Task{Id: "t", Project: projectRef.Identifier, Version: "v"})
+			w := httptest.NewRecorder()
+
+			s3CopyPlugin.S3CopyHandler(w, r)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+
+			pushLog, err := model.FindPushLogAfter("bucket/destination", 0)
+			So(err, ShouldBeNil)
+			So(pushLog, ShouldBeNil)
+		})
+	})
+}
@@ -1,10 +1,12 @@
 package s3copy
 
 import (
+	"net/url"
 	"regexp"
 	"strings"
 
 	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
 	"github.com/pkg/errors"
 )
 
@@ -12,6 +14,54 @@ var (
 	BucketNameRegex = regexp.MustCompile(`^[a-z0-9\-.]+$`)
 )
 
+// copiesOnServer returns whether copies within the given store are done by
+// the API server. Copies within local and HTTP stores are done by the agent
+// instead, since doing them on the server would let a project read or write
+// the server's own files, or make requests from the server to any address.
+func copiesOnServer(conf artifactstore.Config) bool {
+	switch conf.StoreType() {
+	case artifactstore.S3Type, artifactstore.GCSType, artifactstore.AzureType:
+		return true
+	}
+	return false
+}
+
+// validateServerSideCopy returns an error if copies within the given store
+// may not be done by the API server: if it isn't one whose copies are done
+// there, or if it overrides its service's endpoint with one that isn't among
+// the allowed endpoints.
+func validateServerSideCopy(conf artifactstore.Config, allowedEndpoints []string) error {
+	if !copiesOnServer(conf) {
+		return errors.Errorf("copies within %s artifact stores are done by the agent", conf.StoreType())
+	}
+	if conf.Endpoint == "" {
+		return nil
+	}
+	endpoint, err := endpointOrigin(conf.Endpoint)
+	if err != nil {
+		return errors.Wrapf(err, "invalid endpoint '%s'", conf.Endpoint)
+	}
+	for _, allowed := range allowedEndpoints {
+		if origin, err := endpointOrigin(allowed); err == nil && origin == endpoint {
+			return nil
+		}
+	}
+	return errors.Errorf("endpoint '%s' is not allowed for copies", conf.Endpoint)
+}
+
+// endpointOrigin returns the scheme and host of an endpoint, which is what
+// endpoints are compared by.
+func endpointOrigin(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", errors.New("endpoint must have a scheme and host")
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
 func validateS3BucketName(bucket string) error {
 	// if it's an expandable string, we can't expand yet since we don't have
 	// access to the task config expansions. So, we defer till during runtime
@@ -0,0 +1,32 @@
+// +build !windows
+
+package shell
+
+import (
+	"os"
+
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/mongodb/grip/slogger"
+)
+
+func countOrphans() (int, error) {
+	pids, err := findOrphans()
+	return len(pids), err
+}
+
+func cleanupOrphans(log plugin.Logger) error {
+	pids, err := findOrphans()
+	if err != nil {
+		return err
+	}
+	for _, pid := range pids {
+		p := os.Process{}
+		p.Pid = pid
+		if err := p.Kill(); err != nil {
+			log.LogSystem(slogger.ERROR, "Cleanup got error killing orphaned pid %v: %v", pid, err)
+		} else {
+			log.LogSystem(slogger.INFO, "Cleanup killed orphaned pid %v", pid)
+		}
+	}
+	return nil
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/evergreen-ci/evergreen/command"
 	"github.com/evergreen-ci/evergreen/model"
@@ -122,6 +125,26 @@ type ShellExecCommand struct {
 	// should cause the task to be marked as failed. Setting this to true
 	// allows following commands to execute even if this shell command fails.
 	ContinueOnError bool `mapstructure:"continue_on_err"`
+
+	// Env sets environment variables for the shell. Its values may contain
+	// expansions.
+	Env map[string]string `mapstructure:"env"`
+
+	// AddExpansionsToEnv, if set to true, adds every expansion to the
+	// shell's environment, so that scripts can read them without having
+	// their values written into the script.
+	AddExpansionsToEnv bool `mapstructure:"add_expansions_to_env"`
+
+	// CleanEnv, if set to true, starts the shell with only the variables in
+	// cleanEnvVars from the agent's environment, rather than all of them.
+	CleanEnv bool `mapstructure:"clean_env"`
+}
+
+// cleanEnvVars are the variables a shell started with clean_env inherits from
+// the agent's environment, which shells and common tools need to work.
+var cleanEnvVars = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "TMPDIR", "TEMP", "TMP",
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "USERPROFILE",
 }
 
 func (_ *ShellExecCommand) Name() string {
@@ -184,13 +207,14 @@ func (sec *ShellExecCommand) Execute(pluginLogger plugin.Logger,
 			localCmd.Shell, localCmd.CmdString)
 	}
 
+	localCmd.Environment, err = sec.buildEnv(conf)
+	if err != nil {
+		return errors.Wrap(err, "Failed to apply expansions to env")
+	}
+
 	doneStatus := make(chan error)
 	go func() {
 		var err error
-		env := os.Environ()
-		env = append(env, fmt.Sprintf("EVR_TASK_ID=%v", conf.Task.Id))
-		env = append(env, fmt.Sprintf("EVR_AGENT_PID=%v", os.Getpid()))
-		localCmd.Environment = env
 		err = localCmd.Start()
 		if err == nil {
 			pluginLogger.LogSystem(slogger.DEBUG, "spawned shell process with pid %v", localCmd.Cmd.Process.Pid)
@@ -242,6 +266,65 @@ func (sec *ShellExecCommand) Execute(pluginLogger plugin.Logger,
 	return nil
 }
 
+// buildEnv returns the shell's environment.
+func (sec *ShellExecCommand) buildEnv(conf *model.TaskConfig) ([]string, error) {
+	return TaskEnv(conf, sec.Env, sec.AddExpansionsToEnv, sec.CleanEnv)
+}
+
+// TaskEnv returns the environment of a process spawned by a task. Later
+// sources override earlier ones: the agent's environment, or only the
+// variables in cleanEnvVars if clean is set; the expansions, if addExpansions
+// is set; then env, whose values may contain expansions. The markers used to
+// track the task's processes are always added last, so they can't be removed.
+func TaskEnv(conf *model.TaskConfig, env map[string]string, addExpansions, clean bool) ([]string, error) {
+	vars := map[string]string{}
+	if clean {
+		for _, name := range cleanEnvVars {
+			if value, ok := os.LookupEnv(name); ok {
+				vars[name] = value
+			}
+		}
+	} else {
+		for _, envVar := range os.Environ() {
+			if parts := strings.SplitN(envVar, "=", 2); len(parts) == 2 {
+				vars[parts[0]] = parts[1]
+			}
+		}
+	}
+
+	if addExpansions {
+		for name, value := range *conf.Expansions {
+			vars[name] = value
+		}
+	}
+
+	for name, value := range env {
+		expanded, err := conf.Expansions.ExpandString(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error expanding env var '%s'", name)
+		}
+		vars[name] = expanded
+	}
+
+	result := make([]string, 0, len(vars)+2)
+	for name, value := range vars {
+		if name == "EVR_TASK_ID" || name == "EVR_AGENT_PID" {
+			continue
+		}
+		result = append(result, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(result)
+	result = append(result, fmt.Sprintf("EVR_TASK_ID=%v", conf.Task.Id))
+	result = append(result, fmt.Sprintf("EVR_AGENT_PID=%v", os.Getpid()))
+	return result, nil
+}
+
+// TrackProcess tracks a process spawned by a task, with an environment from
+// TaskEnv, so that it is cleaned up along with the task's other processes.
+func TrackProcess(taskId string, pid int, pluginLogger plugin.Logger) {
+	trackProcess(taskId, pid, pluginLogger)
+}
+
 // envHasMarkers returns a bool indicating if both marker vars are found in an environment var list
 func envHasMarkers(env []string, pidMarker, taskMarker string) bool {
 	hasPidMarker := false
@@ -263,3 +346,51 @@ func KillSpawnedProcs(taskId string, pluginLogger plugin.Logger) error {
 	// by calling the platform-specific "cleanup" function
 	return cleanup(taskId, pluginLogger)
 }
+
+// SpawnedProc is a running process that was spawned by a task.
+type SpawnedProc struct {
+	Pid int
+	// Command is the process's command line.
+	Command string
+}
+
+// FindSpawnedProcs returns the processes spawned by the given task that are
+// still running. It is not supported on windows.
+func FindSpawnedProcs(taskId string) ([]SpawnedProc, error) {
+	return findSpawned(taskId)
+}
+
+// CountOrphanedProcs returns the number of shell processes still running that
+// were spawned by tasks this agent, or an agent that is no longer running,
+// has already finished. It must only be called between tasks.
+func CountOrphanedProcs() (int, error) {
+	return countOrphans()
+}
+
+// KillOrphanedProcs kills the processes counted by CountOrphanedProcs.
+func KillOrphanedProcs(pluginLogger plugin.Logger) error {
+	return cleanupOrphans(pluginLogger)
+}
+
+// envIsOrphaned returns true if an environment var list belongs to a process
+// spawned by a task of this agent or of an agent that is no longer running,
+// according to the set of live pids.
+func envIsOrphaned(env []string, livePids map[int]bool) bool {
+	hasTaskMarker := false
+	orphaned := false
+	for _, envVar := range env {
+		if strings.HasPrefix(envVar, "EVR_TASK_ID=") {
+			hasTaskMarker = true
+		}
+		if strings.HasPrefix(envVar, "EVR_AGENT_PID=") {
+			pid, err := strconv.Atoi(strings.TrimPrefix(envVar, "EVR_AGENT_PID="))
+			if err != nil {
+				continue
+			}
+			if pid == os.Getpid() || !livePids[pid] {
+				orphaned = true
+			}
+		}
+	}
+	return hasTaskMarker && orphaned
+}
@@ -2,6 +2,7 @@ package shell
 
 import (
 	"fmt"
+	"os"
 	"runtime"
 	"testing"
 
@@ -56,3 +57,45 @@ func TestShellExecuteCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestShellExecEnv(t *testing.T) {
+	Convey("With a shell command's environment", t, func() {
+		conf := &model.TaskConfig{
+			Expansions: command.NewExpansions(map[string]string{"secret": "hunter2", "dir": "/data"}),
+			Task:       &task.Task{Id: "task_id"},
+		}
+		So(os.Setenv("EVR_TEST_AGENT_VAR", "agent"), ShouldBeNil)
+		defer os.Unsetenv("EVR_TEST_AGENT_VAR")
+
+		Convey("the agent's environment should be inherited by default", func() {
+			cmd := &ShellExecCommand{Env: map[string]string{"DATA_DIR": "${dir}/db"}}
+			env, err := cmd.buildEnv(conf)
+			So(err, ShouldBeNil)
+			So(env, ShouldContain, "EVR_TEST_AGENT_VAR=agent")
+			So(env, ShouldContain, "DATA_DIR=/data/db")
+			So(env, ShouldNotContain, "secret=hunter2")
+			So(env[len(env)-2], ShouldEqual, "EVR_TASK_ID=task_id")
+			So(env[len(env)-1], ShouldEqual, fmt.Sprintf("EVR_AGENT_PID=%v", os.Getpid()))
+		})
+
+		Convey("a clean environment should only keep the basic variables", func() {
+			cmd := &ShellExecCommand{CleanEnv: true, AddExpansionsToEnv: true}
+			env, err := cmd.buildEnv(conf)
+			So(err, ShouldBeNil)
+			So(env, ShouldNotContain, "EVR_TEST_AGENT_VAR=agent")
+			So(env, ShouldContain, "secret=hunter2")
+			So(env, ShouldContain, "PATH="+os.Getenv("PATH"))
+		})
+
+		Convey("env should override expansions, but not the tracking markers", func() {
+			cmd := &ShellExecCommand{
+				AddExpansionsToEnv: true,
+				Env:                map[string]string{"dir": "/other", "EVR_TASK_ID": "other_task"},
+			}
+			env, err := cmd.buildEnv(conf)
+			So(err, ShouldBeNil)
+			So(env, ShouldContain, "dir=/other")
+			So(env, ShouldNotContain, "EVR_TASK_ID=other_task")
+		})
+	})
+}
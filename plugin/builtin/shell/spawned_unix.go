@@ -0,0 +1,35 @@
+// +build !windows
+
+package shell
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+func findSpawned(key string) ([]SpawnedProc, error) {
+	pids, err := findSpawnedPids(key)
+	if err != nil {
+		return nil, err
+	}
+	procs := make([]SpawnedProc, 0, len(pids))
+	for _, pid := range pids {
+		command, err := getCommandLine(pid)
+		if err != nil {
+			// the process has probably exited
+			continue
+		}
+		procs = append(procs, SpawnedProc{Pid: pid, Command: command})
+	}
+	return procs, nil
+}
+
+// getCommandLine returns the command line of the process with the given pid.
+func getCommandLine(pid int) (string, error) {
+	out, err := exec.Command("ps", "-o", "args=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
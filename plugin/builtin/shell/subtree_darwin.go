@@ -74,3 +74,68 @@ func cleanup(key string, log plugin.Logger) error {
 	return nil
 
 }
+
+// findOrphans returns the pids of processes that were spawned by tasks that
+// are no longer running, using the same 'ps' output as cleanup.
+func findOrphans() ([]int, error) {
+	out, err := exec.Command("ps", "-E", "-e", "-o", "pid,command").CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	type proc struct {
+		pid int
+		env []string
+	}
+	procs := []proc{}
+	live := map[int]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		splitLine := strings.Fields(line)
+		if len(splitLine) < 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(splitLine[0])
+		if err != nil {
+			// the header line
+			continue
+		}
+		live[pid] = true
+		procs = append(procs, proc{pid: pid, env: splitLine[2:]})
+	}
+
+	orphans := []int{}
+	for _, p := range procs {
+		if p.pid != os.Getpid() && envIsOrphaned(p.env, live) {
+			orphans = append(orphans, p.pid)
+		}
+	}
+	return orphans, nil
+}
+
+// findSpawnedPids returns the pids of the running processes spawned by the
+// task with the given key, using the same 'ps' output as cleanup.
+func findSpawnedPids(key string) ([]int, error) {
+	out, err := exec.Command("ps", "-E", "-e", "-o", "pid,command").CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+	pidMarker := fmt.Sprintf("EVR_AGENT_PID=%v", os.Getpid())
+	taskMarker := fmt.Sprintf("EVR_TASK_ID=%v", key)
+
+	spawned := []int{}
+	for _, line := range strings.Split(string(out), "\n") {
+		splitLine := strings.Fields(line)
+		if len(splitLine) < 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(splitLine[0])
+		if err != nil {
+			// the header line
+			continue
+		}
+		if pid != os.Getpid() && envHasMarkers(splitLine[2:], pidMarker, taskMarker) {
+			spawned = append(spawned, pid)
+		}
+	}
+	return spawned, nil
+}
@@ -32,6 +32,21 @@ func TestSubtreeCleanup(t *testing.T) {
 		So(localCmd.Start(), ShouldBeNil)
 		trackProcess(id, localCmd.Cmd.Process.Pid, &plugintest.MockLogger{})
 
+		Convey("FindSpawnedProcs should find the process and its command line", func() {
+			procs, err := FindSpawnedProcs(id)
+			So(err, ShouldBeNil)
+			found := false
+			for _, proc := range procs {
+				if proc.Pid == localCmd.Cmd.Process.Pid {
+					found = true
+					So(proc.Command, ShouldContainSubstring, "sh")
+				}
+			}
+			So(found, ShouldBeTrue)
+			So(KillSpawnedProcs(id, &plugintest.MockLogger{}), ShouldBeNil)
+			So(localCmd.Cmd.Wait(), ShouldNotBeNil)
+		})
+
 		Convey("running KillSpawnedProcs should kill the process before it finishes", func() {
 			So(KillSpawnedProcs(id, &plugintest.MockLogger{}), ShouldBeNil)
 			So(localCmd.Cmd.Wait(), ShouldNotBeNil)
@@ -39,3 +54,26 @@ func TestSubtreeCleanup(t *testing.T) {
 		})
 	})
 }
+
+func TestEnvIsOrphaned(t *testing.T) {
+	Convey("When checking whether a process was orphaned by an earlier task", t, func() {
+		livePids := map[int]bool{os.Getpid(): true, 4242: true}
+
+		Convey("a process without a task marker should not be orphaned", func() {
+			So(envIsOrphaned([]string{"EVR_AGENT_PID=1"}, livePids), ShouldBeFalse)
+		})
+
+		Convey("a process started by this agent between tasks should be orphaned", func() {
+			env := []string{"EVR_TASK_ID=t1", fmt.Sprintf("EVR_AGENT_PID=%d", os.Getpid())}
+			So(envIsOrphaned(env, livePids), ShouldBeTrue)
+		})
+
+		Convey("a process started by an agent that has exited should be orphaned", func() {
+			So(envIsOrphaned([]string{"EVR_TASK_ID=t1", "EVR_AGENT_PID=999999"}, livePids), ShouldBeTrue)
+		})
+
+		Convey("a process started by another running agent should not be orphaned", func() {
+			So(envIsOrphaned([]string{"EVR_TASK_ID=t1", "EVR_AGENT_PID=4242"}, livePids), ShouldBeFalse)
+		})
+	})
+}
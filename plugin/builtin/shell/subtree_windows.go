@@ -7,6 +7,7 @@ import (
 	"unsafe"
 
 	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/mongodb/grip"
 	"github.com/mongodb/grip/slogger"
 	"github.com/pkg/errors"
 )
@@ -95,6 +96,18 @@ func (r *processRegistry) getJob(taskId string) (*Job, error) {
 	return j, nil
 }
 
+// taskIds returns the ids of the tasks that have job objects.
+func (r *processRegistry) taskIds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.jobs))
+	for id := range r.jobs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func (r *processRegistry) removeJob(taskId string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -158,6 +171,28 @@ func cleanup(key string, log plugin.Logger) error {
 		"problem removing job object from internal evergreen tracking mechanism")
 }
 
+// countOrphans has a windows-specific implementation which counts the job
+// objects left behind by tasks, since a task's job object is removed once its
+// processes have been cleaned up.
+func countOrphans() (int, error) {
+	return len(processMapping.taskIds()), nil
+}
+
+// cleanupOrphans terminates the job objects of all tasks.
+func cleanupOrphans(log plugin.Logger) error {
+	catcher := grip.NewCatcher()
+	for _, id := range processMapping.taskIds() {
+		catcher.Add(cleanup(id, log))
+	}
+	return catcher.Resolve()
+}
+
+// findSpawned is not supported on windows, since a task's processes are only
+// tracked by its job object.
+func findSpawned(key string) ([]SpawnedProc, error) {
+	return nil, errors.New("listing a task's processes is not supported on windows")
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////
 //
 // All the methods below are boilerplate functions for accessing the Windows syscalls for
@@ -3,6 +3,7 @@
 package shell
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"strconv"
@@ -44,3 +45,57 @@ func listProc() ([]int, error) {
 	}
 	return results, nil
 }
+
+// findOrphans returns the pids of processes that were spawned by tasks that
+// are no longer running.
+func findOrphans() ([]int, error) {
+	pids, err := listProc()
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[int]bool, len(pids))
+	for _, pid := range pids {
+		live[pid] = true
+	}
+
+	orphans := []int{}
+	for _, pid := range pids {
+		if pid == os.Getpid() {
+			continue
+		}
+		env, err := getEnv(pid)
+		if err != nil {
+			continue
+		}
+		if envIsOrphaned(env, live) {
+			orphans = append(orphans, pid)
+		}
+	}
+	return orphans, nil
+}
+
+// findSpawnedPids returns the pids of the running processes spawned by the
+// task with the given key.
+func findSpawnedPids(key string) ([]int, error) {
+	pids, err := listProc()
+	if err != nil {
+		return nil, err
+	}
+	pidMarker := fmt.Sprintf("EVR_AGENT_PID=%v", os.Getpid())
+	taskMarker := fmt.Sprintf("EVR_TASK_ID=%v", key)
+
+	spawned := []int{}
+	for _, pid := range pids {
+		if pid == os.Getpid() {
+			continue
+		}
+		env, err := getEnv(pid)
+		if err != nil {
+			continue
+		}
+		if envHasMarkers(env, pidMarker, taskMarker) {
+			spawned = append(spawned, pid)
+		}
+	}
+	return spawned, nil
+}
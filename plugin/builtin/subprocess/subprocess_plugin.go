@@ -0,0 +1,257 @@
+package subprocess
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/plugin/builtin/shell"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	plugin.Publish(&SubprocessPlugin{})
+}
+
+const (
+	SubprocessPluginName = "subprocess"
+	SubprocessExecCmd    = "exec"
+)
+
+// SubprocessPlugin runs programs on the agent's machine directly, rather than
+// through a shell, so that their arguments need no quoting and hosts without
+// a POSIX shell can run them.
+type SubprocessPlugin struct{}
+
+// Name returns the name of the plugin. Required to fulfill
+// the Plugin interface.
+func (sp *SubprocessPlugin) Name() string {
+	return SubprocessPluginName
+}
+
+// NewCommand returns the requested command, or returns an error
+// if a non-existing command is requested.
+func (sp *SubprocessPlugin) NewCommand(cmdName string) (plugin.Command, error) {
+	if cmdName == SubprocessExecCmd {
+		return &SubprocessExecCommand{}, nil
+	}
+	return nil, errors.Errorf("no such command: %v", cmdName)
+}
+
+// SubprocessExecCommand runs a binary with a list of arguments. Expansions
+// are applied to each argument separately, so an argument is passed to the
+// binary as one argument whatever it expands to. Processes it starts are
+// tracked and cleaned up like those of shell.exec.
+type SubprocessExecCommand struct {
+	// Binary is the program to run. If it has no path separators, it is
+	// looked up in the agent's PATH; if it is a relative path, it is relative
+	// to the working directory.
+	Binary string `mapstructure:"binary" plugin:"expand"`
+
+	// Args are the arguments to pass to the binary.
+	Args []string `mapstructure:"args" plugin:"expand"`
+
+	// Env, AddExpansionsToEnv and CleanEnv set up the process's environment
+	// as they do for shell.exec.
+	Env                map[string]string `mapstructure:"env"`
+	AddExpansionsToEnv bool              `mapstructure:"add_expansions_to_env"`
+	CleanEnv           bool              `mapstructure:"clean_env"`
+
+	// WorkingDir is the directory to run the binary in, relative to the
+	// task's working directory.
+	WorkingDir string `mapstructure:"working_dir" plugin:"expand"`
+
+	// StdinFile, if set, is a file to read the process's standard input from.
+	StdinFile string `mapstructure:"stdin_file" plugin:"expand"`
+
+	// StdoutFile and StderrFile, if set, are files to write the process's
+	// standard output and error to instead of the task logs. They may be the
+	// same file. Like StdinFile, they are relative to the working directory.
+	StdoutFile string `mapstructure:"stdout_file" plugin:"expand"`
+	StderrFile string `mapstructure:"stderr_file" plugin:"expand"`
+
+	// Silent, if set to true, prevents the arguments from being logged, to
+	// avoid exposing sensitive expansions.
+	Silent bool `mapstructure:"silent"`
+
+	// Background, if set to true, returns as soon as the process has started
+	// rather than waiting for it to finish.
+	Background bool `mapstructure:"background"`
+
+	// ContinueOnError determines whether or not a failed return code
+	// should cause the task to be marked as failed.
+	ContinueOnError bool `mapstructure:"continue_on_err"`
+}
+
+func (_ *SubprocessExecCommand) Name() string {
+	return SubprocessExecCmd
+}
+
+func (_ *SubprocessExecCommand) Plugin() string {
+	return SubprocessPluginName
+}
+
+// ParseParams reads in the command's parameters.
+func (sec *SubprocessExecCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, sec); err != nil {
+		return errors.Wrapf(err, "error decoding %v params", sec.Name())
+	}
+	if sec.Binary == "" {
+		return errors.Errorf("error validating %v params: binary cannot be blank", sec.Name())
+	}
+	return nil
+}
+
+// Execute runs the binary with its given parameters.
+func (sec *SubprocessExecCommand) Execute(pluginLogger plugin.Logger,
+	pluginCom plugin.PluginCommunicator,
+	conf *model.TaskConfig,
+	stop chan bool) error {
+
+	if err := plugin.ExpandValues(sec, conf.Expansions); err != nil {
+		return errors.Wrap(err, "Failed to apply expansions")
+	}
+	if sec.Binary == "" {
+		return errors.New("binary cannot be blank after expansion")
+	}
+
+	workDir := conf.WorkDir
+	if sec.WorkingDir != "" {
+		workDir = filepath.Join(conf.WorkDir, sec.WorkingDir)
+	}
+	env, err := shell.TaskEnv(conf, sec.Env, sec.AddExpansionsToEnv, sec.CleanEnv)
+	if err != nil {
+		return errors.Wrap(err, "Failed to apply expansions to env")
+	}
+
+	cmd := exec.Command(sec.Binary, sec.Args...)
+	cmd.Dir = workDir
+	cmd.Env = env
+	files, err := sec.redirect(cmd, workDir, pluginLogger)
+	if err != nil {
+		closeFiles(files, pluginLogger)
+		return errors.WithStack(err)
+	}
+
+	if sec.Silent {
+		pluginLogger.LogExecution(slogger.INFO, "Executing %v (arguments hidden)...", sec.Binary)
+	} else {
+		pluginLogger.LogExecution(slogger.INFO, "Executing %v with arguments %q", sec.Binary, sec.Args)
+	}
+
+	doneStatus := make(chan error)
+	go func() {
+		err := cmd.Start()
+		// the process has its own copies of the files
+		closeFiles(files, pluginLogger)
+		if err == nil {
+			pluginLogger.LogSystem(slogger.DEBUG, "spawned process with pid %v", cmd.Process.Pid)
+			shell.TrackProcess(conf.Task.Id, cmd.Process.Pid, pluginLogger)
+			if !sec.Background {
+				err = cmd.Wait()
+			}
+		} else {
+			pluginLogger.LogSystem(slogger.DEBUG, "error spawning process: %v", err)
+		}
+		doneStatus <- err
+	}()
+
+	defer pluginLogger.Flush()
+	select {
+	case err = <-doneStatus:
+		if err != nil {
+			if sec.ContinueOnError {
+				pluginLogger.LogExecution(slogger.INFO, "(ignoring) Process finished with error: %v", err)
+				return nil
+			}
+			pluginLogger.LogExecution(slogger.INFO, "Process finished with error: %v", err)
+			return errors.Wrapf(err, "error running %v", sec.Binary)
+		}
+		if sec.Background {
+			pluginLogger.LogExecution(slogger.INFO, "Process started in the background.")
+		} else {
+			pluginLogger.LogExecution(slogger.INFO, "Process execution complete.")
+		}
+	case <-stop:
+		pluginLogger.LogExecution(slogger.INFO, "Got kill signal")
+		if cmd.Process != nil {
+			pluginLogger.LogExecution(slogger.INFO, "Stopping process: %v", cmd.Process.Pid)
+			if err := cmd.Process.Kill(); err != nil {
+				pluginLogger.LogExecution(slogger.ERROR, "Error occurred stopping process: %v", err)
+			}
+		}
+		return errors.New("Subprocess command interrupted.")
+	}
+	return nil
+}
+
+// redirect sets up the command's standard input, output and error, returning
+// the files it opened for them, which can be closed once the process has
+// started.
+func (sec *SubprocessExecCommand) redirect(cmd *exec.Cmd, workDir string,
+	pluginLogger plugin.Logger) ([]*os.File, error) {
+	files := []*os.File{}
+
+	if sec.StdinFile != "" {
+		stdin, err := os.Open(inDir(workDir, sec.StdinFile))
+		if err != nil {
+			return files, errors.Wrap(err, "error opening stdin_file")
+		}
+		files = append(files, stdin)
+		cmd.Stdin = stdin
+	}
+
+	var stdout, stderr io.Writer = pluginLogger.GetTaskLogWriter(slogger.INFO),
+		pluginLogger.GetTaskLogWriter(slogger.ERROR)
+	if sec.StdoutFile != "" {
+		file, err := createOutput(inDir(workDir, sec.StdoutFile))
+		if err != nil {
+			return files, errors.Wrap(err, "error creating stdout_file")
+		}
+		files = append(files, file)
+		stdout = file
+	}
+	if sec.StderrFile != "" {
+		if sec.StdoutFile != "" && inDir(workDir, sec.StderrFile) == inDir(workDir, sec.StdoutFile) {
+			stderr = stdout
+		} else {
+			file, err := createOutput(inDir(workDir, sec.StderrFile))
+			if err != nil {
+				return files, errors.Wrap(err, "error creating stderr_file")
+			}
+			files = append(files, file)
+			stderr = file
+		}
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return files, nil
+}
+
+func closeFiles(files []*os.File, pluginLogger plugin.Logger) {
+	for _, file := range files {
+		if err := file.Close(); err != nil {
+			pluginLogger.LogExecution(slogger.WARN, "Error closing %v: %v", file.Name(), err)
+		}
+	}
+}
+
+// inDir returns path, if absolute, or else path relative to dir.
+func inDir(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func createOutput(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return os.Create(path)
+}
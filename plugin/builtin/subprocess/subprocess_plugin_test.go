@@ -0,0 +1,112 @@
+package subprocess
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen/command"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/evergreen-ci/evergreen/plugin/plugintest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSubprocessExecParams(t *testing.T) {
+	Convey("With a subprocess exec command", t, func() {
+		cmd := &SubprocessExecCommand{}
+
+		Convey("the binary must be given", func() {
+			So(cmd.ParseParams(map[string]interface{}{"args": []string{"a"}}), ShouldNotBeNil)
+		})
+
+		Convey("args should be decoded as a list", func() {
+			So(cmd.ParseParams(map[string]interface{}{
+				"binary": "echo",
+				"args":   []interface{}{"a b", "${c}"},
+				"silent": true,
+			}), ShouldBeNil)
+			So(cmd.Args, ShouldResemble, []string{"a b", "${c}"})
+			So(cmd.Silent, ShouldBeTrue)
+		})
+	})
+}
+
+func TestSubprocessExecute(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test binaries are not available on windows")
+	}
+
+	stopper := make(chan bool)
+	defer close(stopper)
+
+	Convey("With a task working directory", t, func() {
+		dir, err := ioutil.TempDir("", "subprocess_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		conf := &model.TaskConfig{
+			Expansions: command.NewExpansions(map[string]string{"greeting": "hello  there; $HOME"}),
+			Task:       &task.Task{Id: "subprocess_test"},
+			WorkDir:    dir,
+		}
+		logger := &plugintest.MockLogger{}
+		output := func(name string) string {
+			data, err := ioutil.ReadFile(filepath.Join(dir, name))
+			So(err, ShouldBeNil)
+			return string(data)
+		}
+
+		Convey("expanded arguments should be passed without shell processing", func() {
+			cmd := &SubprocessExecCommand{
+				Binary:     "printf",
+				Args:       []string{"[%s]", "${greeting}", "*"},
+				StdoutFile: "out/stdout.txt",
+			}
+			So(cmd.Execute(logger, nil, conf, stopper), ShouldBeNil)
+			So(output("out/stdout.txt"), ShouldEqual, "[hello  there; $HOME][*]")
+		})
+
+		Convey("standard input should be read from stdin_file", func() {
+			So(ioutil.WriteFile(filepath.Join(dir, "in.txt"), []byte("from a file"), 0644), ShouldBeNil)
+			cmd := &SubprocessExecCommand{Binary: "cat", StdinFile: "in.txt", StdoutFile: "out.txt"}
+			So(cmd.Execute(logger, nil, conf, stopper), ShouldBeNil)
+			So(output("out.txt"), ShouldEqual, "from a file")
+		})
+
+		Convey("the process should run in working_dir with its env", func() {
+			So(os.Mkdir(filepath.Join(dir, "sub"), 0755), ShouldBeNil)
+			cmd := &SubprocessExecCommand{
+				Binary:     "sh",
+				Args:       []string{"-c", `pwd; echo "$GREETING" >&2`},
+				Env:        map[string]string{"GREETING": "${greeting}"},
+				WorkingDir: "sub",
+				StdoutFile: "both.txt",
+				StderrFile: "both.txt",
+			}
+			So(cmd.Execute(logger, nil, conf, stopper), ShouldBeNil)
+			pwd, err := filepath.EvalSymlinks(filepath.Join(dir, "sub"))
+			So(err, ShouldBeNil)
+			So(output("sub/both.txt"), ShouldEqual, pwd+"\nhello  there; $HOME\n")
+		})
+
+		Convey("a failing process should fail the command unless continue_on_err is set", func() {
+			cmd := &SubprocessExecCommand{Binary: "false"}
+			So(cmd.Execute(logger, nil, conf, stopper), ShouldNotBeNil)
+			cmd = &SubprocessExecCommand{Binary: "false", ContinueOnError: true}
+			So(cmd.Execute(logger, nil, conf, stopper), ShouldBeNil)
+		})
+
+		Convey("a missing binary should fail the command", func() {
+			cmd := &SubprocessExecCommand{Binary: "./no-such-binary"}
+			So(cmd.Execute(logger, nil, conf, stopper), ShouldNotBeNil)
+		})
+
+		Convey("a background process should not be waited for", func() {
+			cmd := &SubprocessExecCommand{Binary: "sleep", Args: []string{"30"}, Background: true}
+			So(cmd.Execute(logger, nil, conf, stopper), ShouldBeNil)
+		})
+	})
+}
@@ -2,6 +2,7 @@ package taskdata
 
 import (
 	"fmt"
+	"html/template"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -25,11 +26,12 @@ func init() {
 }
 
 const (
-	TaskJSONPluginName = "json"
-	TaskJSONSend       = "send"
-	TaskJSONGet        = "get"
-	TaskJSONGetHistory = "get_history"
-	TaskJSONHistory    = "history"
+	TaskJSONPluginName  = "json"
+	TaskJSONSend        = "send"
+	TaskJSONGet         = "get"
+	TaskJSONGetHistory  = "get_history"
+	TaskJSONHistory     = "history"
+	TaskJSONSendMetrics = "send_metrics"
 )
 
 // TaskJSONPlugin handles thet
@@ -46,6 +48,8 @@ func (jsp *TaskJSONPlugin) GetAPIHandler() http.Handler {
 	r.HandleFunc("/tags/{task_name}/{name}", apiGetTagsForTask)
 	r.HandleFunc("/history/{task_name}/{name}", apiGetTaskHistory)
 
+	r.HandleFunc("/metrics", apiInsertMetrics).Methods("POST")
+
 	r.HandleFunc("/data/{name}", apiInsertTask)
 	r.HandleFunc("/data/{task_name}/{name}", apiGetTaskByName)
 	r.HandleFunc("/data/{task_name}/{name}/{variant}", apiGetTaskForVariant)
@@ -103,10 +107,29 @@ func (jsp *TaskJSONPlugin) Configure(map[string]interface{}) error {
 	return nil
 }
 
-// GetPanelConfig is required to fulfill the Plugin interface. This plugin
-// does not have any UI hooks.
+// GetPanelConfig returns a plugin.PanelConfig struct representing a panel
+// that charts the trends of the metrics a task reports on the Task page.
 func (jsp *TaskJSONPlugin) GetPanelConfig() (*plugin.PanelConfig, error) {
-	return &plugin.PanelConfig{}, nil
+	return &plugin.PanelConfig{
+		Panels: []plugin.UIPanel{
+			{
+				Page:     plugin.TaskPage,
+				Position: plugin.PageCenter,
+				Includes: []template.HTML{
+					"<script type=\"text/javascript\" src=\"//cdnjs.cloudflare.com/ajax/libs/d3/3.5.3/d3.min.js\"></script>",
+					"<script type=\"text/javascript\" src=\"/plugin/json/static/js/perf_trends.js\"></script>",
+				},
+				PanelHTML: "<div ng-include=\"'/plugin/json/static/partials/task_perf_panel.html'\" " +
+					"ng-init='trends=plugins.json' ng-show='plugins.json.length'></div>",
+				DataFunc: func(context plugin.UIContext) (interface{}, error) {
+					if context.Task == nil {
+						return nil, nil
+					}
+					return GetMetricTrends(context.Task.Id)
+				},
+			},
+		},
+	}, nil
 }
 
 // NewCommand returns requested commands by name. Fulfills the Plugin interface.
@@ -117,6 +140,8 @@ func (jsp *TaskJSONPlugin) NewCommand(cmdName string) (plugin.Command, error) {
 		return &TaskJSONGetCommand{}, nil
 	} else if cmdName == TaskJSONGetHistory {
 		return &TaskJSONHistoryCommand{}, nil
+	} else if cmdName == TaskJSONSendMetrics {
+		return &TaskJSONSendMetricsCommand{}, nil
 	}
 	return nil, &plugin.ErrUnknownCommand{cmdName}
 }
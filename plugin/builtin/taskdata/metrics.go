@@ -0,0 +1,218 @@
+package taskdata
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/perf"
+	"github.com/evergreen-ci/evergreen/model/version"
+	"github.com/evergreen-ci/evergreen/plugin"
+	"github.com/evergreen-ci/evergreen/util"
+	"github.com/mitchellh/mapstructure"
+	"github.com/mongodb/grip/slogger"
+	"github.com/pkg/errors"
+)
+
+// trendLength is how many revisions of history the task page charts for
+// each metric.
+const trendLength = 50
+
+// MetricsReport is the body of a request to record the metrics of a task.
+type MetricsReport struct {
+	Metrics []perf.Metric `json:"metrics"`
+	Schema  perf.Schema   `json:"schema,omitempty"`
+}
+
+// TaskJSONSendMetricsCommand records named numeric metrics, such as
+// benchmark results, for the task, so that their history can be charted and
+// analyzed for changes.
+type TaskJSONSendMetricsCommand struct {
+	// File is a JSON file of metrics, in the form
+	//   {"metrics": [{"name": "insert", "value": 1200.5, "unit": "ops/sec"}]}
+	File string `mapstructure:"file" plugin:"expand"`
+
+	// Schema, if given, maps the names of the metrics the file must hold to
+	// their units.
+	Schema perf.Schema `mapstructure:"schema"`
+}
+
+func (self *TaskJSONSendMetricsCommand) Name() string {
+	return TaskJSONSendMetrics
+}
+
+func (self *TaskJSONSendMetricsCommand) Plugin() string {
+	return TaskJSONPluginName
+}
+
+func (self *TaskJSONSendMetricsCommand) ParseParams(params map[string]interface{}) error {
+	if err := mapstructure.Decode(params, self); err != nil {
+		return errors.Wrapf(err, "error decoding '%v' params", self.Name())
+	}
+	if self.File == "" {
+		return errors.Errorf("error validating '%v' params: file cannot be blank", self.Name())
+	}
+	for name, unit := range self.Schema {
+		if err := (perf.Metric{Name: name, Unit: unit}).Validate(); err != nil {
+			return errors.Wrapf(err, "error validating '%v' schema", self.Name())
+		}
+	}
+	return nil
+}
+
+func (self *TaskJSONSendMetricsCommand) Execute(log plugin.Logger, com plugin.PluginCommunicator,
+	conf *model.TaskConfig, stop chan bool) error {
+
+	if err := plugin.ExpandValues(self, conf.Expansions); err != nil {
+		return errors.WithStack(err)
+	}
+
+	fileLoc := self.File
+	if !filepath.IsAbs(fileLoc) {
+		fileLoc = filepath.Join(conf.WorkDir, fileLoc)
+	}
+	jsonFile, err := os.Open(fileLoc)
+	if err != nil {
+		return errors.Wrap(err, "Couldn't open metrics file")
+	}
+	defer jsonFile.Close()
+
+	report := MetricsReport{Schema: self.Schema}
+	if err = util.ReadJSONInto(jsonFile, &report); err != nil {
+		return errors.Wrap(err, "File contained invalid json")
+	}
+	if err = report.Schema.Validate(report.Metrics); err != nil {
+		return errors.Wrap(err, "invalid metrics")
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		retriablePost := util.RetriableFunc(
+			func() error {
+				log.LogTask(slogger.INFO, "Posting %d metrics", len(report.Metrics))
+				resp, err := com.TaskPostJSON("metrics", report)
+				if resp != nil {
+					defer resp.Body.Close()
+				}
+				if err != nil {
+					return util.RetriableError{errors.WithStack(err)}
+				}
+				if resp.StatusCode == http.StatusBadRequest {
+					msg := struct {
+						Error string `json:"error"`
+					}{}
+					_ = util.ReadJSONInto(resp.Body, &msg)
+					return errors.Errorf("metrics were rejected: %v", msg.Error)
+				}
+				if resp.StatusCode != http.StatusOK {
+					return util.RetriableError{errors.Errorf("unexpected status code %v", resp.StatusCode)}
+				}
+				return nil
+			},
+		)
+		_, err := util.Retry(retriablePost, 10, 3*time.Second)
+		errChan <- errors.WithStack(err)
+	}()
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			log.LogTask(slogger.ERROR, "Sending metrics failed: %v", err)
+		}
+		return err
+	case <-stop:
+		log.LogExecution(slogger.INFO, "Received abort signal, stopping.")
+		return nil
+	}
+}
+
+// apiInsertMetrics records the metrics of the requesting task.
+func apiInsertMetrics(w http.ResponseWriter, r *http.Request) {
+	t := plugin.GetTask(r)
+	if t == nil {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	report := MetricsReport{}
+	if err := util.ReadJSONInto(util.NewRequestReader(r), &report); err != nil {
+		plugin.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := report.Schema.Validate(report.Metrics); err != nil {
+		plugin.WriteJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := perf.Save(t, report.Metrics); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plugin.WriteJSON(w, http.StatusOK, "ok")
+}
+
+// MetricTrend is a metric reported by a task, with the history of its
+// series up to the task's revision.
+type MetricTrend struct {
+	Metric perf.Point  `json:"metric"`
+	Series perf.Series `json:"series"`
+}
+
+// GetMetricTrends returns the trends of the metrics reported by a task. The
+// history of a patch task's metrics is that of the mainline up to the
+// patch's base revision, followed by the patch's own point.
+func GetMetricTrends(taskId string) ([]MetricTrend, error) {
+	points, err := perf.FindAll(perf.ByTaskId(taskId))
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding metrics for task")
+	}
+	if len(points) == 0 {
+		return []MetricTrend{}, nil
+	}
+
+	// the points were all reported by the task, so they share its place in
+	// the project's history
+	endOrder := points[0].RevisionOrderNumber
+	if points[0].IsPatch {
+		// patch versions have no order number of their own
+		base, err := version.FindOne(version.ByProjectIdAndRevision(points[0].ProjectId, points[0].Revision))
+		if err != nil {
+			return nil, errors.Wrap(err, "error finding base version of patch")
+		}
+		if base == nil {
+			return nil, errors.Errorf("no base version found for revision '%v'", points[0].Revision)
+		}
+		endOrder = base.RevisionOrderNumber
+	}
+
+	trends := []MetricTrend{}
+	for _, point := range points {
+		limit := trendLength
+		if point.IsPatch {
+			limit--
+		}
+		history, err := perf.FindSeries(perf.SeriesQuery{
+			ProjectId: point.ProjectId,
+			Variant:   point.Variant,
+			TaskName:  point.TaskName,
+			Name:      point.Name,
+			EndOrder:  endOrder,
+			Limit:     limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if point.IsPatch {
+			// series only hold mainline points, so add the patch's own,
+			// placed just after its base revision
+			patchPoint := point
+			patchPoint.RevisionOrderNumber = endOrder + 1
+			history = append(history, patchPoint)
+		}
+		trends = append(trends, MetricTrend{
+			Metric: point,
+			Series: perf.Analyze(history, perf.AnalysisOptions{}),
+		})
+	}
+	return trends, nil
+}
@@ -295,6 +295,17 @@ func (sr *SimpleRegistry) ParseCommandConf(cmd model.PluginCommandConf, funcs ma
 				c.Type = cmd.Type
 			}
 
+			// likewise for how the command's failures are handled
+			if c.RetryOnFailure == 0 {
+				c.RetryOnFailure = cmd.RetryOnFailure
+			}
+			if c.RetryBackoffSecs == 0 {
+				c.RetryBackoffSecs = cmd.RetryBackoffSecs
+			}
+			if cmd.ContinueOnError {
+				c.ContinueOnError = true
+			}
+
 			// use function name if no command display name exists
 			if c.DisplayName == "" {
 				c.DisplayName = fmt.Sprintf(`'%v' in "%v"`, c.Command, funcName)
@@ -177,6 +177,34 @@ func TestRegistry(t *testing.T) {
 				}
 			})
 		})
+		Convey("commands in a function should inherit how the function call handles failures", func() {
+			registry := plugin.NewSimpleRegistry()
+			funcs := map[string]*model.YAMLCommandSet{
+				"fetch": {
+					MultiCommand: []model.PluginCommandConf{
+						{Command: "shell.exec"},
+						{Command: "shell.exec", Type: model.TestCommandType, RetryOnFailure: 1},
+					},
+				},
+			}
+			call := model.PluginCommandConf{
+				Function:         "fetch",
+				Type:             model.SetupCommandType,
+				RetryOnFailure:   3,
+				RetryBackoffSecs: 5,
+				ContinueOnError:  true,
+			}
+			cmds, err := registry.ParseCommandConf(call, funcs)
+			So(err, ShouldBeNil)
+			So(len(cmds), ShouldEqual, 2)
+			So(cmds[0].Type, ShouldEqual, model.SetupCommandType)
+			So(cmds[0].RetryOnFailure, ShouldEqual, 3)
+			So(cmds[0].RetryBackoffSecs, ShouldEqual, 5)
+			So(cmds[0].ContinueOnError, ShouldBeTrue)
+			So(cmds[1].Type, ShouldEqual, model.TestCommandType)
+			So(cmds[1].RetryOnFailure, ShouldEqual, 1)
+			So(cmds[1].ContinueOnError, ShouldBeTrue)
+		})
 	})
 }
 
@@ -123,7 +123,7 @@ directives.visualization.directive('progressBar', function($filter) {
        rolledUp: false,
        collapseInfo: {
          collapsed: scope.collapsed,
-         activeTaskStatuses : ['failed','system-failed']
+         activeTaskStatuses : ['failed','system-failed','setup-failed']
        }
      }
      ReactDOM.render(
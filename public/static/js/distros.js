@@ -25,6 +25,12 @@ mciModule.controller('DistrosCtrl', function($scope, $window, $location, mciDist
   }, {
     'id': 'openstack',
     'display': 'OpenStack'
+  }, {
+    'id': 'gce',
+    'display': 'Google Compute Engine'
+  }, {
+    'id': 'vsphere',
+    'display': 'VMware vSphere'
   }];
 
   $scope.architectures = [{
@@ -194,6 +200,34 @@ mciModule.controller('DistrosCtrl', function($scope, $window, $location, mciDist
     $scope.activeDistro.expansions.splice(index, 1);
   }
 
+  $scope.addWarmSchedule = function() {
+    if ($scope.activeDistro.warm_schedules == null) {
+      $scope.activeDistro.warm_schedules = [];
+    }
+    $scope.activeDistro.warm_schedules.push({});
+    $scope.scrollElement('#warm-schedules-table');
+  }
+
+  $scope.removeWarmSchedule = function(schedule) {
+    var index = $scope.activeDistro.warm_schedules.indexOf(schedule);
+    $scope.activeDistro.warm_schedules.splice(index, 1);
+  }
+
+  $scope.newInstanceTag = {};
+
+  $scope.addInstanceTag = function() {
+    if ($scope.activeDistro.instance_tags == null) {
+      $scope.activeDistro.instance_tags = {};
+    }
+    $scope.activeDistro.instance_tags[$scope.newInstanceTag.key] = $scope.newInstanceTag.value || '';
+    $scope.newInstanceTag = {};
+    $scope.scrollElement('#instance-tags-table');
+  }
+
+  $scope.removeInstanceTag = function(key) {
+    delete $scope.activeDistro.instance_tags[key];
+  }
+
   $scope.saveConfiguration = function() {
     if ($scope.activeDistro.new) {
       mciDistroRestService.addDistro(
@@ -275,11 +309,16 @@ mciModule.controller('DistrosCtrl', function($scope, $window, $location, mciDist
         'ssh_options': $scope.activeDistro.ssh_options,
         'setup': $scope.activeDistro.setup,
         'pool_size': $scope.activeDistro.pool_size,
+        'min_hosts': $scope.activeDistro.min_hosts,
         'setup_as_sudo' : $scope.activeDistro.setup_as_sudo,
+        'bootstrap_method': $scope.activeDistro.bootstrap_method,
 
       }
       newDistro.settings = _.clone($scope.activeDistro.settings);
       newDistro.expansions = _.clone($scope.activeDistro.expansions);
+      newDistro.cost_center = $scope.activeDistro.cost_center;
+      newDistro.instance_tags = _.clone($scope.activeDistro.instance_tags);
+      newDistro.warm_schedules = _.clone($scope.activeDistro.warm_schedules);
 
       $scope.distros.unshift(newDistro);
       $scope.hasNew = true;
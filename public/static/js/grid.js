@@ -410,6 +410,9 @@ mciModule.controller('VersionMatrixController', function($scope, $window, $locat
             if (history[i].task_end_details.type == 'system') {
               return 'system-failed';
             }
+            if (history[i].task_end_details.type == 'setup') {
+              return 'setup-failed';
+            }
           }
         }
         return 'failure';
@@ -441,6 +444,9 @@ mciModule.controller('VersionMatrixController', function($scope, $window, $locat
             if (cell.current.task_end_details.type == 'system') {
               cellClass = 'system-failed';
             }
+            if (cell.current.task_end_details.type == 'setup') {
+              cellClass = 'setup-failed';
+            }
           }
         }
       } else if (cell.current.status == 'success') {
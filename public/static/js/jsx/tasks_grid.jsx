@@ -30,6 +30,9 @@ function taskStatusClass(task) {
 
   if (task.status == 'failed') {
     if ('task_end_details' in task) {
+      if ('type' in task.task_end_details && task.task_end_details.type == 'setup') {
+         return 'setup-failed';
+      }
       if ('type' in task.task_end_details && task.task_end_details.type == 'system') {
          return 'system-failed';
       }
@@ -60,6 +63,9 @@ function labelFromTask(task){
   }
 
   if (task.status == 'failed' && 'task_end_details' in task){
+    if (task.task_end_details.type == 'setup') {
+      return 'setup failure';
+    }
     if ('timed_out' in task.task_end_details) {
       if (task.task_end_details.timed_out && task.task_end_details.desc == 'heartbeat') {
         return 'system unresponsive';
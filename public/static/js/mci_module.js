@@ -247,6 +247,9 @@ var mciModule = angular.module('MCI', [
       cls = 'failed';
       if ('task_end_details' in task) {
         if ('type' in task.task_end_details) {
+          if (task.task_end_details.type == 'setup') {
+            cls = 'setup-failed';
+          }
           if (task.task_end_details.type == 'system' && task.task_end_details.status != 'success') {
             cls = 'system-failed';
           }
@@ -154,6 +154,7 @@ mciModule.controller('ProjectCtrl', function($scope, $window, $http, $location)
           alert_config: $scope.projectRef.alert_config || {},
           repotracker_error: $scope.projectRef.repotracker_error || {},
           admins : $scope.projectRef.admins || [],
+          artifact_store: $scope.projectRef.artifact_store || {},
         };
 
         $scope.displayName = $scope.projectRef.display_name ? $scope.projectRef.display_name : $scope.projectRef.identifier;
@@ -344,6 +344,33 @@ mciModule.controller('TaskCtrl', function($scope, $rootScope, $now, $timeout, $i
       $scope.otherExecutions = _.range(task.total_executions + 1)
     }
 
+    // lay out the commands the agent ran on a timeline that spans from the
+    // first command's start to the last command's end
+    $scope.commandTimeline = [];
+    var commands = (task.task_end_details && task.task_end_details.commands) || [];
+    if (commands.length > 0) {
+      var toMillis = function(t) {
+        var d = new Date(t);
+        return d.getFullYear() > 1 ? d.getTime() : null;
+      };
+      var first = toMillis(commands[0].start);
+      var last = first;
+      _.each(commands, function(cmd) {
+        last = Math.max(last, toMillis(cmd.end) || toMillis(cmd.start));
+      });
+      var span = Math.max(last - first, 1);
+      $scope.commandTimeline = _.map(commands, function(cmd) {
+        var start = toMillis(cmd.start);
+        var end = toMillis(cmd.end) || last;
+        return _.extend({}, cmd, {
+          offset: (start - first) / span * 100,
+          width: Math.max((end - start) / span * 100, 0.5),
+          durationNano: (end - start) * 1000 * 1000,
+          unfinished: !toMillis(cmd.end)
+        });
+      });
+    }
+
     $scope.sortBy = $scope.sortOrders[0];
 
     $scope.isMet = function(dependency) {
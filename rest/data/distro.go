@@ -3,8 +3,10 @@ package data
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/evergreen-ci/evergreen/rest"
 )
 
@@ -27,13 +29,32 @@ func (dc *DBDistroConnector) FindAllDistros() ([]distro.Distro, error) {
 	return distros, nil
 }
 
+// GetDistroHostPools returns the current state of the distros' host pools.
+func (dc *DBDistroConnector) GetDistroHostPools(distros []distro.Distro) ([]host.PoolState, error) {
+	return host.GetPoolStates(distros, time.Now())
+}
+
 // MockDistroConnector is a struct that implements Distro-related methods
 // for testing.
 type MockDistroConnector struct {
-	Distros []distro.Distro
+	Distros   []distro.Distro
+	HostPools map[string]host.PoolState
 }
 
 // FindAllDistros is a mock implementation for testing.
 func (dc *MockDistroConnector) FindAllDistros() ([]distro.Distro, error) {
 	return dc.Distros, nil
 }
+
+// GetDistroHostPools is a mock implementation for testing.
+func (dc *MockDistroConnector) GetDistroHostPools(distros []distro.Distro) ([]host.PoolState, error) {
+	pools := make([]host.PoolState, 0, len(distros))
+	for _, d := range distros {
+		pool, ok := dc.HostPools[d.Id]
+		if !ok {
+			pool = host.PoolState{DistroId: d.Id}
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"fmt"
 
+	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/evergreen-ci/evergreen/rest"
 )
@@ -44,6 +45,35 @@ func (hc *DBHostConnector) FindHostById(id string) (*host.Host, error) {
 	return h, nil
 }
 
+// QuarantineHost stops new tasks from being dispatched to the host while
+// letting a running task finish.
+func (hc *DBHostConnector) QuarantineHost(h *host.Host, reason string) error {
+	if h.Status == evergreen.HostQuarantined {
+		return &rest.APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("host %s is already quarantined", h.Id),
+		}
+	}
+	if err := h.Quarantine(reason, false); err != nil {
+		return &rest.APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    err.Error(),
+		}
+	}
+	return nil
+}
+
+// ReadmitHost returns a quarantined host to service.
+func (hc *DBHostConnector) ReadmitHost(h *host.Host, reason string) error {
+	if err := h.Readmit(reason); err != nil {
+		return &rest.APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    err.Error(),
+		}
+	}
+	return nil
+}
+
 // MockHostConnector is a struct that implements the Host related methods
 // from the Connector through interactions with he backing database.
 type MockHostConnector struct {
@@ -89,3 +119,43 @@ func (hc *MockHostConnector) FindHostById(id string) (*host.Host, error) {
 		Message:    fmt.Sprintf("host with id %s not found", id),
 	}
 }
+
+func (hc *MockHostConnector) QuarantineHost(h *host.Host, reason string) error {
+	if h.Status == evergreen.HostQuarantined {
+		return &rest.APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("host %s is already quarantined", h.Id),
+		}
+	}
+	if h.Provider != evergreen.HostTypeStatic {
+		return &rest.APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("host %s is not a static host", h.Id),
+		}
+	}
+	return hc.setStatus(h, evergreen.HostQuarantined)
+}
+
+func (hc *MockHostConnector) ReadmitHost(h *host.Host, reason string) error {
+	if h.Status != evergreen.HostQuarantined {
+		return &rest.APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("cannot re-admit host %s with status %s", h.Id, h.Status),
+		}
+	}
+	return hc.setStatus(h, evergreen.HostRunning)
+}
+
+func (hc *MockHostConnector) setStatus(h *host.Host, status string) error {
+	for ix := range hc.CachedHosts {
+		if hc.CachedHosts[ix].Id == h.Id {
+			hc.CachedHosts[ix].Status = status
+			h.Status = status
+			return nil
+		}
+	}
+	return &rest.APIError{
+		StatusCode: http.StatusNotFound,
+		Message:    fmt.Sprintf("host with id %s not found", h.Id),
+	}
+}
@@ -17,6 +17,7 @@ type DBConnector struct {
 	DBTestConnector
 	DBMetricsConnector
 	DBBuildConnector
+	DBPerfConnector
 }
 
 func (ctx *DBConnector) GetSuperUsers() []string   { return ctx.superUsers }
@@ -39,6 +40,7 @@ type MockConnector struct {
 	MockTestConnector
 	MockMetricsConnector
 	MockBuildConnector
+	MockPerfConnector
 }
 
 func (ctx *MockConnector) GetSuperUsers() []string   { return ctx.superUsers }
@@ -8,6 +8,7 @@ import (
 	"github.com/evergreen-ci/evergreen/model/build"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/model/perf"
 	"github.com/evergreen-ci/evergreen/model/task"
 	"github.com/mongodb/grip/message"
 )
@@ -67,14 +68,33 @@ type Connector interface {
 	FindHostsById(string, string, int, int) ([]host.Host, error)
 	FindHostById(string) (*host.Host, error)
 
+	// QuarantineHost stops new tasks from being dispatched to a host, and
+	// ReadmitHost returns a quarantined host to service. Both take the reason
+	// to record in the host's event log.
+	QuarantineHost(*host.Host, string) error
+	ReadmitHost(*host.Host, string) error
+
 	// FetchContext is a method to fetch a context given a series of identifiers.
 	FetchContext(string, string, string, string, string) (model.Context, error)
 
 	// FindAllDistros is a method to find a sorted list of all distros.
 	FindAllDistros() ([]distro.Distro, error)
 
+	// GetDistroHostPools is a method to find the number of hosts currently
+	// running for each of the given distros and the warm pools they must keep,
+	// in the same order as the distros.
+	GetDistroHostPools([]distro.Distro) ([]host.PoolState, error)
+
 	// FindTaskSystemMetrics and FindTaskProcessMetrics provide
 	// access to the metrics data collected by agents during task execution
 	FindTaskSystemMetrics(string, time.Time, int, int) ([]*message.SystemInfo, error)
 	FindTaskProcessMetrics(string, time.Time, int, int) ([][]*message.ProcessInfo, error)
+
+	// FindPerfMetricsByTask is a method to find the perf metrics reported
+	// by a task.
+	FindPerfMetricsByTask(string) ([]perf.Point, error)
+
+	// FindPerfSeries is a method to find the points of a perf metric series
+	// in order of revision.
+	FindPerfSeries(perf.SeriesQuery) ([]perf.Point, error)
 }
@@ -0,0 +1,115 @@
+package data
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/evergreen-ci/evergreen/model/perf"
+	"github.com/evergreen-ci/evergreen/rest"
+	"github.com/pkg/errors"
+)
+
+// DBPerfConnector is a struct that implements the perf metric related
+// functions of the Connector interface through interactions with the backing
+// database.
+type DBPerfConnector struct{}
+
+// FindPerfMetricsByTask returns the metrics reported by the given task.
+func (pc *DBPerfConnector) FindPerfMetricsByTask(taskId string) ([]perf.Point, error) {
+	points, err := perf.FindAll(perf.ByTaskId(taskId))
+	if err != nil {
+		return nil, errors.Wrapf(err, "problem fetching perf metrics for task %s", taskId)
+	}
+	if len(points) == 0 {
+		return nil, &rest.APIError{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("no perf metrics found for task %s", taskId),
+		}
+	}
+	return points, nil
+}
+
+// FindPerfSeries returns the points of the series matching the query, in
+// order of revision.
+func (pc *DBPerfConnector) FindPerfSeries(q perf.SeriesQuery) ([]perf.Point, error) {
+	points, err := perf.FindSeries(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, &rest.APIError{
+			StatusCode: http.StatusNotFound,
+			Message: fmt.Sprintf("no points found for metric '%s' of task '%s' on variant '%s' of project '%s'",
+				q.Name, q.TaskName, q.Variant, q.ProjectId),
+		}
+	}
+	return points, nil
+}
+
+// MockPerfConnector is a struct that implements the perf metric related
+// functions of the Connector interface without needing to use a database.
+type MockPerfConnector struct {
+	CachedPoints []perf.Point
+}
+
+// FindPerfMetricsByTask returns the cached points of the given task.
+func (mpc *MockPerfConnector) FindPerfMetricsByTask(taskId string) ([]perf.Point, error) {
+	points := []perf.Point{}
+	for _, p := range mpc.CachedPoints {
+		if p.TaskId == taskId {
+			points = append(points, p)
+		}
+	}
+	if len(points) == 0 {
+		return nil, &rest.APIError{
+			StatusCode: http.StatusNotFound,
+			Message:    fmt.Sprintf("no perf metrics found for task %s", taskId),
+		}
+	}
+	sort.Sort(pointsByName(points))
+	return points, nil
+}
+
+// FindPerfSeries returns the cached points matching the query, in order of
+// revision.
+func (mpc *MockPerfConnector) FindPerfSeries(q perf.SeriesQuery) ([]perf.Point, error) {
+	points := []perf.Point{}
+	for _, p := range mpc.CachedPoints {
+		if p.ProjectId != q.ProjectId || p.Variant != q.Variant || p.TaskName != q.TaskName ||
+			p.Name != q.Name || p.IsPatch {
+			continue
+		}
+		if (q.StartOrder != 0 && p.RevisionOrderNumber < q.StartOrder) ||
+			(q.EndOrder != 0 && p.RevisionOrderNumber > q.EndOrder) {
+			continue
+		}
+		points = append(points, p)
+	}
+	if len(points) == 0 {
+		return nil, &rest.APIError{
+			StatusCode: http.StatusNotFound,
+			Message: fmt.Sprintf("no points found for metric '%s' of task '%s' on variant '%s' of project '%s'",
+				q.Name, q.TaskName, q.Variant, q.ProjectId),
+		}
+	}
+	sort.Sort(pointsByOrder(points))
+	if q.Limit > 0 && len(points) > q.Limit {
+		points = points[len(points)-q.Limit:]
+	}
+	return points, nil
+}
+
+type pointsByName []perf.Point
+
+func (p pointsByName) Len() int           { return len(p) }
+func (p pointsByName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p pointsByName) Less(i, j int) bool { return p[i].Name < p[j].Name }
+
+type pointsByOrder []perf.Point
+
+func (p pointsByOrder) Len() int      { return len(p) }
+func (p pointsByOrder) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p pointsByOrder) Less(i, j int) bool {
+	return p[i].RevisionOrderNumber < p[j].RevisionOrderNumber
+}
@@ -2,20 +2,63 @@ package model
 
 import (
 	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/pkg/errors"
 )
 
 // APIDistro is the model to be returned by the API whenever distros are fetched.
 // EVG-1717 will implement the remainder of the distro model.
 type APIDistro struct {
-	Id APIString `json:"_id"`
+	Id            APIString         `json:"_id"`
+	PoolSize      int               `json:"pool_size"`
+	MinHosts      int               `json:"min_hosts"`
+	WarmSchedules []APIWarmSchedule `json:"warm_schedules"`
+	HostPool      APIHostPool       `json:"host_pool"`
 }
 
-// BuildFromService converts from service level structs to an APIDistro.
+// APIWarmSchedule is a window of time during which a distro keeps a
+// minimum number of hosts provisioned.
+type APIWarmSchedule struct {
+	Days      []string  `json:"days"`
+	StartHour int       `json:"start_hour"`
+	EndHour   int       `json:"end_hour"`
+	TimeZone  APIString `json:"time_zone"`
+	MinHosts  int       `json:"min_hosts"`
+}
+
+// APIHostPool is the current state of a distro's hosts.
+type APIHostPool struct {
+	NumHosts        int `json:"num_hosts"`
+	NumFreeHosts    int `json:"num_free_hosts"`
+	CurrentMinHosts int `json:"current_min_hosts"`
+}
+
+// BuildFromService converts from service level structs to an APIDistro. It can
+// be called multiple times with different data types, a service layer distro
+// and a service layer host pool state, which are each loaded into the data
+// structure.
 func (apiDistro *APIDistro) BuildFromService(h interface{}) error {
 	switch v := h.(type) {
 	case distro.Distro:
 		apiDistro.Id = APIString(v.Id)
+		apiDistro.PoolSize = v.PoolSize
+		apiDistro.MinHosts = v.MinHosts
+		apiDistro.WarmSchedules = make([]APIWarmSchedule, 0, len(v.WarmSchedules))
+		for _, s := range v.WarmSchedules {
+			apiDistro.WarmSchedules = append(apiDistro.WarmSchedules, APIWarmSchedule{
+				Days:      s.Days,
+				StartHour: s.StartHour,
+				EndHour:   s.EndHour,
+				TimeZone:  APIString(s.TimeZone),
+				MinHosts:  s.MinHosts,
+			})
+		}
+	case host.PoolState:
+		apiDistro.HostPool = APIHostPool{
+			NumHosts:        v.NumHosts,
+			NumFreeHosts:    v.NumFreeHosts,
+			CurrentMinHosts: v.MinHosts,
+		}
 	default:
 		return errors.Errorf("incorrect type when fetching converting distro type")
 	}
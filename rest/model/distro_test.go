@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,3 +17,34 @@ func TestDistroBuildFromService(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, string(apiDistro.Id), d.Id)
 }
+
+func TestDistroBuildFromServiceWithHostPool(t *testing.T) {
+	d := distro.Distro{
+		Id:       "testId",
+		PoolSize: 10,
+		MinHosts: 1,
+		WarmSchedules: []distro.WarmSchedule{
+			{Days: []string{"weekdays"}, StartHour: 8, EndHour: 18, MinHosts: 4},
+		},
+	}
+	pool := host.PoolState{
+		DistroId:     "testId",
+		NumHosts:     5,
+		NumFreeHosts: 2,
+		MinHosts:     4,
+	}
+	apiDistro := &APIDistro{}
+	assert.Nil(t, apiDistro.BuildFromService(d))
+	assert.Nil(t, apiDistro.BuildFromService(pool))
+
+	assert.Equal(t, 10, apiDistro.PoolSize)
+	assert.Equal(t, 1, apiDistro.MinHosts)
+	assert.Len(t, apiDistro.WarmSchedules, 1)
+	assert.Equal(t, []string{"weekdays"}, apiDistro.WarmSchedules[0].Days)
+	assert.Equal(t, 8, apiDistro.WarmSchedules[0].StartHour)
+	assert.Equal(t, 18, apiDistro.WarmSchedules[0].EndHour)
+	assert.Equal(t, 4, apiDistro.WarmSchedules[0].MinHosts)
+	assert.Equal(t, 5, apiDistro.HostPool.NumHosts)
+	assert.Equal(t, 2, apiDistro.HostPool.NumFreeHosts)
+	assert.Equal(t, 4, apiDistro.HostPool.CurrentMinHosts)
+}
@@ -17,6 +17,9 @@ type APIHost struct {
 	User        APIString  `json:"user"`
 	Status      APIString  `json:"status"`
 	RunningTask taskInfo   `json:"running_task"`
+
+	SystemFailures  int  `json:"system_failures"`
+	AutoQuarantined bool `json:"auto_quarantined"`
 }
 
 type distroInfo struct {
@@ -44,6 +47,8 @@ func (apiHost *APIHost) BuildFromService(h interface{}) error {
 		apiHost.Type = APIString(v.InstanceType)
 		apiHost.User = APIString(v.UserData)
 		apiHost.Status = APIString(v.Status)
+		apiHost.SystemFailures = v.SystemFailures
+		apiHost.AutoQuarantined = v.AutoQuarantined
 
 		di := distroInfo{
 			Id:       APIString(v.Distro.Id),
@@ -74,6 +79,9 @@ func (apiHost *APIHost) ToService() (interface{}, error) {
 		InstanceType: string(apiHost.Type),
 		UserData:     string(apiHost.User),
 		Status:       string(apiHost.Status),
+
+		SystemFailures:  apiHost.SystemFailures,
+		AutoQuarantined: apiHost.AutoQuarantined,
 	}
 	return interface{}(h), nil
 }
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"github.com/evergreen-ci/evergreen/model/perf"
+	"github.com/pkg/errors"
+)
+
+// APIPerfPoint is the model to be returned by the API whenever the perf
+// metrics reported by tasks are fetched.
+type APIPerfPoint struct {
+	TaskId              APIString `json:"task_id"`
+	TaskName            APIString `json:"task_name"`
+	ProjectId           APIString `json:"project_id"`
+	Variant             APIString `json:"build_variant"`
+	VersionId           APIString `json:"version_id"`
+	Revision            APIString `json:"revision"`
+	RevisionOrderNumber int       `json:"order"`
+	IsPatch             bool      `json:"is_patch"`
+	CreateTime          APITime   `json:"create_time"`
+	Name                APIString `json:"name"`
+	Value               float64   `json:"value"`
+	Unit                APIString `json:"unit"`
+}
+
+// BuildFromService converts from a service level perf.Point to an
+// APIPerfPoint.
+func (apiPoint *APIPerfPoint) BuildFromService(h interface{}) error {
+	v, ok := h.(perf.Point)
+	if !ok {
+		return errors.Errorf("incorrect type %T when converting perf point", h)
+	}
+	apiPoint.TaskId = APIString(v.TaskId)
+	apiPoint.TaskName = APIString(v.TaskName)
+	apiPoint.ProjectId = APIString(v.ProjectId)
+	apiPoint.Variant = APIString(v.Variant)
+	apiPoint.VersionId = APIString(v.VersionId)
+	apiPoint.Revision = APIString(v.Revision)
+	apiPoint.RevisionOrderNumber = v.RevisionOrderNumber
+	apiPoint.IsPatch = v.IsPatch
+	apiPoint.CreateTime = NewTime(v.CreateTime)
+	apiPoint.Name = APIString(v.Name)
+	apiPoint.Value = v.Value
+	apiPoint.Unit = APIString(v.Unit)
+	return nil
+}
+
+// ToService returns a service layer perf.Point using the data from the
+// APIPerfPoint.
+func (apiPoint *APIPerfPoint) ToService() (interface{}, error) {
+	return nil, errors.New("not implemented for read-only route")
+}
+
+// APIPerfSeries is the model to be returned by the API whenever the series
+// of a perf metric is fetched, along with a summary of its values and the
+// revisions at which it shifts.
+type APIPerfSeries struct {
+	ProjectId    APIString          `json:"project_id"`
+	Variant      APIString          `json:"build_variant"`
+	TaskName     APIString          `json:"task_name"`
+	Name         APIString          `json:"name"`
+	Unit         APIString          `json:"unit"`
+	Points       []APIPerfPoint     `json:"points"`
+	Stats        perf.Stats         `json:"stats"`
+	ChangePoints []perf.ChangePoint `json:"change_points"`
+}
+
+// BuildFromService converts from a service level perf.Series to an
+// APIPerfSeries.
+func (apiSeries *APIPerfSeries) BuildFromService(h interface{}) error {
+	v, ok := h.(perf.Series)
+	if !ok {
+		return errors.Errorf("incorrect type %T when converting perf series", h)
+	}
+	apiSeries.Points = make([]APIPerfPoint, len(v.Points))
+	for i, p := range v.Points {
+		if err := apiSeries.Points[i].BuildFromService(p); err != nil {
+			return err
+		}
+	}
+	if len(v.Points) > 0 {
+		first := v.Points[0]
+		apiSeries.ProjectId = APIString(first.ProjectId)
+		apiSeries.Variant = APIString(first.Variant)
+		apiSeries.TaskName = APIString(first.TaskName)
+		apiSeries.Name = APIString(first.Name)
+		apiSeries.Unit = APIString(first.Unit)
+	}
+	apiSeries.Stats = v.Stats
+	apiSeries.ChangePoints = v.ChangePoints
+	return nil
+}
+
+// ToService returns a service layer perf.Series using the data from the
+// APIPerfSeries.
+func (apiSeries *APIPerfSeries) ToService() (interface{}, error) {
+	return nil, errors.New("not implemented for read-only route")
+}
@@ -51,9 +51,22 @@ type logLinks struct {
 	SystemLogLink APIString `json:"system_log"`
 }
 type apiTaskEndDetail struct {
-	Status      APIString `json:"status"`
+	Status      APIString         `json:"status"`
+	Type        APIString         `json:"type"`
+	Description APIString         `json:"desc"`
+	TimedOut    bool              `json:"timed_out"`
+	Commands    []apiCommandTrace `json:"commands,omitempty"`
+}
+
+type apiCommandTrace struct {
+	Name        APIString `json:"name"`
+	DisplayName APIString `json:"display_name"`
+	Function    APIString `json:"function"`
 	Type        APIString `json:"type"`
-	Description APIString `json:"desc"`
+	Start       APITime   `json:"start_time"`
+	End         APITime   `json:"end_time"`
+	Status      APIString `json:"status"`
+	Error       APIString `json:"error"`
 	TimedOut    bool      `json:"timed_out"`
 }
 
@@ -89,6 +102,7 @@ func (at *APITask) BuildFromService(t interface{}) error {
 				Type:        APIString(v.Details.Type),
 				Description: APIString(v.Details.Description),
 				TimedOut:    v.Details.TimedOut,
+				Commands:    buildCommandTraces(v.Details.Commands),
 			},
 			Status:           APIString(v.Status),
 			TimeTaken:        v.TimeTaken,
@@ -146,6 +160,7 @@ func (ad *APITask) ToService() (interface{}, error) {
 			Type:        string(ad.Details.Type),
 			Description: string(ad.Details.Description),
 			TimedOut:    ad.Details.TimedOut,
+			Commands:    commandTracesToService(ad.Details.Commands),
 		},
 		Status:           string(ad.Status),
 		TimeTaken:        ad.TimeTaken,
@@ -160,3 +175,45 @@ func (ad *APITask) ToService() (interface{}, error) {
 	st.DependsOn = dependsOn
 	return interface{}(st), nil
 }
+
+func buildCommandTraces(traces []apimodels.CommandTrace) []apiCommandTrace {
+	if len(traces) == 0 {
+		return nil
+	}
+	out := make([]apiCommandTrace, 0, len(traces))
+	for _, t := range traces {
+		out = append(out, apiCommandTrace{
+			Name:        APIString(t.Name),
+			DisplayName: APIString(t.DisplayName),
+			Function:    APIString(t.Function),
+			Type:        APIString(t.Type),
+			Start:       APITime(t.Start),
+			End:         APITime(t.End),
+			Status:      APIString(t.Status),
+			Error:       APIString(t.Error),
+			TimedOut:    t.TimedOut,
+		})
+	}
+	return out
+}
+
+func commandTracesToService(traces []apiCommandTrace) []apimodels.CommandTrace {
+	if len(traces) == 0 {
+		return nil
+	}
+	out := make([]apimodels.CommandTrace, 0, len(traces))
+	for _, t := range traces {
+		out = append(out, apimodels.CommandTrace{
+			Name:        string(t.Name),
+			DisplayName: string(t.DisplayName),
+			Function:    string(t.Function),
+			Type:        string(t.Type),
+			Start:       time.Time(t.Start),
+			End:         time.Time(t.End),
+			Status:      string(t.Status),
+			Error:       string(t.Error),
+			TimedOut:    t.TimedOut,
+		})
+	}
+	return out
+}
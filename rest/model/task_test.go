@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/evergreen-ci/evergreen/apimodels"
 	"github.com/evergreen-ci/evergreen/model/task"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -94,6 +95,58 @@ func TestTaskBuildFromService(t *testing.T) {
 				},
 				st: task.Task{},
 			},
+			{
+				at: APITask{
+					Id: APIString("failedId"),
+					Details: apiTaskEndDetail{
+						Status:      APIString("failed"),
+						Type:        APIString("setup"),
+						Description: APIString("'git.get_project' in \"fetch\""),
+						TimedOut:    true,
+						Commands: []apiCommandTrace{
+							{
+								Name:        APIString("git.get_project"),
+								DisplayName: APIString("'git.get_project' in \"fetch\""),
+								Function:    APIString("fetch"),
+								Type:        APIString("setup"),
+								Start:       APITime(sTime),
+								End:         APITime(fTime),
+								Status:      APIString("failed"),
+								Error:       APIString("exit status 1"),
+								TimedOut:    true,
+							},
+						},
+					},
+					Logs: logLinks{
+						AllLogLink:    "url/task_log_raw/failedId/0?type=ALL",
+						TaskLogLink:   "url/task_log_raw/failedId/0?type=T",
+						SystemLogLink: "url/task_log_raw/failedId/0?type=S",
+						AgentLogLink:  "url/task_log_raw/failedId/0?type=E",
+					},
+				},
+				st: task.Task{
+					Id: "failedId",
+					Details: apimodels.TaskEndDetail{
+						Status:      "failed",
+						Type:        "setup",
+						Description: "'git.get_project' in \"fetch\"",
+						TimedOut:    true,
+						Commands: []apimodels.CommandTrace{
+							{
+								Name:        "git.get_project",
+								DisplayName: "'git.get_project' in \"fetch\"",
+								Function:    "fetch",
+								Type:        "setup",
+								Start:       sTime,
+								End:         fTime,
+								Status:      "failed",
+								Error:       "exit status 1",
+								TimedOut:    true,
+							},
+						},
+					},
+				},
+			},
 		}
 		Convey("running BuildFromService(), should produce the equivalent model", func() {
 			for _, tc := range modelPairs {
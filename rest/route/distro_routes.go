@@ -43,12 +43,22 @@ func (dgh *distroGetHandler) Execute(ctx context.Context, sc data.Connector) (Re
 		}
 		return ResponseData{}, err
 	}
+	pools, err := sc.GetDistroHostPools(distros)
+	if err != nil {
+		if _, ok := err.(*rest.APIError); !ok {
+			err = errors.Wrap(err, "Database error")
+		}
+		return ResponseData{}, err
+	}
 	models := make([]model.Model, len(distros))
 	for i, d := range distros {
 		distroModel := &model.APIDistro{}
 		if err := distroModel.BuildFromService(d); err != nil {
 			return ResponseData{}, err
 		}
+		if err := distroModel.BuildFromService(pools[i]); err != nil {
+			return ResponseData{}, err
+		}
 		models[i] = distroModel
 	}
 
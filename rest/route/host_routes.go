@@ -1,6 +1,9 @@
 package route
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/evergreen-ci/evergreen"
@@ -9,6 +12,7 @@ import (
 	"github.com/evergreen-ci/evergreen/rest"
 	"github.com/evergreen-ci/evergreen/rest/data"
 	"github.com/evergreen-ci/evergreen/rest/model"
+	"github.com/evergreen-ci/evergreen/util"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
@@ -232,3 +236,105 @@ func makePrevHostsPage(hosts []host.Host) *Page {
 	}
 	return prevPage
 }
+
+func getHostQuarantineRouteManager(route string, version int) *RouteManager {
+	return getHostAdmissionRouteManager(route, version, false)
+}
+
+func getHostReadmitRouteManager(route string, version int) *RouteManager {
+	return getHostAdmissionRouteManager(route, version, true)
+}
+
+func getHostAdmissionRouteManager(route string, version int, readmit bool) *RouteManager {
+	hah := &hostAdmissionHandler{readmit: readmit}
+	hostPost := MethodHandler{
+		PrefetchFunctions: []PrefetchFunc{PrefetchUser},
+		Authenticator:     &RequireUserAuthenticator{},
+		RequestHandler:    hah.Handler(),
+		MethodType:        evergreen.MethodPost,
+	}
+
+	hostRoute := RouteManager{
+		Route:   route,
+		Methods: []MethodHandler{hostPost},
+		Version: version,
+	}
+	return &hostRoute
+}
+
+// hostAdmissionHandler implements the routes POST /hosts/{host_id}/quarantine
+// and POST /hosts/{host_id}/readmit. Quarantining a host stops new tasks from
+// being dispatched to it while letting its current task finish; re-admitting
+// it returns it to service. The request body may give a reason for the change.
+type hostAdmissionHandler struct {
+	Reason string `json:"reason"`
+
+	readmit bool
+	hostId  string
+	user    string
+}
+
+func (hah *hostAdmissionHandler) Handler() RequestHandler {
+	return &hostAdmissionHandler{readmit: hah.readmit}
+}
+
+// ParseAndValidate fetches the hostId from the request path, the optional
+// reason from the request body and the user from the request context.
+func (hah *hostAdmissionHandler) ParseAndValidate(ctx context.Context, r *http.Request) error {
+	hah.hostId = mux.Vars(r)["host_id"]
+	hah.user = MustHaveUser(ctx).Username()
+
+	body := util.NewRequestReader(r)
+	defer body.Close()
+	if err := json.NewDecoder(body).Decode(hah); err != nil && err != io.EOF {
+		return rest.APIError{
+			Message:    fmt.Sprintf("Invalid request body: %v", err),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	return nil
+}
+
+// Execute quarantines or re-admits the host and returns its updated state.
+func (hah *hostAdmissionHandler) Execute(ctx context.Context, sc data.Connector) (ResponseData, error) {
+	foundHost, err := sc.FindHostById(hah.hostId)
+	if err != nil {
+		if _, ok := err.(*rest.APIError); !ok {
+			err = errors.Wrap(err, "Database error")
+		}
+		return ResponseData{}, err
+	}
+
+	action := "quarantined"
+	if hah.readmit {
+		action = "re-admitted"
+	}
+	reason := fmt.Sprintf("%s by %s", action, hah.user)
+	if hah.Reason != "" {
+		reason = fmt.Sprintf("%s: %s", reason, hah.Reason)
+	}
+
+	if hah.readmit {
+		err = sc.ReadmitHost(foundHost, reason)
+	} else {
+		err = sc.QuarantineHost(foundHost, reason)
+	}
+	if err != nil {
+		if _, ok := err.(*rest.APIError); !ok {
+			err = errors.Wrap(err, "Database error")
+		}
+		return ResponseData{}, err
+	}
+
+	hostModel := &model.APIHost{}
+	if err = hostModel.BuildFromService(*foundHost); err != nil {
+		if _, ok := err.(*rest.APIError); !ok {
+			err = errors.Wrap(err, "API model error")
+		}
+		return ResponseData{}, err
+	}
+
+	return ResponseData{
+		Result: []model.Model{hostModel},
+	}, nil
+}
@@ -1,9 +1,12 @@
 package route
 
 import (
+	"net/http"
 	"testing"
 
+	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/rest"
 	"github.com/evergreen-ci/evergreen/rest/data"
 	"github.com/evergreen-ci/evergreen/rest/model"
 	"github.com/stretchr/testify/suite"
@@ -59,3 +62,80 @@ func (s *HostSuite) TestFindByIdFail() {
 	_, ok := handler.Execute(nil, s.sc)
 	s.Error(ok)
 }
+
+type HostAdmissionSuite struct {
+	sc *data.MockConnector
+
+	suite.Suite
+}
+
+func TestHostAdmissionSuite(t *testing.T) {
+	suite.Run(t, new(HostAdmissionSuite))
+}
+
+func (s *HostAdmissionSuite) SetupTest() {
+	s.sc = &data.MockConnector{
+		MockHostConnector: data.MockHostConnector{
+			CachedHosts: []host.Host{
+				{Id: "host1", Provider: evergreen.HostTypeStatic, Status: evergreen.HostRunning},
+				{Id: "host2", Provider: evergreen.HostTypeStatic, Status: evergreen.HostQuarantined},
+				{Id: "cloud-host", Provider: "ec2", Status: evergreen.HostRunning},
+			},
+		},
+	}
+}
+
+func (s *HostAdmissionSuite) TestQuarantine() {
+	handler := getHostQuarantineRouteManager("", 2).Methods[0].RequestHandler.Handler().(*hostAdmissionHandler)
+	handler.hostId = "host1"
+	handler.user = "admin"
+	res, err := handler.Execute(nil, s.sc)
+	s.NoError(err)
+	s.Require().Len(res.Result, 1)
+
+	h, ok := (res.Result[0]).(*model.APIHost)
+	s.True(ok)
+	s.Equal(model.APIString(evergreen.HostQuarantined), h.Status)
+	s.Equal(evergreen.HostQuarantined, s.sc.CachedHosts[0].Status)
+
+	// quarantining a quarantined host is an error
+	_, err = handler.Execute(nil, s.sc)
+	s.Error(err)
+}
+
+func (s *HostAdmissionSuite) TestQuarantineCloudHost() {
+	handler := getHostQuarantineRouteManager("", 2).Methods[0].RequestHandler.Handler().(*hostAdmissionHandler)
+	handler.hostId = "cloud-host"
+	handler.user = "admin"
+	_, err := handler.Execute(nil, s.sc)
+	s.Require().Error(err)
+	apiErr, ok := err.(*rest.APIError)
+	s.Require().True(ok)
+	s.Equal(http.StatusBadRequest, apiErr.StatusCode)
+	s.Equal(evergreen.HostRunning, s.sc.CachedHosts[2].Status)
+}
+
+func (s *HostAdmissionSuite) TestReadmit() {
+	handler := getHostReadmitRouteManager("", 2).Methods[0].RequestHandler.Handler().(*hostAdmissionHandler)
+	handler.hostId = "host2"
+	handler.user = "admin"
+	res, err := handler.Execute(nil, s.sc)
+	s.NoError(err)
+	s.Require().Len(res.Result, 1)
+
+	h, ok := (res.Result[0]).(*model.APIHost)
+	s.True(ok)
+	s.Equal(model.APIString(evergreen.HostRunning), h.Status)
+
+	// only quarantined hosts can be re-admitted
+	handler.hostId = "host1"
+	_, err = handler.Execute(nil, s.sc)
+	s.Error(err)
+	s.Equal(evergreen.HostRunning, s.sc.CachedHosts[0].Status)
+}
+
+func (s *HostAdmissionSuite) TestMissingHost() {
+	handler := &hostAdmissionHandler{hostId: "host3", user: "admin"}
+	_, err := handler.Execute(nil, s.sc)
+	s.Error(err)
+}
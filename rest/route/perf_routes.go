@@ -0,0 +1,213 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/perf"
+	"github.com/evergreen-ci/evergreen/rest"
+	"github.com/evergreen-ci/evergreen/rest/data"
+	"github.com/evergreen-ci/evergreen/rest/model"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	// defaultPerfSeriesLimit is how many of the most recent points of a
+	// series are analyzed if no limit is given.
+	defaultPerfSeriesLimit = 100
+	// maxPerfSeriesLimit is the most points of a series that are analyzed
+	// in one request.
+	maxPerfSeriesLimit = 1000
+)
+
+////////////////////////////////////////////////////////////////////////
+//
+// Handler for the perf metrics reported by a task
+//
+//    /tasks/{task_id}/perf_metrics
+
+func getTaskPerfMetricsRouteManager(route string, version int) *RouteManager {
+	tpmh := &taskPerfMetricsHandler{}
+	return &RouteManager{
+		Route:   route,
+		Version: version,
+		Methods: []MethodHandler{
+			{
+				MethodType:     evergreen.MethodGet,
+				Authenticator:  &RequireUserAuthenticator{},
+				RequestHandler: tpmh.Handler(),
+			},
+		},
+	}
+}
+
+type taskPerfMetricsHandler struct {
+	taskId string
+}
+
+func (tpmh *taskPerfMetricsHandler) Handler() RequestHandler {
+	return &taskPerfMetricsHandler{}
+}
+
+func (tpmh *taskPerfMetricsHandler) ParseAndValidate(ctx context.Context, r *http.Request) error {
+	tpmh.taskId = mux.Vars(r)["task_id"]
+	return nil
+}
+
+func (tpmh *taskPerfMetricsHandler) Execute(ctx context.Context, sc data.Connector) (ResponseData, error) {
+	points, err := sc.FindPerfMetricsByTask(tpmh.taskId)
+	if err != nil {
+		if _, ok := err.(*rest.APIError); !ok {
+			err = errors.Wrap(err, "Database error")
+		}
+		return ResponseData{}, err
+	}
+
+	models := make([]model.Model, len(points))
+	for i, p := range points {
+		pointModel := &model.APIPerfPoint{}
+		if err = pointModel.BuildFromService(p); err != nil {
+			return ResponseData{}, errors.Wrap(err, "API model error")
+		}
+		models[i] = pointModel
+	}
+	return ResponseData{Result: models}, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+//
+// Handler for the series of a perf metric, aggregated across revisions
+//
+//    /projects/{project_id}/perf_metrics/{variant}/{task_name}/{metric}
+
+func getPerfSeriesRouteManager(route string, version int) *RouteManager {
+	psh := &perfSeriesHandler{}
+	return &RouteManager{
+		Route:   route,
+		Version: version,
+		Methods: []MethodHandler{
+			{
+				MethodType:     evergreen.MethodGet,
+				Authenticator:  &RequireUserAuthenticator{},
+				RequestHandler: psh.Handler(),
+			},
+		},
+	}
+}
+
+type perfSeriesHandler struct {
+	query   perf.SeriesQuery
+	options perf.AnalysisOptions
+}
+
+func (psh *perfSeriesHandler) Handler() RequestHandler {
+	return &perfSeriesHandler{}
+}
+
+// ParseAndValidate reads the series from the route and the range of
+// revisions and analysis options from the query parameters start_order,
+// end_order, limit, percentiles (a comma separated list), threshold and
+// min_segment.
+func (psh *perfSeriesHandler) ParseAndValidate(ctx context.Context, r *http.Request) error {
+	vars := mux.Vars(r)
+	psh.query = perf.SeriesQuery{
+		ProjectId: vars["project_id"],
+		Variant:   vars["variant"],
+		TaskName:  vars["task_name"],
+		Name:      vars["metric"],
+		Limit:     defaultPerfSeriesLimit,
+	}
+
+	vals := r.URL.Query()
+	var err error
+	if psh.query.StartOrder, err = parsePositiveInt(vals.Get("start_order"), "start_order", 0); err != nil {
+		return err
+	}
+	if psh.query.EndOrder, err = parsePositiveInt(vals.Get("end_order"), "end_order", 0); err != nil {
+		return err
+	}
+	if psh.query.EndOrder != 0 && psh.query.StartOrder > psh.query.EndOrder {
+		return rest.APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    "start_order must not be after end_order",
+		}
+	}
+	if psh.query.Limit, err = parsePositiveInt(vals.Get("limit"), "limit", defaultPerfSeriesLimit); err != nil {
+		return err
+	}
+	if psh.query.Limit > maxPerfSeriesLimit {
+		return rest.APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("limit must be at most %d", maxPerfSeriesLimit),
+		}
+	}
+
+	if p := vals.Get("percentiles"); p != "" {
+		for _, s := range strings.Split(p, ",") {
+			percentile, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil || percentile < 0 || percentile > 100 {
+				return rest.APIError{
+					StatusCode: http.StatusBadRequest,
+					Message:    fmt.Sprintf("invalid percentile '%s': must be between 0 and 100", s),
+				}
+			}
+			psh.options.Percentiles = append(psh.options.Percentiles, percentile)
+		}
+	}
+	if t := vals.Get("threshold"); t != "" {
+		psh.options.Threshold, err = strconv.ParseFloat(t, 64)
+		if err != nil || psh.options.Threshold <= 0 {
+			return rest.APIError{
+				StatusCode: http.StatusBadRequest,
+				Message:    fmt.Sprintf("invalid threshold '%s': must be a positive number", t),
+			}
+		}
+	}
+	if m := vals.Get("min_segment"); m != "" {
+		psh.options.MinSegment, err = strconv.Atoi(m)
+		if err != nil || psh.options.MinSegment < 2 {
+			return rest.APIError{
+				StatusCode: http.StatusBadRequest,
+				Message:    fmt.Sprintf("invalid min_segment '%s': must be an integer of at least 2", m),
+			}
+		}
+	}
+	return nil
+}
+
+func (psh *perfSeriesHandler) Execute(ctx context.Context, sc data.Connector) (ResponseData, error) {
+	points, err := sc.FindPerfSeries(psh.query)
+	if err != nil {
+		if _, ok := err.(*rest.APIError); !ok {
+			err = errors.Wrap(err, "Database error")
+		}
+		return ResponseData{}, err
+	}
+
+	seriesModel := &model.APIPerfSeries{}
+	if err = seriesModel.BuildFromService(perf.Analyze(points, psh.options)); err != nil {
+		return ResponseData{}, errors.Wrap(err, "API model error")
+	}
+	return ResponseData{Result: []model.Model{seriesModel}}, nil
+}
+
+// parsePositiveInt parses the value of the named query parameter, returning
+// def if the value is blank.
+func parsePositiveInt(value, name string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i <= 0 {
+		return 0, rest.APIError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("invalid %s '%s': must be a positive integer", name, value),
+		}
+	}
+	return i, nil
+}
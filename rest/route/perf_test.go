@@ -0,0 +1,153 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/model/perf"
+	"github.com/evergreen-ci/evergreen/rest"
+	"github.com/evergreen-ci/evergreen/rest/data"
+	"github.com/evergreen-ci/evergreen/rest/model"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+type PerfRoutesSuite struct {
+	sc *data.MockConnector
+	suite.Suite
+}
+
+func TestPerfRoutesSuite(t *testing.T) {
+	suite.Run(t, new(PerfRoutesSuite))
+}
+
+func (s *PerfRoutesSuite) SetupTest() {
+	points := []perf.Point{}
+	values := []float64{100, 102, 99, 101, 100, 80, 81, 79, 80, 82}
+	for i, v := range values {
+		points = append(points, perf.Point{
+			TaskId:              fmt.Sprintf("task%d", i+1),
+			TaskName:            "bench",
+			ProjectId:           "project",
+			Variant:             "linux",
+			Revision:            fmt.Sprintf("rev%d", i+1),
+			RevisionOrderNumber: i + 1,
+			Name:                "insert",
+			Value:               v,
+			Unit:                "ops/sec",
+		})
+	}
+	points = append(points,
+		perf.Point{TaskId: "task1", TaskName: "bench", ProjectId: "project", Variant: "linux",
+			RevisionOrderNumber: 1, Name: "delete", Value: 5, Unit: "ops/sec"},
+		perf.Point{TaskId: "patch", TaskName: "bench", ProjectId: "project", Variant: "linux",
+			RevisionOrderNumber: 10, IsPatch: true, Name: "insert", Value: 1, Unit: "ops/sec"},
+	)
+
+	s.sc = &data.MockConnector{
+		MockPerfConnector: data.MockPerfConnector{CachedPoints: points},
+	}
+}
+
+func (s *PerfRoutesSuite) parseSeries(query string) (*perfSeriesHandler, error) {
+	req, err := http.NewRequest(evergreen.MethodGet, "/projects/project/perf_metrics/linux/bench/insert?"+query, nil)
+	s.Require().NoError(err)
+
+	h := (&perfSeriesHandler{}).Handler().(*perfSeriesHandler)
+	r := mux.NewRouter()
+	r.HandleFunc("/projects/{project_id}/perf_metrics/{variant}/{task_name}/{metric}",
+		func(w http.ResponseWriter, req *http.Request) {
+			err = h.ParseAndValidate(context.Background(), req)
+		})
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	s.Equal("insert", h.query.Name)
+	return h, err
+}
+
+func (s *PerfRoutesSuite) TestTaskMetricsAreSortedByName() {
+	h := &taskPerfMetricsHandler{taskId: "task1"}
+	res, err := h.Execute(context.Background(), s.sc)
+	s.NoError(err)
+	s.Len(res.Result, 2)
+	s.Equal(model.APIString("delete"), res.Result[0].(*model.APIPerfPoint).Name)
+	s.Equal(model.APIString("insert"), res.Result[1].(*model.APIPerfPoint).Name)
+}
+
+func (s *PerfRoutesSuite) TestTaskWithoutMetricsIsNotFound() {
+	h := &taskPerfMetricsHandler{taskId: "none"}
+	_, err := h.Execute(context.Background(), s.sc)
+	s.Require().Error(err)
+	apiErr, ok := err.(*rest.APIError)
+	s.Require().True(ok)
+	s.Equal(http.StatusNotFound, apiErr.StatusCode)
+}
+
+func (s *PerfRoutesSuite) TestSeriesIsAnalyzed() {
+	h, err := s.parseSeries("")
+	s.Require().NoError(err)
+	res, err := h.Execute(context.Background(), s.sc)
+	s.Require().NoError(err)
+	s.Require().Len(res.Result, 1)
+
+	series := res.Result[0].(*model.APIPerfSeries)
+	s.Equal(model.APIString("ops/sec"), series.Unit)
+	s.Len(series.Points, 10)
+	s.Equal(10, series.Stats.Count)
+	s.Len(series.Stats.Percentiles, len(perf.DefaultPercentiles))
+	s.Require().Len(series.ChangePoints, 1)
+	s.Equal(6, series.ChangePoints[0].RevisionOrderNumber)
+	s.Equal("task6", series.ChangePoints[0].TaskId)
+}
+
+func (s *PerfRoutesSuite) TestSeriesRangeAndOptions() {
+	h, err := s.parseSeries("start_order=2&end_order=9&limit=4&percentiles=25,75&threshold=2.5&min_segment=2")
+	s.Require().NoError(err)
+	s.Equal(2, h.query.StartOrder)
+	s.Equal(9, h.query.EndOrder)
+	s.Equal(4, h.query.Limit)
+	s.Equal([]float64{25, 75}, h.options.Percentiles)
+	s.Equal(2.5, h.options.Threshold)
+	s.Equal(2, h.options.MinSegment)
+
+	res, err := h.Execute(context.Background(), s.sc)
+	s.Require().NoError(err)
+	series := res.Result[0].(*model.APIPerfSeries)
+	s.Require().Len(series.Points, 4)
+	s.Equal(6, series.Points[0].RevisionOrderNumber)
+	s.Equal(9, series.Points[3].RevisionOrderNumber)
+	s.Len(series.Stats.Percentiles, 2)
+}
+
+func (s *PerfRoutesSuite) TestInvalidParamsAreRejected() {
+	for _, query := range []string{
+		"start_order=x",
+		"end_order=-1",
+		"start_order=5&end_order=4",
+		"limit=0",
+		"limit=1001",
+		"percentiles=50,101",
+		"percentiles=fifty",
+		"threshold=0",
+		"min_segment=1",
+	} {
+		_, err := s.parseSeries(query)
+		s.Require().Error(err, query)
+		apiErr, ok := err.(rest.APIError)
+		s.Require().True(ok, query)
+		s.Equal(http.StatusBadRequest, apiErr.StatusCode, query)
+	}
+}
+
+func (s *PerfRoutesSuite) TestRoutesRequireAUser() {
+	for _, rm := range []*RouteManager{
+		getTaskPerfMetricsRouteManager("", 2),
+		getPerfSeriesRouteManager("", 2),
+	} {
+		s.Require().Len(rm.Methods, 1)
+		s.IsType(&RequireUserAuthenticator{}, rm.Methods[0].Authenticator)
+	}
+}
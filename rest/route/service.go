@@ -30,10 +30,14 @@ func GetHandler(r *mux.Router, sc data.Connector) http.Handler {
 		"/distros":                 getDistroRouteManager,
 		"/hosts":                   getHostRouteManager,
 		"/hosts/{host_id}":                                     getHostIDRouteManager,
-		"/projects/{project_id}/revisions/{commit_hash}/tasks": getTasksByProjectAndCommitRouteManager,
+		"/hosts/{host_id}/quarantine":                          getHostQuarantineRouteManager,
+		"/hosts/{host_id}/readmit":                             getHostReadmitRouteManager,
+		"/projects/{project_id}/perf_metrics/{variant}/{task_name}/{metric}": getPerfSeriesRouteManager,
+		"/projects/{project_id}/revisions/{commit_hash}/tasks":               getTasksByProjectAndCommitRouteManager,
 		"/tasks/{task_id}":                                     getTaskRouteManager,
 		"/tasks/{task_id}/metrics/process":                     getTaskProcessMetricsManager,
 		"/tasks/{task_id}/metrics/system":                      getTaskSystemMetricsManager,
+		"/tasks/{task_id}/perf_metrics":                        getTaskPerfMetricsRouteManager,
 		"/tasks/{task_id}/restart":                             getTaskRestartRouteManager,
 		"/tasks/{task_id}/tests":                               getTestRouteManager,
 	}
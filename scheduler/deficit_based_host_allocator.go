@@ -1,6 +1,8 @@
 package scheduler
 
 import (
+	"time"
+
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/cloud/providers"
 	"github.com/evergreen-ci/evergreen/model/distro"
@@ -40,6 +42,8 @@ func (self *DeficitBasedHostAllocator) NewHostsNeeded(
 			&hostAllocatorData, distro, settings)
 	}
 
+	ensureMinimumHosts(newHostsNeeded, &hostAllocatorData, settings, time.Now())
+
 	return newHostsNeeded, nil
 }
 
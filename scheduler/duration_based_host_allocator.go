@@ -149,6 +149,8 @@ func (self *DurationBasedHostAllocator) NewHostsNeeded(
 		}
 	}
 
+	ensureMinimumHosts(newHostsNeeded, &hostAllocatorData, settings, time.Now())
+
 	grip.InfoWhenf(len(newHostsNeeded) > 0, "Reporting hosts needed: %+v", newHostsNeeded)
 	grip.InfoWhen(len(newHostsNeeded) == 0, "no new hosts needed.")
 
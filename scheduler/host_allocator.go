@@ -1,10 +1,15 @@
 package scheduler
 
 import (
+	"time"
+
 	"github.com/evergreen-ci/evergreen"
+	"github.com/evergreen-ci/evergreen/cloud/providers"
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
 )
 
 // HostAllocator is responsible for determining how many new hosts should be spun up.
@@ -28,3 +33,45 @@ type HostAllocatorData struct {
 	distros              map[string]distro.Distro
 	projectTaskDurations model.ProjectTaskDurations
 }
+
+// ensureMinimumHosts raises the number of new hosts needed for each distro so
+// that, together with its existing hosts, the distro keeps the warm pool of
+// hosts required at the given time by its min_hosts and warm schedules. This
+// considers every distro, including those with empty task queues. A distro
+// whose provider can't be checked is skipped, so that it doesn't hold up the
+// others.
+func ensureMinimumHosts(newHostsNeeded map[string]int, hostAllocatorData *HostAllocatorData,
+	settings *evergreen.Settings, now time.Time) {
+
+	for distroId, d := range hostAllocatorData.distros {
+		minHosts := d.MinimumHosts(now)
+		if minHosts == 0 {
+			continue
+		}
+
+		numWarmHosts := minHosts - len(hostAllocatorData.existingDistroHosts[distroId])
+		if numWarmHosts <= newHostsNeeded[distroId] {
+			continue
+		}
+
+		cloudManager, err := providers.GetCloudManager(d.Provider, settings)
+		if err != nil {
+			grip.Errorf("Couldn't get cloud manager for distro %s with provider %s: %+v",
+				distroId, d.Provider, err)
+			continue
+		}
+		can, err := cloudManager.CanSpawn()
+		if err != nil {
+			grip.Error(errors.Wrapf(err, "Couldn't check if cloud provider %s is spawnable",
+				d.Provider))
+			continue
+		}
+		if !can {
+			continue
+		}
+
+		grip.Infof("Spawning %d hosts for %s to keep a warm pool of %d hosts",
+			numWarmHosts, distroId, minHosts)
+		newHostsNeeded[distroId] = numWarmHosts
+	}
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/cloud/providers/static"
+	"github.com/evergreen-ci/evergreen/model/distro"
+	"github.com/evergreen-ci/evergreen/model/host"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEnsureMinimumHosts(t *testing.T) {
+	Convey("When ensuring distros keep a warm pool of hosts...", t, func() {
+		// 2017-06-05 is a Monday
+		now := time.Date(2017, time.June, 5, 10, 0, 0, 0, time.UTC)
+		hostAllocatorData := &HostAllocatorData{
+			existingDistroHosts: map[string][]host.Host{
+				"warm": {{Id: "h1"}},
+			},
+			distros: map[string]distro.Distro{
+				"warm": {
+					Id:       "warm",
+					Provider: "ec2",
+					PoolSize: 10,
+					MinHosts: 2,
+					WarmSchedules: []distro.WarmSchedule{
+						{Days: []string{"weekdays"}, StartHour: 8, EndHour: 18, MinHosts: 4},
+					},
+				},
+				"cold": {
+					Id:       "cold",
+					Provider: "ec2",
+					PoolSize: 10,
+				},
+				"static": {
+					Id:       "static",
+					Provider: static.ProviderName,
+					PoolSize: 10,
+					MinHosts: 2,
+				},
+				"broken": {
+					Id:       "broken",
+					Provider: "no-such-provider",
+					PoolSize: 10,
+					MinHosts: 2,
+				},
+			},
+		}
+
+		Convey("hosts should be spawned up to the active minimum", func() {
+			newHostsNeeded := map[string]int{}
+			ensureMinimumHosts(newHostsNeeded, hostAllocatorData, hostAllocatorTestConf, now)
+			So(newHostsNeeded["warm"], ShouldEqual, 3)
+			So(newHostsNeeded, ShouldNotContainKey, "cold")
+			So(newHostsNeeded, ShouldNotContainKey, "static")
+			So(newHostsNeeded, ShouldNotContainKey, "broken")
+
+			newHostsNeeded = map[string]int{}
+			ensureMinimumHosts(newHostsNeeded, hostAllocatorData, hostAllocatorTestConf, now.Add(12*time.Hour))
+			So(newHostsNeeded["warm"], ShouldEqual, 1)
+		})
+
+		Convey("hosts already requested by the allocator should count towards the minimum", func() {
+			newHostsNeeded := map[string]int{"warm": 5, "cold": 1}
+			ensureMinimumHosts(newHostsNeeded, hostAllocatorData, hostAllocatorTestConf, now)
+			So(newHostsNeeded["warm"], ShouldEqual, 5)
+			So(newHostsNeeded["cold"], ShouldEqual, 1)
+		})
+	})
+}
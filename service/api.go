@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/codegangsta/negroni"
@@ -355,6 +356,28 @@ func (as *APIServer) FetchProjectVars(w http.ResponseWriter, r *http.Request) {
 	as.WriteJSON(w, http.StatusOK, projectVars.Vars)
 }
 
+// FetchPrivateVars returns the names of the task's project's private variables,
+// so that the agent can keep their values out of the task's logs.
+func (as *APIServer) FetchPrivateVars(w http.ResponseWriter, r *http.Request) {
+	t := MustHaveTask(r)
+	projectVars, err := model.FindOneProjectVars(t.Project)
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	privateVars := []string{}
+	if projectVars != nil {
+		for name, private := range projectVars.PrivateVars {
+			if private {
+				privateVars = append(privateVars, name)
+			}
+		}
+	}
+	sort.Strings(privateVars)
+	as.WriteJSON(w, http.StatusOK, privateVars)
+}
+
 // AttachFiles updates file mappings for a task or build
 func (as *APIServer) AttachFiles(w http.ResponseWriter, r *http.Request) {
 	t := MustHaveTask(r)
@@ -808,6 +831,10 @@ func (as *APIServer) Handler() (http.Handler, error) {
 	// Agent routes
 	agentRouter := r.PathPrefix("/agent").Subrouter()
 	agentRouter.HandleFunc("/next_task", as.checkHost(as.NextTask)).Methods("GET")
+	agentRouter.HandleFunc("/unhealthy", as.checkHost(as.HostUnhealthy)).Methods("POST")
+	agentRouter.HandleFunc("/register", as.checkHost(as.RegisterAgent)).Methods("POST")
+	agentRouter.HandleFunc("/update/{arch}", as.checkHost(as.AgentUpdateInfo)).Methods("GET")
+	agentRouter.HandleFunc("/binary/{arch}", as.checkHost(as.AgentBinary)).Methods("GET")
 
 	taskRouter := r.PathPrefix("/task/{taskId}").Subrouter()
 
@@ -817,6 +844,7 @@ func (as *APIServer) Handler() (http.Handler, error) {
 	taskRouter.HandleFunc("/new_start", as.checkTask(true, as.checkHost(as.StartTask))).Methods("POST")
 
 	taskRouter.HandleFunc("/log", as.checkTask(true, as.checkHost(as.AppendTaskLog))).Methods("POST")
+	taskRouter.HandleFunc("/log_stream", as.checkTask(true, as.checkHost(as.StreamTaskLog))).Methods("GET")
 	taskRouter.HandleFunc("/heartbeat", as.checkTask(true, as.checkHost(as.Heartbeat))).Methods("POST")
 	taskRouter.HandleFunc("/results", as.checkTask(true, as.checkHost(as.AttachResults))).Methods("POST")
 	taskRouter.HandleFunc("/test_logs", as.checkTask(true, as.checkHost(as.AttachTestLog))).Methods("POST")
@@ -828,6 +856,7 @@ func (as *APIServer) Handler() (http.Handler, error) {
 	taskRouter.HandleFunc("/version", as.checkTask(false, as.GetVersion)).Methods("GET")
 	taskRouter.HandleFunc("/project_ref", as.checkTask(false, as.GetProjectRef)).Methods("GET")
 	taskRouter.HandleFunc("/fetch_vars", as.checkTask(true, as.FetchProjectVars)).Methods("GET")
+	taskRouter.HandleFunc("/fetch_private_vars", as.checkTask(true, as.FetchPrivateVars)).Methods("GET")
 
 	// Install plugin routes
 	for _, pl := range as.plugins {
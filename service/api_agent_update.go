@@ -0,0 +1,82 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/taskrunner"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// AgentUpdateInfo tells an agent updating itself which revision the compiled
+// agent for its architecture is, and its checksum.
+func (as *APIServer) AgentUpdateInfo(w http.ResponseWriter, r *http.Request) {
+	arch := mux.Vars(r)["arch"]
+	gateway := taskrunner.NewTaskRunner(&as.Settings).HostGateway
+
+	path, err := gateway.GetAgentExecutable(arch)
+	if err != nil {
+		as.LoggedError(w, r, http.StatusNotFound, err)
+		return
+	}
+	revision, err := gateway.GetAgentRevision()
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	as.WriteJSON(w, http.StatusOK, apimodels.AgentUpdateInfo{
+		Revision: revision,
+		Arch:     arch,
+		SHA256:   sum,
+	})
+}
+
+// AgentBinary sends the compiled agent for an architecture.
+func (as *APIServer) AgentBinary(w http.ResponseWriter, r *http.Request) {
+	path, err := taskrunner.NewTaskRunner(&as.Settings).HostGateway.GetAgentExecutable(mux.Vars(r)["arch"])
+	if err != nil {
+		as.LoggedError(w, r, http.StatusNotFound, err)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, errors.Wrapf(err, "error opening %s", path))
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, errors.Wrapf(err, "error reading %s", path))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 checksum of the file.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "error opening %s", path)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", errors.Wrapf(err, "error reading %s", path)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
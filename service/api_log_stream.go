@@ -0,0 +1,74 @@
+package service
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/apimodels"
+	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/task"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+// StreamTaskLog accepts a websocket from the agent over which it streams the
+// task's logs. Each batch the agent sends is stored and then acknowledged.
+// Batches are numbered, so a batch the agent resends after reconnecting is
+// acknowledged again without being stored twice.
+func (as *APIServer) StreamTaskLog(w http.ResponseWriter, r *http.Request) {
+	t := MustHaveTask(r)
+
+	server := websocket.Server{
+		// the agent isn't a browser, so there's no origin to check
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			grip.Warning(errors.Wrapf(streamTaskLog(ws, t), "log stream for task %s closed", t.Id))
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+func streamTaskLog(ws *websocket.Conn, t *task.Task) error {
+	lastSeq, err := model.FindLastTaskLogSeq(t.Id, t.Execution)
+	if err != nil {
+		return errors.Wrap(err, "error finding the latest log batch")
+	}
+	if err = websocket.JSON.Send(ws, apimodels.LogStreamAck{Seq: lastSeq}); err != nil {
+		return errors.Wrap(err, "error sending initial acknowledgement")
+	}
+
+	for {
+		var frame []byte
+		if err = websocket.Message.Receive(ws, &frame); err != nil {
+			return errors.Wrap(err, "error receiving log batch")
+		}
+		seq, data, err := apimodels.DecodeLogBatch(frame)
+		if err != nil {
+			return errors.Wrap(err, "error decoding log batch")
+		}
+		messages := []model.LogMessage{}
+		if err = apimodels.DecompressLogBatch(data, &messages); err != nil {
+			return errors.Wrapf(err, "error decoding log batch %d", seq)
+		}
+
+		if seq > lastSeq {
+			taskLog := &model.TaskLog{
+				TaskId:       t.Id,
+				Execution:    t.Execution,
+				Timestamp:    time.Now(),
+				MessageCount: len(messages),
+				Messages:     messages,
+				Seq:          seq,
+			}
+			if err = taskLog.Insert(); err != nil {
+				return errors.Wrapf(err, "error storing log batch %d", seq)
+			}
+			lastSeq = seq
+		}
+
+		if err = websocket.JSON.Send(ws, apimodels.LogStreamAck{Seq: lastSeq}); err != nil {
+			return errors.Wrapf(err, "error acknowledging log batch %d", seq)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/evergreen-ci/evergreen"
@@ -11,7 +12,9 @@ import (
 	"github.com/evergreen-ci/evergreen/bookkeeping"
 	"github.com/evergreen-ci/evergreen/cloud"
 	"github.com/evergreen-ci/evergreen/cloud/providers"
+	"github.com/evergreen-ci/evergreen/cloud/providers/static"
 	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/event"
 	"github.com/evergreen-ci/evergreen/model/host"
 	"github.com/evergreen-ci/evergreen/model/task"
 	"github.com/evergreen-ci/evergreen/taskrunner"
@@ -71,12 +74,13 @@ func validateTaskEndDetails(details *apimodels.TaskEndDetail) bool {
 }
 
 // checkHostHealth checks that host is running and creates a task response that is sent back to the agent after the task ends.
-func checkHostHealth(h *host.Host, agentRevision string) (bool, string) {
+// An agent that updates itself is not made to exit when it is out of date.
+func checkHostHealth(h *host.Host, agentRevision string, selfUpdating bool) (bool, string) {
 	if h.Status != evergreen.HostRunning {
 		return true, fmt.Sprintf("host %s is in state %s and agent should exit",
 			h.Id, h.Status)
 	}
-	if h.AgentRevision != agentRevision {
+	if h.AgentRevision != agentRevision && !selfUpdating {
 		return true, fmt.Sprintf("agent should be rebuilt:"+
 			"host has agent revision %s and latest revision is %s",
 			h.AgentRevision, agentRevision)
@@ -158,6 +162,11 @@ func (as *APIServer) EndTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// quarantine static hosts that keep failing tasks for reasons outside the tasks' control
+	systemFailure := details.Status == evergreen.TaskFailed && details.Type == model.SystemCommandType
+	grip.Error(errors.Wrapf(static.RecordTaskResult(currentHost, systemFailure),
+		"error recording result of task %s on host %s", t.Id, currentHost.Id))
+
 	// task cost calculations have no impact on task results, so do them in their own goroutine
 	go as.updateTaskCost(t, currentHost, finishTime)
 
@@ -182,7 +191,7 @@ func (as *APIServer) EndTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shouldExit, message := checkHostHealth(currentHost, agentRevision)
+	shouldExit, message := checkHostHealth(currentHost, agentRevision, agentSelfUpdates(r))
 	if shouldExit {
 		// set the host's last communication time to be zero
 		if err := currentHost.ResetLastCommunicated(); err != nil {
@@ -290,7 +299,15 @@ func (as *APIServer) NextTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shouldExit, message := checkHostHealth(h, agentRevision)
+	selfUpdating := agentSelfUpdates(r)
+	if selfUpdating {
+		if err = recordAgentRevision(h, r); err != nil {
+			as.LoggedError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	shouldExit, message := checkHostHealth(h, agentRevision, selfUpdating)
 	if shouldExit {
 		// set the host's last communication time to be zero
 		if err = h.ResetLastCommunicated(); err != nil {
@@ -304,6 +321,14 @@ func (as *APIServer) NextTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if selfUpdating && h.AgentRevision != agentRevision {
+		response.AgentRevision = agentRevision
+		response.Message = fmt.Sprintf("agent should update from revision %s to %s",
+			h.AgentRevision, agentRevision)
+		as.WriteJSON(w, http.StatusOK, response)
+		return
+	}
+
 	// if there is already a task assigned to the host send back that task
 	if h.RunningTask != "" {
 		var t *task.Task
@@ -392,3 +417,97 @@ func (as *APIServer) NextTask(w http.ResponseWriter, r *http.Request) {
 	grip.Infof("assigned task %s to host %s", nextTask.Id, h.Id)
 	as.WriteJSON(w, http.StatusOK, response)
 }
+
+// HostUnhealthy is called by an agent that has found its host unfit to run
+// tasks, even after cleaning it up. Static hosts are quarantined until their
+// health check passes again. Other hosts are decommissioned, so that the
+// monitor terminates them and new hosts are started in their place.
+func (as *APIServer) HostUnhealthy(w http.ResponseWriter, r *http.Request) {
+	h := MustHaveHost(r)
+
+	req := &apimodels.HostUnhealthyRequest{}
+	if err := util.ReadJSONInto(util.NewRequestReader(r), req); err != nil {
+		as.LoggedError(w, r, http.StatusBadRequest, errors.Wrap(err, "error reading unhealthy host request"))
+		return
+	}
+	reason := strings.Join(req.Reasons, "; ")
+	grip.Warningf("agent on host %s reported the host unhealthy: %s", h.Id, reason)
+	event.LogHostAgentUnhealthy(h.Id, reason)
+
+	var err error
+	switch {
+	case h.Status != evergreen.HostRunning:
+		// the host is already out of service
+	case h.Provider == evergreen.HostTypeStatic:
+		err = h.Quarantine(fmt.Sprintf("agent reported the host unhealthy: %s", reason), true)
+	default:
+		err = h.SetDecommissioned()
+	}
+	if err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError,
+			errors.Wrapf(err, "error taking unhealthy host %s out of service", h.Id))
+		return
+	}
+
+	as.WriteJSON(w, http.StatusOK, struct{}{})
+}
+
+// RegisterAgent is called by the agent on a host that started its own agent,
+// rather than having it started over SSH, before it asks for a task. It marks
+// the host provisioned the first time it is called. The host's secret is
+// required, since it is the only proof the agent runs on the host.
+func (as *APIServer) RegisterAgent(w http.ResponseWriter, r *http.Request) {
+	h := MustHaveHost(r)
+
+	if r.Header.Get(evergreen.HostSecretHeader) == "" {
+		as.LoggedError(w, r, http.StatusUnauthorized, errors.Errorf("registering host %s requires its secret", h.Id))
+		return
+	}
+	if h.Distro.BootstrapsOverSSH() {
+		as.LoggedError(w, r, http.StatusBadRequest,
+			errors.Errorf("host %s has its agent started over SSH and cannot register", h.Id))
+		return
+	}
+	if h.Status != evergreen.HostUninitialized && h.Status != evergreen.HostRunning {
+		as.LoggedError(w, r, http.StatusConflict,
+			errors.Errorf("host %s is in state %s and cannot register", h.Id, h.Status))
+		return
+	}
+
+	if err := recordAgentRevision(h, r); err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.UpdateLastCommunicated(); err != nil {
+		as.LoggedError(w, r, http.StatusInternalServerError,
+			errors.Wrapf(err, "error updating last communication time for host %s", h.Id))
+		return
+	}
+	if !h.Provisioned {
+		grip.Infof("agent on host %s registered, marking the host provisioned", h.Id)
+		if err := h.MarkAsProvisioned(); err != nil {
+			as.LoggedError(w, r, http.StatusInternalServerError,
+				errors.Wrapf(err, "error marking host %s as provisioned", h.Id))
+			return
+		}
+	}
+
+	as.WriteJSON(w, http.StatusOK, struct{}{})
+}
+
+// agentSelfUpdates returns true if the agent that sent the request can update
+// itself, so that it does not need to be restarted when it is out of date.
+func agentSelfUpdates(r *http.Request) bool {
+	return r.Header.Get(evergreen.AgentRevisionHeader) != ""
+}
+
+// recordAgentRevision sets the host's agent revision to the one its agent
+// reports, which changes when the agent updates itself.
+func recordAgentRevision(h *host.Host, r *http.Request) error {
+	revision := r.Header.Get(evergreen.AgentRevisionHeader)
+	if revision == h.AgentRevision {
+		return nil
+	}
+	grip.Infof("agent on host %s is running revision %s", h.Id, revision)
+	return errors.Wrapf(h.SetAgentRevision(revision), "error setting agent revision for host %s", h.Id)
+}
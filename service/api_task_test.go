@@ -62,6 +62,24 @@ func getNextTaskEndpoint(t *testing.T, as *APIServer, hostId string) *httptest.R
 	return w
 }
 
+func getRegisterAgentEndpoint(t *testing.T, as *APIServer, hostId, secret string) *httptest.ResponseRecorder {
+	handler, err := as.Handler()
+	if err != nil {
+		t.Fatalf("creating test API handler: %v", err)
+	}
+
+	request, err := http.NewRequest("POST", "/api/2/agent/register", nil)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	request.Header.Add(evergreen.HostHeader, hostId)
+	request.Header.Add(evergreen.HostSecretHeader, secret)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request)
+	return w
+}
+
 func getEndTaskEndpoint(t *testing.T, as *APIServer, hostId, taskId string, details *apimodels.TaskEndDetail) *httptest.ResponseRecorder {
 	if err := os.MkdirAll(filepath.Join(evergreen.FindEvergreenHome(), evergreen.ClientDirectory), 0644); err != nil {
 		t.Fatal("could not create client directory required to start the API server:", err.Error())
@@ -424,6 +442,59 @@ func TestNextTask(t *testing.T) {
 	})
 }
 
+func TestRegisterAgent(t *testing.T) {
+	Convey("with a host that starts its own agent", t, func() {
+		if err := db.ClearCollections(host.Collection); err != nil {
+			t.Fatalf("clearing db: %v", err)
+		}
+		as, err := NewAPIServer(testutil.TestConfig(), nil)
+		if err != nil {
+			t.Fatalf("creating test API server: %v", err)
+		}
+
+		h := host.Host{
+			Id:     "h1",
+			Distro: distro.Distro{Id: "d1", BootstrapMethod: distro.BootstrapMethodUserData},
+			Secret: hostSecret,
+			Status: evergreen.HostUninitialized,
+		}
+		So(h.Insert(), ShouldBeNil)
+
+		Convey("registering without the host's secret should fail", func() {
+			resp := getRegisterAgentEndpoint(t, as, h.Id, "")
+			So(resp.Code, ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("registering should mark the host provisioned", func() {
+			resp := getRegisterAgentEndpoint(t, as, h.Id, hostSecret)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			dbHost, err := host.FindOne(host.ById(h.Id))
+			So(err, ShouldBeNil)
+			So(dbHost.Provisioned, ShouldBeTrue)
+			So(dbHost.Status, ShouldEqual, evergreen.HostRunning)
+			So(dbHost.LastCommunicationTime.IsZero(), ShouldBeFalse)
+
+			Convey("and registering again should succeed", func() {
+				resp = getRegisterAgentEndpoint(t, as, h.Id, hostSecret)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("registering a host bootstrapped over SSH should fail", func() {
+			h2 := host.Host{Id: "h2", Secret: hostSecret, Status: evergreen.HostRunning}
+			So(h2.Insert(), ShouldBeNil)
+			resp := getRegisterAgentEndpoint(t, as, h2.Id, hostSecret)
+			So(resp.Code, ShouldEqual, http.StatusBadRequest)
+		})
+
+		Convey("registering a decommissioned host should fail", func() {
+			So(h.SetDecommissioned(), ShouldBeNil)
+			resp := getRegisterAgentEndpoint(t, as, h.Id, hostSecret)
+			So(resp.Code, ShouldEqual, http.StatusConflict)
+		})
+	})
+}
+
 func TestValidateTaskEndDetails(t *testing.T) {
 	Convey("With a set of end details with different statuses", t, func() {
 		details := apimodels.TaskEndDetail{}
@@ -445,18 +516,23 @@ func TestCheckHostHealth(t *testing.T) {
 			Status:        evergreen.HostRunning,
 			AgentRevision: currentRevision,
 		}
-		shouldExit, _ := checkHostHealth(h, currentRevision)
+		shouldExit, _ := checkHostHealth(h, currentRevision, false)
 		So(shouldExit, ShouldBeFalse)
 		h.Status = evergreen.HostDecommissioned
-		shouldExit, _ = checkHostHealth(h, currentRevision)
+		shouldExit, _ = checkHostHealth(h, currentRevision, false)
 		So(shouldExit, ShouldBeTrue)
 		h.Status = evergreen.HostQuarantined
-		shouldExit, _ = checkHostHealth(h, currentRevision)
+		shouldExit, _ = checkHostHealth(h, currentRevision, false)
 		So(shouldExit, ShouldBeTrue)
 		Convey("With a host that is running but has a different revision", func() {
-			shouldExit, _ := checkHostHealth(h, "bcd")
+			shouldExit, _ := checkHostHealth(h, "bcd", false)
 			So(shouldExit, ShouldBeTrue)
 		})
+		Convey("With a running host whose agent updates itself and has a different revision", func() {
+			h.Status = evergreen.HostRunning
+			shouldExit, _ := checkHostHealth(h, "bcd", true)
+			So(shouldExit, ShouldBeFalse)
+		})
 	})
 }
 
@@ -103,7 +103,7 @@ func (uis *UIServer) hostsPage(w http.ResponseWriter, r *http.Request) {
 }
 
 func (uis *UIServer) modifyHost(w http.ResponseWriter, r *http.Request) {
-	_ = MustHaveUser(r)
+	u := MustHaveUser(r)
 
 	vars := mux.Vars(r)
 	id := vars["host_id"]
@@ -135,7 +135,7 @@ func (uis *UIServer) modifyHost(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, fmt.Sprintf("'%v' is not a valid status", newStatus), http.StatusBadRequest)
 			return
 		}
-		err := host.SetStatus(newStatus)
+		err := updateHostStatus(host, newStatus, u.Username())
 		if err != nil {
 			uis.LoggedError(w, r, http.StatusInternalServerError, errors.Wrap(err, "Error updating host"))
 			return
@@ -149,7 +149,7 @@ func (uis *UIServer) modifyHost(w http.ResponseWriter, r *http.Request) {
 }
 
 func (uis *UIServer) modifyHosts(w http.ResponseWriter, r *http.Request) {
-	_ = MustHaveUser(r)
+	u := MustHaveUser(r)
 
 	opts := &uiParams{}
 
@@ -187,7 +187,7 @@ func (uis *UIServer) modifyHosts(w http.ResponseWriter, r *http.Request) {
 		numHostsUpdated := 0
 
 		for _, host := range hosts {
-			err := host.SetStatus(newStatus)
+			err := updateHostStatus(&host, newStatus, u.Username())
 			if err != nil {
 				uis.LoggedError(w, r, http.StatusInternalServerError, errors.Wrap(err, "Error updating host"))
 				return
@@ -203,3 +203,17 @@ func (uis *UIServer) modifyHosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// updateHostStatus sets a host's status on behalf of a user. Hosts are
+// quarantined and re-admitted rather than simply changing status, so that
+// those transitions are logged along with who made them.
+func updateHostStatus(h *host.Host, status, username string) error {
+	switch {
+	case status == evergreen.HostQuarantined && h.Status != evergreen.HostQuarantined:
+		return h.Quarantine(fmt.Sprintf("quarantined by %s", username), false)
+	case status == evergreen.HostRunning && h.Status == evergreen.HostQuarantined:
+		return h.Readmit(fmt.Sprintf("re-admitted by %s", username))
+	default:
+		return h.SetStatus(status)
+	}
+}
@@ -0,0 +1,82 @@
+// perfTrendChart draws the history of a metric reported by a task as a line,
+// marking the task's own point and the points at which the series shifts.
+mciModule.directive('perfTrendChart', function() {
+  var width = 700;
+  var height = 160;
+  var margin = {top: 10, right: 20, bottom: 20, left: 60};
+
+  return {
+    restrict: 'A',
+    scope: {
+      trend: '=perfTrendChart',
+    },
+    link: function(scope, element) {
+      var points = scope.trend.series.points || [];
+      if (points.length < 2) {
+        return;
+      }
+      var current = scope.trend.metric;
+      var changes = {};
+      _.each(scope.trend.series.change_points, function(change) {
+        changes[change.order] = change;
+      });
+
+      var x = d3.scale.linear()
+        .domain(d3.extent(points, function(d) { return d.order; }))
+        .range([0, width]);
+      var y = d3.scale.linear()
+        .domain(d3.extent(points, function(d) { return d.value; }))
+        .nice()
+        .range([height, 0]);
+
+      var svg = d3.select(element[0]).append('svg')
+        .attr('width', width + margin.left + margin.right)
+        .attr('height', height + margin.top + margin.bottom)
+        .append('g')
+        .attr('transform', 'translate(' + margin.left + ',' + margin.top + ')');
+
+      svg.append('g')
+        .attr('class', 'x axis')
+        .attr('transform', 'translate(0,' + height + ')')
+        .call(d3.svg.axis().scale(x).orient('bottom').ticks(10).tickFormat(d3.format('d')));
+      svg.append('g')
+        .attr('class', 'y axis')
+        .call(d3.svg.axis().scale(y).orient('left').ticks(5));
+
+      var line = d3.svg.line()
+        .x(function(d) { return x(d.order); })
+        .y(function(d) { return y(d.value); });
+      svg.append('path')
+        .datum(points)
+        .attr('d', line)
+        .attr('fill', 'none')
+        .attr('stroke', 'steelblue')
+        .attr('stroke-width', 1.5);
+
+      svg.selectAll('.point')
+        .data(points)
+        .enter().append('a')
+        .attr('xlink:href', function(d) { return '/task/' + d.task_id; })
+        .append('circle')
+        .attr('class', 'point')
+        .attr('cx', function(d) { return x(d.order); })
+        .attr('cy', function(d) { return y(d.value); })
+        .attr('r', function(d) { return d.task_id == current.task_id ? 5 : 3; })
+        .attr('fill', function(d) {
+          if (d.task_id == current.task_id) {
+            return 'black';
+          }
+          return changes[d.order] ? 'red' : 'steelblue';
+        })
+        .append('title')
+        .text(function(d) {
+          var text = d.revision.substr(0, 7) + ': ' + d.value + ' ' + d.unit;
+          var change = changes[d.order];
+          if (change) {
+            text += ' (' + d3.format('+.1f')(change.percent_change) + '%)';
+          }
+          return text;
+        });
+    },
+  };
+});
@@ -8,6 +8,7 @@ import (
 	"github.com/evergreen-ci/evergreen/alerts"
 	"github.com/evergreen-ci/evergreen/model"
 	"github.com/evergreen-ci/evergreen/model/user"
+	"github.com/evergreen-ci/evergreen/thirdparty/artifactstore"
 	"github.com/evergreen-ci/evergreen/util"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -148,6 +149,7 @@ func (uis *UIServer) modifyProject(w http.ResponseWriter, r *http.Request) {
 			Provider string                 `json:"provider"`
 			Settings map[string]interface{} `json:"settings"`
 		} `json:"alert_config"`
+		ArtifactStore *artifactstore.Config `json:"artifact_store"`
 	}{}
 
 	if err = util.ReadJSONInto(util.NewRequestReader(r), &responseRef); err != nil {
@@ -167,6 +169,15 @@ func (uis *UIServer) modifyProject(w http.ResponseWriter, r *http.Request) {
 	projectRef.Admins = responseRef.Admins
 	projectRef.Identifier = id
 
+	// the artifact store is left as is by clients that don't know of it
+	if responseRef.ArtifactStore != nil {
+		if err = responseRef.ArtifactStore.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid artifact store: %v", err), http.StatusBadRequest)
+			return
+		}
+		projectRef.ArtifactStore = *responseRef.ArtifactStore
+	}
+
 	projectRef.Alerts = map[string][]model.AlertConfig{}
 	for triggerId, alerts := range responseRef.AlertConfig {
 		//TODO validate the triggerID, provider, and settings.
@@ -15,6 +15,7 @@ import (
 	"github.com/evergreen-ci/evergreen/command"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/model/host"
+	"github.com/evergreen-ci/evergreen/model/task"
 	"github.com/evergreen-ci/evergreen/model/user"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -180,6 +181,16 @@ func (sm Spawn) CreateHost(so Options, owner *user.DBUser) error {
 		ExpirationDuration: &expiration,
 		UserData:           so.UserData,
 		UserHost:           true,
+		TaskId:             so.TaskId,
+	}
+	if so.TaskId != "" {
+		t, err := task.FindOne(task.ById(so.TaskId))
+		if err != nil {
+			return errors.Wrapf(err, "error finding task %s", so.TaskId)
+		}
+		if t != nil {
+			hostOptions.ProjectId = t.Project
+		}
 	}
 
 	_, err = cloudManager.SpawnInstance(d, hostOptions)
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -26,6 +28,10 @@ const (
 	agentFile         = "agent"
 )
 
+// archRegexp matches the architectures agents are compiled for, which are
+// named after their GOOS and GOARCH.
+var archRegexp = regexp.MustCompile(`^[a-z0-9]+_[a-z0-9]+$`)
+
 // HostGateway is responsible for kicking off tasks on remote machines.
 type HostGateway interface {
 	// run the specified task on the specified host, return the revision of the
@@ -33,6 +39,8 @@ type HostGateway interface {
 	StartAgentOnHost(*evergreen.Settings, host.Host) error
 	// gets the current revision of the agent
 	GetAgentRevision() (string, error)
+	// gets the path to the compiled agent for the given architecture
+	GetAgentExecutable(arch string) (string, error)
 }
 
 // Implementation of the HostGateway that builds and copies over the MCI
@@ -101,6 +109,20 @@ func (agbh *AgentHostGateway) GetAgentRevision() (string, error) {
 	return strings.TrimSpace(string(hashBytes)), nil
 }
 
+// GetAgentExecutable returns the path to the compiled agent for the
+// architecture, e.g. "linux_amd64", so that agents can download it to update
+// themselves.
+func (agbh *AgentHostGateway) GetAgentExecutable(arch string) (string, error) {
+	if !archRegexp.MatchString(arch) {
+		return "", errors.Errorf("invalid architecture '%s'", arch)
+	}
+	path := filepath.Join(agbh.ExecutablesDir, archSubPath(arch))
+	if _, err := os.Stat(path); err != nil {
+		return "", errors.Wrapf(err, "no agent is compiled for %s", arch)
+	}
+	return path, nil
+}
+
 // executableSubPath returns the directory containing the compiled agents.
 func executableSubPath(id string) (string, error) {
 
@@ -110,12 +132,18 @@ func executableSubPath(id string) (string, error) {
 		return "", errors.Wrapf(err, "error finding distro %v", id)
 	}
 
+	return archSubPath(d.Arch), nil
+}
+
+// archSubPath returns the path of the compiled agent for the architecture,
+// relative to the executables directory.
+func archSubPath(arch string) string {
 	mainName := "main"
-	if strings.HasPrefix(d.Arch, "windows") {
+	if strings.HasPrefix(arch, "windows") {
 		mainName = "main.exe"
 	}
 
-	return filepath.Join(d.Arch, mainName), nil
+	return filepath.Join(arch, mainName)
 }
 
 func newCappedOutputLog() *util.CappedWriter {
@@ -216,9 +244,9 @@ func startAgentOnRemote(settings *evergreen.Settings, hostObj *host.Host, sshOpt
 
 	// build the command to run on the remote machine
 	remoteCmd := fmt.Sprintf(
-		`%v -api_server "%v" -host_id "%v" -host_secret "%v" -log_prefix "%v" -https_cert "%v"`,
+		`%v -api_server "%v" -host_id "%v" -host_secret "%v" -log_prefix "%v" -https_cert "%v" -working_dir "%v"`,
 		pathToExecutable, apiURL, hostObj.Id, hostObj.Secret,
-		filepath.Join(hostObj.Distro.WorkDir, agentFile), "")
+		filepath.Join(hostObj.Distro.WorkDir, agentFile), "", hostObj.Distro.WorkDir)
 	grip.Info(remoteCmd)
 
 	if sumoEndpoint, ok := settings.Credentials["sumologic"]; ok {
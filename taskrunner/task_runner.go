@@ -52,6 +52,11 @@ func (tr *TaskRunner) Run() error {
 
 	// put all of the information needed about the host in a channel
 	for _, h := range freeHosts {
+		// hosts that start their own agent can't have it started over SSH
+		if !h.Distro.BootstrapsOverSSH() {
+			grip.Debugf("Not starting agent on host %s, which starts its own agent", h.Id)
+			continue
+		}
 		freeHostChan <- agentStartData{
 			Host:     h,
 			Settings: tr.Settings,
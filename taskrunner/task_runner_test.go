@@ -29,6 +29,10 @@ func (self *MockHostGateway) GetAgentRevision() (string, error) {
 	return agtRevision, nil
 }
 
+func (self *MockHostGateway) GetAgentExecutable(arch string) (string, error) {
+	return "", nil
+}
+
 func (self *MockHostGateway) StartAgentOnHost(settings *evergreen.Settings,
 	targetHost host.Host) error {
 	return nil
@@ -0,0 +1,379 @@
+package artifactstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evergreen-ci/evergreen/thirdparty"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+const (
+	azureAPIVersion = "2019-12-12"
+	azureSHA256Meta = "x-ms-meta-sha256"
+	azureMaxBlocks  = 50000
+
+	azureCopyPollInterval = time.Second
+	azureCopyTimeout      = 10 * time.Minute
+)
+
+// azureStore keeps files in Azure Blob Storage, as block blobs, authenticating
+// with the storage account's shared key.
+type azureStore struct {
+	account  string
+	key      []byte
+	endpoint string
+	baseURL  string
+	client   *http.Client
+}
+
+func newAzureStore(conf Config, creds Credentials) (*azureStore, error) {
+	if creds.Key == "" {
+		return nil, errors.New("an azure artifact store needs the storage account name as its key")
+	}
+	key, err := base64.StdEncoding.DecodeString(creds.Secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "the azure storage account key is not valid base64")
+	}
+	store := &azureStore{
+		account:  creds.Key,
+		key:      key,
+		endpoint: conf.Endpoint,
+		baseURL:  conf.BaseURL,
+		client:   &http.Client{Timeout: httpStoreTimeout},
+	}
+	if store.endpoint == "" {
+		store.endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", creds.Key)
+	}
+	if store.baseURL == "" {
+		store.baseURL = store.endpoint
+	}
+	return store, nil
+}
+
+func (s *azureStore) Type() string {
+	return AzureType
+}
+
+func (s *azureStore) blobURL(container, blob string) (*url.URL, error) {
+	u, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid azure endpoint %v", s.endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + container + "/" + strings.TrimPrefix(blob, "/")
+	return u, nil
+}
+
+// azureStringToSign returns the string that a request's shared key signature
+// is computed from.
+func azureStringToSign(account string, req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	fields := []string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, which is sent as x-ms-date instead
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}
+
+	msHeaders := []string{}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name+":"+strings.TrimSpace(strings.Join(values, ",")))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	resource := "/" + account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	params := []string{}
+	for name, values := range query {
+		sorted := append([]string{}, values...)
+		sort.Strings(sorted)
+		params = append(params, strings.ToLower(name)+":"+strings.Join(sorted, ","))
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		resource += "\n" + param
+	}
+
+	var buf bytes.Buffer
+	for _, field := range fields {
+		buf.WriteString(field)
+		buf.WriteString("\n")
+	}
+	for _, header := range msHeaders {
+		buf.WriteString(header)
+		buf.WriteString("\n")
+	}
+	buf.WriteString(resource)
+	return buf.String()
+}
+
+// do signs and sends the request, calling handle, if given, with a successful
+// response.
+func (s *azureStore) do(req *http.Request, handle func(*http.Response) error) error {
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(azureStringToSign(s.account, req)))
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s",
+		s.account, base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "error sending %v %v", req.Method, req.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("%v %v failed (%v): %s", req.Method, req.URL, resp.StatusCode, body)
+	}
+	if handle != nil {
+		return handle(resp)
+	}
+	return nil
+}
+
+func (s *azureStore) newRequest(method string, u *url.URL, body io.Reader, length int64) (*http.Request, error) {
+	if length == 0 {
+		// otherwise an empty body is sent chunked
+		body = nil
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.ContentLength = length
+	return req, nil
+}
+
+func (s *azureStore) Put(bucket, key, localPath string, opts PutOptions) (*Checksums, error) {
+	sums, err := fileChecksums(localPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	u, err := s.blobURL(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	md5Sum, _ := hex.DecodeString(sums.MD5)
+	md5b64 := base64.StdEncoding.EncodeToString(md5Sum)
+
+	partSize := opts.PartSize
+	if partSize <= 0 {
+		partSize = thirdparty.DefaultS3PartSize
+	}
+	for info.Size()/partSize >= azureMaxBlocks {
+		partSize *= 2
+	}
+
+	if info.Size() <= partSize {
+		req, err := s.newRequest("PUT", u, file, info.Size())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-ms-blob-type", "BlockBlob")
+		req.Header.Set("Content-MD5", md5b64)
+		req.Header.Set(azureSHA256Meta, sums.SHA256)
+		if opts.ContentType != "" {
+			req.Header.Set("Content-Type", opts.ContentType)
+		}
+		if err = s.do(req, nil); err != nil {
+			return nil, err
+		}
+		return sums, nil
+	}
+
+	blockIds, err := s.putBlocks(u, file, info.Size(), partSize, opts.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	var list bytes.Buffer
+	list.WriteString(xml.Header + "<BlockList>")
+	for _, id := range blockIds {
+		list.WriteString("<Latest>" + id + "</Latest>")
+	}
+	list.WriteString("</BlockList>")
+
+	listURL := *u
+	listURL.RawQuery = url.Values{"comp": {"blocklist"}}.Encode()
+	req, err := s.newRequest("PUT", &listURL, bytes.NewReader(list.Bytes()), int64(list.Len()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-blob-content-md5", md5b64)
+	req.Header.Set(azureSHA256Meta, sums.SHA256)
+	if opts.ContentType != "" {
+		req.Header.Set("x-ms-blob-content-type", opts.ContentType)
+	}
+	if err = s.do(req, nil); err != nil {
+		return nil, errors.Wrapf(err, "error committing blocks of %v", key)
+	}
+	return sums, nil
+}
+
+// putBlocks uploads the file in blocks of blockSize, concurrency at a time,
+// returning the ids of the blocks in order.
+func (s *azureStore) putBlocks(u *url.URL, file *os.File, size, blockSize int64, concurrency int) ([]string, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	numBlocks := int((size + blockSize - 1) / blockSize)
+	ids := make([]string, numBlocks)
+	blockNums := make(chan int, numBlocks)
+	for i := 0; i < numBlocks; i++ {
+		// block ids must all be the same length
+		ids[i] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%08d", i)))
+		blockNums <- i
+	}
+	close(blockNums)
+
+	catcher := grip.NewCatcher()
+	var catcherMu sync.Mutex
+	wg := &sync.WaitGroup{}
+	for i := 0; i < concurrency && i < numBlocks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range blockNums {
+				err := s.putBlock(u, ids[n], io.NewSectionReader(file, int64(n)*blockSize, blockSize))
+				if err != nil {
+					catcherMu.Lock()
+					catcher.Add(errors.Wrapf(err, "error uploading block %d", n))
+					catcherMu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return ids, catcher.Resolve()
+}
+
+func (s *azureStore) putBlock(u *url.URL, id string, block *io.SectionReader) error {
+	blockMD5 := md5.New()
+	if _, err := io.Copy(blockMD5, block); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := block.Seek(0, 0); err != nil {
+		return errors.WithStack(err)
+	}
+
+	blockURL := *u
+	blockURL.RawQuery = url.Values{"comp": {"block"}, "blockid": {id}}.Encode()
+	req, err := s.newRequest("PUT", &blockURL, block, block.Size())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(blockMD5.Sum(nil)))
+	return s.do(req, nil)
+}
+
+func (s *azureStore) Get(bucket, key, localPath string) (*Checksums, bool, error) {
+	u, err := s.blobURL(bucket, key)
+	if err != nil {
+		return nil, false, err
+	}
+	req, err := s.newRequest("GET", u, nil, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	partial := partialPath(localPath)
+	var checksum string
+	err = s.do(req, func(resp *http.Response) error {
+		checksum = resp.Header.Get(azureSHA256Meta)
+		_, err := writeFile(partial, resp.Body, nil)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return finishDownload(partial, localPath, checksum)
+}
+
+func (s *azureStore) Copy(fromBucket, fromKey, toBucket, toKey string, opts PutOptions) error {
+	from, err := s.blobURL(fromBucket, fromKey)
+	if err != nil {
+		return err
+	}
+	to, err := s.blobURL(toBucket, toKey)
+	if err != nil {
+		return err
+	}
+	req, err := s.newRequest("PUT", to, nil, 0)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-copy-source", from.String())
+	status := ""
+	err = s.do(req, func(resp *http.Response) error {
+		status = resp.Header.Get("x-ms-copy-status")
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error copying %v to %v", from, to)
+	}
+
+	// copies within an account usually finish at once, but may not
+	deadline := time.Now().Add(azureCopyTimeout)
+	for status == "pending" && time.Now().Before(deadline) {
+		time.Sleep(azureCopyPollInterval)
+		req, err = s.newRequest("HEAD", to, nil, 0)
+		if err != nil {
+			return err
+		}
+		err = s.do(req, func(resp *http.Response) error {
+			status = resp.Header.Get("x-ms-copy-status")
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error checking copy of %v to %v", from, to)
+		}
+	}
+	if status != "success" {
+		return errors.Errorf("copy of %v to %v did not succeed: status is '%v'", from, to, status)
+	}
+	return nil
+}
+
+func (s *azureStore) Link(bucket, key string) string {
+	return joinURL(s.baseURL, bucket, key)
+}
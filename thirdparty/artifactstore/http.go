@@ -0,0 +1,271 @@
+package artifactstore
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// sha256Header carries the SHA256 of files put to and gotten from an
+	// HTTP store.
+	sha256Header = "X-Checksum-Sha256"
+
+	httpStoreTimeout = 30 * time.Minute
+)
+
+// httpStore keeps files on a file server, such as one running FileServer,
+// that accepts PUT requests to store files and serves them back on GET. The
+// credentials are sent as the user name and password of basic auth.
+type httpStore struct {
+	endpoint string
+	baseURL  string
+	creds    Credentials
+	client   *http.Client
+}
+
+func newHTTPStore(conf Config, creds Credentials) *httpStore {
+	store := &httpStore{
+		endpoint: conf.Endpoint,
+		baseURL:  conf.BaseURL,
+		creds:    creds,
+		client:   &http.Client{Timeout: httpStoreTimeout},
+	}
+	if store.baseURL == "" {
+		store.baseURL = conf.Endpoint
+	}
+	return store
+}
+
+func (s *httpStore) Type() string {
+	return HTTPType
+}
+
+func (s *httpStore) Put(bucket, key, localPath string, opts PutOptions) (*Checksums, error) {
+	sums, err := fileChecksums(localPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var body io.Reader = file
+	if info.Size() == 0 {
+		// otherwise an empty body is sent chunked
+		body = nil
+	}
+	url := joinURL(s.endpoint, bucket, key)
+	req, err := http.NewRequest("PUT", url, body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.ContentLength = info.Size()
+	md5Sum, _ := hex.DecodeString(sums.MD5)
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(md5Sum))
+	req.Header.Set(sha256Header, sums.SHA256)
+	if opts.ContentType != "" {
+		req.Header.Set("Content-Type", opts.ContentType)
+	}
+	if err = s.do(req, nil); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+func (s *httpStore) Get(bucket, key, localPath string) (*Checksums, bool, error) {
+	url := joinURL(s.endpoint, bucket, key)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	partial := partialPath(localPath)
+	var checksum string
+	err = s.do(req, func(resp *http.Response) error {
+		checksum = resp.Header.Get(sha256Header)
+		_, err := writeFile(partial, resp.Body, nil)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return finishDownload(partial, localPath, checksum)
+}
+
+func (s *httpStore) Copy(fromBucket, fromKey, toBucket, toKey string, opts PutOptions) error {
+	tmp, err := ioutil.TempDir("", "artifact_copy")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	local := filepath.Join(tmp, "file")
+	if _, _, err = s.Get(fromBucket, fromKey, local); err != nil {
+		return err
+	}
+	_, err = s.Put(toBucket, toKey, local, opts)
+	return err
+}
+
+func (s *httpStore) Link(bucket, key string) string {
+	return joinURL(s.baseURL, bucket, key)
+}
+
+// do sends the request, calling handle, if given, with a successful response.
+func (s *httpStore) do(req *http.Request, handle func(*http.Response) error) error {
+	if s.creds.Key != "" {
+		req.SetBasicAuth(s.creds.Key, s.creds.Secret)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "error sending %v %v", req.Method, req.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("%v %v failed (%v): %s", req.Method, req.URL, resp.StatusCode, body)
+	}
+	if handle != nil {
+		return handle(resp)
+	}
+	return nil
+}
+
+// FileServer serves the files of an HTTP store from a directory, one
+// subdirectory per bucket, storing files that are PUT to it. It is meant for
+// installations without access to a cloud storage service, and for tests;
+// "evergreen artifact-server" runs one.
+//
+// Requests must authenticate with basic auth as Key and Secret, which are the
+// credentials the project's commands use for the store. Files are stored only
+// if both are set, and can be read without them if PublicRead is set, so
+// that their links can be followed from the UI.
+type FileServer struct {
+	Root       string
+	Key        string
+	Secret     string
+	PublicRead bool
+}
+
+// authorized returns whether the request carries the server's credentials.
+func (fs *FileServer) authorized(r *http.Request) bool {
+	if fs.Key == "" || fs.Secret == "" {
+		return false
+	}
+	key, secret, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(key), []byte(fs.Key)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(secret), []byte(fs.Secret)) == 1
+}
+
+func (fs *FileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	read := r.Method == "GET" || r.Method == "HEAD"
+	if !(read && fs.PublicRead) && !fs.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="artifacts"`)
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	bucket, key := splitBucket(r.URL.Path)
+	path, err := safeJoin(fs.Root, bucket, key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET", "HEAD":
+		file, err := os.Open(path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		if sums, err := readSidecar(path); err == nil {
+			w.Header().Set(sha256Header, sums)
+		}
+		http.ServeContent(w, r, filepath.Base(path), info.ModTime(), file)
+	case "PUT":
+		sums, err := writeFile(path, r.Body, func(sums *Checksums) error {
+			return checkUpload(r, sums)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = writeSidecar(path, sums); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, fmt.Sprintf("%v is not supported", r.Method), http.StatusMethodNotAllowed)
+	}
+}
+
+// splitBucket splits a request path into its bucket and key.
+func splitBucket(path string) (string, string) {
+	parts := strings.SplitN(strings.TrimLeft(path, "/"), "/", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// checkUpload checks an uploaded file against the checksums sent with it.
+func checkUpload(r *http.Request, sums *Checksums) error {
+	if want := r.Header.Get("Content-MD5"); want != "" {
+		sum, _ := hex.DecodeString(sums.MD5)
+		if base64.StdEncoding.EncodeToString(sum) != want {
+			return errors.New("the Content-MD5 did not match")
+		}
+	}
+	if want := r.Header.Get(sha256Header); want != "" && want != sums.SHA256 {
+		return errors.New("the SHA256 did not match")
+	}
+	return nil
+}
+
+// sidecarPath returns where the SHA256 of a file in a local store, or served
+// by a FileServer, is kept.
+func sidecarPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".sha256")
+}
+
+func writeSidecar(path string, sums *Checksums) error {
+	return ioutil.WriteFile(sidecarPath(path), []byte(sums.SHA256), 0644)
+}
+
+func readSidecar(path string) (string, error) {
+	data, err := ioutil.ReadFile(sidecarPath(path))
+	if err != nil {
+		return "", err
+	}
+	if len(data) != sha256.Size*2 {
+		return "", errors.New("invalid checksum file")
+	}
+	return string(data), nil
+}
@@ -0,0 +1,145 @@
+package artifactstore
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// localStore keeps files in a directory, one subdirectory per bucket, with
+// the SHA256 of each next to it. The directory can be served by a FileServer
+// for an HTTP store.
+type localStore struct {
+	root    string
+	baseURL string
+}
+
+func (s *localStore) Type() string {
+	return LocalType
+}
+
+// path returns where a file is kept, making sure it is inside the store.
+func (s *localStore) path(bucket, key string) (string, error) {
+	return safeJoin(s.root, bucket, key)
+}
+
+// safeJoin joins a bucket and key to a root directory, refusing any that
+// would lead outside of it.
+func safeJoin(root, bucket, key string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(bucket + "/" + strings.TrimPrefix(key, "/")))
+	if bucket == "" || strings.Contains(bucket, "/") || rel == "." ||
+		rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", errors.Errorf("'%v/%v' is not a valid location", bucket, key)
+	}
+	return filepath.Join(root, rel), nil
+}
+
+func (s *localStore) Put(bucket, key, localPath string, opts PutOptions) (*Checksums, error) {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	dest, err := s.path(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := writeFile(dest, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sums, errors.WithStack(writeSidecar(dest, sums))
+}
+
+// writeFile writes everything in r to path, atomically, returning its
+// checksums. If check is given, the file is only written if it approves of
+// the checksums.
+func writeFile(path string, r io.Reader, check func(*Checksums) error) (*Checksums, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Wrapf(err, "error creating directory for %v", path)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp_")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating temporary file for %v", path)
+	}
+	sums, err := readerChecksums(io.TeeReader(r, tmp))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && check != nil {
+		err = check(sums)
+	}
+	if err == nil {
+		// files are read by whoever follows their links, not just this user
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, errors.Wrapf(err, "error writing %v", path)
+	}
+	return sums, nil
+}
+
+func (s *localStore) Get(bucket, key, localPath string) (*Checksums, bool, error) {
+	src, err := s.path(bucket, key)
+	if err != nil {
+		return nil, false, err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "error opening %v", src)
+	}
+	defer in.Close()
+
+	expected, _ := readSidecar(src)
+	sums, err := writeFile(localPath, in, func(sums *Checksums) error {
+		if expected != "" && sums.SHA256 != expected {
+			return errors.Errorf("SHA256 is %v, expected %v", sums.SHA256, expected)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return sums, expected != "", nil
+}
+
+func (s *localStore) Copy(fromBucket, fromKey, toBucket, toKey string, opts PutOptions) error {
+	src, err := s.path(fromBucket, fromKey)
+	if err != nil {
+		return err
+	}
+	dest, err := s.path(toBucket, toKey)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrapf(err, "error opening %v", src)
+	}
+	defer in.Close()
+	sums, err := writeFile(dest, in, nil)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(writeSidecar(dest, sums))
+}
+
+func (s *localStore) Link(bucket, key string) string {
+	if s.baseURL != "" {
+		return joinURL(s.baseURL, bucket, key)
+	}
+	path, err := s.path(bucket, key)
+	if err != nil {
+		return ""
+	}
+	return "file://" + filepath.ToSlash(path)
+}
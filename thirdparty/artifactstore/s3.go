@@ -0,0 +1,94 @@
+package artifactstore
+
+import (
+	"strings"
+
+	"github.com/evergreen-ci/evergreen/thirdparty"
+	"github.com/goamz/goamz/aws"
+	"github.com/goamz/goamz/s3"
+	"github.com/pkg/errors"
+)
+
+const (
+	s3BaseURL  = "https://s3.amazonaws.com/"
+	gcsBaseURL = "https://storage.googleapis.com/"
+)
+
+// s3Store keeps files in S3, or in a service with an S3-compatible API.
+type s3Store struct {
+	storeType string
+	auth      *aws.Auth
+	region    aws.Region
+	baseURL   string
+}
+
+func newS3Store(conf Config, creds Credentials) *s3Store {
+	store := &s3Store{
+		storeType: S3Type,
+		auth:      &aws.Auth{AccessKey: creds.Key, SecretKey: creds.Secret},
+		region:    aws.USEast,
+		baseURL:   s3BaseURL,
+	}
+	if conf.Endpoint != "" {
+		store.region = aws.Region{Name: "custom", S3Endpoint: conf.Endpoint}
+		store.baseURL = conf.Endpoint
+	}
+	if conf.BaseURL != "" {
+		store.baseURL = conf.BaseURL
+	}
+	return store
+}
+
+// newGCSStore returns a store that uses Google Cloud Storage's XML API, which
+// is compatible with S3's when authenticated with HMAC keys.
+func newGCSStore(conf Config, creds Credentials) *s3Store {
+	if conf.Endpoint == "" {
+		conf.Endpoint = strings.TrimSuffix(gcsBaseURL, "/")
+	}
+	store := newS3Store(conf, creds)
+	store.storeType = GCSType
+	return store
+}
+
+func (s *s3Store) Type() string {
+	return s.storeType
+}
+
+func (s *s3Store) bucket(name string) *s3.Bucket {
+	return thirdparty.NewS3Session(s.auth, s.region).Bucket(name)
+}
+
+func (s *s3Store) Put(bucket, key, localPath string, opts PutOptions) (*Checksums, error) {
+	sums, err := thirdparty.UploadS3File(s.bucket(bucket), localPath, key, thirdparty.S3PutOptions{
+		ContentType: opts.ContentType,
+		Permissions: opts.Permissions,
+		PartSize:    opts.PartSize,
+		Concurrency: opts.Concurrency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Checksums{MD5: sums.MD5, SHA256: sums.SHA256}, nil
+}
+
+func (s *s3Store) Get(bucket, key, localPath string) (*Checksums, bool, error) {
+	sums, verified, err := thirdparty.DownloadS3File(s.bucket(bucket), key, localPath)
+	if err != nil {
+		return nil, false, err
+	}
+	return &Checksums{MD5: sums.MD5, SHA256: sums.SHA256}, verified, nil
+}
+
+func (s *s3Store) Copy(fromBucket, fromKey, toBucket, toKey string, opts PutOptions) error {
+	if s.storeType == S3Type && s.region.S3Endpoint == aws.USEast.S3Endpoint {
+		return errors.WithStack(thirdparty.S3CopyFile(s.auth, fromBucket, fromKey,
+			toBucket, toKey, opts.Permissions))
+	}
+	source := "/" + fromBucket + "/" + strings.TrimPrefix(fromKey, "/")
+	_, err := s.bucket(toBucket).PutCopy(toKey, s3.ACL(opts.Permissions), s3.CopyOptions{}, source)
+	return errors.Wrapf(err, "error copying %v to %v/%v", source, toBucket, toKey)
+}
+
+func (s *s3Store) Link(bucket, key string) string {
+	return joinURL(s.baseURL, bucket, key)
+}
@@ -0,0 +1,238 @@
+// Package artifactstore provides the storage backends that artifact commands
+// put files to, get files from and copy files between: S3, Google Cloud
+// Storage, Azure Blob Storage, a local directory, and an HTTP file server.
+//
+// Each backend holds files in buckets, which are S3 and GCS buckets, Azure
+// containers, or directories under the local directory or the file server's
+// root.
+package artifactstore
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	S3Type    = "s3"
+	GCSType   = "gcs"
+	AzureType = "azure"
+	LocalType = "local"
+	HTTPType  = "http"
+)
+
+// Types are the kinds of store there are.
+var Types = []string{S3Type, GCSType, AzureType, LocalType, HTTPType}
+
+// Config selects and configures a store. It is set per project; credentials
+// are not part of it, but are given to the commands that use the store.
+type Config struct {
+	// Type is the kind of store. If blank, it is S3.
+	Type string `bson:"type,omitempty" json:"type,omitempty" yaml:"type"`
+
+	// Endpoint overrides the address of an S3, GCS or Azure store's
+	// service, e.g. for an S3-compatible service or the Azure emulator, and is
+	// the address of an HTTP store's file server.
+	Endpoint string `bson:"endpoint,omitempty" json:"endpoint,omitempty" yaml:"endpoint"`
+
+	// Path is the directory a local store keeps its buckets in. It must be
+	// reachable at the same path from the hosts that run tasks and from the
+	// API server, e.g. as a network share.
+	Path string `bson:"path,omitempty" json:"path,omitempty" yaml:"path"`
+
+	// BaseURL, if set, is the address files are linked to, followed by their
+	// bucket and path. It is needed for links to files in a local store to be
+	// of use to anyone other than the host that put them.
+	BaseURL string `bson:"base_url,omitempty" json:"base_url,omitempty" yaml:"base_url"`
+}
+
+// IsZero returns whether the config is unset, in which case S3 is used.
+func (c Config) IsZero() bool {
+	return c == Config{}
+}
+
+// StoreType returns the kind of store the config describes.
+func (c Config) StoreType() string {
+	if c.Type == "" {
+		return S3Type
+	}
+	return c.Type
+}
+
+// UsesCredentials returns whether the store the config describes needs
+// credentials to be reached.
+func (c Config) UsesCredentials() bool {
+	switch c.StoreType() {
+	case S3Type, GCSType, AzureType, HTTPType:
+		return true
+	}
+	return false
+}
+
+// Link returns the address of a file in the store the config describes, as
+// its store's Link would. It needs no credentials, so it returns an empty
+// string for an Azure store without an endpoint or base URL, whose address
+// depends on the account name.
+func (c Config) Link(bucket, key string) string {
+	if c.BaseURL != "" {
+		return joinURL(c.BaseURL, bucket, key)
+	}
+	base := c.Endpoint
+	switch {
+	case c.StoreType() == LocalType:
+		return (&localStore{root: c.Path}).Link(bucket, key)
+	case base != "":
+	case c.StoreType() == S3Type:
+		base = s3BaseURL
+	case c.StoreType() == GCSType:
+		base = gcsBaseURL
+	default:
+		return ""
+	}
+	return joinURL(base, bucket, key)
+}
+
+// Validate checks that the config is complete for its type of store.
+func (c Config) Validate() error {
+	switch c.Type {
+	case "", S3Type, GCSType, AzureType:
+		return nil
+	case LocalType:
+		if c.Path == "" {
+			return errors.New("path cannot be blank for a local artifact store")
+		}
+	case HTTPType:
+		if c.Endpoint == "" {
+			return errors.New("endpoint cannot be blank for an http artifact store")
+		}
+	default:
+		return errors.Errorf("artifact store type must be one of %v, not '%v'", Types, c.Type)
+	}
+	return nil
+}
+
+// Credentials authenticate requests to a store. They are the access key and
+// secret of S3 and GCS (using GCS's HMAC keys), and the account name and key
+// of Azure, and the basic auth user name and password of an HTTP store's file
+// server. Local stores don't use them.
+type Credentials struct {
+	Key    string
+	Secret string
+}
+
+// Checksums are the hex-encoded checksums of a file put to or gotten from a
+// store.
+type Checksums struct {
+	MD5    string
+	SHA256 string
+}
+
+// PutOptions control how files are put to a store.
+type PutOptions struct {
+	// ContentType is the MIME type of the file.
+	ContentType string
+	// Permissions is the canned ACL of files put to S3 or GCS.
+	Permissions string
+	// PartSize is the size of the parts large files are uploaded in, if the
+	// store supports it. Zero means the store's default.
+	PartSize int64
+	// Concurrency is how many parts of a file are uploaded at once.
+	Concurrency int
+}
+
+// Store is somewhere artifacts are kept.
+type Store interface {
+	// Type returns the kind of store it is.
+	Type() string
+	// Put uploads the local file to key in the bucket, returning its
+	// checksums. An error opening the file is returned as is, so that callers
+	// can check it with os.IsNotExist.
+	Put(bucket, key, localPath string, opts PutOptions) (*Checksums, error)
+	// Get downloads key in the bucket to the local path, returning the
+	// checksums of what was downloaded and whether they were checked against
+	// those of the stored file.
+	Get(bucket, key, localPath string) (*Checksums, bool, error)
+	// Copy copies a file from one bucket and key to another.
+	Copy(fromBucket, fromKey, toBucket, toKey string, opts PutOptions) error
+	// Link returns the address a file can be downloaded from.
+	Link(bucket, key string) string
+}
+
+// New returns the store the config describes.
+func New(conf Config, creds Credentials) (Store, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch conf.Type {
+	case "", S3Type:
+		return newS3Store(conf, creds), nil
+	case GCSType:
+		return newGCSStore(conf, creds), nil
+	case AzureType:
+		return newAzureStore(conf, creds)
+	case LocalType:
+		return &localStore{root: conf.Path, baseURL: conf.BaseURL}, nil
+	default:
+		return newHTTPStore(conf, creds), nil
+	}
+}
+
+// joinURL joins a base address, bucket and key into a link.
+func joinURL(base, bucket, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + bucket + "/" + strings.TrimPrefix(key, "/")
+}
+
+// fileChecksums returns the checksums of the file at path.
+func fileChecksums(path string) (*Checksums, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readerChecksums(file)
+}
+
+func readerChecksums(r io.Reader) (*Checksums, error) {
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), r); err != nil {
+		return nil, errors.Wrap(err, "error computing checksums")
+	}
+	return &Checksums{
+		MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}, nil
+}
+
+// partialPath returns where a download to path is written until it is
+// complete.
+func partialPath(path string) string {
+	return path + ".part"
+}
+
+// finishDownload checks a complete download against the stored file's SHA256,
+// if known, and moves it into place. A download that fails its check is
+// discarded.
+func finishDownload(partial, path, expected string) (*Checksums, bool, error) {
+	sums, err := fileChecksums(partial)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "error reading download %v", partial)
+	}
+	if expected != "" && sums.SHA256 != expected {
+		_ = os.Remove(partial)
+		return nil, false, errors.Errorf("download to %v is corrupt: SHA256 is %v, expected %v",
+			path, sums.SHA256, expected)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, false, errors.Wrapf(err, "error creating directory for %v", path)
+	}
+	if err = os.Rename(partial, path); err != nil {
+		return nil, false, errors.Wrapf(err, "error moving download to %v", path)
+	}
+	return sums, expected != "", nil
+}
@@ -0,0 +1,252 @@
+package artifactstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen/thirdparty"
+	"github.com/evergreen-ci/evergreen/thirdparty/s3stub"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeAzure is a minimal Azure Blob Storage service that checks requests'
+// signatures.
+type fakeAzure struct {
+	account string
+	key     []byte
+
+	mu     sync.Mutex
+	blobs  map[string][]byte
+	meta   map[string]string
+	blocks map[string][]byte
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write([]byte(azureStringToSign(f.account, r)))
+	expected := "SharedKey " + f.account + ":" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if r.Header.Get("Authorization") != expected {
+		http.Error(w, "bad signature", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	name := r.URL.Path
+	switch {
+	case r.Method == "PUT" && r.URL.Query().Get("comp") == "block":
+		f.blocks[name+"#"+r.URL.Query().Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "PUT" && r.URL.Query().Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		if err := xml.Unmarshal(body, &list); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var blob bytes.Buffer
+		for _, id := range list.Latest {
+			blob.Write(f.blocks[name+"#"+id])
+		}
+		f.blobs[name] = blob.Bytes()
+		f.meta[name] = r.Header.Get(azureSHA256Meta)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "PUT" && r.Header.Get("x-ms-copy-source") != "":
+		source := strings.TrimPrefix(r.Header.Get("x-ms-copy-source"), "http://"+r.Host)
+		f.blobs[name], f.meta[name] = f.blobs[source], f.meta[source]
+		w.Header().Set("x-ms-copy-status", "success")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == "PUT":
+		f.blobs[name] = body
+		f.meta[name] = r.Header.Get(azureSHA256Meta)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "GET":
+		blob, ok := f.blobs[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set(azureSHA256Meta, f.meta[name])
+		_, _ = w.Write(blob)
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestStores(t *testing.T) {
+	Convey("With files to store", t, func() {
+		dir, err := ioutil.TempDir("", "artifactstore_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		small := filepath.Join(dir, "small.txt")
+		So(ioutil.WriteFile(small, []byte("some artifact"), 0644), ShouldBeNil)
+		large := filepath.Join(dir, "large.bin")
+		So(ioutil.WriteFile(large, bytes.Repeat([]byte("0123456789"), thirdparty.S3MinPartSize/5), 0644), ShouldBeNil)
+		opts := PutOptions{ContentType: "text/plain", PartSize: thirdparty.S3MinPartSize, Concurrency: 2}
+
+		// roundTrip puts a file, copies it and gets the copy back
+		roundTrip := func(store Store, path string) {
+			sums, err := store.Put("bucket", "dir/file", path, opts)
+			So(err, ShouldBeNil)
+			So(store.Copy("bucket", "dir/file", "other", "copy", opts), ShouldBeNil)
+
+			out := filepath.Join(dir, "out", filepath.Base(path))
+			got, verified, err := store.Get("other", "copy", out)
+			So(err, ShouldBeNil)
+			So(verified, ShouldBeTrue)
+			So(*got, ShouldResemble, *sums)
+
+			expected, err := ioutil.ReadFile(path)
+			So(err, ShouldBeNil)
+			actual, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(bytes.Equal(actual, expected), ShouldBeTrue)
+		}
+
+		Convey("a local store should keep them in its directory", func() {
+			root := filepath.Join(dir, "store")
+			store, err := New(Config{Type: LocalType, Path: root, BaseURL: "http://files.example.com/"}, Credentials{})
+			So(err, ShouldBeNil)
+			roundTrip(store, small)
+			So(store.Link("bucket", "dir/file"), ShouldEqual, "http://files.example.com/bucket/dir/file")
+
+			_, err = store.Put("../outside", "file", small, opts)
+			So(err, ShouldNotBeNil)
+			_, err = store.Put("bucket", "../../outside", small, opts)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("an http store should put them to a file server", func() {
+			server := httptest.NewServer(&FileServer{
+				Root:   filepath.Join(dir, "served"),
+				Key:    "key",
+				Secret: "secret",
+			})
+			defer server.Close()
+			store, err := New(Config{Type: HTTPType, Endpoint: server.URL}, Credentials{Key: "key", Secret: "secret"})
+			So(err, ShouldBeNil)
+			roundTrip(store, small)
+			So(store.Link("bucket", "dir/file"), ShouldEqual, server.URL+"/bucket/dir/file")
+
+			Convey("which should refuse requests without its credentials", func() {
+				for _, creds := range []Credentials{{}, {Key: "key", Secret: "wrong"}} {
+					other, err := New(Config{Type: HTTPType, Endpoint: server.URL}, creds)
+					So(err, ShouldBeNil)
+					_, err = other.Put("bucket", "other", small, opts)
+					So(err, ShouldNotBeNil)
+					_, _, err = other.Get("bucket", "dir/file", filepath.Join(dir, "out", "refused"))
+					So(err, ShouldNotBeNil)
+				}
+				_, err = os.Stat(filepath.Join(dir, "served", "bucket", "other"))
+				So(os.IsNotExist(err), ShouldBeTrue)
+			})
+
+			Convey("which should refuse corrupt uploads", func() {
+				req, err := http.NewRequest("PUT", server.URL+"/bucket/corrupt", strings.NewReader("data"))
+				So(err, ShouldBeNil)
+				req.Header.Set(sha256Header, strings.Repeat("0", 64))
+				req.SetBasicAuth("key", "secret")
+				resp, err := http.DefaultClient.Do(req)
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				So(resp.StatusCode, ShouldEqual, http.StatusBadRequest)
+				_, err = os.Stat(filepath.Join(dir, "served", "bucket", "corrupt"))
+				So(os.IsNotExist(err), ShouldBeTrue)
+			})
+		})
+
+		Convey("an s3 store should work with an S3-compatible service", func() {
+			server := s3stub.NewServer()
+			defer server.Close()
+			store, err := New(Config{Type: S3Type, Endpoint: server.URL}, Credentials{Key: "key", Secret: "secret"})
+			So(err, ShouldBeNil)
+			So(store.Type(), ShouldEqual, S3Type)
+			roundTrip(store, large)
+			So(server.PartsReceived(), ShouldEqual, 2)
+			So(store.Link("bucket", "dir/file"), ShouldEqual, server.URL+"/bucket/dir/file")
+		})
+
+		Convey("an azure store should sign its requests and upload large files in blocks", func() {
+			fake := &fakeAzure{
+				account: "account",
+				key:     []byte("secret key"),
+				blobs:   map[string][]byte{},
+				meta:    map[string]string{},
+				blocks:  map[string][]byte{},
+			}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+			creds := Credentials{Key: "account", Secret: base64.StdEncoding.EncodeToString(fake.key)}
+			store, err := New(Config{Type: AzureType, Endpoint: server.URL}, creds)
+			So(err, ShouldBeNil)
+			roundTrip(store, small)
+			roundTrip(store, large)
+			So(len(fake.blocks), ShouldEqual, 2)
+
+			_, err = New(Config{Type: AzureType}, Credentials{Key: "account", Secret: "not base64!"})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("gcs stores should link to Google Cloud Storage", func() {
+			store, err := New(Config{Type: GCSType}, Credentials{})
+			So(err, ShouldBeNil)
+			So(store.Link("bucket", "dir/file"), ShouldEqual, "https://storage.googleapis.com/bucket/dir/file")
+		})
+
+		Convey("incomplete configs should be rejected", func() {
+			_, err := New(Config{Type: LocalType}, Credentials{})
+			So(err, ShouldNotBeNil)
+			_, err = New(Config{Type: HTTPType}, Credentials{})
+			So(err, ShouldNotBeNil)
+			_, err = New(Config{Type: "ftp"}, Credentials{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestConfigLink(t *testing.T) {
+	Convey("Configs should link to files as their stores do", t, func() {
+		So(Config{}.Link("bucket", "/a/b.txt"), ShouldEqual, "https://s3.amazonaws.com/bucket/a/b.txt")
+		So(Config{Type: GCSType}.Link("bucket", "b.txt"), ShouldEqual, "https://storage.googleapis.com/bucket/b.txt")
+		So(Config{Type: HTTPType, Endpoint: "http://files:8080/"}.Link("bucket", "b.txt"),
+			ShouldEqual, "http://files:8080/bucket/b.txt")
+		So(Config{Type: LocalType, Path: "/srv/files"}.Link("bucket", "b.txt"),
+			ShouldEqual, "file:///srv/files/bucket/b.txt")
+		So(Config{Type: LocalType, Path: "/srv/files", BaseURL: "https://files"}.Link("bucket", "b.txt"),
+			ShouldEqual, "https://files/bucket/b.txt")
+		So(Config{Type: AzureType}.Link("bucket", "b.txt"), ShouldEqual, "")
+	})
+}
+
+func TestAzureStringToSign(t *testing.T) {
+	Convey("The string to sign should follow the shared key scheme", t, func() {
+		req, err := http.NewRequest("PUT", "https://account.blob.core.windows.net/container/a%20b?comp=block&blockid=MQ%3D%3D", nil)
+		So(err, ShouldBeNil)
+		req.ContentLength = 11
+		req.Header.Set("Content-MD5", "md5")
+		req.Header.Set("x-ms-version", azureAPIVersion)
+		req.Header.Set("x-ms-date", "Mon, 02 Jan 2006 15:04:05 GMT")
+		So(azureStringToSign("account", req), ShouldEqual, strings.Join([]string{
+			"PUT", "", "", "11", "md5", "", "", "", "", "", "", "",
+			"x-ms-date:Mon, 02 Jan 2006 15:04:05 GMT",
+			"x-ms-version:" + azureAPIVersion,
+			"/account/container/a%20b",
+			"blockid:MQ==",
+			"comp:block",
+		}, "\n"))
+	})
+}
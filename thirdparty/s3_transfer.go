@@ -0,0 +1,447 @@
+package thirdparty
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/evergreen-ci/evergreen/util"
+	"github.com/goamz/goamz/s3"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultS3PartSize is the size of the parts files are uploaded in when
+	// they are too large to upload in one request.
+	DefaultS3PartSize = 16 * 1024 * 1024
+	// S3MinPartSize is the smallest part S3 accepts in a multipart upload,
+	// other than the last one.
+	S3MinPartSize = 5 * 1024 * 1024
+
+	// s3MaxParts is the most parts S3 accepts in a multipart upload.
+	s3MaxParts = 10000
+
+	// s3SHA256Meta is the metadata key under which uploads record the
+	// SHA256 of their content.
+	s3SHA256Meta = "sha256"
+)
+
+// S3Checksums are the hex-encoded checksums of a file transferred to or from
+// S3.
+type S3Checksums struct {
+	MD5    string
+	SHA256 string
+}
+
+// S3PutOptions control how UploadS3File uploads a file.
+type S3PutOptions struct {
+	ContentType string
+	Permissions string
+	// PartSize is the size of the parts of a multipart upload. Files no
+	// larger than it are uploaded in a single request. Defaults to
+	// DefaultS3PartSize.
+	PartSize int64
+	// Concurrency is how many parts are uploaded at once. Defaults to 1.
+	Concurrency int
+}
+
+// fileDigest holds the checksums of a whole file and of each of its parts.
+type fileDigest struct {
+	size     int64
+	md5      []byte
+	sha256   []byte
+	partMD5s [][]byte
+}
+
+// digestFile reads the file once, computing its checksums and, if partSize is
+// positive, those of each of its parts.
+func digestFile(file *os.File, partSize int64) (*fileDigest, error) {
+	if _, err := file.Seek(0, 0); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	digest := &fileDigest{}
+	for {
+		var partHash hash.Hash
+		var w io.Writer = io.MultiWriter(md5Hash, sha256Hash)
+		var n int64
+		var err error
+		if partSize > 0 {
+			partHash = md5.New()
+			n, err = io.CopyN(io.MultiWriter(w, partHash), file, partSize)
+		} else {
+			n, err = io.Copy(w, file)
+		}
+		if err != nil && err != io.EOF {
+			return nil, errors.Wrapf(err, "error reading %v", file.Name())
+		}
+		digest.size += n
+		if partHash != nil && (n > 0 || len(digest.partMD5s) == 0) {
+			digest.partMD5s = append(digest.partMD5s, partHash.Sum(nil))
+		}
+		if partSize <= 0 || n < partSize || err == io.EOF {
+			break
+		}
+	}
+	digest.md5, digest.sha256 = md5Hash.Sum(nil), sha256Hash.Sum(nil)
+	return digest, nil
+}
+
+func (d *fileDigest) checksums() *S3Checksums {
+	return &S3Checksums{
+		MD5:    hex.EncodeToString(d.md5),
+		SHA256: hex.EncodeToString(d.sha256),
+	}
+}
+
+// multipartETag returns the ETag S3 gives an object uploaded in the given
+// parts: the MD5 of their MD5s, followed by their number.
+func multipartETag(partMD5s [][]byte) string {
+	sums := md5.New()
+	for _, sum := range partMD5s {
+		sums.Write(sum)
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sums.Sum(nil)), len(partMD5s))
+}
+
+// s3PartSize returns the part size to upload a file of the given size with,
+// growing the requested size if the file would otherwise need too many parts.
+func s3PartSize(size, requested int64) int64 {
+	if requested <= 0 {
+		requested = DefaultS3PartSize
+	}
+	if requested < S3MinPartSize {
+		requested = S3MinPartSize
+	}
+	for size/requested >= s3MaxParts {
+		requested *= 2
+	}
+	return requested
+}
+
+// UploadS3File uploads the file at localPath to key in the bucket, returning
+// its checksums. Files larger than the part size are uploaded in parts,
+// several at once, and the object's ETag is checked against the parts that
+// were sent. Smaller files are uploaded in one request, with their MD5 checked
+// by S3 and their SHA256 recorded in the object's metadata.
+//
+// An error opening the file is returned as is, so that callers can check it
+// with os.IsNotExist.
+func UploadS3File(bucket *s3.Bucket, localPath, key string, opts S3PutOptions) (*S3Checksums, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting info of %v", localPath)
+	}
+	partSize := s3PartSize(info.Size(), opts.PartSize)
+	if info.Size() <= partSize {
+		partSize = 0
+	}
+	digest, err := digestFile(file, partSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if partSize == 0 {
+		if _, err = file.Seek(0, 0); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		options := s3.Options{
+			ContentMD5: base64.StdEncoding.EncodeToString(digest.md5),
+			Meta:       map[string][]string{s3SHA256Meta: {hex.EncodeToString(digest.sha256)}},
+		}
+		err = bucket.PutReader(key, file, digest.size, opts.ContentType, s3.ACL(opts.Permissions), options)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error uploading %v to %v", localPath, key)
+		}
+		return digest.checksums(), nil
+	}
+
+	multi, err := bucket.InitMulti(key, opts.ContentType, s3.ACL(opts.Permissions))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error starting multipart upload of %v to %v", localPath, key)
+	}
+	if err = uploadParts(multi, file, digest.size, partSize, opts.Concurrency); err == nil {
+		err = checkETag(bucket, key, multipartETag(digest.partMD5s))
+	}
+	if err != nil {
+		grip.Warning(errors.Wrapf(multi.Abort(), "error aborting multipart upload of %v", key))
+		return nil, errors.Wrapf(err, "error uploading %v to %v", localPath, key)
+	}
+	return digest.checksums(), nil
+}
+
+// uploadParts uploads the file in parts of partSize, concurrency at a time,
+// then completes the multipart upload.
+func uploadParts(multi *s3.Multi, file *os.File, size, partSize int64, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	numParts := int((size + partSize - 1) / partSize)
+	parts := make([]s3.Part, numParts)
+	partNums := make(chan int, numParts)
+	for i := 0; i < numParts; i++ {
+		partNums <- i
+	}
+	close(partNums)
+
+	catcher := grip.NewCatcher()
+	var catcherMu sync.Mutex
+	wg := &sync.WaitGroup{}
+	for i := 0; i < concurrency && i < numParts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range partNums {
+				offset := int64(n) * partSize
+				length := partSize
+				if offset+length > size {
+					length = size - offset
+				}
+				part, err := multi.PutPart(n+1, io.NewSectionReader(file, offset, length))
+				if err != nil {
+					catcherMu.Lock()
+					catcher.Add(errors.Wrapf(err, "error uploading part %d", n+1))
+					catcherMu.Unlock()
+					continue
+				}
+				parts[n] = part
+			}
+		}()
+	}
+	wg.Wait()
+	if catcher.HasErrors() {
+		return catcher.Resolve()
+	}
+	return errors.Wrap(multi.Complete(parts), "error completing multipart upload")
+}
+
+// checkETag makes sure the object at key has the expected ETag. The ETags of
+// objects encrypted with KMS or customer keys are not MD5s, so they are not
+// checked.
+func checkETag(bucket *s3.Bucket, key, expected string) error {
+	resp, err := bucket.Head(key, nil)
+	if err != nil {
+		return errors.Wrapf(err, "error checking uploaded object %v", key)
+	}
+	defer resp.Body.Close()
+	if hasOpaqueETag(resp.Header) {
+		grip.Debugf("not checking ETag of encrypted object %v", key)
+		return nil
+	}
+	if etag := strings.Trim(resp.Header.Get("ETag"), `"`); etag != expected {
+		return errors.Errorf("uploaded object %v has ETag %v, but the parts sent give %v",
+			key, etag, expected)
+	}
+	return nil
+}
+
+// hasOpaqueETag returns whether the object with the given headers is
+// encrypted in a way that keeps its ETag from being an MD5 of its data.
+func hasOpaqueETag(h http.Header) bool {
+	return h.Get("X-Amz-Server-Side-Encryption") == "aws:kms" ||
+		h.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != ""
+}
+
+// DownloadS3File downloads key in the bucket to localPath, returning the
+// checksums of what was downloaded and whether they were checked against the
+// object. A download that is interrupted is kept next to localPath and
+// resumed by the next call, as long as the object hasn't changed since.
+//
+// Downloads are checked against the SHA256 recorded by UploadS3File, or else
+// against the object's ETag. The ETag of an object uploaded in parts depends
+// on the part size used, so it can only be checked if that is the default or
+// can be worked out from the number of parts, and the ETag of an encrypted
+// object can't be checked at all; otherwise the download is kept but reported
+// as not verified. A download that fails its check is discarded.
+func DownloadS3File(bucket *s3.Bucket, key, localPath string) (*S3Checksums, bool, error) {
+	head, err := bucket.Head(key, nil)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "error getting info of %v", key)
+	}
+	head.Body.Close()
+	quotedETag := head.Header.Get("ETag")
+	etag := strings.Trim(quotedETag, `"`)
+	sha256Meta := head.Header.Get("X-Amz-Meta-" + s3SHA256Meta)
+	size := head.ContentLength
+
+	// name the partial download after the object's ETag, so that a partial
+	// download of an earlier version is never resumed
+	etagSum := md5.Sum([]byte(etag))
+	partialPath := fmt.Sprintf("%s.%s.part", localPath, hex.EncodeToString(etagSum[:4]))
+	stale, _ := filepath.Glob(localPath + ".*.part")
+	for _, path := range stale {
+		if path != partialPath {
+			grip.Warning(errors.Wrapf(os.Remove(path), "error removing stale partial download %v", path))
+		}
+	}
+
+	if err = os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return nil, false, errors.Wrapf(err, "error creating directory for %v", localPath)
+	}
+	if err = downloadRemainder(bucket, key, quotedETag, size, partialPath); err != nil {
+		return nil, false, err
+	}
+
+	file, err := os.Open(partialPath)
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	digest, err := digestFile(file, 0)
+	file.Close()
+	if err != nil {
+		return nil, false, err
+	}
+	sums := digest.checksums()
+
+	checkedETag := etag
+	if hasOpaqueETag(head.Header) {
+		checkedETag = ""
+	}
+	verified, err := verifyDownload(partialPath, sums, checkedETag, sha256Meta)
+	if err != nil {
+		grip.Warning(errors.Wrapf(os.Remove(partialPath), "error removing download %v", partialPath))
+		return nil, false, errors.Wrapf(err, "download of %v is corrupt", key)
+	}
+	if err = os.Rename(partialPath, localPath); err != nil {
+		return nil, false, errors.Wrapf(err, "error moving download to %v", localPath)
+	}
+	return sums, verified, nil
+}
+
+// downloadRemainder downloads whatever part of the object is not already in
+// partialPath. If the object changes while doing so, the partial download is
+// discarded and an error returned, so that the next attempt starts over.
+func downloadRemainder(bucket *s3.Bucket, key, etag string, size int64, partialPath string) error {
+	var offset int64
+	if info, err := os.Stat(partialPath); err == nil && info.Size() <= size {
+		offset = info.Size()
+	}
+	if offset == size && size > 0 {
+		return nil
+	}
+
+	headers := map[string][]string{}
+	if etag != "" {
+		headers["If-Match"] = []string{etag}
+	}
+	if offset > 0 {
+		headers["Range"] = []string{"bytes=" + strconv.FormatInt(offset, 10) + "-"}
+	}
+	resp, err := bucket.GetResponseWithHeaders(key, headers)
+	if err != nil {
+		if s3Err, ok := err.(*s3.Error); ok && s3Err.StatusCode == http.StatusPreconditionFailed {
+			grip.Warning(errors.Wrapf(os.Remove(partialPath), "error removing partial download %v", partialPath))
+			return errors.Errorf("%v changed while it was being downloaded", key)
+		}
+		return errors.Wrapf(err, "error getting %v", key)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if resp.StatusCode == http.StatusPartialContent {
+		flags |= os.O_APPEND
+	} else {
+		// the whole object was sent
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "error opening %v", partialPath)
+	}
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return errors.Wrapf(err, "error downloading %v", key)
+}
+
+// verifyDownload checks a download's checksums against the object's. It
+// returns whether the download could be checked, and an error if it failed
+// the check.
+func verifyDownload(path string, sums *S3Checksums, etag, sha256Meta string) (bool, error) {
+	if sha256Meta != "" {
+		if sums.SHA256 != sha256Meta {
+			return false, errors.Errorf("SHA256 is %v, expected %v", sums.SHA256, sha256Meta)
+		}
+		return true, nil
+	}
+
+	dash := strings.Index(etag, "-")
+	if dash < 0 {
+		if len(etag) != md5.Size*2 {
+			// not an MD5, e.g. because the object is encrypted
+			return false, nil
+		}
+		if sums.MD5 != etag {
+			return false, errors.Errorf("MD5 is %v, expected %v", sums.MD5, etag)
+		}
+		return true, nil
+	}
+
+	numParts, err := strconv.ParseInt(etag[dash+1:], 10, 64)
+	if err != nil || numParts < 1 {
+		return false, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	for _, partSize := range candidatePartSizes(info.Size(), numParts) {
+		digest, err := digestFile(file, partSize)
+		if err != nil {
+			return false, err
+		}
+		if multipartETag(digest.partMD5s) == etag {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// candidatePartSizes returns the part sizes an object of the given size might
+// have been uploaded in, given how many parts it has: the default and minimum
+// part sizes, and the smallest and largest whole numbers of MiB that give that
+// many parts.
+func candidatePartSizes(size, numParts int64) []int64 {
+	const mib = 1024 * 1024
+	sizes := []int64{DefaultS3PartSize, S3MinPartSize}
+	smallest := (size + numParts - 1) / numParts
+	sizes = append(sizes, (smallest+mib-1)/mib*mib)
+	if numParts > 1 {
+		largest := (size - 1) / (numParts - 1)
+		sizes = append(sizes, largest/mib*mib)
+	}
+
+	candidates := []int64{}
+	for _, partSize := range sizes {
+		if partSize <= 0 || (size+partSize-1)/partSize != numParts {
+			continue
+		}
+		if !util.SliceContains(candidates, partSize) {
+			candidates = append(candidates, partSize)
+		}
+	}
+	return candidates
+}
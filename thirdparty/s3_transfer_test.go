@@ -0,0 +1,137 @@
+package thirdparty
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/evergreen-ci/evergreen/thirdparty/s3stub"
+	"github.com/goamz/goamz/aws"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestS3Transfers(t *testing.T) {
+	Convey("With a local S3 server", t, func() {
+		server := s3stub.NewServer()
+		defer server.Close()
+		bucket := NewS3Session(&aws.Auth{AccessKey: "key", SecretKey: "secret"}, server.Region()).Bucket("bucket")
+
+		dir, err := ioutil.TempDir("", "s3_transfer_test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		writeFile := func(name string, size int) (string, []byte) {
+			data := make([]byte, size)
+			_, err := rand.New(rand.NewSource(int64(size))).Read(data)
+			So(err, ShouldBeNil)
+			path := filepath.Join(dir, name)
+			So(ioutil.WriteFile(path, data, 0644), ShouldBeNil)
+			return path, data
+		}
+		opts := S3PutOptions{ContentType: "application/octet-stream", PartSize: S3MinPartSize, Concurrency: 3}
+
+		Convey("a small file should be uploaded whole with its SHA256", func() {
+			path, data := writeFile("small", 1024)
+			sums, err := UploadS3File(bucket, path, "path/small", opts)
+			So(err, ShouldBeNil)
+			So(sums.SHA256, ShouldHaveLength, 64)
+
+			obj := server.Object("bucket", "path/small")
+			So(obj, ShouldNotBeNil)
+			So(bytes.Equal(obj.Data, data), ShouldBeTrue)
+			So(obj.Meta.Get("X-Amz-Meta-Sha256"), ShouldEqual, sums.SHA256)
+			So(server.PartsReceived(), ShouldEqual, 0)
+
+			Convey("and downloaded and verified", func() {
+				out := filepath.Join(dir, "small.out")
+				got, verified, err := DownloadS3File(bucket, "path/small", out)
+				So(err, ShouldBeNil)
+				So(verified, ShouldBeTrue)
+				So(*got, ShouldResemble, *sums)
+			})
+
+			Convey("and a corrupt download should be discarded", func() {
+				obj.Data[0]++
+				out := filepath.Join(dir, "small.out")
+				_, _, err := DownloadS3File(bucket, "path/small", out)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "corrupt")
+				leftovers, _ := filepath.Glob(out + "*")
+				So(leftovers, ShouldBeEmpty)
+			})
+		})
+
+		Convey("a large file should be uploaded in parts", func() {
+			path, data := writeFile("large", 2*S3MinPartSize+1000)
+			sums, err := UploadS3File(bucket, path, "large", opts)
+			So(err, ShouldBeNil)
+			So(server.PartsReceived(), ShouldEqual, 3)
+
+			obj := server.Object("bucket", "large")
+			So(bytes.Equal(obj.Data, data), ShouldBeTrue)
+			So(strings.HasSuffix(obj.ETag, `-3"`), ShouldBeTrue)
+
+			Convey("and downloaded and verified against its ETag", func() {
+				out := filepath.Join(dir, "large.out")
+				got, verified, err := DownloadS3File(bucket, "large", out)
+				So(err, ShouldBeNil)
+				So(verified, ShouldBeTrue)
+				So(*got, ShouldResemble, *sums)
+			})
+
+			Convey("and an interrupted download should be resumed", func() {
+				out := filepath.Join(dir, "large.out")
+				server.TruncateNextGet(S3MinPartSize)
+				_, _, err := DownloadS3File(bucket, "large", out)
+				So(err, ShouldNotBeNil)
+				partial, _ := filepath.Glob(out + ".*.part")
+				So(len(partial), ShouldEqual, 1)
+
+				got, verified, err := DownloadS3File(bucket, "large", out)
+				So(err, ShouldBeNil)
+				So(verified, ShouldBeTrue)
+				So(*got, ShouldResemble, *sums)
+				downloaded, err := ioutil.ReadFile(out)
+				So(err, ShouldBeNil)
+				So(bytes.Equal(downloaded, data), ShouldBeTrue)
+			})
+		})
+
+		Convey("a large file should be uploaded in parts to a bucket encrypted with KMS", func() {
+			server.EncryptWithKMS()
+			path, data := writeFile("encrypted", 2*S3MinPartSize+1000)
+			_, err := UploadS3File(bucket, path, "encrypted", opts)
+			So(err, ShouldBeNil)
+
+			obj := server.Object("bucket", "encrypted")
+			So(bytes.Equal(obj.Data, data), ShouldBeTrue)
+			So(strings.Contains(obj.ETag, "-"), ShouldBeFalse)
+
+			Convey("and downloaded without being checked against its ETag", func() {
+				out := filepath.Join(dir, "encrypted.out")
+				_, verified, err := DownloadS3File(bucket, "encrypted", out)
+				So(err, ShouldBeNil)
+				So(verified, ShouldBeFalse)
+			})
+		})
+
+		Convey("a missing file should give an error that says so", func() {
+			_, err := UploadS3File(bucket, filepath.Join(dir, "missing"), "missing", opts)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+}
+
+func TestCandidatePartSizes(t *testing.T) {
+	Convey("Part sizes should be guessed from the number of parts", t, func() {
+		const mib = 1024 * 1024
+		So(candidatePartSizes(40*mib, 3), ShouldResemble, []int64{DefaultS3PartSize, 14 * mib, 19 * mib})
+		So(candidatePartSizes(12*mib, 3), ShouldResemble, []int64{S3MinPartSize, 4 * mib})
+		So(candidatePartSizes(12*mib, 1), ShouldResemble, []int64{DefaultS3PartSize, 12 * mib})
+		So(candidatePartSizes(12*mib, 20000), ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,392 @@
+// Package s3stub is an in-memory stand-in for S3, for testing transfers
+// without a real bucket. It supports the requests the agent makes: simple and
+// multipart uploads, copies, ranged and conditional downloads, HEAD requests,
+// and KMS encryption.
+// Requests are not authenticated.
+package s3stub
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goamz/goamz/aws"
+)
+
+// Object is an object stored in the server.
+type Object struct {
+	Data        []byte
+	ETag        string
+	ContentType string
+	Meta        http.Header
+	// Encryption is the object's server-side encryption, if any.
+	Encryption string
+}
+
+type upload struct {
+	bucket, key string
+	contentType string
+	meta        http.Header
+	parts       map[int][]byte
+}
+
+// Server is an S3 stand-in listening on a local port.
+type Server struct {
+	*httptest.Server
+
+	mu             sync.Mutex
+	objects        map[string]*Object
+	uploads        map[string]*upload
+	nextUploadId   int
+	partsReceived  int
+	truncateGetsAt int
+	kms            bool
+}
+
+// NewServer starts a server. Close it when done.
+func NewServer() *Server {
+	srv := &Server{
+		objects: map[string]*Object{},
+		uploads: map[string]*upload{},
+	}
+	srv.Server = httptest.NewServer(http.HandlerFunc(srv.handle))
+	return srv
+}
+
+// Region returns a region whose endpoint is the server, with buckets
+// addressed by path.
+func (srv *Server) Region() aws.Region {
+	return aws.Region{Name: "s3stub", S3Endpoint: srv.URL}
+}
+
+// Object returns the object stored under key in bucket, or nil.
+func (srv *Server) Object(bucket, key string) *Object {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.objects[bucket+"/"+strings.TrimPrefix(key, "/")]
+}
+
+// PartsReceived returns how many multipart upload parts have been received.
+func (srv *Server) PartsReceived() int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.partsReceived
+}
+
+// TruncateNextGet makes the next GET of an object close the connection after
+// n bytes of the body, as if it had dropped.
+func (srv *Server) TruncateNextGet(n int) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.truncateGetsAt = n
+}
+
+// EncryptWithKMS makes the server encrypt the objects uploaded from then on
+// with KMS, as a bucket with default encryption would. As with S3, their
+// ETags are then not MD5s.
+func (srv *Server) EncryptWithKMS() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.kms = true
+}
+
+// store stores an uploaded object, encrypting it if need be.
+func (srv *Server) store(name string, obj *Object) {
+	if srv.kms {
+		sum := md5.Sum([]byte("kms" + obj.ETag))
+		obj.ETag = `"` + hex.EncodeToString(sum[:]) + `"`
+		obj.Encryption = "aws:kms"
+	}
+	srv.objects[name] = obj
+}
+
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(s3Error{Code: code, Message: msg})
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func (srv *Server) handle(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		writeError(w, http.StatusBadRequest, "InvalidURI", "only object requests are supported")
+		return
+	}
+	bucket, key := parts[0], parts[1]
+	query := r.URL.Query()
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	switch {
+	case r.Method == "POST" && hasParam(query, "uploads"):
+		srv.initMulti(w, r, bucket, key)
+	case r.Method == "PUT" && query.Get("uploadId") != "":
+		srv.putPart(w, r, query)
+	case r.Method == "POST" && query.Get("uploadId") != "":
+		srv.completeMulti(w, r, bucket, key, query.Get("uploadId"))
+	case r.Method == "DELETE" && query.Get("uploadId") != "":
+		delete(srv.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "PUT" && r.Header.Get("x-amz-copy-source") != "":
+		srv.copyObject(w, r, bucket, key)
+	case r.Method == "PUT":
+		srv.putObject(w, r, bucket, key)
+	case r.Method == "GET" || r.Method == "HEAD":
+		srv.getObject(w, r, bucket+"/"+key)
+	case r.Method == "DELETE":
+		delete(srv.objects, bucket+"/"+key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported")
+	}
+}
+
+func hasParam(query map[string][]string, name string) bool {
+	_, ok := query[name]
+	return ok
+}
+
+func metaHeaders(h http.Header) http.Header {
+	meta := http.Header{}
+	for name, values := range h {
+		if strings.HasPrefix(name, "X-Amz-Meta-") {
+			meta[name] = values
+		}
+	}
+	return meta
+}
+
+// readBody reads the request body, checking it against its Content-MD5.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return nil, false
+	}
+	if want := r.Header.Get("Content-MD5"); want != "" {
+		sum := md5.Sum(data)
+		if base64.StdEncoding.EncodeToString(sum[:]) != want {
+			writeError(w, http.StatusBadRequest, "BadDigest", "the Content-MD5 did not match")
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+func (srv *Server) putObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	data, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	sum := md5.Sum(data)
+	obj := &Object{
+		Data:        data,
+		ETag:        `"` + hex.EncodeToString(sum[:]) + `"`,
+		ContentType: r.Header.Get("Content-Type"),
+		Meta:        metaHeaders(r.Header),
+	}
+	srv.store(bucket+"/"+key, obj)
+	w.Header().Set("ETag", obj.ETag)
+}
+
+func (srv *Server) copyObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	source := strings.TrimPrefix(r.Header.Get("x-amz-copy-source"), "/")
+	obj, ok := srv.objects[source]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "the copy source does not exist")
+		return
+	}
+	copied := *obj
+	srv.objects[bucket+"/"+key] = &copied
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CopyObjectResult"`
+		ETag    string
+	}{ETag: copied.ETag})
+}
+
+func (srv *Server) initMulti(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	srv.nextUploadId++
+	id := strconv.Itoa(srv.nextUploadId)
+	srv.uploads[id] = &upload{
+		bucket:      bucket,
+		key:         key,
+		contentType: r.Header.Get("Content-Type"),
+		meta:        metaHeaders(r.Header),
+		parts:       map[int][]byte{},
+	}
+	writeXML(w, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string
+		Key      string
+		UploadId string
+	}{Bucket: bucket, Key: key, UploadId: id})
+}
+
+func (srv *Server) putPart(w http.ResponseWriter, r *http.Request, query map[string][]string) {
+	up, ok := srv.uploads[query["uploadId"][0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "the upload does not exist")
+		return
+	}
+	n, err := strconv.Atoi(strings.Join(query["partNumber"], ""))
+	if err != nil || n < 1 {
+		writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+		return
+	}
+	data, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	up.parts[n] = data
+	srv.partsReceived++
+	sum := md5.Sum(data)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+}
+
+func (srv *Server) completeMulti(w http.ResponseWriter, r *http.Request, bucket, key, id string) {
+	up, ok := srv.uploads[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "the upload does not exist")
+		return
+	}
+	var req struct {
+		Parts []struct {
+			PartNumber int
+			ETag       string
+		} `xml:"Part"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	numbers := []int{}
+	for _, part := range req.Parts {
+		data, ok := up.parts[part.PartNumber]
+		sum := md5.Sum(data)
+		if !ok || strings.Trim(part.ETag, `"`) != hex.EncodeToString(sum[:]) {
+			writeError(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("part %d is not valid", part.PartNumber))
+			return
+		}
+		numbers = append(numbers, part.PartNumber)
+	}
+	sort.Ints(numbers)
+
+	// a multipart object's ETag is the MD5 of its parts' MD5s, and the
+	// number of parts
+	var data bytes.Buffer
+	sums := md5.New()
+	for _, n := range numbers {
+		data.Write(up.parts[n])
+		sum := md5.Sum(up.parts[n])
+		sums.Write(sum[:])
+	}
+	obj := &Object{
+		Data:        data.Bytes(),
+		ETag:        fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sums.Sum(nil)), len(numbers)),
+		ContentType: up.contentType,
+		Meta:        up.meta,
+	}
+	srv.store(bucket+"/"+key, obj)
+	delete(srv.uploads, id)
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string
+		Key     string
+		ETag    string
+	}{Bucket: bucket, Key: key, ETag: obj.ETag})
+}
+
+func (srv *Server) getObject(w http.ResponseWriter, r *http.Request, name string) {
+	obj, ok := srv.objects[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "the specified key does not exist")
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && match != obj.ETag {
+		writeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "the object has changed")
+		return
+	}
+
+	data := obj.Data
+	status := http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" {
+		var start, end int
+		spec := strings.TrimPrefix(rng, "bytes=")
+		bounds := strings.SplitN(spec, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil || len(bounds) != 2 {
+			writeError(w, http.StatusBadRequest, "InvalidRange", "only ranges with a start are supported")
+			return
+		}
+		end = len(data) - 1
+		if bounds[1] != "" {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				writeError(w, http.StatusBadRequest, "InvalidRange", "invalid range end")
+				return
+			}
+		}
+		if start >= len(data) {
+			writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "the range is not satisfiable")
+			return
+		}
+		if end >= len(data) {
+			end = len(data) - 1
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+
+	for name, values := range obj.Meta {
+		w.Header()[name] = values
+	}
+	if obj.Encryption != "" {
+		w.Header().Set("X-Amz-Server-Side-Encryption", obj.Encryption)
+	}
+	w.Header().Set("ETag", obj.ETag)
+	w.Header().Set("Content-Type", obj.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == "HEAD" {
+		return
+	}
+
+	if srv.truncateGetsAt > 0 && srv.truncateGetsAt < len(data) {
+		data = data[:srv.truncateGetsAt]
+		srv.truncateGetsAt = 0
+		_, _ = w.Write(data)
+		// drop the connection so the client sees a short body
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				_ = conn.Close()
+			}
+		}
+		return
+	}
+	_, _ = w.Write(data)
+}
@@ -60,6 +60,13 @@ func getBackoff(initialSleep time.Duration, numAttempts int) *backoff.Backoff {
 //
 // If you specify 0 attempts, Retry will use an attempt value of one.
 func Retry(op RetriableFunc, attempts int, sleep time.Duration) (bool, error) {
+	return RetryWithStop(op, attempts, sleep, nil)
+}
+
+// RetryWithStop is Retry, except that it gives up as soon as stop is closed
+// while it is waiting to retry the operation, returning the operation's last
+// failure.
+func RetryWithStop(op RetriableFunc, attempts int, sleep time.Duration, stop <-chan bool) (bool, error) {
 	backoff := getBackoff(sleep, attempts)
 	for i := attempts; i >= 0; i-- {
 		err := op()
@@ -76,7 +83,13 @@ func Retry(op RetriableFunc, attempts int, sleep time.Duration) (bool, error) {
 			}
 
 			// it's safe to retry this, so sleep for a moment and try again
-			time.Sleep(backoff.Duration())
+			timer := time.NewTimer(backoff.Duration())
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				return false, errors.Wrap(err.(RetriableError).Failure, "stopped before retrying operation")
+			}
 		} else {
 			//function returned err but it can't be retried - fail immediately
 			return false, err
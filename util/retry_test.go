@@ -83,3 +83,30 @@ func TestNonRetriableFailure(t *testing.T) {
 		})
 	})
 }
+
+func TestRetryStopped(t *testing.T) {
+	Convey("When retrying a function that never succeeds until stopped", t, func() {
+		failingFunc := func() error {
+			return RetriableError{errors.New("something went wrong")}
+		}
+		stop := make(chan bool)
+		go func() {
+			time.Sleep(TestSleep)
+			close(stop)
+		}()
+
+		start := time.Now()
+		retryFail, err := RetryWithStop(failingFunc, TestRetries, time.Minute, stop)
+
+		Convey("calling it with RetryWithStop should return the failure", func() {
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "something went wrong")
+		})
+		Convey("the 'retried till failure' flag should be false", func() {
+			So(retryFail, ShouldBeFalse)
+		})
+		Convey("it should not wait out the sleep between attempts", func() {
+			So(time.Now(), ShouldHappenBefore, start.Add(time.Minute))
+		})
+	})
+}
@@ -2,9 +2,12 @@ package validator
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/cloud/providers"
+	"github.com/evergreen-ci/evergreen/cloud/providers/docker"
+	"github.com/evergreen-ci/evergreen/cloud/providers/ec2"
 	"github.com/evergreen-ci/evergreen/cloud/providers/static"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/util"
@@ -19,6 +22,15 @@ var distroSyntaxValidators = []distroValidator{
 	ensureValidSSHOptions,
 	ensureValidExpansions,
 	ensureStaticHostsAreNotSpawnable,
+	ensureValidHostPool,
+	ensureValidBootstrapMethod,
+}
+
+// userDataBootstrapProviders are the providers that can start a host's agent
+// from the host's user data or container entrypoint.
+var userDataBootstrapProviders = []string{
+	ec2.OnDemandProviderName,
+	docker.ProviderName,
 }
 
 // CheckDistro checks if the distro configuration syntax is valid. Returns
@@ -88,7 +100,7 @@ func ensureHasRequiredFields(d *distro.Distro, s *evergreen.Settings) []Validati
 		})
 	}
 
-	if d.SSHKey == "" && d.Provider != static.ProviderName {
+	if d.SSHKey == "" && d.Provider != static.ProviderName && d.BootstrapsOverSSH() {
 		errs = append(errs, ValidationError{
 			Message: fmt.Sprintf("distro '%v' cannot be blank", distro.SSHKeyKey),
 			Level:   Error,
@@ -156,3 +168,73 @@ func ensureValidSSHOptions(d *distro.Distro, s *evergreen.Settings) []Validation
 	}
 	return nil
 }
+
+// ensureValidHostPool checks that the distro's warm pool minimums fit within
+// its pool size and that its warm schedules are well-formed.
+func ensureValidHostPool(d *distro.Distro, s *evergreen.Settings) []ValidationError {
+	errs := []ValidationError{}
+
+	if d.MinHosts < 0 {
+		errs = append(errs, ValidationError{
+			Message: fmt.Sprintf("distro '%v' cannot be negative", distro.MinHostsKey),
+			Level:   Error,
+		})
+	}
+	if d.MinHosts > d.PoolSize {
+		errs = append(errs, ValidationError{
+			Message: fmt.Sprintf("distro '%v' (%d) cannot be greater than '%v' (%d)",
+				distro.MinHostsKey, d.MinHosts, distro.PoolSizeKey, d.PoolSize),
+			Level: Error,
+		})
+	}
+
+	for i, schedule := range d.WarmSchedules {
+		if err := schedule.Validate(); err != nil {
+			errs = append(errs, ValidationError{
+				Message: fmt.Sprintf("distro '%v' entry %d is invalid: %v",
+					distro.WarmSchedulesKey, i, err),
+				Level: Error,
+			})
+			continue
+		}
+		if schedule.MinHosts > d.PoolSize {
+			errs = append(errs, ValidationError{
+				Message: fmt.Sprintf("distro '%v' entry %d minimum hosts (%d) cannot be greater than '%v' (%d)",
+					distro.WarmSchedulesKey, i, schedule.MinHosts, distro.PoolSizeKey, d.PoolSize),
+				Level: Error,
+			})
+		}
+	}
+
+	return errs
+}
+
+// ensureValidBootstrapMethod checks that the distro's bootstrap method is known
+// and that the distro's provider and architecture support it.
+func ensureValidBootstrapMethod(d *distro.Distro, s *evergreen.Settings) []ValidationError {
+	switch d.BootstrapMethod {
+	case "", distro.BootstrapMethodSSH:
+		return nil
+	case distro.BootstrapMethodUserData:
+	default:
+		return []ValidationError{{Error, fmt.Sprintf("distro '%v' '%v' is not one of '%v' or '%v'",
+			distro.BootstrapMethodKey, d.BootstrapMethod, distro.BootstrapMethodSSH, distro.BootstrapMethodUserData)}}
+	}
+
+	errs := []ValidationError{}
+	if !util.SliceContains(userDataBootstrapProviders, d.Provider) {
+		errs = append(errs, ValidationError{
+			Message: fmt.Sprintf("distro '%v' '%v' is not supported by provider '%v'",
+				distro.BootstrapMethodKey, d.BootstrapMethod, d.Provider),
+			Level: Error,
+		})
+	}
+	if strings.HasPrefix(d.Arch, "windows") {
+		errs = append(errs, ValidationError{
+			Message: fmt.Sprintf("distro '%v' '%v' is not supported on windows",
+				distro.BootstrapMethodKey, d.BootstrapMethod),
+			Level: Error,
+		})
+	}
+	return errs
+}
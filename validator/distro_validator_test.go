@@ -238,3 +238,58 @@ func TestEnsureValidSSHOptions(t *testing.T) {
 		})
 	})
 }
+
+func TestEnsureValidHostPool(t *testing.T) {
+	Convey("When validating a distro's warm host pool...", t, func() {
+		Convey("if the minimums fit within the pool size, no error should be returned", func() {
+			d := &distro.Distro{
+				PoolSize: 10,
+				MinHosts: 2,
+				WarmSchedules: []distro.WarmSchedule{
+					{Days: []string{"weekdays"}, StartHour: 8, EndHour: 18, MinHosts: 10},
+				},
+			}
+			So(ensureValidHostPool(d, conf), ShouldBeEmpty)
+		})
+		Convey("if min hosts is greater than the pool size, an error should be returned", func() {
+			d := &distro.Distro{PoolSize: 1, MinHosts: 2}
+			So(len(ensureValidHostPool(d, conf)), ShouldEqual, 1)
+		})
+		Convey("if a schedule is malformed or too large, an error should be returned", func() {
+			d := &distro.Distro{
+				PoolSize: 10,
+				WarmSchedules: []distro.WarmSchedule{
+					{Days: []string{"caturday"}, StartHour: 8, EndHour: 18, MinHosts: 1},
+					{StartHour: 8, EndHour: 18, MinHosts: 11},
+				},
+			}
+			So(len(ensureValidHostPool(d, conf)), ShouldEqual, 2)
+		})
+	})
+}
+
+func TestEnsureValidBootstrapMethod(t *testing.T) {
+	Convey("When validating a distro's bootstrap method...", t, func() {
+		d := &distro.Distro{Arch: "linux_amd64", Provider: ec2.OnDemandProviderName}
+		Convey("if it is unset or ssh, no error should be returned", func() {
+			So(ensureValidBootstrapMethod(d, conf), ShouldBeEmpty)
+			d.BootstrapMethod = distro.BootstrapMethodSSH
+			So(ensureValidBootstrapMethod(d, conf), ShouldBeEmpty)
+		})
+		Convey("if it is user-data on a supported provider, no error should be returned", func() {
+			d.BootstrapMethod = distro.BootstrapMethodUserData
+			So(ensureValidBootstrapMethod(d, conf), ShouldBeEmpty)
+		})
+		Convey("if it is unknown, an error should be returned", func() {
+			d.BootstrapMethod = "carrier-pigeon"
+			So(len(ensureValidBootstrapMethod(d, conf)), ShouldEqual, 1)
+		})
+		Convey("if it is user-data on an unsupported provider or windows, an error should be returned", func() {
+			d.BootstrapMethod = distro.BootstrapMethodUserData
+			d.Provider = "static"
+			So(len(ensureValidBootstrapMethod(d, conf)), ShouldEqual, 1)
+			d.Arch = "windows_amd64"
+			So(len(ensureValidBootstrapMethod(d, conf)), ShouldEqual, 2)
+		})
+	})
+}
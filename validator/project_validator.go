@@ -2,11 +2,13 @@ package validator
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/evergreen-ci/evergreen"
 	"github.com/evergreen-ci/evergreen/model"
+	"github.com/evergreen-ci/evergreen/model/artifact"
 	"github.com/evergreen-ci/evergreen/model/distro"
 	"github.com/evergreen-ci/evergreen/plugin"
 	"github.com/evergreen-ci/evergreen/util"
@@ -52,6 +54,7 @@ var projectSyntaxValidators = []projectValidator{
 	checkAllDependenciesSpec,
 	validateProjectTaskNames,
 	validateProjectTaskIdsAndTags,
+	validateTimeoutDiagnostics,
 }
 
 // Functions used to validate the semantics of a project configuration file.
@@ -313,7 +316,8 @@ func ensureHasNecessaryProjectFields(project *model.Project) []ValidationError {
 
 	if project.CommandType != "" {
 		if project.CommandType != model.SystemCommandType &&
-			project.CommandType != model.TestCommandType {
+			project.CommandType != model.TestCommandType &&
+			project.CommandType != model.SetupCommandType {
 			errs = append(errs,
 				ValidationError{
 					Message: fmt.Sprintf("project '%v' contains an invalid "+
@@ -492,11 +496,20 @@ func validateCommands(section string, project *model.Project, registry plugin.Re
 		}
 		if cmd.Type != "" {
 			if cmd.Type != model.SystemCommandType &&
-				cmd.Type != model.TestCommandType {
+				cmd.Type != model.TestCommandType &&
+				cmd.Type != model.SetupCommandType {
 				msg := fmt.Sprintf("%v section in '%v': invalid command type: '%v'", section, command, cmd.Type)
 				errs = append(errs, ValidationError{Message: msg})
 			}
 		}
+		if cmd.RetryOnFailure < 0 {
+			msg := fmt.Sprintf("%v section in '%v': retry_on_failure must not be negative", section, command)
+			errs = append(errs, ValidationError{Message: msg})
+		}
+		if cmd.RetryBackoffSecs < 0 {
+			msg := fmt.Sprintf("%v section in '%v': retry_backoff_secs must not be negative", section, command)
+			errs = append(errs, ValidationError{Message: msg})
+		}
 	}
 	return errs
 }
@@ -597,6 +610,69 @@ func validateProjectTaskNames(project *model.Project) []ValidationError {
 	return errs
 }
 
+// validateTimeoutDiagnostics ensures that the project's timeout diagnostics
+// are complete and that its hooks' process regexes compile
+func validateTimeoutDiagnostics(project *model.Project) []ValidationError {
+	errs := []ValidationError{}
+	diag := project.TimeoutDiagnostics
+	if diag == nil {
+		return errs
+	}
+	if diag.Signal != "" && !util.SliceContains(model.TimeoutDiagnosticsSignals, diag.Signal) {
+		errs = append(errs, ValidationError{
+			Message: fmt.Sprintf("timeout diagnostics signal '%v' must be one of %v",
+				diag.Signal, model.TimeoutDiagnosticsSignals),
+		})
+	}
+	if diag.SignalWaitSecs < 0 {
+		errs = append(errs, ValidationError{
+			Message: "timeout diagnostics signal_wait_secs cannot be negative",
+		})
+	}
+	for i, hook := range diag.Hooks {
+		if hook.Name == "" {
+			errs = append(errs, ValidationError{
+				Message: fmt.Sprintf("timeout diagnostics hook %v must have a name", i),
+			})
+		}
+		if hook.Command == "" {
+			errs = append(errs, ValidationError{
+				Message: fmt.Sprintf("timeout diagnostics hook '%v' must have a command", hook.Name),
+			})
+		}
+		if _, err := regexp.Compile(hook.Processes); err != nil {
+			errs = append(errs, ValidationError{
+				Message: fmt.Sprintf("timeout diagnostics hook '%v' has an invalid processes regex: %v",
+					hook.Name, err),
+			})
+		}
+		if hook.TimeoutSecs < 0 {
+			errs = append(errs, ValidationError{
+				Message: fmt.Sprintf("timeout diagnostics hook '%v' cannot have a negative timeout", hook.Name),
+			})
+		}
+	}
+	if diag.S3 != nil {
+		if diag.S3.Bucket == "" || diag.S3.AwsKey == "" || diag.S3.AwsSecret == "" {
+			errs = append(errs, ValidationError{
+				Message: "timeout diagnostics s3 must have a bucket, aws_key and aws_secret",
+			})
+		}
+		if !util.SliceContains(artifact.ValidVisibilities, diag.S3.Visibility) {
+			errs = append(errs, ValidationError{
+				Message: fmt.Sprintf("invalid timeout diagnostics s3 visibility '%v'", diag.S3.Visibility),
+			})
+		}
+		if len(diag.CoreFiles) == 0 {
+			errs = append(errs, ValidationError{
+				Level:   Warning,
+				Message: "timeout diagnostics s3 is set but no core_files are collected",
+			})
+		}
+	}
+	return errs
+}
+
 // validateProjectTaskIdsAndTags ensures that task tags and ids only contain valid characters
 func validateProjectTaskIdsAndTags(project *model.Project) []ValidationError {
 	errs := []ValidationError{}
@@ -935,6 +935,27 @@ func TestValidatePluginCommands(t *testing.T) {
 			So(validatePluginCommands(project), ShouldNotResemble, []ValidationError{})
 			So(len(validatePluginCommands(project)), ShouldEqual, 1)
 		})
+		Convey("an error should be thrown if a command's retry settings are negative", func() {
+			project := &model.Project{
+				Tasks: []model.ProjectTask{
+					{
+						Name: "compile",
+						Commands: []model.PluginCommandConf{
+							{
+								Command:          "gotest.parse_files",
+								Type:             model.SetupCommandType,
+								RetryOnFailure:   -1,
+								RetryBackoffSecs: -1,
+								Params: map[string]interface{}{
+									"files": []interface{}{"test"},
+								},
+							},
+						},
+					},
+				},
+			}
+			So(len(validatePluginCommands(project)), ShouldEqual, 2)
+		})
 		Convey("no error should be thrown if a function plugin command is valid", func() {
 			project := &model.Project{
 				Functions: map[string]*model.YAMLCommandSet{
@@ -1329,6 +1350,15 @@ func TestEnsureHasNecessaryProjectFields(t *testing.T) {
 				So(len(ensureHasNecessaryProjectFields(project)),
 					ShouldEqual, 1)
 			})
+			Convey("no error should be thrown if the command type "+
+				"field is setup", func() {
+				project := &model.Project{
+					BatchTime:   10,
+					CommandType: model.SetupCommandType,
+				}
+				So(ensureHasNecessaryProjectFields(project),
+					ShouldResemble, []ValidationError{})
+			})
 		})
 	})
 }
@@ -1440,3 +1470,48 @@ func TestEnsureHasNecessaryBVFields(t *testing.T) {
 		})
 	})
 }
+
+func TestValidateTimeoutDiagnostics(t *testing.T) {
+	Convey("When validating a project's timeout diagnostics", t, func() {
+		project := &model.Project{Identifier: "projectId"}
+
+		Convey("no error should be returned if they are not set", func() {
+			So(validateTimeoutDiagnostics(project), ShouldResemble, []ValidationError{})
+		})
+
+		Convey("no error should be returned for complete diagnostics", func() {
+			project.TimeoutDiagnostics = &model.TimeoutDiagnostics{
+				Signal:    "SIGABRT",
+				CoreFiles: []string{"core.*"},
+				Hooks: []model.TimeoutHook{
+					{Name: "jstack", Command: "jstack ${pid}", Processes: "java"},
+				},
+				S3: &model.TimeoutDiagnosticsS3{Bucket: "cores", AwsKey: "key", AwsSecret: "secret"},
+			}
+			So(validateTimeoutDiagnostics(project), ShouldResemble, []ValidationError{})
+		})
+
+		Convey("an error should be returned for an unsupported signal", func() {
+			project.TimeoutDiagnostics = &model.TimeoutDiagnostics{Signal: "SIGSTOP"}
+			So(len(validateTimeoutDiagnostics(project)), ShouldEqual, 1)
+		})
+
+		Convey("errors should be returned for incomplete hooks", func() {
+			project.TimeoutDiagnostics = &model.TimeoutDiagnostics{
+				Hooks: []model.TimeoutHook{
+					{Command: "gdb -p ${pid}"},
+					{Name: "jstack", Command: "jstack ${pid}", Processes: "("},
+				},
+			}
+			So(len(validateTimeoutDiagnostics(project)), ShouldEqual, 2)
+		})
+
+		Convey("an error should be returned for incomplete s3 settings", func() {
+			project.TimeoutDiagnostics = &model.TimeoutDiagnostics{
+				CoreFiles: []string{"core.*"},
+				S3:        &model.TimeoutDiagnosticsS3{Bucket: "cores"},
+			}
+			So(len(validateTimeoutDiagnostics(project)), ShouldEqual, 1)
+		})
+	})
+}